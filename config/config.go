@@ -58,6 +58,14 @@ type ServerConfig struct {
 	LogLevel            string        `toml:"log_level" conf:"default:debug"`
 	EnableSchemaCaching bool          `toml:"enable_schema_caching" conf:"default:true"`
 	EnableAllowAllCORS  bool          `toml:"enable_allow_all_cors" conf:"default:false"`
+	// EnableDIDWebHosting serves the documents of the did:web DIDs stored in the service at the paths the did:web
+	// spec resolves them from, e.g. `/.well-known/did.json`, for the host the request was made to.
+	EnableDIDWebHosting bool `toml:"enable_did_web_hosting" conf:"default:false"`
+	// DIDWebHostingDomains are the hosts, including any port, that did:web documents are served for. Requests made to
+	// any other host are not served. Required when EnableDIDWebHosting is set.
+	DIDWebHostingDomains []string `toml:"did_web_hosting_domains"`
+	// DIDWebCacheMaxAge is how long clients may cache a hosted did:web document.
+	DIDWebCacheMaxAge time.Duration `toml:"did_web_cache_max_age" conf:"default:5m"`
 }
 
 // ServicesConfig represents configurable properties for the components of the SSI Service
@@ -83,6 +91,10 @@ type ServicesConfig struct {
 
 type KeyStoreServiceConfig struct {
 	EncryptionConfig
+
+	// RemoteSignerURL is the base URL of an HTTP remote signer that holds private keys outside the service's storage.
+	// When set, keys can be generated with the "remote" backend.
+	RemoteSignerURL string `toml:"remote_signer_url"`
 }
 
 type EncryptionConfig struct {
@@ -135,8 +147,32 @@ type DIDServiceConfig struct {
 	UniversalResolverURL     string   `toml:"universal_resolver_url"`
 	UniversalResolverMethods []string `toml:"universal_resolver_methods"`
 	IONResolverURL           string   `toml:"ion_resolver_url"`
+	// IONOperationPollInterval is how often ION operations are resubmitted when the ION node was unavailable for
+	// them, and checked for having been published once anchored. Zero disables it.
+	IONOperationPollInterval time.Duration `toml:"ion_operation_poll_interval" conf:"default:30s"`
+	// IONRetryBackoff is how long to wait before resubmitting an ION operation the ION node was unavailable for. It's
+	// doubled after each attempt, up to IONMaxRetryBackoff. Defaults to 10s.
+	IONRetryBackoff time.Duration `toml:"ion_retry_backoff" conf:"default:10s"`
+	// IONMaxRetryBackoff bounds the wait between attempts to submit an ION operation. Defaults to 10m.
+	IONMaxRetryBackoff time.Duration `toml:"ion_max_retry_backoff" conf:"default:10m"`
 	// BatchCreateMaxItems set's the maximum amount that can be.
 	BatchCreateMaxItems int `toml:"batch_create_max_items" conf:"default:100"`
+	// ResolutionCacheTTL is how long resolved DIDs are cached for, unless their method has its own TTL in
+	// ResolutionCacheMethodTTLs. Zero, the default, disables the cache. Only the instance of the service that changes
+	// a DID invalidates its cached resolution, so with the memory backend, other instances may resolve the earlier
+	// document until the TTL passes.
+	ResolutionCacheTTL time.Duration `toml:"resolution_cache_ttl"`
+	// ResolutionCacheMethodTTLs overrides ResolutionCacheTTL for DIDs of the given methods, e.g. `{ ion = "1m" }`. A
+	// zero TTL keeps the method's DIDs out of the cache.
+	ResolutionCacheMethodTTLs map[string]time.Duration `toml:"resolution_cache_method_ttls"`
+	// ResolutionCacheNegativeTTL is how long DIDs that could not be resolved are cached as such. Zero disables
+	// negative caching.
+	ResolutionCacheNegativeTTL time.Duration `toml:"resolution_cache_negative_ttl" conf:"default:30s"`
+	// ResolutionCacheMaxEntries bounds the number of cached DIDs. Defaults to 1000.
+	ResolutionCacheMaxEntries int `toml:"resolution_cache_max_entries" conf:"default:1000"`
+	// ResolutionCacheBackend is where resolved DIDs are cached: "memory", or "storage" to share the cache between
+	// instances of the service through its storage. Defaults to "memory".
+	ResolutionCacheBackend string `toml:"resolution_cache_backend" conf:"default:memory"`
 }
 
 func (d *DIDServiceConfig) IsEmpty() bool {
@@ -151,6 +187,22 @@ type CredentialServiceConfig struct {
 	BatchCreateMaxItems int `toml:"batch_create_max_items" conf:"default:100"`
 	// BatchUpdateStatusMaxItems set's the maximum amount of credentials statuses that can be updated in a single request.
 	BatchUpdateStatusMaxItems int `toml:"batch_update_status_max_items" conf:"default:100"`
+	// StatusListFormat is the kind of status list used for credentials that don't request one. Either "StatusList2021"
+	// or "BitstringStatusList". Defaults to "StatusList2021".
+	StatusListFormat string `toml:"status_list_format"`
+	// StatusListSize is the number of credentials a status list credential holds. A new status list credential is
+	// created once every index of the current one is in use. At most, and by default, 131072.
+	StatusListSize int `toml:"status_list_size"`
+	// StatusListValidity is how long a status list credential is valid after it is signed. When set, status list
+	// credentials get an `expirationDate`, and are re-signed in the background before they expire. When not set,
+	// status list credentials don't expire.
+	StatusListValidity time.Duration `toml:"status_list_validity"`
+	// StatusListRefreshInterval is how often status list credentials close to expiring are re-signed. At most half of
+	// StatusListValidity. Defaults to a quarter of StatusListValidity.
+	StatusListRefreshInterval time.Duration `toml:"status_list_refresh_interval"`
+	// ExpirySweepInterval is how often stored credentials are checked for having passed their `expirationDate`.
+	// Expired credentials are marked as such, and an `Expire` webhook is published for each of them. Zero disables it.
+	ExpirySweepInterval time.Duration `toml:"expiry_sweep_interval" conf:"default:1h"`
 
 	// TODO(gabe) supported key and signature types
 }
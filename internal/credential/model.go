@@ -32,11 +32,21 @@ type Container struct {
 	// `fullyQualifiedVerificationMethodId`.
 	CredentialJWT *keyaccess.JWT `json:"credentialJwt,omitempty"`
 
+	// SD-JWT representation of `credential`, with a disclosure for each of its selectively disclosable claims. The
+	// issuer-signed JWT only contains digests of those claims, while `credential` has all of them.
+	SDJWT *keyaccess.SDJWT `json:"sdJwt,omitempty"`
+
 	// Whether this credential is currently revoked.
 	Revoked bool `json:"revoked,omitempty"`
 
 	// Whether this credential is currently suspended.
 	Suspended bool `json:"suspended,omitempty"`
+
+	// The current value of the credential's multi-bit status, for credentials with a `message` status.
+	StatusValue int `json:"statusValue,omitempty"`
+
+	// Whether this credential has passed its `expirationDate`.
+	Expired bool `json:"expired,omitempty"`
 }
 
 func (c Container) JWTString() string {
@@ -44,11 +54,11 @@ func (c Container) JWTString() string {
 }
 
 func (c Container) IsValid() bool {
-	return c.Credential != nil && c.Credential.ID != "" && (c.HasDataIntegrityCredential() || c.HasJWTCredential())
+	return c.Credential != nil && c.Credential.ID != "" && c.HasSignedCredential()
 }
 
 func (c Container) HasSignedCredential() bool {
-	return c.HasDataIntegrityCredential() || c.HasJWTCredential()
+	return c.HasDataIntegrityCredential() || c.HasJWTCredential() || c.HasSDJWTCredential()
 }
 
 func (c Container) HasDataIntegrityCredential() bool {
@@ -59,6 +69,10 @@ func (c Container) HasJWTCredential() bool {
 	return c.CredentialJWT != nil
 }
 
+func (c Container) HasSDJWTCredential() bool {
+	return c.SDJWT != nil
+}
+
 // NewCredentialContainerFromJWT attempts to parse a VC-JWT credential from a string into a Container
 func NewCredentialContainerFromJWT(credentialJWT string) (*Container, error) {
 	_, _, cred, err := parsing.ToCredential(credentialJWT)
@@ -71,6 +85,19 @@ func NewCredentialContainerFromJWT(credentialJWT string) (*Container, error) {
 	}, nil
 }
 
+// NewCredentialContainerFromSDJWT attempts to parse an SD-JWT credential from a string into a Container. The
+// container's credential has the claims disclosed by the SD-JWT.
+func NewCredentialContainerFromSDJWT(sdJWT string) (*Container, error) {
+	cred, err := keyaccess.SDJWT(sdJWT).DisclosedCredential()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse credential from SD-JWT")
+	}
+	return &Container{
+		Credential: cred,
+		SDJWT:      keyaccess.SDJWT(sdJWT).Ptr(),
+	}, nil
+}
+
 // NewCredentialContainerFromMap attempts to parse a data integrity credential from a piece of JSON,
 // which is represented as a map in go, into a Container
 func NewCredentialContainerFromMap(credMap map[string]any) (*Container, error) {
@@ -94,18 +121,28 @@ func ContainersToInterface(cs []Container) []any {
 			credentials = append(credentials, *container.Credential)
 		} else if container.HasJWTCredential() {
 			credentials = append(credentials, *container.CredentialJWT)
+		} else if container.HasSDJWTCredential() {
+			credentials = append(credentials, *container.SDJWT)
 		}
 	}
 	return credentials
 }
 
-// NewCredentialContainerFromArray attempts to parse arrays of credentials of any type (either data integrity or JWT)
+// NewCredentialContainerFromArray attempts to parse arrays of credentials of any type (data integrity, JWT or SD-JWT)
 // into an array of CredentialContainers. The method will return an error if any of the credentials are invalid.
 func NewCredentialContainerFromArray(creds []any) ([]Container, error) {
 	var containers []Container
 	for _, c := range creds {
 		switch v := c.(type) {
 		case string:
+			if keyaccess.IsSDJWT(v) {
+				container, err := NewCredentialContainerFromSDJWT(v)
+				if err != nil {
+					return nil, errors.Wrap(err, "could not parse credential from SD-JWT")
+				}
+				containers = append(containers, *container)
+				continue
+			}
 			// JWT
 			container, err := NewCredentialContainerFromJWT(v)
 			if err != nil {
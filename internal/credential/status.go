@@ -0,0 +1,317 @@
+package credential
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	"github.com/TBD54566975/ssi-sdk/credential"
+	statussdk "github.com/TBD54566975/ssi-sdk/credential/status"
+	"github.com/TBD54566975/ssi-sdk/util"
+	"github.com/goccy/go-json"
+	"github.com/pkg/errors"
+
+	"github.com/tbd54566975/ssi-service/internal/keyaccess"
+)
+
+// Bitstring Status List https://www.w3.org/TR/vc-bitstring-status-list/
+const (
+	BitstringStatusListEntryType      string = "BitstringStatusListEntry"
+	BitstringStatusListCredentialType string = "BitstringStatusListCredential"
+	BitstringStatusListType           string = "BitstringStatusList"
+
+	// VerifiableCredentialsV2Context defines the Bitstring Status List terms.
+	VerifiableCredentialsV2Context string = "https://www.w3.org/ns/credentials/v2"
+
+	// StatusMessage is the purpose of a status list whose entries hold multi-bit values, which are explained by the
+	// entry's statusMessage.
+	StatusMessage statussdk.StatusPurpose = "message"
+
+	// multibase prefix for unpadded base64url
+	base64URLMultibasePrefix = "u"
+
+	// the uncompressed bitstring is at least 16KB
+	minBitstringBits = 8 * 1024 * 16
+
+	// maxStatusSize bounds the statusMessage mapping to 256 values
+	maxStatusSize = 8
+
+	// maxBitstringSize bounds the uncompressed size of the status lists that are read, so that a small compressed list
+	// can't expand to exhaust memory. It allows 16 times the entries of the largest lists this service issues.
+	maxBitstringSize = 16 * minBitstringBits * maxStatusSize / 8
+)
+
+// StatusEntryContext returns the JSON-LD context defining the terms of a credential status entry, which credentials
+// with the entry must include.
+func StatusEntryContext(entry any) string {
+	if _, ok := ToBitstringStatusListEntry(entry); ok {
+		return keyaccess.BitstringStatusListContext
+	}
+	return statussdk.StatusList2021Context
+}
+
+// StatusMessageEntry maps a status value, as a hex string such as `0x1`, to a message.
+type StatusMessageEntry struct {
+	Status  string `json:"status" validate:"required"`
+	Message string `json:"message" validate:"required"`
+}
+
+// BitstringStatusListEntry is the credentialStatus of a credential associated with a Bitstring Status List.
+type BitstringStatusListEntry struct {
+	ID                   string                  `json:"id" validate:"required"`
+	Type                 string                  `json:"type" validate:"required"`
+	StatusPurpose        statussdk.StatusPurpose `json:"statusPurpose" validate:"required"`
+	StatusListIndex      string                  `json:"statusListIndex" validate:"required"`
+	StatusListCredential string                  `json:"statusListCredential" validate:"required"`
+	// The number of bits per status. Defaults to 1, and is greater than 1 only for the message purpose.
+	StatusSize    int                  `json:"statusSize,omitempty"`
+	StatusMessage []StatusMessageEntry `json:"statusMessage,omitempty"`
+}
+
+// GetStatusSize returns the number of bits per status, which defaults to 1.
+func (e BitstringStatusListEntry) GetStatusSize() int {
+	if e.StatusSize == 0 {
+		return 1
+	}
+	return e.StatusSize
+}
+
+// BitstringStatusList is the credential subject of a Bitstring Status List credential.
+type BitstringStatusList struct {
+	ID            string                  `json:"id" validate:"required"`
+	Type          string                  `json:"type" validate:"required"`
+	StatusPurpose statussdk.StatusPurpose `json:"statusPurpose" validate:"required"`
+	EncodedList   string                  `json:"encodedList" validate:"required"`
+}
+
+// StatusSizeForMessages validates a statusMessage mapping and returns the status size it needs. There must be one
+// message for each of the 2^statusSize values, in order, starting with `0x0`.
+func StatusSizeForMessages(messages []StatusMessageEntry) (int, error) {
+	n := len(messages)
+	if n < 2 || n&(n-1) != 0 {
+		return 0, fmt.Errorf("status message count must be a power of two greater than one, got %d", n)
+	}
+	statusSize := bits.TrailingZeros(uint(n))
+	if statusSize > maxStatusSize {
+		return 0, fmt.Errorf("status messages cannot have more than %d values", 1<<maxStatusSize)
+	}
+	for i, m := range messages {
+		if err := util.IsValidStruct(m); err != nil {
+			return 0, errors.Wrapf(err, "invalid status message<%d>", i)
+		}
+		if m.Status != FormatStatusValue(i) {
+			return 0, fmt.Errorf("status message<%d> must have status<%s>, got<%s>", i, FormatStatusValue(i), m.Status)
+		}
+	}
+	return statusSize, nil
+}
+
+// FormatStatusValue formats a status value the way statusMessage does.
+func FormatStatusValue(value int) string {
+	return fmt.Sprintf("0x%x", value)
+}
+
+// GenerateBitstringStatusListCredential generates a Bitstring Status List credential given an ID (the URI where this
+// entity will be hosted), the issuer DID, the purpose of the list, the number of bits per status, and the status value
+// of each index that is not zero.
+func GenerateBitstringStatusListCredential(id, issuer string, purpose statussdk.StatusPurpose, statusSize int, statuses map[int]int) (*credential.VerifiableCredential, error) {
+	encodedList, err := EncodeBitstring(statusSize, statuses)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not generate bitstring for status list credential")
+	}
+	statusList, err := util.ToJSONMap(BitstringStatusList{
+		ID:            id,
+		Type:          BitstringStatusListType,
+		StatusPurpose: purpose,
+		EncodedList:   encodedList,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not turn status list to JSON")
+	}
+	statusListCredential := credential.VerifiableCredential{
+		Context:           []any{VerifiableCredentialsV2Context},
+		ID:                id,
+		Type:              []any{credential.VerifiableCredentialType, BitstringStatusListCredentialType},
+		Issuer:            issuer,
+		IssuanceDate:      util.GetRFC3339Timestamp(),
+		CredentialSubject: statusList,
+	}
+	if err = statusListCredential.IsValid(); err != nil {
+		return nil, errors.Wrap(err, "could not build status list credential")
+	}
+	return &statusListCredential, nil
+}
+
+// EncodeBitstring sets the status value of each index, where index i occupies the statusSize bits starting at bit
+// i*statusSize, and the first bit is the most significant bit of the first byte. The bitstring is GZIP compressed,
+// and encoded as a multibase base64url string.
+func EncodeBitstring(statusSize int, statuses map[int]int) (string, error) {
+	if statusSize < 1 || statusSize > maxStatusSize {
+		return "", fmt.Errorf("invalid status size: %d", statusSize)
+	}
+	bitstring := make([]byte, minBitstringBits*statusSize/8)
+	for index, value := range statuses {
+		if index < 0 || (index+1)*statusSize > len(bitstring)*8 {
+			return "", fmt.Errorf("status list index out of range: %d", index)
+		}
+		if value < 0 || value >= 1<<statusSize {
+			return "", fmt.Errorf("status value<%d> of index<%d> does not fit in %d bit(s)", value, index, statusSize)
+		}
+		for b := 0; b < statusSize; b++ {
+			if value&(1<<(statusSize-1-b)) != 0 {
+				pos := index*statusSize + b
+				bitstring[pos/8] |= 1 << (7 - pos%8)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(bitstring); err != nil {
+		return "", errors.Wrap(err, "could not compress status list bitstring using GZIP")
+	}
+	if err := zw.Close(); err != nil {
+		return "", errors.Wrap(err, "could not close gzip writer")
+	}
+	return base64URLMultibasePrefix + base64.RawURLEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// DecodeBitstringStatus returns the status value of an index in an encoded bitstring.
+func DecodeBitstringStatus(encodedList string, statusSize, index int) (int, error) {
+	if statusSize < 1 || statusSize > maxStatusSize {
+		return 0, fmt.Errorf("invalid status size: %d", statusSize)
+	}
+	if !strings.HasPrefix(encodedList, base64URLMultibasePrefix) {
+		return 0, errors.New("encoded list is not a multibase base64url string")
+	}
+	compressed, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(encodedList, base64URLMultibasePrefix))
+	if err != nil {
+		return 0, errors.Wrap(err, "could not decode encoded list")
+	}
+	bitstring, err := expandBitstring(compressed)
+	if err != nil {
+		return 0, err
+	}
+
+	if index < 0 || (index+1)*statusSize > len(bitstring)*8 {
+		return 0, fmt.Errorf("status list index out of range: %d", index)
+	}
+	value := 0
+	for b := 0; b < statusSize; b++ {
+		pos := index*statusSize + b
+		value <<= 1
+		if bitstring[pos/8]&(1<<(7-pos%8)) != 0 {
+			value |= 1
+		}
+	}
+	return value, nil
+}
+
+// CheckStatusList2021Size checks that the bitstring of a StatusList2021 credential isn't larger than the status lists
+// that are read, before it's expanded by ValidateCredentialInStatusList, which doesn't bound its size.
+func CheckStatusList2021Size(statusCredential credential.VerifiableCredential) error {
+	encodedList, _ := statusCredential.CredentialSubject["encodedList"].(string)
+	compressed, err := base64.StdEncoding.DecodeString(encodedList)
+	if err != nil {
+		return errors.Wrapf(err, "could not decode encoded list of status credential<%s>", statusCredential.ID)
+	}
+	if _, err = expandBitstring(compressed); err != nil {
+		return errors.Wrapf(err, "could not expand bitstring of status credential<%s>", statusCredential.ID)
+	}
+	return nil
+}
+
+// expandBitstring decompresses a GZIP compressed bitstring, failing when it's larger than maxBitstringSize.
+func expandBitstring(compressed []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not unzip status list bitstring using GZIP")
+	}
+	bitstring, err := io.ReadAll(io.LimitReader(zr, maxBitstringSize+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not expand status list bitstring using GZIP")
+	}
+	if len(bitstring) > maxBitstringSize {
+		return nil, fmt.Errorf("status list bitstring is larger than %d bytes", maxBitstringSize)
+	}
+	if err = zr.Close(); err != nil {
+		return nil, errors.Wrap(err, "could not close gzip reader")
+	}
+	return bitstring, nil
+}
+
+// ToBitstringStatusListEntry returns the credential status as a Bitstring Status List entry, if it is one.
+func ToBitstringStatusListEntry(credentialStatus any) (*BitstringStatusListEntry, bool) {
+	statusBytes, err := json.Marshal(credentialStatus)
+	if err != nil {
+		return nil, false
+	}
+	var entry BitstringStatusListEntry
+	if err = json.Unmarshal(statusBytes, &entry); err != nil {
+		return nil, false
+	}
+	if entry.Type != BitstringStatusListEntryType || util.IsValidStruct(entry) != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+// bitstringStatusListEntryForList returns the credential's entry in a status list. A credential can have an array of
+// entries, each in a different status list.
+func bitstringStatusListEntryForList(credentialStatus any, statusListCredential string) (*BitstringStatusListEntry, bool) {
+	entries, ok := credentialStatus.([]any)
+	if !ok {
+		return ToBitstringStatusListEntry(credentialStatus)
+	}
+	for _, e := range entries {
+		if entry, ok := ToBitstringStatusListEntry(e); ok && entry.StatusListCredential == statusListCredential {
+			return entry, true
+		}
+	}
+	return nil, false
+}
+
+// GetBitstringStatus returns the status value of the credential in the Bitstring Status List credential, and the
+// message for it when the credential's entry has a statusMessage mapping.
+// NOTE: this method does not verify the proofs of either credential.
+func GetBitstringStatus(cred, statusCredential credential.VerifiableCredential) (int, string, error) {
+	entry, ok := bitstringStatusListEntryForList(cred.CredentialStatus, statusCredential.ID)
+	if !ok {
+		return 0, "", fmt.Errorf("credential<%s> not using the BitstringStatusListEntry credentialStatus property", cred.ID)
+	}
+
+	var statusList BitstringStatusList
+	subjectBytes, err := json.Marshal(statusCredential.CredentialSubject)
+	if err != nil {
+		return 0, "", errors.Wrapf(err, "could not marshal status credential<%s> subject value", statusCredential.ID)
+	}
+	if err = json.Unmarshal(subjectBytes, &statusList); err != nil {
+		return 0, "", errors.Wrapf(err, "could not unmarshal status credential<%s> subject value", statusCredential.ID)
+	}
+	if err = util.IsValidStruct(statusList); err != nil {
+		return 0, "", errors.Wrapf(err, "credential<%s> is not a valid status credential", statusCredential.ID)
+	}
+	if entry.StatusPurpose != statusList.StatusPurpose {
+		return 0, "", fmt.Errorf("purpose of credential<%s>: %s, did not match purpose of status credential<%s>: %s",
+			cred.ID, entry.StatusPurpose, statusCredential.ID, statusList.StatusPurpose)
+	}
+
+	index, err := strconv.Atoi(entry.StatusListIndex)
+	if err != nil {
+		return 0, "", errors.Wrapf(err, "invalid status list index: %s", entry.StatusListIndex)
+	}
+	value, err := DecodeBitstringStatus(statusList.EncodedList, entry.GetStatusSize(), index)
+	if err != nil {
+		return 0, "", errors.Wrapf(err, "could not expand bitstring of status credential<%s>", statusCredential.ID)
+	}
+	var message string
+	if value < len(entry.StatusMessage) {
+		message = entry.StatusMessage[value].Message
+	}
+	return value, message, nil
+}
@@ -0,0 +1,145 @@
+package credential
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"testing"
+
+	"github.com/TBD54566975/ssi-sdk/credential"
+	statussdk "github.com/TBD54566975/ssi-sdk/credential/status"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeBitstring(t *testing.T) {
+	t.Run("single bit", func(t *testing.T) {
+		encoded, err := EncodeBitstring(1, map[int]int{0: 1, 94567: 1})
+		assert.NoError(t, err)
+		assert.True(t, len(encoded) > 0 && encoded[0] == 'u')
+
+		for index, want := range map[int]int{0: 1, 1: 0, 94566: 0, 94567: 1, 94568: 0} {
+			value, err := DecodeBitstringStatus(encoded, 1, index)
+			assert.NoError(t, err)
+			assert.Equal(t, want, value, "index %d", index)
+		}
+	})
+
+	t.Run("multi bit", func(t *testing.T) {
+		encoded, err := EncodeBitstring(2, map[int]int{0: 3, 1: 1, 5: 2})
+		assert.NoError(t, err)
+
+		for index, want := range map[int]int{0: 3, 1: 1, 2: 0, 5: 2} {
+			value, err := DecodeBitstringStatus(encoded, 2, index)
+			assert.NoError(t, err)
+			assert.Equal(t, want, value, "index %d", index)
+		}
+	})
+
+	t.Run("bad input", func(t *testing.T) {
+		_, err := EncodeBitstring(2, map[int]int{0: 4})
+		assert.ErrorContains(t, err, "does not fit")
+		_, err = EncodeBitstring(1, map[int]int{minBitstringBits: 1})
+		assert.ErrorContains(t, err, "out of range")
+		_, err = EncodeBitstring(maxStatusSize+1, nil)
+		assert.ErrorContains(t, err, "invalid status size")
+		_, err = DecodeBitstringStatus("not multibase", 1, 0)
+		assert.Error(t, err)
+	})
+
+	t.Run("oversized bitstring", func(t *testing.T) {
+		compressed := compressedZeros(t, maxBitstringSize+1)
+		_, err := DecodeBitstringStatus(base64URLMultibasePrefix+base64.RawURLEncoding.EncodeToString(compressed), 1, 0)
+		assert.ErrorContains(t, err, "status list bitstring is larger than")
+
+		statusCred := credential.VerifiableCredential{
+			ID:                "https://example.com/status/1",
+			CredentialSubject: map[string]any{"encodedList": base64.StdEncoding.EncodeToString(compressed)},
+		}
+		assert.ErrorContains(t, CheckStatusList2021Size(statusCred), "status list bitstring is larger than")
+
+		statusCred.CredentialSubject["encodedList"] = base64.StdEncoding.EncodeToString(compressedZeros(t, maxBitstringSize))
+		assert.NoError(t, CheckStatusList2021Size(statusCred))
+	})
+}
+
+// compressedZeros returns size zero bytes compressed with GZIP, which compress to a small fraction of their size.
+func compressedZeros(t *testing.T, size int) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(make([]byte, size))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestStatusSizeForMessages(t *testing.T) {
+	messages := func(n int) []StatusMessageEntry {
+		var entries []StatusMessageEntry
+		for i := 0; i < n; i++ {
+			entries = append(entries, StatusMessageEntry{Status: FormatStatusValue(i), Message: "message"})
+		}
+		return entries
+	}
+
+	statusSize, err := StatusSizeForMessages(messages(2))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, statusSize)
+
+	statusSize, err = StatusSizeForMessages(messages(16))
+	assert.NoError(t, err)
+	assert.Equal(t, 4, statusSize)
+	assert.Equal(t, "0xf", messages(16)[15].Status)
+
+	_, err = StatusSizeForMessages(messages(3))
+	assert.ErrorContains(t, err, "power of two")
+	_, err = StatusSizeForMessages(messages(1))
+	assert.ErrorContains(t, err, "power of two")
+
+	outOfOrder := messages(2)
+	outOfOrder[0].Status, outOfOrder[1].Status = outOfOrder[1].Status, outOfOrder[0].Status
+	_, err = StatusSizeForMessages(outOfOrder)
+	assert.ErrorContains(t, err, "must have status<0x0>")
+}
+
+func TestGetBitstringStatus(t *testing.T) {
+	statusCred, err := GenerateBitstringStatusListCredential("https://example.com/status/1", "did:example:issuer", StatusMessage, 2, map[int]int{7: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []any{VerifiableCredentialsV2Context}, statusCred.Context)
+
+	cred := credential.VerifiableCredential{
+		ID: "https://example.com/credentials/1",
+		CredentialStatus: BitstringStatusListEntry{
+			ID:                   "https://example.com/credentials/1/status",
+			Type:                 BitstringStatusListEntryType,
+			StatusPurpose:        StatusMessage,
+			StatusListIndex:      "7",
+			StatusListCredential: statusCred.ID,
+			StatusSize:           2,
+			StatusMessage: []StatusMessageEntry{
+				{Status: "0x0", Message: "pending_review"},
+				{Status: "0x1", Message: "accepted"},
+				{Status: "0x2", Message: "rejected"},
+				{Status: "0x3", Message: "undefined"},
+			},
+		},
+	}
+	value, message, err := GetBitstringStatus(cred, *statusCred)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, value)
+	assert.Equal(t, "accepted", message)
+
+	revocationCred, err := GenerateBitstringStatusListCredential("https://example.com/status/2", "did:example:issuer", statussdk.StatusRevocation, 1, nil)
+	require.NoError(t, err)
+	_, _, err = GetBitstringStatus(cred, *revocationCred)
+	assert.ErrorContains(t, err, "did not match purpose")
+
+	_, ok := ToBitstringStatusListEntry(statussdk.StatusList2021Entry{
+		ID:                   "https://example.com/credentials/1/status",
+		Type:                 statussdk.StatusList2021EntryType,
+		StatusPurpose:        statussdk.StatusRevocation,
+		StatusListIndex:      "7",
+		StatusListCredential: statusCred.ID,
+	})
+	assert.False(t, ok)
+}
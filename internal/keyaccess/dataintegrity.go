@@ -2,6 +2,7 @@ package keyaccess
 
 import (
 	gocrypto "crypto"
+	"fmt"
 
 	"github.com/TBD54566975/ssi-sdk/credential"
 	"github.com/TBD54566975/ssi-sdk/crypto/jwx"
@@ -11,42 +12,102 @@ import (
 	"github.com/pkg/errors"
 )
 
-// TODO(gabe) integrate signing for Data Integrity as well in https://github.com/TBD54566975/ssi-service/issues/105
-
-// DataIntegrityKeyAccess represents a key access object for data integrity using the JsonWebSignature2020 suite:
-// https://w3c.github.io/vc-jws-2020/
+// DataIntegrityKeyAccess represents a key access object for documents secured with an embedded proof. The
+// JsonWebSignature2020 (https://w3c.github.io/vc-jws-2020/), Ed25519Signature2020 and eddsa-2022
+// (https://w3c.github.io/vc-di-eddsa/) proof types are supported.
 type DataIntegrityKeyAccess struct {
-	Signer      jws2020.JSONWebKeySigner
-	Verifier    jws2020.JSONWebKeyVerifier
+	Signer      cryptosuite.Signer
+	Verifier    cryptosuite.Verifier
 	CryptoSuite cryptosuite.CryptoSuite
 }
 
 // NewDataIntegrityKeyAccess creates a new DataIntegrityKeyAccess object from an id, key id, and private key, generating both
-// JSON Web Key Signer and Verifier objects.
+// JSON Web Key Signer and Verifier objects for the JsonWebSignature2020 suite.
 func NewDataIntegrityKeyAccess(id, kid string, key gocrypto.PrivateKey) (*DataIntegrityKeyAccess, error) {
+	return NewDataIntegrityKeyAccessWithProofType(id, kid, key, JSONWebSignature2020)
+}
+
+// NewDataIntegrityKeyAccessWithProofType creates a new DataIntegrityKeyAccess object producing proofs of the given type.
+// The kid is set as the proof's verification method. Ed25519Signature2020 and eddsa-2022 require an Ed25519 key.
+func NewDataIntegrityKeyAccessWithProofType(id, kid string, key gocrypto.PrivateKey, proofType DataIntegrityProofType) (*DataIntegrityKeyAccess, error) {
 	if kid == "" {
 		return nil, errors.New("kid cannot be empty")
 	}
 	if key == nil {
 		return nil, errors.New("key cannot be nil")
 	}
-	publicKeyJWK, privateKeyJWK, err := jwx.PrivateKeyToPrivateKeyJWK(kid, key)
-	if err != nil {
-		return nil, errors.Wrapf(err, "could not convert private key to JWK: %s", kid)
+	switch proofType {
+	case JSONWebSignature2020:
+		jwxSigner, err := newJWXSigner(id, kid, key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not create JWK signer: %s", kid)
+		}
+		jwxVerifier, err := jwxSigner.ToVerifier(id)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not create JWK verifier: %s", kid)
+		}
+		signer := &jws2020.JSONWebKeySigner{Signer: *jwxSigner}
+		signer.SetProofPurpose(cryptosuite.AssertionMethod)
+		return &DataIntegrityKeyAccess{
+			Signer:      signer,
+			Verifier:    &jws2020.JSONWebKeyVerifier{Verifier: *jwxVerifier},
+			CryptoSuite: dataIntegritySuite{proofType: proofType},
+		}, nil
+	case Ed25519Signature2020, EdDSA2022:
+		cryptoSigner, ok := key.(gocrypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("proof type<%s> requires an Ed25519 key", proofType)
+		}
+		publicKey, ok := toEd25519PublicKey(cryptoSigner.Public())
+		if !ok {
+			return nil, fmt.Errorf("proof type<%s> requires an Ed25519 key", proofType)
+		}
+		suite := dataIntegritySuite{proofType: proofType}
+		return &DataIntegrityKeyAccess{
+			Signer: &ed25519Signer{
+				kid:       kid,
+				signer:    cryptoSigner,
+				signature: suite.SignatureAlgorithm(),
+				purpose:   cryptosuite.AssertionMethod,
+			},
+			Verifier:    ed25519Verifier{kid: kid, publicKey: publicKey},
+			CryptoSuite: suite,
+		}, nil
 	}
-	signer, err := jws2020.NewJSONWebKeySigner(id, *privateKeyJWK, cryptosuite.AssertionMethod)
-	if err != nil {
-		return nil, errors.Wrapf(err, "could not create JWK signer: %s", kid)
+	return nil, fmt.Errorf("unsupported proof type: %s", proofType)
+}
+
+// NewDataIntegrityKeyAccessVerifier creates a DataIntegrityKeyAccess object from an id, key id, and public key, which
+// is only able to verify proofs of the given type.
+func NewDataIntegrityKeyAccessVerifier(id, kid string, key gocrypto.PublicKey, proofType DataIntegrityProofType) (*DataIntegrityKeyAccess, error) {
+	if kid == "" {
+		return nil, errors.New("kid cannot be empty")
 	}
-	verifier, err := jws2020.NewJSONWebKeyVerifier(id, *publicKeyJWK)
-	if err != nil {
-		return nil, errors.Wrapf(err, "could not create JWK verifier: %s", kid)
+	if key == nil {
+		return nil, errors.New("key cannot be nil")
 	}
-	return &DataIntegrityKeyAccess{
-		Signer:      *signer,
-		Verifier:    *verifier,
-		CryptoSuite: jws2020.GetJSONWebSignature2020Suite(),
-	}, nil
+	switch proofType {
+	case JSONWebSignature2020:
+		publicKeyJWK, err := jwx.PublicKeyToPublicKeyJWK(kid, key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not convert public key to JWK: %s", kid)
+		}
+		verifier, err := jws2020.NewJSONWebKeyVerifier(id, *publicKeyJWK)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not create JWK verifier: %s", kid)
+		}
+		return &DataIntegrityKeyAccess{Verifier: verifier, CryptoSuite: dataIntegritySuite{proofType: proofType}}, nil
+	case Ed25519Signature2020, EdDSA2022:
+		publicKey, ok := toEd25519PublicKey(key)
+		if !ok {
+			return nil, fmt.Errorf("proof type<%s> requires an Ed25519 key", proofType)
+		}
+		return &DataIntegrityKeyAccess{
+			Verifier:    ed25519Verifier{kid: kid, publicKey: publicKey},
+			CryptoSuite: dataIntegritySuite{proofType: proofType},
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported proof type: %s", proofType)
 }
 
 // DataIntegrityJSON represents a response from a DataIntegrityKeyAccess.Sign() call represented
@@ -56,10 +117,13 @@ type DataIntegrityJSON struct {
 }
 
 func (ka DataIntegrityKeyAccess) Sign(payload cryptosuite.WithEmbeddedProof) (*DataIntegrityJSON, error) {
+	if ka.Signer == nil {
+		return nil, errors.New("cannot sign with nil signer")
+	}
 	if payload == nil {
 		return nil, errors.New("payload cannot be nil")
 	}
-	if err := ka.CryptoSuite.Sign(&ka.Signer, payload); err != nil {
+	if err := ka.CryptoSuite.Sign(ka.Signer, payload); err != nil {
 		return nil, errors.Wrap(err, "could not sign payload")
 	}
 	signedJSONBytes, err := json.Marshal(payload)
@@ -70,10 +134,13 @@ func (ka DataIntegrityKeyAccess) Sign(payload cryptosuite.WithEmbeddedProof) (*D
 }
 
 func (ka DataIntegrityKeyAccess) Verify(payload cryptosuite.WithEmbeddedProof) error {
+	if ka.Verifier == nil {
+		return errors.New("cannot verify with nil verifier")
+	}
 	if payload == nil {
 		return errors.New("payload cannot be nil")
 	}
-	if err := ka.CryptoSuite.Verify(&ka.Verifier, payload); err != nil {
+	if err := ka.CryptoSuite.Verify(ka.Verifier, payload); err != nil {
 		return errors.Wrap(err, "could not verify payload")
 	}
 	return nil
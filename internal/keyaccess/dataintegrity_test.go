@@ -1,12 +1,19 @@
 package keyaccess
 
 import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
 	"testing"
 
 	"github.com/TBD54566975/ssi-sdk/credential"
 	"github.com/TBD54566975/ssi-sdk/crypto"
+	"github.com/TBD54566975/ssi-sdk/cryptosuite/jws2020"
+	sdkutil "github.com/TBD54566975/ssi-sdk/util"
 	"github.com/goccy/go-json"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCreateDataIntegrityKeyAccess(t *testing.T) {
@@ -40,6 +47,9 @@ func TestCreateDataIntegrityKeyAccess(t *testing.T) {
 }
 
 func TestDataIntegrityKeyAccessSignVerify(t *testing.T) {
+	// contexts are never fetched, so stand in for the W3C examples context the test credential uses
+	RegisterContext("https://www.w3.org/2018/credentials/examples/v1", []byte(`{"@context": {"@vocab": "https://www.w3.org/2018/credentials/examples#"}}`))
+
 	t.Run("Sign and Verify Credential - Happy Path", func(tt *testing.T) {
 		_, privKey, err := crypto.GenerateEd25519Key()
 		id := "test-id"
@@ -123,3 +133,175 @@ func TestDataIntegrityKeyAccessSignVerify(t *testing.T) {
 		assert.Contains(t, err.Error(), "not implemented")
 	})
 }
+
+func TestDataIntegrityKeyAccessProofTypes(t *testing.T) {
+	for _, proofType := range []DataIntegrityProofType{JSONWebSignature2020, Ed25519Signature2020, EdDSA2022} {
+		t.Run(proofType.String(), func(tt *testing.T) {
+			pubKey, privKey, err := crypto.GenerateEd25519Key()
+			require.NoError(tt, err)
+			kid := "did:example:issuer#key-1"
+			ka, err := NewDataIntegrityKeyAccessWithProofType("did:example:issuer", kid, privKey, proofType)
+			require.NoError(tt, err)
+
+			// only embedded and inline contexts are used, so no network access is needed
+			testCred := getTestCredential("did:example:issuer")
+			testCred.Context = append([]any{"https://www.w3.org/2018/credentials/v1", testVocabContext}, sdkutil.ArrayStrToInterface(proofType.RequiredContexts())...)
+			signedCred, err := ka.Sign(&testCred)
+			require.NoError(tt, err)
+
+			var cred credential.VerifiableCredential
+			require.NoError(tt, json.Unmarshal(signedCred.Data, &cred))
+			require.NotNil(tt, cred.Proof)
+			gotProofType, err := DataIntegrityProofTypeFromProof(*cred.Proof)
+			assert.NoError(tt, err)
+			assert.Equal(tt, proofType, gotProofType)
+			proof := (*cred.Proof).(map[string]any)
+			assert.Equal(tt, kid, proof["verificationMethod"])
+			assert.Equal(tt, "assertionMethod", proof["proofPurpose"])
+
+			// verify with the public key only
+			verifier, err := NewDataIntegrityKeyAccessVerifier("did:example:issuer", kid, pubKey, proofType)
+			require.NoError(tt, err)
+			assert.NoError(tt, verifier.Verify(&cred))
+
+			// the proof is restored after verification, and tampering is detected
+			require.NotNil(tt, cred.Proof)
+			cred.CredentialSubject["happiness"] = "not so happy"
+			assert.Error(tt, verifier.Verify(&cred))
+
+			// a different proof type is rejected
+			other := Ed25519Signature2020
+			if proofType == Ed25519Signature2020 {
+				other = EdDSA2022
+			}
+			otherVerifier, err := NewDataIntegrityKeyAccessVerifier("did:example:issuer", kid, pubKey, other)
+			require.NoError(tt, err)
+			assert.ErrorContains(tt, otherVerifier.Verify(&cred), "expected proof of type")
+		})
+
+		t.Run(proofType.String()+" rejects terms added after signing", func(tt *testing.T) {
+			pubKey, privKey, err := crypto.GenerateEd25519Key()
+			require.NoError(tt, err)
+			kid := "did:example:issuer#key-1"
+			ka, err := NewDataIntegrityKeyAccessWithProofType("did:example:issuer", kid, privKey, proofType)
+			require.NoError(tt, err)
+
+			testCred := getTestCredential("did:example:issuer")
+			testCred.Context = append([]any{"https://www.w3.org/2018/credentials/v1", testTermsContext}, sdkutil.ArrayStrToInterface(proofType.RequiredContexts())...)
+			signedCred, err := ka.Sign(&testCred)
+			require.NoError(tt, err)
+			var cred credential.VerifiableCredential
+			require.NoError(tt, json.Unmarshal(signedCred.Data, &cred))
+
+			verifier, err := NewDataIntegrityKeyAccessVerifier("did:example:issuer", kid, pubKey, proofType)
+			require.NoError(tt, err)
+			require.NoError(tt, verifier.Verify(&cred))
+
+			// a claim the contexts don't define would be left out of the hash, so it must not verify
+			cred.CredentialSubject["isAdmin"] = true
+			assert.ErrorContains(tt, verifier.Verify(&cred), "Dropping property that did not expand into an absolute IRI")
+		})
+	}
+
+	t.Run("terms missing from the context are not signed", func(tt *testing.T) {
+		_, privKey, err := crypto.GenerateEd25519Key()
+		require.NoError(tt, err)
+		ka, err := NewDataIntegrityKeyAccessWithProofType("did:example:issuer", "did:example:issuer#key-1", privKey, EdDSA2022)
+		require.NoError(tt, err)
+
+		testCred := getTestCredential("did:example:issuer")
+		testCred.Context = []any{"https://www.w3.org/2018/credentials/v1", DataIntegrityV1Context}
+		_, err = ka.Sign(&testCred)
+		assert.ErrorContains(tt, err, "Dropping property that did not expand into an absolute IRI")
+	})
+
+	t.Run("contexts that are not embedded are not fetched", func(tt *testing.T) {
+		_, privKey, err := crypto.GenerateEd25519Key()
+		require.NoError(tt, err)
+		ka, err := NewDataIntegrityKeyAccessWithProofType("did:example:issuer", "did:example:issuer#key-1", privKey, EdDSA2022)
+		require.NoError(tt, err)
+
+		testCred := getTestCredential("did:example:issuer")
+		testCred.Context = []any{"https://www.w3.org/2018/credentials/v1", "http://169.254.169.254/context", DataIntegrityV1Context}
+		_, err = ka.Sign(&testCred)
+		assert.ErrorContains(tt, err, "unsupported context: http://169.254.169.254/context")
+	})
+
+	t.Run("EdDSA proof types require an Ed25519 key", func(tt *testing.T) {
+		_, privKey, err := crypto.GenerateP256Key()
+		require.NoError(tt, err)
+		_, err = NewDataIntegrityKeyAccessWithProofType("test-id", "test-kid", privKey, Ed25519Signature2020)
+		assert.ErrorContains(tt, err, "requires an Ed25519 key")
+	})
+
+	t.Run("unsupported proof type", func(tt *testing.T) {
+		_, privKey, err := crypto.GenerateEd25519Key()
+		require.NoError(tt, err)
+		_, err = NewDataIntegrityKeyAccessWithProofType("test-id", "test-kid", privKey, "BbsBlsSignature2020")
+		assert.ErrorContains(tt, err, "unsupported proof type")
+	})
+}
+
+// TestDataIntegrityJSONWebSignature2020Interop checks that JsonWebSignature2020 proofs are interchangeable with the ones
+// produced by the ssi-sdk suite.
+func TestDataIntegrityJSONWebSignature2020Interop(t *testing.T) {
+	// the sdk suite fetches contexts over http, so serve them from the embedded copies
+	defaultTransport := http.DefaultClient.Transport
+	http.DefaultClient.Transport = embeddedContextTransport{}
+	t.Cleanup(func() { http.DefaultClient.Transport = defaultTransport })
+
+	sdkSuite := jws2020.GetJSONWebSignature2020Suite()
+	for _, keyType := range []crypto.KeyType{crypto.Ed25519, crypto.P256, crypto.SECP256k1} {
+		t.Run(string(keyType), func(tt *testing.T) {
+			_, privKey, err := crypto.GenerateKeyByKeyType(keyType)
+			require.NoError(tt, err)
+			ka, err := NewDataIntegrityKeyAccess("did:example:issuer", "did:example:issuer#key-1", privKey)
+			require.NoError(tt, err)
+
+			// a proof from the sdk verifies
+			sdkSigned := getTestCredential("did:example:issuer")
+			sdkSigned.Context = []any{"https://www.w3.org/2018/credentials/v1", testVocabContext, jws2020.JSONWebSignature2020Context}
+			require.NoError(tt, sdkSuite.Sign(ka.Signer, &sdkSigned))
+			assert.NoError(tt, ka.Verify(&sdkSigned))
+			sdkSigned.CredentialSubject["happiness"] = "not so happy"
+			assert.Error(tt, ka.Verify(&sdkSigned))
+
+			// and the sdk verifies our proofs
+			testCred := getTestCredential("did:example:issuer")
+			testCred.Context = []any{"https://www.w3.org/2018/credentials/v1", testVocabContext, jws2020.JSONWebSignature2020Context}
+			signedCred, err := ka.Sign(&testCred)
+			require.NoError(tt, err)
+			var cred credential.VerifiableCredential
+			require.NoError(tt, json.Unmarshal(signedCred.Data, &cred))
+			assert.NoError(tt, sdkSuite.Verify(ka.Verifier, &cred))
+		})
+	}
+}
+
+// embeddedContextTransport serves the contexts embedded in the binary, failing any other request.
+type embeddedContextTransport struct{}
+
+func (embeddedContextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	contextLoader.mu.RLock()
+	content, ok := contextLoader.embedded[req.URL.String()]
+	contextLoader.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unexpected request to %s", req.URL)
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/ld+json"}},
+		Body:       io.NopCloser(bytes.NewReader(content)),
+		Request:    req,
+	}, nil
+}
+
+// testVocabContext defines every otherwise undefined term of the test credential.
+var testVocabContext = map[string]any{"@vocab": "https://example.com/vocab#"}
+
+// testTermsContext defines only the terms of the test credential, unlike testVocabContext which defines every term.
+var testTermsContext = map[string]any{
+	"HappyCredential": "https://example.com/vocab#HappyCredential",
+	"happiness":       "https://example.com/vocab#happiness",
+	"howHappy":        "https://example.com/vocab#howHappy",
+}
@@ -0,0 +1,417 @@
+package keyaccess
+
+import (
+	"bytes"
+	gocrypto "crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	_ "embed"
+	"fmt"
+	"sync"
+
+	"github.com/TBD54566975/ssi-sdk/crypto"
+	"github.com/TBD54566975/ssi-sdk/cryptosuite"
+	"github.com/TBD54566975/ssi-sdk/cryptosuite/jws2020"
+	sdkutil "github.com/TBD54566975/ssi-sdk/util"
+	"github.com/goccy/go-json"
+	ldembed "github.com/hyperledger/aries-framework-go/component/models/ld/context/embed"
+	"github.com/mr-tron/base58"
+	"github.com/piprate/json-gold/ld"
+	"github.com/pkg/errors"
+)
+
+// DataIntegrityProofType identifies the suite used to secure a document with an embedded proof.
+type DataIntegrityProofType string
+
+const (
+	// JSONWebSignature2020 secures documents with a detached JWS https://w3c.github.io/vc-jws-2020/
+	JSONWebSignature2020 DataIntegrityProofType = "JsonWebSignature2020"
+
+	// Ed25519Signature2020 secures documents with an Ed25519 signature https://w3c.github.io/vc-di-eddsa/#ed25519signature2020-0
+	Ed25519Signature2020 DataIntegrityProofType = "Ed25519Signature2020"
+
+	// EdDSA2022 secures documents with a `DataIntegrityProof` using the `eddsa-2022` cryptosuite
+	// https://w3c.github.io/vc-di-eddsa/#eddsa-2022
+	EdDSA2022 DataIntegrityProofType = "eddsa-2022"
+
+	Ed25519Signature2020Context = "https://w3id.org/security/suites/ed25519-2020/v1"
+	DataIntegrityV1Context      = "https://w3id.org/security/data-integrity/v1"
+
+	// BitstringStatusListContext defines the terms of Bitstring Status List entries for credentials of the v1 data
+	// model, which can't use the definitions of the VC v2 context alongside the v1 context.
+	BitstringStatusListContext = "https://www.w3.org/ns/credentials/status/v1"
+
+	dataIntegrityProofType = "DataIntegrityProof"
+)
+
+func (t DataIntegrityProofType) String() string {
+	return string(t)
+}
+
+// IsSupported returns whether documents can be signed and verified with the proof type.
+func (t DataIntegrityProofType) IsSupported() bool {
+	switch t {
+	case JSONWebSignature2020, Ed25519Signature2020, EdDSA2022:
+		return true
+	}
+	return false
+}
+
+// RequiredContexts returns the JSON-LD contexts defining the proof's terms, which a signed document must include.
+func (t DataIntegrityProofType) RequiredContexts() []string {
+	switch t {
+	case JSONWebSignature2020:
+		return []string{jws2020.JSONWebSignature2020Context}
+	case Ed25519Signature2020:
+		return []string{Ed25519Signature2020Context}
+	case EdDSA2022:
+		return []string{DataIntegrityV1Context}
+	}
+	return nil
+}
+
+// DataIntegrityProofTypeFromProof determines the proof type of an embedded proof from its `type` and, for
+// `DataIntegrityProof`, its `cryptosuite` property.
+func DataIntegrityProofTypeFromProof(proof crypto.Proof) (DataIntegrityProofType, error) {
+	genericProof, err := proofToMap(proof)
+	if err != nil {
+		return "", err
+	}
+	proofType, _ := genericProof["type"].(string)
+	if proofType == dataIntegrityProofType {
+		suite, _ := genericProof["cryptosuite"].(string)
+		if DataIntegrityProofType(suite) != EdDSA2022 {
+			return "", fmt.Errorf("unsupported cryptosuite: %s", suite)
+		}
+		return EdDSA2022, nil
+	}
+	if t := DataIntegrityProofType(proofType); t != EdDSA2022 && t.IsSupported() {
+		return t, nil
+	}
+	return "", fmt.Errorf("unsupported proof type: %s", proofType)
+}
+
+// dataIntegritySuite implements the supported proof types following the Data Integrity proof and verification
+// algorithms: both the proof options and the document are canonicalized with URDNA2015 and hashed with SHA-256,
+// and the signature is taken over the concatenation of the two hashes.
+type dataIntegritySuite struct {
+	proofType DataIntegrityProofType
+}
+
+var _ cryptosuite.CryptoSuite = (*dataIntegritySuite)(nil)
+
+func (s dataIntegritySuite) ID() string {
+	if s.proofType == JSONWebSignature2020 {
+		return jws2020.JWSSignatureSuiteID
+	}
+	return "https://w3id.org/security#" + string(s.SignatureAlgorithm())
+}
+
+func (s dataIntegritySuite) Type() cryptosuite.LDKeyType {
+	switch s.proofType {
+	case Ed25519Signature2020:
+		return cryptosuite.Ed25519VerificationKey2020
+	case EdDSA2022:
+		return cryptosuite.MultikeyType
+	}
+	return cryptosuite.JSONWebKey2020Type
+}
+
+func (dataIntegritySuite) CanonicalizationAlgorithm() string {
+	return jws2020.JWSSignatureSuiteCanonicalizationAlgorithm
+}
+
+func (dataIntegritySuite) MessageDigestAlgorithm() gocrypto.Hash {
+	return gocrypto.SHA256
+}
+
+func (s dataIntegritySuite) SignatureAlgorithm() cryptosuite.SignatureType {
+	if s.proofType == EdDSA2022 {
+		return dataIntegrityProofType
+	}
+	return cryptosuite.SignatureType(s.proofType)
+}
+
+func (s dataIntegritySuite) RequiredContexts() []string {
+	return s.proofType.RequiredContexts()
+}
+
+func (s dataIntegritySuite) Sign(signer cryptosuite.Signer, p cryptosuite.WithEmbeddedProof) error {
+	proof := map[string]any{
+		"type":               string(s.SignatureAlgorithm()),
+		"created":            sdkutil.GetRFC3339Timestamp(),
+		"verificationMethod": signer.GetKeyID(),
+		"proofPurpose":       string(signer.GetProofPurpose()),
+	}
+	if s.proofType == EdDSA2022 {
+		proof["cryptosuite"] = string(EdDSA2022)
+	}
+
+	tbs, err := s.createVerifyHash(p, proof)
+	if err != nil {
+		return errors.Wrap(err, "create verify hash algorithm failed")
+	}
+	signature, err := signer.Sign(tbs)
+	if err != nil {
+		return errors.Wrap(err, "could not sign provable value")
+	}
+
+	if s.proofType == JSONWebSignature2020 {
+		proof["jws"] = string(signature)
+	} else {
+		// multibase, base58-btc encoded
+		proof["proofValue"] = "z" + base58.Encode(signature)
+	}
+	genericProof := crypto.Proof(proof)
+	p.SetProof(&genericProof)
+	return nil
+}
+
+func (s dataIntegritySuite) Verify(verifier cryptosuite.Verifier, p cryptosuite.WithEmbeddedProof) error {
+	gotProof := p.GetProof()
+	if gotProof == nil {
+		return errors.New("payload has no proof")
+	}
+	proofType, err := DataIntegrityProofTypeFromProof(*gotProof)
+	if err != nil {
+		return errors.Wrap(err, "determining proof type")
+	}
+	if proofType != s.proofType {
+		return fmt.Errorf("expected proof of type<%s>, got<%s>", s.proofType, proofType)
+	}
+	proof, err := proofToMap(*gotProof)
+	if err != nil {
+		return err
+	}
+
+	// the signature is not part of the proof options
+	var signature []byte
+	if s.proofType == JSONWebSignature2020 {
+		jws, _ := proof["jws"].(string)
+		signature = []byte(jws)
+		delete(proof, "jws")
+	} else {
+		proofValue, _ := proof["proofValue"].(string)
+		if len(proofValue) < 2 || proofValue[0] != 'z' {
+			return errors.New("proofValue must be a base58-btc multibase value")
+		}
+		if signature, err = base58.Decode(proofValue[1:]); err != nil {
+			return errors.Wrap(err, "decoding proofValue")
+		}
+		delete(proof, "proofValue")
+	}
+	if len(signature) == 0 {
+		return errors.New("proof has no signature")
+	}
+
+	// remove the proof before verifying, and make sure we set it back after we're done
+	p.SetProof(nil)
+	defer p.SetProof(gotProof)
+
+	tbv, err := s.createVerifyHash(p, proof)
+	if err != nil {
+		return errors.Wrap(err, "create verify hash algorithm failed")
+	}
+	if err = verifier.Verify(tbv, signature); err != nil {
+		return errors.Wrap(err, "could not verify signature")
+	}
+	return nil
+}
+
+// createVerifyHash https://www.w3.org/community/reports/credentials/CG-FINAL-data-integrity-20220722/#create-verify-hash-algorithm
+// Documents with properties not defined by their contexts are rejected, both when signing and verifying, since those
+// properties would be dropped from the hash and could be added or changed without breaking the signature.
+func (s dataIntegritySuite) createVerifyHash(p cryptosuite.WithEmbeddedProof, proof map[string]any) ([]byte, error) {
+	contexts, err := cryptosuite.GetContextsFromProvable(p)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get contexts from provable")
+	}
+	proofOptions := make(map[string]any, len(proof)+1)
+	for k, v := range proof {
+		proofOptions[k] = v
+	}
+	proofOptions["@context"] = cryptosuite.EnsureRequiredContexts(contexts, s.RequiredContexts())
+
+	doc, err := proofToMap(p)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not marshal provable")
+	}
+
+	canonicalOptions, err := canonicalize(proofOptions)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not canonicalize proof")
+	}
+	canonicalDoc, err := canonicalize(doc)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not canonicalize provable document")
+	}
+	optionsDigest := sha256.Sum256(canonicalOptions)
+	docDigest := sha256.Sum256(canonicalDoc)
+	return append(optionsDigest[:], docDigest[:]...), nil
+}
+
+// canonicalize runs URDNA2015 over a JSON-LD document, returning N-Quads. Properties which do not expand to an IRI
+// are an error instead of being dropped.
+func canonicalize(doc map[string]any) ([]byte, error) {
+	// the processor's Normalize does not pass safe mode on when converting to RDF, so convert first
+	rdfOptions := ld.NewJsonLdOptions("")
+	rdfOptions.ProcessingMode = ld.JsonLd_1_1
+	rdfOptions.DocumentLoader = contextLoader
+	rdfOptions.SafeMode = true
+	dataset, err := ld.NewJsonLdProcessor().ToRDF(doc, rdfOptions)
+	if err != nil {
+		return nil, err
+	}
+	rdfDataset, ok := dataset.(*ld.RDFDataset)
+	if !ok {
+		return nil, errors.New("document did not convert to an RDF dataset")
+	}
+
+	options := ld.NewJsonLdOptions("")
+	options.Format = "application/n-quads"
+	options.Algorithm = ld.AlgorithmURDNA2015
+	normalized, err := ld.NewJsonLdApi().Normalize(rdfDataset, options)
+	if err != nil {
+		return nil, err
+	}
+	canonical, ok := normalized.(string)
+	if !ok {
+		return nil, errors.New("canonicalized document is not a string")
+	}
+	return []byte(canonical), nil
+}
+
+func proofToMap(v any) (map[string]any, error) {
+	vBytes, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err = json.Unmarshal(vBytes, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+//go:embed context/data-integrity-v1.jsonld
+var dataIntegrityV1 []byte
+
+//go:embed context/bitstring-status-list-v1.jsonld
+var bitstringStatusListV1 []byte
+
+// contextLoader resolves contexts from copies embedded in the binary, or registered with RegisterContext, so that
+// signing and verifying documents never depends on the network. Documents using any other context are rejected rather
+// than fetching it, which would let whoever submits a document make the service issue requests to arbitrary URLs.
+var contextLoader = newEmbeddedDocumentLoader()
+
+// RegisterContext makes a JSON-LD context available to documents secured with an embedded proof, under the given
+// URL. It replaces any context previously known by that URL.
+func RegisterContext(u string, content []byte) {
+	contextLoader.mu.Lock()
+	defer contextLoader.mu.Unlock()
+	contextLoader.embedded[u] = content
+}
+
+type embeddedDocumentLoader struct {
+	mu       sync.RWMutex
+	embedded map[string][]byte
+}
+
+func newEmbeddedDocumentLoader() *embeddedDocumentLoader {
+	embedded := map[string][]byte{
+		DataIntegrityV1Context:     dataIntegrityV1,
+		BitstringStatusListContext: bitstringStatusListV1,
+	}
+	for _, c := range ldembed.Contexts {
+		embedded[c.URL] = c.Content
+	}
+	return &embeddedDocumentLoader{embedded: embedded}
+}
+
+func (l *embeddedDocumentLoader) LoadDocument(u string) (*ld.RemoteDocument, error) {
+	l.mu.RLock()
+	content, ok := l.embedded[u]
+	l.mu.RUnlock()
+	if !ok {
+		return nil, ld.NewJsonLdError(ld.LoadingDocumentFailed, fmt.Sprintf("unsupported context: %s", u))
+	}
+	doc, err := ld.DocumentFromReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, ld.NewJsonLdError(ld.LoadingDocumentFailed, err)
+	}
+	return &ld.RemoteDocument{DocumentURL: u, Document: doc}, nil
+}
+
+// ed25519Signer produces Ed25519 signatures for the Ed25519Signature2020 and eddsa-2022 proof types. The key may be
+// held outside the service as an ExternalSigner.
+type ed25519Signer struct {
+	kid       string
+	signer    gocrypto.Signer
+	signature cryptosuite.SignatureType
+	purpose   cryptosuite.ProofPurpose
+	format    cryptosuite.PayloadFormat
+}
+
+var _ cryptosuite.Signer = (*ed25519Signer)(nil)
+
+func (s *ed25519Signer) Sign(tbs []byte) ([]byte, error) {
+	return s.signer.Sign(rand.Reader, tbs, gocrypto.Hash(0))
+}
+
+func (s *ed25519Signer) GetKeyID() string {
+	return s.kid
+}
+
+func (s *ed25519Signer) GetSignatureType() cryptosuite.SignatureType {
+	return s.signature
+}
+
+func (*ed25519Signer) GetSigningAlgorithm() string {
+	return "EdDSA"
+}
+
+func (s *ed25519Signer) SetProofPurpose(purpose cryptosuite.ProofPurpose) {
+	s.purpose = purpose
+}
+
+func (s *ed25519Signer) GetProofPurpose() cryptosuite.ProofPurpose {
+	return s.purpose
+}
+
+func (s *ed25519Signer) SetPayloadFormat(format cryptosuite.PayloadFormat) {
+	s.format = format
+}
+
+func (s *ed25519Signer) GetPayloadFormat() cryptosuite.PayloadFormat {
+	return s.format
+}
+
+type ed25519Verifier struct {
+	kid       string
+	publicKey ed25519.PublicKey
+}
+
+var _ cryptosuite.Verifier = (*ed25519Verifier)(nil)
+
+func (v ed25519Verifier) Verify(message, signature []byte) error {
+	if !ed25519.Verify(v.publicKey, message, signature) {
+		return errors.New("invalid Ed25519 signature")
+	}
+	return nil
+}
+
+func (v ed25519Verifier) GetKeyID() string {
+	return v.kid
+}
+
+// toEd25519PublicKey accepts the forms of Ed25519 public keys found across the service.
+func toEd25519PublicKey(key gocrypto.PublicKey) (ed25519.PublicKey, bool) {
+	switch k := key.(type) {
+	case ed25519.PublicKey:
+		return k, true
+	case *ed25519.PublicKey:
+		return *k, true
+	}
+	return nil, false
+}
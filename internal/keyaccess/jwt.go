@@ -14,6 +14,15 @@ import (
 	"github.com/pkg/errors"
 )
 
+// ExternalSigner is a private key whose material lives outside the service, such as in a KMS or an HSM. Only the
+// public key is known to the service; signatures are produced by the crypto.Signer.
+type ExternalSigner interface {
+	gocrypto.Signer
+
+	// External distinguishes external signers from local private keys, many of which implement crypto.Signer too.
+	External()
+}
+
 type JWKKeyAccess struct {
 	*jwx.Signer
 	*jwx.Verifier
@@ -31,7 +40,7 @@ func NewJWKKeyAccess(id, kid string, key gocrypto.PrivateKey) (*JWKKeyAccess, er
 	if key == nil {
 		return nil, errors.New("key cannot be nil")
 	}
-	signer, err := jwx.NewJWXSigner(id, kid, key)
+	signer, err := newJWXSigner(id, kid, key)
 	if err != nil {
 		return nil, errors.Wrapf(err, "could not create JWK Key Access object for kid: %s, error creating signer", kid)
 	}
@@ -45,6 +54,39 @@ func NewJWKKeyAccess(id, kid string, key gocrypto.PrivateKey) (*JWKKeyAccess, er
 	}, nil
 }
 
+func newJWXSigner(id, kid string, key gocrypto.PrivateKey) (*jwx.Signer, error) {
+	externalSigner, ok := key.(ExternalSigner)
+	if !ok {
+		return jwx.NewJWXSigner(id, kid, key)
+	}
+
+	// the signing library delegates to the crypto.Signer, so only the public portion of the JWK is needed
+	publicKeyJWK, err := jwx.PublicKeyToPublicKeyJWK(kid, externalSigner.Public())
+	if err != nil {
+		return nil, errors.Wrap(err, "converting external signer's public key to JWK")
+	}
+	alg := publicKeyJWK.ALG
+	if alg == "" {
+		if alg, err = jwx.AlgFromKeyAndCurve(publicKeyJWK.KTY, publicKeyJWK.CRV); err != nil {
+			return nil, errors.Wrap(err, "getting alg from key and curve")
+		}
+	}
+	return &jwx.Signer{
+		ID: id,
+		PrivateKeyJWK: jwx.PrivateKeyJWK{
+			KTY: publicKeyJWK.KTY,
+			CRV: publicKeyJWK.CRV,
+			X:   publicKeyJWK.X,
+			Y:   publicKeyJWK.Y,
+			N:   publicKeyJWK.N,
+			E:   publicKeyJWK.E,
+			ALG: alg,
+			KID: kid,
+		},
+		PrivateKey: externalSigner,
+	}, nil
+}
+
 // NewJWKKeyAccessVerifier creates JWKKeyAccess object from an id, key id, and public key, generating a JWT Verifier object.
 func NewJWKKeyAccessVerifier(id, kid string, key gocrypto.PublicKey) (*JWKKeyAccess, error) {
 	if id == "" {
@@ -313,7 +313,7 @@ func getDataIntegrityTestPresentation(ka DataIntegrityKeyAccess) credential.Veri
 	knownID := uuid.NewString()
 	knownType := []string{"VerifiablePresentation", "HappyPresentation"}
 	knownHolder := "did:example:ebfeb1f712ebc6f1c276e12ec21"
-	testCredential := getTestCredential(ka.Signer.ID)
+	testCredential := getTestCredential(ka.Signer.GetKeyID())
 	signedCred, _ := ka.Sign(&testCredential)
 	return credential.VerifiablePresentation{
 		Context:              knownContext,
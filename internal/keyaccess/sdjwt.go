@@ -0,0 +1,377 @@
+package keyaccess
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/TBD54566975/ssi-sdk/credential"
+	"github.com/TBD54566975/ssi-sdk/credential/integrity"
+	"github.com/TBD54566975/ssi-sdk/crypto/jwx"
+	"github.com/goccy/go-json"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/pkg/errors"
+)
+
+// SD-JWT (https://datatracker.ietf.org/doc/draft-ietf-oauth-selective-disclosure-jwt/) secures a VC-JWT whose
+// credential subject claims can be disclosed one by one. Each disclosable claim is replaced in the issuer-signed JWT
+// by the digest of a salted disclosure, and the disclosures travel next to the JWT in the combined format
+// `<issuer-jwt>~<disclosure>~...~<disclosure>~<kb-jwt>`. The key binding JWT is optional; it is added by the holder
+// when presenting and is signed with a key of the credential's subject.
+const (
+	sdJWTSeparator = "~"
+
+	sdClaim      = "_sd"
+	sdAlgClaim   = "_sd_alg"
+	sdAlgSHA256  = "sha-256"
+	nonceClaim   = "nonce"
+	sdHashClaim  = "sd_hash"
+	saltByteSize = 16
+
+	// SDJWTType is the `typ` header of the issuer-signed JWT of an SD-JWT credential.
+	SDJWTType = "vc+sd-jwt"
+	// KeyBindingJWTType is the `typ` header of a key binding JWT.
+	KeyBindingJWTType = "kb+jwt"
+
+	// keyBindingMaxAge is how long after it's issued a key binding JWT is accepted, which limits how long a captured
+	// presentation can be replayed.
+	keyBindingMaxAge = 5 * time.Minute
+	// keyBindingClockSkew is how far in the future a key binding JWT's iat may be, to allow for clock differences with
+	// the holder.
+	keyBindingClockSkew = 30 * time.Second
+)
+
+// SDJWT is an SD-JWT in the combined format: the issuer-signed JWT, followed by the disclosures, optionally
+// followed by a key binding JWT.
+type SDJWT string
+
+func (s SDJWT) String() string {
+	return string(s)
+}
+
+func (s SDJWT) Ptr() *SDJWT {
+	return &s
+}
+
+// IsSDJWT returns whether the string is in the SD-JWT combined format rather than a plain JWT.
+func IsSDJWT(s string) bool {
+	return strings.Contains(s, sdJWTSeparator)
+}
+
+// Disclosure is a base64url encoded JSON array of a salt, a claim name, and the claim's value.
+type Disclosure string
+
+// NewDisclosure creates a salted disclosure for a claim.
+func NewDisclosure(name string, value any) (Disclosure, error) {
+	salt := make([]byte, saltByteSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", errors.Wrap(err, "generating salt")
+	}
+	disclosureBytes, err := json.Marshal([]any{base64.RawURLEncoding.EncodeToString(salt), name, value})
+	if err != nil {
+		return "", errors.Wrapf(err, "marshalling disclosure for claim<%s>", name)
+	}
+	return Disclosure(base64.RawURLEncoding.EncodeToString(disclosureBytes)), nil
+}
+
+// Digest returns the base64url encoded SHA-256 digest of the disclosure, which is what the issuer signs.
+func (d Disclosure) Digest() string {
+	return sdDigest(string(d))
+}
+
+// Decode returns the name and value of the disclosed claim.
+func (d Disclosure) Decode() (name string, value any, err error) {
+	disclosureBytes, err := base64.RawURLEncoding.DecodeString(string(d))
+	if err != nil {
+		return "", nil, errors.Wrap(err, "decoding disclosure")
+	}
+	var elements []any
+	if err = json.Unmarshal(disclosureBytes, &elements); err != nil {
+		return "", nil, errors.Wrap(err, "unmarshalling disclosure")
+	}
+	if len(elements) != 3 {
+		return "", nil, fmt.Errorf("disclosure must have 3 elements, got %d", len(elements))
+	}
+	name, ok := elements[1].(string)
+	if !ok || name == "" {
+		return "", nil, errors.New("disclosure claim name must be a non-empty string")
+	}
+	return name, elements[2], nil
+}
+
+// SDJWTParts holds the components of an SD-JWT.
+type SDJWTParts struct {
+	IssuerJWT   JWT
+	Disclosures []Disclosure
+	// Set only for presentations with key binding.
+	KeyBindingJWT *JWT
+}
+
+// Parse splits the SD-JWT into its components.
+func (s SDJWT) Parse() (*SDJWTParts, error) {
+	components := strings.Split(string(s), sdJWTSeparator)
+	if len(components) < 2 || components[0] == "" {
+		return nil, errors.New("malformed SD-JWT: expected an issuer JWT followed by `~`")
+	}
+	parts := SDJWTParts{IssuerJWT: JWT(components[0])}
+	for _, d := range components[1 : len(components)-1] {
+		if d == "" {
+			return nil, errors.New("malformed SD-JWT: empty disclosure")
+		}
+		parts.Disclosures = append(parts.Disclosures, Disclosure(d))
+	}
+	if kb := components[len(components)-1]; kb != "" {
+		parts.KeyBindingJWT = JWTPtr(kb)
+	}
+	return &parts, nil
+}
+
+// Select returns the SD-JWT with only the disclosures of the given claims, and without a key binding JWT. This is
+// what a holder does before presenting.
+func (s SDJWT) Select(claims ...string) (*SDJWT, error) {
+	parts, err := s.Parse()
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool, len(claims))
+	for _, c := range claims {
+		wanted[c] = true
+	}
+	var selected []Disclosure
+	for _, d := range parts.Disclosures {
+		name, _, err := d.Decode()
+		if err != nil {
+			return nil, err
+		}
+		if wanted[name] {
+			selected = append(selected, d)
+		}
+	}
+	return combineSDJWT(parts.IssuerJWT, selected).Ptr(), nil
+}
+
+// withoutKeyBinding returns the SD-JWT up to and including the last separator, which is the input of `sd_hash`.
+func (s SDJWT) withoutKeyBinding() string {
+	str := string(s)
+	return str[:strings.LastIndex(str, sdJWTSeparator)+1]
+}
+
+// DisclosedCredential returns the credential of the issuer JWT with the disclosed claims of the SD-JWT added to its
+// subject. The signature of the issuer JWT is not verified. An error is returned if a disclosure was not signed by
+// the issuer.
+func (s SDJWT) DisclosedCredential() (*credential.VerifiableCredential, error) {
+	parts, err := s.Parse()
+	if err != nil {
+		return nil, err
+	}
+	_, token, cred, err := integrity.ParseVerifiableCredentialFromJWT(parts.IssuerJWT.String())
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing issuer JWT")
+	}
+	if alg, ok := token.Get(sdAlgClaim); ok && alg != sdAlgSHA256 {
+		return nil, fmt.Errorf("unsupported %s<%v>", sdAlgClaim, alg)
+	}
+
+	subject := cred.CredentialSubject
+	digests := make(map[string]bool)
+	if maybeDigests, ok := subject[sdClaim]; ok {
+		digestArray, ok := maybeDigests.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%s claim must be an array", sdClaim)
+		}
+		for _, d := range digestArray {
+			digest, ok := d.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s claim must be an array of strings", sdClaim)
+			}
+			digests[digest] = true
+		}
+	}
+	delete(subject, sdClaim)
+
+	for _, d := range parts.Disclosures {
+		digest := d.Digest()
+		if !digests[digest] {
+			return nil, fmt.Errorf("disclosure<%s> is not signed by the issuer or was disclosed twice", digest)
+		}
+		delete(digests, digest)
+		name, value, err := d.Decode()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := subject[name]; ok || name == sdClaim {
+			return nil, fmt.Errorf("disclosed claim<%s> is already present", name)
+		}
+		subject[name] = value
+	}
+	return cred, nil
+}
+
+// SignSDJWTVerifiableCredential signs the credential as an SD-JWT, making each of the given credential subject
+// claims selectively disclosable. All disclosures are part of the returned SD-JWT.
+func (ka JWKKeyAccess) SignSDJWTVerifiableCredential(cred credential.VerifiableCredential, disclosable []string) (*SDJWT, error) {
+	if ka.Signer == nil {
+		return nil, errors.New("cannot sign with nil signer")
+	}
+	if err := cred.IsValid(); err != nil {
+		return nil, errors.New("cannot sign invalid credential")
+	}
+
+	// work on a copy of the subject, so the caller's credential keeps all its claims
+	subject := make(credential.CredentialSubject, len(cred.CredentialSubject))
+	for k, v := range cred.CredentialSubject {
+		subject[k] = v
+	}
+	disclosures := make([]Disclosure, 0, len(disclosable))
+	digests := make([]string, 0, len(disclosable))
+	for _, name := range disclosable {
+		value, ok := subject[name]
+		if !ok {
+			return nil, fmt.Errorf("disclosable claim<%s> is not in the credential subject", name)
+		}
+		if name == credential.VerifiableCredentialIDProperty || name == sdClaim {
+			return nil, fmt.Errorf("claim<%s> cannot be selectively disclosable", name)
+		}
+		disclosure, err := NewDisclosure(name, value)
+		if err != nil {
+			return nil, err
+		}
+		delete(subject, name)
+		disclosures = append(disclosures, disclosure)
+		digests = append(digests, disclosure.Digest())
+	}
+	// sorting hides the order of the claims in the credential
+	sort.Strings(digests)
+	subject[sdClaim] = digests
+	cred.CredentialSubject = subject
+
+	t, err := integrity.JWTClaimSetFromVC(cred)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating claim set from credential")
+	}
+	if err = t.Set(sdAlgClaim, sdAlgSHA256); err != nil {
+		return nil, errors.Wrapf(err, "setting %s value", sdAlgClaim)
+	}
+	issuerJWT, err := signJWTWithType(*ka.Signer, t, SDJWTType)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not sign cred")
+	}
+	return combineSDJWT(JWT(issuerJWT), disclosures).Ptr(), nil
+}
+
+// SignSDJWTKeyBinding appends a key binding JWT for the audience and nonce to an SD-JWT that doesn't have one yet.
+func (ka JWKKeyAccess) SignSDJWTKeyBinding(sdJWT SDJWT, audience, nonce string) (*SDJWT, error) {
+	if ka.Signer == nil {
+		return nil, errors.New("cannot sign with nil signer")
+	}
+	parts, err := sdJWT.Parse()
+	if err != nil {
+		return nil, err
+	}
+	if parts.KeyBindingJWT != nil {
+		return nil, errors.New("SD-JWT already has a key binding JWT")
+	}
+
+	t := jwt.New()
+	claims := map[string]any{
+		jwt.AudienceKey: audience,
+		jwt.IssuedAtKey: time.Now().Unix(),
+		nonceClaim:      nonce,
+		sdHashClaim:     sdDigest(sdJWT.String()),
+	}
+	for k, v := range claims {
+		if err = t.Set(k, v); err != nil {
+			return nil, errors.Wrapf(err, "setting %s value", k)
+		}
+	}
+	kbJWT, err := signJWTWithType(*ka.Signer, t, KeyBindingJWTType)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not sign key binding JWT")
+	}
+	return SDJWT(sdJWT.String() + string(kbJWT)).Ptr(), nil
+}
+
+// VerifySDJWTKeyBinding verifies that the SD-JWT's key binding JWT is signed by the verifier's key, is for the
+// audience and nonce, was issued within the last few minutes, and covers the SD-JWT's issuer JWT and disclosures.
+func (ka JWKKeyAccess) VerifySDJWTKeyBinding(sdJWT SDJWT, audience, nonce string) error {
+	if ka.Verifier == nil {
+		return errors.New("cannot verify with nil verifier")
+	}
+	parts, err := sdJWT.Parse()
+	if err != nil {
+		return err
+	}
+	if parts.KeyBindingJWT == nil {
+		return errors.New("SD-JWT has no key binding JWT")
+	}
+	headers, token, err := ka.VerifyAndParse(parts.KeyBindingJWT.String())
+	if err != nil {
+		return errors.Wrap(err, "verifying key binding JWT")
+	}
+	if headers.Type() != KeyBindingJWTType {
+		return fmt.Errorf("key binding JWT has typ<%s>, expected<%s>", headers.Type(), KeyBindingJWTType)
+	}
+	if token.IssuedAt().IsZero() {
+		return errors.New("key binding JWT has no iat")
+	}
+	now := time.Now()
+	if token.IssuedAt().Before(now.Add(-keyBindingMaxAge)) || token.IssuedAt().After(now.Add(keyBindingClockSkew)) {
+		return fmt.Errorf("key binding JWT was issued at %s, which is not recent", token.IssuedAt().Format(time.RFC3339))
+	}
+	if !containsString(token.Audience(), audience) {
+		return fmt.Errorf("key binding JWT is not for audience<%s>", audience)
+	}
+	if gotNonce, _ := token.Get(nonceClaim); gotNonce != nonce {
+		return fmt.Errorf("key binding JWT has nonce<%v>, expected<%s>", gotNonce, nonce)
+	}
+	if gotHash, _ := token.Get(sdHashClaim); gotHash != sdDigest(sdJWT.withoutKeyBinding()) {
+		return errors.New("key binding JWT does not match the presented SD-JWT")
+	}
+	return nil
+}
+
+func signJWTWithType(signer jwx.Signer, t jwt.Token, typ string) ([]byte, error) {
+	hdrs := jws.NewHeaders()
+	if signer.KID != "" {
+		if err := hdrs.Set(jws.KeyIDKey, signer.KID); err != nil {
+			return nil, errors.Wrap(err, "setting KID protected header")
+		}
+	}
+	if typ != "" {
+		if err := hdrs.Set(jws.TypeKey, typ); err != nil {
+			return nil, errors.Wrap(err, "setting typ protected header")
+		}
+	}
+	return jwt.Sign(t, jwt.WithKey(jwa.SignatureAlgorithm(signer.ALG), signer.PrivateKey, jws.WithProtectedHeaders(hdrs)))
+}
+
+func combineSDJWT(issuerJWT JWT, disclosures []Disclosure) SDJWT {
+	var sb strings.Builder
+	sb.WriteString(issuerJWT.String())
+	sb.WriteString(sdJWTSeparator)
+	for _, d := range disclosures {
+		sb.WriteString(string(d))
+		sb.WriteString(sdJWTSeparator)
+	}
+	return SDJWT(sb.String())
+}
+
+func sdDigest(s string) string {
+	digest := sha256.Sum256([]byte(s))
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
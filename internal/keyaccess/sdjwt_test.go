@@ -0,0 +1,175 @@
+package keyaccess
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TBD54566975/ssi-sdk/credential"
+	"github.com/TBD54566975/ssi-sdk/credential/integrity"
+	"github.com/TBD54566975/ssi-sdk/crypto"
+	"github.com/google/uuid"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisclosure(t *testing.T) {
+	disclosure, err := NewDisclosure("address", map[string]any{"country": "US"})
+	assert.NoError(t, err)
+
+	name, value, err := disclosure.Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, "address", name)
+	assert.Equal(t, map[string]any{"country": "US"}, value)
+
+	// the same claim is salted differently every time
+	other, err := NewDisclosure("address", map[string]any{"country": "US"})
+	assert.NoError(t, err)
+	assert.NotEqual(t, disclosure.Digest(), other.Digest())
+
+	_, _, err = Disclosure("bm90IGpzb24").Decode()
+	assert.Error(t, err)
+}
+
+func TestSDJWTVerifiableCredential(t *testing.T) {
+	ka, cred := getSDJWTTestKeyAccessAndCredential(t)
+
+	sdJWT, err := ka.SignSDJWTVerifiableCredential(cred, []string{"firstName", "age"})
+	require.NoError(t, err)
+
+	// the caller's credential is left as is
+	assert.Equal(t, "Satoshi", cred.CredentialSubject["firstName"])
+
+	parts, err := sdJWT.Parse()
+	assert.NoError(t, err)
+	assert.Len(t, parts.Disclosures, 2)
+	assert.Nil(t, parts.KeyBindingJWT)
+	assert.True(t, strings.HasSuffix(sdJWT.String(), "~"))
+
+	// the issuer JWT only has digests of the disclosable claims
+	_, err = ka.VerifyVerifiableCredential(parts.IssuerJWT)
+	assert.NoError(t, err)
+	issuerHeaders, err := GetJWTHeaders([]byte(parts.IssuerJWT.String()))
+	assert.NoError(t, err)
+	assert.Equal(t, SDJWTType, issuerHeaders.Type())
+	_, _, issuerCred, err := integrity.ParseVerifiableCredentialFromJWT(parts.IssuerJWT.String())
+	assert.NoError(t, err)
+	assert.NotContains(t, issuerCred.CredentialSubject, "firstName")
+	assert.Len(t, issuerCred.CredentialSubject["_sd"], 2)
+
+	t.Run("disclose all", func(t *testing.T) {
+		disclosed, err := sdJWT.DisclosedCredential()
+		assert.NoError(t, err)
+		assert.Equal(t, "Satoshi", disclosed.CredentialSubject["firstName"])
+		assert.Equal(t, "Nakamoto", disclosed.CredentialSubject["lastName"])
+		assert.Equal(t, float64(42), disclosed.CredentialSubject["age"])
+		assert.NotContains(t, disclosed.CredentialSubject, "_sd")
+	})
+
+	t.Run("disclose some", func(t *testing.T) {
+		selected, err := sdJWT.Select("age")
+		assert.NoError(t, err)
+		disclosed, err := selected.DisclosedCredential()
+		assert.NoError(t, err)
+		assert.NotContains(t, disclosed.CredentialSubject, "firstName")
+		assert.Equal(t, float64(42), disclosed.CredentialSubject["age"])
+	})
+
+	t.Run("disclosure not signed by the issuer", func(t *testing.T) {
+		forged, err := NewDisclosure("firstName", "Hal")
+		assert.NoError(t, err)
+		selected, err := sdJWT.Select()
+		assert.NoError(t, err)
+		_, err = SDJWT(selected.String() + string(forged) + "~").DisclosedCredential()
+		assert.ErrorContains(t, err, "not signed by the issuer")
+	})
+
+	t.Run("disclosure repeated", func(t *testing.T) {
+		_, err = SDJWT(sdJWT.String() + string(parts.Disclosures[0]) + "~").DisclosedCredential()
+		assert.ErrorContains(t, err, "disclosed twice")
+	})
+
+	t.Run("claims that cannot be disclosable", func(t *testing.T) {
+		_, err = ka.SignSDJWTVerifiableCredential(cred, []string{"id"})
+		assert.ErrorContains(t, err, "cannot be selectively disclosable")
+		_, err = ka.SignSDJWTVerifiableCredential(cred, []string{"middleName"})
+		assert.ErrorContains(t, err, "not in the credential subject")
+	})
+}
+
+func TestSDJWTKeyBinding(t *testing.T) {
+	ka, cred := getSDJWTTestKeyAccessAndCredential(t)
+	sdJWT, err := ka.SignSDJWTVerifiableCredential(cred, []string{"firstName", "age"})
+	require.NoError(t, err)
+
+	_, holderKey, err := crypto.GenerateEd25519Key()
+	require.NoError(t, err)
+	holder, err := NewJWKKeyAccess("did:example:holder", "did:example:holder#key-1", holderKey)
+	require.NoError(t, err)
+
+	selected, err := sdJWT.Select("firstName")
+	require.NoError(t, err)
+	presentation, err := holder.SignSDJWTKeyBinding(*selected, "did:example:verifier", "nonce")
+	require.NoError(t, err)
+
+	parts, err := presentation.Parse()
+	assert.NoError(t, err)
+	assert.NotNil(t, parts.KeyBindingJWT)
+	headers, err := GetJWTHeaders([]byte(parts.KeyBindingJWT.String()))
+	assert.NoError(t, err)
+	assert.Equal(t, KeyBindingJWTType, headers.Type())
+
+	assert.NoError(t, holder.VerifySDJWTKeyBinding(*presentation, "did:example:verifier", "nonce"))
+	assert.ErrorContains(t, holder.VerifySDJWTKeyBinding(*presentation, "did:example:other", "nonce"), "audience")
+	assert.ErrorContains(t, holder.VerifySDJWTKeyBinding(*presentation, "did:example:verifier", "other"), "nonce")
+	assert.ErrorContains(t, holder.VerifySDJWTKeyBinding(*selected, "did:example:verifier", "nonce"), "no key binding JWT")
+
+	// a disclosure added after binding is caught
+	issued, err := sdJWT.Parse()
+	require.NoError(t, err)
+	added := SDJWT(selected.String() + string(issued.Disclosures[1]) + "~" + parts.KeyBindingJWT.String())
+	assert.ErrorContains(t, holder.VerifySDJWTKeyBinding(added, "did:example:verifier", "nonce"), "does not match")
+
+	// only the holder's key verifies the binding
+	_, otherKey, err := crypto.GenerateEd25519Key()
+	require.NoError(t, err)
+	other, err := NewJWKKeyAccess("did:example:other", "did:example:other#key-1", otherKey)
+	require.NoError(t, err)
+	assert.Error(t, other.VerifySDJWTKeyBinding(*presentation, "did:example:verifier", "nonce"))
+
+	_, err = holder.SignSDJWTKeyBinding(*presentation, "did:example:verifier", "nonce")
+	assert.ErrorContains(t, err, "already has a key binding JWT")
+
+	// a key binding JWT that isn't recent can't be replayed
+	stale := jwt.New()
+	require.NoError(t, stale.Set(jwt.AudienceKey, "did:example:verifier"))
+	require.NoError(t, stale.Set(jwt.IssuedAtKey, time.Now().Add(-10*time.Minute).Unix()))
+	require.NoError(t, stale.Set(nonceClaim, "nonce"))
+	require.NoError(t, stale.Set(sdHashClaim, sdDigest(selected.String())))
+	staleJWT, err := signJWTWithType(*holder.Signer, stale, KeyBindingJWTType)
+	require.NoError(t, err)
+	assert.ErrorContains(t, holder.VerifySDJWTKeyBinding(SDJWT(selected.String()+string(staleJWT)), "did:example:verifier", "nonce"), "not recent")
+}
+
+func getSDJWTTestKeyAccessAndCredential(t *testing.T) (*JWKKeyAccess, credential.VerifiableCredential) {
+	_, privKey, err := crypto.GenerateEd25519Key()
+	require.NoError(t, err)
+	ka, err := NewJWKKeyAccess("did:example:issuer", "did:example:issuer#key-1", privKey)
+	require.NoError(t, err)
+
+	cred := credential.VerifiableCredential{
+		Context:      []string{"https://www.w3.org/2018/credentials/v1"},
+		ID:           uuid.NewString(),
+		Type:         []string{"VerifiableCredential"},
+		Issuer:       "did:example:issuer",
+		IssuanceDate: "2021-01-01T19:23:24Z",
+		CredentialSubject: map[string]any{
+			"id":        "did:example:holder",
+			"firstName": "Satoshi",
+			"lastName":  "Nakamoto",
+			"age":       42,
+		},
+	}
+	return ka, cred
+}
@@ -0,0 +1,608 @@
+package verification
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	credsdk "github.com/TBD54566975/ssi-sdk/credential"
+	"github.com/TBD54566975/ssi-sdk/credential/exchange"
+	"github.com/TBD54566975/ssi-sdk/credential/integrity"
+	"github.com/TBD54566975/ssi-sdk/credential/validation"
+	"github.com/TBD54566975/ssi-sdk/crypto/jwx"
+	didsdk "github.com/TBD54566975/ssi-sdk/did"
+	sdkutil "github.com/TBD54566975/ssi-sdk/util"
+	"github.com/goccy/go-json"
+	"github.com/pkg/errors"
+
+	"github.com/tbd54566975/ssi-service/internal/credential"
+	"github.com/tbd54566975/ssi-service/internal/keyaccess"
+)
+
+// Check is one of the checks that are run when verifying a credential or a presentation.
+type Check string
+
+const (
+	// CheckDIDResolution resolves the DID of the credential's issuer, or of the presentation's holder.
+	CheckDIDResolution Check = "didResolution"
+	// CheckSignature verifies the signature of the credential or presentation with the resolved DID's key.
+	CheckSignature Check = "signature"
+	// CheckDataModel checks that the credential complies with the VC Data Model.
+	CheckDataModel Check = "dataModel"
+	// CheckExpiry checks that the credential is past its `issuanceDate`, and hasn't passed its `expirationDate`.
+	CheckExpiry Check = "expiry"
+	// CheckSchema validates the credential's data against its `credentialSchema`.
+	CheckSchema Check = "schema"
+	// CheckStatus checks that the credential isn't revoked or suspended in the status lists of its `credentialStatus`.
+	CheckStatus Check = "status"
+	// CheckIssuerTrust checks that the credential was issued by one of the trusted issuers.
+	CheckIssuerTrust Check = "issuerTrust"
+	// CheckPresentationDefinition checks that the presentation's submission satisfies the constraints of a
+	// presentation definition.
+	CheckPresentationDefinition Check = "presentationDefinition"
+)
+
+// CheckOutcome is the outcome of a check.
+type CheckOutcome string
+
+const (
+	OutcomePassed CheckOutcome = "passed"
+	OutcomeFailed CheckOutcome = "failed"
+	// OutcomeSkipped is the outcome of checks that don't apply, or that can't run because of a failed check.
+	OutcomeSkipped CheckOutcome = "skipped"
+)
+
+// ErrorCode identifies why a check failed, and is stable enough for clients to map to their own messages.
+type ErrorCode string
+
+const (
+	CodeMalformed              ErrorCode = "MALFORMED"
+	CodeDIDNotResolved         ErrorCode = "DID_NOT_RESOLVED"
+	CodeSignatureInvalid       ErrorCode = "SIGNATURE_INVALID"
+	CodeDataModelInvalid       ErrorCode = "DATA_MODEL_INVALID"
+	CodeCredentialExpired      ErrorCode = "CREDENTIAL_EXPIRED"
+	CodeCredentialNotYetValid  ErrorCode = "CREDENTIAL_NOT_YET_VALID"
+	CodeSchemaNotResolved      ErrorCode = "SCHEMA_NOT_RESOLVED"
+	CodeSchemaValidationFailed ErrorCode = "SCHEMA_VALIDATION_FAILED"
+	CodeCredentialRevoked      ErrorCode = "CREDENTIAL_REVOKED"
+	CodeCredentialSuspended    ErrorCode = "CREDENTIAL_SUSPENDED"
+	CodeStatusUnavailable      ErrorCode = "STATUS_UNAVAILABLE"
+	CodeIssuerUntrusted        ErrorCode = "ISSUER_UNTRUSTED"
+	CodeDefinitionNotSatisfied ErrorCode = "PRESENTATION_DEFINITION_NOT_SATISFIED"
+)
+
+// CheckResult is the outcome of a single check.
+type CheckResult struct {
+	Check   Check        `json:"check"`
+	Outcome CheckOutcome `json:"outcome"`
+	// Set when the check failed.
+	Code ErrorCode `json:"code,omitempty"`
+	// Why the check failed or was skipped.
+	Message string `json:"message,omitempty"`
+}
+
+// Report lists each check that ran when verifying a credential or a presentation, and its outcome.
+type Report struct {
+	// Whether none of the checks failed, including those of the presented credentials.
+	Verified bool `json:"verified"`
+
+	// ID of the verified credential, when it could be parsed. Not set on presentation reports.
+	CredentialID string `json:"credentialId,omitempty"`
+
+	Checks []CheckResult `json:"checks"`
+
+	// A report for each credential in a presentation, in the order they were presented.
+	Credentials []Report `json:"credentials,omitempty"`
+
+	statusFailures []StatusFailure
+}
+
+// Options configure the optional checks of a verification.
+type Options struct {
+	// DIDs trusted to issue credentials. When empty, the issuer trust check is skipped.
+	TrustedIssuers []string
+
+	// Definition whose constraints a presentation's submission must satisfy. When nil, the presentation definition
+	// check is skipped.
+	PresentationDefinition *exchange.PresentationDefinition
+
+	// Set for credentials presented with selective disclosure, whose holders may withhold claims required by the
+	// credential's schema. The schema check is skipped for them.
+	selectivelyDisclosed bool
+}
+
+// Err returns nil when the report is verified, or a ReportError with the failed checks otherwise.
+func (r Report) Err() error {
+	if r.Verified {
+		return nil
+	}
+	return &ReportError{Report: r}
+}
+
+// StatusFailures returns a status failure for each credential in the report that is revoked or suspended, or whose
+// status couldn't be checked.
+func (r Report) StatusFailures() []StatusFailure {
+	failures := append([]StatusFailure{}, r.statusFailures...)
+	for _, credentialReport := range r.Credentials {
+		failures = append(failures, credentialReport.StatusFailures()...)
+	}
+	return failures
+}
+
+// OnlyStatusFailed returns whether the status check is the only check that failed, in the report and in the reports of
+// its credentials. Credentials of such reports would be verified, were they neither revoked nor suspended.
+func (r Report) OnlyStatusFailed() bool {
+	statusFailed := false
+	for _, result := range r.Checks {
+		if result.Outcome != OutcomeFailed {
+			continue
+		}
+		if result.Check != CheckStatus {
+			return false
+		}
+		statusFailed = true
+	}
+	for _, credentialReport := range r.Credentials {
+		if credentialReport.Verified {
+			continue
+		}
+		if !credentialReport.OnlyStatusFailed() {
+			return false
+		}
+		statusFailed = true
+	}
+	return statusFailed
+}
+
+func (r *Report) pass(check Check) {
+	r.Checks = append(r.Checks, CheckResult{Check: check, Outcome: OutcomePassed})
+}
+
+func (r *Report) fail(check Check, code ErrorCode, err error) {
+	r.Checks = append(r.Checks, CheckResult{Check: check, Outcome: OutcomeFailed, Code: code, Message: err.Error()})
+}
+
+func (r *Report) skip(message string, checks ...Check) {
+	for _, check := range checks {
+		r.Checks = append(r.Checks, CheckResult{Check: check, Outcome: OutcomeSkipped, Message: message})
+	}
+}
+
+// finish sets whether the report is verified once all of its checks have run.
+func (r *Report) finish() *Report {
+	r.Verified = true
+	for _, result := range r.Checks {
+		if result.Outcome == OutcomeFailed {
+			r.Verified = false
+		}
+	}
+	for _, credentialReport := range r.Credentials {
+		if !credentialReport.Verified {
+			r.Verified = false
+		}
+	}
+	return r
+}
+
+// ReportError is returned by the verification methods that don't return a report, and has the report of the failed
+// verification.
+type ReportError struct {
+	Report Report
+}
+
+func (e *ReportError) Error() string {
+	return strings.Join(failureMessages(e.Report), "; ")
+}
+
+// Unwrap returns a StatusError when the status check is the only check that failed, so that GetStatusFailures works on
+// the error. Errors of reports with other failed checks aren't StatusErrors, so they're never mistaken for a revoked
+// or suspended credential.
+func (e *ReportError) Unwrap() error {
+	if failures := e.Report.StatusFailures(); len(failures) > 0 && e.Report.OnlyStatusFailed() {
+		return &StatusError{Failures: failures}
+	}
+	return nil
+}
+
+func failureMessages(r Report) []string {
+	var messages []string
+	for _, result := range r.Checks {
+		if result.Outcome == OutcomeFailed {
+			messages = append(messages, result.Message)
+		}
+	}
+	for i, credentialReport := range r.Credentials {
+		for _, message := range failureMessages(credentialReport) {
+			messages = append(messages, fmt.Sprintf("verifying credential %d: %s", i, message))
+		}
+	}
+	return messages
+}
+
+// issuanceClockSkew is how far in the future a credential's issuance date may be.
+const issuanceClockSkew = time.Minute
+
+// credentialChecks are the checks run on the contents of a credential, once it's parsed.
+var credentialChecks = []Check{CheckDataModel, CheckExpiry, CheckSchema, CheckStatus, CheckIssuerTrust}
+
+// VerifyCredentialWithReport runs each check on the credential, and reports on their outcome. Checks that depend on a
+// failed check, such as the signature check of a credential whose issuer's DID couldn't be resolved, are skipped.
+// Works for JWT, SD-JWT and LD securing mechanisms.
+func (v Verifier) VerifyCredentialWithReport(ctx context.Context, container credential.Container, opts Options) *Report {
+	var report Report
+	var cred *credsdk.VerifiableCredential
+	switch {
+	case container.HasJWTCredential():
+		cred = v.checkJWTCredential(ctx, &report, *container.CredentialJWT)
+	case container.HasSDJWTCredential():
+		cred = v.checkSDJWTCredential(ctx, &report, *container.SDJWT)
+	case container.Credential != nil:
+		cred = v.checkDataIntegrityCredential(ctx, &report, *container.Credential)
+	default:
+		report.fail(CheckSignature, CodeMalformed, errors.New("no credential was given"))
+	}
+	if cred == nil {
+		report.skip("credential could not be parsed", credentialChecks...)
+		return report.finish()
+	}
+	report.CredentialID = cred.ID
+	v.checkCredential(ctx, &report, *cred, opts)
+	return report.finish()
+}
+
+func (v Verifier) checkJWTCredential(ctx context.Context, report *Report, token keyaccess.JWT) *credsdk.VerifiableCredential {
+	headers, _, cred, err := integrity.ParseVerifiableCredentialFromJWT(token.String())
+	if err != nil {
+		report.fail(CheckSignature, CodeMalformed, errors.Wrap(err, "parsing JWT"))
+		report.skip("credential could not be parsed", CheckDIDResolution)
+		return nil
+	}
+	doc := v.checkDIDResolution(ctx, report, cred.IssuerID(), "verifying JWT credential")
+	if doc != nil {
+		if err = verifyJWTSignature(token.String(), cred.IssuerID(), headers.KeyID(), *doc); err != nil {
+			report.fail(CheckSignature, CodeSignatureInvalid, errors.Wrap(err, "verifying JWT credential"))
+		} else {
+			report.pass(CheckSignature)
+		}
+	}
+	return cred
+}
+
+func (v Verifier) checkSDJWTCredential(ctx context.Context, report *Report, sdJWT keyaccess.SDJWT) *credsdk.VerifiableCredential {
+	parts, err := sdJWT.Parse()
+	if err != nil {
+		report.fail(CheckSignature, CodeMalformed, errors.Wrap(err, "parsing SD-JWT"))
+		report.skip("credential could not be parsed", CheckDIDResolution)
+		return nil
+	}
+	headers, _, issuerCred, err := integrity.ParseVerifiableCredentialFromJWT(parts.IssuerJWT.String())
+	if err != nil {
+		report.fail(CheckSignature, CodeMalformed, errors.Wrap(err, "parsing SD-JWT issuer JWT"))
+		report.skip("credential could not be parsed", CheckDIDResolution)
+		return nil
+	}
+	cred, err := sdJWT.DisclosedCredential()
+	if err != nil {
+		report.fail(CheckSignature, CodeMalformed, errors.Wrap(err, "processing SD-JWT disclosures"))
+		report.skip("credential could not be parsed", CheckDIDResolution)
+		return nil
+	}
+	doc := v.checkDIDResolution(ctx, report, issuerCred.IssuerID(), "verifying SD-JWT credential")
+	if doc != nil {
+		if err = verifyJWTSignature(parts.IssuerJWT.String(), issuerCred.IssuerID(), headers.KeyID(), *doc); err != nil {
+			report.fail(CheckSignature, CodeSignatureInvalid, errors.Wrap(err, "verifying SD-JWT credential"))
+		} else {
+			report.pass(CheckSignature)
+		}
+	}
+	return cred
+}
+
+func (v Verifier) checkDataIntegrityCredential(ctx context.Context, report *Report, cred credsdk.VerifiableCredential) *credsdk.VerifiableCredential {
+	issuer, verificationMethod, err := dataIntegrityVerificationMethod(cred)
+	if err != nil {
+		report.fail(CheckSignature, CodeMalformed, err)
+		report.skip("credential has no verification method", CheckDIDResolution)
+		return &cred
+	}
+	doc := v.checkDIDResolution(ctx, report, issuer, "verifying data integrity credential")
+	if doc != nil {
+		if err = verifyDataIntegrityProof(cred, issuer, verificationMethod, *doc); err != nil {
+			report.fail(CheckSignature, CodeSignatureInvalid, err)
+		} else {
+			report.pass(CheckSignature)
+		}
+	}
+	return &cred
+}
+
+// checkDIDResolution resolves the DID, and returns its document, or nil when it couldn't be resolved. The signature
+// check is skipped when the DID isn't resolved.
+func (v Verifier) checkDIDResolution(ctx context.Context, report *Report, did, errPrefix string) *didsdk.Document {
+	resolved, err := v.didResolver.Resolve(ctx, did)
+	if err != nil {
+		report.fail(CheckDIDResolution, CodeDIDNotResolved, errors.Wrapf(err, "%s: resolving DID<%s>", errPrefix, did))
+		report.skip("DID could not be resolved", CheckSignature)
+		return nil
+	}
+	report.pass(CheckDIDResolution)
+	return &resolved.Document
+}
+
+// checkCredential runs the checks on the contents of a credential.
+func (v Verifier) checkCredential(ctx context.Context, report *Report, cred credsdk.VerifiableCredential, opts Options) {
+	if err := validation.ValidateCredential(cred); err != nil {
+		report.fail(CheckDataModel, CodeDataModelInvalid, errors.Wrap(err, "credential does not comply with the VC Data Model"))
+	} else {
+		report.pass(CheckDataModel)
+	}
+
+	if err := checkValidityPeriod(cred); err != nil {
+		report.fail(CheckExpiry, err.code, err)
+	} else {
+		report.pass(CheckExpiry)
+	}
+
+	if opts.selectivelyDisclosed {
+		report.skip("holders may withhold claims required by the schema of a selectively disclosed credential", CheckSchema)
+	} else {
+		v.checkSchema(ctx, report, cred)
+	}
+
+	if cred.CredentialStatus == nil {
+		report.skip("credential has no credentialStatus", CheckStatus)
+	} else if failure, code := v.getStatusFailure(ctx, cred); failure != nil {
+		report.statusFailures = append(report.statusFailures, *failure)
+		report.fail(CheckStatus, code, &StatusError{Failures: []StatusFailure{*failure}})
+	} else {
+		report.pass(CheckStatus)
+	}
+
+	switch {
+	case len(opts.TrustedIssuers) == 0:
+		report.skip("no trusted issuers were given", CheckIssuerTrust)
+	case !sdkutil.Contains(cred.IssuerID(), opts.TrustedIssuers):
+		report.fail(CheckIssuerTrust, CodeIssuerUntrusted, errors.Errorf("credential<%s> was issued by untrusted issuer<%s>", cred.ID, cred.IssuerID()))
+	default:
+		report.pass(CheckIssuerTrust)
+	}
+}
+
+// codedError is an error along with the code it's reported with.
+type codedError struct {
+	error
+	code ErrorCode
+}
+
+// checkValidityPeriod checks that the credential was issued, and hasn't expired. Credentials issued a little in the
+// future are allowed, since the issuer's clock may be ahead.
+func checkValidityPeriod(cred credsdk.VerifiableCredential) *codedError {
+	now := time.Now()
+	if cred.IssuanceDate != "" {
+		issuanceDate, err := time.Parse(time.RFC3339, cred.IssuanceDate)
+		if err != nil {
+			return &codedError{errors.Wrapf(err, "failed to parse issuance date: %s", cred.IssuanceDate), CodeMalformed}
+		}
+		if issuanceDate.After(now.Add(issuanceClockSkew)) {
+			return &codedError{errors.Errorf("credential is not valid until %s", issuanceDate.String()), CodeCredentialNotYetValid}
+		}
+	}
+	if cred.ExpirationDate != "" {
+		expiry, err := time.Parse(time.RFC3339, cred.ExpirationDate)
+		if err != nil {
+			return &codedError{errors.Wrapf(err, "failed to parse expiry date: %s", cred.ExpirationDate), CodeMalformed}
+		}
+		if expiry.Before(now) {
+			return &codedError{errors.Errorf("credential has expired as of %s", expiry.String()), CodeCredentialExpired}
+		}
+	}
+	return nil
+}
+
+func (v Verifier) checkSchema(ctx context.Context, report *Report, cred credsdk.VerifiableCredential) {
+	if cred.CredentialSchema == nil {
+		report.skip("credential has no credentialSchema", CheckSchema)
+		return
+	}
+	schemaID := cred.CredentialSchema.ID
+	resolvedSchema, _, err := v.schemaResolver.Resolve(ctx, schemaID)
+	if err != nil {
+		report.fail(CheckSchema, CodeSchemaNotResolved, errors.Wrapf(err, "for credential<%s> failed to resolve schemas: %s", cred.ID, schemaID))
+		return
+	}
+	schemaBytes, err := json.Marshal(resolvedSchema)
+	if err != nil {
+		report.fail(CheckSchema, CodeSchemaNotResolved, errors.Wrapf(err, "for credential<%s> failed to marshal schema: %s", cred.ID, schemaID))
+		return
+	}
+	if err = validation.ValidateJSONSchema(cred, validation.WithSchema(string(schemaBytes))); err != nil {
+		report.fail(CheckSchema, CodeSchemaValidationFailed, errors.Wrapf(err, "credential<%s> does not comply with schema<%s>", cred.ID, schemaID))
+		return
+	}
+	report.pass(CheckSchema)
+}
+
+// VerifyJWTPresentationWithReport runs each check on the presentation and its credentials, and reports on their
+// outcome. The report of each presented credential is in the report's credentials.
+func (v Verifier) VerifyJWTPresentationWithReport(ctx context.Context, token keyaccess.JWT, opts Options) *Report {
+	var report Report
+	headers, vpToken, pres, err := integrity.ParseVerifiablePresentationFromJWT(token.String())
+	if err != nil {
+		report.fail(CheckSignature, CodeMalformed, errors.Wrap(err, "verifying JWT presentation: parsing JWT"))
+		report.skip("presentation could not be parsed", CheckDIDResolution, CheckPresentationDefinition)
+		return report.finish()
+	}
+
+	holder := vpToken.Issuer()
+	doc := v.checkDIDResolution(ctx, &report, holder, "verifying JWT presentation")
+	if doc != nil {
+		// the presentation has no expiry check of its own, so its JWT claims are validated along with its signature
+		verifier, err := jwtVerifier(holder, headers.KeyID(), *doc)
+		if err == nil {
+			err = verifier.Verify(token.String())
+		}
+		if err != nil {
+			report.fail(CheckSignature, CodeSignatureInvalid, errors.Wrap(err, "verifying JWT presentation"))
+		} else {
+			report.pass(CheckSignature)
+		}
+	}
+
+	for _, presented := range pres.VerifiableCredential {
+		container, err := presentedCredentialContainer(presented)
+		if err != nil {
+			credentialReport := Report{}
+			credentialReport.fail(CheckSignature, CodeMalformed, err)
+			credentialReport.skip("credential could not be parsed", append([]Check{CheckDIDResolution}, credentialChecks...)...)
+			report.Credentials = append(report.Credentials, *credentialReport.finish())
+			continue
+		}
+		report.Credentials = append(report.Credentials, *v.VerifyCredentialWithReport(ctx, *container, opts))
+	}
+
+	if opts.PresentationDefinition == nil {
+		report.skip("no presentation definition was given", CheckPresentationDefinition)
+	} else if _, err = exchange.VerifyPresentationSubmissionVP(*opts.PresentationDefinition, *pres); err != nil {
+		report.fail(CheckPresentationDefinition, CodeDefinitionNotSatisfied, errors.Wrapf(err, "presentation does not satisfy presentation definition<%s>", opts.PresentationDefinition.ID))
+	} else {
+		report.pass(CheckPresentationDefinition)
+	}
+	return report.finish()
+}
+
+// VerifySDJWTPresentationWithReport runs each check on an SD-JWT presented by a holder, and reports on their outcome.
+// The holder is the credential's subject, whose DID is resolved, and the signature check verifies the key binding JWT,
+// which must be signed by one of the holder's verification methods, be for the audience and nonce, and be recent. The
+// credential's checks are reported as the presentation's only credential, except for the schema check, since holders
+// may withhold claims required by the schema. The single credential of the presentation must satisfy every input
+// descriptor of the presentation definition.
+func (v Verifier) VerifySDJWTPresentationWithReport(ctx context.Context, presentation keyaccess.SDJWT, audience, nonce string, opts Options) *Report {
+	var report Report
+	credentialOpts := opts
+	credentialOpts.selectivelyDisclosed = true
+	report.Credentials = []Report{*v.VerifyCredentialWithReport(ctx, credential.Container{SDJWT: &presentation}, credentialOpts)}
+
+	cred, err := presentation.DisclosedCredential()
+	if err != nil {
+		report.fail(CheckSignature, CodeMalformed, errors.Wrap(err, "verifying SD-JWT presentation: processing disclosures"))
+		report.skip("presentation could not be parsed", CheckDIDResolution, CheckPresentationDefinition)
+		return report.finish()
+	}
+
+	holder := cred.CredentialSubject.GetID()
+	if holder == "" {
+		report.fail(CheckSignature, CodeMalformed, errors.New("verifying SD-JWT presentation: credential has no subject to bind the presentation to"))
+		report.skip("presentation has no holder", CheckDIDResolution)
+	} else if doc := v.checkDIDResolution(ctx, &report, holder, "verifying SD-JWT presentation"); doc != nil {
+		if err = verifyKeyBinding(presentation, holder, audience, nonce, *doc); err != nil {
+			report.fail(CheckSignature, CodeSignatureInvalid, errors.Wrap(err, "verifying SD-JWT presentation"))
+		} else {
+			report.pass(CheckSignature)
+		}
+	}
+
+	if opts.PresentationDefinition == nil {
+		report.skip("no presentation definition was given", CheckPresentationDefinition)
+	} else if err = verifySDJWTSubmission(*opts.PresentationDefinition, *cred); err != nil {
+		report.fail(CheckPresentationDefinition, CodeDefinitionNotSatisfied, errors.Wrapf(err, "presentation does not satisfy presentation definition<%s>", opts.PresentationDefinition.ID))
+	} else {
+		report.pass(CheckPresentationDefinition)
+	}
+	return report.finish()
+}
+
+// verifyKeyBinding verifies the key binding JWT of an SD-JWT presentation with the key of the verification method in
+// the holder's DID document.
+func verifyKeyBinding(presentation keyaccess.SDJWT, holder, audience, nonce string, doc didsdk.Document) error {
+	parts, err := presentation.Parse()
+	if err != nil {
+		return errors.Wrap(err, "parsing SD-JWT presentation")
+	}
+	if parts.KeyBindingJWT == nil {
+		return errors.New("SD-JWT presentation has no key binding JWT")
+	}
+	headers, err := keyaccess.GetJWTHeaders([]byte(parts.KeyBindingJWT.String()))
+	if err != nil {
+		return errors.Wrap(err, "getting key binding JWT headers")
+	}
+	if headers.KeyID() == "" {
+		return errors.New("missing kid in key binding JWT header")
+	}
+	pubKey, err := didsdk.GetKeyFromVerificationMethod(doc, headers.KeyID())
+	if err != nil {
+		return errors.Wrapf(err, "getting verification information from DID Document: %s", holder)
+	}
+	holderVerifier, err := keyaccess.NewJWKKeyAccessVerifier(holder, headers.KeyID(), pubKey)
+	if err != nil {
+		return errors.Wrapf(err, "could not create validator for kid %s", headers.KeyID())
+	}
+	return holderVerifier.VerifySDJWTKeyBinding(presentation, audience, nonce)
+}
+
+// verifySDJWTSubmission checks that the credential of an SD-JWT presentation satisfies each input descriptor of the
+// definition. An SD-JWT presentation has no presentation submission of its own, so the credential is submitted for
+// every input descriptor.
+func verifySDJWTSubmission(def exchange.PresentationDefinition, cred credsdk.VerifiableCredential) error {
+	credJSON, err := sdkutil.ToJSONMap(cred)
+	if err != nil {
+		return errors.Wrap(err, "marshalling disclosed credential")
+	}
+	submission := exchange.PresentationSubmission{ID: cred.ID, DefinitionID: def.ID}
+	for _, inputDescriptor := range def.InputDescriptors {
+		submission.DescriptorMap = append(submission.DescriptorMap, exchange.SubmissionDescriptor{
+			ID:     inputDescriptor.ID,
+			Format: string(exchange.JWTVC),
+			Path:   "$.verifiableCredential[0]",
+		})
+	}
+	vp := credsdk.VerifiablePresentation{
+		Context:                []string{credsdk.VerifiableCredentialsLinkedDataContext},
+		Type:                   []string{credsdk.VerifiablePresentationType},
+		VerifiableCredential:   []any{credJSON},
+		PresentationSubmission: submission,
+	}
+	_, err = exchange.VerifyPresentationSubmissionVP(def, vp)
+	return err
+}
+
+// presentedCredentialContainer returns a container for a credential in a presentation, which is verified as is.
+func presentedCredentialContainer(presented any) (*credential.Container, error) {
+	switch v := presented.(type) {
+	case string:
+		if keyaccess.IsSDJWT(v) {
+			return &credential.Container{SDJWT: keyaccess.SDJWT(v).Ptr()}, nil
+		}
+		return &credential.Container{CredentialJWT: keyaccess.JWTPtr(v)}, nil
+	case map[string]any:
+		return credential.NewCredentialContainerFromMap(v)
+	default:
+		return nil, errors.Errorf("invalid credential type: %T", presented)
+	}
+}
+
+// jwtVerifier returns a verifier for JWTs signed with the key of the verification method in the signer's DID document.
+func jwtVerifier(did, kid string, doc didsdk.Document) (*jwx.Verifier, error) {
+	if kid == "" {
+		return nil, errors.New("missing kid in JWT header")
+	}
+	pubKey, err := didsdk.GetKeyFromVerificationMethod(doc, kid)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting verification information from DID Document: %s", did)
+	}
+	verifier, err := jwx.NewJWXVerifier(did, kid, pubKey)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not create validator for kid %s", kid)
+	}
+	return verifier, nil
+}
+
+// verifyJWTSignature verifies only the signature of a credential's JWT, since its validity period is reported on by
+// the expiry check.
+func verifyJWTSignature(token, did, kid string, doc didsdk.Document) error {
+	verifier, err := jwtVerifier(did, kid, doc)
+	if err != nil {
+		return err
+	}
+	return verifier.VerifyJWS(token)
+}
@@ -0,0 +1,166 @@
+package verification
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	credsdk "github.com/TBD54566975/ssi-sdk/credential"
+	"github.com/TBD54566975/ssi-sdk/credential/schema"
+	statussdk "github.com/TBD54566975/ssi-sdk/credential/status"
+	"github.com/TBD54566975/ssi-sdk/crypto"
+	"github.com/TBD54566975/ssi-sdk/did/key"
+	"github.com/TBD54566975/ssi-sdk/did/resolution"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tbd54566975/ssi-service/internal/credential"
+	"github.com/tbd54566975/ssi-service/internal/keyaccess"
+)
+
+type mapSchemaResolver map[string]*schema.JSONSchema
+
+func (m mapSchemaResolver) Resolve(_ context.Context, id string) (*schema.JSONSchema, schema.VCJSONSchemaType, error) {
+	if s, ok := m[id]; ok {
+		return s, schema.JSONSchemaType, nil
+	}
+	return nil, "", errors.Errorf("schema not found with id: %s", id)
+}
+
+func outcomes(report *Report) map[Check]CheckResult {
+	results := make(map[Check]CheckResult)
+	for _, result := range report.Checks {
+		results[result.Check] = result
+	}
+	return results
+}
+
+func TestVerifyCredentialWithReport(t *testing.T) {
+	resolver, err := resolution.NewResolver([]resolution.Resolver{key.Resolver{}}...)
+	require.NoError(t, err)
+	schemas := mapSchemaResolver{"name-schema": {
+		"$schema":  "https://json-schema.org/draft/2020-12/schema",
+		"type":     "object",
+		"required": []any{"credentialSubject"},
+		"properties": map[string]any{
+			"credentialSubject": map[string]any{
+				"type":       "object",
+				"required":   []any{"name"},
+				"properties": map[string]any{"name": map[string]any{"type": "string"}},
+			},
+		},
+	}}
+	privKey, didKey, err := key.GenerateDIDKey(crypto.Ed25519)
+	require.NoError(t, err)
+	revocations := "https://ssi.example.com/v1/credentials/status/revocations"
+	revocationList, err := statussdk.GenerateStatusList2021Credential(revocations, didKey.String(), statussdk.StatusRevocation,
+		[]credsdk.VerifiableCredential{credentialWithStatus("revoked", revocations, statussdk.StatusRevocation, "1")})
+	require.NoError(t, err)
+	verifier, err := NewVerifiableDataVerifier(resolver, schemas, mapStatusListStore{revocations: revocationList})
+	require.NoError(t, err)
+
+	expanded, err := didKey.Expand()
+	require.NoError(t, err)
+	ka, err := keyaccess.NewJWKKeyAccess(didKey.String(), expanded.VerificationMethod[0].ID, privKey)
+	require.NoError(t, err)
+
+	sign := func(tt *testing.T, update func(cred *credsdk.VerifiableCredential)) credential.Container {
+		cred := credsdk.VerifiableCredential{
+			Context:           []any{"https://www.w3.org/2018/credentials/v1"},
+			ID:                "test-credential",
+			Type:              []any{"VerifiableCredential"},
+			Issuer:            didKey.String(),
+			IssuanceDate:      time.Now().Format(time.RFC3339),
+			CredentialSubject: credsdk.CredentialSubject{"id": "did:example:subject", "name": "Satoshi"},
+		}
+		if update != nil {
+			update(&cred)
+		}
+		token, err := ka.SignVerifiableCredential(cred)
+		require.NoError(tt, err)
+		return credential.Container{CredentialJWT: token}
+	}
+
+	t.Run("passes every check that applies", func(tt *testing.T) {
+		container := sign(tt, func(cred *credsdk.VerifiableCredential) {
+			cred.CredentialSchema = &credsdk.CredentialSchema{ID: "name-schema", Type: schema.JSONSchemaType.String()}
+		})
+		report := verifier.VerifyCredentialWithReport(context.Background(), container, Options{TrustedIssuers: []string{didKey.String()}})
+		assert.True(tt, report.Verified)
+		assert.NoError(tt, report.Err())
+		assert.Equal(tt, "test-credential", report.CredentialID)
+
+		results := outcomes(report)
+		for _, check := range []Check{CheckDIDResolution, CheckSignature, CheckDataModel, CheckExpiry, CheckSchema, CheckIssuerTrust} {
+			assert.Equal(tt, OutcomePassed, results[check].Outcome, check)
+		}
+		assert.Equal(tt, OutcomeSkipped, results[CheckStatus].Outcome)
+	})
+
+	t.Run("reports every failed check", func(tt *testing.T) {
+		container := sign(tt, func(cred *credsdk.VerifiableCredential) {
+			cred.ExpirationDate = time.Now().Add(-time.Hour).Format(time.RFC3339)
+			cred.CredentialSchema = &credsdk.CredentialSchema{ID: "name-schema", Type: schema.JSONSchemaType.String()}
+			delete(cred.CredentialSubject, "name")
+		})
+		report := verifier.VerifyCredentialWithReport(context.Background(), container, Options{TrustedIssuers: []string{"did:example:other"}})
+		assert.False(tt, report.Verified)
+
+		results := outcomes(report)
+		assert.Equal(tt, OutcomePassed, results[CheckSignature].Outcome)
+		assert.Equal(tt, CodeCredentialExpired, results[CheckExpiry].Code)
+		assert.Equal(tt, CodeSchemaValidationFailed, results[CheckSchema].Code)
+		assert.Equal(tt, CodeIssuerUntrusted, results[CheckIssuerTrust].Code)
+
+		err := report.Err()
+		assert.ErrorContains(tt, err, "credential has expired")
+		assert.ErrorContains(tt, err, "was issued by untrusted issuer")
+	})
+
+	t.Run("skips the signature check when the DID isn't resolved", func(tt *testing.T) {
+		container := sign(tt, func(cred *credsdk.VerifiableCredential) {
+			cred.CredentialSchema = &credsdk.CredentialSchema{ID: "unknown-schema", Type: schema.JSONSchemaType.String()}
+		})
+		// a resolver without any methods can't resolve the issuer's DID
+		otherVerifier, err := NewVerifiableDataVerifier(resolution.MultiMethodResolver{}, schemas, nil)
+		require.NoError(tt, err)
+
+		report := otherVerifier.VerifyCredentialWithReport(context.Background(), container, Options{})
+		results := outcomes(report)
+		assert.Equal(tt, CodeDIDNotResolved, results[CheckDIDResolution].Code)
+		assert.Equal(tt, OutcomeSkipped, results[CheckSignature].Outcome)
+		assert.Equal(tt, CodeSchemaNotResolved, results[CheckSchema].Code)
+		assert.Equal(tt, OutcomePassed, results[CheckExpiry].Outcome)
+	})
+
+	t.Run("reports a malformed credential", func(tt *testing.T) {
+		report := verifier.VerifyCredentialWithReport(context.Background(), credential.Container{CredentialJWT: keyaccess.JWTPtr("bad")}, Options{})
+		assert.False(tt, report.Verified)
+		results := outcomes(report)
+		assert.Equal(tt, CodeMalformed, results[CheckSignature].Code)
+		for _, check := range credentialChecks {
+			assert.Equal(tt, OutcomeSkipped, results[check].Outcome, check)
+		}
+		assert.EqualError(tt, report.Err(), results[CheckSignature].Message)
+	})
+	t.Run("only unwraps to a status error when the status check is the only one that failed", func(tt *testing.T) {
+		revoke := func(cred *credsdk.VerifiableCredential) {
+			cred.CredentialStatus = credentialWithStatus("revoked", revocations, statussdk.StatusRevocation, "1").CredentialStatus
+		}
+		report := verifier.VerifyCredentialWithReport(context.Background(), sign(tt, revoke), Options{})
+		assert.True(tt, report.OnlyStatusFailed())
+		assert.Equal(tt, []StatusFailure{{CredentialID: "test-credential", Reason: "is revoked"}}, GetStatusFailures(report.Err()))
+
+		report = verifier.VerifyCredentialWithReport(context.Background(), sign(tt, func(cred *credsdk.VerifiableCredential) {
+			revoke(cred)
+			cred.ExpirationDate = time.Now().Add(-time.Hour).Format(time.RFC3339)
+		}), Options{})
+		assert.False(tt, report.OnlyStatusFailed())
+		assert.Len(tt, report.StatusFailures(), 1)
+		assert.Nil(tt, GetStatusFailures(report.Err()))
+
+		report = verifier.VerifyCredentialWithReport(context.Background(), sign(tt, nil), Options{})
+		assert.False(tt, report.OnlyStatusFailed())
+	})
+}
@@ -0,0 +1,417 @@
+package verification
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	credsdk "github.com/TBD54566975/ssi-sdk/credential"
+	"github.com/TBD54566975/ssi-sdk/credential/integrity"
+	statussdk "github.com/TBD54566975/ssi-sdk/credential/status"
+	"github.com/TBD54566975/ssi-sdk/did/resolution"
+	sdkutil "github.com/TBD54566975/ssi-sdk/util"
+	"github.com/goccy/go-json"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/tbd54566975/ssi-service/internal/credential"
+	"github.com/tbd54566975/ssi-service/internal/keyaccess"
+	"github.com/tbd54566975/ssi-service/internal/util"
+)
+
+const (
+	// statusListCacheTTL is how long a status list credential fetched over HTTP is used for before it's fetched again.
+	statusListCacheTTL = 5 * time.Minute
+	// statusListCacheMaxEntries bounds the number of cached status list credentials.
+	statusListCacheMaxEntries = 1000
+	// maxStatusListCredentialSize bounds the size of a status list credential fetched over HTTP.
+	maxStatusListCredentialSize = 1 << 20
+	// statusListFetchTimeout bounds how long fetching a status list credential over HTTP takes.
+	statusListFetchTimeout = 10 * time.Second
+)
+
+// StatusListCredentialStore looks up the status list credentials issued by the service.
+type StatusListCredentialStore interface {
+	// GetStatusListCredentialByURI returns the status list credential hosted at the URI, or nil when the URI isn't one
+	// of the service's status lists.
+	GetStatusListCredentialByURI(ctx context.Context, uri string) (*credsdk.VerifiableCredential, error)
+}
+
+// CredentialStatus is the status of a credential, as set in the status lists of its credentialStatus.
+type CredentialStatus struct {
+	Revoked   bool
+	Suspended bool
+	// The status value of a credential in a Bitstring Status List with the `message` purpose, along with its message.
+	StatusValue   int
+	StatusMessage string
+}
+
+// StatusFailure is why a credential didn't pass its status check.
+type StatusFailure struct {
+	CredentialID string `json:"credentialId"`
+	Reason       string `json:"reason"`
+}
+
+// StatusError is returned when credentials are revoked or suspended, or when their status couldn't be checked. It has
+// a failure for each of those credentials.
+type StatusError struct {
+	Failures []StatusFailure
+}
+
+func (e *StatusError) Error() string {
+	reasons := make([]string, 0, len(e.Failures))
+	for _, failure := range e.Failures {
+		reasons = append(reasons, fmt.Sprintf("credential<%s> %s", failure.CredentialID, failure.Reason))
+	}
+	return "credential status check failed: " + strings.Join(reasons, "; ")
+}
+
+// GetStatusFailures returns the status failures of an error, or nil when it isn't a StatusError.
+func GetStatusFailures(err error) []StatusFailure {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Failures
+	}
+	return nil
+}
+
+// VerifyCredentialStatus checks that none of the credentials are revoked or suspended. Credentials without a
+// credentialStatus pass. A StatusError is returned with a failure for each credential that doesn't pass.
+func (v Verifier) VerifyCredentialStatus(ctx context.Context, credentials ...credsdk.VerifiableCredential) error {
+	var failures []StatusFailure
+	for _, cred := range credentials {
+		if cred.CredentialStatus == nil {
+			continue
+		}
+		if failure, _ := v.getStatusFailure(ctx, cred); failure != nil {
+			failures = append(failures, *failure)
+		}
+	}
+	if len(failures) > 0 {
+		return &StatusError{Failures: failures}
+	}
+	return nil
+}
+
+// getStatusFailure returns the status failure of a credential with a credentialStatus, along with its error code, or
+// nil when the credential is neither revoked nor suspended.
+func (v Verifier) getStatusFailure(ctx context.Context, cred credsdk.VerifiableCredential) (*StatusFailure, ErrorCode) {
+	status, err := v.GetCredentialStatus(ctx, cred)
+	switch {
+	case err != nil:
+		return &StatusFailure{CredentialID: cred.ID, Reason: fmt.Sprintf("status could not be checked: %s", err)}, CodeStatusUnavailable
+	case status.Revoked:
+		return &StatusFailure{CredentialID: cred.ID, Reason: "is revoked"}, CodeCredentialRevoked
+	case status.Suspended:
+		return &StatusFailure{CredentialID: cred.ID, Reason: "is suspended"}, CodeCredentialSuspended
+	}
+	return nil, ""
+}
+
+// GetCredentialStatus dereferences the status list credential of each entry of the credential's status, and reads the
+// credential's status from it. Both StatusList2021 and Bitstring Status List entries are supported.
+func (v Verifier) GetCredentialStatus(ctx context.Context, cred credsdk.VerifiableCredential) (*CredentialStatus, error) {
+	entries, ok := cred.CredentialStatus.([]any)
+	if !ok {
+		entries = []any{cred.CredentialStatus}
+	}
+	var status CredentialStatus
+	for _, entry := range entries {
+		purpose, value, message, err := v.getStatusEntryValue(ctx, cred, entry)
+		if err != nil {
+			return nil, err
+		}
+		switch purpose {
+		case statussdk.StatusRevocation:
+			status.Revoked = status.Revoked || value != 0
+		case statussdk.StatusSuspension:
+			status.Suspended = status.Suspended || value != 0
+		case credential.StatusMessage:
+			status.StatusValue, status.StatusMessage = value, message
+		}
+	}
+	return &status, nil
+}
+
+// getStatusEntryValue returns the purpose of an entry of a credential's status, and the credential's status value in
+// the entry's status list.
+func (v Verifier) getStatusEntryValue(ctx context.Context, cred credsdk.VerifiableCredential, entry any) (statussdk.StatusPurpose, int, string, error) {
+	// each status list only reads the entry that is in it
+	credWithEntry := cred
+	credWithEntry.CredentialStatus = entry
+
+	if bitstringEntry, ok := credential.ToBitstringStatusListEntry(entry); ok {
+		statusListCredential, err := v.statusLists.get(ctx, bitstringEntry.StatusListCredential)
+		if err != nil {
+			return "", 0, "", err
+		}
+		if err = checkStatusListIssuer(cred, *statusListCredential); err != nil {
+			return "", 0, "", err
+		}
+		value, message, err := credential.GetBitstringStatus(credWithEntry, *statusListCredential)
+		if err != nil {
+			return "", 0, "", errors.Wrapf(err, "reading status list credential<%s>", bitstringEntry.StatusListCredential)
+		}
+		return bitstringEntry.StatusPurpose, value, message, nil
+	}
+
+	statusBytes, err := json.Marshal(entry)
+	if err != nil {
+		return "", 0, "", errors.Wrap(err, "marshalling credential status")
+	}
+	var statusListEntry statussdk.StatusList2021Entry
+	if err = json.Unmarshal(statusBytes, &statusListEntry); err != nil {
+		return "", 0, "", errors.Wrap(err, "unmarshalling credential status")
+	}
+	if statusListEntry.Type != statussdk.StatusList2021EntryType {
+		return "", 0, "", errors.Errorf("unsupported credential status type<%s>", statusListEntry.Type)
+	}
+	if err = sdkutil.IsValidStruct(statusListEntry); err != nil {
+		return "", 0, "", errors.Wrap(err, "invalid StatusList2021 credential status")
+	}
+	statusListCredential, err := v.statusLists.get(ctx, statusListEntry.StatusListCredential)
+	if err != nil {
+		return "", 0, "", err
+	}
+	if err = checkStatusListIssuer(cred, *statusListCredential); err != nil {
+		return "", 0, "", err
+	}
+	if err = credential.CheckStatusList2021Size(*statusListCredential); err != nil {
+		return "", 0, "", errors.Wrapf(err, "reading status list credential<%s>", statusListEntry.StatusListCredential)
+	}
+	credWithEntry.CredentialStatus = statusListEntry
+	set, err := statussdk.ValidateCredentialInStatusList(credWithEntry, *statusListCredential)
+	if err != nil {
+		return "", 0, "", errors.Wrapf(err, "reading status list credential<%s>", statusListEntry.StatusListCredential)
+	}
+	value := 0
+	if set {
+		value = 1
+	}
+	return statusListEntry.StatusPurpose, value, "", nil
+}
+
+// checkStatusListIssuer checks that a status list credential was issued by the issuer of the credential whose status
+// it has, so that no one else can revoke or suspend the credential.
+func checkStatusListIssuer(cred, statusListCredential credsdk.VerifiableCredential) error {
+	if statusListCredential.IssuerID() != cred.IssuerID() {
+		return errors.Errorf("status list credential<%s> was issued by issuer<%s>, not the credential's issuer<%s>",
+			statusListCredential.ID, statusListCredential.IssuerID(), cred.IssuerID())
+	}
+	return nil
+}
+
+// statusListResolver dereferences status list credentials. The service's own status lists are read from its
+// storage, so that changes to them apply right away. Others are fetched over HTTPS from public hosts, and cached.
+type statusListResolver struct {
+	store       StatusListCredentialStore
+	client      *http.Client
+	didResolver resolution.Resolver
+
+	// fetches makes concurrent misses for the same status list share one fetch
+	fetches singleflight.Group
+
+	mu    sync.Mutex
+	cache map[string]cachedStatusList
+}
+
+type cachedStatusList struct {
+	credential credsdk.VerifiableCredential
+	expiresAt  time.Time
+}
+
+func newStatusListResolver(store StatusListCredentialStore, didResolver resolution.Resolver) *statusListResolver {
+	return &statusListResolver{
+		store:       store,
+		client:      newStatusListHTTPClient(),
+		didResolver: didResolver,
+		cache:       make(map[string]cachedStatusList),
+	}
+}
+
+func (r *statusListResolver) get(ctx context.Context, uri string) (*credsdk.VerifiableCredential, error) {
+	if r.store != nil {
+		statusListCredential, err := r.store.GetStatusListCredentialByURI(ctx, uri)
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting status list credential<%s>", uri)
+		}
+		if statusListCredential != nil {
+			return statusListCredential, nil
+		}
+	}
+
+	now := time.Now()
+	r.mu.Lock()
+	cached, ok := r.cache[uri]
+	r.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		statusListCredential := cached.credential
+		return &statusListCredential, nil
+	}
+
+	// the shared fetch isn't cancelled with the caller that started it, since other callers may be waiting on it
+	fetches := r.fetches.DoChan(uri, func() (any, error) {
+		fetchCtx, cancel := context.WithTimeout(detachedContext{ctx}, statusListFetchTimeout)
+		defer cancel()
+		statusListCredential, err := r.fetch(fetchCtx, uri)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fetching status list credential<%s>", uri)
+		}
+		r.cacheStatusList(uri, *statusListCredential, now)
+		return *statusListCredential, nil
+	})
+	select {
+	case <-ctx.Done():
+		return nil, errors.Wrapf(ctx.Err(), "fetching status list credential<%s>", uri)
+	case fetched := <-fetches:
+		if fetched.Err != nil {
+			return nil, fetched.Err
+		}
+		statusListCredential := fetched.Val.(credsdk.VerifiableCredential)
+		return &statusListCredential, nil
+	}
+}
+
+// detachedContext keeps the values of a context, but not its deadline or cancellation.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (r *statusListResolver) cacheStatusList(uri string, statusListCredential credsdk.VerifiableCredential, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.cache) >= statusListCacheMaxEntries {
+		for cachedURI, entry := range r.cache {
+			if !now.Before(entry.expiresAt) {
+				delete(r.cache, cachedURI)
+			}
+		}
+	}
+	if len(r.cache) < statusListCacheMaxEntries {
+		r.cache[uri] = cachedStatusList{credential: statusListCredential, expiresAt: now.Add(statusListCacheTTL)}
+	}
+}
+
+// fetch gets a status list credential over HTTP, and verifies its proof. The credential can be a JWT, a credential
+// with an embedded proof, or a credential container like the ones this service hosts.
+func (r *statusListResolver) fetch(ctx context.Context, uri string) (*credsdk.VerifiableCredential, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating http request")
+	}
+	if httpReq.URL.Scheme != "https" {
+		return nil, errors.Errorf("unsupported status list credential URI scheme<%s>", httpReq.URL.Scheme)
+	}
+	httpReq.Header.Set("Accept", "application/vc+ld+json, application/vc+jwt, application/json")
+	httpResponse, err := r.client.Do(httpReq)
+	if err != nil {
+		return nil, errors.Wrap(err, "performing http request")
+	}
+	defer func() {
+		_ = httpResponse.Body.Close()
+	}()
+	if !util.Is2xxResponse(httpResponse.StatusCode) {
+		return nil, errors.Errorf("expected 2xx code, got %d", httpResponse.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(httpResponse.Body, maxStatusListCredentialSize))
+	if err != nil {
+		return nil, errors.Wrap(err, "reading response body")
+	}
+	body = bytes.TrimSpace(body)
+
+	if !bytes.HasPrefix(body, []byte("{")) {
+		return r.verifyJWT(ctx, keyaccess.JWT(body))
+	}
+	var container credential.Container
+	if err = json.Unmarshal(body, &container); err == nil && container.CredentialJWT != nil {
+		return r.verifyJWT(ctx, *container.CredentialJWT)
+	}
+	var statusListCredential credsdk.VerifiableCredential
+	if err = json.Unmarshal(body, &statusListCredential); err != nil {
+		return nil, errors.Wrap(err, "unmarshalling status list credential")
+	}
+	if statusListCredential.Proof == nil {
+		return nil, errors.New("status list credential has no proof")
+	}
+	if err = verifyDataIntegritySignature(ctx, r.didResolver, statusListCredential); err != nil {
+		return nil, errors.Wrap(err, "verifying status list credential")
+	}
+	return &statusListCredential, nil
+}
+
+func (r *statusListResolver) verifyJWT(ctx context.Context, token keyaccess.JWT) (*credsdk.VerifiableCredential, error) {
+	if _, err := integrity.VerifyJWTCredential(ctx, token.String(), r.didResolver); err != nil {
+		return nil, errors.Wrap(err, "verifying status list credential")
+	}
+	_, _, statusListCredential, err := integrity.ParseVerifiableCredentialFromJWT(token.String())
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing status list credential from jwt")
+	}
+	return statusListCredential, nil
+}
+
+// newStatusListHTTPClient returns a client that only connects to public addresses over HTTPS, so that credentials
+// can't make the service send requests to hosts on its own network.
+func newStatusListHTTPClient() *http.Client {
+	dialer := &net.Dialer{Timeout: statusListFetchTimeout, Control: rejectNonPublicAddress}
+	// there's no proxy, since the address that is dialed must be the status list's host for it to be checked
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   statusListFetchTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+	return &http.Client{
+		Transport: otelhttp.NewTransport(transport),
+		Timeout:   statusListFetchTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.URL.Scheme != "https" {
+				return errors.Errorf("unsupported redirect URI scheme<%s>", req.URL.Scheme)
+			}
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return nil
+		},
+	}
+}
+
+// rejectNonPublicAddress fails connections to loopback, private, link-local, multicast and unspecified addresses. It
+// checks the address after it's resolved, so hosts that resolve to those addresses are rejected too.
+func rejectNonPublicAddress(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return errors.Wrapf(err, "parsing address<%s>", address)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return errors.Errorf("address<%s> is not an IP address", address)
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return errors.Errorf("address<%s> is not public", address)
+	}
+	return nil
+}
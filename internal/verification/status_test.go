@@ -0,0 +1,198 @@
+package verification
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	credsdk "github.com/TBD54566975/ssi-sdk/credential"
+	statussdk "github.com/TBD54566975/ssi-sdk/credential/status"
+	"github.com/TBD54566975/ssi-sdk/crypto"
+	"github.com/TBD54566975/ssi-sdk/did/key"
+	"github.com/TBD54566975/ssi-sdk/did/resolution"
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tbd54566975/ssi-service/internal/keyaccess"
+)
+
+type mapStatusListStore map[string]*credsdk.VerifiableCredential
+
+func (m mapStatusListStore) GetStatusListCredentialByURI(_ context.Context, uri string) (*credsdk.VerifiableCredential, error) {
+	return m[uri], nil
+}
+
+func statusListCredential(t *testing.T, uri string, purpose statussdk.StatusPurpose, setIndices ...string) *credsdk.VerifiableCredential {
+	var setCredentials []credsdk.VerifiableCredential
+	for _, index := range setIndices {
+		setCredentials = append(setCredentials, credentialWithStatus("set-"+index, uri, purpose, index))
+	}
+	statusList, err := statussdk.GenerateStatusList2021Credential(uri, "did:example:issuer", purpose, setCredentials)
+	require.NoError(t, err)
+	return statusList
+}
+
+func credentialWithStatus(id, uri string, purpose statussdk.StatusPurpose, index string) credsdk.VerifiableCredential {
+	return credsdk.VerifiableCredential{
+		ID:     id,
+		Issuer: "did:example:issuer",
+		CredentialStatus: statussdk.StatusList2021Entry{
+			ID:                   id + "#status",
+			Type:                 statussdk.StatusList2021EntryType,
+			StatusPurpose:        purpose,
+			StatusListIndex:      index,
+			StatusListCredential: uri,
+		},
+	}
+}
+
+func TestVerifyCredentialStatus(t *testing.T) {
+	resolver, err := resolution.NewResolver([]resolution.Resolver{key.Resolver{}}...)
+	require.NoError(t, err)
+
+	t.Run("local status lists", func(tt *testing.T) {
+		revocations := "https://ssi.example.com/v1/credentials/status/revocations"
+		suspensions := "https://ssi.example.com/v1/credentials/status/suspensions"
+		store := mapStatusListStore{
+			revocations: statusListCredential(tt, revocations, statussdk.StatusRevocation, "1"),
+			suspensions: statusListCredential(tt, suspensions, statussdk.StatusSuspension, "2"),
+		}
+		verifier := Verifier{statusLists: newStatusListResolver(store, resolver)}
+
+		valid := credentialWithStatus("valid", revocations, statussdk.StatusRevocation, "0")
+		revoked := credentialWithStatus("revoked", revocations, statussdk.StatusRevocation, "1")
+		suspended := credentialWithStatus("suspended", suspensions, statussdk.StatusSuspension, "2")
+		noStatus := credsdk.VerifiableCredential{ID: "no-status"}
+
+		assert.NoError(tt, verifier.VerifyCredentialStatus(context.Background(), valid, noStatus))
+
+		err := verifier.VerifyCredentialStatus(context.Background(), valid, revoked, suspended)
+		assert.ErrorContains(tt, err, "credential status check failed")
+		assert.Equal(tt, []StatusFailure{
+			{CredentialID: "revoked", Reason: "is revoked"},
+			{CredentialID: "suspended", Reason: "is suspended"},
+		}, GetStatusFailures(err))
+
+		status, err := verifier.GetCredentialStatus(context.Background(), revoked)
+		assert.NoError(tt, err)
+		assert.True(tt, status.Revoked)
+		assert.False(tt, status.Suspended)
+	})
+
+	t.Run("remote status lists", func(tt *testing.T) {
+		privKey, didKey, err := key.GenerateDIDKey(crypto.Ed25519)
+		require.NoError(tt, err)
+		expanded, err := didKey.Expand()
+		require.NoError(tt, err)
+		ka, err := keyaccess.NewJWKKeyAccess(didKey.String(), expanded.VerificationMethod[0].ID, privKey)
+		require.NoError(tt, err)
+
+		var requests atomic.Int32
+		var server *httptest.Server
+		server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			uri := server.URL + r.URL.Path
+			switch r.URL.Path {
+			case "/slow", "/shared":
+				time.Sleep(50 * time.Millisecond)
+				fallthrough
+			case "/signed":
+				statusList := statusListCredential(tt, uri, statussdk.StatusRevocation, "3")
+				statusList.Issuer = didKey.String()
+				token, err := ka.SignVerifiableCredential(*statusList)
+				require.NoError(tt, err)
+				_, _ = w.Write([]byte(token.String()))
+			case "/unsigned":
+				statusList := statusListCredential(tt, uri, statussdk.StatusRevocation, "3")
+				statusListBytes, err := json.Marshal(statusList)
+				require.NoError(tt, err)
+				_, _ = w.Write(statusListBytes)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer server.Close()
+
+		// the test server is on the loopback address, which the resolver's own client doesn't connect to
+		statusLists := newStatusListResolver(mapStatusListStore{}, resolver)
+		statusLists.client = server.Client()
+		verifier := Verifier{statusLists: statusLists}
+		issuedBy := func(cred credsdk.VerifiableCredential, issuer string) credsdk.VerifiableCredential {
+			cred.Issuer = issuer
+			return cred
+		}
+
+		valid := issuedBy(credentialWithStatus("valid", server.URL+"/signed", statussdk.StatusRevocation, "2"), didKey.String())
+		revoked := issuedBy(credentialWithStatus("revoked", server.URL+"/signed", statussdk.StatusRevocation, "3"), didKey.String())
+		assert.NoError(tt, verifier.VerifyCredentialStatus(context.Background(), valid))
+		err = verifier.VerifyCredentialStatus(context.Background(), revoked)
+		assert.Equal(tt, []StatusFailure{{CredentialID: "revoked", Reason: "is revoked"}}, GetStatusFailures(err))
+
+		// the status list is only fetched once while it's cached
+		assert.Equal(tt, int32(1), requests.Load())
+
+		// status lists of other issuers can't revoke credentials
+		otherIssuer := credentialWithStatus("other-issuer", server.URL+"/signed", statussdk.StatusRevocation, "3")
+		failures := GetStatusFailures(verifier.VerifyCredentialStatus(context.Background(), otherIssuer))
+		require.Len(tt, failures, 1)
+		assert.Contains(tt, failures[0].Reason, "not the credential's issuer")
+
+		// concurrent misses share one fetch
+		slow := issuedBy(credentialWithStatus("slow", server.URL+"/slow", statussdk.StatusRevocation, "2"), didKey.String())
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(tt, verifier.VerifyCredentialStatus(context.Background(), slow))
+			}()
+		}
+		wg.Wait()
+		assert.Equal(tt, int32(2), requests.Load())
+
+		// a caller that gives up doesn't fail the others waiting on the same fetch
+		shared := issuedBy(credentialWithStatus("shared", server.URL+"/shared", statussdk.StatusRevocation, "2"), didKey.String())
+		cancelled, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		cancelledErr := make(chan error)
+		go func() {
+			cancelledErr <- verifier.VerifyCredentialStatus(cancelled, shared)
+		}()
+		time.Sleep(5 * time.Millisecond)
+		assert.NoError(tt, verifier.VerifyCredentialStatus(context.Background(), shared))
+		assert.Error(tt, <-cancelledErr)
+		assert.Equal(tt, int32(3), requests.Load())
+
+		unsigned := credentialWithStatus("unsigned", server.URL+"/unsigned", statussdk.StatusRevocation, "0")
+		missing := credentialWithStatus("missing", server.URL+"/missing", statussdk.StatusRevocation, "0")
+		failures = GetStatusFailures(verifier.VerifyCredentialStatus(context.Background(), unsigned, missing))
+		require.Len(tt, failures, 2)
+		assert.Contains(tt, failures[0].Reason, "status list credential has no proof")
+		assert.Contains(tt, failures[1].Reason, "expected 2xx code, got 404")
+	})
+}
+
+func TestStatusListHTTPClient(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+	statusLists := newStatusListResolver(nil, nil)
+
+	_, err := statusLists.fetch(context.Background(), "http://example.com/status/1")
+	assert.ErrorContains(t, err, "unsupported status list credential URI scheme<http>")
+
+	// hosts on the service's own network are never fetched from
+	_, err = statusLists.fetch(context.Background(), server.URL+"/status/1")
+	assert.ErrorContains(t, err, "is not public")
+
+	for _, address := range []string{"127.0.0.1:443", "[::1]:443", "10.0.0.1:443", "192.168.1.1:443", "169.254.169.254:80", "[fe80::1]:443", "0.0.0.0:443"} {
+		assert.Error(t, rejectNonPublicAddress("tcp", address, nil), address)
+	}
+	assert.NoError(t, rejectNonPublicAddress("tcp", "93.184.216.34:443", nil))
+}
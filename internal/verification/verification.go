@@ -8,15 +8,13 @@ import (
 	"github.com/TBD54566975/ssi-sdk/credential/integrity"
 	"github.com/TBD54566975/ssi-sdk/credential/validation"
 	"github.com/TBD54566975/ssi-sdk/crypto"
-	"github.com/TBD54566975/ssi-sdk/crypto/jwx"
-	"github.com/TBD54566975/ssi-sdk/cryptosuite/jws2020"
+	didsdk "github.com/TBD54566975/ssi-sdk/did"
 	"github.com/TBD54566975/ssi-sdk/did/resolution"
 	sdkutil "github.com/TBD54566975/ssi-sdk/util"
 	"github.com/goccy/go-json"
 	"github.com/pkg/errors"
 
 	"github.com/tbd54566975/ssi-service/internal/credential"
-	didint "github.com/tbd54566975/ssi-service/internal/did"
 	"github.com/tbd54566975/ssi-service/internal/keyaccess"
 	"github.com/tbd54566975/ssi-service/internal/schema"
 )
@@ -25,11 +23,13 @@ type Verifier struct {
 	validator      *validation.CredentialValidator
 	didResolver    resolution.Resolver
 	schemaResolver schema.Resolution
+	statusLists    *statusListResolver
 }
 
 // NewVerifiableDataVerifier creates a new verifier for both verifiable credentials and verifiable presentations. The verifier
-// executes both signature and static verification checks. In the future the set of verification checks will be configurable.
-func NewVerifiableDataVerifier(didResolver resolution.Resolver, schemaResolver schema.Resolution) (*Verifier, error) {
+// executes signature, static verification and status checks. In the future the set of verification checks will be configurable.
+// The status lists in the store are read from it, and any others are fetched over HTTP. The store may be nil.
+func NewVerifiableDataVerifier(didResolver resolution.Resolver, schemaResolver schema.Resolution, statusListStore StatusListCredentialStore) (*Verifier, error) {
 	if didResolver == nil {
 		return nil, errors.New("didResolver cannot be nil")
 	}
@@ -46,105 +46,137 @@ func NewVerifiableDataVerifier(didResolver resolution.Resolver, schemaResolver s
 		validator:      validator,
 		didResolver:    didResolver,
 		schemaResolver: schemaResolver,
+		statusLists:    newStatusListResolver(statusListStore, didResolver),
 	}, nil
 }
 
-// VerifyCredential first parses and checks the signature on the given credential. Next, it runs
-// a set of static verification checks on the credential as per the service's configuration.
-// Works for both JWT and LD securing mechanisms.
+// VerifyCredential runs each of the checks of VerifyCredentialWithReport on the credential, and returns a ReportError
+// with the checks that failed. Works for JWT, SD-JWT and LD securing mechanisms.
 func (v Verifier) VerifyCredential(ctx context.Context, credential credential.Container) error {
-	if credential.HasJWTCredential() {
-		err := v.VerifyJWTCredential(ctx, *credential.CredentialJWT)
-		if err != nil {
-			return err
-		}
-	} else {
-		if err := v.VerifyDataIntegrityCredential(ctx, *credential.Credential); err != nil {
-			return err
-		}
-	}
-	return nil
+	return v.VerifyCredentialWithReport(ctx, credential, Options{}).Err()
 }
 
 // VerifyJWTCredential first parses and checks the signature on the given JWT verification. Next, it runs
-// a set of static verification checks on the credential as per the service's configuration.
+// a set of static verification checks on the credential as per the service's configuration, and checks its status.
 func (v Verifier) VerifyJWTCredential(ctx context.Context, token keyaccess.JWT) error {
-	_, err := integrity.VerifyJWTCredential(ctx, token.String(), v.didResolver)
+	return v.VerifyCredential(ctx, credential.Container{CredentialJWT: &token})
+}
+
+// VerifyDataIntegrityCredential first checks the signature on the given data integrity verification. Next, it runs
+// a set of static verification checks on the credential as per the service's configuration, and checks its status.
+func (v Verifier) VerifyDataIntegrityCredential(ctx context.Context, cred credsdk.VerifiableCredential) error {
+	return v.VerifyCredential(ctx, credential.Container{Credential: &cred})
+}
+
+// verifyDataIntegritySignature checks the signature on a data integrity credential with the key of its issuer.
+func verifyDataIntegritySignature(ctx context.Context, didResolver resolution.Resolver, credential credsdk.VerifiableCredential) error {
+	issuer, verificationMethod, err := dataIntegrityVerificationMethod(credential)
 	if err != nil {
-		return errors.Wrap(err, "verifying JWT credential")
+		return err
 	}
-	_, _, cred, err := integrity.ParseVerifiableCredentialFromJWT(token.String())
+	resolved, err := didResolver.Resolve(ctx, issuer)
 	if err != nil {
-		return errors.Wrap(err, "parsing vc from jwt")
+		return sdkutil.LoggingErrorMsgf(err, "resolving DID: %s", issuer)
 	}
-	return v.staticValidationChecks(ctx, *cred)
+	return verifyDataIntegrityProof(credential, issuer, verificationMethod, resolved.Document)
 }
 
-// VerifyDataIntegrityCredential first checks the signature on the given data integrity verification. Next, it runs
-// a set of static verification checks on the credential as per the service's configuration.
-func (v Verifier) VerifyDataIntegrityCredential(ctx context.Context, credential credsdk.VerifiableCredential) error {
-	// resolve the issuer's key material
+// dataIntegrityVerificationMethod returns the issuer of a data integrity credential, and the verification method of
+// its proof.
+func dataIntegrityVerificationMethod(credential credsdk.VerifiableCredential) (issuer, verificationMethod string, err error) {
+	if credential.Proof == nil {
+		return "", "", sdkutil.LoggingNewError("credential has no proof")
+	}
 	issuer, ok := credential.Issuer.(string)
 	if !ok {
-		return sdkutil.LoggingNewErrorf("could not convert issuer to string: %v", credential.Issuer)
+		return "", "", sdkutil.LoggingNewErrorf("could not convert issuer to string: %v", credential.Issuer)
 	}
 
 	maybeVerificationMethod, err := getKeyFromProof(*credential.Proof, "verificationMethod")
 	if err != nil {
-		return sdkutil.LoggingErrorMsg(err, "could not get verification method from proof")
+		return "", "", sdkutil.LoggingErrorMsg(err, "could not get verification method from proof")
 	}
-	verificationMethod, ok := maybeVerificationMethod.(string)
+	verificationMethod, ok = maybeVerificationMethod.(string)
 	if !ok {
-		return sdkutil.LoggingNewErrorf("could not convert verification method to string: %v", maybeVerificationMethod)
+		return "", "", sdkutil.LoggingNewErrorf("could not convert verification method to string: %v", maybeVerificationMethod)
 	}
+	return issuer, verificationMethod, nil
+}
 
-	pubKey, err := didint.ResolveKeyForDID(ctx, v.didResolver, issuer, verificationMethod)
+// verifyDataIntegrityProof checks the proof of a data integrity credential with the key of the verification method in
+// the issuer's DID document.
+func verifyDataIntegrityProof(credential credsdk.VerifiableCredential, issuer, verificationMethod string, doc didsdk.Document) error {
+	pubKey, err := didsdk.GetKeyFromVerificationMethod(doc, verificationMethod)
 	if err != nil {
-		return sdkutil.LoggingError(err)
+		return sdkutil.LoggingErrorMsgf(err, "getting verification information from DID Document: %s", issuer)
 	}
 
-	// construct a signature validator from the verification information
-	publicKeyJWK, err := jwx.PublicKeyToPublicKeyJWK(verificationMethod, pubKey)
+	// construct a signature validator for the proof's type from the verification information
+	proofType, err := keyaccess.DataIntegrityProofTypeFromProof(*credential.Proof)
 	if err != nil {
-		return sdkutil.LoggingErrorMsgf(err, "could not convert private key to JWK: %s", verificationMethod)
+		return sdkutil.LoggingErrorMsg(err, "could not determine the credential's proof type")
 	}
-	verifier, err := jws2020.NewJSONWebKeyVerifier(issuer, *publicKeyJWK)
+	verifier, err := keyaccess.NewDataIntegrityKeyAccessVerifier(issuer, verificationMethod, pubKey, proofType)
 	if err != nil {
 		errMsg := fmt.Sprintf("could not create validator for kid %s", verificationMethod)
 		return sdkutil.LoggingErrorMsg(err, errMsg)
 	}
 
-	cryptoSuite := jws2020.GetJSONWebSignature2020Suite()
 	// verify the signature on the credential
-	if err = cryptoSuite.Verify(verifier, &credential); err != nil {
+	if err = verifier.Verify(&credential); err != nil {
 		return sdkutil.LoggingErrorMsg(err, "could not verify the credential's signature")
 	}
-
-	return v.staticValidationChecks(ctx, credential)
+	return nil
 }
 
-// VerifyJWTPresentation first parses and checks the signature on the given JWT presentation. Next, it runs
-// a set of static verification checks on the presentation's credentials as per the service's configuration.
-func (v Verifier) VerifyJWTPresentation(ctx context.Context, token keyaccess.JWT) error {
-	_, err := integrity.VerifyJWTPresentation(ctx, token.String(), v.didResolver)
+// VerifySDJWTCredential checks the issuer's signature on the given SD-JWT and that each of its disclosures was
+// signed by the issuer. Next, it runs a set of static verification checks on the credential with the disclosed
+// claims, as per the service's configuration, and checks its status. The disclosed credential is returned.
+func (v Verifier) VerifySDJWTCredential(ctx context.Context, sdJWT keyaccess.SDJWT) (*credsdk.VerifiableCredential, error) {
+	cred, err := v.verifySDJWT(ctx, sdJWT)
 	if err != nil {
-		return errors.Wrap(err, "verifying JWT presentation")
+		return nil, err
 	}
-	_, _, pres, err := integrity.ParseVerifiablePresentationFromJWT(token.String())
-	if err != nil {
-		return errors.Wrap(err, "parsing vc from jwt")
+	if err = v.staticValidationChecks(ctx, *cred); err != nil {
+		return nil, err
 	}
-	// for each credential in the presentation, run a set of static verification checks
-	creds, err := credential.NewCredentialContainerFromArray(pres.VerifiableCredential)
+	if err = v.VerifyCredentialStatus(ctx, *cred); err != nil {
+		return nil, err
+	}
+	return cred, nil
+}
+
+// VerifySDJWTPresentation runs each of the checks of VerifySDJWTPresentationWithReport on an SD-JWT presented by a
+// holder, and returns a ReportError with the checks that failed. Otherwise, the credential with the disclosed claims
+// is returned.
+func (v Verifier) VerifySDJWTPresentation(ctx context.Context, presentation keyaccess.SDJWT, audience, nonce string) (*credsdk.VerifiableCredential, error) {
+	if err := v.VerifySDJWTPresentationWithReport(ctx, presentation, audience, nonce, Options{}).Err(); err != nil {
+		return nil, err
+	}
+	return presentation.DisclosedCredential()
+}
+
+// verifySDJWT checks the issuer's signature on the SD-JWT and returns the credential with the disclosed claims.
+func (v Verifier) verifySDJWT(ctx context.Context, sdJWT keyaccess.SDJWT) (*credsdk.VerifiableCredential, error) {
+	parts, err := sdJWT.Parse()
 	if err != nil {
-		return errors.Wrapf(err, "error parsing credentials in presentation<%s>", pres.ID)
+		return nil, errors.Wrap(err, "parsing SD-JWT")
 	}
-	for _, cred := range creds {
-		if err = v.staticValidationChecks(ctx, *cred.Credential); err != nil {
-			return errors.Wrapf(err, "error running static validation checks on credential in presentation<%v>", cred.ID)
-		}
+	if _, err = integrity.VerifyJWTCredential(ctx, parts.IssuerJWT.String(), v.didResolver); err != nil {
+		return nil, errors.Wrap(err, "verifying SD-JWT credential")
 	}
-	return nil
+	cred, err := sdJWT.DisclosedCredential()
+	if err != nil {
+		return nil, errors.Wrap(err, "processing SD-JWT disclosures")
+	}
+	return cred, nil
+}
+
+// VerifyJWTPresentation runs each of the checks of VerifyJWTPresentationWithReport on the presentation and its
+// credentials, and returns a ReportError with the checks that failed. Its StatusError has a failure for each credential
+// whose status check didn't pass.
+func (v Verifier) VerifyJWTPresentation(ctx context.Context, token keyaccess.JWT) error {
+	return v.VerifyJWTPresentationWithReport(ctx, token, Options{}).Err()
 }
 
 func getKeyFromProof(proof crypto.Proof, key string) (any, error) {
@@ -3,6 +3,8 @@ package router
 import (
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	credsdk "github.com/TBD54566975/ssi-sdk/credential"
 	"github.com/TBD54566975/ssi-sdk/did"
@@ -10,6 +12,7 @@ import (
 	"github.com/pkg/errors"
 	credmodel "github.com/tbd54566975/ssi-service/internal/credential"
 	"github.com/tbd54566975/ssi-service/internal/keyaccess"
+	"github.com/tbd54566975/ssi-service/internal/verification"
 	"github.com/tbd54566975/ssi-service/pkg/server/framework"
 	"github.com/tbd54566975/ssi-service/pkg/server/pagination"
 	"github.com/tbd54566975/ssi-service/pkg/service/credential"
@@ -21,6 +24,7 @@ const (
 	IssuerParam  string = "issuer"
 	SubjectParam string = "subject"
 	SchemaParam  string = "schema"
+	ExpiredParam string = "expired"
 )
 
 type CredentialRouter struct {
@@ -126,12 +130,34 @@ type CreateCredentialRequest struct {
 	Revocable bool `json:"revocable,omitempty" example:"true"`
 
 	// Whether this credential can be suspended. When true, the created VC will have the "credentialStatus"
-	// property set.
+	// property set. A credential that is both revocable and suspendable has an array of two "credentialStatus"
+	// entries, one in a revocation status list and one in a suspension status list.
 	Suspendable bool `json:"suspendable,omitempty" example:"false"`
 
 	// Optional. Corresponds to `evidence` in https://www.w3.org/TR/vc-data-model-2.0/#evidence
 	Evidence []any `json:"evidence" example:"[{\"id\":\"https://example.edu/evidence/f2aeec97-fc0d-42bf-8ca7-0548192d4231\",\"type\":[\"DocumentVerification\"]}]"`
-	// TODO(gabe) support more capabilities like signature type, format, and more.
+
+	// Optional. How the credential is secured. One of `jwt` (the default), which returns the credential as a VC-JWT in
+	// `credentialJwt`; `sd-jwt`, which returns the credential as an SD-JWT with its disclosures in `sdJwt`; or
+	// `JsonWebSignature2020`, `Ed25519Signature2020` or `eddsa-2022`, which return the credential with an embedded Data
+	// Integrity proof in `credential`. The Ed25519 based formats require an Ed25519 key. When an embedded proof is used,
+	// every property of `data` must be defined by the credential's `@context`, and only well known contexts that are
+	// bundled with the service can be used.
+	ProofFormat credential.ProofFormat `json:"proofFormat,omitempty" example:"jwt"`
+
+	// Optional. The properties of `data` that holders can selectively disclose. Requires the `sd-jwt` proof format.
+	Disclosable []string `json:"disclosable,omitempty" example:"alumniOf"`
+
+	// Optional. The kind of status list that tracks the credential's status. One of `StatusList2021` or
+	// `BitstringStatusList`. Defaults to `services.credentials.status_list_format`, or `StatusList2021` when that is
+	// not set.
+	StatusListFormat credential.StatusListFormat `json:"statusListFormat,omitempty" example:"BitstringStatusList"`
+
+	// Optional. When present, the credential gets a multi-bit status with the `message` purpose, and each status
+	// value maps to the message at the same position. There must be a power of two entries, with statuses `0x0`,
+	// `0x1`, and so on. Requires the `BitstringStatusList` format, and cannot be combined with `revocable` or
+	// `suspendable`.
+	StatusMessage []credmodel.StatusMessageEntry `json:"statusMessage,omitempty"`
 }
 
 func (c CreateCredentialRequest) toServiceRequest() credential.CreateCredentialRequest {
@@ -147,6 +173,10 @@ func (c CreateCredentialRequest) toServiceRequest() credential.CreateCredentialR
 		Revocable:                          c.Revocable,
 		Suspendable:                        c.Suspendable,
 		Evidence:                           c.Evidence,
+		ProofFormat:                        c.ProofFormat,
+		Disclosable:                        c.Disclosable,
+		StatusListFormat:                   c.StatusListFormat,
+		StatusMessage:                      c.StatusMessage,
 	}
 }
 
@@ -179,6 +209,24 @@ func (cr CredentialRouter) CreateCredential(c *gin.Context) {
 		return
 	}
 
+	if !request.ProofFormat.IsSupported() {
+		errMsg := fmt.Sprintf("%s: unsupported proof format<%s>", invalidCreateCredentialRequest, request.ProofFormat)
+		framework.LoggingRespondErrMsg(c, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	if len(request.Disclosable) > 0 && request.ProofFormat != credential.SDJWTProofFormat {
+		errMsg := fmt.Sprintf("%s: disclosable claims require the %s proof format", invalidCreateCredentialRequest, credential.SDJWTProofFormat)
+		framework.LoggingRespondErrMsg(c, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	if !request.StatusListFormat.IsSupported() {
+		errMsg := fmt.Sprintf("%s: unsupported status list format<%s>", invalidCreateCredentialRequest, request.StatusListFormat)
+		framework.LoggingRespondErrMsg(c, errMsg, http.StatusBadRequest)
+		return
+	}
+
 	req := request.toServiceRequest()
 	createCredentialResponse, err := cr.service.CreateCredential(c, req)
 	if err != nil {
@@ -236,6 +284,10 @@ type GetCredentialStatusResponse struct {
 	Revoked bool `json:"revoked"`
 	// Whether the credential has been suspended.
 	Suspended bool `json:"suspended"`
+	// The value of the credential's multi-bit status, when it has a `message` status.
+	StatusValue int `json:"statusValue,omitempty"`
+	// The message the credential's `statusMessage` maps the status value to.
+	StatusMessage string `json:"statusMessage,omitempty"`
 }
 
 // GetCredentialStatus godoc
@@ -266,8 +318,10 @@ func (cr CredentialRouter) GetCredentialStatus(c *gin.Context) {
 	}
 
 	resp := GetCredentialStatusResponse{
-		Revoked:   getCredentialStatusResponse.Revoked,
-		Suspended: getCredentialStatusResponse.Suspended,
+		Revoked:       getCredentialStatusResponse.Revoked,
+		Suspended:     getCredentialStatusResponse.Suspended,
+		StatusValue:   getCredentialStatusResponse.StatusValue,
+		StatusMessage: getCredentialStatusResponse.StatusMessage,
 	}
 
 	framework.Respond(c, resp, http.StatusOK)
@@ -275,7 +329,8 @@ func (cr CredentialRouter) GetCredentialStatus(c *gin.Context) {
 
 type GetCredentialStatusListResponse struct {
 	ID string `json:"id"`
-	// Credential where type includes "VerifiableCredential" and "StatusList2021".
+	// Credential where type includes "VerifiableCredential" and either "StatusList2021Credential" or
+	// "BitstringStatusListCredential".
 	Credential *credsdk.VerifiableCredential `json:"credential,omitempty"`
 
 	// The JWT signed with the associated issuer's private key.
@@ -318,25 +373,68 @@ func (cr CredentialRouter) GetCredentialStatusList(c *gin.Context) {
 	framework.Respond(c, resp, http.StatusOK)
 }
 
+type GetStatusListUsageResponse struct {
+	// The status list credentials of the matching issuers and schemas, with how many of their indexes are in use.
+	StatusLists []credential.StatusListUsage `json:"statusLists"`
+}
+
+// GetStatusListUsage godoc
+//
+//	@Summary		Get status list usage
+//	@Description	Reports how many of the indexes of each status list credential are in use. Once a status list is
+//	@Description	full, a new one is created for new credentials of the same issuer, schema and status purpose.
+//	@Tags			Credentials
+//	@Accept			json
+//	@Produce		json
+//	@Param			issuer	query		string	false	"The issuer id to filter by"
+//	@Param			schema	query		string	false	"The credentialSchema.id value to filter by"
+//	@Success		200		{object}	GetStatusListUsageResponse
+//	@Failure		500		{string}	string	"Internal server error"
+//	@Router			/v1/credentials/status/usage [get]
+func (cr CredentialRouter) GetStatusListUsage(c *gin.Context) {
+	var request credential.GetStatusListUsageRequest
+	if issuer := framework.GetQueryValue(c, IssuerParam); issuer != nil {
+		request.Issuer = *issuer
+	}
+	if schema := framework.GetQueryValue(c, SchemaParam); schema != nil {
+		request.SchemaID = *schema
+	}
+
+	usage, err := cr.service.GetStatusListUsage(c, request)
+	if err != nil {
+		errMsg := "could not get status list usage"
+		framework.LoggingRespondErrWithMsg(c, err, errMsg, http.StatusInternalServerError)
+		return
+	}
+
+	resp := GetStatusListUsageResponse{StatusLists: usage.StatusLists}
+	framework.Respond(c, resp, http.StatusOK)
+}
+
 type UpdateCredentialStatusRequest struct {
-	// The new revoked status of this credential. The status will be saved in the encodedList of the StatusList2021
-	// credential associated with this VC.
+	// The new revoked status of this credential. The status will be saved in the encodedList of the status list
+	// credential associated with this VC. Only a credential that is both revocable and suspendable can be revoked and
+	// suspended at the same time.
 	Revoked   bool `json:"revoked,omitempty"`
 	Suspended bool `json:"suspended,omitempty"`
+	// The new value of a multi-bit status. Only valid for credentials created with a `statusMessage`.
+	StatusValue *int `json:"statusValue,omitempty" example:"1"`
 }
 
 func (c UpdateCredentialStatusRequest) toServiceRequest(id string) credential.UpdateCredentialStatusRequest {
 	return credential.UpdateCredentialStatusRequest{
-		ID:        id,
-		Revoked:   c.Revoked,
-		Suspended: c.Suspended,
+		ID:          id,
+		Revoked:     c.Revoked,
+		Suspended:   c.Suspended,
+		StatusValue: c.StatusValue,
 	}
 }
 
 type UpdateCredentialStatusResponse struct {
 	// The updated status of this credential.
-	Revoked   bool `json:"revoked"`
-	Suspended bool `json:"suspended"`
+	Revoked     bool `json:"revoked"`
+	Suspended   bool `json:"suspended"`
+	StatusValue int  `json:"statusValue,omitempty"`
 }
 
 type SingleUpdateCredentialStatusRequest struct {
@@ -450,8 +548,9 @@ func (cr CredentialRouter) UpdateCredentialStatus(c *gin.Context) {
 	}
 
 	resp := UpdateCredentialStatusResponse{
-		Revoked:   gotCredential.Revoked,
-		Suspended: gotCredential.Suspended,
+		Revoked:     gotCredential.Revoked,
+		Suspended:   gotCredential.Suspended,
+		StatusValue: gotCredential.StatusValue,
 	}
 
 	framework.Respond(c, resp, http.StatusOK)
@@ -459,10 +558,14 @@ func (cr CredentialRouter) UpdateCredentialStatus(c *gin.Context) {
 
 type VerifyCredentialRequest struct {
 	// A credential secured via data integrity. Must have the "proof" property set.
-	DataIntegrityCredential *credsdk.VerifiableCredential `json:"credential,omitempty"`
+	// It's validated when it's verified, since the request validator can't dive into its credentialStatus.
+	DataIntegrityCredential *credsdk.VerifiableCredential `json:"credential,omitempty" validate:"-"`
 
 	// A JWT that encodes a credential.
 	CredentialJWT *keyaccess.JWT `json:"credentialJwt,omitempty"`
+
+	// DIDs trusted to issue the credential. When set, a credential from any other issuer fails the `issuerTrust` check.
+	TrustedIssuers []string `json:"trustedIssuers,omitempty"`
 }
 
 func (vcr VerifyCredentialRequest) IsValid() bool {
@@ -476,16 +579,26 @@ type VerifyCredentialResponse struct {
 
 	// The reason why this credential couldn't be verified.
 	Reason string `json:"reason,omitempty"`
+
+	// The credential's status failure, when it's revoked or suspended, or its status couldn't be checked.
+	StatusFailures []verification.StatusFailure `json:"statusFailures,omitempty"`
+
+	// Each check that was run on the credential, with its outcome, and an error code when it failed. Checks that don't
+	// apply, or that depend on a failed check, are skipped.
+	Report verification.Report `json:"report"`
 }
 
 // VerifyCredential godoc
 //
 //	@Summary		Verify a Verifiable Credential
-//	@Description	Verifies a given verifiable credential. The system does the following levels of verification:
-//	@Description	1. Makes sure the credential has a valid signature
-//	@Description	2. Makes sure the credential has is not expired
-//	@Description	3. Makes sure the credential complies with the VC Data Model v1.1
-//	@Description	4. If the credential has a schema, makes sure its data complies with the schema
+//	@Description	Verifies a given verifiable credential. The system runs the following checks, and reports on each of them:
+//	@Description	1. `didResolution`: resolves the DID of the credential's issuer
+//	@Description	2. `signature`: makes sure the credential has a valid signature
+//	@Description	3. `dataModel`: makes sure the credential complies with the VC Data Model v1.1
+//	@Description	4. `expiry`: makes sure the credential is not expired
+//	@Description	5. `schema`: if the credential has a schema, makes sure its data complies with the schema
+//	@Description	6. `status`: if the credential has a status, makes sure it's neither revoked nor suspended
+//	@Description	7. `issuerTrust`: if trusted issuers are given, makes sure the credential was issued by one of them
 //	@Tags			Credentials
 //	@Accept			json
 //	@Produce		json
@@ -511,6 +624,7 @@ func (cr CredentialRouter) VerifyCredential(c *gin.Context) {
 	verificationResult, err := cr.service.VerifyCredential(c, credential.VerifyCredentialRequest{
 		DataIntegrityCredential: request.DataIntegrityCredential,
 		CredentialJWT:           request.CredentialJWT,
+		TrustedIssuers:          request.TrustedIssuers,
 	})
 	if err != nil {
 		errMsg := "could not verify credential"
@@ -518,7 +632,12 @@ func (cr CredentialRouter) VerifyCredential(c *gin.Context) {
 		return
 	}
 
-	resp := VerifyCredentialResponse{Verified: verificationResult.Verified, Reason: verificationResult.Reason}
+	resp := VerifyCredentialResponse{
+		Verified:       verificationResult.Verified,
+		Reason:         verificationResult.Reason,
+		StatusFailures: verificationResult.StatusFailures,
+		Report:         verificationResult.Report,
+	}
 	framework.Respond(c, resp, http.StatusOK)
 }
 
@@ -534,20 +653,24 @@ type listCredentialsRequest struct {
 	issuer  *string
 	schema  *string
 	subject *string
+	expired *bool
 }
 
 func (l listCredentialsRequest) GetFilter() string {
-	filter := ""
+	var terms []string
 	if l.issuer != nil {
-		filter += fmt.Sprintf(`issuer="%s"`, *l.issuer)
+		terms = append(terms, fmt.Sprintf(`issuer="%s"`, *l.issuer))
 	}
 	if l.schema != nil {
-		filter += fmt.Sprintf(`schema="%s"`, *l.schema)
+		terms = append(terms, fmt.Sprintf(`schema="%s"`, *l.schema))
 	}
 	if l.subject != nil {
-		filter += fmt.Sprintf(`subject="%s"`, *l.subject)
+		terms = append(terms, fmt.Sprintf(`subject="%s"`, *l.subject))
+	}
+	if l.expired != nil {
+		terms = append(terms, fmt.Sprintf(`expired=%t`, *l.expired))
 	}
-	return filter
+	return strings.Join(terms, " AND ")
 }
 
 var listCredentialsFilterDeclarations *filtering.Declarations
@@ -564,10 +687,28 @@ func init() {
 				filtering.TypeString,
 				filtering.TypeString,
 			),
+			filtering.NewFunctionOverload(
+				filtering.FunctionOverloadEqualsBool,
+				filtering.TypeBool,
+				filtering.TypeBool,
+				filtering.TypeBool,
+			),
+		),
+		filtering.DeclareFunction(
+			filtering.FunctionAnd,
+			filtering.NewFunctionOverload(
+				filtering.FunctionOverloadAndBool,
+				filtering.TypeBool,
+				filtering.TypeBool,
+				filtering.TypeBool,
+			),
 		),
 		filtering.DeclareIdent("issuer", filtering.TypeString),
 		filtering.DeclareIdent("schema", filtering.TypeString),
 		filtering.DeclareIdent("subject", filtering.TypeString),
+		filtering.DeclareIdent(ExpiredParam, filtering.TypeBool),
+		filtering.DeclareIdent(True, filtering.TypeBool),
+		filtering.DeclareIdent(False, filtering.TypeBool),
 	)
 	if err != nil {
 		panic(err)
@@ -578,13 +719,15 @@ func init() {
 //
 //	@Summary		List Verifiable Credentials
 //	@Description	Checks for the presence of an optional query parameter and calls the associated filtered get method.
-//	@Description	Only one optional parameter is allowed to be specified.
+//	@Description	Only one of the issuer, schema, and subject query parameters is allowed to be specified. It can be combined
+//	@Description	with the expired query parameter.
 //	@Tags			Credentials
 //	@Accept			json
 //	@Produce		json
 //	@Param			issuer		query		string	false	"The issuer id, e.g. did:key:z6MkiTBz1ymuepAQ4HEHYSF1H8quG5GLVVQR3djdX3mDooWp"
 //	@Param			schema		query		string	false	"The credentialSchema.id value to filter by"
 //	@Param			subject		query		string	false	"The credentialSubject.id value to filter by"
+//	@Param			expired		query		boolean	false	"Whether to list only expired credentials (true) or only active ones (false)"
 //	@Param			pageSize	query		number	false	"Hint to the server of the maximum elements to return. More may be returned. When not set, the server will return all elements."
 //	@Param			pageToken	query		string	false	"Used to indicate to the server to return a specific page of the list results. Must match a previous requests' `nextPageToken`."
 //	@Success		200			{object}	ListCredentialsResponse
@@ -614,6 +757,15 @@ func (cr CredentialRouter) ListCredentials(c *gin.Context) {
 		schema:  schema,
 		subject: subject,
 	}
+	if expiredValue := framework.GetQueryValue(c, ExpiredParam); expiredValue != nil {
+		expired, err := strconv.ParseBool(*expiredValue)
+		if err != nil {
+			errMsg := fmt.Sprintf("invalid value for the %s query parameter: %s", ExpiredParam, *expiredValue)
+			framework.LoggingRespondErrMsg(c, errMsg, http.StatusBadRequest)
+			return
+		}
+		req.expired = &expired
+	}
 
 	filter, err := filtering.ParseFilter(req, listCredentialsFilterDeclarations)
 	if err != nil {
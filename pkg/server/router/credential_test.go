@@ -15,6 +15,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/tbd54566975/ssi-service/config"
+	credint "github.com/tbd54566975/ssi-service/internal/credential"
 	"github.com/tbd54566975/ssi-service/pkg/server/pagination"
 	"github.com/tbd54566975/ssi-service/pkg/service/credential"
 	"github.com/tbd54566975/ssi-service/pkg/service/did"
@@ -1029,11 +1030,11 @@ func TestCredentialRouter(t *testing.T) {
 				assert.Equal(tt, updatedStatus.Revoked, false)
 			})
 
-			t.Run("Create Suspendable and Revocable Credential Should Be Error", func(tt *testing.T) {
+			t.Run("Create Suspendable and Revocable Credential", func(tt *testing.T) {
 				issuer, verificationMethodID, schemaID, credService := createCredServicePrereqs(tt, test.ServiceStorage(tt))
 				subject := "did:test:345"
 
-				createdCred, err := credService.CreateCredential(context.Background(), credential.CreateCredentialRequest{
+				createRequest := credential.CreateCredentialRequest{
 					Issuer:                             issuer,
 					FullyQualifiedVerificationMethodID: verificationMethodID,
 					Subject:                            subject,
@@ -1044,10 +1045,103 @@ func TestCredentialRouter(t *testing.T) {
 					Expiry:      time.Now().Add(24 * time.Hour).Format(time.RFC3339),
 					Revocable:   true,
 					Suspendable: true,
+				}
+				createdCred, err := credService.CreateCredential(context.Background(), createRequest)
+				assert.NoError(tt, err)
+				assert.NotEmpty(tt, createdCred)
+				assert.NotEmpty(tt, createdCred.CredentialJWT)
+
+				// a credential that is only revocable shares the revocation status list
+				revocableRequest := createRequest
+				revocableRequest.Suspendable = false
+				revocableCred, err := credService.CreateCredential(context.Background(), revocableRequest)
+				assert.NoError(tt, err)
+
+				statusBytes, err := json.Marshal(createdCred.Credential.CredentialStatus)
+				assert.NoError(tt, err)
+				var statusEntries []status.StatusList2021Entry
+				err = json.Unmarshal(statusBytes, &statusEntries)
+				assert.NoError(tt, err)
+				require.Len(tt, statusEntries, 2)
+
+				revocationEntry, suspensionEntry := statusEntries[0], statusEntries[1]
+				assert.Equal(tt, status.StatusRevocation, revocationEntry.StatusPurpose)
+				assert.Equal(tt, status.StatusSuspension, suspensionEntry.StatusPurpose)
+				assert.Equal(tt, fmt.Sprintf("%s/status#revocation", createdCred.Credential.ID), revocationEntry.ID)
+				assert.Equal(tt, fmt.Sprintf("%s/status#suspension", createdCred.Credential.ID), suspensionEntry.ID)
+				assert.NotEqual(tt, revocationEntry.StatusListCredential, suspensionEntry.StatusListCredential)
+				assert.Equal(tt, revocationEntry.StatusListCredential, revocableCred.Credential.CredentialStatus.(map[string]any)["statusListCredential"])
+
+				// each entry is checked against its own status list
+				credInList := func(entry status.StatusList2021Entry) bool {
+					statusList, err := credService.GetCredentialStatusList(context.Background(), credential.GetCredentialStatusListRequest{ID: idFromURI(entry.StatusListCredential)})
+					require.NoError(tt, err)
+					cred := *createdCred.Credential
+					cred.CredentialStatus = entry
+					valid, err := status.ValidateCredentialInStatusList(cred, *statusList.Credential)
+					require.NoError(tt, err)
+					return valid
+				}
+				assert.False(tt, credInList(revocationEntry))
+				assert.False(tt, credInList(suspensionEntry))
+
+				updatedStatus, err := credService.UpdateCredentialStatus(context.Background(), credential.UpdateCredentialStatusRequest{ID: createdCred.ID, Suspended: true})
+				assert.NoError(tt, err)
+				assert.True(tt, updatedStatus.Suspended)
+				assert.False(tt, updatedStatus.Revoked)
+				assert.False(tt, credInList(revocationEntry))
+				assert.True(tt, credInList(suspensionEntry))
+
+				updatedStatus, err = credService.UpdateCredentialStatus(context.Background(), credential.UpdateCredentialStatusRequest{ID: createdCred.ID, Revoked: true, Suspended: true})
+				assert.NoError(tt, err)
+				assert.True(tt, updatedStatus.Suspended)
+				assert.True(tt, updatedStatus.Revoked)
+				assert.True(tt, credInList(revocationEntry))
+				assert.True(tt, credInList(suspensionEntry))
+
+				credStatus, err := credService.GetCredentialStatus(context.Background(), credential.GetCredentialStatusRequest{ID: createdCred.ID})
+				assert.NoError(tt, err)
+				assert.True(tt, credStatus.Revoked)
+				assert.True(tt, credStatus.Suspended)
+
+				batchStatus, err := credService.BatchUpdateCredentialStatus(context.Background(), credential.BatchUpdateCredentialStatusRequest{
+					Requests: []credential.UpdateCredentialStatusRequest{
+						{ID: createdCred.ID, Revoked: true},
+						{ID: revocableCred.ID, Revoked: true},
+					},
 				})
+				assert.NoError(tt, err)
+				assert.Len(tt, batchStatus.CredentialStatuses, 2)
+				assert.True(tt, credInList(revocationEntry))
+				assert.False(tt, credInList(suspensionEntry))
 
-				assert.Error(tt, err)
-				assert.ErrorContains(tt, err, "credential may have at most one status")
+				// the other credential in the revocation list is revoked too
+				revocableInList, err := credService.GetCredentialStatusList(context.Background(), credential.GetCredentialStatusListRequest{ID: idFromURI(revocationEntry.StatusListCredential)})
+				assert.NoError(tt, err)
+				valid, err := status.ValidateCredentialInStatusList(*revocableCred.Credential, *revocableInList.Credential)
+				assert.NoError(tt, err)
+				assert.True(tt, valid)
+			})
+
+			t.Run("Create Credential With Status Message and Revocable Should Be Error", func(tt *testing.T) {
+				issuer, verificationMethodID, schemaID, credService := createCredServicePrereqs(tt, test.ServiceStorage(tt))
+
+				createdCred, err := credService.CreateCredential(context.Background(), credential.CreateCredentialRequest{
+					Issuer:                             issuer,
+					FullyQualifiedVerificationMethodID: verificationMethodID,
+					Subject:                            "did:test:345",
+					SchemaID:                           schemaID,
+					Data: map[string]any{
+						"email": "Satoshi@Nakamoto.btc",
+					},
+					Revocable:        true,
+					StatusListFormat: credential.BitstringStatusListFormat,
+					StatusMessage: []credint.StatusMessageEntry{
+						{Status: "0x0", Message: "valid"},
+						{Status: "0x1", Message: "invalid"},
+					},
+				})
+				assert.ErrorContains(tt, err, "cannot also be revocable or suspendable")
 				assert.Empty(tt, createdCred)
 			})
 
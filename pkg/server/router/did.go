@@ -17,13 +17,19 @@ import (
 	"github.com/tbd54566975/ssi-service/pkg/server/framework"
 	"github.com/tbd54566975/ssi-service/pkg/server/pagination"
 	"github.com/tbd54566975/ssi-service/pkg/service/did"
+	didresolution "github.com/tbd54566975/ssi-service/pkg/service/did/resolution"
 	svcframework "github.com/tbd54566975/ssi-service/pkg/service/framework"
 )
 
 const (
-	MethodParam  = "method"
-	IDParam      = "id"
-	DeletedParam = "deleted"
+	MethodParam         = "method"
+	IDParam             = "id"
+	DeletedParam        = "deleted"
+	IncludeDeletedParam = "includeDeleted"
+	VersionIDParam      = "versionId"
+	VersionTimeParam    = "versionTime"
+	AcceptParam         = "accept"
+	NoCacheParam        = "noCache"
 )
 
 // DIDRouter represents the dependencies required to instantiate a DID-HTTP service
@@ -72,6 +78,10 @@ type CreateDIDByMethodRequest struct {
 
 type CreateDIDByMethodResponse struct {
 	DID didsdk.Document `json:"did,omitempty"`
+
+	// ID of the operation that tracks the anchoring of the DID. Only set for `ion`. Its progress can be queried from
+	// `/v1/operations`.
+	OperationID string `json:"operationId,omitempty"`
 }
 
 // CreateDIDByMethod godoc
@@ -80,7 +90,12 @@ type CreateDIDByMethodResponse struct {
 //	@Description	Creates a fully custodial DID document with the given method. The document created is stored internally
 //	@Description	and can be retrieved using the GetOperation. Method dependent registration (for example, DID web
 //	@Description	registration) is left up to the clients of this API. The private key(s) created by the method are stored
-//	@Description	internally never leave the service boundary.
+//	@Description	internally never leave the service boundary. Peer DIDs are created with numalgo 0 unless their options
+//	@Description	ask for numalgo 2, which adds a separate key agreement key and service endpoints.
+//	@Description	PKH DIDs need options naming the chain of the account (eip155 or solana), whose address is derived
+//	@Description	from the generated key.
+//	@Description	ION DIDs are anchored by an ION node. When it's unavailable, the create operation is retried with
+//	@Description	backoff. The anchoring is tracked by the operation whose ID is returned.
 //	@Tags			DecentralizedIdentifiers
 //	@Accept			json
 //	@Produce		json
@@ -124,31 +139,91 @@ func (dr DIDRouter) CreateDIDByMethod(c *gin.Context) {
 		return
 	}
 
-	resp := CreateDIDByMethodResponse{DID: createDIDResponse.DID}
+	resp := CreateDIDByMethodResponse{DID: createDIDResponse.DID, OperationID: createDIDResponse.OperationID}
 	framework.Respond(c, resp, http.StatusCreated)
 }
 
 type StateChange struct {
-	ServicesToAdd        []didsdk.Service `json:"servicesToAdd,omitempty"`
-	ServiceIDsToRemove   []string         `json:"serviceIdsToRemove,omitempty"`
-	PublicKeysToAdd      []ion.PublicKey  `json:"publicKeysToAdd,omitempty"`
-	PublicKeyIDsToRemove []string         `json:"publicKeyIdsToRemove"`
+	ServicesToAdd      []didsdk.Service `json:"servicesToAdd,omitempty"`
+	ServiceIDsToRemove []string         `json:"serviceIdsToRemove,omitempty"`
+
+	// Public keys to add, whose private keys are held outside the service. Only supported for `ion`.
+	PublicKeysToAdd []ion.PublicKey `json:"publicKeysToAdd,omitempty"`
+
+	// IDs of the verification methods to remove. For `web`, their keys are revoked in the keystore.
+	PublicKeyIDsToRemove []string `json:"publicKeyIdsToRemove"`
+
+	// Keys to generate in the keystore, and add as verification methods. Only supported for `web`.
+	KeysToAdd []KeyToAdd `json:"keysToAdd,omitempty"`
+
+	// Verification relationships to set for existing verification methods, replacing their current ones. Only
+	// supported for `web`.
+	PurposesToSet []PublicKeyPurposes `json:"purposesToSet,omitempty"`
+
+	// IDs of the verification methods whose keys are rotated in the keystore. Only supported for `web`.
+	PublicKeyIDsToRotate []string `json:"publicKeyIdsToRotate,omitempty"`
 }
 
+type KeyToAdd struct {
+	// ID of the verification method, either fully qualified or a fragment like `#key-2`.
+	ID string `json:"id" validate:"required" example:"#key-2"`
+
+	// Type of the key to generate.
+	KeyType crypto.KeyType `json:"keyType" validate:"required"`
+
+	// Verification relationships of the verification method.
+	Purposes []ion.PublicKeyPurpose `json:"purposes,omitempty"`
+}
+
+type PublicKeyPurposes struct {
+	// ID of the verification method, either fully qualified or a fragment like `#key-2`.
+	ID string `json:"id" validate:"required"`
+
+	// The verification relationships of the verification method. An empty list removes it from all relationships.
+	Purposes []ion.PublicKeyPurpose `json:"purposes"`
+}
+
+// The operations a DID can be changed with.
+const (
+	updateOperation     = "update"
+	recoverOperation    = "recover"
+	deactivateOperation = "deactivate"
+)
+
 type UpdateDIDByMethodRequest struct {
-	// Expected to be populated when `method == "ion"`. Describes the changes that are requested.
-	StateChange StateChange `json:"stateChange" validate:"required"`
+	// Operation to make: `update`, which is the default, `recover` or `deactivate`. Only `ion` supports `recover` and
+	// `deactivate`.
+	Operation string `json:"operation,omitempty" validate:"omitempty,oneof=update recover deactivate" example:"update"`
+
+	// Describes the changes that are requested. Required by `update`.
+	StateChange StateChange `json:"stateChange" validate:"required_without=Operation"`
+
+	// Document state that replaces the whole document state of the DID. Required by `recover`.
+	Document *ion.Document `json:"document,omitempty"`
 }
 
 type UpdateDIDByMethodResponse struct {
 	DID didsdk.Document `json:"did,omitempty"`
+
+	// Version of the document after the update. Not set for `ion`, whose versions are tracked by the ION network.
+	Version int `json:"version,omitempty"`
+
+	// ID of the operation that tracks the anchoring of the update, recovery or deactivation. Only set for `ion`. Its
+	// progress can be queried from `/v1/operations`.
+	OperationID string `json:"operationId,omitempty"`
 }
 
 // UpdateDIDByMethod godoc
 //
 //	@Summary		Updates a DID document.
 //	@Description	Updates a DID for which SSI is the custodian. The DID must have been previously created by calling
-//	@Description	the "Create DID Document" endpoint. Currently, only ION dids support updates.
+//	@Description	the "Create DID Document" endpoint. Currently, ION and web DIDs support updates. Updates to web DIDs
+//	@Description	are stored as new versions of the document, and new keys are generated in the keystore.
+//	@Description	ION updates are anchored by an ION node. When it's unavailable, the update is queued and retried with
+//	@Description	backoff; the document returned is the one the DID will have once the update is anchored.
+//	@Description	ION DIDs also support the `recover` operation, which replaces the whole document state and rotates
+//	@Description	the update and recovery keys, and the `deactivate` operation, which permanently deactivates the DID.
+//	@Description	Both are signed with the recovery key, so they can be used when the update key is compromised.
 //	@Tags			DecentralizedIdentifiers
 //	@Accept			json
 //	@Produce		json
@@ -166,9 +241,6 @@ func (dr DIDRouter) UpdateDIDByMethod(c *gin.Context) {
 		framework.LoggingRespondErrMsg(c, errMsg, http.StatusBadRequest)
 		return
 	}
-	if *method != didsdk.IONMethod.String() {
-		framework.LoggingRespondErrMsg(c, "ion is the only method supported", http.StatusBadRequest)
-	}
 
 	id := framework.GetParam(c, IDParam)
 	if id == nil {
@@ -188,6 +260,29 @@ func (dr DIDRouter) UpdateDIDByMethod(c *gin.Context) {
 		return
 	}
 
+	operation := request.Operation
+	if operation == "" {
+		operation = updateOperation
+	}
+	if *method != didsdk.IONMethod.String() {
+		if operation != updateOperation {
+			errMsg := fmt.Sprintf("%s: operation<%s> is not supported for method<%s>", invalidRequest, operation, *method)
+			framework.LoggingRespondErrMsg(c, errMsg, http.StatusBadRequest)
+			return
+		}
+		dr.updateDIDDocument(c, didsdk.Method(*method), *id, request)
+		return
+	}
+
+	switch operation {
+	case recoverOperation:
+		dr.recoverIONDID(c, *id, request)
+		return
+	case deactivateOperation:
+		dr.deactivateIONDID(c, *id)
+		return
+	}
+
 	updateDIDRequest, err := toUpdateIONDIDRequest(*id, request)
 	if err != nil {
 		errMsg := fmt.Sprintf("%s: could not update DID for method<%s>", invalidRequest, *method)
@@ -201,9 +296,134 @@ func (dr DIDRouter) UpdateDIDByMethod(c *gin.Context) {
 		return
 	}
 
-	resp := CreateDIDByMethodResponse{DID: updateIONDIDResponse.DID}
+	resp := UpdateDIDByMethodResponse{DID: updateIONDIDResponse.DID, OperationID: updateIONDIDResponse.OperationID}
+	framework.Respond(c, resp, http.StatusOK)
+}
+
+// recoverIONDID replaces the document state of an ION DID.
+func (dr DIDRouter) recoverIONDID(c *gin.Context, id string, request UpdateDIDByMethodRequest) {
+	didION := ion.ION(id)
+	if !didION.IsValid() {
+		errMsg := fmt.Sprintf("invalid recover DID request: invalid ion did %s", id)
+		framework.LoggingRespondErrMsg(c, errMsg, http.StatusBadRequest)
+		return
+	}
+	if request.Document == nil {
+		framework.LoggingRespondErrMsg(c, "invalid recover DID request: document is required", http.StatusBadRequest)
+		return
+	}
+
+	recoverIONDIDResponse, err := dr.service.RecoverIONDID(c, did.RecoverIONDIDRequest{DID: didION, Document: *request.Document})
+	if err != nil {
+		framework.LoggingRespondErrWithMsg(c, err, "could not recover DID", http.StatusInternalServerError)
+		return
+	}
+
+	resp := UpdateDIDByMethodResponse{DID: recoverIONDIDResponse.DID, OperationID: recoverIONDIDResponse.OperationID}
 	framework.Respond(c, resp, http.StatusOK)
+}
+
+// deactivateIONDID permanently deactivates an ION DID.
+func (dr DIDRouter) deactivateIONDID(c *gin.Context, id string) {
+	didION := ion.ION(id)
+	if !didION.IsValid() {
+		errMsg := fmt.Sprintf("invalid deactivate DID request: invalid ion did %s", id)
+		framework.LoggingRespondErrMsg(c, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	deactivateIONDIDResponse, err := dr.service.DeactivateIONDID(c, did.DeactivateIONDIDRequest{DID: didION})
+	if err != nil {
+		framework.LoggingRespondErrWithMsg(c, err, "could not deactivate DID", http.StatusInternalServerError)
+		return
+	}
+
+	resp := UpdateDIDByMethodResponse{DID: deactivateIONDIDResponse.DID, OperationID: deactivateIONDIDResponse.OperationID}
+	framework.Respond(c, resp, http.StatusOK)
+}
+
+// updateDIDDocument updates the document of a DID whose method's handler supports updates, such as web.
+func (dr DIDRouter) updateDIDDocument(c *gin.Context, method didsdk.Method, id string, request UpdateDIDByMethodRequest) {
+	if len(request.StateChange.PublicKeysToAdd) > 0 {
+		errMsg := fmt.Sprintf("publicKeysToAdd is not supported for method<%s>; use keysToAdd", method)
+		framework.LoggingRespondErrMsg(c, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	updateDIDResponse, err := dr.service.UpdateDIDByMethod(c, request.toServiceRequest(method, id))
+	if err != nil {
+		errMsg := fmt.Sprintf("could not update DID for method<%s>", method)
+		if errors.Is(err, did.ErrInvalidStateChange) {
+			framework.LoggingRespondErrWithMsg(c, err, errMsg, http.StatusBadRequest)
+			return
+		}
+		framework.LoggingRespondErrWithMsg(c, err, errMsg, http.StatusInternalServerError)
+		return
+	}
+
+	resp := UpdateDIDByMethodResponse{DID: updateDIDResponse.DID, Version: updateDIDResponse.Version}
+	framework.Respond(c, resp, http.StatusOK)
+}
+
+func (r UpdateDIDByMethodRequest) toServiceRequest(method didsdk.Method, id string) did.UpdateDIDRequest {
+	stateChange := did.DocumentStateChange{
+		ServicesToAdd:                 r.StateChange.ServicesToAdd,
+		ServiceIDsToRemove:            r.StateChange.ServiceIDsToRemove,
+		VerificationMethodIDsToRemove: r.StateChange.PublicKeyIDsToRemove,
+		VerificationMethodIDsToRotate: r.StateChange.PublicKeyIDsToRotate,
+	}
+	for _, keyToAdd := range r.StateChange.KeysToAdd {
+		stateChange.VerificationMethodsToAdd = append(stateChange.VerificationMethodsToAdd, did.VerificationMethodToAdd{
+			ID:       keyToAdd.ID,
+			KeyType:  keyToAdd.KeyType,
+			Purposes: keyToAdd.Purposes,
+		})
+	}
+	for _, purposes := range r.StateChange.PurposesToSet {
+		stateChange.VerificationRelationshipsToSet = append(stateChange.VerificationRelationshipsToSet, did.VerificationRelationships{
+			ID:       purposes.ID,
+			Purposes: purposes.Purposes,
+		})
+	}
+	return did.UpdateDIDRequest{Method: method, ID: id, StateChange: stateChange}
+}
+
+type ListDIDVersionsResponse struct {
+	// Every version of the DID document, oldest first.
+	Versions []did.DIDVersion `json:"versions"`
+}
+
+// ListDIDVersions godoc
+//
+//	@Summary		List DID document versions
+//	@Description	Lists every version of the document of a DID stored in the service, oldest first. A new version is
+//	@Description	stored each time the DID is updated.
+//	@Tags			DecentralizedIdentifiers
+//	@Accept			json
+//	@Produce		json
+//	@Param			method	path		string	true	"Method"
+//	@Param			id		path		string	true	"ID"
+//	@Success		200		{object}	ListDIDVersionsResponse
+//	@Failure		400		{string}	string	"Bad request"
+//	@Failure		500		{string}	string	"Internal server error"
+//	@Router			/v1/dids/{method}/{id}/versions [get]
+func (dr DIDRouter) ListDIDVersions(c *gin.Context) {
+	method := framework.GetParam(c, MethodParam)
+	id := framework.GetParam(c, IDParam)
+	if method == nil || id == nil {
+		errMsg := "list DID versions request missing method or id parameter"
+		framework.LoggingRespondErrMsg(c, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	versions, err := dr.service.GetDIDVersions(c, did.GetDIDVersionsRequest{ID: *id})
+	if err != nil {
+		errMsg := fmt.Sprintf("could not get versions of DID for method<%s> with id: %s", *method, *id)
+		framework.LoggingRespondErrWithMsg(c, err, errMsg, http.StatusInternalServerError)
+		return
+	}
 
+	framework.Respond(c, ListDIDVersionsResponse{Versions: versions.Versions}, http.StatusOK)
 }
 
 func toUpdateIONDIDRequest(id string, request UpdateDIDByMethodRequest) (*did.UpdateIONDIDRequest, error) {
@@ -248,6 +468,18 @@ func toCreateDIDRequest(m didsdk.Method, request CreateDIDByMethodRequest) (*did
 			return nil, errors.Wrap(err, "parsing web options")
 		}
 		createRequest.Options = opts
+	case didsdk.PeerMethod:
+		var opts did.CreatePeerDIDOptions
+		if err := optionsToType(request.Options, &opts); err != nil {
+			return nil, errors.Wrap(err, "parsing peer options")
+		}
+		createRequest.Options = opts
+	case didsdk.PKHMethod:
+		var opts did.CreatePKHDIDOptions
+		if err := optionsToType(request.Options, &opts); err != nil {
+			return nil, errors.Wrap(err, "parsing pkh options")
+		}
+		createRequest.Options = opts
 	default:
 		if request.Options != nil {
 			return nil, fmt.Errorf("invalid options for method<%s>", m)
@@ -334,12 +566,14 @@ type GetDIDsRequest struct {
 //
 //	@Summary		List DIDs by method
 //	@Description	List DIDs by method. Checks for an optional "deleted=true" query parameter, which exclusively
-//	@Description	returns DIDs that have been "Soft Deleted".
+//	@Description	returns DIDs that have been "Soft Deleted", and an optional "includeDeleted=true" query parameter,
+//	@Description	which returns them along with the DIDs that have not been.
 //	@Tags			DecentralizedIdentifiers
 //	@Accept			json
 //	@Produce		json
 //	@Param			method		path		string	true	"Method must be one returned by GET /v1/dids"
-//	@Param			deleted		query		boolean	false	"When true, returns soft-deleted DIDs. Otherwise, returns DIDs that have not been soft-deleted. Default is false."
+//	@Param			deleted			query		boolean	false	"When true, returns soft-deleted DIDs. Otherwise, returns DIDs that have not been soft-deleted. Default is false."
+//	@Param			includeDeleted	query		boolean	false	"When true, returns soft-deleted DIDs along with the others. Cannot be combined with deleted. Default is false."
 //	@Param			pageSize	query		number	false	"Hint to the server of the maximum elements to return. More may be returned. When not set, the server will return all elements."
 //	@Param			pageToken	query		string	false	"Used to indicate to the server to return a specific page of the list results. Must match a previous requests' `nextPageToken`."
 //	@Success		200			{object}	ListDIDsByMethodResponse
@@ -365,11 +599,27 @@ func (dr DIDRouter) ListDIDsByMethod(c *gin.Context) {
 			return
 		}
 	}
+	includeDeleted := false
+	if includeDeletedValue := framework.GetQueryValue(c, IncludeDeletedParam); includeDeletedValue != nil {
+		checkIncludeDeleted, err := strconv.ParseBool(*includeDeletedValue)
+		if err != nil {
+			errMsg := "list DIDs by method request encountered a problem with the `includeDeleted` query param"
+			framework.LoggingRespondErrMsg(c, errMsg, http.StatusBadRequest)
+			return
+		}
+		includeDeleted = checkIncludeDeleted
+	}
+	if getIsDeleted && includeDeleted {
+		errMsg := "list DIDs by method request cannot combine the `deleted` and `includeDeleted` query params"
+		framework.LoggingRespondErrMsg(c, errMsg, http.StatusBadRequest)
+		return
+	}
 	// TODO(gabe) check if the method is supported, to tell whether this is a bad req or internal error
 	// TODO(gabe) differentiate between internal errors and not found DIDs
 	getDIDsRequest := did.ListDIDsRequest{
-		Method:  didsdk.Method(*method),
-		Deleted: getIsDeleted,
+		Method:         didsdk.Method(*method),
+		Deleted:        getIsDeleted,
+		IncludeDeleted: includeDeleted,
 	}
 	var pageRequest pagination.PageRequest
 	if pagination.ParsePaginationQueryValues(c, &pageRequest) {
@@ -439,16 +689,98 @@ func (dr DIDRouter) SoftDeleteDIDByMethod(c *gin.Context) {
 	framework.Respond(c, nil, http.StatusNoContent)
 }
 
+// RestoreDIDByMethod godoc
+//
+//	@Summary		Restore a soft deleted DID
+//	@Description	Restores a DID that was soft deleted, so that it shows up in the ListDIDsByMethod call again.
+//	@Tags			DecentralizedIdentifiers
+//	@Accept			json
+//	@Produce		json
+//	@Param			method	path		string	true	"Method"
+//	@Param			id		path		string	true	"ID"
+//	@Success		204		{string}	string	"No Content"
+//	@Failure		400		{string}	string	"Bad request"
+//	@Failure		500		{string}	string	"Internal server error"
+//	@Router			/v1/dids/{method}/{id}/restore [put]
+func (dr DIDRouter) RestoreDIDByMethod(c *gin.Context) {
+	method := framework.GetParam(c, MethodParam)
+	id := framework.GetParam(c, IDParam)
+	if method == nil || id == nil {
+		errMsg := "restore DID by method request missing method or id parameter"
+		framework.LoggingRespondErrMsg(c, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	restoreDIDRequest := did.RestoreDIDRequest{Method: didsdk.Method(*method), ID: *id}
+	if err := dr.service.RestoreDIDByMethod(c, restoreDIDRequest); err != nil {
+		errMsg := fmt.Sprintf("could not restore DID with id: %s", *id)
+		framework.LoggingRespondErrWithMsg(c, err, errMsg, http.StatusInternalServerError)
+		return
+	}
+
+	framework.Respond(c, nil, http.StatusNoContent)
+}
+
+type PurgeDIDByMethodResponse struct {
+	// IDs of the keys controlled by the DID, which were deleted from the keystore.
+	DeletedKeyIDs []string `json:"deletedKeyIds"`
+}
+
+// PurgeDIDByMethod godoc
+//
+//	@Summary		Purge a soft deleted DID
+//	@Description	Permanently removes a DID that was soft deleted, along with the earlier versions of its document.
+//	@Description	Every key in the keystore whose controller is the DID is deleted. Purging a DID has no effect on
+//	@Description	DIDs anchored in a network, such as ION DIDs.
+//	@Tags			DecentralizedIdentifiers
+//	@Accept			json
+//	@Produce		json
+//	@Param			method	path		string	true	"Method"
+//	@Param			id		path		string	true	"ID"
+//	@Success		200		{object}	PurgeDIDByMethodResponse
+//	@Failure		400		{string}	string	"Bad request"
+//	@Failure		500		{string}	string	"Internal server error"
+//	@Router			/v1/dids/{method}/{id}/purge [delete]
+func (dr DIDRouter) PurgeDIDByMethod(c *gin.Context) {
+	method := framework.GetParam(c, MethodParam)
+	id := framework.GetParam(c, IDParam)
+	if method == nil || id == nil {
+		errMsg := "purge DID by method request missing method or id parameter"
+		framework.LoggingRespondErrMsg(c, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	purgeDIDRequest := did.PurgeDIDRequest{Method: didsdk.Method(*method), ID: *id}
+	purged, err := dr.service.PurgeDIDByMethod(c, purgeDIDRequest)
+	if err != nil {
+		errMsg := fmt.Sprintf("could not purge DID with id: %s", *id)
+		framework.LoggingRespondErrWithMsg(c, err, errMsg, http.StatusInternalServerError)
+		return
+	}
+
+	framework.Respond(c, PurgeDIDByMethodResponse{DeletedKeyIDs: purged.DeletedKeyIDs}, http.StatusOK)
+}
+
 // ResolveDID godoc
 //
 //	@Summary		Resolve a DID
-//	@Description	Resolve a DID that may not be stored in this service
+//	@Description	Resolve a DID that may not be stored in this service. The DID resolution options described in
+//	@Description	https://www.w3.org/TR/did-spec-registries/#did-resolution-options are taken as query parameters.
+//	@Description	Resolution errors are reported with the status matching their DID resolution error code.
 //	@Tags			DecentralizedIdentifiers
 //	@Accept			json
 //	@Produce		json
-//	@Param			id	path		string	true	"ID"
-//	@Success		200	{object}	ResolveDIDResponse
-//	@Failure		400	{string}	string	"Bad request"
+//	@Param			id			path		string	true	"ID"
+//	@Param			versionId	query		string	false	"The version of the document to resolve"
+//	@Param			versionTime	query		string	false	"Resolve the version of the document valid at this RFC3339 date time"
+//	@Param			accept		query		string	false	"The representation of the document: application/did+ld+json (default) or application/did+json"
+//	@Param			noCache		query		bool	false	"Resolve the DID without using cached resolutions"
+//	@Success		200			{object}	ResolveDIDResponse
+//	@Failure		400			{string}	string	"Bad request"
+//	@Failure		404			{string}	string	"DID not found"
+//	@Failure		406			{string}	string	"Representation not supported"
+//	@Failure		500			{string}	string	"Internal server error"
+//	@Failure		501			{string}	string	"DID method not supported"
 //	@Router			/v1/dids/resolver/{id} [get]
 func (dr DIDRouter) ResolveDID(c *gin.Context) {
 	id := framework.GetParam(c, IDParam)
@@ -458,11 +790,17 @@ func (dr DIDRouter) ResolveDID(c *gin.Context) {
 		return
 	}
 
-	resolveDIDRequest := did.ResolveDIDRequest{DID: *id}
+	options, err := resolutionOptionsFromQuery(c)
+	if err != nil {
+		framework.LoggingRespondErrWithMsg(c, err, "resolve DID request has invalid query params", http.StatusBadRequest)
+		return
+	}
+
+	resolveDIDRequest := did.ResolveDIDRequest{DID: *id, Options: *options}
 	resolvedDID, err := dr.service.ResolveDID(resolveDIDRequest)
 	if err != nil {
 		errMsg := fmt.Sprintf("could not get DID with id: %s", *id)
-		framework.LoggingRespondErrWithMsg(c, err, errMsg, http.StatusBadRequest)
+		framework.LoggingRespondErrWithMsg(c, err, errMsg, resolutionErrorStatus(err))
 		return
 	}
 
@@ -470,6 +808,96 @@ func (dr DIDRouter) ResolveDID(c *gin.Context) {
 	framework.Respond(c, resp, http.StatusOK)
 }
 
+type DereferenceDIDURLResponse struct {
+	DereferencingMetadata didresolution.DereferencingMetadata `json:"dereferencingMetadata"`
+	// The dereferenced resource: the DID document, a verification method or service from it, or the newline separated
+	// URLs of a service endpoint.
+	ContentStream   any                          `json:"contentStream"`
+	ContentMetadata *resolution.DocumentMetadata `json:"contentMetadata,omitempty"`
+}
+
+// DereferenceDIDURL godoc
+//
+//	@Summary		Dereference a DID URL
+//	@Description	Dereference a DID URL, such as `did:web:example.com#key-1` or `did:example:123?service=files&relativeRef=/a`,
+//	@Description	as described in https://www.w3.org/TR/did-core/#did-url-dereferencing. The DID URL must be percent encoded.
+//	@Description	A fragment selects a verification method or service of the DID document, and the `service` DID URL parameter
+//	@Description	selects the endpoint of a service. The DID is resolved like the resolver does, and takes the same query parameters.
+//	@Tags			DecentralizedIdentifiers
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		string	true	"DID URL"
+//	@Param			accept		query		string	false	"The representation of the document: application/did+ld+json (default) or application/did+json"
+//	@Param			noCache		query		bool	false	"Resolve the DID without using cached resolutions"
+//	@Success		200			{object}	DereferenceDIDURLResponse
+//	@Failure		400			{string}	string	"Bad request"
+//	@Failure		404			{string}	string	"DID or resource not found"
+//	@Failure		406			{string}	string	"Representation not supported"
+//	@Failure		500			{string}	string	"Internal server error"
+//	@Failure		501			{string}	string	"DID method not supported"
+//	@Router			/v1/dids/dereferencer/{id} [get]
+func (dr DIDRouter) DereferenceDIDURL(c *gin.Context) {
+	didURL := framework.GetParam(c, IDParam)
+	if didURL == nil {
+		errMsg := "dereference DID URL request missing id parameter"
+		framework.LoggingRespondErrMsg(c, errMsg, http.StatusBadRequest)
+		return
+	}
+	options, err := resolutionOptionsFromQuery(c)
+	if err != nil {
+		framework.LoggingRespondErrWithMsg(c, err, "dereference DID URL request has invalid query params", http.StatusBadRequest)
+		return
+	}
+
+	dereferenceRequest := did.DereferenceDIDURLRequest{DIDURL: *didURL, Options: *options}
+	dereferenced, err := dr.service.DereferenceDIDURL(c, dereferenceRequest)
+	if err != nil {
+		errMsg := fmt.Sprintf("could not dereference DID URL: %s", *didURL)
+		framework.LoggingRespondErrWithMsg(c, err, errMsg, resolutionErrorStatus(err))
+		return
+	}
+
+	resp := DereferenceDIDURLResponse{
+		DereferencingMetadata: dereferenced.DereferencingMetadata,
+		ContentStream:         dereferenced.ContentStream,
+		ContentMetadata:       dereferenced.ContentMetadata,
+	}
+	framework.Respond(c, resp, http.StatusOK)
+}
+
+// resolutionOptionsFromQuery reads the DID resolution options from the query parameters of a request.
+func resolutionOptionsFromQuery(c *gin.Context) (*didresolution.Options, error) {
+	options := didresolution.Options{
+		VersionID:   c.Query(VersionIDParam),
+		VersionTime: c.Query(VersionTimeParam),
+		Accept:      c.Query(AcceptParam),
+	}
+	if noCache := framework.GetQueryValue(c, NoCacheParam); noCache != nil {
+		checkNoCache, err := strconv.ParseBool(*noCache)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing the `noCache` query param")
+		}
+		options.NoCache = checkNoCache
+	}
+	return &options, nil
+}
+
+// resolutionErrorStatus returns the HTTP status matching the DID resolution error code of an error, as the DID
+// resolution HTTP(S) binding does in https://w3c-ccg.github.io/did-resolution/#bindings-https.
+func resolutionErrorStatus(err error) int {
+	switch didresolution.GetErrorCode(err) {
+	case didresolution.InvalidDIDCode, didresolution.InvalidDIDURLCode, didresolution.InvalidOptionsCode:
+		return http.StatusBadRequest
+	case didresolution.NotFoundCode:
+		return http.StatusNotFound
+	case didresolution.RepresentationNotSupportedCode:
+		return http.StatusNotAcceptable
+	case didresolution.MethodNotSupportedCode:
+		return http.StatusNotImplemented
+	}
+	return http.StatusInternalServerError
+}
+
 type BatchCreateDIDsRequest struct {
 	// Required. The list of create credential requests. Cannot be more than {{.Services.DIDConfig.BatchCreateMaxItems}} items.
 	Requests []CreateDIDByMethodRequest `json:"requests" maxItems:"100" validate:"required,dive"`
@@ -0,0 +1,124 @@
+package router
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	didsdk "github.com/TBD54566975/ssi-sdk/did"
+	sdkutil "github.com/TBD54566975/ssi-sdk/util"
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+	"github.com/pkg/errors"
+
+	"github.com/tbd54566975/ssi-service/pkg/server/framework"
+	"github.com/tbd54566975/ssi-service/pkg/service/did"
+	svcframework "github.com/tbd54566975/ssi-service/pkg/service/framework"
+)
+
+const (
+	// DIDWebWellKnownPath is where the document of a did:web DID without a path is resolved from.
+	DIDWebWellKnownPath = "/.well-known/did.json"
+	// DIDWebPathDocumentPath is where the document of a did:web DID with a single path segment is resolved from.
+	DIDWebPathDocumentPath = "/:path/did.json"
+	didWebDocumentName     = "/did.json"
+	didWebContentType      = "application/did+ld+json"
+)
+
+// DIDWebRouter serves the documents of the did:web DIDs stored in the service, making the service the origin the
+// DIDs are resolved from.
+type DIDWebRouter struct {
+	service     *did.Service
+	domains     []string
+	cacheMaxAge time.Duration
+}
+
+// NewDIDWebRouter creates an HTTP router that hosts did:web documents for the given domains, which clients may cache
+// for cacheMaxAge.
+func NewDIDWebRouter(s svcframework.Service, domains []string, cacheMaxAge time.Duration) (*DIDWebRouter, error) {
+	if s == nil {
+		return nil, errors.New("service cannot be nil")
+	}
+	didService, ok := s.(*did.Service)
+	if !ok {
+		return nil, fmt.Errorf("could not create DID web router with service type: %s", s.Type())
+	}
+	if len(domains) == 0 {
+		return nil, errors.New("at least one domain to host did:web documents for is required")
+	}
+	lowerDomains := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		lowerDomains = append(lowerDomains, strings.ToLower(domain))
+	}
+	return &DIDWebRouter{service: didService, domains: lowerDomains, cacheMaxAge: cacheMaxAge}, nil
+}
+
+// GetDIDDocument serves the document of the did:web DID that resolves to the request's host and path, as described in
+// https://w3c-ccg.github.io/did-method-web/#read-resolve. For example, a request to
+// `https://example.com/alice/did.json` serves the document of `did:web:example.com:alice`. Requests made to hosts
+// other than the router's domains are not served.
+func (wr DIDWebRouter) GetDIDDocument(c *gin.Context) {
+	host := strings.ToLower(c.Request.Host)
+	if !sdkutil.Contains(host, wr.domains) {
+		framework.LoggingRespondErrMsg(c, fmt.Sprintf("did:web documents are not hosted for: %s", host), http.StatusNotFound)
+		return
+	}
+	id, err := didWebIDFromRequest(host, c.Request.URL.Path)
+	if err != nil {
+		framework.LoggingRespondErrWithMsg(c, err, "not a did:web document path", http.StatusNotFound)
+		return
+	}
+
+	document, err := wr.service.GetHostedWebDID(c, id)
+	if err != nil {
+		errMsg := fmt.Sprintf("could not get DID: %s", id)
+		framework.LoggingRespondErrWithMsg(c, err, errMsg, http.StatusInternalServerError)
+		return
+	}
+	if document == nil {
+		framework.LoggingRespondErrMsg(c, fmt.Sprintf("did:web document not found: %s", id), http.StatusNotFound)
+		return
+	}
+
+	documentBytes, err := json.Marshal(document)
+	if err != nil {
+		framework.LoggingRespondErrWithMsg(c, err, "could not marshal DID document", http.StatusInternalServerError)
+		return
+	}
+
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256(documentBytes))
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(wr.cacheMaxAge.Seconds())))
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, didWebContentType, documentBytes)
+}
+
+// didWebIDFromRequest returns the did:web DID that resolves to the given host and path. Ports are percent encoded in
+// did:web DIDs, and path segments are separated by colons.
+func didWebIDFromRequest(host, path string) (string, error) {
+	if host == "" {
+		return "", errors.New("request has no host")
+	}
+	var segments []string
+	if path != DIDWebWellKnownPath {
+		if !strings.HasSuffix(path, didWebDocumentName) {
+			return "", errors.Errorf("path<%s> is not a did:web document path", path)
+		}
+		segments = strings.Split(strings.TrimPrefix(strings.TrimSuffix(path, didWebDocumentName), "/"), "/")
+		for _, segment := range segments {
+			if segment == "" || strings.Contains(segment, ":") {
+				return "", errors.Errorf("path<%s> is not a did:web document path", path)
+			}
+		}
+	}
+	id := fmt.Sprintf("did:%s:%s", didsdk.WebMethod, strings.ReplaceAll(host, ":", "%3A"))
+	if len(segments) > 0 {
+		id += ":" + strings.Join(segments, ":")
+	}
+	return id, nil
+}
@@ -0,0 +1,59 @@
+package router
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tbd54566975/ssi-service/pkg/service/did"
+)
+
+func TestDIDWebRouter(t *testing.T) {
+	t.Run("Nil Service", func(tt *testing.T) {
+		didWebRouter, err := NewDIDWebRouter(nil, []string{"example.com"}, 0)
+		assert.Error(tt, err)
+		assert.Empty(tt, didWebRouter)
+		assert.Contains(tt, err.Error(), "service cannot be nil")
+	})
+
+	t.Run("Bad Service", func(tt *testing.T) {
+		didWebRouter, err := NewDIDWebRouter(&testService{}, []string{"example.com"}, 0)
+		assert.Error(tt, err)
+		assert.Empty(tt, didWebRouter)
+		assert.Contains(tt, err.Error(), "could not create DID web router with service type: test")
+	})
+
+	t.Run("No Domains", func(tt *testing.T) {
+		didWebRouter, err := NewDIDWebRouter(&did.Service{}, nil, 0)
+		assert.Error(tt, err)
+		assert.Empty(tt, didWebRouter)
+		assert.Contains(tt, err.Error(), "at least one domain to host did:web documents for is required")
+	})
+
+	t.Run("DID Web ID From Request", func(tt *testing.T) {
+		for path, want := range map[string]string{
+			"/.well-known/did.json":  "did:web:example.com",
+			"/user/alice/did.json":   "did:web:example.com:user:alice",
+			"/issuers/did.json":      "did:web:example.com:issuers",
+			"/.well-known/did.jsonx": "",
+			"/did.json":              "",
+			"/user//did.json":        "",
+			"/v1/dids":               "",
+		} {
+			id, err := didWebIDFromRequest("example.com", path)
+			if want == "" {
+				assert.Error(tt, err, path)
+				continue
+			}
+			assert.NoError(tt, err, path)
+			assert.Equal(tt, want, id)
+		}
+
+		id, err := didWebIDFromRequest("localhost:3000", "/.well-known/did.json")
+		assert.NoError(tt, err)
+		assert.Equal(tt, "did:web:localhost%3A3000", id)
+
+		_, err = didWebIDFromRequest("", "/.well-known/did.json")
+		assert.Error(tt, err)
+	})
+}
@@ -3,6 +3,7 @@ package router
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/TBD54566975/ssi-sdk/crypto"
 	"github.com/TBD54566975/ssi-sdk/crypto/jwx"
@@ -15,6 +16,10 @@ import (
 	"github.com/tbd54566975/ssi-service/pkg/service/keystore"
 )
 
+const (
+	VersionParam = "version"
+)
+
 type KeyStoreRouter struct {
 	service *keystore.Service
 }
@@ -100,6 +105,94 @@ func (ksr *KeyStoreRouter) StoreKey(c *gin.Context) {
 	framework.Respond(c, nil, http.StatusCreated)
 }
 
+type GenerateKeyRequest struct {
+	// The `id` field is the unique identifier for the generated key. When empty, the ssi-service assigns a random ID.
+	ID string `json:"id,omitempty"`
+
+	// Identifies the cryptographic algorithm family of the key to generate.
+	// One of the following: "Ed25519", "secp256k1", "P-256", "P-384", "RSA".
+	Type crypto.KeyType `json:"type,omitempty" validate:"required"`
+
+	// See https://www.w3.org/TR/did-core/#did-controller
+	Controller string `json:"controller,omitempty" validate:"required"`
+
+	// Identifies where the private key is held. One of "local" (the default), which keeps the key encrypted in the
+	// ssi-service's storage, or "remote", which keeps it in the configured remote signer.
+	Backend keystore.BackendType `json:"backend,omitempty"`
+}
+
+func (gk GenerateKeyRequest) ToServiceRequest() keystore.GenerateKeyRequest {
+	return keystore.GenerateKeyRequest{
+		ID:         gk.ID,
+		Type:       gk.Type,
+		Controller: gk.Controller,
+		Backend:    gk.Backend,
+	}
+}
+
+type GenerateKeyResponse struct {
+	ID string `json:"id"`
+
+	// The public key in JWK format according to RFC7517. The private key never leaves the ssi-service.
+	PublicKeyJWK jwx.PublicKeyJWK `json:"publicKeyJwk"`
+}
+
+// GenerateKey godoc
+//
+//	@Summary		Generate a key
+//	@Description	Generates a key inside the service and stores it. Only the public key is returned.
+//	@Tags			KeyStore
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		GenerateKeyRequest	true	"request body"
+//	@Success		201		{object}	GenerateKeyResponse
+//	@Failure		400		{string}	string	"Bad request"
+//	@Failure		409		{string}	string	"Key already exists"
+//	@Failure		500		{string}	string	"Internal server error"
+//	@Router			/v1/keys/generate [put]
+func (ksr *KeyStoreRouter) GenerateKey(c *gin.Context) {
+	var request GenerateKeyRequest
+	invalidGenerateKeyRequest := "invalid generate key request"
+	if err := framework.Decode(c.Request, &request); err != nil {
+		framework.LoggingRespondErrWithMsg(c, err, invalidGenerateKeyRequest, http.StatusBadRequest)
+		return
+	}
+
+	if err := framework.ValidateRequest(request); err != nil {
+		framework.LoggingRespondErrWithMsg(c, err, invalidGenerateKeyRequest, http.StatusBadRequest)
+		return
+	}
+
+	if !keystore.IsSupportedGenerationKeyType(request.Type) {
+		errMsg := fmt.Sprintf("unsupported key type for generation: %s", request.Type)
+		framework.LoggingRespondErrMsg(c, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	if request.Backend != "" && !ksr.service.SupportsBackend(request.Backend) {
+		errMsg := fmt.Sprintf("key backend<%s> is not configured", request.Backend)
+		framework.LoggingRespondErrMsg(c, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	generatedKey, err := ksr.service.GenerateKey(c, request.ToServiceRequest())
+	if err != nil {
+		errMsg := "could not generate key"
+		if errors.Is(err, keystore.ErrKeyAlreadyExists) {
+			framework.LoggingRespondErrWithMsg(c, err, errMsg, http.StatusConflict)
+			return
+		}
+		framework.LoggingRespondErrWithMsg(c, err, errMsg, http.StatusInternalServerError)
+		return
+	}
+
+	resp := GenerateKeyResponse{
+		ID:           generatedKey.ID,
+		PublicKeyJWK: generatedKey.PublicKeyJWK,
+	}
+	framework.Respond(c, resp, http.StatusCreated)
+}
+
 type GetKeyDetailsResponse struct {
 	ID         string         `json:"id,omitempty"`
 	Type       crypto.KeyType `json:"type,omitempty"`
@@ -111,6 +204,30 @@ type GetKeyDetailsResponse struct {
 	// The public key in JWK format according to RFC7517. This public key is associated with the private
 	// key with the associated ID.
 	PublicKeyJWK jwx.PublicKeyJWK `json:"publicKeyJwk"`
+
+	// The latest version of the key. Starts at 1 and is incremented every time the key is rotated.
+	Version int `json:"version,omitempty"`
+
+	// Every version of the key, oldest first.
+	Versions []KeyVersion `json:"versions,omitempty"`
+
+	// Identifies where the private key is held, e.g. "local" or "remote".
+	Backend keystore.BackendType `json:"backend,omitempty"`
+}
+
+type KeyVersion struct {
+	Version int            `json:"version"`
+	Type    crypto.KeyType `json:"type,omitempty"`
+
+	// Represents the time at which this version was created. Encoded according to RFC3339.
+	CreatedAt string `json:"createdAt,omitempty"`
+
+	// Whether this version has been revoked, and the time at which it was revoked. Encoded according to RFC3339.
+	Revoked   bool   `json:"revoked"`
+	RevokedAt string `json:"revokedAt,omitempty"`
+
+	// The public key of this version in JWK format according to RFC7517.
+	PublicKeyJWK jwx.PublicKeyJWK `json:"publicKeyJwk"`
 }
 
 // GetKeyDetails godoc
@@ -145,6 +262,93 @@ func (ksr *KeyStoreRouter) GetKeyDetails(c *gin.Context) {
 		Controller:   gotKeyDetails.Controller,
 		CreatedAt:    gotKeyDetails.CreatedAt,
 		PublicKeyJWK: gotKeyDetails.PublicKeyJWK,
+		Version:      gotKeyDetails.Version,
+		Backend:      gotKeyDetails.Backend,
+	}
+	for _, version := range gotKeyDetails.Versions {
+		resp.Versions = append(resp.Versions, KeyVersion{
+			Version:      version.Version,
+			Type:         version.KeyType,
+			CreatedAt:    version.CreatedAt,
+			Revoked:      version.Revoked,
+			RevokedAt:    version.RevokedAt,
+			PublicKeyJWK: version.PublicKeyJWK,
+		})
+	}
+	framework.Respond(c, resp, http.StatusOK)
+}
+
+type RotateKeyRequest struct {
+	// Identifies the cryptographic algorithm family of the new key version. When empty, the type of the current
+	// version is kept. One of the following: "Ed25519", "secp256k1", "P-256", "P-384", "RSA".
+	Type crypto.KeyType `json:"type,omitempty"`
+}
+
+type RotateKeyResponse struct {
+	ID string `json:"id"`
+
+	// The version created by the rotation.
+	Version int `json:"version"`
+
+	// The public key of the new version in JWK format according to RFC7517.
+	PublicKeyJWK jwx.PublicKeyJWK `json:"publicKeyJwk"`
+}
+
+// RotateKey godoc
+//
+//	@Summary		Rotate a key
+//	@Description	Generates a new version of the key with the given ID. The new version is used for all subsequent signing,
+//	@Description	while earlier versions are kept for verifying signatures they produced.
+//	@Tags			KeyStore
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string				true	"ID of the key to rotate"
+//	@Param			request	body		RotateKeyRequest	false	"request body"
+//	@Success		200		{object}	RotateKeyResponse
+//	@Failure		400		{string}	string	"Bad request"
+//	@Failure		404		{string}	string	"Not found"
+//	@Failure		500		{string}	string	"Internal server error"
+//	@Router			/v1/keys/{id}/rotate [put]
+func (ksr *KeyStoreRouter) RotateKey(c *gin.Context) {
+	id := framework.GetParam(c, IDParam)
+	if id == nil {
+		errMsg := "cannot rotate key without ID parameter"
+		framework.LoggingRespondErrMsg(c, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	var request RotateKeyRequest
+	if c.Request.ContentLength != 0 {
+		if err := framework.Decode(c.Request, &request); err != nil {
+			errMsg := "invalid rotate key request"
+			framework.LoggingRespondErrWithMsg(c, err, errMsg, http.StatusBadRequest)
+			return
+		}
+	}
+	if request.Type != "" && !keystore.IsSupportedGenerationKeyType(request.Type) {
+		errMsg := fmt.Sprintf("unsupported key type for generation: %s", request.Type)
+		framework.LoggingRespondErrMsg(c, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	rotated, err := ksr.service.RotateKey(c, keystore.RotateKeyRequest{ID: *id, Type: request.Type})
+	if err != nil {
+		errMsg := fmt.Sprintf("could not rotate key for id: %s", *id)
+		switch {
+		case errors.Is(err, keystore.ErrKeyNotFound):
+			framework.LoggingRespondErrWithMsg(c, err, errMsg, http.StatusNotFound)
+		case errors.Is(err, keystore.ErrUnsupportedKeyType):
+			framework.LoggingRespondErrWithMsg(c, err, errMsg, http.StatusBadRequest)
+		default:
+			framework.LoggingRespondErrWithMsg(c, err, errMsg, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	resp := RotateKeyResponse{
+		ID:           rotated.ID,
+		Version:      rotated.Version,
+		PublicKeyJWK: rotated.PublicKeyJWK,
 	}
 	framework.Respond(c, resp, http.StatusOK)
 }
@@ -156,15 +360,17 @@ type RevokeKeyResponse struct {
 // RevokeKey godoc
 //
 //	@Summary		Revoke a key
-//	@Description	Marks a key as being revoked, along with the timestamps of when it was revoked.
-//	@Description	NB: the key can still be used for signing. This will likely be addressed before v1 is released.
+//	@Description	Marks a key as being revoked, along with the timestamps of when it was revoked. By default every version
+//	@Description	of the key is revoked. When a version is given, only that version is revoked and signing falls back to the
+//	@Description	newest remaining non-revoked version.
 //	@Tags			KeyStore
 //	@Accept			json
 //	@Produce		json
-//	@Param			id	path		string	true	"ID of the key to revoke"
-//	@Success		200	{object}	RevokeKeyResponse
-//	@Failure		400	{string}	string	"Bad request"
-//	@Failure		500	{string}	string	"Internal server error"
+//	@Param			id		path		string	true	"ID of the key to revoke"
+//	@Param			version	query		number	false	"Version of the key to revoke. When not set, all versions are revoked."
+//	@Success		200		{object}	RevokeKeyResponse
+//	@Failure		400		{string}	string	"Bad request"
+//	@Failure		500		{string}	string	"Internal server error"
 //	@Router			/v1/keys/{id} [delete]
 func (ksr *KeyStoreRouter) RevokeKey(c *gin.Context) {
 	id := framework.GetParam(c, IDParam)
@@ -174,7 +380,18 @@ func (ksr *KeyStoreRouter) RevokeKey(c *gin.Context) {
 		return
 	}
 
-	if err := ksr.service.RevokeKey(c, keystore.RevokeKeyRequest{ID: *id}); err != nil {
+	request := keystore.RevokeKeyRequest{ID: *id}
+	if version := framework.GetQueryValue(c, VersionParam); version != nil {
+		parsedVersion, err := strconv.Atoi(*version)
+		if err != nil || parsedVersion < 1 {
+			errMsg := "revoke key request encountered a problem with the `version` query param"
+			framework.LoggingRespondErrMsg(c, errMsg, http.StatusBadRequest)
+			return
+		}
+		request.Version = parsedVersion
+	}
+
+	if err := ksr.service.RevokeKey(c, request); err != nil {
 		errMsg := fmt.Sprintf("could not revoke key for id: %s", *id)
 		framework.LoggingRespondErrWithMsg(c, err, errMsg, http.StatusInternalServerError)
 		return
@@ -0,0 +1,359 @@
+package router
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/tbd54566975/ssi-service/pkg/server/framework"
+	svcframework "github.com/tbd54566975/ssi-service/pkg/service/framework"
+	"github.com/tbd54566975/ssi-service/pkg/service/manifest/model"
+	"github.com/tbd54566975/ssi-service/pkg/service/oid4vci"
+)
+
+type OID4VCIRouter struct {
+	service *oid4vci.Service
+}
+
+func NewOID4VCIRouter(s svcframework.Service) (*OID4VCIRouter, error) {
+	if s == nil {
+		return nil, errors.New("service cannot be nil")
+	}
+	service, ok := s.(*oid4vci.Service)
+	if !ok {
+		return nil, fmt.Errorf("could not create oid4vci router with service type: %s", s.Type())
+	}
+	return &OID4VCIRouter{service: service}, nil
+}
+
+type CreateCredentialOfferRequest struct {
+	// ID of the issuance template the offered credentials are issued with. Every credential of the template is
+	// offered. Templates whose claims are JSON paths into a credential application can't be offered.
+	IssuanceTemplateID string `json:"issuanceTemplateId" validate:"required"`
+
+	// Overrides the template's claims, keyed by output descriptor ID.
+	// Optional.
+	CredentialOverrides map[string]model.CredentialOverride `json:"credentialOverrides,omitempty"`
+
+	// Whether the offer has a pre-authorized code. When false, wallets redeem the offer through the authorization code
+	// flow, with the offer's ID as the `issuer_state`.
+	PreAuthorized bool `json:"preAuthorized"`
+
+	// Whether the holder must enter a numeric transaction code to redeem the pre-authorized code. The code is only
+	// returned when the offer is created, and must be sent to the holder out of band.
+	RequireTxCode bool `json:"requireTxCode"`
+
+	// Length of the transaction code, between 4 and 12. Defaults to 6.
+	TxCodeLength int `json:"txCodeLength,omitempty"`
+
+	// When the offer expires, as an RFC3339 time. Defaults to 24 hours from now.
+	// Optional.
+	Expiration string `json:"expiration,omitempty"`
+}
+
+func (r CreateCredentialOfferRequest) toServiceRequest() (*oid4vci.CreateOfferRequest, error) {
+	req := &oid4vci.CreateOfferRequest{
+		IssuanceTemplateID:  r.IssuanceTemplateID,
+		CredentialOverrides: r.CredentialOverrides,
+		PreAuthorized:       r.PreAuthorized,
+		RequireTxCode:       r.RequireTxCode,
+		TxCodeLength:        r.TxCodeLength,
+	}
+	if r.Expiration != "" {
+		expiration, err := time.Parse(time.RFC3339, r.Expiration)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing expiration")
+		}
+		req.Expiration = &expiration
+	}
+	return req, nil
+}
+
+type CreateCredentialOfferResponse struct {
+	Offer *oid4vci.Offer `json:"offer"`
+}
+
+// CreateCredentialOffer godoc
+//
+//	@Summary		Create an OID4VCI Credential Offer
+//	@Description	Create an OpenID for Verifiable Credential Issuance credential offer for the credentials of an
+//	@Description	issuance template, according to https://openid.net/specs/openid-4-verifiable-credential-issuance-1_0.html.
+//	@Description	Wallets are invoked with `credentialOfferDeepLink`, and fetch the offer from `credentialOfferUri`.
+//	@Tags			OID4VCI
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		CreateCredentialOfferRequest	true	"request body"
+//	@Success		201		{object}	CreateCredentialOfferResponse
+//	@Failure		400		{string}	string	"Bad request"
+//	@Failure		500		{string}	string	"Internal server error"
+//	@Router			/v1/oidc/issuer/offers [put]
+func (or OID4VCIRouter) CreateCredentialOffer(c *gin.Context) {
+	var request CreateCredentialOfferRequest
+	errMsg := "invalid create credential offer request"
+	if err := framework.Decode(c.Request, &request); err != nil {
+		framework.LoggingRespondErrWithMsg(c, err, errMsg, http.StatusBadRequest)
+		return
+	}
+	if err := framework.ValidateRequest(request); err != nil {
+		framework.LoggingRespondErrWithMsg(c, err, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	req, err := request.toServiceRequest()
+	if err != nil {
+		framework.LoggingRespondErrWithMsg(c, err, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	offer, err := or.service.CreateOffer(c, *req)
+	if err != nil {
+		framework.LoggingRespondErrWithMsg(c, err, "could not create credential offer", http.StatusBadRequest)
+		return
+	}
+	framework.Respond(c, CreateCredentialOfferResponse{Offer: offer}, http.StatusCreated)
+}
+
+// GetCredentialOffer godoc
+//
+//	@Summary		Get an OID4VCI Credential Offer
+//	@Description	Get a credential offer, which wallets fetch from the offer's `credential_offer_uri`.
+//	@Tags			OID4VCI
+//	@Produce		json
+//	@Param			id	path		string	true	"ID"
+//	@Success		200	{object}	oid4vci.CredentialOffer
+//	@Failure		400	{string}	string	"Bad request"
+//	@Failure		404	{string}	string	"Not found"
+//	@Router			/v1/oidc/issuer/offers/{id} [get]
+func (or OID4VCIRouter) GetCredentialOffer(c *gin.Context) {
+	id := framework.GetParam(c, IDParam)
+	if id == nil {
+		framework.LoggingRespondErrMsg(c, "cannot get credential offer without an ID", http.StatusBadRequest)
+		return
+	}
+
+	offer, err := or.service.GetOffer(c, *id)
+	if err != nil {
+		framework.LoggingRespondErrWithMsg(c, err, "could not get credential offer", http.StatusNotFound)
+		return
+	}
+	framework.Respond(c, offer.CredentialOffer, http.StatusOK)
+}
+
+// DeleteCredentialOffer godoc
+//
+//	@Summary		Delete an OID4VCI Credential Offer
+//	@Description	Delete a credential offer, so it can't be redeemed anymore.
+//	@Tags			OID4VCI
+//	@Produce		json
+//	@Param			id	path		string	true	"ID"
+//	@Success		204	{string}	string	"No Content"
+//	@Failure		400	{string}	string	"Bad request"
+//	@Failure		500	{string}	string	"Internal server error"
+//	@Router			/v1/oidc/issuer/offers/{id} [delete]
+func (or OID4VCIRouter) DeleteCredentialOffer(c *gin.Context) {
+	id := framework.GetParam(c, IDParam)
+	if id == nil {
+		framework.LoggingRespondErrMsg(c, "cannot delete credential offer without an ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := or.service.DeleteOffer(c, *id); err != nil {
+		errMsg := fmt.Sprintf("could not delete credential offer with id: %s", *id)
+		framework.LoggingRespondErrWithMsg(c, err, errMsg, http.StatusInternalServerError)
+		return
+	}
+	framework.Respond(c, nil, http.StatusNoContent)
+}
+
+// GetIssuerMetadata godoc
+//
+//	@Summary		Get the OID4VCI Credential Issuer Metadata
+//	@Description	Get the credential issuer metadata. Every output descriptor of every credential manifest is a
+//	@Description	credential configuration, with the ID `<manifest ID>/<output descriptor ID>`.
+//	@Tags			OID4VCI
+//	@Produce		json
+//	@Success		200	{object}	oid4vci.IssuerMetadata
+//	@Failure		500	{string}	string	"Internal server error"
+//	@Router			/v1/oidc/issuer/.well-known/openid-credential-issuer [get]
+func (or OID4VCIRouter) GetIssuerMetadata(c *gin.Context) {
+	metadata, err := or.service.GetIssuerMetadata(c)
+	if err != nil {
+		framework.LoggingRespondErrWithMsg(c, err, "could not get issuer metadata", http.StatusInternalServerError)
+		return
+	}
+	framework.Respond(c, metadata, http.StatusOK)
+}
+
+// GetAuthorizationServerMetadata godoc
+//
+//	@Summary		Get the OID4VCI Authorization Server Metadata
+//	@Description	Get the metadata of the credential issuer's authorization server, as described in
+//	@Description	https://www.rfc-editor.org/rfc/rfc8414.html.
+//	@Tags			OID4VCI
+//	@Produce		json
+//	@Success		200	{object}	oid4vci.AuthorizationServerMetadata
+//	@Router			/v1/oidc/issuer/.well-known/oauth-authorization-server [get]
+func (or OID4VCIRouter) GetAuthorizationServerMetadata(c *gin.Context) {
+	framework.Respond(c, or.service.GetAuthorizationServerMetadata(), http.StatusOK)
+}
+
+// Authorize godoc
+//
+//	@Summary		OID4VCI Authorization Endpoint
+//	@Description	Starts the authorization code flow for a credential offer. The `issuer_state` must be the ID of an
+//	@Description	offer without a pre-authorized code, and PKCE with the S256 method is required. The wallet is
+//	@Description	redirected to `redirect_uri` with the authorization `code`, or with an `error`.
+//	@Tags			OID4VCI
+//	@Param			response_type			query	string	true	"Must be code"
+//	@Param			client_id				query	string	false	"Client ID of the wallet"
+//	@Param			redirect_uri			query	string	true	"Where the wallet is redirected to"
+//	@Param			state					query	string	false	"State returned to the wallet"
+//	@Param			issuer_state			query	string	true	"ID of the credential offer"
+//	@Param			code_challenge			query	string	true	"PKCE code challenge"
+//	@Param			code_challenge_method	query	string	true	"Must be S256"
+//	@Success		302
+//	@Failure		400	{string}	string	"Bad request"
+//	@Router			/v1/oidc/issuer/authorize [get]
+func (or OID4VCIRouter) Authorize(c *gin.Context) {
+	redirectURI, err := url.Parse(c.Query("redirect_uri"))
+	if err != nil || !redirectURI.IsAbs() {
+		framework.LoggingRespondErrMsg(c, "redirect_uri must be an absolute URI", http.StatusBadRequest)
+		return
+	}
+
+	code, err := or.service.Authorize(c, oid4vci.AuthorizationRequest{
+		ResponseType:        c.Query("response_type"),
+		RedirectURI:         redirectURI.String(),
+		IssuerState:         c.Query("issuer_state"),
+		CodeChallenge:       c.Query("code_challenge"),
+		CodeChallengeMethod: c.Query("code_challenge_method"),
+	})
+	query := redirectURI.Query()
+	if err != nil {
+		oauthErr := oid4vci.GetError(err)
+		if oauthErr == nil {
+			framework.LoggingRespondErrWithMsg(c, err, "could not authorize", http.StatusInternalServerError)
+			return
+		}
+		query.Set("error", string(oauthErr.Code))
+		query.Set("error_description", oauthErr.Description)
+	} else {
+		query.Set("code", code)
+	}
+	if state := c.Query("state"); state != "" {
+		query.Set("state", state)
+	}
+	redirectURI.RawQuery = query.Encode()
+	c.Redirect(http.StatusFound, redirectURI.String())
+}
+
+// Token godoc
+//
+//	@Summary		OID4VCI Token Endpoint
+//	@Description	Exchanges a pre-authorized code, along with the transaction code when the offer requires one, or an
+//	@Description	authorization code and its PKCE code verifier, for an access token to the credential endpoint.
+//	@Tags			OID4VCI
+//	@Accept			x-www-form-urlencoded
+//	@Produce		json
+//	@Param			grant_type			formData	string	true	"authorization_code or urn:ietf:params:oauth:grant-type:pre-authorized_code"
+//	@Param			pre-authorized_code	formData	string	false	"Pre-authorized code from the credential offer"
+//	@Param			tx_code				formData	string	false	"Transaction code the holder entered"
+//	@Param			code				formData	string	false	"Authorization code"
+//	@Param			code_verifier		formData	string	false	"PKCE code verifier"
+//	@Param			redirect_uri		formData	string	false	"Redirect URI of the authorization request"
+//	@Success		200					{object}	oid4vci.TokenResponse
+//	@Failure		400					{object}	oid4vci.Error
+//	@Router			/v1/oidc/issuer/token [post]
+func (or OID4VCIRouter) Token(c *gin.Context) {
+	if err := c.Request.ParseForm(); err != nil {
+		respondOAuthError(c, oid4vci.Error{Code: oid4vci.InvalidRequest, Description: "could not parse form"})
+		return
+	}
+
+	form := c.Request.PostForm
+	token, err := or.service.Token(c, oid4vci.TokenRequest{
+		GrantType:         form.Get("grant_type"),
+		PreAuthorizedCode: form.Get("pre-authorized_code"),
+		TxCode:            form.Get("tx_code"),
+		Code:              form.Get("code"),
+		CodeVerifier:      form.Get("code_verifier"),
+		RedirectURI:       form.Get("redirect_uri"),
+	})
+	if err != nil {
+		if oauthErr := oid4vci.GetError(err); oauthErr != nil {
+			respondOAuthError(c, *oauthErr)
+			return
+		}
+		framework.LoggingRespondErrWithMsg(c, err, "could not issue token", http.StatusInternalServerError)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	framework.Respond(c, token, http.StatusOK)
+}
+
+// IssueCredential godoc
+//
+//	@Summary		OID4VCI Credential Endpoint
+//	@Description	Issues an offered credential to the holder, with an access token from the token endpoint. The
+//	@Description	request's proof must be a JWT with the `openid4vci-proof+jwt` typ, signed by the key `kid` refers to
+//	@Description	in the holder's DID document, with the issuer as its audience and the latest `c_nonce`. The
+//	@Description	credential's subject is the holder's DID.
+//	@Tags			OID4VCI
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string						true	"Bearer access token"
+//	@Param			request			body		oid4vci.CredentialRequest	true	"request body"
+//	@Success		200				{object}	oid4vci.CredentialResponse
+//	@Failure		400				{object}	oid4vci.Error
+//	@Failure		401				{object}	oid4vci.Error
+//	@Router			/v1/oidc/issuer/credential [post]
+func (or OID4VCIRouter) IssueCredential(c *gin.Context) {
+	accessToken, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !found || accessToken == "" {
+		respondOAuthError(c, oid4vci.Error{Code: oid4vci.InvalidToken, Description: "a bearer access token is required"})
+		return
+	}
+
+	var request oid4vci.CredentialRequest
+	body, err := io.ReadAll(c.Request.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &request)
+	}
+	if err != nil {
+		respondOAuthError(c, oid4vci.Error{Code: oid4vci.InvalidCredentialRequest, Description: "could not parse credential request"})
+		return
+	}
+
+	credentialResponse, err := or.service.IssueCredential(c, accessToken, request)
+	if err != nil {
+		if oauthErr := oid4vci.GetError(err); oauthErr != nil {
+			respondOAuthError(c, *oauthErr)
+			return
+		}
+		framework.LoggingRespondErrWithMsg(c, err, "could not issue credential", http.StatusInternalServerError)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	framework.Respond(c, credentialResponse, http.StatusOK)
+}
+
+// respondOAuthError responds with an OAuth 2.0 error. Invalid access tokens get a 401 with a WWW-Authenticate header,
+// as described in https://www.rfc-editor.org/rfc/rfc6750.html#section-3.
+func respondOAuthError(c *gin.Context, oauthErr oid4vci.Error) {
+	logrus.WithError(&oauthErr).Warn("oid4vci request failed")
+	status := http.StatusBadRequest
+	if oauthErr.Code == oid4vci.InvalidToken {
+		status = http.StatusUnauthorized
+		c.Header("WWW-Authenticate", fmt.Sprintf("Bearer error=%q", oauthErr.Code))
+	}
+	c.Header("Cache-Control", "no-store")
+	framework.Respond(c, oauthErr, status)
+}
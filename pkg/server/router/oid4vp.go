@@ -0,0 +1,187 @@
+package router
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/TBD54566975/ssi-sdk/credential/exchange"
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+	"github.com/pkg/errors"
+
+	"github.com/tbd54566975/ssi-service/internal/keyaccess"
+	"github.com/tbd54566975/ssi-service/pkg/server/framework"
+	"github.com/tbd54566975/ssi-service/pkg/service/presentation/model"
+)
+
+const requestObjectContentType = "application/oauth-authz-req+jwt"
+
+type CreateAuthorizationRequestRequest struct {
+	// DID of the verifier, used as the client_id of the request. The DID must have been previously created with the
+	// DID API.
+	ClientID string `json:"clientId" validate:"required"`
+
+	// The id of the verificationMethod (see https://www.w3.org/TR/did-core/#verification-methods) whose privateKey is
+	// stored in ssi-service, and signs the request object. The verificationMethod must be part of the did document
+	// associated with `clientId`.
+	VerificationMethodID string `json:"verificationMethodId" validate:"required" example:"did:key:z6MkkZDjunoN4gyPMx5TSy7Mfzw22D2RZQZUcx46bii53Ex3#z6MkkZDjunoN4gyPMx5TSy7Mfzw22D2RZQZUcx46bii53Ex3"`
+
+	// ID of the presentation definition to request a presentation for.
+	PresentationDefinitionID string `json:"presentationDefinitionId" validate:"required"`
+
+	// When the request expires, as an RFC3339 time. Responses to expired requests are rejected.
+	// Optional.
+	Expiration string `json:"expiration,omitempty"`
+}
+
+func (r CreateAuthorizationRequestRequest) toServiceRequest() (*model.CreateAuthorizationRequestRequest, error) {
+	req := &model.CreateAuthorizationRequestRequest{
+		ClientID:                 r.ClientID,
+		VerificationMethodID:     r.VerificationMethodID,
+		PresentationDefinitionID: r.PresentationDefinitionID,
+	}
+	if r.Expiration != "" {
+		expiration, err := time.Parse(time.RFC3339, r.Expiration)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing expiration")
+		}
+		req.Expiration = &expiration
+	}
+	return req, nil
+}
+
+type CreateAuthorizationRequestResponse struct {
+	AuthorizationRequest *model.AuthorizationRequest `json:"authorizationRequest"`
+}
+
+// CreateAuthorizationRequest godoc
+//
+//	@Summary		Create an OID4VP Authorization Request
+//	@Description	Create an OpenID for Verifiable Presentations authorization request from an existing Presentation
+//	@Description	Definition, according to https://openid.net/specs/openid-4-verifiable-presentations-1_0.html. The
+//	@Description	signed request object is served from `requestUri`, and wallets are invoked with `authorizationUri`.
+//	@Description	Wallets respond with the `direct_post` response mode to `responseUri`.
+//	@Tags			PresentationRequests
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		CreateAuthorizationRequestRequest	true	"request body"
+//	@Success		201		{object}	CreateAuthorizationRequestResponse
+//	@Failure		400		{string}	string	"Bad request"
+//	@Failure		500		{string}	string	"Internal server error"
+//	@Router			/v1/presentations/oid4vp/requests [put]
+func (pr PresentationRouter) CreateAuthorizationRequest(c *gin.Context) {
+	var request CreateAuthorizationRequestRequest
+	errMsg := "invalid create authorization request request"
+	if err := framework.Decode(c.Request, &request); err != nil {
+		framework.LoggingRespondErrWithMsg(c, err, errMsg, http.StatusBadRequest)
+		return
+	}
+	if err := framework.ValidateRequest(request); err != nil {
+		framework.LoggingRespondErrWithMsg(c, err, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	req, err := request.toServiceRequest()
+	if err != nil {
+		framework.LoggingRespondErrWithMsg(c, err, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	authorizationRequest, err := pr.service.CreateAuthorizationRequest(c, *req)
+	if err != nil {
+		framework.LoggingRespondErrWithMsg(c, err, "could not create authorization request", http.StatusInternalServerError)
+		return
+	}
+	framework.Respond(c, CreateAuthorizationRequestResponse{AuthorizationRequest: authorizationRequest}, http.StatusCreated)
+}
+
+// GetRequestObject godoc
+//
+//	@Summary		Get an OID4VP Request Object
+//	@Description	Get the signed request object of an OID4VP authorization request, which wallets fetch from the
+//	@Description	request's `request_uri`.
+//	@Tags			PresentationRequests
+//	@Produce		application/oauth-authz-req+jwt
+//	@Param			id	path		string	true	"ID"
+//	@Success		200	{string}	string	"The signed request object"
+//	@Failure		400	{string}	string	"Bad request"
+//	@Failure		404	{string}	string	"Not found"
+//	@Router			/v1/presentations/oid4vp/requests/{id} [get]
+func (pr PresentationRouter) GetRequestObject(c *gin.Context) {
+	id := framework.GetParam(c, IDParam)
+	if id == nil {
+		framework.LoggingRespondErrMsg(c, "cannot get request object without an ID", http.StatusBadRequest)
+		return
+	}
+
+	authorizationRequest, err := pr.service.GetAuthorizationRequest(c, *id)
+	if err != nil {
+		framework.LoggingRespondErrWithMsg(c, err, "could not get authorization request", http.StatusNotFound)
+		return
+	}
+	c.Data(http.StatusOK, requestObjectContentType, []byte(authorizationRequest.RequestJWT))
+}
+
+// SubmitAuthorizationResponse godoc
+//
+//	@Summary		Submit an OID4VP Authorization Response
+//	@Description	Accepts a wallet's response to an OID4VP authorization request with the `direct_post` response
+//	@Description	mode, as described in https://openid.net/specs/openid-4-verifiable-presentations-1_0.html#name-response-mode-direct_post.
+//	@Description	The `state` must be the ID of an authorization request that hasn't expired, and hasn't been responded
+//	@Description	to. The `vp_token` must contain the request's `nonce`, and have the request's `client_id` in its
+//	@Description	audience. An SD-JWT `vp_token` must have them in its key binding JWT. A Presentation Submission is then
+//	@Description	created from the `vp_token` and `presentation_submission`. Wallets that can't or won't present send an
+//	@Description	`error` and `error_description` instead of the `vp_token` and `presentation_submission`, which are set on the
+//	@Description	authorization request, and no submission is created.
+//	@Tags			PresentationSubmissions
+//	@Accept			x-www-form-urlencoded
+//	@Produce		json
+//	@Param			vp_token				formData	string		false	"Verifiable Presentation encoded as a JWT, or an SD-JWT with a key binding JWT"
+//	@Param			presentation_submission	formData	string		false	"Presentation Submission, encoded as JSON"
+//	@Param			error					formData	string		false	"Error code, sent instead of a vp_token"
+//	@Param			error_description		formData	string		false	"Description of the error"
+//	@Param			state					formData	string		true	"State of the authorization request"
+//	@Success		200						{object}	Operation	"The type of response is Submission once the operation has finished."
+//	@Success		204						{string}	string		"No Content"
+//	@Failure		400						{string}	string		"Bad request"
+//	@Router			/v1/presentations/oid4vp/response [post]
+func (pr PresentationRouter) SubmitAuthorizationResponse(c *gin.Context) {
+	errMsg := "invalid authorization response"
+	if err := c.Request.ParseForm(); err != nil {
+		framework.LoggingRespondErrWithMsg(c, err, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	// wallets send an error instead when the holder declines the request, or when it can't be satisfied
+	if walletErr := c.Request.PostForm.Get("error"); walletErr != "" {
+		errResponse := model.AuthorizationErrorResponse{
+			Error:            walletErr,
+			ErrorDescription: c.Request.PostForm.Get("error_description"),
+			State:            c.Request.PostForm.Get("state"),
+		}
+		if err := pr.service.SubmitAuthorizationErrorResponse(c, errResponse); err != nil {
+			framework.LoggingRespondErrWithMsg(c, err, errMsg, http.StatusBadRequest)
+			return
+		}
+		framework.Respond(c, nil, http.StatusNoContent)
+		return
+	}
+
+	var submission exchange.PresentationSubmission
+	if err := json.Unmarshal([]byte(c.Request.PostForm.Get("presentation_submission")), &submission); err != nil {
+		framework.LoggingRespondErrWithMsg(c, err, "could not parse presentation_submission", http.StatusBadRequest)
+		return
+	}
+	response := model.AuthorizationResponse{
+		VPToken:                keyaccess.JWT(c.Request.PostForm.Get("vp_token")),
+		PresentationSubmission: submission,
+		State:                  c.Request.PostForm.Get("state"),
+	}
+
+	operation, err := pr.service.SubmitAuthorizationResponse(c, response)
+	if err != nil {
+		framework.LoggingRespondErrWithMsg(c, err, errMsg, http.StatusBadRequest)
+		return
+	}
+	framework.Respond(c, routerModel(*operation), http.StatusOK)
+}
@@ -15,11 +15,14 @@ import (
 
 	credint "github.com/tbd54566975/ssi-service/internal/credential"
 	"github.com/tbd54566975/ssi-service/internal/keyaccess"
+	"github.com/tbd54566975/ssi-service/internal/verification"
 	"github.com/tbd54566975/ssi-service/pkg/server/framework"
 	"github.com/tbd54566975/ssi-service/pkg/server/pagination"
 	svcframework "github.com/tbd54566975/ssi-service/pkg/service/framework"
 	"github.com/tbd54566975/ssi-service/pkg/service/presentation"
 	"github.com/tbd54566975/ssi-service/pkg/service/presentation/model"
+	"github.com/tbd54566975/ssi-service/pkg/service/presentation/policy"
+	presentationstorage "github.com/tbd54566975/ssi-service/pkg/service/presentation/storage"
 )
 
 type PresentationRouter struct {
@@ -39,7 +42,25 @@ func NewPresentationRouter(s svcframework.Service) (*PresentationRouter, error)
 
 type VerifyPresentationRequest struct {
 	// A JWT that encodes a verifiable presentation according to https://www.w3.org/TR/vc-data-model/#json-web-token
-	PresentationJWT *keyaccess.JWT `json:"presentationJwt,omitempty" validate:"required"`
+	// Exactly one of `presentationJwt` or `presentationSdJwt` must be set.
+	PresentationJWT *keyaccess.JWT `json:"presentationJwt,omitempty" validate:"required_without=PresentationSDJWT"`
+
+	// An SD-JWT credential presented by its subject, ending with a key binding JWT signed by one of the subject's
+	// verification methods.
+	PresentationSDJWT *keyaccess.SDJWT `json:"presentationSdJwt,omitempty" validate:"required_without=PresentationJWT"`
+
+	// The audience the key binding JWT of `presentationSdJwt` must be for. Required with `presentationSdJwt`.
+	Audience string `json:"audience,omitempty" validate:"required_with=PresentationSDJWT"`
+
+	// The nonce the key binding JWT of `presentationSdJwt` must have. Required with `presentationSdJwt`.
+	Nonce string `json:"nonce,omitempty" validate:"required_with=PresentationSDJWT"`
+
+	// DIDs trusted to issue the presented credentials. When set, a credential from any other issuer fails the
+	// `issuerTrust` check.
+	TrustedIssuers []string `json:"trustedIssuers,omitempty"`
+
+	// ID of a presentation definition whose constraints the presentation's submission must satisfy.
+	PresentationDefinitionID string `json:"presentationDefinitionId,omitempty"`
 }
 
 type VerifyPresentationResponse struct {
@@ -48,20 +69,27 @@ type VerifyPresentationResponse struct {
 
 	// The reason why this presentation couldn't be verified.
 	Reason string `json:"reason,omitempty"`
+
+	// A failure for each credential that is revoked or suspended, or whose status couldn't be checked.
+	StatusFailures []verification.StatusFailure `json:"statusFailures,omitempty"`
+
+	// Each check that was run on the presentation, with its outcome, and an error code when it failed. The report of
+	// each presented credential is in `credentials`.
+	Report verification.Report `json:"report"`
 }
 
 // VerifyPresentation godoc
 //
 //	@Summary		Verifies a Verifiable Presentation
-//	@Description	Verifies a given presentation. The system does the following levels of verification:
-//	@Description	1. Makes sure the presentation has a valid signature
-//	@Description	2. Makes sure the presentation is not expired
-//	@Description	3. Makes sure the presentation complies with https://www.w3.org/TR/vc-data-model/#presentations-0 of VC Data Model v1.1
-//	@Description	4. For each credential in the presentation, makes sure:
-//	@Description	a. Makes sure the credential has a valid signature
-//	@Description	b. Makes sure the credential is not expired
-//	@Description	c. Makes sure the credential complies with the VC Data Model
-//	@Description	d. If the credential has a schema, makes sure its data complies with the schema
+//	@Description	Verifies a given presentation. The system runs the following checks, and reports on each of them:
+//	@Description	1. `didResolution`: resolves the DID of the presentation's holder
+//	@Description	2. `signature`: makes sure the presentation has a valid signature
+//	@Description	3. `presentationDefinition`: if a presentation definition is given, makes sure the presentation's submission satisfies its constraints
+//	@Description	4. For each credential in the presentation, runs the checks of credential verification:
+//	@Description	`didResolution`, `signature`, `dataModel`, `expiry`, `schema`, `status` and `issuerTrust`
+//	@Description	For an SD-JWT presentation, the holder is the credential's subject, and the signature is the key binding JWT's,
+//	@Description	which must be for the given audience and nonce, and be recent. The schema check is skipped, since holders may
+//	@Description	withhold claims, and the credential must satisfy every input descriptor of the presentation definition.
 //	@Tags			Presentations
 //	@Accept			json
 //	@Produce		json
@@ -82,17 +110,35 @@ func (pr PresentationRouter) VerifyPresentation(c *gin.Context) {
 		framework.LoggingRespondError(c, err, http.StatusBadRequest)
 		return
 	}
+	if request.PresentationJWT != nil && request.PresentationSDJWT != nil {
+		framework.LoggingRespondErrMsg(c, "only one of presentationJwt or presentationSdJwt can be set", http.StatusBadRequest)
+		return
+	}
 
 	verificationResult, err := pr.service.VerifyPresentation(c, presentation.VerifyPresentationRequest{
-		PresentationJWT: request.PresentationJWT,
+		PresentationJWT:          request.PresentationJWT,
+		PresentationSDJWT:        request.PresentationSDJWT,
+		Audience:                 request.Audience,
+		Nonce:                    request.Nonce,
+		TrustedIssuers:           request.TrustedIssuers,
+		PresentationDefinitionID: request.PresentationDefinitionID,
 	})
 	if err != nil {
 		errMsg := "could not verify presentation"
+		if errors.Is(err, presentationstorage.ErrDefinitionNotFound) {
+			framework.LoggingRespondErrWithMsg(c, err, errMsg, http.StatusBadRequest)
+			return
+		}
 		framework.LoggingRespondErrWithMsg(c, err, errMsg, http.StatusInternalServerError)
 		return
 	}
 
-	resp := VerifyPresentationResponse{Verified: verificationResult.Verified, Reason: verificationResult.Reason}
+	resp := VerifyPresentationResponse{
+		Verified:       verificationResult.Verified,
+		Reason:         verificationResult.Reason,
+		StatusFailures: verificationResult.StatusFailures,
+		Report:         verificationResult.Report,
+	}
 	framework.Respond(c, resp, http.StatusOK)
 }
 
@@ -102,11 +148,18 @@ type CreatePresentationDefinitionRequest struct {
 	Format                 *exchange.ClaimFormat            `json:"format,omitempty" validate:"omitempty,dive"`
 	InputDescriptors       []exchange.InputDescriptor       `json:"inputDescriptors" validate:"required,dive"`
 	SubmissionRequirements []exchange.SubmissionRequirement `json:"submissionRequirements,omitempty" validate:"omitempty,dive"`
+
+	// Policy that automatically approves or denies the submissions made against the definition. Submissions are left
+	// pending for a manual review when there is no policy, or when it can't decide.
+	Policy *policy.Policy `json:"policy,omitempty"`
 }
 
 type CreatePresentationDefinitionResponse struct {
 	PresentationDefinition exchange.PresentationDefinition `json:"presentation_definition,omitempty"`
 
+	// Policy that automatically approves or denies the submissions made against the definition.
+	Policy *policy.Policy `json:"policy,omitempty"`
+
 	// Signed envelope that contains the PresentationDefinition created using the privateKey of the author of the
 	// definition.
 	PresentationDefinitionJWT keyaccess.JWT `json:"presentationDefinitionJwt,omitempty"`
@@ -142,8 +195,15 @@ func (pr PresentationRouter) CreateDefinition(c *gin.Context) {
 		framework.LoggingRespondErrWithMsg(c, err, errMsg, http.StatusBadRequest)
 		return
 	}
+	if request.Policy != nil {
+		if err = request.Policy.Validate(); err != nil {
+			framework.LoggingRespondErrWithMsg(c, err, errMsg, http.StatusBadRequest)
+			return
+		}
+	}
 	serviceResp, err := pr.service.CreatePresentationDefinition(c, model.CreatePresentationDefinitionRequest{
 		PresentationDefinition: *def,
+		Policy:                 request.Policy,
 	})
 	if err != nil {
 		framework.LoggingRespondErrWithMsg(c, err, errMsg, http.StatusInternalServerError)
@@ -152,6 +212,7 @@ func (pr PresentationRouter) CreateDefinition(c *gin.Context) {
 
 	resp := CreatePresentationDefinitionResponse{
 		PresentationDefinition: serviceResp.PresentationDefinition,
+		Policy:                 serviceResp.Policy,
 	}
 	framework.Respond(c, resp, http.StatusCreated)
 }
@@ -189,6 +250,9 @@ func definitionFromRequest(request CreatePresentationDefinitionRequest) (*exchan
 
 type GetPresentationDefinitionResponse struct {
 	PresentationDefinition exchange.PresentationDefinition `json:"presentation_definition,omitempty"`
+
+	// Policy that automatically approves or denies the submissions made against the definition.
+	Policy *policy.Policy `json:"policy,omitempty"`
 }
 
 // GetDefinition godoc
@@ -219,6 +283,7 @@ func (pr PresentationRouter) GetDefinition(c *gin.Context) {
 
 	resp := GetPresentationDefinitionResponse{
 		PresentationDefinition: def.PresentationDefinition,
+		Policy:                 def.Policy,
 	}
 	framework.Respond(c, resp, http.StatusOK)
 }
@@ -323,7 +388,7 @@ func (r CreateSubmissionRequest) toServiceRequest() (*model.CreateSubmissionRequ
 // CreateSubmission godoc
 //
 //	@Summary		Create a Presentation Submission
-//	@Description	Accepts a Presentation Submission (https://identity.foundation/presentation-exchange/spec/v2.0.0/#presentation-submission) in this server ready to be reviewed.
+//	@Description	Accepts a Presentation Submission (https://identity.foundation/presentation-exchange/spec/v2.0.0/#presentation-submission) in this server ready to be reviewed. When the Presentation Definition has a policy, the submission is approved or denied by it, and the operation is done.
 //	@Tags			PresentationSubmissions
 //	@Accept			json
 //	@Produce		json
@@ -353,8 +418,7 @@ func (pr PresentationRouter) CreateSubmission(c *gin.Context) {
 		return
 	}
 
-	resp := Operation{ID: operation.ID}
-	framework.Respond(c, resp, http.StatusCreated)
+	framework.Respond(c, routerModel(*operation), http.StatusCreated)
 }
 
 type GetSubmissionResponse struct {
@@ -32,7 +32,7 @@ func NewWebhookRouter(s svcframework.Service) (*WebhookRouter, error) {
 type CreateWebhookRequest struct {
 	// The noun (entity) for the new webhook.eg: Credential
 	Noun webhook.Noun `json:"noun" validate:"required"`
-	// The verb for the new webhook.eg: Create
+	// The verb for the new webhook.eg: Create. Expire can only be used with the Credential noun.
 	Verb webhook.Verb `json:"verb" validate:"required"`
 	// The URL to post the output of this request to Noun.Verb action to.
 	URL string `json:"url" validate:"required"`
@@ -3,22 +3,29 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"time"
 
 	sdkutil "github.com/TBD54566975/ssi-sdk/util"
 	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+	"github.com/sirupsen/logrus"
 	swaggerfiles "github.com/swaggo/files"
 	ginswagger "github.com/swaggo/gin-swagger"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 
 	"github.com/tbd54566975/ssi-service/config"
+	credint "github.com/tbd54566975/ssi-service/internal/credential"
 	"github.com/tbd54566975/ssi-service/pkg/server/framework"
 	"github.com/tbd54566975/ssi-service/pkg/server/middleware"
 	"github.com/tbd54566975/ssi-service/pkg/server/router"
 	"github.com/tbd54566975/ssi-service/pkg/service"
 	didsvc "github.com/tbd54566975/ssi-service/pkg/service/did"
 	svcframework "github.com/tbd54566975/ssi-service/pkg/service/framework"
+	"github.com/tbd54566975/ssi-service/pkg/service/oid4vci"
+	"github.com/tbd54566975/ssi-service/pkg/service/presentation"
 	"github.com/tbd54566975/ssi-service/pkg/service/webhook"
 )
 
@@ -30,6 +37,7 @@ const (
 	OperationPrefix         = "/operations"
 	DIDsPrefix              = "/dids"
 	ResolverPrefix          = "/resolver"
+	DereferencerPrefix      = "/dereferencer"
 	SchemasPrefix           = "/schemas"
 	CredentialsPrefix       = "/credentials"
 	StatusPrefix            = "/status"
@@ -43,8 +51,10 @@ const (
 	ResponsesPrefix         = "/responses"
 	KeyStorePrefix          = "/keys"
 	VerificationPath        = "/verification"
+	UsagePath               = "/usage"
 	WebhookPrefix           = "/webhooks"
 	DIDConfigurationsPrefix = "/did-configurations"
+	OIDCIssuerPrefix        = "/oidc/issuer"
 
 	batchSuffix = "/batch"
 )
@@ -75,6 +85,13 @@ func NewSSIServer(shutdown chan os.Signal, cfg config.SSIServiceConfig) (*SSISer
 	engine.StaticFile("swagger.yaml", "./doc/swagger.yaml")
 	engine.GET(SwaggerPrefix, ginswagger.WrapHandler(swaggerfiles.Handler, ginswagger.URL("/swagger.yaml")))
 
+	// serve stored did:web documents at the paths they are resolved from
+	if cfg.Server.EnableDIDWebHosting {
+		if err = DIDWebAPI(engine, ssi.DID, cfg.Server.DIDWebHostingDomains, cfg.Server.DIDWebCacheMaxAge); err != nil {
+			return nil, sdkutil.LoggingErrorMsg(err, "unable to instantiate DID web API")
+		}
+	}
+
 	// register all v1 routers
 	v1 := engine.Group(V1Prefix)
 	if err = KeyStoreAPI(v1, ssi.KeyStore); err != nil {
@@ -107,6 +124,19 @@ func NewSSIServer(shutdown chan os.Signal, cfg config.SSIServiceConfig) (*SSISer
 	if err = DIDConfigurationAPI(v1, ssi.DIDConfiguration); err != nil {
 		return nil, sdkutil.LoggingErrorMsg(err, "unable to instantiate DIDConfiguration API")
 	}
+	if err = OID4VCIAPI(v1, ssi.OID4VCI); err != nil {
+		return nil, sdkutil.LoggingErrorMsg(err, "unable to instantiate OID4VCI API")
+	}
+
+	// run the background jobs, stopping them before the server shuts down
+	backgroundCtx, stopBackgroundJobs := context.WithCancel(context.Background())
+	go ssi.Credential.RunStatusListRefresher(backgroundCtx)
+	go ssi.Credential.RunExpirySweeper(backgroundCtx, publishCredentialExpired(ssi.Webhook))
+	go ssi.DID.RunIONOperationProcessor(backgroundCtx)
+	httpServer.RegisterPreShutdownHook(func(_ context.Context) error {
+		stopBackgroundJobs()
+		return nil
+	})
 
 	return &SSIServer{
 		Server:       httpServer,
@@ -115,6 +145,18 @@ func NewSSIServer(shutdown chan os.Signal, cfg config.SSIServiceConfig) (*SSISer
 	}, nil
 }
 
+// publishCredentialExpired returns a function that publishes an Expire webhook for an expired credential.
+func publishCredentialExpired(webhookService *webhook.Service) func(context.Context, credint.Container) {
+	return func(ctx context.Context, expired credint.Container) {
+		payload, err := json.Marshal(router.GetCredentialResponse{ID: expired.ID, Container: expired})
+		if err != nil {
+			logrus.WithError(err).Errorf("marshalling expired credential<%s>", expired.ID)
+			return
+		}
+		webhookService.Publish(ctx, webhook.Credential, webhook.Expire, payload)
+	}
+}
+
 // setUpEngine creates the gin engine and sets up the middleware based on config
 func setUpEngine(cfg config.ServerConfig, shutdown chan os.Signal) *gin.Engine {
 	gin.ForceConsoleColor()
@@ -155,7 +197,9 @@ func KeyStoreAPI(rg *gin.RouterGroup, service svcframework.Service) (err error)
 	config.SetServicePath(svcframework.KeyStore, KeyStorePrefix)
 	keyStoreAPI := rg.Group(KeyStorePrefix)
 	keyStoreAPI.PUT("", keyStoreRouter.StoreKey)
+	keyStoreAPI.PUT("/generate", keyStoreRouter.GenerateKey)
 	keyStoreAPI.GET("/:id", keyStoreRouter.GetKeyDetails)
+	keyStoreAPI.PUT("/:id/rotate", keyStoreRouter.RotateKey)
 	keyStoreAPI.DELETE("/:id", keyStoreRouter.RevokeKey)
 	return
 }
@@ -177,11 +221,28 @@ func DecentralizedIdentityAPI(rg *gin.RouterGroup, service *didsvc.Service, did
 	didAPI.PUT("/:method/batch", middleware.Webhook(webhookService, webhook.DID, webhook.BatchCreate), batchDIDRouter.BatchCreateDIDs)
 	didAPI.GET("/:method", didRouter.ListDIDsByMethod)
 	didAPI.GET("/:method/:id", didRouter.GetDIDByMethod)
+	didAPI.GET("/:method/:id/versions", didRouter.ListDIDVersions)
 	didAPI.DELETE("/:method/:id", didRouter.SoftDeleteDIDByMethod)
+	didAPI.PUT("/:method/:id/restore", didRouter.RestoreDIDByMethod)
+	didAPI.DELETE("/:method/:id/purge", didRouter.PurgeDIDByMethod)
 	didAPI.GET(ResolverPrefix+"/:id", didRouter.ResolveDID)
+	didAPI.GET(DereferencerPrefix+"/*id", didRouter.DereferenceDIDURL)
 	return
 }
 
+// DIDWebAPI registers the HTTP handlers that host did:web documents for the given domains. Only documents of DIDs
+// without a path, or with a single path segment, are served.
+func DIDWebAPI(engine *gin.Engine, service svcframework.Service, domains []string, cacheMaxAge time.Duration) error {
+	didWebRouter, err := router.NewDIDWebRouter(service, domains, cacheMaxAge)
+	if err != nil {
+		return sdkutil.LoggingErrorMsg(err, "creating DID web router")
+	}
+
+	engine.GET(router.DIDWebWellKnownPath, didWebRouter.GetDIDDocument)
+	engine.GET(router.DIDWebPathDocumentPath, didWebRouter.GetDIDDocument)
+	return nil
+}
+
 // SchemaAPI registers all HTTP handlers for the Schema Service
 func SchemaAPI(rg *gin.RouterGroup, service svcframework.Service, webhookService *webhook.Service) (err error) {
 	schemaRouter, err := router.NewSchemaRouter(service)
@@ -229,6 +290,7 @@ func CredentialAPI(rg *gin.RouterGroup, service svcframework.Service, webhookSer
 	credentialAPI.GET("/:id"+StatusPrefix, credRouter.GetCredentialStatus)
 	credentialAPI.PUT("/:id"+StatusPrefix, credRouter.UpdateCredentialStatus)
 	credentialAPI.PUT(StatusPrefix+batchSuffix, credRouter.BatchUpdateCredentialStatus)
+	credentialAPI.GET(StatusPrefix+UsagePath, credRouter.GetStatusListUsage)
 	credentialAPI.GET(StatusPrefix+"/:id", credRouter.GetCredentialStatusList)
 	return
 }
@@ -263,6 +325,11 @@ func PresentationAPI(rg *gin.RouterGroup, service svcframework.Service, webhookS
 	presSubAPI.GET("/:id", presRouter.GetSubmission)
 	presSubAPI.GET("", presRouter.ListSubmissions)
 	presSubAPI.PUT("/:id/review", presRouter.ReviewSubmission)
+
+	// OID4VP request objects are fetched, and responses posted, by wallets
+	presAPI.PUT(presentation.OID4VPRequestsPath, presRouter.CreateAuthorizationRequest)
+	presAPI.GET(presentation.OID4VPRequestsPath+"/:id", presRouter.GetRequestObject)
+	presAPI.POST(presentation.OID4VPResponsePath, middleware.Webhook(webhookService, webhook.Submission, webhook.Create), presRouter.SubmitAuthorizationResponse)
 	return
 }
 
@@ -376,3 +443,25 @@ func DIDConfigurationAPI(rg *gin.RouterGroup, service svcframework.Service) erro
 
 	return nil
 }
+
+// OID4VCIAPI registers all HTTP handlers for the OID4VCI credential issuer, which is its own authorization server
+func OID4VCIAPI(rg *gin.RouterGroup, service svcframework.Service) error {
+	oid4vciRouter, err := router.NewOID4VCIRouter(service)
+	if err != nil {
+		return sdkutil.LoggingErrorMsg(err, "creating oid4vci router")
+	}
+
+	// make sure the oid4vci service is configured to use the correct path, which is the credential issuer identifier
+	config.SetServicePath(svcframework.OID4VCI, OIDCIssuerPrefix)
+
+	issuerAPI := rg.Group(OIDCIssuerPrefix)
+	issuerAPI.PUT(oid4vci.OffersPath, oid4vciRouter.CreateCredentialOffer)
+	issuerAPI.GET(oid4vci.OffersPath+"/:id", oid4vciRouter.GetCredentialOffer)
+	issuerAPI.DELETE(oid4vci.OffersPath+"/:id", oid4vciRouter.DeleteCredentialOffer)
+	issuerAPI.GET(oid4vci.IssuerMetadataPath, oid4vciRouter.GetIssuerMetadata)
+	issuerAPI.GET(oid4vci.AuthorizationServerMetadataPath, oid4vciRouter.GetAuthorizationServerMetadata)
+	issuerAPI.GET(oid4vci.AuthorizationPath, oid4vciRouter.Authorize)
+	issuerAPI.POST(oid4vci.TokenPath, oid4vciRouter.Token)
+	issuerAPI.POST(oid4vci.CredentialPath, oid4vciRouter.IssueCredential)
+	return nil
+}
@@ -13,17 +13,24 @@ import (
 	"github.com/google/uuid"
 	"github.com/mohae/deepcopy"
 
+	"github.com/tbd54566975/ssi-service/config"
 	"github.com/tbd54566975/ssi-service/pkg/testutil"
 
 	credsdk "github.com/TBD54566975/ssi-sdk/credential"
+	"github.com/TBD54566975/ssi-sdk/credential/integrity"
+	"github.com/TBD54566975/ssi-sdk/credential/status"
 	"github.com/TBD54566975/ssi-sdk/crypto"
 	didsdk "github.com/TBD54566975/ssi-sdk/did"
+	"github.com/TBD54566975/ssi-sdk/did/key"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	credint "github.com/tbd54566975/ssi-service/internal/credential"
 	"github.com/tbd54566975/ssi-service/internal/keyaccess"
 	"github.com/tbd54566975/ssi-service/internal/util"
+	"github.com/tbd54566975/ssi-service/internal/verification"
 	"github.com/tbd54566975/ssi-service/pkg/server/router"
+	"github.com/tbd54566975/ssi-service/pkg/service/credential"
 	"github.com/tbd54566975/ssi-service/pkg/service/did"
 	"github.com/tbd54566975/ssi-service/pkg/service/schema"
 )
@@ -984,6 +991,11 @@ func TestCredentialAPI(t *testing.T) {
 				assert.NoError(ttt, err)
 				assert.NotEmpty(ttt, verifyResp)
 				assert.True(ttt, verifyResp.Verified)
+				assert.True(ttt, verifyResp.Report.Verified)
+				assert.Equal(ttt, resp.Credential.ID, verifyResp.Report.CredentialID)
+				for _, result := range verifyResp.Report.Checks {
+					assert.NotEqual(ttt, verification.OutcomeFailed, result.Outcome, result.Check)
+				}
 
 				// bad credential
 				requestValue = newRequestValue(ttt, router.VerifyCredentialRequest{CredentialJWT: keyaccess.JWTPtr("bad")})
@@ -997,6 +1009,341 @@ func TestCredentialAPI(t *testing.T) {
 				assert.NotEmpty(ttt, verifyResp)
 				assert.False(ttt, verifyResp.Verified)
 				assert.Contains(ttt, verifyResp.Reason, "parsing JWT: parsing credential token: invalid JWT")
+				require.NotEmpty(ttt, verifyResp.Report.Checks)
+				assert.Equal(ttt, verification.CheckSignature, verifyResp.Report.Checks[0].Check)
+				assert.Equal(ttt, verification.CodeMalformed, verifyResp.Report.Checks[0].Code)
+			})
+
+			tt.Run("Test Create Data Integrity Credential", func(ttt *testing.T) {
+				db := test.ServiceStorage(ttt)
+				require.NotEmpty(ttt, db)
+
+				keyStoreService, _ := testKeyStoreService(ttt, db)
+				didService, _ := testDIDService(ttt, db, keyStoreService, nil)
+				schemaService := testSchemaService(ttt, db, keyStoreService, didService)
+				credRouter := testCredentialRouter(ttt, db, keyStoreService, didService, schemaService)
+
+				issuerDID, err := didService.CreateDIDByMethod(context.Background(), did.CreateDIDRequest{
+					Method:  didsdk.KeyMethod,
+					KeyType: crypto.Ed25519,
+				})
+				assert.NoError(ttt, err)
+				assert.NotEmpty(ttt, issuerDID)
+
+				// the credential's data must be defined by its context to be covered by the proof
+				const vocabContext = "https://example.com/vocab/v1"
+				keyaccess.RegisterContext(vocabContext, []byte(`{"@context": {"@vocab": "https://example.com/vocab#"}}`))
+
+				for _, proofFormat := range []credential.ProofFormat{credential.JSONWebSignature2020ProofFormat, credential.Ed25519Signature2020ProofFormat, credential.EdDSA2022ProofFormat} {
+					ttt.Run(string(proofFormat), func(ttt *testing.T) {
+						createCredRequest := router.CreateCredentialRequest{
+							Issuer:               issuerDID.DID.ID,
+							VerificationMethodID: issuerDID.DID.VerificationMethod[0].ID,
+							Subject:              "did:abc:456",
+							Context:              vocabContext,
+							Data: map[string]any{
+								"firstName": "Jack",
+								"lastName":  "Dorsey",
+							},
+							Expiry:      time.Now().Add(24 * time.Hour).Format(time.RFC3339),
+							ProofFormat: proofFormat,
+						}
+						requestValue := newRequestValue(ttt, createCredRequest)
+						req := httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/credentials", requestValue)
+						w := httptest.NewRecorder()
+						c := newRequestContext(w, req)
+						credRouter.CreateCredential(c)
+						require.Equal(ttt, http.StatusCreated, w.Code, w.Body.String())
+
+						var resp router.CreateCredentialResponse
+						err = json.NewDecoder(w.Body).Decode(&resp)
+						assert.NoError(ttt, err)
+
+						// the credential carries its proof, and there is no JWT
+						assert.Empty(ttt, resp.CredentialJWT)
+						require.NotNil(ttt, resp.Credential)
+						require.NotNil(ttt, resp.Credential.Proof)
+						proofType, err := keyaccess.DataIntegrityProofTypeFromProof(*resp.Credential.Proof)
+						assert.NoError(ttt, err)
+						assert.Equal(ttt, keyaccess.DataIntegrityProofType(proofFormat), proofType)
+						for _, requiredContext := range proofType.RequiredContexts() {
+							assert.Contains(ttt, resp.Credential.Context, requiredContext)
+						}
+
+						// the stored credential is the signed one
+						w = httptest.NewRecorder()
+						req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("https://ssi-service.com/v1/credentials/%s", resp.ID), nil)
+						c = newRequestContextWithParams(w, req, map[string]string{"id": resp.ID})
+						credRouter.GetCredential(c)
+						assert.True(ttt, util.Is2xxResponse(w.Code))
+						var getCredResp router.GetCredentialResponse
+						err = json.NewDecoder(w.Body).Decode(&getCredResp)
+						assert.NoError(ttt, err)
+						assert.Equal(ttt, resp.Credential, getCredResp.Credential)
+
+						// verify the credential
+						w = httptest.NewRecorder()
+						requestValue = newRequestValue(ttt, router.VerifyCredentialRequest{DataIntegrityCredential: resp.Credential})
+						req = httptest.NewRequest(http.MethodPost, "https://ssi-service.com/v1/credentials/verification", requestValue)
+						c = newRequestContext(w, req)
+						credRouter.VerifyCredential(c)
+						assert.True(ttt, util.Is2xxResponse(w.Code))
+
+						var verifyResp router.VerifyCredentialResponse
+						err = json.NewDecoder(w.Body).Decode(&verifyResp)
+						assert.NoError(ttt, err)
+						assert.True(ttt, verifyResp.Verified, verifyResp.Reason)
+
+						// a tampered credential does not verify
+						tampered := deepcopy.Copy(resp.Credential).(*credsdk.VerifiableCredential)
+						tampered.CredentialSubject["firstName"] = "Jill"
+						w = httptest.NewRecorder()
+						requestValue = newRequestValue(ttt, router.VerifyCredentialRequest{DataIntegrityCredential: tampered})
+						req = httptest.NewRequest(http.MethodPost, "https://ssi-service.com/v1/credentials/verification", requestValue)
+						c = newRequestContext(w, req)
+						credRouter.VerifyCredential(c)
+						assert.True(ttt, util.Is2xxResponse(w.Code))
+						err = json.NewDecoder(w.Body).Decode(&verifyResp)
+						assert.NoError(ttt, err)
+						assert.False(ttt, verifyResp.Verified)
+					})
+				}
+
+				// status entries need their terms defined too
+				for statusListFormat, statusContext := range map[credential.StatusListFormat]string{
+					credential.StatusList2021Format:      status.StatusList2021Context,
+					credential.BitstringStatusListFormat: keyaccess.BitstringStatusListContext,
+				} {
+					ttt.Run("revocable with "+string(statusListFormat), func(ttt *testing.T) {
+						createCredRequest := router.CreateCredentialRequest{
+							Issuer:               issuerDID.DID.ID,
+							VerificationMethodID: issuerDID.DID.VerificationMethod[0].ID,
+							Subject:              "did:abc:456",
+							Context:              vocabContext,
+							Data: map[string]any{
+								"firstName": "Jack",
+							},
+							ProofFormat:      credential.EdDSA2022ProofFormat,
+							Revocable:        true,
+							StatusListFormat: statusListFormat,
+						}
+						requestValue := newRequestValue(ttt, createCredRequest)
+						req := httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/credentials", requestValue)
+						w := httptest.NewRecorder()
+						credRouter.CreateCredential(newRequestContext(w, req))
+						require.Equal(ttt, http.StatusCreated, w.Code, w.Body.String())
+
+						var resp router.CreateCredentialResponse
+						require.NoError(ttt, json.NewDecoder(w.Body).Decode(&resp))
+						require.NotNil(ttt, resp.Credential)
+						assert.Contains(ttt, resp.Credential.Context, statusContext)
+
+						w = httptest.NewRecorder()
+						requestValue = newRequestValue(ttt, router.VerifyCredentialRequest{DataIntegrityCredential: resp.Credential})
+						req = httptest.NewRequest(http.MethodPost, "https://ssi-service.com/v1/credentials/verification", requestValue)
+						credRouter.VerifyCredential(newRequestContext(w, req))
+						assert.True(ttt, util.Is2xxResponse(w.Code))
+						var verifyResp router.VerifyCredentialResponse
+						require.NoError(ttt, json.NewDecoder(w.Body).Decode(&verifyResp))
+						assert.True(ttt, verifyResp.Verified, verifyResp.Reason)
+					})
+				}
+
+				ttt.Run("data not defined by the context", func(ttt *testing.T) {
+					createCredRequest := router.CreateCredentialRequest{
+						Issuer:               issuerDID.DID.ID,
+						VerificationMethodID: issuerDID.DID.VerificationMethod[0].ID,
+						Subject:              "did:abc:456",
+						Data: map[string]any{
+							"firstName": "Jack",
+						},
+						ProofFormat: credential.EdDSA2022ProofFormat,
+					}
+					requestValue := newRequestValue(ttt, createCredRequest)
+					req := httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/credentials", requestValue)
+					w := httptest.NewRecorder()
+					c := newRequestContext(w, req)
+					credRouter.CreateCredential(c)
+					assert.Equal(ttt, http.StatusInternalServerError, w.Code)
+					assert.Contains(ttt, w.Body.String(), "Dropping property that did not expand into an absolute IRI")
+				})
+
+				ttt.Run("unsupported proof format", func(ttt *testing.T) {
+					createCredRequest := router.CreateCredentialRequest{
+						Issuer:               issuerDID.DID.ID,
+						VerificationMethodID: issuerDID.DID.VerificationMethod[0].ID,
+						Subject:              "did:abc:456",
+						Data: map[string]any{
+							"firstName": "Jack",
+						},
+						ProofFormat: "BbsBlsSignature2020",
+					}
+					requestValue := newRequestValue(ttt, createCredRequest)
+					req := httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/credentials", requestValue)
+					w := httptest.NewRecorder()
+					c := newRequestContext(w, req)
+					credRouter.CreateCredential(c)
+					assert.Equal(ttt, http.StatusBadRequest, w.Code)
+					assert.Contains(ttt, w.Body.String(), "unsupported proof format<BbsBlsSignature2020>")
+				})
+			})
+
+			tt.Run("Test Create Credentials Issued By did:jwk and did:pkh", func(ttt *testing.T) {
+				db := test.ServiceStorage(ttt)
+				require.NotEmpty(ttt, db)
+
+				keyStoreService, _ := testKeyStoreService(ttt, db)
+				didService, _ := testDIDService(ttt, db, keyStoreService, nil, "jwk", "pkh")
+				schemaService := testSchemaService(ttt, db, keyStoreService, didService)
+				credRouter := testCredentialRouter(ttt, db, keyStoreService, didService, schemaService)
+				verifier, err := verification.NewVerifiableDataVerifier(didService.GetResolver(), schemaService, nil)
+				require.NoError(ttt, err)
+
+				for _, createDIDRequest := range []did.CreateDIDRequest{
+					{Method: didsdk.JWKMethod, KeyType: crypto.P256},
+					{Method: didsdk.PKHMethod, KeyType: crypto.SECP256k1, Options: did.CreatePKHDIDOptions{Namespace: did.EIP155Namespace}},
+					{Method: didsdk.PKHMethod, KeyType: crypto.Ed25519, Options: did.CreatePKHDIDOptions{Namespace: did.SolanaNamespace}},
+				} {
+					issuerDID, err := didService.CreateDIDByMethod(context.Background(), createDIDRequest)
+					require.NoError(ttt, err)
+
+					w := httptest.NewRecorder()
+					req := httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/credentials", newRequestValue(ttt, router.CreateCredentialRequest{
+						Issuer:               issuerDID.DID.ID,
+						VerificationMethodID: issuerDID.DID.VerificationMethod[0].ID,
+						Subject:              "did:abc:456",
+						Data:                 map[string]any{"firstName": "Jack"},
+					}))
+					credRouter.CreateCredential(newRequestContext(w, req))
+					require.True(ttt, util.Is2xxResponse(w.Code), w.Body.String())
+
+					var resp router.CreateCredentialResponse
+					require.NoError(ttt, json.NewDecoder(w.Body).Decode(&resp))
+					assert.Equal(ttt, issuerDID.DID.ID, resp.Credential.IssuerID())
+					assert.NoError(ttt, verifier.VerifyCredential(context.Background(), resp.Container))
+				}
+			})
+
+			tt.Run("Test Create SD-JWT Credential", func(ttt *testing.T) {
+				db := test.ServiceStorage(ttt)
+				require.NotEmpty(ttt, db)
+
+				keyStoreService, _ := testKeyStoreService(ttt, db)
+				didService, _ := testDIDService(ttt, db, keyStoreService, nil)
+				schemaService := testSchemaService(ttt, db, keyStoreService, didService)
+				credRouter := testCredentialRouter(ttt, db, keyStoreService, didService, schemaService)
+
+				issuerDID, err := didService.CreateDIDByMethod(context.Background(), did.CreateDIDRequest{
+					Method:  didsdk.KeyMethod,
+					KeyType: crypto.Ed25519,
+				})
+				assert.NoError(ttt, err)
+				assert.NotEmpty(ttt, issuerDID)
+
+				holderPrivKey, holderDIDKey, err := key.GenerateDIDKey(crypto.Ed25519)
+				assert.NoError(ttt, err)
+				holderDID, err := holderDIDKey.Expand()
+				assert.NoError(ttt, err)
+
+				createCredRequest := router.CreateCredentialRequest{
+					Issuer:               issuerDID.DID.ID,
+					VerificationMethodID: issuerDID.DID.VerificationMethod[0].ID,
+					Subject:              holderDID.ID,
+					Data: map[string]any{
+						"firstName": "Jack",
+						"lastName":  "Dorsey",
+						"age":       42,
+					},
+					Expiry:      time.Now().Add(24 * time.Hour).Format(time.RFC3339),
+					ProofFormat: credential.SDJWTProofFormat,
+					Disclosable: []string{"firstName", "age"},
+				}
+				requestValue := newRequestValue(ttt, createCredRequest)
+				req := httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/credentials", requestValue)
+				w := httptest.NewRecorder()
+				c := newRequestContext(w, req)
+				credRouter.CreateCredential(c)
+				require.Equal(ttt, http.StatusCreated, w.Code, w.Body.String())
+
+				var resp router.CreateCredentialResponse
+				err = json.NewDecoder(w.Body).Decode(&resp)
+				assert.NoError(ttt, err)
+
+				// the credential has all claims, while the issuer-signed JWT only has the non-disclosable ones
+				assert.Empty(ttt, resp.CredentialJWT)
+				require.NotNil(ttt, resp.SDJWT)
+				require.NotNil(ttt, resp.Credential)
+				assert.Equal(ttt, "Jack", resp.Credential.CredentialSubject["firstName"])
+				parts, err := resp.SDJWT.Parse()
+				assert.NoError(ttt, err)
+				assert.Len(ttt, parts.Disclosures, 2)
+				assert.Nil(ttt, parts.KeyBindingJWT)
+				_, _, issuerCred, err := integrity.ParseVerifiableCredentialFromJWT(parts.IssuerJWT.String())
+				assert.NoError(ttt, err)
+				assert.Equal(ttt, "Dorsey", issuerCred.CredentialSubject["lastName"])
+				assert.NotContains(ttt, issuerCred.CredentialSubject, "firstName")
+				assert.NotContains(ttt, issuerCred.CredentialSubject, "age")
+
+				// the stored credential keeps the SD-JWT
+				w = httptest.NewRecorder()
+				req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("https://ssi-service.com/v1/credentials/%s", resp.ID), nil)
+				c = newRequestContextWithParams(w, req, map[string]string{"id": resp.ID})
+				credRouter.GetCredential(c)
+				assert.True(ttt, util.Is2xxResponse(w.Code))
+				var getCredResp router.GetCredentialResponse
+				err = json.NewDecoder(w.Body).Decode(&getCredResp)
+				assert.NoError(ttt, err)
+				assert.Equal(ttt, resp.SDJWT, getCredResp.SDJWT)
+
+				verifier, err := verification.NewVerifiableDataVerifier(didService.GetResolver(), schemaService, nil)
+				require.NoError(ttt, err)
+				assert.NoError(ttt, verifier.VerifyCredential(context.Background(), resp.Container))
+
+				// the holder presents only their first name
+				holderKeyAccess, err := keyaccess.NewJWKKeyAccess(holderDID.ID, holderDID.VerificationMethod[0].ID, holderPrivKey)
+				require.NoError(ttt, err)
+				selected, err := resp.SDJWT.Select("firstName")
+				require.NoError(ttt, err)
+				presentation, err := holderKeyAccess.SignSDJWTKeyBinding(*selected, "https://verifier.example.com", "test-nonce")
+				require.NoError(ttt, err)
+
+				disclosed, err := verifier.VerifySDJWTPresentation(context.Background(), *presentation, "https://verifier.example.com", "test-nonce")
+				require.NoError(ttt, err)
+				assert.Equal(ttt, "Jack", disclosed.CredentialSubject["firstName"])
+				assert.Equal(ttt, "Dorsey", disclosed.CredentialSubject["lastName"])
+				assert.NotContains(ttt, disclosed.CredentialSubject, "age")
+
+				// the key binding is specific to the audience and nonce
+				_, err = verifier.VerifySDJWTPresentation(context.Background(), *presentation, "https://verifier.example.com", "other-nonce")
+				assert.ErrorContains(ttt, err, "nonce")
+				_, err = verifier.VerifySDJWTPresentation(context.Background(), *presentation, "https://other.example.com", "test-nonce")
+				assert.ErrorContains(ttt, err, "audience")
+
+				// a presentation without key binding is rejected
+				_, err = verifier.VerifySDJWTPresentation(context.Background(), *selected, "https://verifier.example.com", "test-nonce")
+				assert.ErrorContains(ttt, err, "no key binding JWT")
+
+				// the key binding must be signed by the subject
+				otherPrivKey, otherDIDKey, err := key.GenerateDIDKey(crypto.Ed25519)
+				assert.NoError(ttt, err)
+				otherDID, err := otherDIDKey.Expand()
+				assert.NoError(ttt, err)
+				otherKeyAccess, err := keyaccess.NewJWKKeyAccess(otherDID.ID, otherDID.VerificationMethod[0].ID, otherPrivKey)
+				require.NoError(ttt, err)
+				stolen, err := otherKeyAccess.SignSDJWTKeyBinding(*selected, "https://verifier.example.com", "test-nonce")
+				require.NoError(ttt, err)
+				_, err = verifier.VerifySDJWTPresentation(context.Background(), *stolen, "https://verifier.example.com", "test-nonce")
+				assert.Error(ttt, err)
+
+				// disclosable claims require the SD-JWT format
+				createCredRequest.ProofFormat = credential.JWTProofFormat
+				requestValue = newRequestValue(ttt, createCredRequest)
+				req = httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/credentials", requestValue)
+				w = httptest.NewRecorder()
+				c = newRequestContext(w, req)
+				credRouter.CreateCredential(c)
+				assert.Equal(ttt, http.StatusBadRequest, w.Code)
+				assert.Contains(ttt, w.Body.String(), "disclosable claims require the sd-jwt proof format")
 			})
 
 			tt.Run("Test Create Revocable Credential", func(ttt *testing.T) {
@@ -1255,6 +1602,78 @@ func TestCredentialAPI(t *testing.T) {
 
 			})
 
+			tt.Run("Test Verifying a Revoked Credential", func(ttt *testing.T) {
+				db := test.ServiceStorage(ttt)
+				require.NotEmpty(ttt, db)
+
+				keyStoreService, _ := testKeyStoreService(ttt, db)
+				didService, _ := testDIDService(ttt, db, keyStoreService, nil)
+				schemaService := testSchemaService(ttt, db, keyStoreService, didService)
+				credRouter := testCredentialRouter(ttt, db, keyStoreService, didService, schemaService)
+
+				issuerDID, err := didService.CreateDIDByMethod(context.Background(), did.CreateDIDRequest{
+					Method:  didsdk.KeyMethod,
+					KeyType: crypto.Ed25519,
+				})
+				assert.NoError(ttt, err)
+				assert.NotEmpty(ttt, issuerDID)
+
+				createCredRequest := router.CreateCredentialRequest{
+					Issuer:               issuerDID.DID.ID,
+					VerificationMethodID: issuerDID.DID.VerificationMethod[0].ID,
+					Subject:              "did:abc:456",
+					Data: map[string]any{
+						"firstName": "Jack",
+						"lastName":  "Dorsey",
+					},
+					Expiry:    time.Now().Add(24 * time.Hour).Format(time.RFC3339),
+					Revocable: true,
+				}
+				requestValue := newRequestValue(ttt, createCredRequest)
+				req := httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/credentials", requestValue)
+				w := httptest.NewRecorder()
+				c := newRequestContext(w, req)
+				credRouter.CreateCredential(c)
+				assert.True(ttt, util.Is2xxResponse(w.Code))
+
+				var resp router.CreateCredentialResponse
+				err = json.NewDecoder(w.Body).Decode(&resp)
+				assert.NoError(ttt, err)
+				assert.NotEmpty(ttt, resp.CredentialJWT)
+
+				verify := func() router.VerifyCredentialResponse {
+					w := httptest.NewRecorder()
+					requestValue := newRequestValue(ttt, router.VerifyCredentialRequest{CredentialJWT: resp.CredentialJWT})
+					req := httptest.NewRequest(http.MethodPost, "https://ssi-service.com/v1/credentials/verification", requestValue)
+					c := newRequestContext(w, req)
+					credRouter.VerifyCredential(c)
+					assert.True(ttt, util.Is2xxResponse(w.Code))
+
+					var verifyResp router.VerifyCredentialResponse
+					err := json.NewDecoder(w.Body).Decode(&verifyResp)
+					assert.NoError(ttt, err)
+					return verifyResp
+				}
+
+				// the credential verifies before it's revoked
+				verifyResp := verify()
+				assert.True(ttt, verifyResp.Verified)
+				assert.Empty(ttt, verifyResp.StatusFailures)
+
+				requestValue = newRequestValue(ttt, router.UpdateCredentialStatusRequest{Revoked: true})
+				req = httptest.NewRequest(http.MethodPut, fmt.Sprintf("%s/status", resp.Credential.ID), requestValue)
+				w = httptest.NewRecorder()
+				c = newRequestContextWithParams(w, req, map[string]string{"id": idFromURI(resp.Credential.ID)})
+				credRouter.UpdateCredentialStatus(c)
+				assert.True(ttt, util.Is2xxResponse(w.Code))
+
+				// the revocation applies right away
+				verifyResp = verify()
+				assert.False(ttt, verifyResp.Verified)
+				assert.Contains(ttt, verifyResp.Reason, "is revoked")
+				assert.Equal(ttt, []verification.StatusFailure{{CredentialID: resp.Credential.ID, Reason: "is revoked"}}, verifyResp.StatusFailures)
+			})
+
 			tt.Run("Test Get Status List Credential", func(ttt *testing.T) {
 				db := test.ServiceStorage(ttt)
 				require.NotEmpty(ttt, db)
@@ -1325,6 +1744,457 @@ func TestCredentialAPI(t *testing.T) {
 				assert.Empty(ttt, credListResp.Credential.CredentialStatus)
 				assert.Equal(ttt, credListResp.Credential.ID, credStatusListID)
 			})
+
+			tt.Run("Test Status List Rollover", func(ttt *testing.T) {
+				db := test.ServiceStorage(ttt)
+				require.NotEmpty(ttt, db)
+
+				keyStoreService, _ := testKeyStoreService(ttt, db)
+				didService, _ := testDIDService(ttt, db, keyStoreService, nil)
+				schemaService := testSchemaService(ttt, db, keyStoreService, didService)
+				credService, err := credential.NewCredentialService(config.CredentialServiceConfig{StatusListSize: 2}, db, keyStoreService, didService.GetResolver(), schemaService)
+				require.NoError(ttt, err)
+				credRouter, err := router.NewCredentialRouter(credService)
+				require.NoError(ttt, err)
+
+				issuerDID, err := didService.CreateDIDByMethod(context.Background(), did.CreateDIDRequest{
+					Method:  didsdk.KeyMethod,
+					KeyType: crypto.Ed25519,
+				})
+				assert.NoError(ttt, err)
+				assert.NotEmpty(ttt, issuerDID)
+
+				var creds []*credsdk.VerifiableCredential
+				for i := 0; i < 3; i++ {
+					w := httptest.NewRecorder()
+					createCredRequest := router.CreateCredentialRequest{
+						Issuer:               issuerDID.DID.ID,
+						VerificationMethodID: issuerDID.DID.VerificationMethod[0].ID,
+						Subject:              "did:abc:456",
+						Data: map[string]any{
+							"firstName": "Jack",
+							"lastName":  "Dorsey",
+						},
+						Revocable: true,
+					}
+					req := httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/credentials", newRequestValue(ttt, createCredRequest))
+					credRouter.CreateCredential(newRequestContext(w, req))
+					require.True(ttt, util.Is2xxResponse(w.Code))
+
+					var resp router.CreateCredentialResponse
+					require.NoError(ttt, json.NewDecoder(w.Body).Decode(&resp))
+					creds = append(creds, resp.Credential)
+				}
+				statusListOf := func(cred *credsdk.VerifiableCredential) string {
+					return cred.CredentialStatus.(map[string]any)["statusListCredential"].(string)
+				}
+
+				// the third credential is in a new status list
+				fullList, currentList := statusListOf(creds[0]), statusListOf(creds[2])
+				assert.Equal(ttt, fullList, statusListOf(creds[1]))
+				assert.NotEqual(ttt, fullList, currentList)
+
+				w := httptest.NewRecorder()
+				req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("https://ssi-service.com/v1/credentials/status/usage?issuer=%s", issuerDID.DID.ID), nil)
+				credRouter.GetStatusListUsage(newRequestContext(w, req))
+				assert.True(ttt, util.Is2xxResponse(w.Code))
+
+				var usageResp router.GetStatusListUsageResponse
+				assert.NoError(ttt, json.NewDecoder(w.Body).Decode(&usageResp))
+				require.Len(ttt, usageResp.StatusLists, 2)
+				assert.Equal(ttt, credential.StatusListUsage{
+					ID:            fullList,
+					Issuer:        issuerDID.DID.ID,
+					StatusPurpose: "revocation",
+					Format:        credential.StatusList2021Format,
+					Used:          2,
+					Capacity:      2,
+				}, usageResp.StatusLists[0])
+				assert.Equal(ttt, credential.StatusListUsage{
+					ID:            currentList,
+					Issuer:        issuerDID.DID.ID,
+					StatusPurpose: "revocation",
+					Format:        credential.StatusList2021Format,
+					Used:          1,
+					Capacity:      2,
+					Current:       true,
+				}, usageResp.StatusLists[1])
+
+				// credentials in a full status list can still be revoked
+				w = httptest.NewRecorder()
+				req = httptest.NewRequest(http.MethodPut, fmt.Sprintf("%s/status", creds[0].ID), newRequestValue(ttt, router.UpdateCredentialStatusRequest{Revoked: true}))
+				credRouter.UpdateCredentialStatus(newRequestContextWithParams(w, req, map[string]string{"id": idFromURI(creds[0].ID)}))
+				assert.True(ttt, util.Is2xxResponse(w.Code))
+
+				for list, revoked := range map[string]bool{fullList: true, currentList: false} {
+					statusList, err := credService.GetCredentialStatusList(context.Background(), credential.GetCredentialStatusListRequest{ID: idFromURI(list)})
+					assert.NoError(ttt, err)
+					cred := creds[0]
+					if list == currentList {
+						cred = creds[2]
+					}
+					valid, err := status.ValidateCredentialInStatusList(*cred, *statusList.Credential)
+					assert.NoError(ttt, err)
+					assert.Equal(ttt, revoked, valid)
+				}
+			})
+
+			tt.Run("Test Status List Credential Refresh", func(ttt *testing.T) {
+				db := test.ServiceStorage(ttt)
+				require.NotEmpty(ttt, db)
+
+				keyStoreService, _ := testKeyStoreService(ttt, db)
+				didService, _ := testDIDService(ttt, db, keyStoreService, nil)
+				schemaService := testSchemaService(ttt, db, keyStoreService, didService)
+				serviceConfig := config.CredentialServiceConfig{StatusListValidity: time.Hour, StatusListRefreshInterval: 15 * time.Minute}
+				credService, err := credential.NewCredentialService(serviceConfig, db, keyStoreService, didService.GetResolver(), schemaService)
+				require.NoError(ttt, err)
+
+				issuerDID, err := didService.CreateDIDByMethod(context.Background(), did.CreateDIDRequest{
+					Method:  didsdk.KeyMethod,
+					KeyType: crypto.Ed25519,
+				})
+				require.NoError(ttt, err)
+
+				createdCred, err := credService.CreateCredential(context.Background(), credential.CreateCredentialRequest{
+					Issuer:                             issuerDID.DID.ID,
+					FullyQualifiedVerificationMethodID: issuerDID.DID.VerificationMethod[0].ID,
+					Subject:                            "did:abc:456",
+					Data:                               map[string]any{"firstName": "Jack"},
+					Revocable:                          true,
+				})
+				require.NoError(ttt, err)
+				statusListID := idFromURI(createdCred.Credential.CredentialStatus.(map[string]any)["statusListCredential"].(string))
+
+				getExpiry := func(ttt *testing.T) (time.Time, keyaccess.JWT) {
+					statusList, err := credService.GetCredentialStatusList(context.Background(), credential.GetCredentialStatusListRequest{ID: statusListID})
+					require.NoError(ttt, err)
+					expiry, err := time.Parse(time.RFC3339, statusList.Credential.ExpirationDate)
+					require.NoError(ttt, err)
+					return expiry, *statusList.CredentialJWT
+				}
+				expiry, statusListJWT := getExpiry(ttt)
+				assert.WithinDuration(ttt, time.Now().Add(time.Hour), expiry, time.Minute)
+
+				// the status list is far from expiring
+				refreshed, err := credService.RefreshStatusListCredentials(context.Background())
+				assert.NoError(ttt, err)
+				assert.Equal(ttt, 0, refreshed)
+
+				// with a longer validity window the status list is re-signed
+				serviceConfig = config.CredentialServiceConfig{StatusListValidity: 2 * time.Hour, StatusListRefreshInterval: 45 * time.Minute}
+				credService, err = credential.NewCredentialService(serviceConfig, db, keyStoreService, didService.GetResolver(), schemaService)
+				require.NoError(ttt, err)
+				refreshed, err = credService.RefreshStatusListCredentials(context.Background())
+				assert.NoError(ttt, err)
+				assert.Equal(ttt, 1, refreshed)
+
+				refreshedExpiry, refreshedJWT := getExpiry(ttt)
+				assert.WithinDuration(ttt, time.Now().Add(2*time.Hour), refreshedExpiry, time.Minute)
+				assert.NotEqual(ttt, statusListJWT, refreshedJWT)
+
+				// credentials in the refreshed status list can still be revoked
+				_, err = credService.UpdateCredentialStatus(context.Background(), credential.UpdateCredentialStatusRequest{ID: createdCred.ID, Revoked: true})
+				assert.NoError(ttt, err)
+				statusList, err := credService.GetCredentialStatusList(context.Background(), credential.GetCredentialStatusListRequest{ID: statusListID})
+				require.NoError(ttt, err)
+				revoked, err := status.ValidateCredentialInStatusList(*createdCred.Credential, *statusList.Credential)
+				assert.NoError(ttt, err)
+				assert.True(ttt, revoked)
+				assert.NotEmpty(ttt, statusList.Credential.ExpirationDate)
+			})
+
+			tt.Run("Test Credential Expiry", func(ttt *testing.T) {
+				db := test.ServiceStorage(ttt)
+				require.NotEmpty(ttt, db)
+
+				keyStoreService, _ := testKeyStoreService(ttt, db)
+				didService, _ := testDIDService(ttt, db, keyStoreService, nil)
+				schemaService := testSchemaService(ttt, db, keyStoreService, didService)
+				credService, err := credential.NewCredentialService(config.CredentialServiceConfig{}, db, keyStoreService, didService.GetResolver(), schemaService)
+				require.NoError(ttt, err)
+				credRouter, err := router.NewCredentialRouter(credService)
+				require.NoError(ttt, err)
+
+				issuerDID, err := didService.CreateDIDByMethod(context.Background(), did.CreateDIDRequest{
+					Method:  didsdk.KeyMethod,
+					KeyType: crypto.Ed25519,
+				})
+				require.NoError(ttt, err)
+
+				createCredential := func(ttt *testing.T, expiry string) *credsdk.VerifiableCredential {
+					createdCred, err := credService.CreateCredential(context.Background(), credential.CreateCredentialRequest{
+						Issuer:                             issuerDID.DID.ID,
+						FullyQualifiedVerificationMethodID: issuerDID.DID.VerificationMethod[0].ID,
+						Subject:                            "did:abc:456",
+						Data:                               map[string]any{"firstName": "Jack"},
+						Expiry:                             expiry,
+					})
+					require.NoError(ttt, err)
+					return createdCred.Credential
+				}
+				expiredCred := createCredential(ttt, time.Now().Add(-time.Minute).Format(time.RFC3339))
+				activeCred := createCredential(ttt, time.Now().Add(time.Hour).Format(time.RFC3339))
+				createCredential(ttt, "")
+				listCredentials := func(ttt *testing.T, query string) []credint.Container {
+					w := httptest.NewRecorder()
+					req := httptest.NewRequest(http.MethodGet, "https://ssi-service.com/v1/credentials?"+query, nil)
+					credRouter.ListCredentials(newRequestContext(w, req))
+					require.True(ttt, util.Is2xxResponse(w.Code), w.Body.String())
+
+					var resp router.ListCredentialsResponse
+					require.NoError(ttt, json.NewDecoder(w.Body).Decode(&resp))
+					return resp.Credentials
+				}
+
+				// credentials are expired as soon as they pass their expiration date, before they're swept
+				expiredCreds := listCredentials(ttt, "expired=true")
+				require.Len(ttt, expiredCreds, 1)
+				assert.Equal(ttt, expiredCred.ID, expiredCreds[0].Credential.ID)
+				assert.True(ttt, expiredCreds[0].Expired)
+				gotCred, err := credService.GetCredential(context.Background(), credential.GetCredentialRequest{ID: idFromURI(expiredCred.ID)})
+				assert.NoError(ttt, err)
+				assert.True(ttt, gotCred.Expired)
+
+				expired, err := credService.ExpireCredentials(context.Background())
+				assert.NoError(ttt, err)
+				require.Len(ttt, expired, 1)
+				assert.Equal(ttt, idFromURI(expiredCred.ID), expired[0].ID)
+				assert.True(ttt, expired[0].Expired)
+
+				// credentials are only expired once
+				expired, err = credService.ExpireCredentials(context.Background())
+				assert.NoError(ttt, err)
+				assert.Empty(ttt, expired)
+
+				gotCred, err = credService.GetCredential(context.Background(), credential.GetCredentialRequest{ID: idFromURI(expiredCred.ID)})
+				assert.NoError(ttt, err)
+				assert.True(ttt, gotCred.Expired)
+
+				expiredCreds = listCredentials(ttt, "expired=true")
+				require.Len(ttt, expiredCreds, 1)
+				assert.Equal(ttt, expiredCred.ID, expiredCreds[0].Credential.ID)
+
+				activeCreds := listCredentials(ttt, fmt.Sprintf("expired=false&issuer=%s", issuerDID.DID.ID))
+				var activeIDs []string
+				for _, activeContainer := range activeCreds {
+					assert.False(ttt, activeContainer.Expired)
+					activeIDs = append(activeIDs, activeContainer.Credential.ID)
+				}
+				assert.Len(ttt, activeIDs, 2)
+				assert.Contains(ttt, activeIDs, activeCred.ID)
+				assert.NotContains(ttt, activeIDs, expiredCred.ID)
+				assert.Len(ttt, listCredentials(ttt, "expired=true&issuer=did:abc:123"), 0)
+				assert.Len(ttt, listCredentials(ttt, ""), 3)
+
+				w := httptest.NewRecorder()
+				req := httptest.NewRequest(http.MethodGet, "https://ssi-service.com/v1/credentials?expired=maybe", nil)
+				credRouter.ListCredentials(newRequestContext(w, req))
+				assert.Equal(ttt, http.StatusBadRequest, w.Code)
+			})
+
+			tt.Run("Test Bitstring Status List Credential", func(ttt *testing.T) {
+				db := test.ServiceStorage(ttt)
+				require.NotEmpty(ttt, db)
+
+				keyStoreService, _ := testKeyStoreService(ttt, db)
+				didService, _ := testDIDService(ttt, db, keyStoreService, nil)
+				schemaService := testSchemaService(ttt, db, keyStoreService, didService)
+				credRouter := testCredentialRouter(ttt, db, keyStoreService, didService, schemaService)
+
+				issuerDID, err := didService.CreateDIDByMethod(context.Background(), did.CreateDIDRequest{
+					Method:  didsdk.KeyMethod,
+					KeyType: crypto.Ed25519,
+				})
+				assert.NoError(ttt, err)
+				assert.NotEmpty(ttt, issuerDID)
+
+				createCredential := func(ttt *testing.T, request router.CreateCredentialRequest) *credsdk.VerifiableCredential {
+					w := httptest.NewRecorder()
+					req := httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/credentials", newRequestValue(ttt, request))
+					credRouter.CreateCredential(newRequestContext(w, req))
+					require.True(ttt, util.Is2xxResponse(w.Code), w.Body.String())
+
+					var resp router.CreateCredentialResponse
+					require.NoError(ttt, json.NewDecoder(w.Body).Decode(&resp))
+					return resp.Credential
+				}
+				updateStatus := func(ttt *testing.T, cred *credsdk.VerifiableCredential, request router.UpdateCredentialStatusRequest) *httptest.ResponseRecorder {
+					w := httptest.NewRecorder()
+					req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("%s/status", cred.ID), newRequestValue(ttt, request))
+					credRouter.UpdateCredentialStatus(newRequestContextWithParams(w, req, map[string]string{"id": idFromURI(cred.ID)}))
+					return w
+				}
+				getStatusList := func(ttt *testing.T, cred *credsdk.VerifiableCredential) *credsdk.VerifiableCredential {
+					statusListURI := cred.CredentialStatus.(map[string]any)["statusListCredential"].(string)
+					w := httptest.NewRecorder()
+					req := httptest.NewRequest(http.MethodGet, statusListURI, nil)
+					credRouter.GetCredentialStatusList(newRequestContextWithParams(w, req, map[string]string{"id": idFromURI(statusListURI)}))
+					require.True(ttt, util.Is2xxResponse(w.Code))
+
+					var resp router.GetCredentialStatusListResponse
+					require.NoError(ttt, json.NewDecoder(w.Body).Decode(&resp))
+					return resp.Credential
+				}
+
+				ttt.Run("revocation", func(ttt *testing.T) {
+					createCredRequest := router.CreateCredentialRequest{
+						Issuer:               issuerDID.DID.ID,
+						VerificationMethodID: issuerDID.DID.VerificationMethod[0].ID,
+						Subject:              "did:abc:456",
+						Data: map[string]any{
+							"firstName": "Jack",
+							"lastName":  "Dorsey",
+						},
+						Revocable:        true,
+						StatusListFormat: credential.BitstringStatusListFormat,
+					}
+					cred := createCredential(ttt, createCredRequest)
+					other := createCredential(ttt, createCredRequest)
+
+					entry, ok := credint.ToBitstringStatusListEntry(cred.CredentialStatus)
+					require.True(ttt, ok)
+					assert.Equal(ttt, "revocation", string(entry.StatusPurpose))
+					assert.Equal(ttt, entry.StatusListCredential, other.CredentialStatus.(map[string]any)["statusListCredential"])
+
+					statusList := getStatusList(ttt, cred)
+					assert.Contains(ttt, statusList.Type, credint.BitstringStatusListCredentialType)
+					value, _, err := credint.GetBitstringStatus(*cred, *statusList)
+					assert.NoError(ttt, err)
+					assert.Equal(ttt, 0, value)
+
+					w := updateStatus(ttt, cred, router.UpdateCredentialStatusRequest{Revoked: true})
+					assert.True(ttt, util.Is2xxResponse(w.Code))
+
+					statusList = getStatusList(ttt, cred)
+					value, _, err = credint.GetBitstringStatus(*cred, *statusList)
+					assert.NoError(ttt, err)
+					assert.Equal(ttt, 1, value)
+					value, _, err = credint.GetBitstringStatus(*other, *statusList)
+					assert.NoError(ttt, err)
+					assert.Equal(ttt, 0, value)
+
+					w = updateStatus(ttt, cred, router.UpdateCredentialStatusRequest{Revoked: false})
+					assert.True(ttt, util.Is2xxResponse(w.Code))
+
+					statusList = getStatusList(ttt, cred)
+					value, _, err = credint.GetBitstringStatus(*cred, *statusList)
+					assert.NoError(ttt, err)
+					assert.Equal(ttt, 0, value)
+
+					// a multi-bit value cannot be set on a revocation status
+					statusValue := 1
+					w = updateStatus(ttt, cred, router.UpdateCredentialStatusRequest{StatusValue: &statusValue})
+					assert.Contains(ttt, w.Body.String(), "does not have a multi-bit status")
+				})
+
+				ttt.Run("revocable and suspendable", func(ttt *testing.T) {
+					cred := createCredential(ttt, router.CreateCredentialRequest{
+						Issuer:               issuerDID.DID.ID,
+						VerificationMethodID: issuerDID.DID.VerificationMethod[0].ID,
+						Subject:              "did:abc:456",
+						Data: map[string]any{
+							"firstName": "Jack",
+							"lastName":  "Dorsey",
+						},
+						Revocable:        true,
+						Suspendable:      true,
+						StatusListFormat: credential.BitstringStatusListFormat,
+					})
+					entries, ok := cred.CredentialStatus.([]any)
+					require.True(ttt, ok)
+					require.Len(ttt, entries, 2)
+
+					getStatus := func(ttt *testing.T, entry any) int {
+						statusList := getStatusList(ttt, &credsdk.VerifiableCredential{CredentialStatus: entry})
+						value, _, err := credint.GetBitstringStatus(*cred, *statusList)
+						require.NoError(ttt, err)
+						return value
+					}
+
+					w := updateStatus(ttt, cred, router.UpdateCredentialStatusRequest{Suspended: true})
+					assert.True(ttt, util.Is2xxResponse(w.Code))
+					assert.Equal(ttt, 0, getStatus(ttt, entries[0]))
+					assert.Equal(ttt, 1, getStatus(ttt, entries[1]))
+
+					w = updateStatus(ttt, cred, router.UpdateCredentialStatusRequest{Revoked: true})
+					assert.True(ttt, util.Is2xxResponse(w.Code))
+					assert.Equal(ttt, 1, getStatus(ttt, entries[0]))
+					assert.Equal(ttt, 0, getStatus(ttt, entries[1]))
+				})
+
+				ttt.Run("message", func(ttt *testing.T) {
+					cred := createCredential(ttt, router.CreateCredentialRequest{
+						Issuer:               issuerDID.DID.ID,
+						VerificationMethodID: issuerDID.DID.VerificationMethod[0].ID,
+						Subject:              "did:abc:456",
+						Data: map[string]any{
+							"firstName": "Jack",
+							"lastName":  "Dorsey",
+						},
+						StatusListFormat: credential.BitstringStatusListFormat,
+						StatusMessage: []credint.StatusMessageEntry{
+							{Status: "0x0", Message: "pending_review"},
+							{Status: "0x1", Message: "accepted"},
+							{Status: "0x2", Message: "rejected"},
+							{Status: "0x3", Message: "undefined"},
+						},
+					})
+
+					entry, ok := credint.ToBitstringStatusListEntry(cred.CredentialStatus)
+					require.True(ttt, ok)
+					assert.Equal(ttt, credint.StatusMessage, entry.StatusPurpose)
+					assert.Equal(ttt, 2, entry.StatusSize)
+					assert.Len(ttt, entry.StatusMessage, 4)
+
+					statusValue := 2
+					w := updateStatus(ttt, cred, router.UpdateCredentialStatusRequest{StatusValue: &statusValue})
+					assert.True(ttt, util.Is2xxResponse(w.Code))
+					var updateResp router.UpdateCredentialStatusResponse
+					assert.NoError(ttt, json.NewDecoder(w.Body).Decode(&updateResp))
+					assert.Equal(ttt, 2, updateResp.StatusValue)
+
+					statusList := getStatusList(ttt, cred)
+					value, message, err := credint.GetBitstringStatus(*cred, *statusList)
+					assert.NoError(ttt, err)
+					assert.Equal(ttt, 2, value)
+					assert.Equal(ttt, "rejected", message)
+
+					w = httptest.NewRecorder()
+					req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("%s/status", cred.ID), nil)
+					credRouter.GetCredentialStatus(newRequestContextWithParams(w, req, map[string]string{"id": idFromURI(cred.ID)}))
+					assert.True(ttt, util.Is2xxResponse(w.Code))
+					var statusResp router.GetCredentialStatusResponse
+					assert.NoError(ttt, json.NewDecoder(w.Body).Decode(&statusResp))
+					assert.Equal(ttt, 2, statusResp.StatusValue)
+					assert.Equal(ttt, "rejected", statusResp.StatusMessage)
+
+					// the value must fit in the status size
+					statusValue = 4
+					w = updateStatus(ttt, cred, router.UpdateCredentialStatusRequest{StatusValue: &statusValue})
+					assert.Contains(ttt, w.Body.String(), "does not fit")
+
+					// a message status cannot be revoked
+					w = updateStatus(ttt, cred, router.UpdateCredentialStatusRequest{Revoked: true})
+					assert.Contains(ttt, w.Body.String(), "cannot be revoked or suspended")
+				})
+
+				ttt.Run("status messages require the bitstring format", func(ttt *testing.T) {
+					w := httptest.NewRecorder()
+					req := httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/credentials", newRequestValue(ttt, router.CreateCredentialRequest{
+						Issuer:               issuerDID.DID.ID,
+						VerificationMethodID: issuerDID.DID.VerificationMethod[0].ID,
+						Subject:              "did:abc:456",
+						Data:                 map[string]any{"firstName": "Jack"},
+						StatusMessage: []credint.StatusMessageEntry{
+							{Status: "0x0", Message: "valid"},
+							{Status: "0x1", Message: "invalid"},
+						},
+					}))
+					credRouter.CreateCredential(newRequestContext(w, req))
+					assert.Contains(ttt, w.Body.String(), "status messages require")
+				})
+			})
 		})
 	}
 }
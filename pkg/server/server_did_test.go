@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	_ "embed"
 	"fmt"
 	"net/http"
@@ -8,20 +9,28 @@ import (
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/TBD54566975/ssi-sdk/crypto"
 	"github.com/TBD54566975/ssi-sdk/crypto/jwx"
 	didsdk "github.com/TBD54566975/ssi-sdk/did"
 	"github.com/TBD54566975/ssi-sdk/did/ion"
+	"github.com/TBD54566975/ssi-sdk/did/peer"
+	"github.com/gin-gonic/gin"
 	"github.com/goccy/go-json"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/tbd54566975/ssi-service/pkg/testutil"
 	"gopkg.in/h2non/gock.v1"
 
+	"github.com/tbd54566975/ssi-service/config"
 	"github.com/tbd54566975/ssi-service/internal/util"
 	"github.com/tbd54566975/ssi-service/pkg/server/router"
 	"github.com/tbd54566975/ssi-service/pkg/service/did"
+	"github.com/tbd54566975/ssi-service/pkg/service/did/resolution"
+	"github.com/tbd54566975/ssi-service/pkg/service/keystore"
+	"github.com/tbd54566975/ssi-service/pkg/service/operation"
+	"github.com/tbd54566975/ssi-service/pkg/service/operation/anchor"
 )
 
 //go:embed testdata/basic_did_resolution.json
@@ -175,6 +184,164 @@ func TestDIDAPI(t *testing.T) {
 				assert.Contains(tt, resp.DID.ID, didsdk.WebMethod)
 			})
 
+			t.Run("Test Create DID By Method: Peer", func(tt *testing.T) {
+				db := test.ServiceStorage(tt)
+				require.NotEmpty(tt, db)
+
+				_, keyStoreService, _ := testKeyStore(tt, db)
+				didService, _ := testDIDRouter(tt, db, keyStoreService, []string{"peer"}, nil)
+
+				params := map[string]string{"method": "peer"}
+				create := func(createDIDRequest router.CreateDIDByMethodRequest) *httptest.ResponseRecorder {
+					w := httptest.NewRecorder()
+					req := httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/dids/peer", newRequestValue(tt, createDIDRequest))
+					c := newRequestContextWithParams(w, req, params)
+					didService.CreateDIDByMethod(c)
+					return w
+				}
+
+				// without options, a numalgo 0 DID is created
+				w := create(router.CreateDIDByMethodRequest{KeyType: crypto.Ed25519})
+				require.True(tt, util.Is2xxResponse(w.Code), w.Body.String())
+
+				var resp router.CreateDIDByMethodResponse
+				require.NoError(tt, json.NewDecoder(w.Body).Decode(&resp))
+				assert.True(tt, strings.HasPrefix(resp.DID.ID, "did:peer:0z"))
+				require.Len(tt, resp.DID.VerificationMethod, 1)
+				assert.Len(tt, resp.DID.Authentication, 1)
+				assert.Empty(tt, resp.DID.KeyAgreement)
+
+				keyDetails, err := keyStoreService.GetKeyDetails(context.Background(), keystore.GetKeyDetailsRequest{ID: resp.DID.VerificationMethod[0].ID})
+				require.NoError(tt, err)
+				assert.Equal(tt, resp.DID.ID, keyDetails.Controller)
+
+				// numalgo 2, with separate authentication and key agreement keys, and a service endpoint
+				w = create(router.CreateDIDByMethodRequest{
+					KeyType: crypto.Ed25519,
+					Options: did.CreatePeerDIDOptions{
+						NumAlgo: 2,
+						Services: []didsdk.Service{{
+							Type:            "DIDCommMessaging",
+							ServiceEndpoint: "https://example.com/didcomm",
+							Accept:          []string{"didcomm/v2"},
+						}},
+					},
+				})
+				require.True(tt, util.Is2xxResponse(w.Code), w.Body.String())
+				require.NoError(tt, json.NewDecoder(w.Body).Decode(&resp))
+				assert.True(tt, strings.HasPrefix(resp.DID.ID, "did:peer:2.Vz"))
+				require.Len(tt, resp.DID.VerificationMethod, 2)
+				authenticationID := resp.DID.VerificationMethod[0].ID
+				keyAgreementID := resp.DID.VerificationMethod[1].ID
+				assert.Equal(tt, []didsdk.VerificationMethodSet{authenticationID}, resp.DID.Authentication)
+				assert.Equal(tt, []didsdk.VerificationMethodSet{keyAgreementID}, resp.DID.KeyAgreement)
+				require.Len(tt, resp.DID.Services, 1)
+				assert.Equal(tt, resp.DID.ID+"#service", resp.DID.Services[0].ID)
+				assert.Equal(tt, "https://example.com/didcomm", resp.DID.Services[0].ServiceEndpoint)
+
+				for keyID, keyType := range map[string]crypto.KeyType{authenticationID: crypto.Ed25519, keyAgreementID: crypto.X25519} {
+					keyDetails, err = keyStoreService.GetKeyDetails(context.Background(), keystore.GetKeyDetailsRequest{ID: keyID})
+					require.NoError(tt, err)
+					assert.Equal(tt, resp.DID.ID, keyDetails.Controller)
+					assert.Equal(tt, keyType, keyDetails.Type)
+				}
+
+				// the DID alone resolves to the same keys and service, without the stored document
+				resolved, err := peer.Resolver{}.Resolve(context.Background(), resp.DID.ID)
+				require.NoError(tt, err)
+				require.Len(tt, resolved.Document.Authentication, 1)
+				assert.Equal(tt, authenticationID, resolved.Document.Authentication[0].(didsdk.VerificationMethod).ID)
+				require.Len(tt, resolved.Document.KeyAgreement, 1)
+				assert.Equal(tt, keyAgreementID, resolved.Document.KeyAgreement[0].(didsdk.VerificationMethod).ID)
+				require.Len(tt, resolved.Document.Services, 1)
+				assert.Equal(tt, "DIDCommMessaging", resolved.Document.Services[0].Type)
+				assert.Equal(tt, "https://example.com/didcomm", resolved.Document.Services[0].ServiceEndpoint)
+
+				// numalgo 0 DIDs have no services
+				w = create(router.CreateDIDByMethodRequest{
+					KeyType: crypto.Ed25519,
+					Options: did.CreatePeerDIDOptions{Services: []didsdk.Service{{Type: "DIDCommMessaging", ServiceEndpoint: "https://example.com"}}},
+				})
+				assert.Equal(tt, http.StatusInternalServerError, w.Code)
+
+				// numalgo 1 isn't supported
+				w = create(router.CreateDIDByMethodRequest{KeyType: crypto.Ed25519, Options: did.CreatePeerDIDOptions{NumAlgo: 1}})
+				assert.Equal(tt, http.StatusInternalServerError, w.Code)
+
+				// key agreement keys can't be used for authentication
+				w = create(router.CreateDIDByMethodRequest{KeyType: crypto.X25519})
+				assert.Equal(tt, http.StatusInternalServerError, w.Code)
+			})
+
+			t.Run("Test Create DID By Method: JWK and PKH", func(tt *testing.T) {
+				db := test.ServiceStorage(tt)
+				require.NotEmpty(tt, db)
+
+				_, keyStoreService, _ := testKeyStore(tt, db)
+				didService, _ := testDIDRouter(tt, db, keyStoreService, []string{"jwk", "pkh"}, nil)
+
+				create := func(method string, createDIDRequest router.CreateDIDByMethodRequest) *httptest.ResponseRecorder {
+					w := httptest.NewRecorder()
+					req := httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/dids/"+method, newRequestValue(tt, createDIDRequest))
+					c := newRequestContextWithParams(w, req, map[string]string{"method": method})
+					didService.CreateDIDByMethod(c)
+					return w
+				}
+
+				w := create("jwk", router.CreateDIDByMethodRequest{KeyType: crypto.Ed25519})
+				require.True(tt, util.Is2xxResponse(w.Code), w.Body.String())
+				var resp router.CreateDIDByMethodResponse
+				require.NoError(tt, json.NewDecoder(w.Body).Decode(&resp))
+				assert.True(tt, strings.HasPrefix(resp.DID.ID, "did:jwk:"))
+				require.Len(tt, resp.DID.VerificationMethod, 1)
+				assert.Equal(tt, resp.DID.ID+"#0", resp.DID.VerificationMethod[0].ID)
+				keyDetails, err := keyStoreService.GetKeyDetails(context.Background(), keystore.GetKeyDetailsRequest{ID: resp.DID.VerificationMethod[0].ID})
+				require.NoError(tt, err)
+				assert.Equal(tt, resp.DID.ID, keyDetails.Controller)
+
+				// eip155 accounts are checksummed addresses of secp256k1 keys
+				w = create("pkh", router.CreateDIDByMethodRequest{
+					KeyType: crypto.SECP256k1,
+					Options: did.CreatePKHDIDOptions{Namespace: did.EIP155Namespace, Reference: "137"},
+				})
+				require.True(tt, util.Is2xxResponse(w.Code), w.Body.String())
+				require.NoError(tt, json.NewDecoder(w.Body).Decode(&resp))
+				assert.Regexp(tt, `^did:pkh:eip155:137:0x[0-9a-fA-F]{40}$`, resp.DID.ID)
+				require.Len(tt, resp.DID.VerificationMethod, 1)
+				assert.Equal(tt, resp.DID.ID+"#blockchainAccountId", resp.DID.VerificationMethod[0].ID)
+				assert.Equal(tt, strings.TrimPrefix(resp.DID.ID, "did:pkh:"), resp.DID.VerificationMethod[0].BlockchainAccountID)
+				assert.NotNil(tt, resp.DID.VerificationMethod[0].PublicKeyJWK)
+				keyDetails, err = keyStoreService.GetKeyDetails(context.Background(), keystore.GetKeyDetailsRequest{ID: resp.DID.VerificationMethod[0].ID})
+				require.NoError(tt, err)
+				assert.Equal(tt, resp.DID.ID, keyDetails.Controller)
+				assert.Equal(tt, crypto.SECP256k1, keyDetails.Type)
+
+				// solana accounts are base58 encoded Ed25519 keys, on mainnet by default
+				w = create("pkh", router.CreateDIDByMethodRequest{
+					KeyType: crypto.Ed25519,
+					Options: did.CreatePKHDIDOptions{Namespace: did.SolanaNamespace},
+				})
+				require.True(tt, util.Is2xxResponse(w.Code), w.Body.String())
+				require.NoError(tt, json.NewDecoder(w.Body).Decode(&resp))
+				assert.True(tt, strings.HasPrefix(resp.DID.ID, "did:pkh:solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp:"))
+				assert.Equal(tt, resp.DID.ID+"#controller", resp.DID.VerificationMethod[0].ID)
+
+				// the key type must match the namespace
+				w = create("pkh", router.CreateDIDByMethodRequest{
+					KeyType: crypto.Ed25519,
+					Options: did.CreatePKHDIDOptions{Namespace: did.EIP155Namespace},
+				})
+				assert.Equal(tt, http.StatusInternalServerError, w.Code)
+				assert.Contains(tt, w.Body.String(), "not supported for eip155 accounts")
+
+				// only eip155 and solana are supported
+				w = create("pkh", router.CreateDIDByMethodRequest{
+					KeyType: crypto.SECP256k1,
+					Options: did.CreatePKHDIDOptions{Namespace: "bip122"},
+				})
+				assert.Equal(tt, http.StatusInternalServerError, w.Code)
+			})
+
 			t.Run("Test Create DID By Method: ION", func(tt *testing.T) {
 				db := test.ServiceStorage(tt)
 				require.NotEmpty(tt, db)
@@ -375,6 +542,615 @@ func TestDIDAPI(t *testing.T) {
 				assert.Len(tt, updateDIDResponse.DID.CapabilityInvocation, 0+len(createDIDResponse.DID.CapabilityInvocation))
 				assert.Len(tt, updateDIDResponse.DID.CapabilityInvocation, 0+len(createDIDResponse.DID.CapabilityInvocation))
 
+				// every applied update is a version the DID can be resolved at
+				for versionID, expectedDID := range map[string]didsdk.Document{"1": createDIDResponse.DID, "3": updateDIDResponse.DID} {
+					w = httptest.NewRecorder()
+					req = httptest.NewRequest(http.MethodGet, "https://ssi-service.com/v1/dids/resolver/"+createDIDResponse.DID.ID+"?versionId="+versionID, nil)
+					c = newRequestContextWithParams(w, req, map[string]string{"id": createDIDResponse.DID.ID})
+					didService.ResolveDID(c)
+					require.True(tt, util.Is2xxResponse(w.Code), w.Body.String())
+
+					var resolveDIDResponse router.ResolveDIDResponse
+					require.NoError(tt, json.NewDecoder(w.Body).Decode(&resolveDIDResponse))
+					assert.Equal(tt, versionID, resolveDIDResponse.DIDDocumentMetadata.VersionID)
+					assert.Equal(tt, expectedDID.VerificationMethod, resolveDIDResponse.DIDDocument.VerificationMethod)
+					assert.Equal(tt, expectedDID.Services, resolveDIDResponse.DIDDocument.Services)
+				}
+			})
+
+			t.Run("Test Update DID By Method: Web", func(tt *testing.T) {
+				db := test.ServiceStorage(tt)
+				require.NotEmpty(tt, db)
+
+				_, keyStore, keyStoreFactory := testKeyStore(tt, db)
+				didRouter, _ := testDIDRouter(tt, db, keyStore, []string{"key", "web"}, keyStoreFactory)
+
+				gock.New("https://example.com").Get("/").Reply(404)
+				defer gock.Off()
+
+				w := httptest.NewRecorder()
+				params := map[string]string{"method": "web"}
+				createDIDRequest := router.CreateDIDByMethodRequest{
+					KeyType: crypto.Ed25519,
+					Options: did.CreateWebDIDOptions{DIDWebID: "did:web:example.com"},
+				}
+				req := httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/dids/web", newRequestValue(tt, createDIDRequest))
+				c := newRequestContextWithParams(w, req, params)
+				didRouter.CreateDIDByMethod(c)
+				require.True(tt, util.Is2xxResponse(w.Code))
+
+				var createDIDResponse router.CreateDIDByMethodResponse
+				require.NoError(tt, json.NewDecoder(w.Body).Decode(&createDIDResponse))
+				id := createDIDResponse.DID.ID
+				params["id"] = id
+
+				update := func(stateChange router.StateChange) *httptest.ResponseRecorder {
+					w := httptest.NewRecorder()
+					requestReader := newRequestValue(tt, router.UpdateDIDByMethodRequest{StateChange: stateChange})
+					req := httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/dids/web/"+id, requestReader)
+					c := newRequestContextWithParams(w, req, params)
+					didRouter.UpdateDIDByMethod(c)
+					return w
+				}
+
+				// add a key and a service
+				w = update(router.StateChange{
+					KeysToAdd: []router.KeyToAdd{{
+						ID:       "#key-2",
+						KeyType:  crypto.SECP256k1,
+						Purposes: []ion.PublicKeyPurpose{ion.Authentication, ion.AssertionMethod},
+					}},
+					ServicesToAdd: []didsdk.Service{{
+						ID:              "#linked-domain",
+						Type:            "LinkedDomains",
+						ServiceEndpoint: "https://example.com",
+					}},
+				})
+				require.True(tt, util.Is2xxResponse(w.Code), w.Body.String())
+
+				var updateDIDResponse router.UpdateDIDByMethodResponse
+				require.NoError(tt, json.NewDecoder(w.Body).Decode(&updateDIDResponse))
+				assert.Equal(tt, 2, updateDIDResponse.Version)
+				assert.Len(tt, updateDIDResponse.DID.VerificationMethod, 1+len(createDIDResponse.DID.VerificationMethod))
+				assert.Len(tt, updateDIDResponse.DID.Authentication, 1+len(createDIDResponse.DID.Authentication))
+				assert.Len(tt, updateDIDResponse.DID.AssertionMethod, 1+len(createDIDResponse.DID.AssertionMethod))
+				assert.Len(tt, updateDIDResponse.DID.KeyAgreement, len(createDIDResponse.DID.KeyAgreement))
+				require.Len(tt, updateDIDResponse.DID.Services, 1)
+				assert.Equal(tt, id+"#linked-domain", updateDIDResponse.DID.Services[0].ID)
+
+				keyID := id + "#key-2"
+				keyDetails, err := keyStore.GetKeyDetails(context.Background(), keystore.GetKeyDetailsRequest{ID: keyID})
+				require.NoError(tt, err)
+				assert.Equal(tt, id, keyDetails.Controller)
+				assert.Equal(tt, crypto.SECP256k1, keyDetails.Type)
+
+				// the key can't be added twice
+				w = update(router.StateChange{KeysToAdd: []router.KeyToAdd{{ID: "#key-2", KeyType: crypto.Ed25519}}})
+				assert.Equal(tt, http.StatusBadRequest, w.Code)
+
+				// nothing is stored when part of an update is invalid
+				w = update(router.StateChange{
+					KeysToAdd:          []router.KeyToAdd{{ID: "#key-3", KeyType: crypto.Ed25519}},
+					ServiceIDsToRemove: []string{"#unknown"},
+				})
+				assert.Equal(tt, http.StatusBadRequest, w.Code)
+				_, err = keyStore.GetKeyDetails(context.Background(), keystore.GetKeyDetailsRequest{ID: id + "#key-3"})
+				assert.Error(tt, err)
+
+				// move the key to key agreement, and rotate it
+				w = update(router.StateChange{
+					PurposesToSet:        []router.PublicKeyPurposes{{ID: "#key-2", Purposes: []ion.PublicKeyPurpose{ion.KeyAgreement}}},
+					PublicKeyIDsToRotate: []string{keyID},
+				})
+				require.True(tt, util.Is2xxResponse(w.Code), w.Body.String())
+				var rotatedDIDResponse router.UpdateDIDByMethodResponse
+				require.NoError(tt, json.NewDecoder(w.Body).Decode(&rotatedDIDResponse))
+				assert.Equal(tt, 3, rotatedDIDResponse.Version)
+				assert.Len(tt, rotatedDIDResponse.DID.Authentication, len(createDIDResponse.DID.Authentication))
+				assert.Len(tt, rotatedDIDResponse.DID.AssertionMethod, len(createDIDResponse.DID.AssertionMethod))
+				assert.Len(tt, rotatedDIDResponse.DID.KeyAgreement, 1+len(createDIDResponse.DID.KeyAgreement))
+				require.Len(tt, rotatedDIDResponse.DID.VerificationMethod, 2)
+				assert.Equal(tt, keyID, rotatedDIDResponse.DID.VerificationMethod[1].ID)
+				assert.NotEqual(tt, updateDIDResponse.DID.VerificationMethod[1].PublicKeyJWK.X, rotatedDIDResponse.DID.VerificationMethod[1].PublicKeyJWK.X)
+
+				// remove the key and the service
+				w = update(router.StateChange{PublicKeyIDsToRemove: []string{"#key-2"}, ServiceIDsToRemove: []string{"#linked-domain"}})
+				require.True(tt, util.Is2xxResponse(w.Code), w.Body.String())
+				var removedDIDResponse router.UpdateDIDByMethodResponse
+				require.NoError(tt, json.NewDecoder(w.Body).Decode(&removedDIDResponse))
+				assert.Equal(tt, 4, removedDIDResponse.Version)
+				assert.Len(tt, removedDIDResponse.DID.VerificationMethod, len(createDIDResponse.DID.VerificationMethod))
+				assert.Len(tt, removedDIDResponse.DID.KeyAgreement, len(createDIDResponse.DID.KeyAgreement))
+				assert.Empty(tt, removedDIDResponse.DID.Services)
+
+				keyDetails, err = keyStore.GetKeyDetails(context.Background(), keystore.GetKeyDetailsRequest{ID: keyID})
+				require.NoError(tt, err)
+				assert.True(tt, keyDetails.Revoked)
+
+				// public keys can only be added to ION DIDs
+				w = update(router.StateChange{PublicKeysToAdd: []ion.PublicKey{{ID: "key-3"}}})
+				assert.Equal(tt, http.StatusBadRequest, w.Code)
+
+				// every version of the document is kept
+				w = httptest.NewRecorder()
+				req = httptest.NewRequest(http.MethodGet, "https://ssi-service.com/v1/dids/web/"+id+"/versions", nil)
+				c = newRequestContextWithParams(w, req, params)
+				didRouter.ListDIDVersions(c)
+				require.True(tt, util.Is2xxResponse(w.Code), w.Body.String())
+
+				var versionsResponse router.ListDIDVersionsResponse
+				require.NoError(tt, json.NewDecoder(w.Body).Decode(&versionsResponse))
+				require.Len(tt, versionsResponse.Versions, 4)
+				for i, version := range versionsResponse.Versions {
+					assert.Equal(tt, i+1, version.Version)
+				}
+				assert.Equal(tt, createDIDResponse.DID.VerificationMethod, versionsResponse.Versions[0].DID.VerificationMethod)
+				assert.Len(tt, versionsResponse.Versions[2].DID.VerificationMethod, 2)
+
+				// did:key documents can't be updated
+				w = httptest.NewRecorder()
+				params = map[string]string{"method": "key"}
+				req = httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/dids/key", newRequestValue(tt, router.CreateDIDByMethodRequest{KeyType: crypto.Ed25519}))
+				c = newRequestContextWithParams(w, req, params)
+				didRouter.CreateDIDByMethod(c)
+				require.True(tt, util.Is2xxResponse(w.Code))
+				require.NoError(tt, json.NewDecoder(w.Body).Decode(&createDIDResponse))
+				params["id"] = createDIDResponse.DID.ID
+				w = update(router.StateChange{ServiceIDsToRemove: []string{"#linked-domain"}})
+				assert.Equal(tt, http.StatusInternalServerError, w.Code)
+				assert.Contains(tt, w.Body.String(), "not supported")
+			})
+
+			t.Run("Test Resolve Cached DID After Update", func(tt *testing.T) {
+				db := test.ServiceStorage(tt)
+				require.NotEmpty(tt, db)
+
+				keyStoreService, keyStoreServiceFactory := testKeyStoreService(tt, db)
+				didService, err := did.NewDIDService(config.DIDServiceConfig{
+					Methods:                []string{"key", "web"},
+					LocalResolutionMethods: []string{"key", "web"},
+					ResolutionCacheTTL:     time.Hour,
+					ResolutionCacheBackend: resolution.StorageCacheBackend,
+				}, db, keyStoreService, keyStoreServiceFactory)
+				require.NoError(tt, err)
+
+				gock.New("https://example.com").Get("/").Reply(404)
+				defer gock.Off()
+
+				created, err := didService.CreateDIDByMethod(context.Background(), did.CreateDIDRequest{
+					Method:  didsdk.WebMethod,
+					KeyType: crypto.Ed25519,
+					Options: did.CreateWebDIDOptions{DIDWebID: "did:web:example.com"},
+				})
+				require.NoError(tt, err)
+				id := created.DID.ID
+
+				resolved, err := didService.ResolveDID(did.ResolveDIDRequest{DID: id})
+				require.NoError(tt, err)
+				assert.Empty(tt, resolved.DIDDocument.Services)
+
+				// the cached resolution is invalidated by the update
+				_, err = didService.UpdateDIDByMethod(context.Background(), did.UpdateDIDRequest{
+					Method: didsdk.WebMethod,
+					ID:     id,
+					StateChange: did.DocumentStateChange{
+						ServicesToAdd: []didsdk.Service{{
+							ID:              "#linked-domain",
+							Type:            "LinkedDomains",
+							ServiceEndpoint: "https://example.com",
+						}},
+					},
+				})
+				require.NoError(tt, err)
+
+				resolved, err = didService.ResolveDID(did.ResolveDIDRequest{DID: id})
+				require.NoError(tt, err)
+				require.Len(tt, resolved.DIDDocument.Services, 1)
+				assert.Equal(tt, id+"#linked-domain", resolved.DIDDocument.Services[0].ID)
+			})
+
+			t.Run("Test Recover And Deactivate DID By Method: ION", func(tt *testing.T) {
+				db := test.ServiceStorage(tt)
+				require.NotEmpty(tt, db)
+
+				_, keyStoreService, keyStoreServiceFactory := testKeyStore(tt, db)
+				didService, _ := testDIDRouter(tt, db, keyStoreService, []string{"ion"}, keyStoreServiceFactory)
+
+				var submittedOps []map[string]any
+				gock.New(testIONResolverURL).
+					Post("/operations").
+					Times(5).
+					AddMatcher(func(req *http.Request, _ *gock.Request) (bool, error) {
+						var op map[string]any
+						err := json.NewDecoder(req.Body).Decode(&op)
+						submittedOps = append(submittedOps, op)
+						return true, err
+					}).
+					Reply(200).
+					JSON("{}")
+				defer gock.Off()
+
+				changeDID := func(id string, request router.UpdateDIDByMethodRequest) *httptest.ResponseRecorder {
+					w := httptest.NewRecorder()
+					req := httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/dids/ion/"+id, newRequestValue(tt, request))
+					c := newRequestContextWithParams(w, req, map[string]string{"method": "ion", "id": id})
+					didService.UpdateDIDByMethod(c)
+					return w
+				}
+
+				w := httptest.NewRecorder()
+				req := httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/dids/ion", newRequestValue(tt, router.CreateDIDByMethodRequest{KeyType: crypto.Ed25519}))
+				c := newRequestContextWithParams(w, req, map[string]string{"method": "ion"})
+				didService.CreateDIDByMethod(c)
+				require.True(tt, util.Is2xxResponse(w.Code))
+				var createDIDResponse router.CreateDIDByMethodResponse
+				require.NoError(tt, json.NewDecoder(w.Body).Decode(&createDIDResponse))
+				id := createDIDResponse.DID.ID
+
+				update := router.UpdateDIDByMethodRequest{
+					StateChange: router.StateChange{
+						ServicesToAdd: []didsdk.Service{{ID: "hub", Type: "Hub", ServiceEndpoint: "https://hub.example.com"}},
+					},
+				}
+				w = changeDID(id, update)
+				require.True(tt, util.Is2xxResponse(w.Code), w.Body.String())
+
+				// recover requires the document that replaces the DID's state
+				w = changeDID(id, router.UpdateDIDByMethodRequest{Operation: "recover"})
+				assert.Equal(tt, http.StatusBadRequest, w.Code)
+				assert.Contains(tt, w.Body.String(), "document is required")
+
+				recovered := ion.Document{
+					PublicKeys: []ion.PublicKey{
+						{
+							ID:   "recoveredKey",
+							Type: "EcdsaSecp256k1VerificationKey2019",
+							PublicKeyJWK: jwx.PublicKeyJWK{
+								KTY: "EC",
+								CRV: "secp256k1",
+								X:   "tXSKB_rubXS7sCjXqupVJEzTcW3MsjmEvq1YpXn96Zg",
+								Y:   "dOicXqbjFxoGJ-K0-GJ1kHYJqic_D_OMuUwkQ7Ol6nk",
+							},
+							Purposes: []ion.PublicKeyPurpose{ion.Authentication},
+						},
+					},
+				}
+				w = changeDID(id, router.UpdateDIDByMethodRequest{Operation: "recover", Document: &recovered})
+				require.True(tt, util.Is2xxResponse(w.Code), w.Body.String())
+				var recoverDIDResponse router.UpdateDIDByMethodResponse
+				require.NoError(tt, json.NewDecoder(w.Body).Decode(&recoverDIDResponse))
+				assert.NotEmpty(tt, recoverDIDResponse.OperationID)
+				assert.True(tt, strings.HasPrefix(recoverDIDResponse.DID.ID, id))
+				require.Len(tt, recoverDIDResponse.DID.VerificationMethod, 1)
+				assert.Equal(tt, "#recoveredKey", recoverDIDResponse.DID.VerificationMethod[0].ID)
+				assert.Empty(tt, recoverDIDResponse.DID.Services)
+
+				// updates after the recovery are signed with the rotated update key
+				w = changeDID(id, update)
+				require.True(tt, util.Is2xxResponse(w.Code), w.Body.String())
+				var updateDIDResponse router.UpdateDIDByMethodResponse
+				require.NoError(tt, json.NewDecoder(w.Body).Decode(&updateDIDResponse))
+				assert.Len(tt, updateDIDResponse.DID.VerificationMethod, 1)
+				assert.Len(tt, updateDIDResponse.DID.Services, 1)
+
+				w = changeDID(id, router.UpdateDIDByMethodRequest{Operation: "deactivate"})
+				require.True(tt, util.Is2xxResponse(w.Code), w.Body.String())
+				var deactivateDIDResponse router.UpdateDIDByMethodResponse
+				require.NoError(tt, json.NewDecoder(w.Body).Decode(&deactivateDIDResponse))
+				assert.True(tt, strings.HasPrefix(deactivateDIDResponse.DID.ID, id))
+				assert.Empty(tt, deactivateDIDResponse.DID.VerificationMethod)
+				assert.Empty(tt, deactivateDIDResponse.DID.Services)
+
+				require.Len(tt, submittedOps, 5)
+				types := make([]any, 0, len(submittedOps))
+				for _, op := range submittedOps {
+					types = append(types, op["type"])
+				}
+				assert.Equal(tt, []any{"create", "update", "recover", "update", "deactivate"}, types)
+				assert.NotEqual(tt, submittedOps[1]["revealValue"], submittedOps[3]["revealValue"])
+				// recover and deactivate are both signed with the recovery key, which the recovery rotated
+				assert.NotEqual(tt, submittedOps[2]["revealValue"], submittedOps[4]["revealValue"])
+
+				w = httptest.NewRecorder()
+				req = httptest.NewRequest(http.MethodGet, "https://ssi-service.com/v1/dids/resolver/"+id, nil)
+				c = newRequestContextWithParams(w, req, map[string]string{"id": id})
+				didService.ResolveDID(c)
+				require.True(tt, util.Is2xxResponse(w.Code), w.Body.String())
+				var resolveDIDResponse router.ResolveDIDResponse
+				require.NoError(tt, json.NewDecoder(w.Body).Decode(&resolveDIDResponse))
+				assert.True(tt, resolveDIDResponse.DIDDocumentMetadata.Deactivated)
+				assert.Equal(tt, "5", resolveDIDResponse.DIDDocumentMetadata.VersionID)
+
+				// deactivated DIDs cannot be changed anymore
+				for _, request := range []router.UpdateDIDByMethodRequest{update, {Operation: "recover", Document: &recovered}, {Operation: "deactivate"}} {
+					w = changeDID(id, request)
+					assert.Equal(tt, http.StatusInternalServerError, w.Code)
+					assert.Contains(tt, w.Body.String(), "is deactivated")
+				}
+
+				// other methods only support updates
+				w = changeDID("did:web:example.com", router.UpdateDIDByMethodRequest{Operation: "deactivate"})
+				assert.Equal(tt, http.StatusBadRequest, w.Code)
+			})
+
+			t.Run("Test Retry ION Operations While Node Is Unavailable", func(tt *testing.T) {
+				db := test.ServiceStorage(tt)
+				require.NotEmpty(tt, db)
+
+				keyStoreService, keyStoreServiceFactory := testKeyStoreService(tt, db)
+				didService, err := did.NewDIDService(config.DIDServiceConfig{
+					Methods:         []string{"ion"},
+					IONResolverURL:  testIONResolverURL,
+					IONRetryBackoff: time.Nanosecond,
+				}, db, keyStoreService, keyStoreServiceFactory)
+				require.NoError(tt, err)
+				operationService, err := operation.NewOperationService(db)
+				require.NoError(tt, err)
+
+				ctx := context.Background()
+				getAnchoring := func(id string) (bool, anchor.Anchoring) {
+					op, err := operationService.GetOperation(ctx, operation.GetOperationRequest{ID: id})
+					require.NoError(tt, err)
+					return op.Done, op.Result.Response.(anchor.Anchoring)
+				}
+				published := func(id, updateCommitment string) {
+					gock.New(testIONResolverURL).
+						Get("/identifiers/" + id).
+						Reply(200).
+						JSON(map[string]any{
+							"didDocument":         map[string]any{"id": id},
+							"didDocumentMetadata": map[string]any{"method": map[string]any{"published": true, "updateCommitment": updateCommitment}},
+						})
+				}
+				defer gock.Off()
+
+				// the create operation is queued while the node is unavailable
+				gock.New(testIONResolverURL).Post("/operations").Reply(503)
+				created, err := didService.CreateDIDByMethod(ctx, did.CreateDIDRequest{Method: didsdk.IONMethod, KeyType: crypto.Ed25519})
+				require.NoError(tt, err)
+				require.True(tt, strings.HasPrefix(created.OperationID, anchor.ParentResource))
+				id := created.DID.ID
+
+				done, anchoring := getAnchoring(created.OperationID)
+				assert.False(tt, done)
+				assert.Equal(tt, id, anchoring.DID)
+				assert.Equal(tt, anchor.TypeCreate, anchoring.Type)
+				assert.Equal(tt, anchor.StatusPending, anchoring.Status)
+				assert.Equal(tt, 1, anchoring.Attempts)
+				assert.Contains(tt, anchoring.LastError, "503")
+				assert.NotEmpty(tt, anchoring.NextAttemptAt)
+
+				// and resubmitted once the node is available
+				gock.New(testIONResolverURL).Post("/operations").Reply(200).JSON("{}")
+				require.NoError(tt, didService.ProcessIONOperations(ctx))
+				done, anchoring = getAnchoring(created.OperationID)
+				assert.False(tt, done)
+				assert.Equal(tt, anchor.StatusAnchored, anchoring.Status)
+				assert.Equal(tt, 2, anchoring.Attempts)
+				assert.Empty(tt, anchoring.LastError)
+
+				// unpublished DIDs cannot be resolved from the node
+				gock.New(testIONResolverURL).Get("/identifiers/" + id).Reply(404)
+				require.NoError(tt, didService.ProcessIONOperations(ctx))
+				done, anchoring = getAnchoring(created.OperationID)
+				assert.False(tt, done)
+				assert.Equal(tt, anchor.StatusAnchored, anchoring.Status)
+
+				published(id, "")
+				require.NoError(tt, didService.ProcessIONOperations(ctx))
+				done, anchoring = getAnchoring(created.OperationID)
+				assert.True(tt, done)
+				assert.Equal(tt, anchor.StatusPublished, anchoring.Status)
+				assert.NotEmpty(tt, anchoring.PublishedAt)
+
+				// updates are queued too, and applied once they are anchored
+				var updateOp ion.UpdateRequest
+				gock.New(testIONResolverURL).
+					Post("/operations").
+					AddMatcher(func(req *http.Request, _ *gock.Request) (bool, error) {
+						return true, json.NewDecoder(req.Body).Decode(&updateOp)
+					}).
+					Reply(503)
+				update := did.UpdateIONDIDRequest{
+					DID: ion.ION(id),
+					StateChange: ion.StateChange{
+						ServicesToAdd: []didsdk.Service{{ID: "hub", Type: "Hub", ServiceEndpoint: "https://hub.example.com"}},
+					},
+				}
+				updated, err := didService.UpdateIONDID(ctx, update)
+				require.NoError(tt, err)
+				assert.Len(tt, updated.DID.Services, 1)
+				assert.NotEmpty(tt, updateOp.Delta.UpdateCommitment)
+
+				done, anchoring = getAnchoring(updated.OperationID)
+				assert.False(tt, done)
+				assert.Equal(tt, anchor.TypeUpdate, anchoring.Type)
+				assert.Equal(tt, anchor.StatusPending, anchoring.Status)
+
+				gotDID, err := didService.GetDIDByMethod(ctx, did.GetDIDRequest{Method: didsdk.IONMethod, ID: id})
+				require.NoError(tt, err)
+				assert.Empty(tt, gotDID.DID.Services)
+
+				_, err = didService.UpdateIONDID(ctx, update)
+				assert.ErrorContains(tt, err, "already has an operation queued by operation<"+updated.OperationID+">")
+
+				gock.New(testIONResolverURL).Post("/operations").Reply(200).JSON("{}")
+				require.NoError(tt, didService.ProcessIONOperations(ctx))
+				done, anchoring = getAnchoring(updated.OperationID)
+				assert.False(tt, done)
+				assert.Equal(tt, anchor.StatusAnchored, anchoring.Status)
+
+				gotDID, err = didService.GetDIDByMethod(ctx, did.GetDIDRequest{Method: didsdk.IONMethod, ID: id})
+				require.NoError(tt, err)
+				assert.Len(tt, gotDID.DID.Services, 1)
+
+				// the update is published once the DID has its update commitment
+				published(id, "stale")
+				require.NoError(tt, didService.ProcessIONOperations(ctx))
+				done, _ = getAnchoring(updated.OperationID)
+				assert.False(tt, done)
+
+				published(id, updateOp.Delta.UpdateCommitment)
+				require.NoError(tt, didService.ProcessIONOperations(ctx))
+				done, anchoring = getAnchoring(updated.OperationID)
+				assert.True(tt, done)
+				assert.Equal(tt, anchor.StatusPublished, anchoring.Status)
+
+				// operations the node rejects fail right away
+				gock.New(testIONResolverURL).Post("/operations").Reply(400).JSON(map[string]any{"code": "invalid_signature"})
+				_, err = didService.UpdateIONDID(ctx, update)
+				assert.ErrorContains(tt, err, "anchor operation failed with status 400")
+
+				// unless the operation was already submitted
+				gock.New(testIONResolverURL).Post("/operations").Reply(400).JSON(map[string]any{"code": "queueing_multiple_operations_per_did_not_allowed"})
+				updated, err = didService.UpdateIONDID(ctx, update)
+				require.NoError(tt, err)
+				done, anchoring = getAnchoring(updated.OperationID)
+				assert.False(tt, done)
+				assert.Equal(tt, anchor.StatusAnchored, anchoring.Status)
+
+				// deactivations are queued too, and published once the DID resolves as deactivated
+				gock.New(testIONResolverURL).Post("/operations").Reply(503)
+				deactivated, err := didService.DeactivateIONDID(ctx, did.DeactivateIONDIDRequest{DID: ion.ION(id)})
+				require.NoError(tt, err)
+				done, anchoring = getAnchoring(deactivated.OperationID)
+				assert.False(tt, done)
+				assert.Equal(tt, anchor.TypeDeactivate, anchoring.Type)
+				assert.Equal(tt, anchor.StatusPending, anchoring.Status)
+
+				gock.New(testIONResolverURL).Post("/operations").Reply(200).JSON("{}")
+				gock.New(testIONResolverURL).
+					Get("/identifiers/" + id).
+					Times(2).
+					Reply(200).
+					JSON(map[string]any{
+						"didDocument":         map[string]any{"id": id},
+						"didDocumentMetadata": map[string]any{"deactivated": true, "method": map[string]any{"published": true}},
+					})
+				require.NoError(tt, didService.ProcessIONOperations(ctx))
+				require.NoError(tt, didService.ProcessIONOperations(ctx))
+				done, anchoring = getAnchoring(deactivated.OperationID)
+				assert.True(tt, done)
+				assert.Equal(tt, anchor.StatusPublished, anchoring.Status)
+				// the update that was anchored before is published too
+				done, _ = getAnchoring(updated.OperationID)
+				assert.True(tt, done)
+
+				gotDID, err = didService.GetDIDByMethod(ctx, did.GetDIDRequest{Method: didsdk.IONMethod, ID: id})
+				require.NoError(tt, err)
+				assert.Empty(tt, gotDID.DID.Services)
+				assert.True(tt, gock.IsDone())
+			})
+
+			t.Run("Test Recover Or Deactivate ION DID With Queued Update", func(tt *testing.T) {
+				db := test.ServiceStorage(tt)
+				require.NotEmpty(tt, db)
+
+				keyStoreService, keyStoreServiceFactory := testKeyStoreService(tt, db)
+				didService, err := did.NewDIDService(config.DIDServiceConfig{
+					Methods:         []string{"ion"},
+					IONResolverURL:  testIONResolverURL,
+					IONRetryBackoff: time.Nanosecond,
+				}, db, keyStoreService, keyStoreServiceFactory)
+				require.NoError(tt, err)
+				operationService, err := operation.NewOperationService(db)
+				require.NoError(tt, err)
+				defer gock.Off()
+
+				ctx := context.Background()
+				getAnchoring := func(id string) (bool, anchor.Anchoring) {
+					op, err := operationService.GetOperation(ctx, operation.GetOperationRequest{ID: id})
+					require.NoError(tt, err)
+					return op.Done, op.Result.Response.(anchor.Anchoring)
+				}
+				update := func(id string) *did.UpdateIONDIDResponse {
+					gock.New(testIONResolverURL).Post("/operations").Reply(503)
+					updated, err := didService.UpdateIONDID(ctx, did.UpdateIONDIDRequest{
+						DID: ion.ION(id),
+						StateChange: ion.StateChange{
+							ServicesToAdd: []didsdk.Service{{ID: "hub", Type: "Hub", ServiceEndpoint: "https://hub.example.com"}},
+						},
+					})
+					require.NoError(tt, err)
+					_, anchoring := getAnchoring(updated.OperationID)
+					require.Equal(tt, anchor.StatusPending, anchoring.Status)
+					return updated
+				}
+				recovered := ion.Document{
+					PublicKeys: []ion.PublicKey{
+						{
+							ID:   "recoveredKey",
+							Type: "EcdsaSecp256k1VerificationKey2019",
+							PublicKeyJWK: jwx.PublicKeyJWK{
+								KTY: "EC",
+								CRV: "secp256k1",
+								X:   "tXSKB_rubXS7sCjXqupVJEzTcW3MsjmEvq1YpXn96Zg",
+								Y:   "dOicXqbjFxoGJ-K0-GJ1kHYJqic_D_OMuUwkQ7Ol6nk",
+							},
+							Purposes: []ion.PublicKeyPurpose{ion.Authentication},
+						},
+					},
+				}
+
+				gock.New(testIONResolverURL).Post("/operations").Reply(200).JSON("{}")
+				created, err := didService.CreateDIDByMethod(ctx, did.CreateDIDRequest{Method: didsdk.IONMethod, KeyType: crypto.Ed25519})
+				require.NoError(tt, err)
+				id := created.DID.ID
+
+				// a recover replaces the queued update
+				updated := update(id)
+				gock.New(testIONResolverURL).Post("/operations").Reply(200).JSON("{}")
+				recoveredDID, err := didService.RecoverIONDID(ctx, did.RecoverIONDIDRequest{DID: ion.ION(id), Document: recovered})
+				require.NoError(tt, err)
+				assert.NotEqual(tt, updated.OperationID, recoveredDID.OperationID)
+				require.Len(tt, recoveredDID.DID.VerificationMethod, 1)
+				assert.Equal(tt, "#recoveredKey", recoveredDID.DID.VerificationMethod[0].ID)
+				assert.Empty(tt, recoveredDID.DID.Services)
+
+				done, anchoring := getAnchoring(updated.OperationID)
+				assert.True(tt, done)
+				assert.Equal(tt, anchor.StatusFailed, anchoring.Status)
+				assert.Contains(tt, anchoring.LastError, "superseded by a recover operation")
+				_, anchoring = getAnchoring(recoveredDID.OperationID)
+				assert.Equal(tt, anchor.TypeRecover, anchoring.Type)
+				assert.Equal(tt, anchor.StatusAnchored, anchoring.Status)
+
+				gotDID, err := didService.GetDIDByMethod(ctx, did.GetDIDRequest{Method: didsdk.IONMethod, ID: id})
+				require.NoError(tt, err)
+				assert.Empty(tt, gotDID.DID.Services)
+
+				// the superseded update isn't resubmitted
+				require.NoError(tt, didService.ProcessIONOperations(ctx))
+				assert.True(tt, gock.IsDone())
+
+				// a deactivate replaces the queued update too
+				updated = update(id)
+				gock.New(testIONResolverURL).Post("/operations").Reply(200).JSON("{}")
+				deactivated, err := didService.DeactivateIONDID(ctx, did.DeactivateIONDIDRequest{DID: ion.ION(id)})
+				require.NoError(tt, err)
+				assert.Empty(tt, deactivated.DID.VerificationMethod)
+
+				done, anchoring = getAnchoring(updated.OperationID)
+				assert.True(tt, done)
+				assert.Contains(tt, anchoring.LastError, "superseded by a deactivate operation")
+				_, anchoring = getAnchoring(deactivated.OperationID)
+				assert.Equal(tt, anchor.TypeDeactivate, anchoring.Type)
+				assert.Equal(tt, anchor.StatusAnchored, anchoring.Status)
+				assert.True(tt, gock.IsDone())
+
+				// but an update doesn't replace a queued recover
+				gock.New(testIONResolverURL).Post("/operations").Reply(200).JSON("{}")
+				created, err = didService.CreateDIDByMethod(ctx, did.CreateDIDRequest{Method: didsdk.IONMethod, KeyType: crypto.Ed25519})
+				require.NoError(tt, err)
+				id = created.DID.ID
+				gock.New(testIONResolverURL).Post("/operations").Reply(503)
+				recoveredDID, err = didService.RecoverIONDID(ctx, did.RecoverIONDIDRequest{DID: ion.ION(id), Document: recovered})
+				require.NoError(tt, err)
+				_, err = didService.UpdateIONDID(ctx, did.UpdateIONDIDRequest{
+					DID:         ion.ION(id),
+					StateChange: ion.StateChange{ServiceIDsToRemove: []string{"hub"}},
+				})
+				assert.ErrorContains(tt, err, "already has a recover operation queued by operation<"+recoveredDID.OperationID+">")
 			})
 
 			t.Run("Test Create Duplicate DID:Webs", func(tt *testing.T) {
@@ -632,6 +1408,90 @@ func TestDIDAPI(t *testing.T) {
 				assert.Len(tt, gotDeletedDIDsResponseAfterDelete.DIDs, 1)
 			})
 
+			t.Run("Test Restore And Purge DID By Method", func(tt *testing.T) {
+				db := test.ServiceStorage(tt)
+				require.NotEmpty(tt, db)
+
+				_, keyStore, keyStoreFactory := testKeyStore(tt, db)
+				didService, _ := testDIDRouter(tt, db, keyStore, []string{"key"}, keyStoreFactory)
+
+				createDID := func() string {
+					w := httptest.NewRecorder()
+					req := httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/dids/key", newRequestValue(tt, router.CreateDIDByMethodRequest{KeyType: crypto.Ed25519}))
+					didService.CreateDIDByMethod(newRequestContextWithParams(w, req, map[string]string{"method": "key"}))
+					require.True(tt, util.Is2xxResponse(w.Code))
+					var createdDID router.CreateDIDByMethodResponse
+					require.NoError(tt, json.NewDecoder(w.Body).Decode(&createdDID))
+					return createdDID.DID.ID
+				}
+				listDIDs := func(query string) []string {
+					w := httptest.NewRecorder()
+					req := httptest.NewRequest(http.MethodGet, "https://ssi-service.com/v1/dids/key"+query, nil)
+					didService.ListDIDsByMethod(newRequestContextWithParams(w, req, map[string]string{"method": "key"}))
+					require.True(tt, util.Is2xxResponse(w.Code), w.Body.String())
+					var gotDIDsResponse router.ListDIDsByMethodResponse
+					require.NoError(tt, json.NewDecoder(w.Body).Decode(&gotDIDsResponse))
+					ids := make([]string, 0, len(gotDIDsResponse.DIDs))
+					for _, gotDID := range gotDIDsResponse.DIDs {
+						ids = append(ids, gotDID.ID)
+					}
+					return ids
+				}
+				call := func(handler gin.HandlerFunc, httpMethod, id string) *httptest.ResponseRecorder {
+					w := httptest.NewRecorder()
+					req := httptest.NewRequest(httpMethod, "https://ssi-service.com/v1/dids/key/"+id, nil)
+					handler(newRequestContextWithParams(w, req, map[string]string{"method": "key", "id": id}))
+					return w
+				}
+
+				kept := createDID()
+				deleted := createDID()
+				assert.True(tt, util.Is2xxResponse(call(didService.SoftDeleteDIDByMethod, http.MethodDelete, deleted).Code))
+
+				assert.Equal(tt, []string{kept}, listDIDs(""))
+				assert.Equal(tt, []string{deleted}, listDIDs("?deleted=true"))
+				assert.ElementsMatch(tt, []string{kept, deleted}, listDIDs("?includeDeleted=true"))
+
+				w := httptest.NewRecorder()
+				req := httptest.NewRequest(http.MethodGet, "https://ssi-service.com/v1/dids/key?deleted=true&includeDeleted=true", nil)
+				didService.ListDIDsByMethod(newRequestContextWithParams(w, req, map[string]string{"method": "key"}))
+				assert.Equal(tt, http.StatusBadRequest, w.Code)
+
+				// only deleted DIDs can be restored
+				w = call(didService.RestoreDIDByMethod, http.MethodPut, kept)
+				assert.Equal(tt, http.StatusInternalServerError, w.Code)
+				assert.Contains(tt, w.Body.String(), "is not deleted")
+
+				assert.True(tt, util.Is2xxResponse(call(didService.RestoreDIDByMethod, http.MethodPut, deleted).Code))
+				assert.ElementsMatch(tt, []string{kept, deleted}, listDIDs(""))
+				assert.Empty(tt, listDIDs("?deleted=true"))
+
+				// only deleted DIDs can be purged
+				w = call(didService.PurgeDIDByMethod, http.MethodDelete, deleted)
+				assert.Equal(tt, http.StatusInternalServerError, w.Code)
+				assert.Contains(tt, w.Body.String(), "must be soft deleted before it is purged")
+
+				assert.True(tt, util.Is2xxResponse(call(didService.SoftDeleteDIDByMethod, http.MethodDelete, deleted).Code))
+				w = call(didService.PurgeDIDByMethod, http.MethodDelete, deleted)
+				require.True(tt, util.Is2xxResponse(w.Code), w.Body.String())
+				var purgeResponse router.PurgeDIDByMethodResponse
+				require.NoError(tt, json.NewDecoder(w.Body).Decode(&purgeResponse))
+				require.NotEmpty(tt, purgeResponse.DeletedKeyIDs)
+				for _, keyID := range purgeResponse.DeletedKeyIDs {
+					assert.True(tt, strings.HasPrefix(keyID, deleted))
+					_, err := keyStore.GetKeyDetails(context.Background(), keystore.GetKeyDetailsRequest{ID: keyID})
+					assert.Error(tt, err)
+				}
+
+				assert.Equal(tt, []string{kept}, listDIDs("?includeDeleted=true"))
+				assert.Equal(tt, http.StatusBadRequest, call(didService.GetDIDByMethod, http.MethodGet, deleted).Code)
+
+				// the keys of other DIDs are kept
+				keys, err := keyStore.ListKeysByController(context.Background(), keystore.ListKeysByControllerRequest{Controller: kept})
+				require.NoError(tt, err)
+				assert.NotEmpty(tt, keys.KeyIDs)
+			})
+
 			t.Run("List DIDs made up token fails", func(tt *testing.T) {
 				db := test.ServiceStorage(tt)
 				require.NotEmpty(tt, db)
@@ -810,6 +1670,70 @@ func TestDIDAPI(t *testing.T) {
 				assert.Len(tt, knownDIDs, 0)
 			})
 
+			t.Run("Test Hosted DID Web Documents", func(tt *testing.T) {
+				db := test.ServiceStorage(tt)
+				require.NotEmpty(tt, db)
+
+				_, keyStore, _ := testKeyStore(tt, db)
+				didService, _ := testDIDService(tt, db, keyStore, nil, "web")
+				engine := gin.New()
+				require.NoError(tt, DIDWebAPI(engine, didService, []string{"example.com"}, 5*time.Minute))
+
+				defer gock.Off()
+				for _, id := range []string{"did:web:example.com", "did:web:example.com:alice", "did:web:example.com:user:bob", "did:web:other.com"} {
+					gock.New("https://" + strings.Split(id, ":")[2]).Get("/").Reply(404)
+					_, err := didService.CreateDIDByMethod(context.Background(), did.CreateDIDRequest{
+						Method:  didsdk.WebMethod,
+						KeyType: crypto.Ed25519,
+						Options: did.CreateWebDIDOptions{DIDWebID: id},
+					})
+					require.NoError(tt, err)
+				}
+
+				get := func(url string, headers map[string]string) *httptest.ResponseRecorder {
+					req := httptest.NewRequest(http.MethodGet, url, nil)
+					for k, v := range headers {
+						req.Header.Set(k, v)
+					}
+					w := httptest.NewRecorder()
+					engine.ServeHTTP(w, req)
+					return w
+				}
+
+				for url, id := range map[string]string{
+					"https://example.com/.well-known/did.json": "did:web:example.com",
+					"https://example.com/alice/did.json":       "did:web:example.com:alice",
+				} {
+					w := get(url, nil)
+					require.Equal(tt, http.StatusOK, w.Code, w.Body.String())
+					assert.Equal(tt, "application/did+ld+json", w.Header().Get("Content-Type"))
+					assert.Equal(tt, "public, max-age=300", w.Header().Get("Cache-Control"))
+					assert.NotEmpty(tt, w.Header().Get("ETag"))
+
+					var document didsdk.Document
+					assert.NoError(tt, json.NewDecoder(w.Body).Decode(&document))
+					assert.Equal(tt, id, document.ID)
+
+					// the document hasn't changed
+					notModified := get(url, map[string]string{"If-None-Match": w.Header().Get("ETag")})
+					assert.Equal(tt, http.StatusNotModified, notModified.Code)
+					assert.Empty(tt, notModified.Body.String())
+				}
+
+				// DIDs that aren't stored, paths that aren't document paths, and DIDs with nested paths aren't served
+				assert.Equal(tt, http.StatusNotFound, get("https://example.com/carol/did.json", nil).Code)
+				assert.Equal(tt, http.StatusNotFound, get("https://example.com/alice", nil).Code)
+				assert.Equal(tt, http.StatusNotFound, get("https://example.com/user/bob/did.json", nil).Code)
+
+				// documents are only served for the configured domains, even when they're stored
+				assert.Equal(tt, http.StatusNotFound, get("https://other.com/.well-known/did.json", nil).Code)
+
+				// deleted DIDs aren't served
+				require.NoError(tt, didService.SoftDeleteDIDByMethod(context.Background(), did.DeleteDIDRequest{Method: didsdk.WebMethod, ID: "did:web:example.com"}))
+				assert.Equal(tt, http.StatusNotFound, get("https://example.com/.well-known/did.json", nil).Code)
+				assert.Equal(tt, http.StatusOK, get("https://example.com/alice/did.json", nil).Code)
+			})
+
 			t.Run("Test Resolve DIDs", func(tt *testing.T) {
 				db := test.ServiceStorage(tt)
 				require.NotEmpty(tt, db)
@@ -854,6 +1778,144 @@ func TestDIDAPI(t *testing.T) {
 				assert.NotEmpty(tt, resolutionResponse.DIDDocument)
 				assert.Equal(tt, "did:key:z6MkiTBz1ymuepAQ4HEHYSF1H8quG5GLVVQR3djdX3mDooWp", resolutionResponse.DIDDocument.ID)
 			})
+
+			t.Run("Test Resolve DID With Resolution Options", func(tt *testing.T) {
+				db := test.ServiceStorage(tt)
+				require.NotEmpty(tt, db)
+
+				_, keyStore, keyStoreFactory := testKeyStore(tt, db)
+				didService, _ := testDIDService(tt, db, keyStore, keyStoreFactory, "key", "web")
+				didRouter, err := router.NewDIDRouter(didService)
+				require.NoError(tt, err)
+
+				gock.New("https://example.com").Get("/").Reply(404)
+				defer gock.Off()
+
+				created, err := didService.CreateDIDByMethod(context.Background(), did.CreateDIDRequest{
+					Method:  didsdk.WebMethod,
+					KeyType: crypto.Ed25519,
+					Options: did.CreateWebDIDOptions{DIDWebID: "did:web:example.com"},
+				})
+				require.NoError(tt, err)
+				id := created.DID.ID
+				beforeUpdate := time.Now().Add(-time.Minute).Format(time.RFC3339)
+
+				_, err = didService.UpdateDIDByMethod(context.Background(), did.UpdateDIDRequest{
+					Method: didsdk.WebMethod,
+					ID:     id,
+					StateChange: did.DocumentStateChange{
+						ServicesToAdd: []didsdk.Service{{ID: "#linked-domain", Type: "LinkedDomains", ServiceEndpoint: "https://example.com"}},
+					},
+				})
+				require.NoError(tt, err)
+
+				resolve := func(id string, query string) *httptest.ResponseRecorder {
+					w := httptest.NewRecorder()
+					req := httptest.NewRequest(http.MethodGet, "https://ssi-service.com/v1/dids/resolver/"+id+"?"+query, nil)
+					c := newRequestContextWithParams(w, req, map[string]string{"id": id})
+					didRouter.ResolveDID(c)
+					return w
+				}
+				decode := func(w *httptest.ResponseRecorder) router.ResolveDIDResponse {
+					require.True(tt, util.Is2xxResponse(w.Code), w.Body.String())
+					var resp router.ResolveDIDResponse
+					require.NoError(tt, json.NewDecoder(w.Body).Decode(&resp))
+					return resp
+				}
+
+				// the latest version is resolved by default
+				latest := decode(resolve(id, ""))
+				assert.Len(tt, latest.DIDDocument.Services, 1)
+				assert.Equal(tt, "2", latest.DIDDocumentMetadata.VersionID)
+				assert.Empty(tt, latest.DIDDocumentMetadata.NextVersionID)
+				assert.NotEmpty(tt, latest.DIDDocumentMetadata.Updated)
+				assert.Equal(tt, "application/did+ld+json", latest.ResolutionMetadata.ContentType)
+
+				first := decode(resolve(id, "versionId=1"))
+				assert.Empty(tt, first.DIDDocument.Services)
+				assert.Equal(tt, "1", first.DIDDocumentMetadata.VersionID)
+				assert.Equal(tt, "2", first.DIDDocumentMetadata.NextVersionID)
+
+				first = decode(resolve(id, url.Values{"versionTime": {beforeUpdate}}.Encode()))
+				assert.Equal(tt, "1", first.DIDDocumentMetadata.VersionID)
+				latest = decode(resolve(id, url.Values{"versionTime": {time.Now().Add(time.Minute).Format(time.RFC3339)}}.Encode()))
+				assert.Equal(tt, "2", latest.DIDDocumentMetadata.VersionID)
+
+				plainJSON := decode(resolve(id, url.Values{"accept": {"application/did+json"}, "noCache": {"true"}}.Encode()))
+				assert.Equal(tt, "application/did+json", plainJSON.ResolutionMetadata.ContentType)
+				assert.Empty(tt, plainJSON.DIDDocument.Context)
+
+				// resolution errors map to their statuses
+				assert.Equal(tt, http.StatusNotFound, resolve(id, "versionId=3").Code)
+				assert.Equal(tt, http.StatusBadRequest, resolve(id, "versionTime=yesterday").Code)
+				assert.Equal(tt, http.StatusBadRequest, resolve(id, "noCache=maybe").Code)
+				assert.Equal(tt, http.StatusNotAcceptable, resolve(id, "accept=text/plain").Code)
+				assert.Equal(tt, http.StatusBadRequest, resolve("bad", "").Code)
+				assert.Equal(tt, http.StatusNotImplemented, resolve("did:example:123", "").Code)
+				assert.Equal(tt, http.StatusNotFound, resolve("did:key:abcd", "").Code)
+			})
+
+			t.Run("Test Dereference DID URL", func(tt *testing.T) {
+				db := test.ServiceStorage(tt)
+				require.NotEmpty(tt, db)
+
+				_, keyStore, keyStoreFactory := testKeyStore(tt, db)
+				didService, _ := testDIDService(tt, db, keyStore, keyStoreFactory, "key", "web")
+				didRouter, err := router.NewDIDRouter(didService)
+				require.NoError(tt, err)
+
+				gock.New("https://example.com").Get("/").Reply(404)
+				defer gock.Off()
+
+				created, err := didService.CreateDIDByMethod(context.Background(), did.CreateDIDRequest{
+					Method:  didsdk.WebMethod,
+					KeyType: crypto.Ed25519,
+					Options: did.CreateWebDIDOptions{DIDWebID: "did:web:example.com"},
+				})
+				require.NoError(tt, err)
+				id := created.DID.ID
+				_, err = didService.UpdateDIDByMethod(context.Background(), did.UpdateDIDRequest{
+					Method: didsdk.WebMethod,
+					ID:     id,
+					StateChange: did.DocumentStateChange{
+						ServicesToAdd: []didsdk.Service{{ID: "#files", Type: "Files", ServiceEndpoint: "https://files.example.com"}},
+					},
+				})
+				require.NoError(tt, err)
+
+				dereference := func(didURL string) *httptest.ResponseRecorder {
+					w := httptest.NewRecorder()
+					req := httptest.NewRequest(http.MethodGet, "https://ssi-service.com/v1/dids/dereferencer/"+url.PathEscape(didURL), nil)
+					// the id is a wildcard param, which gin gives with a leading slash
+					c := newRequestContextWithParams(w, req, map[string]string{"id": "/" + didURL})
+					didRouter.DereferenceDIDURL(c)
+					return w
+				}
+				decode := func(w *httptest.ResponseRecorder) router.DereferenceDIDURLResponse {
+					require.True(tt, util.Is2xxResponse(w.Code), w.Body.String())
+					var resp router.DereferenceDIDURLResponse
+					require.NoError(tt, json.NewDecoder(w.Body).Decode(&resp))
+					return resp
+				}
+
+				// a verification method of the document
+				vmID := created.DID.VerificationMethod[0].ID
+				dereferenced := decode(dereference(vmID))
+				assert.Equal(tt, "application/did+ld+json", dereferenced.DereferencingMetadata.ContentType)
+				vm, ok := dereferenced.ContentStream.(map[string]any)
+				require.True(tt, ok)
+				assert.Equal(tt, vmID, vm["id"])
+				assert.Equal(tt, "2", dereferenced.ContentMetadata.VersionID)
+
+				// the endpoint of a service, of the version of the document that has it
+				dereferenced = decode(dereference(id + "?service=files&relativeRef=%2Fa"))
+				assert.Equal(tt, "text/uri-list", dereferenced.DereferencingMetadata.ContentType)
+				assert.Equal(tt, "https://files.example.com/a", dereferenced.ContentStream)
+				assert.Equal(tt, http.StatusNotFound, dereference(id+"?versionId=1&service=files").Code)
+
+				assert.Equal(tt, http.StatusNotFound, dereference(id+"#unknown").Code)
+				assert.Equal(tt, http.StatusBadRequest, dereference("bad#key-1").Code)
+			})
 		})
 	}
 }
@@ -14,6 +14,7 @@ import (
 
 	"github.com/tbd54566975/ssi-service/internal/util"
 	"github.com/tbd54566975/ssi-service/pkg/server/router"
+	"github.com/tbd54566975/ssi-service/pkg/service/keystore"
 	"github.com/tbd54566975/ssi-service/pkg/testutil"
 )
 
@@ -65,6 +66,69 @@ func TestKeyStoreAPI(t *testing.T) {
 				assert.True(tt, util.Is2xxResponse(w.Code))
 			})
 
+			t.Run("Test Generate Key", func(tt *testing.T) {
+				db := test.ServiceStorage(tt)
+				require.NotEmpty(tt, db)
+
+				keyStoreRouter, _, _ := testKeyStore(tt, db)
+
+				// unsupported key type
+				badRequest := router.GenerateKeyRequest{
+					Type:       crypto.X25519,
+					Controller: "did:test:me",
+				}
+				req := httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/keys/generate", newRequestValue(tt, badRequest))
+				w := httptest.NewRecorder()
+				c := newRequestContext(w, req)
+				keyStoreRouter.GenerateKey(c)
+				assert.Equal(tt, http.StatusBadRequest, w.Code)
+				assert.Contains(tt, w.Body.String(), "unsupported key type for generation: X25519")
+
+				// backend that isn't configured
+				badRequest = router.GenerateKeyRequest{
+					Type:       crypto.Ed25519,
+					Controller: "did:test:me",
+					Backend:    keystore.RemoteBackend,
+				}
+				req = httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/keys/generate", newRequestValue(tt, badRequest))
+				w = httptest.NewRecorder()
+				c = newRequestContext(w, req)
+				keyStoreRouter.GenerateKey(c)
+				assert.Equal(tt, http.StatusBadRequest, w.Code)
+				assert.Contains(tt, w.Body.String(), "key backend<remote> is not configured")
+
+				// good request
+				generateKeyRequest := router.GenerateKeyRequest{
+					ID:         "did:test:me#key-generated",
+					Type:       crypto.P256,
+					Controller: "did:test:me",
+				}
+				req = httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/keys/generate", newRequestValue(tt, generateKeyRequest))
+				w = httptest.NewRecorder()
+				c = newRequestContext(w, req)
+				keyStoreRouter.GenerateKey(c)
+				assert.Equal(tt, http.StatusCreated, w.Code)
+
+				var resp router.GenerateKeyResponse
+				err := json.NewDecoder(w.Body).Decode(&resp)
+				assert.NoError(tt, err)
+				assert.Equal(tt, "did:test:me#key-generated", resp.ID)
+				assert.NotContains(tt, w.Body.String(), "base58PrivateKey")
+
+				// the details of the generated key are retrievable
+				w = httptest.NewRecorder()
+				getReq := httptest.NewRequest(http.MethodGet, fmt.Sprintf("https://ssi-service.com/v1/keys/%s", resp.ID), nil)
+				c = newRequestContextWithParams(w, getReq, map[string]string{"id": resp.ID})
+				keyStoreRouter.GetKeyDetails(c)
+				assert.True(tt, util.Is2xxResponse(w.Code))
+
+				var details router.GetKeyDetailsResponse
+				err = json.NewDecoder(w.Body).Decode(&details)
+				assert.NoError(tt, err)
+				assert.Equal(tt, crypto.P256, details.Type)
+				assert.Equal(tt, resp.PublicKeyJWK, details.PublicKeyJWK)
+			})
+
 			t.Run("Test Get Key Details", func(tt *testing.T) {
 				db := test.ServiceStorage(tt)
 				require.NotEmpty(tt, db)
@@ -115,6 +179,84 @@ func TestKeyStoreAPI(t *testing.T) {
 				assert.NoError(tt, err)
 				assert.NotEmpty(tt, wantPubKey, gotPubKey)
 			})
+
+			t.Run("Test Rotate Key", func(tt *testing.T) {
+				db := test.ServiceStorage(tt)
+				require.NotEmpty(tt, db)
+
+				keyStoreRouter, _, _ := testKeyStore(tt, db)
+
+				keyID := "did:test:me#key-rotated"
+				generateKeyRequest := router.GenerateKeyRequest{
+					ID:         keyID,
+					Type:       crypto.Ed25519,
+					Controller: "did:test:me",
+				}
+				req := httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/keys/generate", newRequestValue(tt, generateKeyRequest))
+				w := httptest.NewRecorder()
+				c := newRequestContext(w, req)
+				keyStoreRouter.GenerateKey(c)
+				assert.True(tt, util.Is2xxResponse(w.Code))
+
+				// rotate without a body keeps the key type
+				req = httptest.NewRequest(http.MethodPut, fmt.Sprintf("https://ssi-service.com/v1/keys/%s/rotate", keyID), nil)
+				w = httptest.NewRecorder()
+				c = newRequestContextWithParams(w, req, map[string]string{"id": keyID})
+				keyStoreRouter.RotateKey(c)
+				assert.True(tt, util.Is2xxResponse(w.Code))
+
+				var rotateResp router.RotateKeyResponse
+				err := json.NewDecoder(w.Body).Decode(&rotateResp)
+				assert.NoError(tt, err)
+				assert.Equal(tt, keyID, rotateResp.ID)
+				assert.Equal(tt, 2, rotateResp.Version)
+
+				// revoke the first version only
+				req = httptest.NewRequest(http.MethodDelete, fmt.Sprintf("https://ssi-service.com/v1/keys/%s?version=1", keyID), nil)
+				w = httptest.NewRecorder()
+				c = newRequestContextWithParams(w, req, map[string]string{"id": keyID})
+				keyStoreRouter.RevokeKey(c)
+				assert.True(tt, util.Is2xxResponse(w.Code))
+
+				// both versions are listed in the details
+				req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("https://ssi-service.com/v1/keys/%s", keyID), nil)
+				w = httptest.NewRecorder()
+				c = newRequestContextWithParams(w, req, map[string]string{"id": keyID})
+				keyStoreRouter.GetKeyDetails(c)
+				assert.True(tt, util.Is2xxResponse(w.Code))
+
+				var details router.GetKeyDetailsResponse
+				err = json.NewDecoder(w.Body).Decode(&details)
+				assert.NoError(tt, err)
+				assert.Equal(tt, 2, details.Version)
+				assert.Equal(tt, rotateResp.PublicKeyJWK, details.PublicKeyJWK)
+				assert.Len(tt, details.Versions, 2)
+				assert.True(tt, details.Versions[0].Revoked)
+				assert.NotEmpty(tt, details.Versions[0].RevokedAt)
+				assert.False(tt, details.Versions[1].Revoked)
+
+				// bad version query value
+				req = httptest.NewRequest(http.MethodDelete, fmt.Sprintf("https://ssi-service.com/v1/keys/%s?version=latest", keyID), nil)
+				w = httptest.NewRecorder()
+				c = newRequestContextWithParams(w, req, map[string]string{"id": keyID})
+				keyStoreRouter.RevokeKey(c)
+				assert.Equal(tt, http.StatusBadRequest, w.Code)
+
+				// rotating to a type that can't be generated
+				rotateKeyRequest := router.RotateKeyRequest{Type: crypto.X25519}
+				req = httptest.NewRequest(http.MethodPut, fmt.Sprintf("https://ssi-service.com/v1/keys/%s/rotate", keyID), newRequestValue(tt, rotateKeyRequest))
+				w = httptest.NewRecorder()
+				c = newRequestContextWithParams(w, req, map[string]string{"id": keyID})
+				keyStoreRouter.RotateKey(c)
+				assert.Equal(tt, http.StatusBadRequest, w.Code)
+
+				// rotating a key that doesn't exist
+				req = httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/keys/did:test:me%23missing/rotate", nil)
+				w = httptest.NewRecorder()
+				c = newRequestContextWithParams(w, req, map[string]string{"id": "did:test:me#missing"})
+				keyStoreRouter.RotateKey(c)
+				assert.Equal(tt, http.StatusNotFound, w.Code)
+			})
 		})
 	}
 }
@@ -0,0 +1,500 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TBD54566975/ssi-sdk/credential/exchange"
+	"github.com/TBD54566975/ssi-sdk/credential/integrity"
+	manifestsdk "github.com/TBD54566975/ssi-sdk/credential/manifest"
+	"github.com/TBD54566975/ssi-sdk/crypto"
+	"github.com/TBD54566975/ssi-sdk/crypto/jwx"
+	"github.com/goccy/go-json"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tbd54566975/ssi-service/config"
+	"github.com/tbd54566975/ssi-service/internal/keyaccess"
+	"github.com/tbd54566975/ssi-service/internal/util"
+	"github.com/tbd54566975/ssi-service/pkg/server/router"
+	"github.com/tbd54566975/ssi-service/pkg/service/did"
+	svcframework "github.com/tbd54566975/ssi-service/pkg/service/framework"
+	"github.com/tbd54566975/ssi-service/pkg/service/issuance"
+	"github.com/tbd54566975/ssi-service/pkg/service/manifest/model"
+	"github.com/tbd54566975/ssi-service/pkg/service/oid4vci"
+	"github.com/tbd54566975/ssi-service/pkg/service/schema"
+	"github.com/tbd54566975/ssi-service/pkg/storage"
+	"github.com/tbd54566975/ssi-service/pkg/testutil"
+)
+
+func TestOID4VCIAPI(t *testing.T) {
+	for _, test := range testutil.TestDatabases {
+		t.Run(test.Name, func(t *testing.T) {
+			t.Run("Pre-authorized code flow with a transaction code", func(tt *testing.T) {
+				r, manifestID, templateID := setupOID4VCI(tt, test.ServiceStorage(tt))
+
+				offer := createCredentialOffer(tt, r, router.CreateCredentialOfferRequest{
+					IssuanceTemplateID: templateID,
+					CredentialOverrides: map[string]model.CredentialOverride{
+						"drivers-license": {Data: map[string]any{"licenseType": "Class M"}},
+					},
+					PreAuthorized: true,
+					RequireTxCode: true,
+				})
+				configurationID := manifestID + "/drivers-license"
+				assert.Len(tt, offer.TxCode, 6)
+				assert.Equal(tt, "https://ssi-service.com/v1/oidc/issuer/offers/"+offer.ID, offer.CredentialOfferURI)
+				assert.Equal(tt, "openid-credential-offer://?credential_offer_uri="+url.QueryEscape(offer.CredentialOfferURI), offer.CredentialOfferDeepLink)
+
+				// the wallet fetches the offer by reference, which doesn't include the transaction code
+				req := httptest.NewRequest(http.MethodGet, offer.CredentialOfferURI, nil)
+				w := httptest.NewRecorder()
+				r.GetCredentialOffer(newRequestContextWithParams(w, req, map[string]string{"id": offer.ID}))
+				require.Equal(tt, http.StatusOK, w.Code)
+				assert.NotContains(tt, w.Body.String(), offer.TxCode)
+				var credentialOffer oid4vci.CredentialOffer
+				require.NoError(tt, json.NewDecoder(w.Body).Decode(&credentialOffer))
+				assert.Equal(tt, "https://ssi-service.com/v1/oidc/issuer", credentialOffer.CredentialIssuer)
+				assert.Equal(tt, []string{configurationID}, credentialOffer.CredentialConfigurationIDs)
+				require.NotNil(tt, credentialOffer.Grants.PreAuthorizedCode)
+				assert.Nil(tt, credentialOffer.Grants.AuthorizationCode)
+				assert.Equal(tt, &oid4vci.TxCode{InputMode: "numeric", Length: 6}, credentialOffer.Grants.PreAuthorizedCode.TxCode)
+				preAuthorizedCode := credentialOffer.Grants.PreAuthorizedCode.PreAuthorizedCode
+
+				// and discovers the offered credential in the issuer metadata
+				req = httptest.NewRequest(http.MethodGet, "https://ssi-service.com/v1/oidc/issuer/.well-known/openid-credential-issuer", nil)
+				w = httptest.NewRecorder()
+				r.GetIssuerMetadata(newRequestContext(w, req))
+				require.Equal(tt, http.StatusOK, w.Code)
+				var metadata oid4vci.IssuerMetadata
+				require.NoError(tt, json.NewDecoder(w.Body).Decode(&metadata))
+				assert.Equal(tt, "https://ssi-service.com/v1/oidc/issuer/credential", metadata.CredentialEndpoint)
+				require.Contains(tt, metadata.CredentialConfigurationsSupported, configurationID)
+				assert.Equal(tt, oid4vci.JWTVCJSONFormat, metadata.CredentialConfigurationsSupported[configurationID].Format)
+				assert.Contains(tt, metadata.CredentialConfigurationsSupported[configurationID].CryptographicBindingMethodsSupported, "did:key")
+
+				form := url.Values{
+					"grant_type":          {oid4vci.PreAuthorizedCodeGrantType},
+					"pre-authorized_code": {preAuthorizedCode},
+				}
+				w = requestToken(tt, r, form)
+				assert.Equal(tt, oid4vci.InvalidRequest, oauthError(tt, w).Code)
+
+				form.Set("tx_code", "not the code")
+				w = requestToken(tt, r, form)
+				assert.Equal(tt, oid4vci.InvalidGrant, oauthError(tt, w).Code)
+
+				form.Set("tx_code", offer.TxCode)
+				token := tokenResponse(tt, requestToken(tt, r, form))
+				assert.Equal(tt, "Bearer", token.TokenType)
+				assert.NotEmpty(tt, token.CNonce)
+
+				// codes can only be exchanged once
+				w = requestToken(tt, r, form)
+				assert.Equal(tt, oid4vci.InvalidGrant, oauthError(tt, w).Code)
+
+				signer, holderDID := getSigner(tt)
+				credentialRequest := oid4vci.CredentialRequest{
+					Format:               oid4vci.JWTVCJSONFormat,
+					CredentialDefinition: &oid4vci.CredentialDefinition{Type: []string{"VerifiableCredential"}},
+				}
+				w = requestCredential(tt, r, token.AccessToken, credentialRequest)
+				proofErr := oauthError(tt, w)
+				assert.Equal(tt, oid4vci.InvalidProof, proofErr.Code)
+				assert.NotEmpty(tt, proofErr.CNonce)
+				assert.NotEqual(tt, token.CNonce, proofErr.CNonce)
+
+				// proofs must have the latest c_nonce
+				credentialRequest.Proof = &oid4vci.Proof{ProofType: oid4vci.JWTProofType, JWT: createProof(tt, signer, token.CNonce)}
+				w = requestCredential(tt, r, token.AccessToken, credentialRequest)
+				proofErr = oauthError(tt, w)
+				assert.Equal(tt, oid4vci.InvalidProof, proofErr.Code)
+				assert.Contains(tt, proofErr.Description, "c_nonce")
+
+				credentialRequest.Proof.JWT = createProof(tt, signer, proofErr.CNonce)
+				w = requestCredential(tt, r, token.AccessToken, credentialRequest)
+				require.Equal(tt, http.StatusOK, w.Code, w.Body.String())
+				var credentialResponse oid4vci.CredentialResponse
+				require.NoError(tt, json.NewDecoder(w.Body).Decode(&credentialResponse))
+				assert.NotEmpty(tt, credentialResponse.CNonce)
+
+				_, _, vc, err := integrity.ParseVerifiableCredentialFromJWT(credentialResponse.Credential.String())
+				require.NoError(tt, err)
+				assert.Equal(tt, holderDID.String(), vc.CredentialSubject.GetID())
+				assert.Equal(tt, "Class M", vc.CredentialSubject["licenseType"])
+
+				// every offered credential is issued once
+				credentialRequest.Proof.JWT = createProof(tt, signer, credentialResponse.CNonce)
+				w = requestCredential(tt, r, token.AccessToken, credentialRequest)
+				assert.Equal(tt, oid4vci.InvalidCredentialRequest, oauthError(tt, w).Code)
+			})
+
+			t.Run("Transaction code attempts are limited", func(tt *testing.T) {
+				r, _, templateID := setupOID4VCI(tt, test.ServiceStorage(tt))
+				offer := createCredentialOffer(tt, r, router.CreateCredentialOfferRequest{
+					IssuanceTemplateID: templateID,
+					PreAuthorized:      true,
+					RequireTxCode:      true,
+					TxCodeLength:       8,
+				})
+				assert.Len(tt, offer.TxCode, 8)
+
+				form := url.Values{
+					"grant_type":          {oid4vci.PreAuthorizedCodeGrantType},
+					"pre-authorized_code": {offer.CredentialOffer.Grants.PreAuthorizedCode.PreAuthorizedCode},
+					"tx_code":             {"wrong"},
+				}
+				for i := 0; i < 5; i++ {
+					assert.Equal(tt, oid4vci.InvalidGrant, oauthError(tt, requestToken(tt, r, form)).Code)
+				}
+				form.Set("tx_code", offer.TxCode)
+				assert.Equal(tt, oid4vci.InvalidGrant, oauthError(tt, requestToken(tt, r, form)).Code)
+			})
+
+			t.Run("Concurrent requests redeem codes and issue credentials once", func(tt *testing.T) {
+				r, _, templateID := setupOID4VCI(tt, test.ServiceStorage(tt))
+				offer := createCredentialOffer(tt, r, router.CreateCredentialOfferRequest{IssuanceTemplateID: templateID, PreAuthorized: true})
+				form := url.Values{
+					"grant_type":          {oid4vci.PreAuthorizedCodeGrantType},
+					"pre-authorized_code": {offer.CredentialOffer.Grants.PreAuthorizedCode.PreAuthorizedCode},
+				}
+
+				var wg sync.WaitGroup
+				responses := make([]*httptest.ResponseRecorder, 5)
+				for i := range responses {
+					wg.Add(1)
+					go func(i int) {
+						defer wg.Done()
+						responses[i] = requestToken(tt, r, form)
+					}(i)
+				}
+				wg.Wait()
+				var token oid4vci.TokenResponse
+				redeemed := 0
+				for _, w := range responses {
+					if w.Code == http.StatusOK {
+						redeemed++
+						token = tokenResponse(tt, w)
+					}
+				}
+				require.Equal(tt, 1, redeemed)
+
+				// a proof can only be used once
+				signer, _ := getSigner(tt)
+				credentialRequest := oid4vci.CredentialRequest{
+					Format: oid4vci.JWTVCJSONFormat,
+					Proof:  &oid4vci.Proof{ProofType: oid4vci.JWTProofType, JWT: createProof(tt, signer, token.CNonce)},
+				}
+				for i := range responses {
+					wg.Add(1)
+					go func(i int) {
+						defer wg.Done()
+						responses[i] = requestCredential(tt, r, token.AccessToken, credentialRequest)
+					}(i)
+				}
+				wg.Wait()
+				issued := 0
+				var credentialResponse oid4vci.CredentialResponse
+				for _, w := range responses {
+					if w.Code == http.StatusOK {
+						issued++
+						require.NoError(tt, json.NewDecoder(w.Body).Decode(&credentialResponse))
+					}
+				}
+				require.Equal(tt, 1, issued)
+
+				credentialRequest.Proof.JWT = createProof(tt, signer, credentialResponse.CNonce)
+				w := requestCredential(tt, r, token.AccessToken, credentialRequest)
+				assert.Equal(tt, oid4vci.InvalidCredentialRequest, oauthError(tt, w).Code)
+			})
+
+			t.Run("Authorization code flow", func(tt *testing.T) {
+				r, manifestID, templateID := setupOID4VCI(tt, test.ServiceStorage(tt))
+				offer := createCredentialOffer(tt, r, router.CreateCredentialOfferRequest{IssuanceTemplateID: templateID})
+				assert.Empty(tt, offer.TxCode)
+				assert.Nil(tt, offer.CredentialOffer.Grants.PreAuthorizedCode)
+				require.NotNil(tt, offer.CredentialOffer.Grants.AuthorizationCode)
+				issuerState := offer.CredentialOffer.Grants.AuthorizationCode.IssuerState
+
+				// PKCE is required
+				query := url.Values{
+					"response_type": {"code"},
+					"client_id":     {"wallet"},
+					"redirect_uri":  {"https://wallet.example.com/callback"},
+					"state":         {"wallet-state"},
+					"issuer_state":  {issuerState},
+				}
+				redirect := authorize(tt, r, query)
+				assert.Equal(tt, string(oid4vci.InvalidRequest), redirect.Query().Get("error"))
+				assert.Equal(tt, "wallet-state", redirect.Query().Get("state"))
+
+				verifier := "a-code-verifier-that-is-long-enough-to-be-valid"
+				challenge := sha256.Sum256([]byte(verifier))
+				query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+				query.Set("code_challenge_method", oid4vci.CodeChallengeMethodS256)
+				redirect = authorize(tt, r, query)
+				assert.Equal(tt, "wallet.example.com", redirect.Host)
+				assert.Equal(tt, "wallet-state", redirect.Query().Get("state"))
+				code := redirect.Query().Get("code")
+				require.NotEmpty(tt, code)
+
+				form := url.Values{
+					"grant_type":    {oid4vci.AuthorizationCodeGrantType},
+					"code":          {code},
+					"code_verifier": {"the wrong verifier"},
+					"redirect_uri":  {"https://wallet.example.com/callback"},
+				}
+				assert.Equal(tt, oid4vci.InvalidGrant, oauthError(tt, requestToken(tt, r, form)).Code)
+
+				// a failed exchange uses up the code
+				form.Set("code_verifier", verifier)
+				assert.Equal(tt, oid4vci.InvalidGrant, oauthError(tt, requestToken(tt, r, form)).Code)
+
+				form.Set("code", authorize(tt, r, query).Query().Get("code"))
+				token := tokenResponse(tt, requestToken(tt, r, form))
+				assert.Equal(tt, oid4vci.InvalidGrant, oauthError(tt, requestToken(tt, r, form)).Code)
+
+				signer, holderDID := getSigner(tt)
+				credentialRequest := oid4vci.CredentialRequest{
+					CredentialIdentifier: manifestID + "/drivers-license",
+					Proof:                &oid4vci.Proof{ProofType: oid4vci.JWTProofType, JWT: createProof(tt, signer, token.CNonce)},
+				}
+				w := requestCredential(tt, r, token.AccessToken, credentialRequest)
+				require.Equal(tt, http.StatusOK, w.Code, w.Body.String())
+				var credentialResponse oid4vci.CredentialResponse
+				require.NoError(tt, json.NewDecoder(w.Body).Decode(&credentialResponse))
+				_, _, vc, err := integrity.ParseVerifiableCredentialFromJWT(credentialResponse.Credential.String())
+				require.NoError(tt, err)
+				assert.Equal(tt, holderDID.String(), vc.CredentialSubject.GetID())
+				assert.Equal(tt, "Class D", vc.CredentialSubject["licenseType"])
+
+				// pre-authorized offers can't be redeemed through the authorization code flow
+				preAuthorized := createCredentialOffer(tt, r, router.CreateCredentialOfferRequest{IssuanceTemplateID: templateID, PreAuthorized: true})
+				query.Set("issuer_state", preAuthorized.ID)
+				assert.Equal(tt, string(oid4vci.AccessDenied), authorize(tt, r, query).Query().Get("error"))
+			})
+
+			t.Run("Invalid requests are rejected", func(tt *testing.T) {
+				s := test.ServiceStorage(tt)
+				r, _, templateID := setupOID4VCI(tt, s)
+
+				// transaction codes only protect pre-authorized codes
+				value := newRequestValue(tt, router.CreateCredentialOfferRequest{IssuanceTemplateID: templateID, RequireTxCode: true})
+				req := httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/oidc/issuer/offers", value)
+				w := httptest.NewRecorder()
+				r.CreateCredentialOffer(newRequestContext(w, req))
+				assert.Equal(tt, http.StatusBadRequest, w.Code)
+
+				// templates that read claims from credential applications can't be offered
+				template, err := testIssuanceService(tt, s).GetIssuanceTemplate(context.Background(), &issuance.GetIssuanceTemplateRequest{ID: templateID})
+				require.NoError(tt, err)
+				jsonPathTemplate := *template.IssuanceTemplate
+				jsonPathTemplate.Credentials[0].Data = issuance.ClaimTemplates{"licenseType": "$.vc.credentialSubject.licenseType"}
+				createdTemplate, err := testIssuanceService(tt, s).CreateIssuanceTemplate(context.Background(), &issuance.CreateIssuanceTemplateRequest{IssuanceTemplate: jsonPathTemplate})
+				require.NoError(tt, err)
+				value = newRequestValue(tt, router.CreateCredentialOfferRequest{IssuanceTemplateID: createdTemplate.ID, PreAuthorized: true})
+				req = httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/oidc/issuer/offers", value)
+				w = httptest.NewRecorder()
+				r.CreateCredentialOffer(newRequestContext(w, req))
+				assert.Equal(tt, http.StatusBadRequest, w.Code)
+				assert.Contains(tt, w.Body.String(), "JSON path")
+
+				// credentials are issued by the manifest's issuer
+				keyStoreService, keyStoreFactory := testKeyStoreService(tt, s)
+				didService, _ := testDIDService(tt, s, keyStoreService, keyStoreFactory)
+				otherIssuer, err := didService.CreateDIDByMethod(context.Background(), did.CreateDIDRequest{Method: "key", KeyType: crypto.Ed25519})
+				require.NoError(tt, err)
+				template, err = testIssuanceService(tt, s).GetIssuanceTemplate(context.Background(), &issuance.GetIssuanceTemplateRequest{ID: templateID})
+				require.NoError(tt, err)
+				otherIssuerTemplate := *template.IssuanceTemplate
+				otherIssuerTemplate.Issuer = otherIssuer.DID.ID
+				otherIssuerTemplate.VerificationMethodID = otherIssuer.DID.VerificationMethod[0].ID
+				createdTemplate, err = testIssuanceService(tt, s).CreateIssuanceTemplate(context.Background(), &issuance.CreateIssuanceTemplateRequest{IssuanceTemplate: otherIssuerTemplate})
+				require.NoError(tt, err)
+				value = newRequestValue(tt, router.CreateCredentialOfferRequest{IssuanceTemplateID: createdTemplate.ID})
+				req = httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/oidc/issuer/offers", value)
+				w = httptest.NewRecorder()
+				r.CreateCredentialOffer(newRequestContext(w, req))
+				assert.Equal(tt, http.StatusBadRequest, w.Code)
+				assert.Contains(tt, w.Body.String(), "is not the issuer of manifest")
+
+				w = requestToken(tt, r, url.Values{"grant_type": {"password"}})
+				assert.Equal(tt, oid4vci.UnsupportedGrantType, oauthError(tt, w).Code)
+
+				w = requestCredential(tt, r, "not-a-token", oid4vci.CredentialRequest{Format: oid4vci.JWTVCJSONFormat})
+				assert.Equal(tt, http.StatusUnauthorized, w.Code)
+				assert.Equal(tt, `Bearer error="invalid_token"`, w.Header().Get("WWW-Authenticate"))
+
+				// deleted offers can't be redeemed
+				offer := createCredentialOffer(tt, r, router.CreateCredentialOfferRequest{IssuanceTemplateID: templateID, PreAuthorized: true})
+				req = httptest.NewRequest(http.MethodDelete, offer.CredentialOfferURI, nil)
+				w = httptest.NewRecorder()
+				r.DeleteCredentialOffer(newRequestContextWithParams(w, req, map[string]string{"id": offer.ID}))
+				assert.True(tt, util.Is2xxResponse(w.Code))
+
+				req = httptest.NewRequest(http.MethodGet, offer.CredentialOfferURI, nil)
+				w = httptest.NewRecorder()
+				r.GetCredentialOffer(newRequestContextWithParams(w, req, map[string]string{"id": offer.ID}))
+				assert.Equal(tt, http.StatusNotFound, w.Code)
+
+				w = requestToken(tt, r, url.Values{
+					"grant_type":          {oid4vci.PreAuthorizedCodeGrantType},
+					"pre-authorized_code": {offer.CredentialOffer.Grants.PreAuthorizedCode.PreAuthorizedCode},
+				})
+				assert.Equal(tt, oid4vci.InvalidGrant, oauthError(tt, w).Code)
+			})
+		})
+	}
+}
+
+// setupOID4VCI creates an OID4VCI router, along with a manifest and an issuance template to offer credentials from.
+func setupOID4VCI(t *testing.T, s storage.ServiceStorage) (*router.OID4VCIRouter, string, string) {
+	keyStoreService, keyStoreFactory := testKeyStoreService(t, s)
+	didService, _ := testDIDService(t, s, keyStoreService, keyStoreFactory)
+	schemaService := testSchemaService(t, s, keyStoreService, didService)
+	credentialService := testCredentialService(t, s, keyStoreService, didService, schemaService)
+	_, manifestService := testManifest(t, s, keyStoreService, didService, credentialService)
+
+	issuer, err := didService.CreateDIDByMethod(context.Background(), did.CreateDIDRequest{Method: "key", KeyType: crypto.Ed25519})
+	require.NoError(t, err)
+	verificationMethodID := issuer.DID.VerificationMethod[0].ID
+	licenseSchema, err := schemaService.CreateSchema(context.Background(), schema.CreateSchemaRequest{
+		Issuer:                             issuer.DID.ID,
+		FullyQualifiedVerificationMethodID: verificationMethodID,
+		Name:                               "license schema",
+		Schema: map[string]any{
+			"$schema": "https://json-schema.org/draft-07/schema",
+			"type":    "object",
+			"properties": map[string]any{
+				"credentialSubject": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"id":          map[string]any{"type": "string"},
+						"licenseType": map[string]any{"type": "string"},
+					},
+					"required": []any{"licenseType", "id"},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	manifest, err := manifestService.CreateManifest(context.Background(), model.CreateManifestRequest{
+		IssuerDID:                          issuer.DID.ID,
+		FullyQualifiedVerificationMethodID: verificationMethodID,
+		ClaimFormat: &exchange.ClaimFormat{
+			JWTVC: &exchange.JWTType{Alg: []crypto.SignatureAlgorithm{crypto.EdDSA}},
+		},
+		OutputDescriptors: []manifestsdk.OutputDescriptor{
+			{ID: "drivers-license", Schema: licenseSchema.ID, Name: "drivers license"},
+		},
+	})
+	require.NoError(t, err)
+
+	template, err := testIssuanceService(t, s).CreateIssuanceTemplate(context.Background(), &issuance.CreateIssuanceTemplateRequest{
+		IssuanceTemplate: issuance.Template{
+			CredentialManifest:   manifest.Manifest.ID,
+			Issuer:               issuer.DID.ID,
+			VerificationMethodID: verificationMethodID,
+			Credentials: []issuance.CredentialTemplate{
+				{
+					ID:     "drivers-license",
+					Schema: licenseSchema.ID,
+					Data:   issuance.ClaimTemplates{"licenseType": "Class D"},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	service, err := oid4vci.NewOID4VCIService(s, didService.GetResolver(), credentialService)
+	require.NoError(t, err)
+	r, err := router.NewOID4VCIRouter(service)
+	require.NoError(t, err)
+
+	// set the issuer identifier
+	config.SetServicePath(svcframework.OID4VCI, OIDCIssuerPrefix)
+	return r, manifest.Manifest.ID, template.ID
+}
+
+func createCredentialOffer(t *testing.T, r *router.OID4VCIRouter, request router.CreateCredentialOfferRequest) oid4vci.Offer {
+	value := newRequestValue(t, request)
+	req := httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/oidc/issuer/offers", value)
+	w := httptest.NewRecorder()
+	r.CreateCredentialOffer(newRequestContext(w, req))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var resp router.CreateCredentialOfferResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	return *resp.Offer
+}
+
+// authorize sends an authorization request, and returns the URI the wallet is redirected to.
+func authorize(t *testing.T, r *router.OID4VCIRouter, query url.Values) *url.URL {
+	req := httptest.NewRequest(http.MethodGet, "https://ssi-service.com/v1/oidc/issuer/authorize?"+query.Encode(), nil)
+	w := httptest.NewRecorder()
+	r.Authorize(newRequestContext(w, req))
+	require.Equal(t, http.StatusFound, w.Code, w.Body.String())
+
+	redirect, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(t, err)
+	return redirect
+}
+
+func requestToken(t *testing.T, r *router.OID4VCIRouter, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "https://ssi-service.com/v1/oidc/issuer/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	r.Token(newRequestContext(w, req))
+	return w
+}
+
+func tokenResponse(t *testing.T, w *httptest.ResponseRecorder) oid4vci.TokenResponse {
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var token oid4vci.TokenResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&token))
+	return token
+}
+
+func requestCredential(t *testing.T, r *router.OID4VCIRouter, accessToken string, request oid4vci.CredentialRequest) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "https://ssi-service.com/v1/oidc/issuer/credential", newRequestValue(t, request))
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	w := httptest.NewRecorder()
+	r.IssueCredential(newRequestContext(w, req))
+	return w
+}
+
+func oauthError(t *testing.T, w *httptest.ResponseRecorder) oid4vci.Error {
+	require.True(t, w.Code == http.StatusBadRequest || w.Code == http.StatusUnauthorized, w.Body.String())
+	var oauthErr oid4vci.Error
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&oauthErr))
+	return oauthErr
+}
+
+// createProof creates a proof of possession of the signer's key, as a wallet would.
+func createProof(t *testing.T, signer jwx.Signer, nonce string) keyaccess.JWT {
+	token, err := jwt.NewBuilder().
+		Audience([]string{"https://ssi-service.com/v1/oidc/issuer"}).
+		IssuedAt(time.Now()).
+		Claim("nonce", nonce).
+		Build()
+	require.NoError(t, err)
+
+	headers := jws.NewHeaders()
+	require.NoError(t, headers.Set(jws.KeyIDKey, signer.KID))
+	require.NoError(t, headers.Set(jws.TypeKey, oid4vci.ProofJWTType))
+	signed, err := jwt.Sign(token, jwt.WithKey(jwa.SignatureAlgorithm(signer.ALG), signer.PrivateKey, jws.WithProtectedHeaders(headers)))
+	require.NoError(t, err)
+	return keyaccess.JWT(signed)
+}
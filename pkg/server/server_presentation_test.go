@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -21,6 +22,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/google/uuid"
+	"github.com/lestrrat-go/jwx/v2/jwt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -28,11 +30,14 @@ import (
 
 	"github.com/tbd54566975/ssi-service/internal/keyaccess"
 	"github.com/tbd54566975/ssi-service/internal/util"
+	"github.com/tbd54566975/ssi-service/internal/verification"
 	"github.com/tbd54566975/ssi-service/pkg/server/router"
+	credsvc "github.com/tbd54566975/ssi-service/pkg/service/credential"
 	"github.com/tbd54566975/ssi-service/pkg/service/did"
 	opstorage "github.com/tbd54566975/ssi-service/pkg/service/operation/storage"
 	"github.com/tbd54566975/ssi-service/pkg/service/presentation"
 	"github.com/tbd54566975/ssi-service/pkg/service/presentation/model"
+	"github.com/tbd54566975/ssi-service/pkg/service/presentation/policy"
 	"github.com/tbd54566975/ssi-service/pkg/storage"
 )
 
@@ -185,6 +190,171 @@ func TestPresentationAPI(t *testing.T) {
 					assert.NoError(tttt, json.NewDecoder(w.Body).Decode(&resp))
 					assert.True(tttt, resp.Verified)
 				})
+
+				ttt.Run("Verification report lists each check", func(tttt *testing.T) {
+					testPresentation.VerifiableCredential = []any{createResp.CredentialJWT}
+					signedPresentation, err := integrity.SignVerifiablePresentationJWT(holderSigner, &integrity.JWTVVPParameters{Audience: []string{holderSigner.ID}}, testPresentation)
+					assert.NoError(tttt, err)
+					definition := createPresentationDefinition(tttt, presRouter)
+
+					verify := func(request router.VerifyPresentationRequest) *httptest.ResponseRecorder {
+						value := newRequestValue(tttt, request)
+						req := httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/presentations/verification", value)
+						w := httptest.NewRecorder()
+						c := newRequestContext(w, req)
+						presRouter.VerifyPresentation(c)
+						return w
+					}
+
+					w := verify(router.VerifyPresentationRequest{
+						PresentationJWT:          keyaccess.JWTPtr(string(signedPresentation)),
+						TrustedIssuers:           []string{"did:example:trusted"},
+						PresentationDefinitionID: definition.PresentationDefinition.ID,
+					})
+					assert.True(tttt, util.Is2xxResponse(w.Code))
+
+					var resp router.VerifyPresentationResponse
+					assert.NoError(tttt, json.NewDecoder(w.Body).Decode(&resp))
+					assert.False(tttt, resp.Verified)
+					assert.False(tttt, resp.Report.Verified)
+
+					checks := func(report verification.Report) map[verification.Check]verification.CheckResult {
+						results := make(map[verification.Check]verification.CheckResult)
+						for _, result := range report.Checks {
+							results[result.Check] = result
+						}
+						return results
+					}
+					presentationChecks := checks(resp.Report)
+					assert.Equal(tttt, verification.OutcomePassed, presentationChecks[verification.CheckDIDResolution].Outcome)
+					assert.Equal(tttt, verification.OutcomePassed, presentationChecks[verification.CheckSignature].Outcome)
+					assert.Equal(tttt, verification.CodeDefinitionNotSatisfied, presentationChecks[verification.CheckPresentationDefinition].Code)
+
+					require.Len(tttt, resp.Report.Credentials, 1)
+					credentialReport := resp.Report.Credentials[0]
+					assert.Equal(tttt, createResp.Credential.ID, credentialReport.CredentialID)
+					credentialChecks := checks(credentialReport)
+					assert.Equal(tttt, verification.OutcomePassed, credentialChecks[verification.CheckSignature].Outcome)
+					assert.Equal(tttt, verification.OutcomePassed, credentialChecks[verification.CheckExpiry].Outcome)
+					assert.Equal(tttt, verification.OutcomeSkipped, credentialChecks[verification.CheckStatus].Outcome)
+					assert.Equal(tttt, verification.CodeIssuerUntrusted, credentialChecks[verification.CheckIssuerTrust].Code)
+					assert.Contains(tttt, resp.Reason, "verifying credential 0: credential<"+createResp.Credential.ID+"> was issued by untrusted issuer")
+
+					// the presentation definition must exist
+					w = verify(router.VerifyPresentationRequest{
+						PresentationJWT:          keyaccess.JWTPtr(string(signedPresentation)),
+						PresentationDefinitionID: "unknown",
+					})
+					assert.Equal(tttt, http.StatusBadRequest, w.Code)
+				})
+
+				ttt.Run("SD-JWT presentation with key binding", func(tttt *testing.T) {
+					holderPrivKey, holderDIDKey, err := key.GenerateDIDKey(crypto.Ed25519)
+					require.NoError(tttt, err)
+					expandedHolderDID, err := holderDIDKey.Expand()
+					require.NoError(tttt, err)
+
+					sdJWTRequest := createCredRequest
+					sdJWTRequest.Subject = expandedHolderDID.ID
+					sdJWTRequest.ProofFormat = credsvc.SDJWTProofFormat
+					sdJWTRequest.Disclosable = []string{"firstName", "lastName"}
+					req := httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/credentials", newRequestValue(tttt, sdJWTRequest))
+					w := httptest.NewRecorder()
+					credRouter.CreateCredential(newRequestContext(w, req))
+					require.True(tttt, util.Is2xxResponse(w.Code), w.Body.String())
+					var sdJWTResp router.CreateCredentialResponse
+					require.NoError(tttt, json.NewDecoder(w.Body).Decode(&sdJWTResp))
+					require.NotNil(tttt, sdJWTResp.SDJWT)
+
+					holderKeyAccess, err := keyaccess.NewJWKKeyAccess(expandedHolderDID.ID, expandedHolderDID.VerificationMethod[0].ID, holderPrivKey)
+					require.NoError(tttt, err)
+					selected, err := sdJWTResp.SDJWT.Select("firstName")
+					require.NoError(tttt, err)
+					presentation, err := holderKeyAccess.SignSDJWTKeyBinding(*selected, "https://verifier.example.com", "test-nonce")
+					require.NoError(tttt, err)
+
+					verify := func(request router.VerifyPresentationRequest) (int, router.VerifyPresentationResponse) {
+						req := httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/presentations/verification", newRequestValue(tttt, request))
+						w := httptest.NewRecorder()
+						presRouter.VerifyPresentation(newRequestContext(w, req))
+						var resp router.VerifyPresentationResponse
+						if util.Is2xxResponse(w.Code) {
+							assert.NoError(tttt, json.NewDecoder(w.Body).Decode(&resp))
+						}
+						return w.Code, resp
+					}
+
+					code, resp := verify(router.VerifyPresentationRequest{
+						PresentationSDJWT: presentation,
+						Audience:          "https://verifier.example.com",
+						Nonce:             "test-nonce",
+					})
+					assert.True(tttt, util.Is2xxResponse(code))
+					assert.True(tttt, resp.Verified, resp.Reason)
+					require.Len(tttt, resp.Report.Credentials, 1)
+					assert.Equal(tttt, sdJWTResp.Credential.ID, resp.Report.Credentials[0].CredentialID)
+
+					// the disclosed claims must satisfy the presentation definition
+					definitionFor := func(path string) string {
+						definition := createPresentationDefinition(tttt, presRouter, WithInputDescriptors([]exchange.InputDescriptor{{
+							ID: "name",
+							Constraints: &exchange.Constraints{
+								Fields: []exchange.Field{{Path: []string{path}}},
+							},
+						}}))
+						return definition.PresentationDefinition.ID
+					}
+					code, resp = verify(router.VerifyPresentationRequest{
+						PresentationSDJWT:        presentation,
+						Audience:                 "https://verifier.example.com",
+						Nonce:                    "test-nonce",
+						PresentationDefinitionID: definitionFor("$.credentialSubject.firstName"),
+					})
+					assert.True(tttt, util.Is2xxResponse(code))
+					assert.True(tttt, resp.Verified, resp.Reason)
+					code, resp = verify(router.VerifyPresentationRequest{
+						PresentationSDJWT:        presentation,
+						Audience:                 "https://verifier.example.com",
+						Nonce:                    "test-nonce",
+						PresentationDefinitionID: definitionFor("$.credentialSubject.lastName"),
+					})
+					assert.True(tttt, util.Is2xxResponse(code))
+					assert.False(tttt, resp.Verified)
+					assert.Contains(tttt, resp.Reason, "presentation does not satisfy presentation definition")
+
+					// the key binding JWT must be for the verifier's nonce
+					code, resp = verify(router.VerifyPresentationRequest{
+						PresentationSDJWT: presentation,
+						Audience:          "https://verifier.example.com",
+						Nonce:             "other-nonce",
+					})
+					assert.True(tttt, util.Is2xxResponse(code))
+					assert.False(tttt, resp.Verified)
+					assert.Contains(tttt, resp.Reason, "nonce")
+
+					// an SD-JWT without key binding is not a presentation
+					code, resp = verify(router.VerifyPresentationRequest{
+						PresentationSDJWT: selected,
+						Audience:          "https://verifier.example.com",
+						Nonce:             "test-nonce",
+					})
+					assert.True(tttt, util.Is2xxResponse(code))
+					assert.False(tttt, resp.Verified)
+					assert.Contains(tttt, resp.Reason, "no key binding JWT")
+
+					// the audience and nonce are required
+					code, _ = verify(router.VerifyPresentationRequest{PresentationSDJWT: presentation})
+					assert.Equal(tttt, http.StatusBadRequest, code)
+
+					// unknown presentation definitions are the request's fault
+					code, _ = verify(router.VerifyPresentationRequest{
+						PresentationSDJWT:        presentation,
+						Audience:                 "https://verifier.example.com",
+						Nonce:                    "test-nonce",
+						PresentationDefinitionID: "unknown",
+					})
+					assert.Equal(tttt, http.StatusBadRequest, code)
+				})
 			})
 
 			tt.Run("Create, Get, and Delete Presentation Definition", func(ttt *testing.T) {
@@ -489,6 +659,77 @@ func TestPresentationAPI(t *testing.T) {
 					assert.Equal(tttt, definition.PresentationDefinition.ID, resp.GetSubmission().DefinitionID)
 				})
 
+				ttt.Run("Create submission is reviewed by the definition's policy", func(tttt *testing.T) {
+					s := test.ServiceStorage(tttt)
+					pRouter, didService := setupPresentationRouter(tttt, s)
+					authorDID := createDID(tttt, didService)
+					holderSigner, holderDID := getSigner(tttt)
+
+					submit := func(p policy.Policy) (router.Operation, model.Submission) {
+						definition := createPresentationDefinition(tttt, pRouter, WithPolicy(p))
+						assert.Equal(tttt, &p, definition.Policy)
+						op := createSubmission(tttt, pRouter, definition.PresentationDefinition.ID, authorDID.DID.ID, VerifiableCredential(), holderDID, holderSigner)
+
+						id := opstorage.StatusObjectID(op.ID)
+						req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("https://ssi-service.com/v1/presentations/submissions/%s", id), nil)
+						w := httptest.NewRecorder()
+						pRouter.GetSubmission(newRequestContextWithParams(w, req, map[string]string{"id": id}))
+						require.True(tttt, util.Is2xxResponse(w.Code))
+						var resp router.GetSubmissionResponse
+						require.NoError(tttt, json.NewDecoder(w.Body).Decode(&resp))
+						return op, *resp.Submission
+					}
+
+					op, approved := submit(policy.Policy{
+						MaxCredentialAge: "876000h",
+						Expressions:      []string{`credentials.exists(c, c.credentialSubject.givenName == "Uribe")`},
+					})
+					assert.True(tttt, op.Done)
+					assert.Equal(tttt, "approved", approved.Status)
+					assert.Equal(tttt, "approved by policy", approved.Reason)
+					assert.Equal(tttt, "approved", op.Result.Response.(map[string]any)["status"])
+
+					op, denied := submit(policy.Policy{
+						TrustedIssuers: []string{"did:example:trusted"},
+						Expressions:    []string{`holder.startsWith("did:key:")`},
+					})
+					assert.True(tttt, op.Done)
+					assert.Equal(tttt, "denied", denied.Status)
+					assert.Contains(tttt, denied.Reason, "untrusted issuer")
+					assert.Equal(tttt, "denied", op.Result.Response.(map[string]any)["status"])
+
+					// the credential has no age claim, so the policy can't decide
+					op, pending := submit(policy.Policy{
+						Expressions: []string{`credentials.all(c, c.credentialSubject.age >= 18)`},
+					})
+					assert.False(tttt, op.Done)
+					assert.Equal(tttt, "pending", pending.Status)
+					assert.Contains(tttt, pending.Reason, "left for manual review")
+					reviewed := reviewSubmission(tttt, pRouter, opstorage.StatusObjectID(op.ID))
+					assert.Equal(tttt, "approved", reviewed.Status)
+				})
+
+				ttt.Run("Create definition with invalid policy returns error", func(tttt *testing.T) {
+					s := test.ServiceStorage(tttt)
+					pRouter, _ := setupPresentationRouter(tttt, s)
+
+					for _, p := range []policy.Policy{
+						{MaxCredentialAge: "a month"},
+						{MaxCredentialAge: "-1h"},
+						{Expressions: []string{"credentials.exists(c,"}},
+						{Expressions: []string{"holder"}},
+					} {
+						request := router.CreatePresentationDefinitionRequest{
+							InputDescriptors: []exchange.InputDescriptor{{ID: "id", Constraints: &exchange.Constraints{Fields: []exchange.Field{{Path: []string{"$.credentialSubject.id"}}}}}},
+							Policy:           &p,
+						}
+						req := httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/presentations/definitions", newRequestValue(tttt, request))
+						w := httptest.NewRecorder()
+						pRouter.CreateDefinition(newRequestContext(w, req))
+						assert.Equal(tttt, http.StatusBadRequest, w.Code)
+					}
+				})
+
 				ttt.Run("Review submission twice fails", func(tttt *testing.T) {
 					s := test.ServiceStorage(tttt)
 					pRouter, didService := setupPresentationRouter(tttt, s)
@@ -857,10 +1098,341 @@ func TestPresentationAPI(t *testing.T) {
 					assert.Empty(tttt, resp.Submissions)
 				})
 			})
+
+			tt.Run("OID4VP endpoints", func(ttt *testing.T) {
+				ttt.Run("Request object is served from the request URI", func(tttt *testing.T) {
+					s := test.ServiceStorage(tttt)
+					pRouter, didService := setupPresentationRouter(tttt, s)
+					verifierDID := createDID(tttt, didService)
+					definition := createPresentationDefinition(tttt, pRouter)
+					authzRequest := createAuthorizationRequest(tttt, pRouter, definition.PresentationDefinition.ID, verifierDID.DID)
+					assert.Contains(tttt, authzRequest.RequestURI, "/oid4vp/requests/"+authzRequest.ID)
+					assert.Contains(tttt, authzRequest.ResponseURI, "/oid4vp/response")
+					assert.True(tttt, strings.HasPrefix(authzRequest.AuthorizationURI, "openid4vp://?"))
+					assert.Contains(tttt, authzRequest.AuthorizationURI, "request_uri="+url.QueryEscape(authzRequest.RequestURI))
+
+					req := httptest.NewRequest(http.MethodGet, "https://ssi-service.com/v1/presentations/oid4vp/requests/"+authzRequest.ID, nil)
+					w := httptest.NewRecorder()
+					pRouter.GetRequestObject(newRequestContextWithParams(w, req, map[string]string{"id": authzRequest.ID}))
+					require.True(tttt, util.Is2xxResponse(w.Code))
+					assert.Equal(tttt, "application/oauth-authz-req+jwt", w.Header().Get("Content-Type"))
+					assert.Equal(tttt, authzRequest.RequestJWT.String(), w.Body.String())
+
+					token, err := jwt.Parse(w.Body.Bytes(), jwt.WithVerify(false))
+					require.NoError(tttt, err)
+					claims := token.PrivateClaims()
+					assert.Equal(tttt, "vp_token", claims["response_type"])
+					assert.Equal(tttt, "direct_post", claims["response_mode"])
+					assert.Equal(tttt, verifierDID.DID.ID, claims["client_id"])
+					assert.Equal(tttt, authzRequest.ResponseURI, claims["response_uri"])
+					assert.Equal(tttt, authzRequest.Nonce, claims["nonce"])
+					assert.Equal(tttt, authzRequest.ID, claims["state"])
+					assert.Equal(tttt, definition.PresentationDefinition.ID, claims["presentation_definition"].(map[string]any)["id"])
+
+					req = httptest.NewRequest(http.MethodGet, "https://ssi-service.com/v1/presentations/oid4vp/requests/unknown", nil)
+					w = httptest.NewRecorder()
+					pRouter.GetRequestObject(newRequestContextWithParams(w, req, map[string]string{"id": "unknown"}))
+					assert.Equal(tttt, http.StatusNotFound, w.Code)
+				})
+
+				ttt.Run("direct_post response creates a submission", func(tttt *testing.T) {
+					s := test.ServiceStorage(tttt)
+					pRouter, didService := setupPresentationRouter(tttt, s)
+					verifierDID := createDID(tttt, didService)
+					holderSigner, holderDID := getSigner(tttt)
+					definition := createPresentationDefinition(tttt, pRouter)
+					authzRequest := createAuthorizationRequest(tttt, pRouter, definition.PresentationDefinition.ID, verifierDID.DID)
+
+					vpToken, submission := createVPToken(tttt, definition.PresentationDefinition.ID, verifierDID.DID.ID, authzRequest.Nonce, holderSigner, holderDID)
+					w := postAuthorizationResponse(tttt, pRouter, vpToken, submission, authzRequest.ID)
+					require.Equal(tttt, http.StatusOK, w.Code, w.Body.String())
+
+					var resp router.Operation
+					assert.NoError(tttt, json.NewDecoder(w.Body).Decode(&resp))
+					assert.Equal(tttt, "presentations/submissions/"+submission.ID, resp.ID)
+
+					req := httptest.NewRequest(http.MethodGet, "https://ssi-service.com/v1/presentations/submissions/"+submission.ID, nil)
+					w = httptest.NewRecorder()
+					pRouter.GetSubmission(newRequestContextWithParams(w, req, map[string]string{"id": submission.ID}))
+					require.True(tttt, util.Is2xxResponse(w.Code))
+					var submissionResp router.GetSubmissionResponse
+					require.NoError(tttt, json.NewDecoder(w.Body).Decode(&submissionResp))
+					assert.Equal(tttt, "pending", submissionResp.Status)
+					assert.Equal(tttt, definition.PresentationDefinition.ID, submissionResp.GetSubmission().DefinitionID)
+
+					// the request can only be responded to once
+					vpToken, submission = createVPToken(tttt, definition.PresentationDefinition.ID, verifierDID.DID.ID, authzRequest.Nonce, holderSigner, holderDID)
+					w = postAuthorizationResponse(tttt, pRouter, vpToken, submission, authzRequest.ID)
+					assert.Equal(tttt, http.StatusBadRequest, w.Code)
+					assert.Contains(tttt, w.Body.String(), "was already responded to")
+				})
+
+				ttt.Run("concurrent direct_post responses create one submission", func(tttt *testing.T) {
+					s := test.ServiceStorage(tttt)
+					pRouter, didService := setupPresentationRouter(tttt, s)
+					verifierDID := createDID(tttt, didService)
+					holderSigner, holderDID := getSigner(tttt)
+					definition := createPresentationDefinition(tttt, pRouter)
+					authzRequest := createAuthorizationRequest(tttt, pRouter, definition.PresentationDefinition.ID, verifierDID.DID)
+
+					var wg sync.WaitGroup
+					codes := make([]int, 5)
+					for i := range codes {
+						vpToken, submission := createVPToken(tttt, definition.PresentationDefinition.ID, verifierDID.DID.ID, authzRequest.Nonce, holderSigner, holderDID)
+						wg.Add(1)
+						go func(i int) {
+							defer wg.Done()
+							codes[i] = postAuthorizationResponse(tttt, pRouter, vpToken, submission, authzRequest.ID).Code
+						}(i)
+					}
+					wg.Wait()
+					created := 0
+					for _, code := range codes {
+						if code == http.StatusOK {
+							created++
+						}
+					}
+					assert.Equal(tttt, 1, created, codes)
+				})
+
+				ttt.Run("direct_post error response is recorded on the request", func(tttt *testing.T) {
+					s := test.ServiceStorage(tttt)
+					pRouter, didService := setupPresentationRouter(tttt, s)
+					verifierDID := createDID(tttt, didService)
+					holderSigner, holderDID := getSigner(tttt)
+					definition := createPresentationDefinition(tttt, pRouter)
+					authzRequest := createAuthorizationRequest(tttt, pRouter, definition.PresentationDefinition.ID, verifierDID.DID)
+
+					w := postAuthorizationForm(pRouter, url.Values{"error": {"access_denied"}, "state": {"unknown-state"}})
+					assert.Equal(tttt, http.StatusBadRequest, w.Code)
+					assert.Contains(tttt, w.Body.String(), "no authorization request found for state")
+
+					w = postAuthorizationForm(pRouter, url.Values{
+						"error":             {"access_denied"},
+						"error_description": {"the holder declined the request"},
+						"state":             {authzRequest.ID},
+					})
+					assert.True(tttt, util.Is2xxResponse(w.Code), w.Body.String())
+
+					// the request can't be responded to after the wallet declined it
+					vpToken, submission := createVPToken(tttt, definition.PresentationDefinition.ID, verifierDID.DID.ID, authzRequest.Nonce, holderSigner, holderDID)
+					w = postAuthorizationResponse(tttt, pRouter, vpToken, submission, authzRequest.ID)
+					assert.Equal(tttt, http.StatusBadRequest, w.Code)
+					assert.Contains(tttt, w.Body.String(), "was already responded to")
+
+					req := httptest.NewRequest(http.MethodGet, "https://ssi-service.com/v1/presentations/submissions/"+submission.ID, nil)
+					w = httptest.NewRecorder()
+					pRouter.GetSubmission(newRequestContextWithParams(w, req, map[string]string{"id": submission.ID}))
+					assert.False(tttt, util.Is2xxResponse(w.Code))
+				})
+
+				ttt.Run("direct_post response with an SD-JWT vp_token creates a submission", func(tttt *testing.T) {
+					s := test.ServiceStorage(tttt)
+					pRouter, didService := setupPresentationRouter(tttt, s)
+					verifierDID := createDID(tttt, didService)
+					definition := createPresentationDefinition(tttt, pRouter)
+					authzRequest := createAuthorizationRequest(tttt, pRouter, definition.PresentationDefinition.ID, verifierDID.DID)
+
+					// the key binding JWT must be for the request's nonce
+					vpToken, holderDID := createSDJWTVPToken(tttt, verifierDID.DID.ID, "other-nonce")
+					submission := exchange.PresentationSubmission{
+						ID:           uuid.NewString(),
+						DefinitionID: definition.PresentationDefinition.ID,
+						DescriptorMap: []exchange.SubmissionDescriptor{
+							{
+								ID:     "wa_driver_license",
+								Format: presentation.SDJWTFormat,
+								Path:   "$.verifiableCredential[0]",
+							},
+						},
+					}
+					w := postAuthorizationResponse(tttt, pRouter, keyaccess.JWT(vpToken), submission, authzRequest.ID)
+					assert.Equal(tttt, http.StatusBadRequest, w.Code)
+					assert.Contains(tttt, w.Body.String(), "nonce")
+
+					vpToken, holderDID = createSDJWTVPToken(tttt, verifierDID.DID.ID, authzRequest.Nonce)
+					w = postAuthorizationResponse(tttt, pRouter, keyaccess.JWT(vpToken), submission, authzRequest.ID)
+					require.Equal(tttt, http.StatusOK, w.Code, w.Body.String())
+
+					req := httptest.NewRequest(http.MethodGet, "https://ssi-service.com/v1/presentations/submissions/"+submission.ID, nil)
+					w = httptest.NewRecorder()
+					pRouter.GetSubmission(newRequestContextWithParams(w, req, map[string]string{"id": submission.ID}))
+					require.True(tttt, util.Is2xxResponse(w.Code))
+					var submissionResp router.GetSubmissionResponse
+					require.NoError(tttt, json.NewDecoder(w.Body).Decode(&submissionResp))
+					assert.Equal(tttt, "pending", submissionResp.Status)
+					assert.Equal(tttt, holderDID, submissionResp.VerifiablePresentation.Holder)
+					assert.Equal(tttt, []any{vpToken.String()}, submissionResp.VerifiablePresentation.VerifiableCredential)
+				})
+
+				ttt.Run("direct_post response must be bound to its request", func(tttt *testing.T) {
+					s := test.ServiceStorage(tttt)
+					pRouter, didService := setupPresentationRouter(tttt, s)
+					verifierDID := createDID(tttt, didService)
+					holderSigner, holderDID := getSigner(tttt)
+					definition := createPresentationDefinition(tttt, pRouter)
+					authzRequest := createAuthorizationRequest(tttt, pRouter, definition.PresentationDefinition.ID, verifierDID.DID)
+
+					vpToken, submission := createVPToken(tttt, definition.PresentationDefinition.ID, verifierDID.DID.ID, "other-nonce", holderSigner, holderDID)
+					w := postAuthorizationResponse(tttt, pRouter, vpToken, submission, authzRequest.ID)
+					assert.Equal(tttt, http.StatusBadRequest, w.Code)
+					assert.Contains(tttt, w.Body.String(), "vp_token nonce does not match the authorization request")
+
+					vpToken, submission = createVPToken(tttt, definition.PresentationDefinition.ID, "did:example:other", authzRequest.Nonce, holderSigner, holderDID)
+					w = postAuthorizationResponse(tttt, pRouter, vpToken, submission, authzRequest.ID)
+					assert.Equal(tttt, http.StatusBadRequest, w.Code)
+					assert.Contains(tttt, w.Body.String(), "vp_token audience does not include the client_id")
+
+					vpToken, submission = createVPToken(tttt, definition.PresentationDefinition.ID, verifierDID.DID.ID, authzRequest.Nonce, holderSigner, holderDID)
+					w = postAuthorizationResponse(tttt, pRouter, vpToken, submission, "unknown-state")
+					assert.Equal(tttt, http.StatusBadRequest, w.Code)
+					assert.Contains(tttt, w.Body.String(), "no authorization request found for state")
+
+					otherDefinition := createPresentationDefinition(tttt, pRouter)
+					vpToken, submission = createVPToken(tttt, otherDefinition.PresentationDefinition.ID, verifierDID.DID.ID, authzRequest.Nonce, holderSigner, holderDID)
+					w = postAuthorizationResponse(tttt, pRouter, vpToken, submission, authzRequest.ID)
+					assert.Equal(tttt, http.StatusBadRequest, w.Code)
+					assert.Contains(tttt, w.Body.String(), "presentation submission is for definition")
+				})
+
+				ttt.Run("direct_post response to an expired request fails", func(tttt *testing.T) {
+					s := test.ServiceStorage(tttt)
+					pRouter, didService := setupPresentationRouter(tttt, s)
+					verifierDID := createDID(tttt, didService)
+					holderSigner, holderDID := getSigner(tttt)
+					definition := createPresentationDefinition(tttt, pRouter)
+
+					request := router.CreateAuthorizationRequestRequest{
+						ClientID:                 verifierDID.DID.ID,
+						VerificationMethodID:     verifierDID.DID.VerificationMethod[0].ID,
+						PresentationDefinitionID: definition.PresentationDefinition.ID,
+						Expiration:               time.Now().Add(-time.Minute).Format(time.RFC3339),
+					}
+					req := httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/presentations/oid4vp/requests", newRequestValue(tttt, request))
+					w := httptest.NewRecorder()
+					pRouter.CreateAuthorizationRequest(newRequestContext(w, req))
+					require.True(tttt, util.Is2xxResponse(w.Code))
+					var resp router.CreateAuthorizationRequestResponse
+					require.NoError(tttt, json.NewDecoder(w.Body).Decode(&resp))
+
+					vpToken, submission := createVPToken(tttt, definition.PresentationDefinition.ID, verifierDID.DID.ID, resp.AuthorizationRequest.Nonce, holderSigner, holderDID)
+					w = postAuthorizationResponse(tttt, pRouter, vpToken, submission, resp.AuthorizationRequest.ID)
+					assert.Equal(tttt, http.StatusBadRequest, w.Code)
+					assert.Contains(tttt, w.Body.String(), "has expired")
+				})
+			})
 		})
 	}
 }
 
+func createAuthorizationRequest(t *testing.T, pRouter *router.PresentationRouter, definitionID string, verifierDID didsdk.Document) *model.AuthorizationRequest {
+	request := router.CreateAuthorizationRequestRequest{
+		ClientID:                 verifierDID.ID,
+		VerificationMethodID:     verifierDID.VerificationMethod[0].ID,
+		PresentationDefinitionID: definitionID,
+	}
+	value := newRequestValue(t, request)
+	req := httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/presentations/oid4vp/requests", value)
+	w := httptest.NewRecorder()
+	pRouter.CreateAuthorizationRequest(newRequestContext(w, req))
+	require.True(t, util.Is2xxResponse(w.Code))
+
+	var resp router.CreateAuthorizationRequestResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	return resp.AuthorizationRequest
+}
+
+// createVPToken creates a vp_token as a wallet responding to an OID4VP authorization request would, with the
+// presentation_submission sent alongside it.
+func createVPToken(t *testing.T, definitionID, clientID, nonce string, holderSigner jwx.Signer, holderDID key.DIDKey) (keyaccess.JWT, exchange.PresentationSubmission) {
+	issuerSigner, didKey := getSigner(t)
+	vc := VerifiableCredential()
+	vc.Issuer = didKey.String()
+	vcData, err := integrity.SignVerifiableCredentialJWT(issuerSigner, vc)
+	require.NoError(t, err)
+
+	ps := exchange.PresentationSubmission{
+		ID:           uuid.NewString(),
+		DefinitionID: definitionID,
+		DescriptorMap: []exchange.SubmissionDescriptor{
+			{
+				ID:     "wa_driver_license",
+				Format: string(exchange.JWTVPTarget),
+				Path:   "$.verifiableCredential[0]",
+			},
+		},
+	}
+	vp := credential.VerifiablePresentation{
+		Context:              []string{credential.VerifiableCredentialsLinkedDataContext},
+		Type:                 []string{credential.VerifiablePresentationType},
+		VerifiableCredential: []any{keyaccess.JWT(vcData)},
+	}
+	signed, err := holderSigner.SignWithDefaults(map[string]any{
+		"aud":                   []string{clientID},
+		integrity.NonceProperty: nonce,
+		integrity.VPJWTProperty: vp,
+	})
+	require.NoError(t, err)
+	require.Equal(t, holderDID.String(), holderSigner.ID)
+	return keyaccess.JWT(signed), ps
+}
+
+// createSDJWTVPToken creates an SD-JWT vp_token as a wallet would, disclosing only the subject's date of birth. It
+// returns the DID of the holder, who is the credential's subject.
+func createSDJWTVPToken(t *testing.T, clientID, nonce string) (keyaccess.SDJWT, string) {
+	issuerPrivKey, issuerDIDKey, err := key.GenerateDIDKey(crypto.Ed25519)
+	require.NoError(t, err)
+	issuerDID, err := issuerDIDKey.Expand()
+	require.NoError(t, err)
+	holderPrivKey, holderDIDKey, err := key.GenerateDIDKey(crypto.Ed25519)
+	require.NoError(t, err)
+	holderDID, err := holderDIDKey.Expand()
+	require.NoError(t, err)
+
+	vc := credential.VerifiableCredential{
+		Context:      []any{credential.VerifiableCredentialsLinkedDataContext},
+		ID:           uuid.NewString(),
+		Type:         []any{credential.VerifiableCredentialType},
+		Issuer:       issuerDID.ID,
+		IssuanceDate: time.Now().Format(time.RFC3339),
+		CredentialSubject: credential.CredentialSubject{
+			"id":          holderDID.ID,
+			"dateOfBirth": "1990-01-01",
+			"name":        "Satoshi",
+		},
+	}
+	issuerKeyAccess, err := keyaccess.NewJWKKeyAccess(issuerDID.ID, issuerDID.VerificationMethod[0].ID, issuerPrivKey)
+	require.NoError(t, err)
+	sdJWT, err := issuerKeyAccess.SignSDJWTVerifiableCredential(vc, []string{"dateOfBirth", "name"})
+	require.NoError(t, err)
+	selected, err := sdJWT.Select("dateOfBirth")
+	require.NoError(t, err)
+
+	holderKeyAccess, err := keyaccess.NewJWKKeyAccess(holderDID.ID, holderDID.VerificationMethod[0].ID, holderPrivKey)
+	require.NoError(t, err)
+	presentation, err := holderKeyAccess.SignSDJWTKeyBinding(*selected, clientID, nonce)
+	require.NoError(t, err)
+	return *presentation, holderDID.ID
+}
+
+func postAuthorizationResponse(t *testing.T, pRouter *router.PresentationRouter, vpToken keyaccess.JWT, submission exchange.PresentationSubmission, state string) *httptest.ResponseRecorder {
+	submissionBytes, err := json.Marshal(submission)
+	require.NoError(t, err)
+	return postAuthorizationForm(pRouter, url.Values{
+		"vp_token":                {vpToken.String()},
+		"presentation_submission": {string(submissionBytes)},
+		"state":                   {state},
+	})
+}
+
+func postAuthorizationForm(pRouter *router.PresentationRouter, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "https://ssi-service.com/v1/presentations/oid4vp/response", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	pRouter.SubmitAuthorizationResponse(newRequestContext(w, req))
+	return w
+}
+
 func createPresentationRequest(t *testing.T, pRouter *router.PresentationRouter, definitionID string, issuerDID didsdk.Document) router.CreateRequestResponse {
 	request := router.CreateRequestRequest{
 		CommonCreateRequestRequest: &router.CommonCreateRequestRequest{
@@ -992,6 +1564,12 @@ func WithInputDescriptors(inputDescriptors []exchange.InputDescriptor) Definitio
 	}
 }
 
+func WithPolicy(p policy.Policy) DefinitionOption {
+	return func(r *router.CreatePresentationDefinitionRequest) {
+		r.Policy = &p
+	}
+}
+
 func createPresentationDefinition(t *testing.T, pRouter *router.PresentationRouter, opts ...DefinitionOption) router.CreatePresentationDefinitionResponse {
 	request := router.CreatePresentationDefinitionRequest{
 		Name:    "name",
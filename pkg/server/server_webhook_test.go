@@ -107,6 +107,84 @@ func TestSimpleWebhook(t *testing.T) {
 	assert.NoError(t, server.Close())
 }
 
+func TestExpiredCredentialWebhook(t *testing.T) {
+	ch := make(chan []byte, 10)
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		ch <- received
+	}))
+	defer testServer.Close()
+
+	shutdown := make(chan os.Signal, 1)
+	serviceConfig, err := config.LoadConfig("", nil)
+	assert.NoError(t, err)
+
+	serviceConfig.Server.APIHost = "0.0.0.0:" + freePort()
+	serviceConfig.Services.CredentialConfig.ExpirySweepInterval = 100 * time.Millisecond
+	name := tempBoltFileName(t)
+	serviceConfig.Services.StorageOptions = append(serviceConfig.Services.StorageOptions, storage.Option{
+		ID:     "boltdb-filepath-option",
+		Option: name,
+	})
+
+	server, err := NewSSIServer(shutdown, *serviceConfig)
+	assert.NoError(t, err)
+
+	go func() {
+		require.ErrorIs(t, server.ListenAndServe(), http.ErrServerClosed)
+	}()
+
+	require.Eventually(t, isHealthy(t, server), 30*time.Second, 100*time.Millisecond)
+
+	webhookRequest := router.CreateWebhookRequest{
+		Noun: "Credential",
+		Verb: "Expire",
+		URL:  testServer.URL,
+	}
+	requestData, err := json.Marshal(webhookRequest)
+	assert.NoError(t, err)
+	put(t, server, "/v1/webhooks", requestData)
+
+	var didResp router.CreateDIDByMethodResponse
+	assert.NoError(t, json.Unmarshal(put(t, server, "/v1/dids/key", []byte(`{"keyType":"Ed25519"}`)), &didResp))
+
+	createCredRequest := router.CreateCredentialRequest{
+		Issuer:               didResp.DID.ID,
+		VerificationMethodID: didResp.DID.VerificationMethod[0].ID,
+		Subject:              "did:abc:456",
+		Data:                 map[string]any{"firstName": "Jack"},
+		Expiry:               time.Now().Add(-time.Minute).Format(time.RFC3339),
+	}
+	requestData, err = json.Marshal(createCredRequest)
+	assert.NoError(t, err)
+	var credResp router.CreateCredentialResponse
+	assert.NoError(t, json.Unmarshal(put(t, server, "/v1/credentials", requestData), &credResp))
+
+	select {
+	case received := <-ch:
+		var payload struct {
+			Noun string                       `json:"noun"`
+			Verb string                       `json:"verb"`
+			Data router.GetCredentialResponse `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(received, &payload))
+		assert.Equal(t, "Credential", payload.Noun)
+		assert.Equal(t, "Expire", payload.Verb)
+		assert.True(t, payload.Data.Expired)
+		assert.Equal(t, credResp.Credential.ID, payload.Data.Credential.ID)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "should receive an expire webhook")
+	}
+	select {
+	case <-ch:
+		assert.Fail(t, "should not receive more than 1 message")
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	assert.NoError(t, server.Close())
+}
+
 func tempBoltFileName(t *testing.T) string {
 	file, err := os.CreateTemp("", "bolt")
 	require.NoError(t, err)
@@ -126,7 +204,7 @@ func isHealthy(t *testing.T, server *SSIServer) func() bool {
 	}
 }
 
-func put(t *testing.T, server *SSIServer, endpoint string, data []byte) {
+func put(t *testing.T, server *SSIServer, endpoint string, data []byte) []byte {
 	request, err := http.NewRequest(http.MethodPut, "http://"+server.Addr+endpoint, bytes.NewReader(data))
 	assert.NoError(t, err)
 	request.Header.Set("Content-Type", "application/json")
@@ -137,6 +215,7 @@ func put(t *testing.T, server *SSIServer, endpoint string, data []byte) {
 	assert.NoError(t, err)
 
 	assert.NotEmpty(t, string(body))
+	return body
 }
 
 func TestWebhookAPI(t *testing.T) {
@@ -182,6 +261,28 @@ func TestWebhookAPI(t *testing.T) {
 				assert.Contains(tt, w.Body.String(), "invalid create webhook request")
 			})
 
+			t.Run("CreateWebhook returns error when expire is used for a noun other than credential", func(tt *testing.T) {
+				db := test.ServiceStorage(tt)
+				require.NotEmpty(tt, db)
+
+				webhookRouter := testWebhookRouter(tt, db)
+
+				badWebhookRequest := router.CreateWebhookRequest{
+					Noun: "DID",
+					Verb: "Expire",
+					URL:  "https://www.tbd.website/",
+				}
+
+				badRequestValue := newRequestValue(tt, badWebhookRequest)
+				req := httptest.NewRequest(http.MethodPut, "https://ssi-service.com/v1/webhooks", badRequestValue)
+				w := httptest.NewRecorder()
+
+				c := newRequestContext(w, req)
+				webhookRouter.CreateWebhook(c)
+				assert.Equal(tt, http.StatusBadRequest, w.Code)
+				assert.Contains(tt, w.Body.String(), "invalid create webhook request")
+			})
+
 			t.Run("CreateWebhook returns error when url is not supported", func(tt *testing.T) {
 				db := test.ServiceStorage(tt)
 				require.NotEmpty(tt, db)
@@ -0,0 +1,110 @@
+package credential
+
+import (
+	"context"
+	"time"
+
+	sdkutil "github.com/TBD54566975/ssi-sdk/util"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"go.einride.tech/aip/filtering"
+
+	credint "github.com/tbd54566975/ssi-service/internal/credential"
+	"github.com/tbd54566975/ssi-service/pkg/service/common"
+	"github.com/tbd54566975/ssi-service/pkg/storage"
+)
+
+// expirySweepPageSize is how many stored credentials are read at a time while looking for expired ones.
+const expirySweepPageSize = 100
+
+// RunExpirySweeper marks credentials as expired once they pass their `expirationDate`, until the context is done.
+// onExpired is called for each credential that was marked as expired. It returns right away when the sweep is
+// disabled.
+func (s Service) RunExpirySweeper(ctx context.Context, onExpired func(ctx context.Context, expired credint.Container)) {
+	if s.config.ExpirySweepInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(s.config.ExpirySweepInterval)
+	defer ticker.Stop()
+	for {
+		expired, err := s.ExpireCredentials(ctx)
+		if err != nil {
+			logrus.WithError(err).Error("could not expire credentials")
+		}
+		for _, container := range expired {
+			onExpired(ctx, container)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ExpireCredentials marks every stored credential that has passed its `expirationDate` as expired, and returns the
+// ones that were newly marked. A credential that cannot be marked doesn't stop the others from being marked.
+func (s Service) ExpireCredentials(ctx context.Context) ([]credint.Container, error) {
+	now := time.Now()
+	var expired []credint.Container
+	ae := sdkutil.NewAppendError()
+	page := &common.Page{Size: expirySweepPageSize}
+	for {
+		storedCreds, err := s.storage.ListCredentials(ctx, filtering.Filter{}, page)
+		if err != nil {
+			return expired, errors.Wrap(err, "listing credentials")
+		}
+		for _, storedCred := range storedCreds.StoredCredentials {
+			if !isExpired(storedCred, now) {
+				continue
+			}
+			watchKey := storage.WatchKey{Namespace: credentialNamespace, Key: storedCred.Key}
+			container, err := s.storage.db.Execute(ctx, s.expireCredentialFunc(storedCred.LocalCredentialID), []storage.WatchKey{watchKey})
+			if err != nil {
+				ae.Append(errors.Wrapf(err, "expiring credential<%s>", storedCred.LocalCredentialID))
+				continue
+			}
+			if container != nil {
+				expired = append(expired, *container.(*credint.Container))
+			}
+		}
+		if storedCreds.NextPageToken == "" {
+			break
+		}
+		page.Token = storedCreds.NextPageToken
+	}
+	return expired, ae.Error()
+}
+
+func (s Service) expireCredentialFunc(id string) storage.BusinessLogicFunc {
+	return func(ctx context.Context, tx storage.Tx) (any, error) {
+		// read it again, since it could have changed since it was found to be expired
+		gotCred, err := s.storage.GetCredential(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if gotCred.Expired {
+			return nil, nil
+		}
+		container := credint.Container{
+			ID:                                 gotCred.LocalCredentialID,
+			FullyQualifiedVerificationMethodID: gotCred.FullyQualifiedVerificationMethodID,
+			Credential:                         gotCred.Credential,
+			CredentialJWT:                      gotCred.CredentialJWT,
+			SDJWT:                              gotCred.SDJWT,
+			Revoked:                            gotCred.Revoked,
+			Suspended:                          gotCred.Suspended,
+			StatusValue:                        gotCred.StatusValue,
+			Expired:                            true,
+		}
+		if err = s.storage.StoreCredentialTx(ctx, tx, StoreCredentialRequest{Container: container}); err != nil {
+			return nil, sdkutil.LoggingErrorMsg(err, "could not store credential")
+		}
+		return &container, nil
+	}
+}
+
+// isExpired returns whether a stored credential has passed its `expirationDate` without being marked as expired.
+func isExpired(storedCred StoredCredential, now time.Time) bool {
+	return !storedCred.Expired && storedCred.passedExpirationDate(now)
+}
@@ -5,9 +5,57 @@ import (
 
 	"github.com/TBD54566975/ssi-sdk/util"
 	"github.com/tbd54566975/ssi-service/internal/credential"
+	"github.com/tbd54566975/ssi-service/internal/keyaccess"
 	"github.com/tbd54566975/ssi-service/pkg/service/common"
 )
 
+// ProofFormat determines how an issued credential is secured.
+type ProofFormat string
+
+const (
+	// JWTProofFormat secures the credential as a VC-JWT, which is set as the container's `credentialJwt`. This is the
+	// default.
+	JWTProofFormat ProofFormat = "jwt"
+
+	// SDJWTProofFormat secures the credential as an SD-JWT, which is set as the container's `sdJwt`. The claims listed
+	// in the request's Disclosable are selectively disclosable.
+	SDJWTProofFormat ProofFormat = "sd-jwt"
+
+	// The following formats secure the credential with an embedded Data Integrity proof; the signed credential is set
+	// as the container's `credential`.
+
+	JSONWebSignature2020ProofFormat = ProofFormat(keyaccess.JSONWebSignature2020)
+	Ed25519Signature2020ProofFormat = ProofFormat(keyaccess.Ed25519Signature2020)
+	// EdDSA2022ProofFormat is a `DataIntegrityProof` using the `eddsa-2022` cryptosuite.
+	EdDSA2022ProofFormat = ProofFormat(keyaccess.EdDSA2022)
+)
+
+// IsDataIntegrity returns whether the format secures credentials with an embedded proof.
+func (f ProofFormat) IsDataIntegrity() bool {
+	return keyaccess.DataIntegrityProofType(f).IsSupported()
+}
+
+// IsSupported returns whether the format is known. An empty format is supported, and means JWTProofFormat.
+func (f ProofFormat) IsSupported() bool {
+	return f == "" || f == JWTProofFormat || f == SDJWTProofFormat || f.IsDataIntegrity()
+}
+
+// StatusListFormat determines the kind of status list that tracks the status of a credential.
+type StatusListFormat string
+
+const (
+	// StatusList2021Format tracks the status with a `StatusList2021Entry`. This is the default.
+	StatusList2021Format StatusListFormat = "StatusList2021"
+	// BitstringStatusListFormat tracks the status with a `BitstringStatusListEntry`, which supports multi-bit status
+	// values through the `message` purpose.
+	BitstringStatusListFormat StatusListFormat = "BitstringStatusList"
+)
+
+// IsSupported returns whether the format is known. An empty format is supported, and means the configured default.
+func (f StatusListFormat) IsSupported() bool {
+	return f == "" || f == StatusList2021Format || f == BitstringStatusListFormat
+}
+
 type BatchCreateCredentialsRequest struct {
 	Requests []CreateCredentialRequest
 }
@@ -31,7 +79,15 @@ type CreateCredentialRequest struct {
 	Revocable   bool           `json:"revocable,omitempty"`
 	Suspendable bool           `json:"suspendable,omitempty"`
 	Evidence    []any          `json:"evidence,omitempty"`
-	// TODO(gabe) support more capabilities like signature type, format, evidence, and more.
+	// How the credential is secured. Defaults to JWTProofFormat.
+	ProofFormat ProofFormat `json:"proofFormat,omitempty"`
+	// Names of the Data claims that are selectively disclosable. Only valid with SDJWTProofFormat.
+	Disclosable []string `json:"disclosable,omitempty"`
+	// The kind of status list for the credential's status. Defaults to the service's configured format.
+	StatusListFormat StatusListFormat `json:"statusListFormat,omitempty"`
+	// When present, the credential gets a multi-bit status with the `message` purpose, which has one of these messages
+	// at a time. Only valid with BitstringStatusListFormat.
+	StatusMessage []credential.StatusMessageEntry `json:"statusMessage,omitempty"`
 }
 
 // CreateCredentialResponse holds a resulting credential from credential creation, which is an XOR type:
@@ -76,12 +132,18 @@ type GetCredentialStatusRequest struct {
 type GetCredentialStatusResponse struct {
 	Revoked   bool `json:"revoked" validate:"required"`
 	Suspended bool `json:"suspended" validate:"required"`
+	// Set for credentials with a `message` status.
+	StatusValue   int    `json:"statusValue,omitempty"`
+	StatusMessage string `json:"statusMessage,omitempty"`
 }
 
 type UpdateCredentialStatusRequest struct {
 	ID        string `json:"id" validate:"required"`
 	Revoked   bool   `json:"revoked" validate:"required"`
 	Suspended bool   `json:"suspended" validate:"required"`
+	// The new value of a multi-bit status. Only valid for credentials with a `message` status; when nil, the value
+	// is left as is.
+	StatusValue *int `json:"statusValue,omitempty"`
 }
 
 type UpdateCredentialStatusResponse struct {
@@ -93,6 +155,8 @@ type Status struct {
 	ID        string `json:"id,omitempty"`
 	Revoked   bool   `json:"revoked" validate:"required"`
 	Suspended bool   `json:"suspended" validate:"required"`
+	// Set for credentials with a `message` status.
+	StatusValue int `json:"statusValue,omitempty"`
 }
 
 type BatchUpdateCredentialStatusRequest struct {
@@ -111,15 +175,38 @@ type GetCredentialStatusListResponse struct {
 	credential.Container `json:"credential,omitempty"`
 }
 
+// GetStatusListUsageRequest filters the status lists whose usage is reported. Empty fields match every list.
+type GetStatusListUsageRequest struct {
+	Issuer   string `json:"issuer,omitempty"`
+	SchemaID string `json:"schemaId,omitempty"`
+}
+
+// StatusListUsage is how many of the indexes of a status list credential are in use.
+type StatusListUsage struct {
+	// The URI of the status list credential.
+	ID            string           `json:"id"`
+	Issuer        string           `json:"issuer"`
+	SchemaID      string           `json:"schemaId,omitempty"`
+	StatusPurpose string           `json:"statusPurpose"`
+	Format        StatusListFormat `json:"format"`
+	Used          int              `json:"used"`
+	Capacity      int              `json:"capacity"`
+	// Whether new credentials get an index in this status list. Once a list is full, a new one becomes current.
+	Current bool `json:"current"`
+}
+
+type GetStatusListUsageResponse struct {
+	StatusLists []StatusListUsage `json:"statusLists"`
+}
+
+// isStatusValid returns whether the requested statuses can be combined. A credential can be both revocable and
+// suspendable, with an entry in a status list for each, but a status message is the credential's only status.
 func (csr CreateCredentialRequest) isStatusValid() bool {
-	if csr.Revocable && csr.Suspendable {
-		return false
-	}
-	return true
+	return len(csr.StatusMessage) == 0 || !(csr.Revocable || csr.Suspendable)
 }
 
 func (csr CreateCredentialRequest) hasStatus() bool {
-	return csr.Suspendable || csr.Revocable
+	return csr.Suspendable || csr.Revocable || len(csr.StatusMessage) > 0
 }
 
 func (csr CreateCredentialRequest) hasEvidence() bool {
@@ -148,5 +235,24 @@ func (csr CreateCredentialRequest) IsValid() error {
 	if err := util.IsValidStruct(csr); err != nil {
 		return err
 	}
+	if !csr.ProofFormat.IsSupported() {
+		return fmt.Errorf("unsupported proof format: %s", csr.ProofFormat)
+	}
+	if len(csr.Disclosable) > 0 && csr.ProofFormat != SDJWTProofFormat {
+		return fmt.Errorf("disclosable claims require the %s proof format", SDJWTProofFormat)
+	}
+	if !csr.StatusListFormat.IsSupported() {
+		return fmt.Errorf("unsupported status list format: %s", csr.StatusListFormat)
+	}
+	if len(csr.StatusMessage) > 0 {
+		if _, err := credential.StatusSizeForMessages(csr.StatusMessage); err != nil {
+			return err
+		}
+	}
+	for _, claim := range csr.Disclosable {
+		if _, ok := csr.Data[claim]; !ok {
+			return fmt.Errorf("disclosable claim<%s> is not in the credential data", claim)
+		}
+	}
 	return common.ValidateVerificationMethodID(csr.FullyQualifiedVerificationMethodID, csr.Issuer)
 }
@@ -3,6 +3,7 @@ package credential
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/TBD54566975/ssi-sdk/credential"
@@ -11,6 +12,7 @@ import (
 	"github.com/TBD54566975/ssi-sdk/did"
 	"github.com/TBD54566975/ssi-sdk/did/resolution"
 	sdkutil "github.com/TBD54566975/ssi-sdk/util"
+	"github.com/goccy/go-json"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -54,6 +56,16 @@ func (s Service) Status() framework.Status {
 	if s.schema == nil {
 		ae.AppendString("no schema service configured")
 	}
+	if s.config.StatusListSize < 0 || s.config.StatusListSize > bitStringLength {
+		ae.AppendString(fmt.Sprintf("status list size must be between 1 and %d", bitStringLength))
+	}
+	if s.config.StatusListValidity < 0 || s.config.StatusListRefreshInterval < 0 {
+		ae.AppendString("status list validity and refresh interval cannot be negative")
+	} else if s.config.StatusListValidity > 0 && s.statusListRefreshInterval() > s.config.StatusListValidity/2 {
+		ae.AppendString("status list refresh interval must be at most half of the status list validity")
+	} else if s.config.StatusListValidity > 0 && s.statusListRefreshInterval() == 0 {
+		ae.AppendString("status list refresh interval must be positive")
+	}
 	if !ae.IsEmpty() {
 		return framework.Status{
 			Status:  framework.StatusNotReady,
@@ -73,7 +85,7 @@ func NewCredentialService(config config.CredentialServiceConfig, s storage.Servi
 	if err != nil {
 		return nil, sdkutil.LoggingErrorMsg(err, "could not instantiate storage for the credential service")
 	}
-	verifier, err := verification.NewVerifiableDataVerifier(didResolver, schema)
+	verifier, err := verification.NewVerifiableDataVerifier(didResolver, schema, credentialStorage)
 	if err != nil {
 		return nil, sdkutil.LoggingErrorMsg(err, "could not instantiate verifier for the credential service")
 	}
@@ -97,26 +109,16 @@ func (s Service) CreateCredential(ctx context.Context, request CreateCredentialR
 
 	watchKeys := make([]storage.WatchKey, 0)
 
-	var statusMetadata StatusListCredentialMetadata
+	var statusLists []requestedStatusList
 	if request.hasStatus() && request.isStatusValid() {
-		statusPurpose := statussdk.StatusRevocation
-
-		if request.Suspendable {
-			statusPurpose = statussdk.StatusSuspension
+		var err error
+		if statusLists, err = s.requestedStatusLists(request); err != nil {
+			return nil, errors.Wrap(err, "validating request")
 		}
-
-		statusListCredentialWatchKey := s.storage.GetStatusListCredentialWatchKey(request.Issuer, request.SchemaID, string(statusPurpose))
-		statusListCredentialIndexPoolWatchKey := s.storage.GetStatusListIndexPoolWatchKey(request.Issuer, request.SchemaID, string(statusPurpose))
-		statusListCredentialCurrentIndexWatchKey := s.storage.GetStatusListCurrentIndexWatchKey(request.Issuer, request.SchemaID, string(statusPurpose))
-
-		watchKeys = append(watchKeys, statusListCredentialWatchKey)
-		watchKeys = append(watchKeys, statusListCredentialIndexPoolWatchKey)
-		watchKeys = append(watchKeys, statusListCredentialCurrentIndexWatchKey)
-
-		statusMetadata = StatusListCredentialMetadata{statusListCredentialWatchKey: statusListCredentialWatchKey, statusListIndexPoolWatchKey: statusListCredentialIndexPoolWatchKey, statusListCurrentIndexWatchKey: statusListCredentialCurrentIndexWatchKey}
+		watchKeys = append(watchKeys, watchKeysForStatusLists(statusLists)...)
 	}
 
-	returnFunc := s.createCredentialFunc(request, statusMetadata)
+	returnFunc := s.createCredentialFunc(request, statusLists)
 	returnValue, err := s.storage.db.Execute(ctx, returnFunc, watchKeys)
 	if err != nil {
 		return nil, errors.Wrap(err, "execute")
@@ -130,17 +132,17 @@ func (s Service) CreateCredential(ctx context.Context, request CreateCredentialR
 	return credResponse, nil
 }
 
-func (s Service) createCredentialFunc(request CreateCredentialRequest, slcMetadata StatusListCredentialMetadata) storage.BusinessLogicFunc {
+func (s Service) createCredentialFunc(request CreateCredentialRequest, statusLists []requestedStatusList) storage.BusinessLogicFunc {
 	return func(ctx context.Context, tx storage.Tx) (any, error) {
-		return s.createCredential(ctx, request, tx, slcMetadata)
+		return s.createCredential(ctx, request, tx, statusLists)
 	}
 }
 
-func (s Service) createCredential(ctx context.Context, request CreateCredentialRequest, tx storage.Tx, statusMetadata StatusListCredentialMetadata) (*CreateCredentialResponse, error) {
+func (s Service) createCredential(ctx context.Context, request CreateCredentialRequest, tx storage.Tx, statusLists []requestedStatusList) (*CreateCredentialResponse, error) {
 	logrus.Debugf("creating credential: %+v", request)
 
 	if !request.isStatusValid() {
-		return nil, sdkutil.LoggingNewError("credential may have at most one status")
+		return nil, sdkutil.LoggingNewError("credential with a status message cannot also be revocable or suspendable")
 	}
 
 	builder := credential.NewVerifiableCredentialBuilder()
@@ -173,6 +175,14 @@ func (s Service) createCredential(ctx context.Context, request CreateCredentialR
 		}
 	}
 
+	// embedded proofs need the terms of their suite defined by the credential's context
+	if request.ProofFormat.IsDataIntegrity() {
+		proofType := keyaccess.DataIntegrityProofType(request.ProofFormat)
+		if err := builder.AddContext(proofType.RequiredContexts()); err != nil {
+			return nil, sdkutil.LoggingErrorMsgf(err, "could not add context for proof type: %s", proofType)
+		}
+	}
+
 	// if a schema value exists, verify we can access it, validate the data against it, then set it
 	var knownSchema *schemalib.JSONSchema
 	if request.SchemaID != "" {
@@ -202,14 +212,25 @@ func (s Service) createCredential(ctx context.Context, request CreateCredentialR
 		return nil, sdkutil.LoggingErrorMsg(err, "could not set credential issuance date")
 	}
 
-	if request.hasStatus() {
-		statusEntry, err := s.createStatusListEntryForCredential(ctx, builder.ID, request, tx, statusMetadata)
+	// a credential in several status lists has an array of entries, which the builder does not support, so it is
+	// set once the credential is built
+	var statusEntries []any
+	for _, statusList := range statusLists {
+		entryID := fmt.Sprintf("%s/status", builder.ID)
+		if len(statusLists) > 1 {
+			entryID = fmt.Sprintf("%s/status#%s", builder.ID, statusList.list.purpose)
+		}
+		statusEntry, err := s.createStatusListEntryForCredential(ctx, entryID, request, tx, statusList)
 		if err != nil {
 			return nil, sdkutil.LoggingErrorMsg(err, "could not create status list entry for credential")
 		}
 		if err = builder.SetCredentialStatus(statusEntry); err != nil {
 			return nil, sdkutil.LoggingErrorMsg(err, "could not set credential status")
 		}
+		if err = builder.AddContext(credint.StatusEntryContext(statusEntry)); err != nil {
+			return nil, sdkutil.LoggingErrorMsg(err, "could not add context for credential status")
+		}
+		statusEntries = append(statusEntries, statusEntry)
 	}
 
 	if request.hasEvidence() {
@@ -226,6 +247,9 @@ func (s Service) createCredential(ctx context.Context, request CreateCredentialR
 	if err != nil {
 		return nil, sdkutil.LoggingErrorMsg(err, "could not build credential")
 	}
+	if len(statusEntries) > 1 {
+		cred.CredentialStatus = statusEntries
+	}
 
 	// verify the built schema complies with the schema we've set
 	if knownSchema != nil {
@@ -234,24 +258,37 @@ func (s Service) createCredential(ctx context.Context, request CreateCredentialR
 		}
 	}
 
-	// TODO(gabe) support Data Integrity creds too https://github.com/TBD54566975/ssi-service/issues/105
 	credCopy, err := credint.CopyCredential(*cred)
 	if err != nil {
 		return nil, sdkutil.LoggingErrorMsg(err, "could not copy credential")
 	}
-	credJWT, err := s.signCredentialJWT(ctx, request.FullyQualifiedVerificationMethodID, *credCopy)
-	if err != nil {
-		return nil, sdkutil.LoggingErrorMsg(err, "signing credential")
-	}
-
 	container := credint.Container{
 		ID:                                 credentialID,
 		FullyQualifiedVerificationMethodID: request.FullyQualifiedVerificationMethodID,
 		Credential:                         cred,
-		CredentialJWT:                      credJWT,
 		Revoked:                            false,
 		Suspended:                          false,
 	}
+	if request.ProofFormat.IsDataIntegrity() {
+		proofType := keyaccess.DataIntegrityProofType(request.ProofFormat)
+		signedCred, err := s.signCredentialDataIntegrity(ctx, request.FullyQualifiedVerificationMethodID, proofType, *credCopy)
+		if err != nil {
+			return nil, sdkutil.LoggingErrorMsg(err, "signing credential")
+		}
+		container.Credential = signedCred
+	} else if request.ProofFormat == SDJWTProofFormat {
+		sdJWT, err := s.signCredentialSDJWT(ctx, request.FullyQualifiedVerificationMethodID, *credCopy, request.Disclosable)
+		if err != nil {
+			return nil, sdkutil.LoggingErrorMsg(err, "signing credential")
+		}
+		container.SDJWT = sdJWT
+	} else {
+		credJWT, err := s.signCredentialJWT(ctx, request.FullyQualifiedVerificationMethodID, *credCopy)
+		if err != nil {
+			return nil, sdkutil.LoggingErrorMsg(err, "signing credential")
+		}
+		container.CredentialJWT = credJWT
+	}
 
 	credentialStorageRequest := StoreCredentialRequest{
 		Container: container,
@@ -265,16 +302,9 @@ func (s Service) createCredential(ctx context.Context, request CreateCredentialR
 
 // signCredentialJWT signs a credential and returns it as a vc-jwt
 func (s Service) signCredentialJWT(ctx context.Context, verificationMethodID string, cred credential.VerifiableCredential) (*keyaccess.JWT, error) {
-	keyStoreID := did.FullyQualifiedVerificationMethodID(cred.IssuerID(), verificationMethodID)
-	gotKey, err := s.keyStore.GetKey(ctx, keystore.GetKeyRequest{ID: keyStoreID})
+	gotKey, err := s.getSigningKey(ctx, verificationMethodID, cred)
 	if err != nil {
-		return nil, sdkutil.LoggingErrorMsgf(err, "getting key for signing credential<%s>", verificationMethodID)
-	}
-	if gotKey.Controller != cred.Issuer.(string) {
-		return nil, sdkutil.LoggingNewErrorf("key controller<%s> does not match credential issuer<%s> for key<%s>", gotKey.Controller, cred.Issuer, verificationMethodID)
-	}
-	if gotKey.Revoked {
-		return nil, sdkutil.LoggingNewErrorf("cannot use revoked key<%s>", gotKey.ID)
+		return nil, err
 	}
 	keyAccess, err := keyaccess.NewJWKKeyAccess(verificationMethodID, gotKey.ID, gotKey.Key)
 	if err != nil {
@@ -287,9 +317,67 @@ func (s Service) signCredentialJWT(ctx context.Context, verificationMethodID str
 	return credToken, nil
 }
 
+// signCredentialSDJWT signs a credential as an SD-JWT with the given subject claims selectively disclosable, and
+// returns it with all of its disclosures
+func (s Service) signCredentialSDJWT(ctx context.Context, verificationMethodID string, cred credential.VerifiableCredential, disclosable []string) (*keyaccess.SDJWT, error) {
+	gotKey, err := s.getSigningKey(ctx, verificationMethodID, cred)
+	if err != nil {
+		return nil, err
+	}
+	keyAccess, err := keyaccess.NewJWKKeyAccess(verificationMethodID, gotKey.ID, gotKey.Key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "creating key access for signing credential with key<%s>", gotKey.ID)
+	}
+	sdJWT, err := keyAccess.SignSDJWTVerifiableCredential(cred, disclosable)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not sign credential with key<%s>", gotKey.ID)
+	}
+	return sdJWT, nil
+}
+
+// signCredentialDataIntegrity signs a credential with an embedded proof of the given type, and returns the credential
+// with its proof set
+func (s Service) signCredentialDataIntegrity(ctx context.Context, verificationMethodID string, proofType keyaccess.DataIntegrityProofType, cred credential.VerifiableCredential) (*credential.VerifiableCredential, error) {
+	gotKey, err := s.getSigningKey(ctx, verificationMethodID, cred)
+	if err != nil {
+		return nil, err
+	}
+	keyAccess, err := keyaccess.NewDataIntegrityKeyAccessWithProofType(cred.IssuerID(), verificationMethodID, gotKey.Key, proofType)
+	if err != nil {
+		return nil, errors.Wrapf(err, "creating key access for signing credential with key<%s>", gotKey.ID)
+	}
+	signed, err := keyAccess.Sign(&cred)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not sign credential with key<%s>", gotKey.ID)
+	}
+	var signedCred credential.VerifiableCredential
+	if err = json.Unmarshal(signed.Data, &signedCred); err != nil {
+		return nil, errors.Wrap(err, "unmarshalling signed credential")
+	}
+	return &signedCred, nil
+}
+
+// getSigningKey returns the key the credential's issuer holds for the verification method
+func (s Service) getSigningKey(ctx context.Context, verificationMethodID string, cred credential.VerifiableCredential) (*keystore.GetKeyResponse, error) {
+	keyStoreID := did.FullyQualifiedVerificationMethodID(cred.IssuerID(), verificationMethodID)
+	gotKey, err := s.keyStore.GetKey(ctx, keystore.GetKeyRequest{ID: keyStoreID})
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsgf(err, "getting key for signing credential<%s>", verificationMethodID)
+	}
+	if gotKey.Controller != cred.Issuer.(string) {
+		return nil, sdkutil.LoggingNewErrorf("key controller<%s> does not match credential issuer<%s> for key<%s>", gotKey.Controller, cred.Issuer, verificationMethodID)
+	}
+	if gotKey.Revoked {
+		return nil, sdkutil.LoggingNewErrorf("cannot use revoked key<%s>", gotKey.ID)
+	}
+	return gotKey, nil
+}
+
 type VerifyCredentialRequest struct {
 	DataIntegrityCredential *credential.VerifiableCredential `json:"credential,omitempty"`
 	CredentialJWT           *keyaccess.JWT                   `json:"credentialJwt,omitempty"`
+	// DIDs trusted to issue the credential. The issuer trust check is skipped when empty.
+	TrustedIssuers []string `json:"trustedIssuers,omitempty"`
 }
 
 // IsValid checks if the request is valid, meaning there is at least one data integrity (with proof)
@@ -307,14 +395,20 @@ func (vcr VerifyCredentialRequest) IsValid() error {
 type VerifyCredentialResponse struct {
 	Verified bool   `json:"verified"`
 	Reason   string `json:"reason,omitempty"`
+	// Set when the credential is revoked or suspended, or its status couldn't be checked.
+	StatusFailures []verification.StatusFailure `json:"statusFailures,omitempty"`
+	// Each check that was run on the credential, and its outcome.
+	Report verification.Report `json:"report"`
 }
 
-// VerifyCredential does three levels of verification on a credential:
-// 1. Makes sure the credential has a valid signature
-// 2. Makes sure the credential has is not expired
+// VerifyCredential runs the following checks on a credential, and reports on each of them:
+// 1. Resolves the DID of the credential's issuer
+// 2. Makes sure the credential has a valid signature
 // 3. Makes sure the credential complies with the VC Data Model
-// 4. If the credential has a schema, makes sure its data complies with the schema
-// LATER: Makes sure the credential has not been revoked, other checks.
+// 4. Makes sure the credential has is not expired
+// 5. If the credential has a schema, makes sure its data complies with the schema
+// 6. If the credential has a status, makes sure it's neither revoked nor suspended
+// 7. If trusted issuers are given, makes sure the credential was issued by one of them
 func (s Service) VerifyCredential(ctx context.Context, request VerifyCredentialRequest) (*VerifyCredentialResponse, error) {
 	logrus.Debugf("verifying credential: %+v", request)
 
@@ -322,18 +416,17 @@ func (s Service) VerifyCredential(ctx context.Context, request VerifyCredentialR
 		return nil, sdkutil.LoggingErrorMsg(err, "invalid verify credential request")
 	}
 
-	if request.CredentialJWT != nil {
-		err := s.verifier.VerifyJWTCredential(ctx, *request.CredentialJWT)
-		if err != nil {
-			return &VerifyCredentialResponse{Verified: false, Reason: err.Error()}, nil
-		}
-	} else {
-		if err := s.verifier.VerifyDataIntegrityCredential(ctx, *request.DataIntegrityCredential); err != nil {
-			return &VerifyCredentialResponse{Verified: false, Reason: err.Error()}, nil
-		}
+	container := credint.Container{CredentialJWT: request.CredentialJWT}
+	if request.CredentialJWT == nil {
+		container.Credential = request.DataIntegrityCredential
 	}
-
-	return &VerifyCredentialResponse{Verified: true}, nil
+	report := s.verifier.VerifyCredentialWithReport(ctx, container, verification.Options{TrustedIssuers: request.TrustedIssuers})
+	resp := VerifyCredentialResponse{Verified: report.Verified, Report: *report}
+	if err := report.Err(); err != nil {
+		resp.Reason = err.Error()
+		resp.StatusFailures = report.StatusFailures()
+	}
+	return &resp, nil
 }
 
 func (s Service) GetCredential(ctx context.Context, request GetCredentialRequest) (*GetCredentialResponse, error) {
@@ -351,8 +444,11 @@ func (s Service) GetCredential(ctx context.Context, request GetCredentialRequest
 			ID:            gotCred.LocalCredentialID,
 			Credential:    gotCred.Credential,
 			CredentialJWT: gotCred.CredentialJWT,
+			SDJWT:         gotCred.SDJWT,
 			Revoked:       gotCred.Revoked,
 			Suspended:     gotCred.Suspended,
+			StatusValue:   gotCred.StatusValue,
+			Expired:       gotCred.HasExpired(time.Now()),
 		},
 	}
 	return &response, nil
@@ -372,8 +468,11 @@ func (s Service) ListCredentials(ctx context.Context, filter filtering.Filter, r
 			ID:            cred.LocalCredentialID,
 			Credential:    cred.Credential,
 			CredentialJWT: cred.CredentialJWT,
+			SDJWT:         cred.SDJWT,
 			Revoked:       cred.Revoked,
 			Suspended:     cred.Suspended,
+			StatusValue:   cred.StatusValue,
+			Expired:       cred.HasExpired(time.Now()),
 		}
 		creds = append(creds, container)
 	}
@@ -396,8 +495,20 @@ func (s Service) GetCredentialStatus(ctx context.Context, request GetCredentialS
 		return nil, sdkutil.LoggingNewErrorf("credential returned is not valid: %s", request.ID)
 	}
 	response := GetCredentialStatusResponse{
-		Revoked:   gotCred.Revoked,
-		Suspended: gotCred.Suspended,
+		Revoked:     gotCred.Revoked,
+		Suspended:   gotCred.Suspended,
+		StatusValue: gotCred.StatusValue,
+	}
+	if gotCred.HasCredentialStatus() {
+		entries, err := statusListEntries(gotCred.Credential.CredentialStatus)
+		if err != nil {
+			return nil, sdkutil.LoggingErrorMsgf(err, "credential %q has an unknown credentialStatus", request.ID)
+		}
+		for _, entry := range entries {
+			if bitstringEntry, ok := credint.ToBitstringStatusListEntry(entry.entry); ok && gotCred.StatusValue < len(bitstringEntry.StatusMessage) {
+				response.StatusMessage = bitstringEntry.StatusMessage[gotCred.StatusValue].Message
+			}
+		}
 	}
 	return &response, nil
 }
@@ -417,6 +528,7 @@ func (s Service) GetCredentialStatusList(ctx context.Context, request GetCredent
 			ID:            gotCred.LocalCredentialID,
 			Credential:    gotCred.Credential,
 			CredentialJWT: gotCred.CredentialJWT,
+			SDJWT:         gotCred.SDJWT,
 			Revoked:       false, // Credential Status List cannot be revoked
 			Suspended:     false, // Credential Status List cannot be suspended
 		},
@@ -424,16 +536,66 @@ func (s Service) GetCredentialStatusList(ctx context.Context, request GetCredent
 	return &response, nil
 }
 
+// GetStatusListUsage reports how many of the indexes of each status list credential are in use.
+func (s Service) GetStatusListUsage(ctx context.Context, request GetStatusListUsageRequest) (*GetStatusListUsageResponse, error) {
+	logrus.Debugf("getting status list usage: %+v", request)
+
+	storedUsages, err := s.storage.GetStatusListUsages(ctx)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsg(err, "could not get status list usage")
+	}
+
+	usages := make([]StatusListUsage, 0, len(storedUsages))
+	for _, storedUsage := range storedUsages {
+		statusListCred := storedUsage.StatusListCredential
+		if (request.Issuer != "" && statusListCred.Issuer != request.Issuer) || (request.SchemaID != "" && statusListCred.Schema != request.SchemaID) {
+			continue
+		}
+		if statusListCred.Credential == nil {
+			continue
+		}
+		format := StatusList2021Format
+		if statusListCred.Credential.CredentialSubject["type"] == credint.BitstringStatusListType {
+			format = BitstringStatusListFormat
+		}
+		statusPurpose, _ := statusListCred.Credential.CredentialSubject["statusPurpose"].(string)
+		usages = append(usages, StatusListUsage{
+			ID:            statusListCred.Credential.ID,
+			Issuer:        statusListCred.Issuer,
+			SchemaID:      statusListCred.Schema,
+			StatusPurpose: statusPurpose,
+			Format:        format,
+			Used:          storedUsage.Used,
+			Capacity:      storedUsage.Capacity,
+			Current:       storedUsage.Current,
+		})
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		a, b := usages[i], usages[j]
+		if a.Issuer != b.Issuer {
+			return a.Issuer < b.Issuer
+		}
+		if a.SchemaID != b.SchemaID {
+			return a.SchemaID < b.SchemaID
+		}
+		if a.StatusPurpose != b.StatusPurpose {
+			return a.StatusPurpose < b.StatusPurpose
+		}
+		// full lists come before the current one
+		return !a.Current && b.Current
+	})
+
+	return &GetStatusListUsageResponse{StatusLists: usages}, nil
+}
+
 func (s Service) UpdateCredentialStatus(ctx context.Context, request UpdateCredentialStatusRequest) (*UpdateCredentialStatusResponse, error) {
 
-	statusListCredentialWatchKey, err := s.statusListCredentialWatchKey(ctx, request.ID)
+	watchKeys, err := s.statusListCredentialWatchKeys(ctx, request.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	slcMetadata := StatusListCredentialMetadata{statusListCredentialWatchKey: *statusListCredentialWatchKey}
-	watchKeys := []storage.WatchKey{*statusListCredentialWatchKey}
-	returnFunc := s.updateCredentialStatusFunc(request, slcMetadata)
+	returnFunc := s.updateCredentialStatusFunc(request)
 
 	returnValue, err := s.storage.db.Execute(ctx, returnFunc, watchKeys)
 	if err != nil {
@@ -448,19 +610,15 @@ func (s Service) UpdateCredentialStatus(ctx context.Context, request UpdateCrede
 	return credResponse, nil
 }
 
-func (s Service) updateCredentialStatusFunc(request UpdateCredentialStatusRequest, slcMetadata StatusListCredentialMetadata) storage.BusinessLogicFunc {
+func (s Service) updateCredentialStatusFunc(request UpdateCredentialStatusRequest) storage.BusinessLogicFunc {
 	return func(ctx context.Context, tx storage.Tx) (any, error) {
-		return s.updateCredentialStatusBusinessLogic(ctx, tx, request, slcMetadata)
+		return s.updateCredentialStatusBusinessLogic(ctx, tx, request)
 	}
 }
 
-func (s Service) updateCredentialStatusBusinessLogic(ctx context.Context, tx storage.Tx, request UpdateCredentialStatusRequest, slcMetadata StatusListCredentialMetadata) (*UpdateCredentialStatusResponse, error) {
+func (s Service) updateCredentialStatusBusinessLogic(ctx context.Context, tx storage.Tx, request UpdateCredentialStatusRequest) (*UpdateCredentialStatusResponse, error) {
 	logrus.Debugf("updating credential status: %s to Revoked: %v, Suspended: %v", request.ID, request.Revoked, request.Suspended)
 
-	if request.Suspended && request.Revoked {
-		return nil, sdkutil.LoggingNewErrorf("cannot update both suspended and revoked status")
-	}
-
 	gotCred, err := s.storage.GetCredential(ctx, request.ID)
 	if err != nil {
 		return nil, sdkutil.LoggingErrorMsgf(err, "could not get credential: %s", request.ID)
@@ -468,35 +626,79 @@ func (s Service) updateCredentialStatusBusinessLogic(ctx context.Context, tx sto
 	if !gotCred.IsValid() {
 		return nil, sdkutil.LoggingNewErrorf("credential returned is not valid: %s", request.ID)
 	}
+	if !gotCred.HasCredentialStatus() {
+		return nil, sdkutil.LoggingNewErrorf("credential %q has no credentialStatus field", request.ID)
+	}
+
+	entries, err := statusListEntries(gotCred.Credential.CredentialStatus)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsgf(err, "credential %q has an unknown credentialStatus", request.ID)
+	}
+	purposes := make(map[statussdk.StatusPurpose]statusList, len(entries))
+	for _, entry := range entries {
+		purposes[entry.list.purpose] = entry.list
+	}
+	_, revocable := purposes[statussdk.StatusRevocation]
+	_, suspendable := purposes[statussdk.StatusSuspension]
+
+	// only a credential in both a revocation and a suspension list can be both revoked and suspended
+	if request.Suspended && request.Revoked && !(revocable && suspendable) {
+		return nil, sdkutil.LoggingNewErrorf("cannot update both suspended and revoked status")
+	}
+
+	statusValue := gotCred.StatusValue
+	if messageList, ok := purposes[credint.StatusMessage]; ok {
+		if request.Revoked || request.Suspended {
+			return nil, sdkutil.LoggingNewErrorf("credential %q has a %s status, which cannot be revoked or suspended", request.ID, credint.StatusMessage)
+		}
+		if request.StatusValue != nil {
+			statusValue = *request.StatusValue
+		}
+		if statusValue < 0 || statusValue >= 1<<messageList.statusSize {
+			return nil, sdkutil.LoggingNewErrorf("status value<%d> does not fit in the credential's status size<%d>", statusValue, messageList.statusSize)
+		}
+	} else if request.StatusValue != nil {
+		return nil, sdkutil.LoggingNewErrorf("credential %q does not have a multi-bit status", request.ID)
+	} else if request.Revoked && !revocable {
+		return nil, sdkutil.LoggingNewErrorf("credential<%s> has a different status purpose<%s> value than the status credential<%s>", request.ID, statussdk.StatusSuspension, statussdk.StatusRevocation)
+	} else if request.Suspended && !suspendable {
+		return nil, sdkutil.LoggingNewErrorf("credential<%s> has a different status purpose<%s> value than the status credential<%s>", request.ID, statussdk.StatusRevocation, statussdk.StatusSuspension)
+	}
 
 	// if the request is the same as what the current credential is there is no action
-	if gotCred.Revoked == request.Revoked && gotCred.Suspended == request.Suspended {
+	if gotCred.Revoked == request.Revoked && gotCred.Suspended == request.Suspended && gotCred.StatusValue == statusValue {
 		logrus.Warn("request and credential have same status, no action is needed")
 		response := UpdateCredentialStatusResponse{Status{
-			Revoked:   gotCred.Revoked,
-			Suspended: gotCred.Suspended,
+			Revoked:     gotCred.Revoked,
+			Suspended:   gotCred.Suspended,
+			StatusValue: gotCred.StatusValue,
 		}}
 		return &response, nil
 	}
 
-	container, err := updateCredentialStatus(ctx, tx, s, gotCred, request, slcMetadata)
+	container, err := updateCredentialStatus(ctx, tx, s, gotCred, entries, request.Revoked, request.Suspended, statusValue)
 	if err != nil {
 		return nil, sdkutil.LoggingErrorMsg(err, "updating credential")
 	}
 
-	response := UpdateCredentialStatusResponse{Status{Revoked: container.Revoked, Suspended: container.Suspended}}
+	response := UpdateCredentialStatusResponse{Status{Revoked: container.Revoked, Suspended: container.Suspended, StatusValue: container.StatusValue}}
 	return &response, nil
 }
 
-func updateCredentialStatus(ctx context.Context, tx storage.Tx, s Service, gotCred *StoredCredential, request UpdateCredentialStatusRequest, slcMetadata StatusListCredentialMetadata) (*credint.Container, error) {
+// updateCredentialStatus stores the credential with its new status, and regenerates each of its status lists whose
+// status changed.
+func updateCredentialStatus(ctx context.Context, tx storage.Tx, s Service, gotCred *StoredCredential, entries []statusListEntry, revoked, suspended bool, statusValue int) (*credint.Container, error) {
 	// store the credential with updated status
 	container := credint.Container{
 		ID:                                 gotCred.LocalCredentialID,
 		FullyQualifiedVerificationMethodID: gotCred.FullyQualifiedVerificationMethodID,
 		Credential:                         gotCred.Credential,
 		CredentialJWT:                      gotCred.CredentialJWT,
-		Revoked:                            request.Revoked,
-		Suspended:                          request.Suspended,
+		SDJWT:                              gotCred.SDJWT,
+		Revoked:                            revoked,
+		Suspended:                          suspended,
+		StatusValue:                        statusValue,
+		Expired:                            gotCred.Expired,
 	}
 
 	storageRequest := StoreCredentialRequest{
@@ -507,57 +709,54 @@ func updateCredentialStatus(ctx context.Context, tx storage.Tx, s Service, gotCr
 		return nil, sdkutil.LoggingErrorMsg(err, "could not store credential")
 	}
 
-	statusListCredentialURI := gotCred.Credential.CredentialStatus.(map[string]any)["statusListCredential"].(string)
-
-	if len(statusListCredentialURI) == 0 {
-		return nil, sdkutil.LoggingNewErrorf("problem with getting status list credential id")
-	}
-
-	statusListCredentialID, err := parseIDFromURI(statusListCredentialURI)
-	if err != nil {
-		return nil, err
-	}
-
 	creds, err := s.storage.GetCredentialsByIssuerAndSchema(ctx, gotCred.Issuer, gotCred.Schema)
 	if err != nil {
 		return nil, sdkutil.LoggingNewErrorf("problem with getting status list credential for issuer: %s schema: %s", gotCred.Issuer, gotCred.Schema)
 	}
 
-	var revokedOrSuspendedStatusCreds []credential.VerifiableCredential
+	// we add the current cred to the creds list based on request, not on what could be in stale database that the tx has not updated yet
+	statusListCreds := make([]StoredCredential, 0, len(creds))
 	for _, cred := range creds {
-		// we add the current cred to the creds list based on request, not on what could be in stale database that the tx has not updated yet
-		if cred.Credential.ID == gotCred.Credential.ID {
-			continue
-		}
-
-		if request.Revoked && cred.Credential.CredentialStatus != nil && cred.Revoked {
-			revokedOrSuspendedStatusCreds = append(revokedOrSuspendedStatusCreds, *cred.Credential)
-		} else if request.Suspended && cred.Credential.CredentialStatus != nil && cred.Suspended {
-			revokedOrSuspendedStatusCreds = append(revokedOrSuspendedStatusCreds, *cred.Credential)
+		if cred.Credential.ID != gotCred.Credential.ID {
+			statusListCreds = append(statusListCreds, cred)
 		}
 	}
+	updatedCred := *gotCred
+	updatedCred.Revoked = revoked
+	updatedCred.Suspended = suspended
+	updatedCred.StatusValue = statusValue
+	statusListCreds = append(statusListCreds, updatedCred)
 
-	// add current one since it has not been saved yet and won't be available in the creds array
-	if request.Revoked == true || request.Suspended == true {
-		revokedOrSuspendedStatusCreds = append(revokedOrSuspendedStatusCreds, *gotCred.Credential)
+	for _, entry := range entries {
+		if gotCred.statusValue(entry.list.purpose) == updatedCred.statusValue(entry.list.purpose) {
+			continue
+		}
+		if err = s.updateStatusListCredential(ctx, tx, gotCred, entry, statusListCreds); err != nil {
+			return nil, err
+		}
 	}
 
-	statusPurpose := statussdk.StatusRevocation
+	return &container, nil
+}
 
-	if request.Suspended {
-		statusPurpose = statussdk.StatusSuspension
+// updateStatusListCredential regenerates, signs and stores the status list credential of an entry of a credential's
+// status from the credentials of the same issuer and schema.
+func (s Service) updateStatusListCredential(ctx context.Context, tx storage.Tx, gotCred *StoredCredential, entry statusListEntry, creds []StoredCredential) error {
+	statusListCredentialID, err := parseIDFromURI(entry.statusListCredential)
+	if err != nil {
+		return err
 	}
 
-	generatedStatusListCredential, err := statussdk.GenerateStatusList2021Credential(statusListCredentialURI, gotCred.Issuer, statusPurpose, revokedOrSuspendedStatusCreds)
+	generatedStatusListCredential, err := generateStatusListCredential(entry.list, entry.statusListCredential, gotCred.Issuer, creds)
 	if err != nil {
-		return nil, sdkutil.LoggingErrorMsg(err, "could not generate status list")
+		return sdkutil.LoggingErrorMsg(err, "could not generate status list")
 	}
 
 	generatedStatusListCredential.CredentialSchema = gotCred.Credential.CredentialSchema
 
-	statusListCredJWT, err := s.signCredentialJWT(ctx, gotCred.FullyQualifiedVerificationMethodID, *generatedStatusListCredential)
+	statusListCredJWT, err := s.signStatusListCredential(ctx, gotCred.FullyQualifiedVerificationMethodID, generatedStatusListCredential)
 	if err != nil {
-		return nil, sdkutil.LoggingErrorMsg(err, "could not sign status list credential")
+		return sdkutil.LoggingErrorMsg(err, "could not sign status list credential")
 	}
 
 	// store the status list credential
@@ -568,15 +767,19 @@ func updateCredentialStatus(ctx context.Context, tx storage.Tx, s Service, gotCr
 		CredentialJWT:                      statusListCredJWT,
 	}
 
-	storageRequest = StoreCredentialRequest{
+	storageRequest := StoreCredentialRequest{
 		Container: statusListContainer,
 	}
 
+	watchKey, err := s.statusListCredentialWatchKey(ctx, gotCred.Issuer, gotCred.Schema, entry)
+	if err != nil {
+		return err
+	}
+	slcMetadata := StatusListCredentialMetadata{statusListCredentialWatchKey: *watchKey}
 	if err = s.storage.StoreStatusListCredentialTx(ctx, tx, storageRequest, slcMetadata); err != nil {
-		return nil, sdkutil.LoggingErrorMsg(err, "could not store credential status list")
+		return sdkutil.LoggingErrorMsg(err, "could not store credential status list")
 	}
-
-	return &container, nil
+	return nil
 }
 
 func parseIDFromURI(uri string) (string, error) {
@@ -603,30 +806,16 @@ func (s Service) BatchCreateCredentials(ctx context.Context, batchRequest BatchC
 
 	funcs := make([]storage.BusinessLogicFunc, 0, len(batchRequest.Requests))
 	for _, request := range batchRequest.Requests {
-		var statusMetadata StatusListCredentialMetadata
+		var statusLists []requestedStatusList
 		if request.hasStatus() && request.isStatusValid() {
-			statusPurpose := statussdk.StatusRevocation
-
-			if request.Suspendable {
-				statusPurpose = statussdk.StatusSuspension
-			}
-
-			statusListCredentialWatchKey := s.storage.GetStatusListCredentialWatchKey(request.Issuer, request.SchemaID, string(statusPurpose))
-			statusListCredentialIndexPoolWatchKey := s.storage.GetStatusListIndexPoolWatchKey(request.Issuer, request.SchemaID, string(statusPurpose))
-			statusListCredentialCurrentIndexWatchKey := s.storage.GetStatusListCurrentIndexWatchKey(request.Issuer, request.SchemaID, string(statusPurpose))
-
-			watchKeys = append(watchKeys, statusListCredentialWatchKey)
-			watchKeys = append(watchKeys, statusListCredentialIndexPoolWatchKey)
-			watchKeys = append(watchKeys, statusListCredentialCurrentIndexWatchKey)
-
-			statusMetadata = StatusListCredentialMetadata{
-				statusListCredentialWatchKey:   statusListCredentialWatchKey,
-				statusListIndexPoolWatchKey:    statusListCredentialIndexPoolWatchKey,
-				statusListCurrentIndexWatchKey: statusListCredentialCurrentIndexWatchKey,
+			var err error
+			if statusLists, err = s.requestedStatusLists(request); err != nil {
+				return nil, errors.Wrapf(err, "validating request for subject<%s>", request.Subject)
 			}
+			watchKeys = append(watchKeys, watchKeysForStatusLists(statusLists)...)
 		}
 
-		funcs = append(funcs, s.createCredentialFunc(request, statusMetadata))
+		funcs = append(funcs, s.createCredentialFunc(request, statusLists))
 	}
 
 	returnFunc := storage.BusinessLogicFunc(func(ctx context.Context, tx storage.Tx) (any, error) {
@@ -663,14 +852,13 @@ func (s Service) BatchUpdateCredentialStatus(ctx context.Context, batchRequest B
 	watchKeys := make([]storage.WatchKey, 0, len(batchRequest.Requests))
 	updateFuncs := make([]storage.BusinessLogicFunc, 0, len(batchRequest.Requests))
 	for _, request := range batchRequest.Requests {
-		statusListCredentialWatchKey, err := s.statusListCredentialWatchKey(ctx, request.ID)
+		statusListCredentialWatchKeys, err := s.statusListCredentialWatchKeys(ctx, request.ID)
 		if err != nil {
 			return nil, err
 		}
-		watchKeys = append(watchKeys, *statusListCredentialWatchKey)
+		watchKeys = append(watchKeys, statusListCredentialWatchKeys...)
 
-		slcMetadata := StatusListCredentialMetadata{statusListCredentialWatchKey: *statusListCredentialWatchKey}
-		returnFunc := s.updateCredentialStatusFunc(request, slcMetadata)
+		returnFunc := s.updateCredentialStatusFunc(request)
 		updateFuncs = append(updateFuncs, returnFunc)
 	}
 	returnValue, err := s.storage.db.Execute(ctx, func(ctx context.Context, tx storage.Tx) (any, error) {
@@ -699,7 +887,8 @@ func (s Service) BatchUpdateCredentialStatus(ctx context.Context, batchRequest B
 	return batchResponse, nil
 }
 
-func (s Service) statusListCredentialWatchKey(ctx context.Context, id string) (*storage.WatchKey, error) {
+// statusListCredentialWatchKeys returns the watch keys of the status lists a credential is in.
+func (s Service) statusListCredentialWatchKeys(ctx context.Context, id string) ([]storage.WatchKey, error) {
 	gotCred, err := s.storage.GetCredential(ctx, id)
 	if err != nil {
 		return nil, errors.Wrap(err, "reading credential")
@@ -709,20 +898,18 @@ func (s Service) statusListCredentialWatchKey(ctx context.Context, id string) (*
 		return nil, sdkutil.LoggingNewErrorf("credential %q has no credentialStatus field", gotCred.LocalCredentialID)
 	}
 
-	statusPurpose := gotCred.GetStatusPurpose()
-	if len(statusPurpose) == 0 {
-		return nil, sdkutil.LoggingNewErrorf("status purpose could not be derived from credential status")
-	}
-
-	statusListCredential, err := s.storage.GetStatusListCredentialKeyData(ctx, gotCred.Issuer, gotCred.Schema, statussdk.StatusPurpose(statusPurpose))
+	entries, err := statusListEntries(gotCred.Credential.CredentialStatus)
 	if err != nil {
-		return nil, errors.Wrap(err, "getting status list watch key uuid data")
+		return nil, sdkutil.LoggingErrorMsgf(err, "credential %q has an unknown credentialStatus", gotCred.LocalCredentialID)
 	}
 
-	if statusListCredential == nil {
-		return nil, errors.Wrap(err, "status list credential should exist in order to update")
+	watchKeys := make([]storage.WatchKey, 0, len(entries))
+	for _, entry := range entries {
+		watchKey, err := s.statusListCredentialWatchKey(ctx, gotCred.Issuer, gotCred.Schema, entry)
+		if err != nil {
+			return nil, err
+		}
+		watchKeys = append(watchKeys, *watchKey)
 	}
-
-	statusListCredentialWatchKey := s.storage.GetStatusListCredentialWatchKey(gotCred.Issuer, gotCred.Schema, statusPurpose)
-	return &statusListCredentialWatchKey, nil
+	return watchKeys, nil
 }
@@ -4,41 +4,195 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/TBD54566975/ssi-sdk/credential"
 	statussdk "github.com/TBD54566975/ssi-sdk/credential/status"
 	sdkutil "github.com/TBD54566975/ssi-sdk/util"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 
 	"github.com/tbd54566975/ssi-service/config"
 	credint "github.com/tbd54566975/ssi-service/internal/credential"
+	"github.com/tbd54566975/ssi-service/internal/keyaccess"
 	"github.com/tbd54566975/ssi-service/pkg/storage"
 )
 
-func (s Service) createStatusListEntryForCredential(ctx context.Context, credID string, request CreateCredentialRequest,
-	tx storage.Tx, statusMetadata StatusListCredentialMetadata) (*statussdk.StatusList2021Entry, error) {
-	issuerID := request.Issuer
-	fullyQualifiedVerificationMethodID := request.FullyQualifiedVerificationMethodID
-	schemaID := request.SchemaID
+// statusList describes the status list that tracks a credential's status.
+type statusList struct {
+	format     StatusListFormat
+	purpose    statussdk.StatusPurpose
+	statusSize int
+}
+
+// key identifies the status list among those of an issuer and schema. Lists of different formats, and Bitstring
+// Status Lists of different status sizes, are kept apart.
+func (l statusList) key() string {
+	if l.format == BitstringStatusListFormat {
+		return storage.Join(string(l.format), string(l.purpose), strconv.Itoa(l.statusSize))
+	}
+	return string(l.purpose)
+}
+
+// statusListsForRequest returns the status lists for the statuses requested for a credential, using the configured
+// format when the request doesn't have one. A credential that is both revocable and suspendable is in a list for each.
+func (s Service) statusListsForRequest(request CreateCredentialRequest) ([]statusList, error) {
+	format := request.StatusListFormat
+	if format == "" {
+		format = StatusListFormat(s.config.StatusListFormat)
+	}
+	if format == "" {
+		format = StatusList2021Format
+	}
+	if !format.IsSupported() {
+		return nil, fmt.Errorf("unsupported status list format: %s", format)
+	}
+
+	if len(request.StatusMessage) > 0 {
+		if format != BitstringStatusListFormat {
+			return nil, fmt.Errorf("status messages require the %s status list format", BitstringStatusListFormat)
+		}
+		statusSize, err := credint.StatusSizeForMessages(request.StatusMessage)
+		if err != nil {
+			return nil, err
+		}
+		return []statusList{{format: format, purpose: credint.StatusMessage, statusSize: statusSize}}, nil
+	}
 
-	statusPurpose := statussdk.StatusRevocation
+	var lists []statusList
+	if request.Revocable {
+		lists = append(lists, statusList{format: format, purpose: statussdk.StatusRevocation, statusSize: 1})
+	}
 	if request.Suspendable {
-		statusPurpose = statussdk.StatusSuspension
+		lists = append(lists, statusList{format: format, purpose: statussdk.StatusSuspension, statusSize: 1})
+	}
+	return lists, nil
+}
+
+// statusListEntry is an entry of a credential's status, along with the status list it is in.
+type statusListEntry struct {
+	list                 statusList
+	statusListCredential string
+	entry                any
+}
+
+// statusListEntries returns the entries of a credential's status. The status of a credential that is both revocable
+// and suspendable is an array with an entry for each status list.
+func statusListEntries(credentialStatus any) ([]statusListEntry, error) {
+	entries, ok := credentialStatus.([]any)
+	if !ok {
+		entries = []any{credentialStatus}
+	}
+	statusEntries := make([]statusListEntry, 0, len(entries))
+	for _, entry := range entries {
+		statusEntry, err := statusListEntryForCredentialStatus(entry)
+		if err != nil {
+			return nil, err
+		}
+		statusEntries = append(statusEntries, *statusEntry)
+	}
+	return statusEntries, nil
+}
+
+// statusListEntryForCredentialStatus returns a single entry of a credential's status.
+func statusListEntryForCredentialStatus(entry any) (*statusListEntry, error) {
+	if bitstringEntry, ok := credint.ToBitstringStatusListEntry(entry); ok {
+		list := statusList{format: BitstringStatusListFormat, purpose: bitstringEntry.StatusPurpose, statusSize: bitstringEntry.GetStatusSize()}
+		return &statusListEntry{list: list, statusListCredential: bitstringEntry.StatusListCredential, entry: entry}, nil
+	}
+	statusMap, err := sdkutil.ToJSONMap(entry)
+	if err != nil {
+		return nil, errors.Wrap(err, "credential status is not an object")
+	}
+	statusPurpose, ok := statusMap["statusPurpose"].(string)
+	if !ok || len(statusPurpose) == 0 {
+		return nil, errors.New("status purpose could not be derived from credential status")
 	}
+	statusListCredential, ok := statusMap["statusListCredential"].(string)
+	if !ok || len(statusListCredential) == 0 {
+		return nil, errors.New("status list credential could not be derived from credential status")
+	}
+	list := statusList{format: StatusList2021Format, purpose: statussdk.StatusPurpose(statusPurpose), statusSize: 1}
+	return &statusListEntry{list: list, statusListCredential: statusListCredential, entry: entry}, nil
+}
+
+// statusListCredentialMetadata returns the watch keys of a status list of an issuer and schema.
+func (s Service) statusListCredentialMetadata(issuer, schema string, list statusList) StatusListCredentialMetadata {
+	return StatusListCredentialMetadata{
+		statusListCredentialWatchKey:   s.storage.GetStatusListCredentialWatchKey(issuer, schema, list.key()),
+		statusListIndexPoolWatchKey:    s.storage.GetStatusListIndexPoolWatchKey(issuer, schema, list.key()),
+		statusListCurrentIndexWatchKey: s.storage.GetStatusListCurrentIndexWatchKey(issuer, schema, list.key()),
+	}
+}
+
+func (m StatusListCredentialMetadata) watchKeys() []storage.WatchKey {
+	return []storage.WatchKey{m.statusListCredentialWatchKey, m.statusListIndexPoolWatchKey, m.statusListCurrentIndexWatchKey}
+}
+
+// requestedStatusList is a status list a new credential gets an entry in, along with the watch keys of the list.
+type requestedStatusList struct {
+	list     statusList
+	metadata StatusListCredentialMetadata
+}
+
+// requestedStatusLists returns the status lists for the statuses requested for a credential.
+func (s Service) requestedStatusLists(request CreateCredentialRequest) ([]requestedStatusList, error) {
+	lists, err := s.statusListsForRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	requested := make([]requestedStatusList, 0, len(lists))
+	for _, list := range lists {
+		requested = append(requested, requestedStatusList{
+			list:     list,
+			metadata: s.statusListCredentialMetadata(request.Issuer, request.SchemaID, list),
+		})
+	}
+	return requested, nil
+}
+
+func watchKeysForStatusLists(lists []requestedStatusList) []storage.WatchKey {
+	var watchKeys []storage.WatchKey
+	for _, list := range lists {
+		watchKeys = append(watchKeys, list.metadata.watchKeys()...)
+	}
+	return watchKeys
+}
+
+func (s Service) createStatusListEntryForCredential(ctx context.Context, entryID string, request CreateCredentialRequest,
+	tx storage.Tx, requested requestedStatusList) (any, error) {
+	issuerID := request.Issuer
+	fullyQualifiedVerificationMethodID := request.FullyQualifiedVerificationMethodID
+	schemaID := request.SchemaID
+	list, statusMetadata := requested.list, requested.metadata
 
 	var statusCred *credential.VerifiableCredential
 	var statusListCredentialID string
 	var randomIndex int
-	var err error
-	statusListCredential, err := s.storage.GetStatusListCredentialKeyData(ctx, issuerID, schemaID, statusPurpose)
+	statusListCredential, err := s.storage.GetStatusListCredentialKeyData(ctx, issuerID, schemaID, list.key())
 	if err != nil {
 		return nil, errors.Wrap(err, "getting status list credential key data")
 	}
 
+	if statusListCredential != nil {
+		statusListIndex, err := s.storage.GetStatusListIndex(ctx, statusMetadata)
+		if err != nil {
+			return nil, errors.Wrap(err, "getting status list index")
+		}
+		// a new status list takes the place of a full one
+		if statusListIndex.isFull() {
+			logrus.Infof("status list credential<%s> is full, creating a new one", statusListCredential.Credential.ID)
+			if err = s.storage.RollOverStatusListCredentialTx(ctx, tx, *statusListCredential, statusMetadata); err != nil {
+				return nil, errors.Wrap(err, "rolling over status list credential")
+			}
+			statusListCredential = nil
+		}
+	}
+
 	if statusListCredential == nil {
 		// creates status list credential with random index
-		randomIndex, statusCred, err = s.createStatusListCredential(ctx, tx, statusPurpose, issuerID, fullyQualifiedVerificationMethodID, statusMetadata)
+		randomIndex, statusCred, err = s.createStatusListCredential(ctx, tx, list, issuerID, fullyQualifiedVerificationMethodID, statusMetadata)
 		if err != nil {
 			return nil, sdkutil.LoggingErrorMsgf(err, "problem with getting status list credential")
 		}
@@ -57,24 +211,70 @@ func (s Service) createStatusListEntryForCredential(ctx context.Context, credID
 	}
 
 	indexStr := strconv.Itoa(randomIndex)
+	if list.format == BitstringStatusListFormat {
+		entry := credint.BitstringStatusListEntry{
+			ID:                   entryID,
+			Type:                 credint.BitstringStatusListEntryType,
+			StatusPurpose:        list.purpose,
+			StatusListIndex:      indexStr,
+			StatusListCredential: statusListCredentialID,
+			StatusMessage:        request.StatusMessage,
+		}
+		if list.statusSize > 1 {
+			entry.StatusSize = list.statusSize
+		}
+		return &entry, nil
+	}
 	return &statussdk.StatusList2021Entry{
-		ID:                   fmt.Sprintf(`%s/status`, credID),
+		ID:                   entryID,
 		Type:                 statussdk.StatusList2021EntryType,
-		StatusPurpose:        statusPurpose,
+		StatusPurpose:        list.purpose,
 		StatusListIndex:      indexStr,
 		StatusListCredential: statusListCredentialID,
 	}, nil
 }
 
-func (s Service) createStatusListCredential(ctx context.Context, tx storage.Tx, statusPurpose statussdk.StatusPurpose, issuerID, fullyQualifiedVerificationMethodID string, slcMetadata StatusListCredentialMetadata) (int, *credential.VerifiableCredential, error) {
+// statusListSize returns the number of credentials a new status list credential holds.
+func (s Service) statusListSize() int {
+	if s.config.StatusListSize == 0 {
+		return bitStringLength
+	}
+	return s.config.StatusListSize
+}
+
+// statusListCredentialWatchKey returns the watch key of the status list credential of an entry of a credential's
+// status, which is either the current list of the credential's issuer, schema and purpose, or one that is full.
+func (s Service) statusListCredentialWatchKey(ctx context.Context, issuer, schema string, entry statusListEntry) (*storage.WatchKey, error) {
+	statusListCredential, err := s.storage.GetStatusListCredentialKeyData(ctx, issuer, schema, entry.list.key())
+	if err != nil {
+		return nil, errors.Wrap(err, "getting status list watch key uuid data")
+	}
+
+	if statusListCredential == nil {
+		return nil, errors.New("status list credential should exist in order to update")
+	}
+
+	if statusListCredential.Credential.ID == entry.statusListCredential {
+		watchKey := s.storage.GetStatusListCredentialWatchKey(issuer, schema, entry.list.key())
+		return &watchKey, nil
+	}
+	statusListCredentialID, err := parseIDFromURI(entry.statusListCredential)
+	if err != nil {
+		return nil, err
+	}
+	watchKey := s.storage.GetFullStatusListCredentialWatchKey(issuer, schema, entry.list.key(), statusListCredentialID)
+	return &watchKey, nil
+}
+
+func (s Service) createStatusListCredential(ctx context.Context, tx storage.Tx, list statusList, issuerID, fullyQualifiedVerificationMethodID string, slcMetadata StatusListCredentialMetadata) (int, *credential.VerifiableCredential, error) {
 	statusListID := uuid.NewString()
 	statusListURI := fmt.Sprintf("%s/%s", config.GetStatusBase(), statusListID)
-	generatedStatusListCredential, err := statussdk.GenerateStatusList2021Credential(statusListURI, issuerID, statusPurpose, []credential.VerifiableCredential{})
+	generatedStatusListCredential, err := generateStatusListCredential(list, statusListURI, issuerID, nil)
 	if err != nil {
 		return -1, nil, sdkutil.LoggingErrorMsg(err, "could not generate status list")
 	}
 
-	statusListCredJWT, err := s.signCredentialJWT(ctx, fullyQualifiedVerificationMethodID, *generatedStatusListCredential)
+	statusListCredJWT, err := s.signStatusListCredential(ctx, fullyQualifiedVerificationMethodID, generatedStatusListCredential)
 	if err != nil {
 		return -1, nil, sdkutil.LoggingErrorMsg(err, "could not sign status list credential")
 	}
@@ -90,10 +290,172 @@ func (s Service) createStatusListCredential(ctx context.Context, tx storage.Tx,
 		Container: statusListContainer,
 	}
 
-	randomIndex, err := s.storage.CreateStatusListCredentialTx(ctx, tx, statusListStorageRequest, slcMetadata)
+	randomIndex, err := s.storage.CreateStatusListCredentialTx(ctx, tx, statusListStorageRequest, slcMetadata, s.statusListSize())
 	if err != nil {
 		return -1, nil, errors.Wrap(err, "creating status list credential")
 	}
 
 	return randomIndex, generatedStatusListCredential, nil
 }
+
+// generateStatusListCredential generates the status list credential from the credentials tracked in it, in the
+// list's format. Credentials without an entry in the list are ignored.
+func generateStatusListCredential(list statusList, statusListURI, issuerID string, creds []StoredCredential) (*credential.VerifiableCredential, error) {
+	statuses := make(map[int]int)
+	statusCreds := make([]credential.VerifiableCredential, 0)
+	for _, cred := range creds {
+		if !cred.HasCredentialStatus() {
+			continue
+		}
+		entries, err := statusListEntries(cred.Credential.CredentialStatus)
+		if err != nil {
+			return nil, errors.Wrapf(err, "credential<%s> has an unknown credentialStatus", cred.Credential.ID)
+		}
+		for _, entry := range entries {
+			if entry.statusListCredential != statusListURI {
+				continue
+			}
+			value := cred.statusValue(list.purpose)
+			if value == 0 {
+				continue
+			}
+			if list.format == StatusList2021Format {
+				// the status list only reads the entry that is in it
+				statusCred := *cred.Credential
+				statusCred.CredentialStatus = entry.entry
+				statusCreds = append(statusCreds, statusCred)
+				continue
+			}
+			bitstringEntry, ok := credint.ToBitstringStatusListEntry(entry.entry)
+			if !ok {
+				return nil, fmt.Errorf("credential<%s> not using the %s credentialStatus property", cred.Credential.ID, credint.BitstringStatusListEntryType)
+			}
+			index, err := strconv.Atoi(bitstringEntry.StatusListIndex)
+			if err != nil {
+				return nil, errors.Wrapf(err, "credential<%s> has an invalid status list index", cred.Credential.ID)
+			}
+			statuses[index] = value
+		}
+	}
+
+	if list.format == BitstringStatusListFormat {
+		return credint.GenerateBitstringStatusListCredential(statusListURI, issuerID, list.purpose, list.statusSize, statuses)
+	}
+	return statussdk.GenerateStatusList2021Credential(statusListURI, issuerID, list.purpose, statusCreds)
+}
+
+// signStatusListCredential dates a status list credential, giving it the configured validity window, and signs it.
+func (s Service) signStatusListCredential(ctx context.Context, verificationMethodID string, statusListCredential *credential.VerifiableCredential) (*keyaccess.JWT, error) {
+	now := time.Now()
+	statusListCredential.IssuanceDate = now.Format(time.RFC3339)
+	if s.config.StatusListValidity > 0 {
+		statusListCredential.ExpirationDate = now.Add(s.config.StatusListValidity).Format(time.RFC3339)
+	}
+	return s.signCredentialJWT(ctx, verificationMethodID, *statusListCredential)
+}
+
+// statusListRefreshInterval returns how often status list credentials close to expiring are re-signed.
+func (s Service) statusListRefreshInterval() time.Duration {
+	if s.config.StatusListRefreshInterval == 0 {
+		return s.config.StatusListValidity / 4
+	}
+	return s.config.StatusListRefreshInterval
+}
+
+// needsRefresh returns whether a status list credential would expire before the refresh after the next one, or has
+// no validity window even though one is configured.
+func (s Service) needsRefresh(statusListCredential StoredCredential, now time.Time) bool {
+	if statusListCredential.Credential == nil {
+		return false
+	}
+	if statusListCredential.Credential.ExpirationDate == "" {
+		return true
+	}
+	expiry, err := time.Parse(time.RFC3339, statusListCredential.Credential.ExpirationDate)
+	if err != nil {
+		logrus.WithError(err).Warnf("status list credential<%s> has an invalid expiration date", statusListCredential.Credential.ID)
+		return true
+	}
+	return expiry.Before(now.Add(2 * s.statusListRefreshInterval()))
+}
+
+// RunStatusListRefresher re-signs status list credentials before they expire, until the context is done. It returns
+// right away when status list credentials have no validity window.
+func (s Service) RunStatusListRefresher(ctx context.Context) {
+	if s.config.StatusListValidity == 0 {
+		return
+	}
+	ticker := time.NewTicker(s.statusListRefreshInterval())
+	defer ticker.Stop()
+	for {
+		refreshed, err := s.RefreshStatusListCredentials(ctx)
+		if err != nil {
+			logrus.WithError(err).Error("could not refresh status list credentials")
+		} else if refreshed > 0 {
+			logrus.Infof("re-signed %d status list credential(s)", refreshed)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RefreshStatusListCredentials re-signs the status list credentials that are close to expiring, and returns how many
+// were re-signed. A status list credential that cannot be re-signed doesn't stop the others from being re-signed.
+func (s Service) RefreshStatusListCredentials(ctx context.Context) (int, error) {
+	if s.config.StatusListValidity == 0 {
+		return 0, nil
+	}
+	watchKeys, err := s.storage.GetStatusListCredentialWatchKeys(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	refreshed := 0
+	ae := sdkutil.NewAppendError()
+	for _, watchKey := range watchKeys {
+		statusListCredential, err := s.storage.GetStatusListCredentialByWatchKey(ctx, watchKey)
+		if err != nil {
+			ae.Append(err)
+			continue
+		}
+		if !s.needsRefresh(*statusListCredential, time.Now()) {
+			continue
+		}
+		if _, err = s.storage.db.Execute(ctx, s.refreshStatusListCredentialFunc(watchKey), []storage.WatchKey{watchKey}); err != nil {
+			ae.Append(errors.Wrapf(err, "re-signing status list credential<%s>", statusListCredential.LocalCredentialID))
+			continue
+		}
+		refreshed++
+	}
+	return refreshed, ae.Error()
+}
+
+func (s Service) refreshStatusListCredentialFunc(watchKey storage.WatchKey) storage.BusinessLogicFunc {
+	return func(ctx context.Context, tx storage.Tx) (any, error) {
+		// read it again, since its status could have changed since it was found to need a refresh
+		statusListCredential, err := s.storage.GetStatusListCredentialByWatchKey(ctx, watchKey)
+		if err != nil {
+			return nil, err
+		}
+		refreshedCredential, err := credint.CopyCredential(*statusListCredential.Credential)
+		if err != nil {
+			return nil, sdkutil.LoggingErrorMsg(err, "could not copy status list credential")
+		}
+		statusListCredJWT, err := s.signStatusListCredential(ctx, statusListCredential.FullyQualifiedVerificationMethodID, refreshedCredential)
+		if err != nil {
+			return nil, sdkutil.LoggingErrorMsg(err, "could not sign status list credential")
+		}
+		storageRequest := StoreCredentialRequest{
+			Container: credint.Container{
+				ID:                                 statusListCredential.LocalCredentialID,
+				FullyQualifiedVerificationMethodID: statusListCredential.FullyQualifiedVerificationMethodID,
+				Credential:                         refreshedCredential,
+				CredentialJWT:                      statusListCredJWT,
+			},
+		}
+		return nil, s.storage.StoreStatusListCredentialTx(ctx, tx, storageRequest, StatusListCredentialMetadata{statusListCredentialWatchKey: watchKey})
+	}
+}
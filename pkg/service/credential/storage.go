@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math/rand"
 	"strings"
+	"time"
 
 	"github.com/TBD54566975/ssi-sdk/credential"
 	"github.com/TBD54566975/ssi-sdk/credential/integrity"
@@ -13,9 +14,11 @@ import (
 	"github.com/goccy/go-json"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"github.com/tbd54566975/ssi-service/config"
 	credint "github.com/tbd54566975/ssi-service/internal/credential"
 	"github.com/tbd54566975/ssi-service/internal/keyaccess"
 	"github.com/tbd54566975/ssi-service/internal/util"
+	"github.com/tbd54566975/ssi-service/internal/verification"
 	"github.com/tbd54566975/ssi-service/pkg/service/common"
 	"github.com/tbd54566975/ssi-service/pkg/storage"
 	"go.einride.tech/aip/filtering"
@@ -40,6 +43,8 @@ type StoredCredential struct {
 	// only one of these fields should be present
 	Credential    *credential.VerifiableCredential `json:"credential,omitempty"`
 	CredentialJWT *keyaccess.JWT                   `json:"token,omitempty"`
+	// set alongside the full Credential when the credential is secured as an SD-JWT
+	SDJWT *keyaccess.SDJWT `json:"sdJwt,omitempty"`
 
 	Issuer                             string `json:"issuer"`
 	FullyQualifiedVerificationMethodID string `json:"fullyQualifiedVerificationMethodId"`
@@ -48,6 +53,8 @@ type StoredCredential struct {
 	IssuanceDate                       string `json:"issuanceDate"`
 	Revoked                            bool   `json:"revoked"`
 	Suspended                          bool   `json:"suspended"`
+	StatusValue                        int    `json:"statusValue,omitempty"`
+	Expired                            bool   `json:"expired,omitempty"`
 }
 
 func (sc *StoredCredential) FilterVariablesMap() map[string]any {
@@ -55,9 +62,31 @@ func (sc *StoredCredential) FilterVariablesMap() map[string]any {
 		"issuer":  sc.Issuer,
 		"schema":  sc.Schema,
 		"subject": sc.Subject,
+		"expired": sc.HasExpired(time.Now()),
+		// "true" and "false" are parsed as identifiers, so we pass in the values that they evaluate to.
+		"true":  true,
+		"false": false,
 	}
 }
 
+// HasExpired returns whether the credential has passed its `expirationDate` at the given time. Credentials are
+// expired as soon as they pass it, even before the expiry sweep marks them as expired.
+func (sc *StoredCredential) HasExpired(now time.Time) bool {
+	return sc.Expired || sc.passedExpirationDate(now)
+}
+
+func (sc *StoredCredential) passedExpirationDate(now time.Time) bool {
+	if sc.Credential == nil || sc.Credential.ExpirationDate == "" {
+		return false
+	}
+	expiry, err := time.Parse(time.RFC3339, sc.Credential.ExpirationDate)
+	if err != nil {
+		logrus.WithError(err).Warnf("credential<%s> has an invalid expiration date", sc.LocalCredentialID)
+		return false
+	}
+	return !expiry.After(now)
+}
+
 type WriteContext struct {
 	namespace string
 	key       string
@@ -71,7 +100,7 @@ type StatusListCredentialMetadata struct {
 }
 
 func (sc *StoredCredential) IsValid() bool {
-	return sc.Key != "" && (sc.HasDataIntegrityCredential() || sc.HasJWTCredential())
+	return sc.Key != "" && (sc.HasDataIntegrityCredential() || sc.HasJWTCredential() || sc.HasSDJWTCredential())
 }
 
 func (sc *StoredCredential) HasDataIntegrityCredential() bool {
@@ -82,6 +111,10 @@ func (sc *StoredCredential) HasJWTCredential() bool {
 	return sc.CredentialJWT != nil
 }
 
+func (sc *StoredCredential) HasSDJWTCredential() bool {
+	return sc.SDJWT != nil
+}
+
 func (sc *StoredCredential) HasCredentialStatus() bool {
 	return sc != nil && sc.Credential != nil && sc.Credential.CredentialStatus != nil
 }
@@ -90,6 +123,18 @@ func (sc *StoredCredential) GetStatusPurpose() string {
 	return sc.Credential.CredentialStatus.(map[string]any)["statusPurpose"].(string)
 }
 
+// statusValue returns the credential's status value for the purpose of its status list.
+func (sc *StoredCredential) statusValue(purpose statussdk.StatusPurpose) int {
+	switch {
+	case purpose == statussdk.StatusRevocation && sc.Revoked, purpose == statussdk.StatusSuspension && sc.Suspended:
+		return 1
+	case purpose == credint.StatusMessage:
+		return sc.StatusValue
+	default:
+		return 0
+	}
+}
+
 const (
 	credentialNamespace                    = "credential"
 	statusListCredentialNamespace          = "status-list-credential"
@@ -106,8 +151,25 @@ type Storage struct {
 	db storage.ServiceStorage
 }
 
+// StatusListIndex tracks how many of the indexes of a status list are in use.
 type StatusListIndex struct {
 	Index int `json:"index"`
+	// The number of indexes of the status list. Lists created before sizes were configurable have none, and hold
+	// bitStringLength indexes.
+	Size int `json:"size,omitempty"`
+}
+
+// capacity returns the number of indexes of the status list.
+func (i StatusListIndex) capacity() int {
+	if i.Size == 0 {
+		return bitStringLength
+	}
+	return i.Size
+}
+
+// isFull returns whether every index of the status list is in use.
+func (i StatusListIndex) isFull() bool {
+	return i.Index >= i.capacity()
 }
 
 func NewCredentialStorage(db storage.ServiceStorage) (*Storage, error) {
@@ -133,17 +195,29 @@ func (cs *Storage) GetNextStatusListRandomIndex(ctx context.Context, slcMetadata
 		return -1, sdkutil.LoggingErrorMsgf(err, "unmarshalling unique numbers")
 	}
 
+	statusListIndex, err := cs.GetStatusListIndex(ctx, slcMetadata)
+	if err != nil {
+		return -1, err
+	}
+	if statusListIndex.Index >= len(uniqueNums) {
+		return -1, sdkutil.LoggingNewError("no more indexes available for status list index")
+	}
+
+	return uniqueNums[statusListIndex.Index], nil
+}
+
+// GetStatusListIndex returns how many of the indexes of the current status list are in use.
+func (cs *Storage) GetStatusListIndex(ctx context.Context, slcMetadata StatusListCredentialMetadata) (*StatusListIndex, error) {
 	gotCurrentListIndexBytes, err := cs.db.Read(ctx, slcMetadata.statusListCurrentIndexWatchKey.Namespace, slcMetadata.statusListCurrentIndexWatchKey.Key)
 	if err != nil {
-		return -1, sdkutil.LoggingErrorMsgf(err, "could not get list index")
+		return nil, sdkutil.LoggingErrorMsg(err, "could not get list index")
 	}
 
 	var statusListIndex StatusListIndex
 	if err = json.Unmarshal(gotCurrentListIndexBytes, &statusListIndex); err != nil {
-		return -1, sdkutil.LoggingErrorMsgf(err, "unmarshalling unique numbers")
+		return nil, sdkutil.LoggingErrorMsg(err, "unmarshalling status list index")
 	}
-
-	return uniqueNums[statusListIndex.Index], nil
+	return &statusListIndex, nil
 }
 
 func (cs *Storage) WriteMany(ctx context.Context, writeContexts []WriteContext) error {
@@ -161,21 +235,16 @@ func (cs *Storage) WriteMany(ctx context.Context, writeContexts []WriteContext)
 }
 
 func (cs *Storage) IncrementStatusListIndexTx(ctx context.Context, tx storage.Tx, slcMetadata StatusListCredentialMetadata) error {
-	gotCurrentListIndexBytes, err := cs.db.Read(ctx, slcMetadata.statusListCurrentIndexWatchKey.Namespace, slcMetadata.statusListCurrentIndexWatchKey.Key)
+	statusListIndex, err := cs.GetStatusListIndex(ctx, slcMetadata)
 	if err != nil {
-		return sdkutil.LoggingErrorMsg(err, "could not get list index")
+		return err
 	}
 
-	var statusListIndex StatusListIndex
-	if err = json.Unmarshal(gotCurrentListIndexBytes, &statusListIndex); err != nil {
-		return sdkutil.LoggingErrorMsg(err, "unmarshalling unique numbers")
-	}
-
-	if statusListIndex.Index >= bitStringLength-1 {
-		return sdkutil.LoggingErrorMsg(err, "no more indexes available for status list index")
+	if statusListIndex.isFull() {
+		return sdkutil.LoggingNewError("no more indexes available for status list index")
 	}
 
-	statusListIndexBytes, err := json.Marshal(StatusListIndex{Index: statusListIndex.Index + 1})
+	statusListIndexBytes, err := json.Marshal(StatusListIndex{Index: statusListIndex.Index + 1, Size: statusListIndex.Size})
 	if err != nil {
 		return sdkutil.LoggingErrorMsg(err, "could not marshal status list index bytes")
 	}
@@ -198,9 +267,9 @@ func (cs *Storage) StoreCredentialTx(ctx context.Context, tx storage.Tx, request
 
 // CreateStatusListCredentialTx creates a new status list credential with the provided metadata and stores it in the database as a transaction.
 // The function generates a unique random number and stores it along with the metadata in the database and then returns it
-func (cs *Storage) CreateStatusListCredentialTx(ctx context.Context, tx storage.Tx, request StoreCredentialRequest, slcMetadata StatusListCredentialMetadata) (int, error) {
+func (cs *Storage) CreateStatusListCredentialTx(ctx context.Context, tx storage.Tx, request StoreCredentialRequest, slcMetadata StatusListCredentialMetadata, size int) (int, error) {
 
-	randUniqueList := randomUniqueNum(bitStringLength)
+	randUniqueList := randomUniqueNum(size)
 	uniqueNumBytes, err := json.Marshal(randUniqueList)
 	if err != nil {
 		return -1, sdkutil.LoggingErrorMsg(err, "could not marshal random unique numbers")
@@ -211,7 +280,7 @@ func (cs *Storage) CreateStatusListCredentialTx(ctx context.Context, tx storage.
 	}
 
 	// Set the index to 1 since this is a new statusListCredential
-	statusListIndexBytes, err := json.Marshal(StatusListIndex{Index: 1})
+	statusListIndexBytes, err := json.Marshal(StatusListIndex{Index: 1, Size: size})
 	if err != nil {
 		return -1, sdkutil.LoggingErrorMsg(err, "could not marshal status list index bytes")
 	}
@@ -223,6 +292,78 @@ func (cs *Storage) CreateStatusListCredentialTx(ctx context.Context, tx storage.
 	return randUniqueList[0], cs.StoreStatusListCredentialTx(ctx, tx, request, slcMetadata)
 }
 
+// RollOverStatusListCredentialTx keeps a full status list credential, which is the current status list of its issuer,
+// schema and purpose, under its own keys, so that a new status list can take its place.
+func (cs *Storage) RollOverStatusListCredentialTx(ctx context.Context, tx storage.Tx, statusListCredential StoredCredential, slcMetadata StatusListCredentialMetadata) error {
+	statusListIndex, err := cs.GetStatusListIndex(ctx, slcMetadata)
+	if err != nil {
+		return err
+	}
+
+	storedCredBytes, err := json.Marshal(statusListCredential)
+	if err != nil {
+		return sdkutil.LoggingErrorMsgf(err, "could not marshal status list credential: %s", statusListCredential.LocalCredentialID)
+	}
+	credentialKey := getFullStatusListKey(slcMetadata.statusListCredentialWatchKey.Key, statusListCredential.LocalCredentialID)
+	if err = tx.Write(ctx, statusListCredentialNamespace, credentialKey, storedCredBytes); err != nil {
+		return sdkutil.LoggingErrorMsg(err, "problem writing full status list credential to db")
+	}
+
+	statusListIndexBytes, err := json.Marshal(statusListIndex)
+	if err != nil {
+		return sdkutil.LoggingErrorMsg(err, "could not marshal status list index bytes")
+	}
+	indexKey := getFullStatusListKey(slcMetadata.statusListCurrentIndexWatchKey.Key, statusListCredential.LocalCredentialID)
+	if err = tx.Write(ctx, statusListCredentialCurrentIndex, indexKey, statusListIndexBytes); err != nil {
+		return sdkutil.LoggingErrorMsg(err, "problem writing full status list index to db")
+	}
+	return nil
+}
+
+// StoredStatusListUsage is how many of the indexes of a status list credential are in use.
+type StoredStatusListUsage struct {
+	StatusListCredential StoredCredential
+	Used                 int
+	Capacity             int
+	// Whether new credentials get an index in this status list.
+	Current bool
+}
+
+// GetStatusListUsages returns the usage of every status list credential, including the full ones.
+func (cs *Storage) GetStatusListUsages(ctx context.Context) ([]StoredStatusListUsage, error) {
+	statusListCredentials, err := cs.db.ReadAll(ctx, statusListCredentialNamespace)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsg(err, "could not read status list credentials")
+	}
+	statusListIndexes, err := cs.db.ReadAll(ctx, statusListCredentialCurrentIndex)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsg(err, "could not read status list indexes")
+	}
+
+	usages := make([]StoredStatusListUsage, 0, len(statusListCredentials))
+	for key, credBytes := range statusListCredentials {
+		var cred StoredCredential
+		if err = json.Unmarshal(credBytes, &cred); err != nil {
+			logrus.WithError(err).Errorf("unmarshalling status list credential with key: %s", key)
+			continue
+		}
+		var statusListIndex StatusListIndex
+		if indexBytes, ok := statusListIndexes[key]; ok {
+			if err = json.Unmarshal(indexBytes, &statusListIndex); err != nil {
+				logrus.WithError(err).Errorf("unmarshalling status list index with key: %s", key)
+				continue
+			}
+		}
+		usages = append(usages, StoredStatusListUsage{
+			StatusListCredential: cred,
+			Used:                 statusListIndex.Index,
+			Capacity:             statusListIndex.capacity(),
+			Current:              !strings.HasSuffix(key, getFullStatusListKey("", cred.LocalCredentialID)),
+		})
+	}
+	return usages, nil
+}
+
 func (cs *Storage) StoreStatusListCredentialTx(ctx context.Context, tx storage.Tx, request StoreCredentialRequest, slcMetadata StatusListCredentialMetadata) error {
 	if !request.IsValid() {
 		return sdkutil.LoggingNewError("store request request is not valid")
@@ -276,6 +417,25 @@ func (cs *Storage) GetStatusListCredential(ctx context.Context, id string) (*Sto
 	return &storedCreds[0], nil
 }
 
+// GetStatusListCredentialByURI returns the status list credential the service hosts at the URI, or nil when the URI
+// isn't under the service's status base URL.
+func (cs *Storage) GetStatusListCredentialByURI(ctx context.Context, uri string) (*credential.VerifiableCredential, error) {
+	statusListPrefix := config.GetStatusBase() + "/"
+	if !strings.HasPrefix(uri, statusListPrefix) {
+		return nil, nil
+	}
+	storedCred, err := cs.GetStatusListCredential(ctx, strings.TrimPrefix(uri, statusListPrefix))
+	if err != nil {
+		return nil, err
+	}
+	if storedCred.Credential == nil {
+		return nil, errors.Errorf("status list credential<%s> has no credential", uri)
+	}
+	return storedCred.Credential, nil
+}
+
+var _ verification.StatusListCredentialStore = (*Storage)(nil)
+
 func (cs *Storage) getStoreCredentialWriteContext(request StoreCredentialRequest, namespace string) (*WriteContext, error) {
 	if !request.IsValid() {
 		return nil, sdkutil.LoggingNewError("store request request is not valid")
@@ -330,6 +490,7 @@ func buildStoredCredential(request StoreCredentialRequest) (*StoredCredential, e
 		LocalCredentialID:                  credID,
 		Credential:                         cred,
 		CredentialJWT:                      request.CredentialJWT,
+		SDJWT:                              request.SDJWT,
 		Issuer:                             issuer,
 		FullyQualifiedVerificationMethodID: request.FullyQualifiedVerificationMethodID,
 		Subject:                            subject,
@@ -337,6 +498,8 @@ func buildStoredCredential(request StoreCredentialRequest) (*StoredCredential, e
 		IssuanceDate:                       cred.IssuanceDate,
 		Revoked:                            request.Revoked,
 		Suspended:                          request.Suspended,
+		StatusValue:                        request.StatusValue,
+		Expired:                            request.Expired,
 	}, nil
 }
 
@@ -408,13 +571,15 @@ func (cs *Storage) GetCredentialsByIssuerAndSchema(ctx context.Context, issuer s
 	return cs.getCredentialsByIssuerAndSchema(ctx, issuer, schema, credentialNamespace)
 }
 
-func (cs *Storage) GetStatusListCredentialsByIssuerSchemaPurpose(ctx context.Context, issuer string, schema string, statusPurpose statussdk.StatusPurpose) ([]StoredCredential, error) {
+// GetStatusListCredentialsByIssuerSchemaPurpose gets the status list credentials of an issuer and schema, for a status
+// purpose as qualified by the status list's format.
+func (cs *Storage) GetStatusListCredentialsByIssuerSchemaPurpose(ctx context.Context, issuer string, schema string, statusPurpose string) ([]StoredCredential, error) {
 	keys, err := cs.db.ReadAllKeys(ctx, statusListCredentialNamespace)
 	if err != nil {
 		return nil, sdkutil.LoggingErrorMsgf(err, "could not read credential storage while searching for creds for issuer: %s", issuer)
 	}
 
-	query := storage.Join("sc", schema, "sp", string(statusPurpose))
+	query := storage.Join("sc", schema, "sp", statusPurpose)
 	var issuerSchemaKeys []string
 	for _, k := range keys {
 		if strings.Contains(k, issuer) && strings.HasSuffix(k, query) {
@@ -423,7 +588,7 @@ func (cs *Storage) GetStatusListCredentialsByIssuerSchemaPurpose(ctx context.Con
 	}
 
 	if len(issuerSchemaKeys) == 0 {
-		logrus.Warnf("no status list credentials found for issuer: %s schema %s and status purpose %s", util.SanitizeLog(issuer), util.SanitizeLog(schema), util.SanitizeLog(string(statusPurpose)))
+		logrus.Warnf("no status list credentials found for issuer: %s schema %s and status purpose %s", util.SanitizeLog(issuer), util.SanitizeLog(schema), util.SanitizeLog(statusPurpose))
 		return nil, nil
 	}
 
@@ -541,7 +706,42 @@ func (cs *Storage) GetStatusListCurrentIndexWatchKey(issuer, schema, statusPurpo
 	return storage.WatchKey{Namespace: statusListCredentialCurrentIndex, Key: getStatusListKey(issuer, schema, statusPurpose)}
 }
 
-func (cs *Storage) GetStatusListCredentialKeyData(ctx context.Context, issuer string, schema string, statusPurpose statussdk.StatusPurpose) (*StoredCredential, error) {
+// GetStatusListCredentialWatchKeys returns the watch keys of every status list credential, including the full ones.
+func (cs *Storage) GetStatusListCredentialWatchKeys(ctx context.Context) ([]storage.WatchKey, error) {
+	keys, err := cs.db.ReadAllKeys(ctx, statusListCredentialNamespace)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsg(err, "could not read status list credential keys")
+	}
+	watchKeys := make([]storage.WatchKey, 0, len(keys))
+	for _, key := range keys {
+		watchKeys = append(watchKeys, storage.WatchKey{Namespace: statusListCredentialNamespace, Key: key})
+	}
+	return watchKeys, nil
+}
+
+// GetStatusListCredentialByWatchKey returns the status list credential stored under a watch key.
+func (cs *Storage) GetStatusListCredentialByWatchKey(ctx context.Context, watchKey storage.WatchKey) (*StoredCredential, error) {
+	credBytes, err := cs.db.Read(ctx, watchKey.Namespace, watchKey.Key)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsgf(err, "could not read status list credential with key: %s", watchKey.Key)
+	}
+	if len(credBytes) == 0 {
+		return nil, sdkutil.LoggingNewErrorf("status list credential not found with key: %s", watchKey.Key)
+	}
+	var cred StoredCredential
+	if err = json.Unmarshal(credBytes, &cred); err != nil {
+		return nil, sdkutil.LoggingErrorMsgf(err, "unmarshalling status list credential with key: %s", watchKey.Key)
+	}
+	return &cred, nil
+}
+
+// GetFullStatusListCredentialWatchKey returns the watch key of a status list credential that is no longer the current
+// one of its issuer, schema and purpose.
+func (cs *Storage) GetFullStatusListCredentialWatchKey(issuer, schema, statusPurpose, id string) storage.WatchKey {
+	return storage.WatchKey{Namespace: statusListCredentialNamespace, Key: getFullStatusListKey(getStatusListKey(issuer, schema, statusPurpose), id)}
+}
+
+func (cs *Storage) GetStatusListCredentialKeyData(ctx context.Context, issuer string, schema string, statusPurpose string) (*StoredCredential, error) {
 	storedStatusListCreds, err := cs.GetStatusListCredentialsByIssuerSchemaPurpose(ctx, issuer, schema, statusPurpose)
 	if err != nil {
 		return nil, sdkutil.LoggingNewErrorf("getting status list credential for issuer: %s schema: %s", issuer, schema)
@@ -565,6 +765,11 @@ func getStatusListKey(issuer, schema, statusPurpose string) string {
 	return storage.Join("is", issuer, "sc", schema, "sp", statusPurpose)
 }
 
+// getFullStatusListKey returns the key of a status list that was rolled over, given the key of the current list.
+func getFullStatusListKey(statusListKey, id string) string {
+	return storage.Join(statusListKey, "id", id)
+}
+
 // unique key for a credential
 func createPrefixKey(id, issuer, subject, schema string) string {
 	return storage.Join(id, "is", issuer, "su", subject, "sc", schema)
@@ -573,7 +778,7 @@ func createPrefixKey(id, issuer, subject, schema string) string {
 func randomUniqueNum(count int) []int {
 	randomNumbers := make([]int, 0, count)
 
-	for i := 1; i <= count; i++ {
+	for i := 0; i < count; i++ {
 		randomNumbers = append(randomNumbers, i)
 	}
 
@@ -2,12 +2,16 @@ package did
 
 import (
 	"context"
+	"strconv"
+	"time"
 
 	didsdk "github.com/TBD54566975/ssi-sdk/did"
 	"github.com/TBD54566975/ssi-sdk/did/resolution"
 	"github.com/TBD54566975/ssi-sdk/util"
 	"github.com/pkg/errors"
+
 	"github.com/tbd54566975/ssi-service/pkg/service/common"
+	didresolution "github.com/tbd54566975/ssi-service/pkg/service/did/resolution"
 )
 
 // MethodHandler describes the functionality of *all* possible DID service, regardless of method
@@ -21,7 +25,6 @@ type MethodHandler interface {
 	CreateDID(ctx context.Context, request CreateDIDRequest) (*CreateDIDResponse, error)
 
 	// GetDID returns a DID document for a did who's method is `GetMethod`. The DID must not have been soft-deleted.
-	// TODO(gabe): support query parameters to get soft deleted and other DIDs https://github.com/TBD54566975/ssi-service/issues/364
 	GetDID(ctx context.Context, request GetDIDRequest) (*GetDIDResponse, error)
 
 	// ListDIDs returns all non-deleted DIDs for the given page. When page is nil, all non-deleted DIDs will be returned.
@@ -32,6 +35,23 @@ type MethodHandler interface {
 
 	// SoftDeleteDID marks the given DID as deleted. It is not removed from storage.
 	SoftDeleteDID(ctx context.Context, request DeleteDIDRequest) error
+
+	// RestoreDID clears the deleted mark of a DID that was soft-deleted.
+	RestoreDID(ctx context.Context, request RestoreDIDRequest) error
+}
+
+// MethodUpdater is implemented by the MethodHandlers of methods whose documents can be updated by the service.
+type MethodUpdater interface {
+	// UpdateDIDDocument applies the requested changes to the document of a DID whose method is `GetMethod`, and
+	// stores it as a new version.
+	UpdateDIDDocument(ctx context.Context, request UpdateDIDRequest) (*UpdateDIDResponse, error)
+}
+
+// MethodVersioner is implemented by the MethodHandlers of methods that keep the earlier versions of their DIDs'
+// documents. DIDs of other methods are resolved as having a single version.
+type MethodVersioner interface {
+	// GetDIDVersions returns every version of the document of a DID whose method is `GetMethod`, oldest first.
+	GetDIDVersions(ctx context.Context, id string) ([]DIDVersion, error)
 }
 
 // NewHandlerResolver creates a new HandlerResolver from a map of MethodHandlers which are used to resolve DIDs
@@ -61,7 +81,9 @@ type handlerResolver struct {
 	method  didsdk.Method
 }
 
-func (h handlerResolver) Resolve(ctx context.Context, did string, _ ...resolution.Option) (*resolution.Result, error) {
+// Resolve resolves a DID stored by the handler. When the handler is a MethodVersioner, the version of the document
+// requested in the resolution options is resolved, and its version is described in the document metadata.
+func (h handlerResolver) Resolve(ctx context.Context, did string, opts ...resolution.Option) (*resolution.Result, error) {
 	method, err := resolution.GetMethodForDID(did)
 	if err != nil {
 		return nil, errors.Wrap(err, "getting method from DID")
@@ -71,6 +93,41 @@ func (h handlerResolver) Resolve(ctx context.Context, did string, _ ...resolutio
 		return nil, errors.Errorf("invalid method %s for handler %s", method, h.method)
 	}
 
+	options := didresolution.GetOptions(opts...)
+	versioner, ok := h.handler.(MethodVersioner)
+	if !ok {
+		// documents that can't be updated only have a first version
+		if options.VersionID != "" && options.VersionID != "1" {
+			return nil, didresolution.NewError(didresolution.NotFoundCode, errors.Errorf("version<%s> of DID<%s> not found", options.VersionID, did))
+		}
+		return h.resolveLatest(ctx, did)
+	}
+
+	versions, err := versioner.GetDIDVersions(ctx, did)
+	if err != nil {
+		if !options.IsVersioned() {
+			// the handler may still resolve DIDs it doesn't store, such as did:ion DIDs from the network
+			return h.resolveLatest(ctx, did)
+		}
+		return nil, errors.Wrap(err, "getting DID versions from handler")
+	}
+	i, err := selectVersion(versions, options)
+	if err != nil {
+		return nil, errors.Wrapf(err, "resolving DID<%s>", did)
+	}
+	version := versions[i]
+	metadata := resolution.DocumentMetadata{
+		VersionID:   strconv.Itoa(version.Version),
+		Updated:     version.UpdatedAt,
+		Deactivated: version.Deactivated,
+	}
+	if i+1 < len(versions) {
+		metadata.NextVersionID = strconv.Itoa(versions[i+1].Version)
+	}
+	return &resolution.Result{Document: version.DID, DocumentMetadata: &metadata}, nil
+}
+
+func (h handlerResolver) resolveLatest(ctx context.Context, did string) (*resolution.Result, error) {
 	gotDIDResponse, err := h.handler.GetDID(ctx, GetDIDRequest{
 		Method: h.method,
 		ID:     did,
@@ -81,6 +138,39 @@ func (h handlerResolver) Resolve(ctx context.Context, did string, _ ...resolutio
 	return &resolution.Result{Document: gotDIDResponse.DID}, nil
 }
 
+// selectVersion returns the index of the version requested by the options: the one with the requested versionId, or
+// the last one updated at or before the requested versionTime. The latest version is selected by default.
+func selectVersion(versions []DIDVersion, options didresolution.Options) (int, error) {
+	if len(versions) == 0 {
+		return 0, didresolution.NewError(didresolution.NotFoundCode, errors.New("DID has no versions"))
+	}
+	switch {
+	case options.VersionID != "":
+		for i, version := range versions {
+			if strconv.Itoa(version.Version) == options.VersionID {
+				return i, nil
+			}
+		}
+		return 0, didresolution.NewError(didresolution.NotFoundCode, errors.Errorf("version<%s> not found", options.VersionID))
+	case options.VersionTime != "":
+		versionTime, err := options.GetVersionTime()
+		if err != nil {
+			return 0, didresolution.NewError(didresolution.InvalidOptionsCode, err)
+		}
+		// the first version is valid from when the DID was created, which isn't recorded
+		selected := 0
+		for i, version := range versions[1:] {
+			updatedAt, err := time.Parse(time.RFC3339, version.UpdatedAt)
+			if err != nil || updatedAt.After(versionTime) {
+				break
+			}
+			selected = i + 1
+		}
+		return selected, nil
+	}
+	return len(versions) - 1, nil
+}
+
 func (h handlerResolver) Methods() []didsdk.Method {
 	return []didsdk.Method{h.method}
 }
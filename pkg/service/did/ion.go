@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/TBD54566975/ssi-sdk/crypto"
 	"github.com/TBD54566975/ssi-sdk/crypto/jwx"
@@ -19,6 +20,7 @@ import (
 
 	"github.com/tbd54566975/ssi-service/pkg/service/common"
 	"github.com/tbd54566975/ssi-service/pkg/service/keystore"
+	"github.com/tbd54566975/ssi-service/pkg/service/operation/anchor"
 	"github.com/tbd54566975/ssi-service/pkg/storage"
 )
 
@@ -44,6 +46,10 @@ func NewIONHandler(baseURL string, s *Storage, ks *keystore.Service, factory key
 	return &ionHandler{
 		method:            did.IONMethod,
 		resolver:          r,
+		baseURL:           baseURL,
+		client:            http.DefaultClient,
+		retryBackoff:      defaultIONRetryBackoff,
+		maxRetryBackoff:   defaultIONMaxRetryBackoff,
 		storage:           s,
 		keyStore:          ks,
 		keyStoreFactory:   factory,
@@ -52,8 +58,15 @@ func NewIONHandler(baseURL string, s *Storage, ks *keystore.Service, factory key
 }
 
 type ionHandler struct {
-	method            did.Method
-	resolver          *ion.Resolver
+	method   did.Method
+	resolver *ion.Resolver
+	// baseURL of the ION node operations are submitted to, through client.
+	baseURL string
+	client  *http.Client
+	// retryBackoff is how long to wait before resubmitting an operation the ION node was unavailable for. It's doubled
+	// after each attempt, up to maxRetryBackoff.
+	retryBackoff      time.Duration
+	maxRetryBackoff   time.Duration
 	storage           *Storage
 	keyStore          *keystore.Service
 	keyStoreFactory   keystore.ServiceFactory
@@ -88,8 +101,10 @@ type ionStoredDID struct {
 	ID          string       `json:"id"`
 	DID         did.Document `json:"did"`
 	SoftDeleted bool         `json:"softDeleted"`
-	LongFormDID string       `json:"longFormDID"`
-	Operations  []any        `json:"operations"`
+	// Deactivated DIDs were permanently deactivated on the ION network, and cannot be changed anymore.
+	Deactivated bool   `json:"deactivated,omitempty"`
+	LongFormDID string `json:"longFormDID"`
+	Operations  []any  `json:"operations"`
 }
 
 func (i ionStoredDID) GetID() string {
@@ -104,11 +119,42 @@ func (i ionStoredDID) IsSoftDeleted() bool {
 	return i.SoftDeleted
 }
 
+// PreAnchor is an update, recover or deactivate operation of a DID, prepared to be anchored. Exactly one of
+// UpdateOperation, RecoverOperation and DeactivateOperation is set.
 type PreAnchor struct {
 	UpdateOperation        *ion.UpdateRequest
+	RecoverOperation       *ion.RecoverRequest
+	DeactivateOperation    *ion.DeactivateRequest
 	NextUpdatePublicJWK    *jwx.PublicKeyJWK
 	UpdatedDID             *ionStoredDID
 	NextUpdatePrivateJWKID string
+	// NextRecoveryPrivateJWKID is the ID of the staged recovery key that a recover operation rotates to.
+	NextRecoveryPrivateJWKID string
+}
+
+// operation returns the type of the prepared operation, and the operation to submit to the ION node.
+func (p PreAnchor) operation() (anchor.Type, any) {
+	switch {
+	case p.RecoverOperation != nil:
+		return anchor.TypeRecover, p.RecoverOperation
+	case p.DeactivateOperation != nil:
+		return anchor.TypeDeactivate, p.DeactivateOperation
+	default:
+		return anchor.TypeUpdate, p.UpdateOperation
+	}
+}
+
+// updateCommitment returns the update commitment the DID has once the prepared operation is published. Deactivated DIDs
+// have none.
+func (p PreAnchor) updateCommitment() string {
+	switch {
+	case p.UpdateOperation != nil:
+		return p.UpdateOperation.Delta.UpdateCommitment
+	case p.RecoverOperation != nil:
+		return p.RecoverOperation.Delta.UpdateCommitment
+	default:
+		return ""
+	}
 }
 
 type Anchor struct {
@@ -121,64 +167,117 @@ type updateState struct {
 	Status    UpdateRequestStatus
 	PreAnchor *PreAnchor
 	Anchor    *Anchor
+	// UpdatedAt is when the operation was applied to the stored DID, formatted as RFC3339.
+	UpdatedAt string
+	// OperationID is the ID of the operation that tracks the anchoring of the operation.
+	OperationID string
 }
 
+// prepareFunc prepares an operation of a stored DID, within the transaction the DID's update states are changed in.
+type prepareFunc func(ctx context.Context, tx storage.Tx, storedDID ionStoredDID) (*PreAnchor, error)
+
 func (h *ionHandler) UpdateDID(ctx context.Context, request UpdateIONDIDRequest) (*UpdateIONDIDResponse, error) {
 	if err := request.StateChange.IsValid(); err != nil {
 		return nil, errors.Wrap(err, "validating StateChange")
 	}
+	state, err := h.submitOperation(ctx, request.DID.String(), anchor.TypeUpdate, h.prepareUpdate(request))
+	if err != nil {
+		return nil, err
+	}
+	return &UpdateIONDIDResponse{
+		DID:         state.PreAnchor.UpdatedDID.DID,
+		OperationID: state.OperationID,
+	}, nil
+}
+
+// RecoverDID replaces the document state of a DID with a recover operation, which is signed with the DID's recovery key.
+// Both the recovery and update keys are rotated to fresh ones, so a compromised update key can be recovered from.
+func (h *ionHandler) RecoverDID(ctx context.Context, request RecoverIONDIDRequest) (*RecoverIONDIDResponse, error) {
+	if request.Document.IsEmpty() {
+		return nil, errors.New("document cannot be empty")
+	}
+	state, err := h.submitOperation(ctx, request.DID.String(), anchor.TypeRecover, h.prepareRecover(request))
+	if err != nil {
+		return nil, err
+	}
+	return &RecoverIONDIDResponse{
+		DID:         state.PreAnchor.UpdatedDID.DID,
+		OperationID: state.OperationID,
+	}, nil
+}
+
+// DeactivateDID permanently deactivates a DID with a deactivate operation, which is signed with the DID's recovery key.
+// Once the operation is anchored, the DID's update and recovery keys are revoked.
+func (h *ionHandler) DeactivateDID(ctx context.Context, request DeactivateIONDIDRequest) (*DeactivateIONDIDResponse, error) {
+	state, err := h.submitOperation(ctx, request.DID.String(), anchor.TypeDeactivate, h.prepareDeactivate(request))
+	if err != nil {
+		return nil, err
+	}
+	return &DeactivateIONDIDResponse{
+		DID:         state.PreAnchor.UpdatedDID.DID,
+		OperationID: state.OperationID,
+	}, nil
+}
 
-	updateStatesKey := request.DID.String()
+// submitOperation prepares an operation of a DID, and submits it to the ION node. Operations that get anchored are
+// applied to the stored DID. Those the ION node is unavailable for are queued, and resubmitted by ProcessOperations.
+// It returns the state of the operation.
+func (h *ionHandler) submitOperation(ctx context.Context, id string, opType anchor.Type, prepare prepareFunc) (*updateState, error) {
 	watchKeys := []storage.WatchKey{
 		{
 			Namespace: updateRequestStatesNamespace,
-			Key:       updateStatesKey,
+			Key:       id,
 		},
 	}
 
-	execResp, err := h.storage.db.Execute(ctx, h.prepareUpdate(request), watchKeys)
+	execResp, err := h.storage.db.Execute(ctx, h.prepareOperation(id, opType, prepare), watchKeys)
 	if err != nil {
 		return nil, errors.Wrapf(err, "executing transition to %s", PreAnchorStatus)
 	}
-	updateStates := execResp.([]updateState)
+	prepared := execResp.(preparedOperation)
+	if prepared.replacedOperationID != "" {
+		if err = h.supersedeAnchoring(ctx, prepared.replacedOperationID, opType); err != nil {
+			return nil, err
+		}
+	}
+	updateStates := prepared.updateStates
 	state := &updateStates[len(updateStates)-1]
 
 	if state.Status == PreAnchorStatus {
-		state.Anchor = new(Anchor)
-		_, err := h.resolver.Anchor(ctx, state.PreAnchor.UpdateOperation)
-		if err != nil {
-			// Signature errors are OK, as they mean that the update operation has already been applied. It means we haven't updated our updateKey to the latest one.
-			state.Anchor.Err = err.Error()
-			if isPreviouslyAnchoredError(err) {
-				state.Status = AnchoredStatus
-			} else {
-				state.Status = AnchorErrorStatus
-				if storeErr := h.storeUpdateStates(ctx, h.storage.db, request.DID.String(), updateStates); storeErr != nil {
-					return nil, storeErr
-				}
+		if state.OperationID != "" {
+			return nil, errors.Errorf("DID<%s> already has an operation queued by operation<%s>", id, state.OperationID)
+		}
+		opType, op := state.PreAnchor.operation()
+		anchoring := anchor.Anchoring{DID: id, Type: opType}
+		anchorErr := h.attemptAnchor(ctx, &anchoring, op)
+		setUpdateAnchoring(state, anchoring)
+		if anchorErr == nil {
+			state.OperationID = anchor.NewID()
+			if err := h.storeAnchoring(ctx, state.OperationID, anchoring); err != nil {
 				return nil, err
 			}
-		} else {
-			state.Status = AnchoredStatus
 		}
-		if err := h.storeUpdateStates(ctx, h.storage.db, request.DID.String(), updateStates); err != nil {
+		if err := h.storeUpdateStates(ctx, h.storage.db, id, updateStates); err != nil {
 			return nil, err
 		}
+		if anchorErr != nil {
+			return nil, anchorErr
+		}
+		if state.Status == PreAnchorStatus {
+			return state, nil
+		}
 	}
 
 	_, err = h.storage.db.Execute(ctx, h.applyUpdate(state.ID), watchKeys)
 	if err != nil {
 		return nil, errors.Wrapf(err, "executing transition to %s", DoneStatus)
 	}
-
-	return &UpdateIONDIDResponse{
-		DID: state.PreAnchor.UpdatedDID.DID,
-	}, nil
+	return state, nil
 }
 
 func (h *ionHandler) applyUpdate(id string) func(ctx context.Context, tx storage.Tx) (any, error) {
 	return func(ctx context.Context, tx storage.Tx) (any, error) {
-		updateStates, _, err := h.readUpdateStates(ctx, id)
+		updateStates, err := h.readUpdateStates(ctx, id)
 		if err != nil {
 			return nil, err
 		}
@@ -189,32 +288,36 @@ func (h *ionHandler) applyUpdate(id string) func(ctx context.Context, tx storage
 				return nil, errors.Wrap(err, "creating key store service")
 			}
 
-			gotKey, err := keyStore.GetKey(ctx, keystore.GetKeyRequest{ID: state.PreAnchor.NextUpdatePrivateJWKID})
-			if err != nil {
-				return nil, errors.Wrap(err, "getting key from keystore")
-			}
-			_, nextUpdatePrivateJWK, err := jwx.PrivateKeyToPrivateKeyJWK(gotKey.ID, gotKey.Key)
-			if err != nil {
-				return nil, errors.Wrap(err, "converting stored key to JWK")
+			preAnchor := state.PreAnchor
+			if preAnchor.NextUpdatePrivateJWKID != "" {
+				if err = promoteStagedKey(ctx, keyStore, preAnchor.NextUpdatePrivateJWKID, updateKeyID(state.ID), state.ID); err != nil {
+					return nil, errors.Wrap(err, "could not store did:ion update private key")
+				}
 			}
-
-			updateStoreRequest, err := keyToStoreRequest(updateKeyID(state.ID), *nextUpdatePrivateJWK, state.ID)
-			if err != nil {
-				return nil, errors.Wrap(err, "converting update private key to store request")
+			if preAnchor.NextRecoveryPrivateJWKID != "" {
+				if err = promoteStagedKey(ctx, keyStore, preAnchor.NextRecoveryPrivateJWKID, recoveryKeyID(state.ID), state.ID); err != nil {
+					return nil, errors.Wrap(err, "could not store did:ion recovery private key")
+				}
 			}
-			if err := keyStore.StoreKey(ctx, *updateStoreRequest); err != nil {
-				return nil, errors.Wrap(err, "could not store did:ion update private key")
+			if preAnchor.DeactivateOperation != nil {
+				// deactivated DIDs cannot be changed anymore, so the keys that controlled them are of no use
+				for _, keyID := range []string{updateKeyID(state.ID), recoveryKeyID(state.ID)} {
+					if err = keyStore.RevokeKey(ctx, keystore.RevokeKeyRequest{ID: keyID}); err != nil {
+						return nil, errors.Wrapf(err, "revoking key<%s>", keyID)
+					}
+				}
 			}
 
 			didStorage, err := h.didStorageFactory(tx)
 			if err != nil {
 				return nil, errors.Wrap(err, "creating did storage")
 			}
-			if err := didStorage.StoreDID(ctx, state.PreAnchor.UpdatedDID); err != nil {
+			if err := didStorage.StoreDID(ctx, preAnchor.UpdatedDID); err != nil {
 				return nil, errors.Wrap(err, "storing DID in storage")
 			}
 
 			state.Status = DoneStatus
+			state.UpdatedAt = time.Now().Format(time.RFC3339)
 			if err := h.storeUpdateStates(ctx, tx, state.ID, updateStates); err != nil {
 				return nil, err
 			}
@@ -223,92 +326,286 @@ func (h *ionHandler) applyUpdate(id string) func(ctx context.Context, tx storage
 	}
 }
 
-func (h *ionHandler) prepareUpdate(request UpdateIONDIDRequest) func(ctx context.Context, tx storage.Tx) (any, error) {
+// promoteStagedKey stores the staged private key with ID stagedKeyID as the key with ID keyID.
+func promoteStagedKey(ctx context.Context, keyStore *keystore.Service, stagedKeyID, keyID, controller string) error {
+	gotKey, err := keyStore.GetKey(ctx, keystore.GetKeyRequest{ID: stagedKeyID})
+	if err != nil {
+		return errors.Wrap(err, "getting key from keystore")
+	}
+	_, privateJWK, err := jwx.PrivateKeyToPrivateKeyJWK(gotKey.ID, gotKey.Key)
+	if err != nil {
+		return errors.Wrap(err, "converting stored key to JWK")
+	}
+	storeRequest, err := keyToStoreRequest(keyID, *privateJWK, controller)
+	if err != nil {
+		return errors.Wrap(err, "converting private key to store request")
+	}
+	return keyStore.StoreKey(ctx, *storeRequest)
+}
+
+// preparedOperation is the result of prepareOperation.
+type preparedOperation struct {
+	updateStates []updateState
+	// replacedOperationID is the ID of the operation that tracks the anchoring of the queued update that the prepared
+	// operation replaced, if any.
+	replacedOperationID string
+}
+
+// prepareOperation moves the state of a DID to PreAnchorStatus, with the operation of type opType made by prepare.
+// When the last operation of the DID is still in progress, and of the same type, its state is returned unchanged
+// instead. A recover or deactivate operation replaces an update that wasn't anchored yet, since it's signed with the
+// recovery key, such as when the update key was compromised. Otherwise, operations of another type are rejected.
+func (h *ionHandler) prepareOperation(id string, opType anchor.Type, prepare prepareFunc) storage.BusinessLogicFunc {
 	return func(ctx context.Context, tx storage.Tx) (any, error) {
-		updateStates, updatePrivateKey, err := h.readUpdateStates(ctx, request.DID.String())
+		updateStates, err := h.readUpdateStates(ctx, id)
 		if err != nil {
 			return nil, err
 		}
 		state := &updateStates[len(updateStates)-1]
 		if state.Status == DoneStatus || state.Status == AnchorErrorStatus {
 			updateStates = append(updateStates, updateState{
-				ID: request.DID.String(),
+				ID: id,
 			})
 			state = &updateStates[len(updateStates)-1]
 		}
+		var replacedOperationID string
+		if state.Status == PreAnchorStatus {
+			pendingType, _ := state.PreAnchor.operation()
+			switch {
+			case pendingType == opType:
+			case pendingType == anchor.TypeUpdate:
+				replacedOperationID = state.OperationID
+				*state = updateState{ID: id}
+			default:
+				return nil, errors.Errorf("DID<%s> already has a %s operation queued by operation<%s>", id, pendingType, state.OperationID)
+			}
+		}
 		if state.Status == "" {
-
-			didSuffix, err := request.DID.Suffix()
-			if err != nil {
-				return nil, errors.Wrap(err, "getting did suffix")
+			storedDID := new(ionStoredDID)
+			if err := h.storage.GetDID(ctx, id, storedDID); err != nil {
+				return nil, errors.Wrap(err, "getting ion did from storage")
 			}
-
-			updateKey := updatePrivateKey.ToPublicKeyJWK()
-			// ION does not like keys that have KID nor ALG. See https://github.com/decentralized-identity/sidetree-reference-impl/blob/bf1f7aeab251083cfb5ea5d612f481cd41f0ab1b/lib/core/versions/latest/util/Jwk.ts#L35
-			updateKey.ALG = ""
-			updateKey.KID = ""
-
-			signer, err := ion.NewBTCSignerVerifier(*updatePrivateKey)
-			if err != nil {
-				return nil, errors.Wrap(err, "creating btc signer verifier")
+			if storedDID.Deactivated {
+				return nil, errors.Errorf("DID<%s> is deactivated", id)
 			}
 
-			nextUpdateKey, nextUpdatePrivateKey, err := h.nextUpdateKey()
+			preAnchor, err := prepare(ctx, tx, *storedDID)
 			if err != nil {
 				return nil, err
 			}
-
-			updateOp, err := ion.NewUpdateRequest(didSuffix, updateKey, *nextUpdateKey, *signer, request.StateChange)
-			if err != nil {
-				return nil, errors.Wrap(err, "creating update request")
+			state.PreAnchor = preAnchor
+			state.Status = PreAnchorStatus
+			if err := h.storeUpdateStates(ctx, tx, id, updateStates); err != nil {
+				return nil, err
 			}
+		}
 
-			keyStore, err := h.keyStoreFactory(tx)
-			if err != nil {
-				return nil, errors.Wrap(err, "creating key store service")
-			}
-			storeRequestForUpdateKey, err := keyToStoreRequest("staging:"+request.DID.String(), *nextUpdatePrivateKey, request.DID.String())
-			if err != nil {
-				return nil, errors.Wrap(err, "converting update private key to store request")
-			}
-			if err := keyStore.StoreKey(ctx, *storeRequestForUpdateKey); err != nil {
-				return nil, errors.Wrap(err, "could not store did:ion update private key")
-			}
+		return preparedOperation{updateStates: updateStates, replacedOperationID: replacedOperationID}, nil
+	}
+}
 
-			storedDID := new(ionStoredDID)
-			if err := h.storage.GetDID(ctx, request.DID.String(), storedDID); err != nil {
-				return nil, errors.Wrap(err, "getting ion did from storage")
-			}
+// supersedeAnchoring fails the operation that tracks the anchoring of a queued update, once another operation replaced
+// the update, so that it isn't resubmitted.
+func (h *ionHandler) supersedeAnchoring(ctx context.Context, id string, opType anchor.Type) error {
+	anchoringBytes, err := h.storage.db.Read(ctx, unfinishedAnchoringsNamespace, id)
+	if err != nil {
+		return errors.Wrapf(err, "reading anchoring of operation<%s>", id)
+	}
+	var anchoring anchor.Anchoring
+	if len(anchoringBytes) > 0 {
+		if err = json.Unmarshal(anchoringBytes, &anchoring); err != nil {
+			return errors.Wrapf(err, "unmarshalling anchoring of operation<%s>", id)
+		}
+	}
+	anchoring.Status = anchor.StatusFailed
+	anchoring.NextAttemptAt = ""
+	anchoring.LastError = fmt.Sprintf("superseded by a %s operation", opType)
+	return h.storeAnchoring(ctx, id, anchoring)
+}
 
-			updatedLongForm, updatedDIDDoc, err := updateLongForm(request.DID.String(), storedDID.LongFormDID, updateOp)
-			if err != nil {
-				return nil, err
-			}
+func (h *ionHandler) prepareUpdate(request UpdateIONDIDRequest) prepareFunc {
+	return func(ctx context.Context, tx storage.Tx, storedDID ionStoredDID) (*PreAnchor, error) {
+		didSuffix, err := request.DID.Suffix()
+		if err != nil {
+			return nil, errors.Wrap(err, "getting did suffix")
+		}
 
-			updatedDID := &ionStoredDID{
-				ID:          storedDID.ID,
-				DID:         *updatedDIDDoc,
-				SoftDeleted: storedDID.SoftDeleted,
-				LongFormDID: updatedLongForm,
-				Operations:  append(storedDID.Operations, updateOp),
-			}
+		updatePrivateKey, err := h.readPrivateKey(ctx, updateKeyID(storedDID.ID))
+		if err != nil {
+			return nil, errors.Wrap(err, "reading update private key")
+		}
+		updateKey := updatePrivateKey.ToPublicKeyJWK()
+		// ION does not like keys that have KID nor ALG. See https://github.com/decentralized-identity/sidetree-reference-impl/blob/bf1f7aeab251083cfb5ea5d612f481cd41f0ab1b/lib/core/versions/latest/util/Jwk.ts#L35
+		updateKey.ALG = ""
+		updateKey.KID = ""
 
-			state.PreAnchor = &PreAnchor{
-				UpdateOperation:        updateOp,
-				UpdatedDID:             updatedDID,
-				NextUpdatePrivateJWKID: storeRequestForUpdateKey.ID,
-				NextUpdatePublicJWK:    nextUpdateKey,
-			}
-			state.Status = PreAnchorStatus
-			if err := h.storeUpdateStates(ctx, tx, request.DID.String(), updateStates); err != nil {
-				return nil, err
-			}
+		signer, err := ion.NewBTCSignerVerifier(*updatePrivateKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "creating btc signer verifier")
 		}
 
-		return updateStates, nil
+		nextUpdateKey, nextUpdatePrivateKey, err := h.nextKeyPair()
+		if err != nil {
+			return nil, err
+		}
+
+		updateOp, err := ion.NewUpdateRequest(didSuffix, updateKey, *nextUpdateKey, *signer, request.StateChange)
+		if err != nil {
+			return nil, errors.Wrap(err, "creating update request")
+		}
+
+		keyStore, err := h.keyStoreFactory(tx)
+		if err != nil {
+			return nil, errors.Wrap(err, "creating key store service")
+		}
+		storeRequestForUpdateKey, err := keyToStoreRequest(stagedKeyID(storedDID.ID), *nextUpdatePrivateKey, storedDID.ID)
+		if err != nil {
+			return nil, errors.Wrap(err, "converting update private key to store request")
+		}
+		if err := keyStore.StoreKey(ctx, *storeRequestForUpdateKey); err != nil {
+			return nil, errors.Wrap(err, "could not store did:ion update private key")
+		}
+
+		updatedLongForm, updatedDIDDoc, err := updateLongForm(storedDID.ID, storedDID.LongFormDID, updateOp)
+		if err != nil {
+			return nil, err
+		}
+
+		updatedDID := &ionStoredDID{
+			ID:          storedDID.ID,
+			DID:         *updatedDIDDoc,
+			SoftDeleted: storedDID.SoftDeleted,
+			LongFormDID: updatedLongForm,
+			Operations:  append(storedDID.Operations, updateOp),
+		}
+
+		return &PreAnchor{
+			UpdateOperation:        updateOp,
+			UpdatedDID:             updatedDID,
+			NextUpdatePrivateJWKID: storeRequestForUpdateKey.ID,
+			NextUpdatePublicJWK:    nextUpdateKey,
+		}, nil
+	}
+}
+
+func (h *ionHandler) prepareRecover(request RecoverIONDIDRequest) prepareFunc {
+	return func(ctx context.Context, tx storage.Tx, storedDID ionStoredDID) (*PreAnchor, error) {
+		didSuffix, err := request.DID.Suffix()
+		if err != nil {
+			return nil, errors.Wrap(err, "getting did suffix")
+		}
+		recoveryKey, signer, err := h.recoverySigner(ctx, storedDID.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		nextRecoveryKey, nextRecoveryPrivateKey, err := h.nextKeyPair()
+		if err != nil {
+			return nil, err
+		}
+		nextUpdateKey, nextUpdatePrivateKey, err := h.nextKeyPair()
+		if err != nil {
+			return nil, err
+		}
+
+		recoverOp, err := ion.NewRecoverRequest(didSuffix, *recoveryKey, *nextRecoveryKey, *nextUpdateKey, request.Document, *signer)
+		if err != nil {
+			return nil, errors.Wrap(err, "creating recover request")
+		}
+
+		keyStore, err := h.keyStoreFactory(tx)
+		if err != nil {
+			return nil, errors.Wrap(err, "creating key store service")
+		}
+		storeRequestForUpdateKey, err := keyToStoreRequest(stagedKeyID(storedDID.ID), *nextUpdatePrivateKey, storedDID.ID)
+		if err != nil {
+			return nil, errors.Wrap(err, "converting update private key to store request")
+		}
+		if err := keyStore.StoreKey(ctx, *storeRequestForUpdateKey); err != nil {
+			return nil, errors.Wrap(err, "could not store did:ion update private key")
+		}
+		storeRequestForRecoveryKey, err := keyToStoreRequest(stagedKeyID(recoveryKeyID(storedDID.ID)), *nextRecoveryPrivateKey, storedDID.ID)
+		if err != nil {
+			return nil, errors.Wrap(err, "converting recovery private key to store request")
+		}
+		if err := keyStore.StoreKey(ctx, *storeRequestForRecoveryKey); err != nil {
+			return nil, errors.Wrap(err, "could not store did:ion recovery private key")
+		}
+
+		recoveredLongForm, recoveredDIDDoc, err := recoverLongForm(storedDID.ID, storedDID.LongFormDID, recoverOp)
+		if err != nil {
+			return nil, err
+		}
+
+		recoveredDID := &ionStoredDID{
+			ID:          storedDID.ID,
+			DID:         *recoveredDIDDoc,
+			SoftDeleted: storedDID.SoftDeleted,
+			LongFormDID: recoveredLongForm,
+			Operations:  append(storedDID.Operations, recoverOp),
+		}
+
+		return &PreAnchor{
+			RecoverOperation:         recoverOp,
+			UpdatedDID:               recoveredDID,
+			NextUpdatePrivateJWKID:   storeRequestForUpdateKey.ID,
+			NextUpdatePublicJWK:      nextUpdateKey,
+			NextRecoveryPrivateJWKID: storeRequestForRecoveryKey.ID,
+		}, nil
+	}
+}
+
+func (h *ionHandler) prepareDeactivate(request DeactivateIONDIDRequest) prepareFunc {
+	return func(ctx context.Context, _ storage.Tx, storedDID ionStoredDID) (*PreAnchor, error) {
+		didSuffix, err := request.DID.Suffix()
+		if err != nil {
+			return nil, errors.Wrap(err, "getting did suffix")
+		}
+		recoveryKey, signer, err := h.recoverySigner(ctx, storedDID.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		deactivateOp, err := ion.NewDeactivateRequest(didSuffix, *recoveryKey, *signer)
+		if err != nil {
+			return nil, errors.Wrap(err, "creating deactivate request")
+		}
+
+		// deactivated DIDs resolve to a document without verification methods nor services
+		deactivatedDID := &ionStoredDID{
+			ID:          storedDID.ID,
+			DID:         did.Document{Context: storedDID.DID.Context, ID: storedDID.DID.ID},
+			SoftDeleted: storedDID.SoftDeleted,
+			Deactivated: true,
+			LongFormDID: storedDID.LongFormDID,
+			Operations:  append(storedDID.Operations, deactivateOp),
+		}
+
+		return &PreAnchor{
+			DeactivateOperation: deactivateOp,
+			UpdatedDID:          deactivatedDID,
+		}, nil
 	}
 }
 
+// recoverySigner returns the public recovery key of a DID, and a signer for its private recovery key.
+func (h *ionHandler) recoverySigner(ctx context.Context, id string) (*jwx.PublicKeyJWK, *ion.BTCSignerVerifier, error) {
+	recoveryPrivateKey, err := h.readPrivateKey(ctx, recoveryKeyID(id))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "reading recovery private key")
+	}
+	recoveryKey := recoveryPrivateKey.ToPublicKeyJWK()
+	// ION does not like keys that have KID nor ALG, see prepareUpdate
+	recoveryKey.ALG = ""
+	recoveryKey.KID = ""
+
+	signer, err := ion.NewBTCSignerVerifier(*recoveryPrivateKey)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "creating btc signer verifier")
+	}
+	return &recoveryKey, signer, nil
+}
+
 func updateLongForm(shortFormDID string, longFormDID string, updateOp *ion.UpdateRequest) (string, *did.Document, error) {
 	_, initialState, err := ion.DecodeLongFormDID(longFormDID)
 	if err != nil {
@@ -319,7 +616,21 @@ func updateLongForm(shortFormDID string, longFormDID string, updateOp *ion.Updat
 		Patches:          append(initialState.Delta.Patches, updateOp.Delta.GetPatches()...),
 		UpdateCommitment: updateOp.Delta.UpdateCommitment,
 	}
-	suffixData := initialState.SuffixData
+	return longFormFromDelta(shortFormDID, initialState.SuffixData, delta)
+}
+
+// recoverLongForm returns the long form of a DID whose document state was replaced by a recover operation. The suffix
+// data of the DID doesn't change, since it's what the DID is derived from.
+func recoverLongForm(shortFormDID string, longFormDID string, recoverOp *ion.RecoverRequest) (string, *did.Document, error) {
+	_, initialState, err := ion.DecodeLongFormDID(longFormDID)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "invalid long form DID")
+	}
+	return longFormFromDelta(shortFormDID, initialState.SuffixData, recoverOp.Delta)
+}
+
+// longFormFromDelta returns the long form of a DID with the given suffix data and delta, and its document.
+func longFormFromDelta(shortFormDID string, suffixData ion.SuffixData, delta ion.Delta) (string, *did.Document, error) {
 	createRequest := ion.CreateRequest{
 		Type:       ion.Create,
 		SuffixData: suffixData,
@@ -343,9 +654,66 @@ func updateLongForm(shortFormDID string, longFormDID string, updateOp *ion.Updat
 	return newLongFormDID, didDoc, nil
 }
 
-func isPreviouslyAnchoredError(_ error) bool {
-	// TODO: figure out how to determine this error from the body of the response.
-	return false
+var _ MethodVersioner = (*ionHandler)(nil)
+
+// GetDIDVersions returns every version of the document of a did:ion DID stored in the service, oldest first. The first
+// version is the document the DID was created with, and each update, recover or deactivate operation that was applied
+// makes a new version.
+func (h *ionHandler) GetDIDVersions(ctx context.Context, id string) ([]DIDVersion, error) {
+	storedDID := new(ionStoredDID)
+	if err := h.storage.GetDID(ctx, id, storedDID); err != nil {
+		return nil, errors.Wrap(err, "getting ion did from storage")
+	}
+	createdDID, err := createdDocument(*storedDID)
+	if err != nil {
+		return nil, err
+	}
+	versions := []DIDVersion{{Version: 1, DID: *createdDID}}
+
+	updateStatesBytes, err := h.storage.db.Read(ctx, updateRequestStatesNamespace, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading update states")
+	}
+	if updateStatesBytes == nil {
+		return versions, nil
+	}
+	var updateStates []updateState
+	if err = json.Unmarshal(updateStatesBytes, &updateStates); err != nil {
+		return nil, errors.Wrap(err, "unmarshalling update states")
+	}
+	for _, state := range updateStates {
+		if state.Status != DoneStatus || state.PreAnchor == nil || state.PreAnchor.UpdatedDID == nil {
+			continue
+		}
+		versions = append(versions, DIDVersion{
+			Version:     len(versions) + 1,
+			UpdatedAt:   state.UpdatedAt,
+			DID:         state.PreAnchor.UpdatedDID.DID,
+			Deactivated: state.PreAnchor.DeactivateOperation != nil,
+		})
+	}
+	return versions, nil
+}
+
+// createdDocument rebuilds the document a did:ion DID was created with from its create operation, which is the first
+// of its operations.
+func createdDocument(storedDID ionStoredDID) (*did.Document, error) {
+	if len(storedDID.Operations) == 0 {
+		return nil, errors.Errorf("DID<%s> has no create operation", storedDID.ID)
+	}
+	createOpBytes, err := json.Marshal(storedDID.Operations[0])
+	if err != nil {
+		return nil, errors.Wrap(err, "marshalling create operation")
+	}
+	var createOp ion.CreateRequest
+	if err = json.Unmarshal(createOpBytes, &createOp); err != nil {
+		return nil, errors.Wrap(err, "unmarshalling create operation")
+	}
+	didDoc, err := ion.PatchesToDIDDocument("unused", storedDID.ID, createOp.Delta.GetPatches())
+	if err != nil {
+		return nil, errors.Wrap(err, "patching the created did document")
+	}
+	return didDoc, nil
 }
 
 func (h *ionHandler) CreateDID(ctx context.Context, request CreateDIDRequest) (*CreateDIDResponse, error) {
@@ -423,8 +791,10 @@ func (h *ionHandler) CreateDID(ctx context.Context, request CreateDIDRequest) (*
 		return nil, errors.Wrap(err, "creating new ION DID")
 	}
 
-	// submit the create operation to the ION service
-	if _, err = h.resolver.Anchor(ctx, createOp); err != nil {
+	// submit the create operation to the ION service; when it's unavailable, the operation is queued, and resubmitted by
+	// ProcessOperations
+	anchoring := anchor.Anchoring{DID: ionDID.ID(), Type: anchor.TypeCreate}
+	if err = h.attemptAnchor(ctx, &anchoring, createOp); err != nil {
 		return nil, errors.Wrap(err, "anchoring create operation")
 	}
 
@@ -463,7 +833,12 @@ func (h *ionHandler) CreateDID(ctx context.Context, request CreateDIDRequest) (*
 		return nil, errors.Wrap(err, "could not store did:ion private key")
 	}
 
-	return &CreateDIDResponse{DID: *didDoc}, nil
+	operationID := anchor.NewID()
+	if err = h.storeAnchoring(ctx, operationID, anchoring); err != nil {
+		return nil, err
+	}
+
+	return &CreateDIDResponse{DID: *didDoc, OperationID: operationID}, nil
 }
 
 func (h *ionHandler) storeKeys(ctx context.Context, ionDID *ion.DID) error {
@@ -587,16 +962,33 @@ func (h *ionHandler) SoftDeleteDID(ctx context.Context, request DeleteDIDRequest
 	return h.storage.StoreDID(ctx, *gotDID)
 }
 
-func (h *ionHandler) readUpdatePrivateKey(ctx context.Context, did string) (*jwx.PrivateKeyJWK, error) {
-	keyID := updateKeyID(did)
+// RestoreDID clears the SoftDeleted flag of a did:ion DID. Like soft deletion, it has no effect on the network.
+func (h *ionHandler) RestoreDID(ctx context.Context, request RestoreDIDRequest) error {
+	logrus.Debugf("restoring DID: %+v", request)
+
+	id := request.ID
+	gotDID := new(ionStoredDID)
+	if err := h.storage.GetDID(ctx, id, gotDID); err != nil {
+		return errors.Wrapf(err, "getting DID: %s", id)
+	}
+	if !gotDID.IsSoftDeleted() {
+		return errors.Errorf("did with id<%s> is not deleted", id)
+	}
+
+	gotDID.SoftDeleted = false
+
+	return h.storage.StoreDID(ctx, *gotDID)
+}
+
+func (h *ionHandler) readPrivateKey(ctx context.Context, keyID string) (*jwx.PrivateKeyJWK, error) {
 	getKeyRequest := keystore.GetKeyRequest{ID: keyID}
 	key, err := h.keyStore.GetKey(ctx, getKeyRequest)
 	if err != nil {
-		return nil, errors.Wrap(err, "fetching update private key")
+		return nil, errors.Wrapf(err, "fetching private key<%s>", keyID)
 	}
 	_, privateJWK, err := jwx.PrivateKeyToPrivateKeyJWK(keyID, key.Key)
 	if err != nil {
-		return nil, errors.Wrap(err, "getting update private key")
+		return nil, errors.Wrapf(err, "getting private key<%s>", keyID)
 	}
 	return privateJWK, err
 }
@@ -609,42 +1001,42 @@ func recoveryKeyID(did string) string {
 	return did + "#" + recoverKeySuffix
 }
 
-func (h *ionHandler) nextUpdateKey() (*jwx.PublicKeyJWK, *jwx.PrivateKeyJWK, error) {
-	_, nextUpdatePrivateKey, err := crypto.GenerateSECP256k1Key()
+// stagedKeyID is the ID the next key of an operation is stored with until the operation is applied.
+func stagedKeyID(keyID string) string {
+	return "staging:" + keyID
+}
+
+// nextKeyPair generates the key pair that an operation commits to as the next update or recovery key.
+func (h *ionHandler) nextKeyPair() (*jwx.PublicKeyJWK, *jwx.PrivateKeyJWK, error) {
+	_, nextPrivateKey, err := crypto.GenerateSECP256k1Key()
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "generating next update keypair")
+		return nil, nil, errors.Wrap(err, "generating next keypair")
 	}
-	nextUpdatePubKeyJWK, nextUpdatePrivateKeyJWK, err := jwx.PrivateKeyToPrivateKeyJWK(uuid.NewString(), nextUpdatePrivateKey)
+	nextPubKeyJWK, nextPrivateKeyJWK, err := jwx.PrivateKeyToPrivateKeyJWK(uuid.NewString(), nextPrivateKey)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "converting next update key pair to JWK")
+		return nil, nil, errors.Wrap(err, "converting next key pair to JWK")
 	}
-	return nextUpdatePubKeyJWK, nextUpdatePrivateKeyJWK, nil
+	return nextPubKeyJWK, nextPrivateKeyJWK, nil
 }
 
 const updateRequestStatesNamespace = "update-request-states"
 
-func (h *ionHandler) readUpdateStates(ctx context.Context, id string) ([]updateState, *jwx.PrivateKeyJWK, error) {
-	privateUpdateJWK, err := h.readUpdatePrivateKey(ctx, id)
-	if err != nil {
-		return nil, nil, err
-	}
-
+func (h *ionHandler) readUpdateStates(ctx context.Context, id string) ([]updateState, error) {
 	readData, err := h.storage.db.Read(ctx, updateRequestStatesNamespace, id)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "reading update status")
+		return nil, errors.Wrap(err, "reading update status")
 	}
 	if readData == nil {
 		return []updateState{{
 			ID: id,
-		}}, privateUpdateJWK, nil
+		}}, nil
 	}
 	var statuses []updateState
 	if err := json.Unmarshal(readData, &statuses); err != nil {
-		return nil, nil, errors.Wrap(err, "unmarhsalling status array")
+		return nil, errors.Wrap(err, "unmarhsalling status array")
 	}
 
-	return statuses, privateUpdateJWK, nil
-
+	return statuses, nil
 }
 
 func (h *ionHandler) storeUpdateStates(ctx context.Context, tx storage.Tx, id string, states []updateState) error {
@@ -0,0 +1,407 @@
+package did
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/TBD54566975/ssi-sdk/did/resolution"
+	sdkutil "github.com/TBD54566975/ssi-sdk/util"
+	"github.com/goccy/go-json"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/tbd54566975/ssi-service/pkg/service/operation/anchor"
+	opstorage "github.com/tbd54566975/ssi-service/pkg/service/operation/storage"
+	opnamespace "github.com/tbd54566975/ssi-service/pkg/service/operation/storage/namespace"
+	"github.com/tbd54566975/ssi-service/pkg/storage"
+)
+
+const (
+	defaultIONRetryBackoff    = 10 * time.Second
+	defaultIONMaxRetryBackoff = 10 * time.Minute
+
+	// anchorClaimDuration is how long a pending ION operation that's being resubmitted is kept from being resubmitted
+	// by other instances of the service.
+	anchorClaimDuration = time.Minute
+
+	// unfinishedAnchoringsNamespace holds the anchoring of every ION operation that isn't published or failed yet,
+	// keyed by the ID of the operation that tracks it. It's what ProcessOperations works through, so that finished
+	// operations are never read again.
+	unfinishedAnchoringsNamespace = "ion-unfinished-anchorings"
+)
+
+// previouslyAnchoredErrorCodes are the Sidetree error codes an ION node rejects operations with when they, or another
+// operation for the same DID, were already submitted to it. Since the service custodies the DID, the operation is ours.
+// See https://github.com/decentralized-identity/sidetree/blob/master/lib/core/ErrorCode.ts
+var previouslyAnchoredErrorCodes = map[string]bool{
+	"queueing_multiple_operations_per_did_not_allowed": true,
+}
+
+// anchorError is returned when an ION node could not anchor an operation.
+type anchorError struct {
+	// StatusCode of the ION node's response. Zero when the ION node could not be reached.
+	StatusCode int
+	// Code is the Sidetree error code of the ION node's response, if any.
+	Code string
+	Err  error
+}
+
+func (e *anchorError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *anchorError) Unwrap() error {
+	return e.Err
+}
+
+// isRetryableAnchorError returns whether an operation could not be anchored because the ION node was unavailable.
+func isRetryableAnchorError(err error) bool {
+	var anchorErr *anchorError
+	if !errors.As(err, &anchorErr) {
+		return false
+	}
+	return anchorErr.StatusCode == 0 ||
+		anchorErr.StatusCode == http.StatusTooManyRequests ||
+		anchorErr.StatusCode >= http.StatusInternalServerError
+}
+
+// isPreviouslyAnchoredError returns whether an operation was rejected because it had already been submitted.
+func isPreviouslyAnchoredError(err error) bool {
+	var anchorErr *anchorError
+	return errors.As(err, &anchorErr) && previouslyAnchoredErrorCodes[anchorErr.Code]
+}
+
+// anchor submits an operation to the ION node. Failures are returned as an *anchorError.
+func (h *ionHandler) anchor(ctx context.Context, op any) error {
+	opBytes, err := json.Marshal(op)
+	if err != nil {
+		return errors.Wrap(err, "marshalling anchor operation")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.Join([]string{h.baseURL, "operations"}, "/"), bytes.NewReader(opBytes))
+	if err != nil {
+		return errors.Wrap(err, "creating anchor request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return &anchorError{Err: errors.Wrap(err, "posting anchor operation")}
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &anchorError{Err: errors.Wrap(err, "reading anchor response")}
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		var sidetreeErr struct {
+			Code string `json:"code"`
+		}
+		_ = json.Unmarshal(body, &sidetreeErr)
+		return &anchorError{
+			StatusCode: resp.StatusCode,
+			Code:       sidetreeErr.Code,
+			Err:        errors.Errorf("anchor operation failed with status %d: %s", resp.StatusCode, string(body)),
+		}
+	}
+	logrus.Infof("successfully anchored operation: %s", string(body))
+	return nil
+}
+
+// attemptAnchor submits an operation to the ION node, and records the outcome in anchoring. When the ION node is
+// unavailable, the operation stays pending until its next attempt, which is backed off exponentially. The error is only
+// returned when the ION node rejected the operation, in which case it failed.
+func (h *ionHandler) attemptAnchor(ctx context.Context, anchoring *anchor.Anchoring, op any) error {
+	now := time.Now()
+	anchoring.Attempts++
+	anchoring.NextAttemptAt = ""
+	err := h.anchor(ctx, op)
+	switch {
+	case err == nil || isPreviouslyAnchoredError(err):
+		anchoring.Status = anchor.StatusAnchored
+		anchoring.AnchoredAt = now.Format(time.RFC3339)
+		anchoring.LastError = ""
+		return nil
+	case isRetryableAnchorError(err):
+		anchoring.Status = anchor.StatusPending
+		anchoring.LastError = err.Error()
+		anchoring.NextAttemptAt = now.Add(h.retryBackoffFor(anchoring.Attempts)).Format(time.RFC3339)
+		logrus.WithError(err).Warnf("ION node unavailable, retrying %s operation for DID<%s> at %s", anchoring.Type, anchoring.DID, anchoring.NextAttemptAt)
+		return nil
+	default:
+		anchoring.Status = anchor.StatusFailed
+		anchoring.LastError = err.Error()
+		return err
+	}
+}
+
+// retryBackoffFor returns how long to wait before the next attempt, after the given number of attempts.
+func (h *ionHandler) retryBackoffFor(attempts int) time.Duration {
+	backoff := h.retryBackoff
+	for i := 1; i < attempts && backoff < h.maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > h.maxRetryBackoff {
+		return h.maxRetryBackoff
+	}
+	return backoff
+}
+
+// storeAnchoring stores the operation that tracks the anchoring of an ION operation. It's done once the ION operation
+// was published, or failed, at which point it's no longer processed by ProcessOperations.
+func (h *ionHandler) storeAnchoring(ctx context.Context, id string, anchoring anchor.Anchoring) error {
+	response, err := json.Marshal(anchoring)
+	if err != nil {
+		return errors.Wrap(err, "marshalling anchoring")
+	}
+	op := opstorage.StoredOperation{
+		ID:       id,
+		Done:     anchoring.Status == anchor.StatusPublished || anchoring.Status == anchor.StatusFailed,
+		Response: response,
+	}
+	if anchoring.Status == anchor.StatusFailed {
+		op.Error = anchoring.LastError
+	}
+	opBytes, err := json.Marshal(op)
+	if err != nil {
+		return errors.Wrapf(err, "marshalling operation<%s>", id)
+	}
+	db := h.storage.db
+	if err = db.Write(ctx, opnamespace.FromID(id), id, opBytes); err != nil {
+		return errors.Wrapf(err, "writing operation<%s>", id)
+	}
+
+	if !op.Done {
+		if err = db.Write(ctx, unfinishedAnchoringsNamespace, id, response); err != nil {
+			return errors.Wrapf(err, "writing anchoring of operation<%s>", id)
+		}
+		return nil
+	}
+	exists, err := db.Exists(ctx, unfinishedAnchoringsNamespace, id)
+	if err != nil {
+		return errors.Wrapf(err, "checking for anchoring of operation<%s>", id)
+	}
+	if !exists {
+		return nil
+	}
+	if err = db.Delete(ctx, unfinishedAnchoringsNamespace, id); err != nil {
+		return errors.Wrapf(err, "deleting anchoring of operation<%s>", id)
+	}
+	return nil
+}
+
+// ProcessOperations resubmits the pending ION operations that are due, and checks whether the anchored ones were
+// published. It returns the DIDs whose stored document changed because an update, recover or deactivate operation was
+// anchored. An operation that cannot be processed doesn't stop the others from being processed.
+func (h *ionHandler) ProcessOperations(ctx context.Context) ([]string, error) {
+	storedAnchorings, err := h.storage.db.ReadAll(ctx, unfinishedAnchoringsNamespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading ION operations")
+	}
+	ae := sdkutil.NewAppendError()
+	// the anchorings are all decoded before any is processed, since the bytes read may not outlive the writes that
+	// processing makes
+	anchorings := make(map[string]anchor.Anchoring, len(storedAnchorings))
+	for id, anchoringBytes := range storedAnchorings {
+		var anchoring anchor.Anchoring
+		if err = json.Unmarshal(anchoringBytes, &anchoring); err != nil {
+			ae.Append(errors.Wrapf(err, "unmarshalling anchoring of operation<%s>", id))
+			continue
+		}
+		anchorings[id] = anchoring
+	}
+
+	var updatedDIDs []string
+	for id, anchoring := range anchorings {
+		switch anchoring.Status {
+		case anchor.StatusPending:
+			claimed, err := h.claimAnchoring(ctx, id)
+			if err != nil {
+				ae.Append(errors.Wrapf(err, "claiming operation<%s>", id))
+				continue
+			}
+			if claimed == nil {
+				continue
+			}
+			updated, err := h.retryAnchor(ctx, id, *claimed)
+			if err != nil {
+				ae.Append(errors.Wrapf(err, "retrying operation<%s>", id))
+			}
+			if updated {
+				updatedDIDs = append(updatedDIDs, anchoring.DID)
+			}
+		case anchor.StatusAnchored:
+			if err = h.checkPublished(ctx, id, anchoring); err != nil {
+				ae.Append(errors.Wrapf(err, "checking whether operation<%s> was published", id))
+			}
+		}
+	}
+	return updatedDIDs, ae.Error()
+}
+
+// claimAnchoring claims a pending ION operation that's due to be resubmitted, by pushing its next attempt back by
+// anchorClaimDuration, so that other instances of the service don't resubmit it too. It returns nil when the operation
+// isn't pending or due anymore.
+func (h *ionHandler) claimAnchoring(ctx context.Context, id string) (*anchor.Anchoring, error) {
+	watchKeys := []storage.WatchKey{{Namespace: unfinishedAnchoringsNamespace, Key: id}}
+	claimed, err := h.storage.db.Execute(ctx, func(ctx context.Context, tx storage.Tx) (any, error) {
+		anchoringBytes, err := h.storage.db.Read(ctx, unfinishedAnchoringsNamespace, id)
+		if err != nil {
+			return nil, errors.Wrap(err, "reading anchoring")
+		}
+		if len(anchoringBytes) == 0 {
+			return (*anchor.Anchoring)(nil), nil
+		}
+		var anchoring anchor.Anchoring
+		if err = json.Unmarshal(anchoringBytes, &anchoring); err != nil {
+			return nil, errors.Wrap(err, "unmarshalling anchoring")
+		}
+		if anchoring.Status != anchor.StatusPending {
+			return (*anchor.Anchoring)(nil), nil
+		}
+		now := time.Now()
+		if nextAttemptAt, err := time.Parse(time.RFC3339, anchoring.NextAttemptAt); err == nil && now.Before(nextAttemptAt) {
+			return (*anchor.Anchoring)(nil), nil
+		}
+
+		claim := anchoring
+		claim.NextAttemptAt = now.Add(anchorClaimDuration).Format(time.RFC3339)
+		claimBytes, err := json.Marshal(claim)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshalling anchoring")
+		}
+		if err = tx.Write(ctx, unfinishedAnchoringsNamespace, id, claimBytes); err != nil {
+			return nil, errors.Wrap(err, "writing anchoring")
+		}
+		return &anchoring, nil
+	}, watchKeys)
+	if err != nil {
+		return nil, err
+	}
+	return claimed.(*anchor.Anchoring), nil
+}
+
+// retryAnchor resubmits a claimed pending ION operation. Update, recover and deactivate operations that get anchored
+// are applied to the stored DID, in which case true is returned.
+func (h *ionHandler) retryAnchor(ctx context.Context, id string, anchoring anchor.Anchoring) (bool, error) {
+	switch anchoring.Type {
+	case anchor.TypeCreate:
+		storedDID := new(ionStoredDID)
+		if err := h.storage.GetDID(ctx, anchoring.DID, storedDID); err != nil {
+			return false, errors.Wrap(err, "getting ion did from storage")
+		}
+		if len(storedDID.Operations) == 0 {
+			return false, errors.Errorf("DID<%s> has no create operation", anchoring.DID)
+		}
+		if err := h.attemptAnchor(ctx, &anchoring, storedDID.Operations[0]); err != nil {
+			logrus.WithError(err).Errorf("ION node rejected create operation for DID<%s>", anchoring.DID)
+		}
+		return false, h.storeAnchoring(ctx, id, anchoring)
+	case anchor.TypeUpdate, anchor.TypeRecover, anchor.TypeDeactivate:
+		return h.retryUpdate(ctx, id, anchoring)
+	default:
+		return false, errors.Errorf("unsupported ION operation type: %s", anchoring.Type)
+	}
+}
+
+// retryUpdate resubmits the queued update, recover or deactivate operation of a DID, and applies it to the stored DID
+// once it's anchored.
+func (h *ionHandler) retryUpdate(ctx context.Context, id string, anchoring anchor.Anchoring) (bool, error) {
+	updateStates, err := h.readUpdateStates(ctx, anchoring.DID)
+	if err != nil {
+		return false, err
+	}
+	state := &updateStates[len(updateStates)-1]
+	if state.OperationID != id || state.Status != PreAnchorStatus {
+		return false, errors.Errorf("DID<%s> has no operation<%s> queued", anchoring.DID, id)
+	}
+	_, op := state.PreAnchor.operation()
+	if err = h.attemptAnchor(ctx, &anchoring, op); err != nil {
+		logrus.WithError(err).Errorf("ION node rejected %s operation for DID<%s>", anchoring.Type, anchoring.DID)
+	}
+	setUpdateAnchoring(state, anchoring)
+	if err = h.storeUpdateStates(ctx, h.storage.db, anchoring.DID, updateStates); err != nil {
+		return false, err
+	}
+	if err = h.storeAnchoring(ctx, id, anchoring); err != nil {
+		return false, err
+	}
+	if state.Status != AnchoredStatus {
+		return false, nil
+	}
+	watchKeys := []storage.WatchKey{{Namespace: updateRequestStatesNamespace, Key: anchoring.DID}}
+	if _, err = h.storage.db.Execute(ctx, h.applyUpdate(anchoring.DID), watchKeys); err != nil {
+		return false, errors.Wrapf(err, "executing transition to %s", DoneStatus)
+	}
+	return true, nil
+}
+
+// setUpdateAnchoring moves the state of an update on according to the anchoring of its operation. Pending updates stay
+// in PreAnchorStatus, so that they can be resubmitted.
+func setUpdateAnchoring(state *updateState, anchoring anchor.Anchoring) {
+	state.Anchor = &Anchor{Err: anchoring.LastError}
+	switch anchoring.Status {
+	case anchor.StatusAnchored:
+		state.Status = AnchoredStatus
+	case anchor.StatusFailed:
+		state.Status = AnchorErrorStatus
+	}
+}
+
+// checkPublished resolves the DID of an anchored ION operation from the ION node, and marks the operation as done once
+// the resolved DID reflects it. DIDs cannot be resolved until their create operation is published, so resolution
+// errors only mean that the operation isn't published yet.
+func (h *ionHandler) checkPublished(ctx context.Context, id string, anchoring anchor.Anchoring) error {
+	resolved, err := h.resolver.Resolve(ctx, anchoring.DID)
+	if err != nil {
+		logrus.WithError(err).Debugf("DID<%s> of operation<%s> is not published yet", anchoring.DID, id)
+		return nil
+	}
+	published, err := h.isPublished(ctx, id, anchoring, resolved)
+	if err != nil || !published {
+		return err
+	}
+	anchoring.Status = anchor.StatusPublished
+	anchoring.PublishedAt = time.Now().Format(time.RFC3339)
+	return h.storeAnchoring(ctx, id, anchoring)
+}
+
+// isPublished returns whether a resolved DID reflects an ION operation. Deactivations are published once the DID
+// resolves as deactivated, at which point every earlier operation of the DID was published too. Updates and recoveries
+// are published once the DID's update commitment is the one made by the operation, or by a later one.
+func (h *ionHandler) isPublished(ctx context.Context, id string, anchoring anchor.Anchoring, resolved *resolution.Result) (bool, error) {
+	if resolved.DocumentMetadata == nil {
+		return false, nil
+	}
+	if anchoring.Type == anchor.TypeDeactivate || resolved.DocumentMetadata.Deactivated {
+		return resolved.DocumentMetadata.Deactivated, nil
+	}
+	if !resolved.DocumentMetadata.Method.Published {
+		return false, nil
+	}
+	if anchoring.Type == anchor.TypeCreate {
+		return true, nil
+	}
+	updateStates, err := h.readUpdateStates(ctx, anchoring.DID)
+	if err != nil {
+		return false, err
+	}
+	found := false
+	for _, state := range updateStates {
+		found = found || state.OperationID == id
+		if !found || state.PreAnchor == nil || state.PreAnchor.updateCommitment() == "" {
+			continue
+		}
+		if resolved.DocumentMetadata.Method.UpdateCommitment == state.PreAnchor.updateCommitment() {
+			return true, nil
+		}
+	}
+	if !found {
+		return false, errors.Errorf("DID<%s> has no state for operation<%s>", anchoring.DID, id)
+	}
+	return false, nil
+}
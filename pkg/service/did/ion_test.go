@@ -5,6 +5,7 @@ import (
 	_ "embed"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/TBD54566975/ssi-sdk/crypto"
 	"github.com/TBD54566975/ssi-sdk/crypto/jwx"
@@ -17,6 +18,7 @@ import (
 
 	"github.com/tbd54566975/ssi-service/config"
 	"github.com/tbd54566975/ssi-service/pkg/service/keystore"
+	"github.com/tbd54566975/ssi-service/pkg/service/operation/anchor"
 	"github.com/tbd54566975/ssi-service/pkg/storage"
 	"github.com/tbd54566975/ssi-service/pkg/testutil"
 )
@@ -284,6 +286,49 @@ func TestIONHandler(t *testing.T) {
 	}
 }
 
+func TestIONRetryBackoff(t *testing.T) {
+	h := ionHandler{retryBackoff: 10 * time.Second, maxRetryBackoff: time.Minute}
+	assert.Equal(t, 10*time.Second, h.retryBackoffFor(1))
+	assert.Equal(t, 20*time.Second, h.retryBackoffFor(2))
+	assert.Equal(t, 40*time.Second, h.retryBackoffFor(3))
+	assert.Equal(t, time.Minute, h.retryBackoffFor(4))
+	assert.Equal(t, time.Minute, h.retryBackoffFor(100))
+}
+
+func TestIONClaimAnchoring(t *testing.T) {
+	for _, test := range testutil.TestDatabases {
+		t.Run(test.Name, func(tt *testing.T) {
+			didStorage, err := NewDIDStorage(test.ServiceStorage(tt))
+			require.NoError(tt, err)
+			h := ionHandler{storage: didStorage}
+			ctx := context.Background()
+
+			id := anchor.NewID()
+			pending := anchor.Anchoring{DID: "did:ion:test", Type: anchor.TypeCreate, Status: anchor.StatusPending, Attempts: 1}
+			require.NoError(tt, h.storeAnchoring(ctx, id, pending))
+
+			// only one claim is granted while the operation is resubmitted
+			claimed, err := h.claimAnchoring(ctx, id)
+			require.NoError(tt, err)
+			require.NotNil(tt, claimed)
+			assert.Equal(tt, pending, *claimed)
+			claimed, err = h.claimAnchoring(ctx, id)
+			require.NoError(tt, err)
+			assert.Nil(tt, claimed)
+
+			// finished operations aren't processed anymore
+			pending.Status = anchor.StatusFailed
+			require.NoError(tt, h.storeAnchoring(ctx, id, pending))
+			unfinished, err := didStorage.db.ReadAll(ctx, unfinishedAnchoringsNamespace)
+			require.NoError(tt, err)
+			assert.Empty(tt, unfinished)
+			claimed, err = h.claimAnchoring(ctx, id)
+			require.NoError(tt, err)
+			assert.Nil(tt, claimed)
+		})
+	}
+}
+
 func testKeyStoreService(t *testing.T, db storage.ServiceStorage) *keystore.Service {
 	serviceConfig := new(config.KeyStoreServiceConfig)
 
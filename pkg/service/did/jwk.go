@@ -0,0 +1,156 @@
+package did
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/TBD54566975/ssi-sdk/crypto"
+	"github.com/TBD54566975/ssi-sdk/did"
+	"github.com/TBD54566975/ssi-sdk/did/jwk"
+	"github.com/mr-tron/base58"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/tbd54566975/ssi-service/pkg/service/common"
+	"github.com/tbd54566975/ssi-service/pkg/service/keystore"
+)
+
+func NewJWKHandler(s *Storage, ks *keystore.Service) (MethodHandler, error) {
+	if s == nil {
+		return nil, errors.New("storage cannot be empty")
+	}
+	if ks == nil {
+		return nil, errors.New("keystore cannot be empty")
+	}
+	return &jwkHandler{method: did.JWKMethod, storage: s, keyStore: ks}, nil
+}
+
+type jwkHandler struct {
+	method   did.Method
+	storage  *Storage
+	keyStore *keystore.Service
+}
+
+var _ MethodHandler = (*jwkHandler)(nil)
+
+func (h *jwkHandler) GetMethod() did.Method {
+	return h.method
+}
+
+func (h *jwkHandler) CreateDID(ctx context.Context, request CreateDIDRequest) (*CreateDIDResponse, error) {
+	logrus.Debugf("creating DID: %+v", request)
+
+	// create the DID
+	privKey, doc, err := jwk.GenerateDIDJWK(request.KeyType)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create did:jwk")
+	}
+
+	// expand it to the full docs for storage
+	expanded, err := doc.Expand()
+	if err != nil {
+		return nil, errors.Wrap(err, "error generating did:jwk document")
+	}
+
+	// store metadata in DID storage
+	id := doc.String()
+	storedDID := DefaultStoredDID{
+		ID:          id,
+		DID:         *expanded,
+		SoftDeleted: false,
+	}
+	if err = h.storage.StoreDID(ctx, storedDID); err != nil {
+		return nil, errors.Wrap(err, "could not store did:jwk value")
+	}
+
+	// convert to a serialized format for return to the client
+	privKeyBytes, err := crypto.PrivKeyToBytes(privKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not encode private key as base58")
+	}
+	privKeyBase58 := base58.Encode(privKeyBytes)
+
+	// store private key in key storage
+	keyStoreRequest := keystore.StoreKeyRequest{
+		ID:               expanded.VerificationMethod[0].ID,
+		Type:             request.KeyType,
+		Controller:       id,
+		PrivateKeyBase58: privKeyBase58,
+	}
+
+	if err = h.keyStore.StoreKey(ctx, keyStoreRequest); err != nil {
+		return nil, errors.Wrap(err, "could not store did:jwk private key")
+	}
+	return &CreateDIDResponse{DID: storedDID.DID}, nil
+}
+
+func (h *jwkHandler) GetDID(ctx context.Context, request GetDIDRequest) (*GetDIDResponse, error) {
+	logrus.Debugf("getting DID: %+v", request)
+
+	id := request.ID
+	gotDID, err := h.storage.GetDIDDefault(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting DID: %s", id)
+	}
+	if gotDID == nil {
+		return nil, fmt.Errorf("did with id<%s> could not be found", id)
+	}
+	return &GetDIDResponse{DID: gotDID.DID}, nil
+}
+
+func (h *jwkHandler) ListDIDs(ctx context.Context, page *common.Page) (*ListDIDsResponse, error) {
+	gotDIDs, err := h.storage.ListDIDsPage(ctx, did.JWKMethod.String(), page, new(DefaultStoredDID))
+	if err != nil {
+		return nil, errors.Wrap(err, "listing did:jwk DIDs page")
+	}
+	dids := make([]did.Document, 0, len(gotDIDs.DIDs))
+	for _, gotDID := range gotDIDs.DIDs {
+		if !gotDID.IsSoftDeleted() {
+			dids = append(dids, gotDID.GetDocument())
+		}
+	}
+	return &ListDIDsResponse{
+		DIDs:          dids,
+		NextPageToken: gotDIDs.NextPageToken,
+	}, nil
+}
+
+// ListDeletedDIDs returns only DIDs we have in storage for JWK with SoftDeleted flag set to true
+func (h *jwkHandler) ListDeletedDIDs(ctx context.Context) (*ListDIDsResponse, error) {
+	logrus.Debug("listing did:jwk DIDs")
+
+	gotDIDs, err := h.storage.ListDIDsDefault(ctx, did.JWKMethod.String())
+	if err != nil {
+		return nil, fmt.Errorf("error getting did:jwk DIDs")
+	}
+	dids := make([]did.Document, 0, len(gotDIDs))
+	for _, gotDID := range gotDIDs {
+		if gotDID.IsSoftDeleted() {
+			dids = append(dids, gotDID.GetDocument())
+		}
+	}
+	return &ListDIDsResponse{DIDs: dids}, nil
+}
+
+func (h *jwkHandler) SoftDeleteDID(ctx context.Context, request DeleteDIDRequest) error {
+	logrus.Debugf("soft deleting DID: %+v", request)
+
+	id := request.ID
+	gotStoredDID, err := h.storage.GetDIDDefault(ctx, id)
+	if err != nil {
+		return fmt.Errorf("error getting DID: %s", id)
+	}
+	if gotStoredDID == nil {
+		return fmt.Errorf("did with id<%s> could not be found", id)
+	}
+
+	gotStoredDID.SoftDeleted = true
+
+	return h.storage.StoreDID(ctx, *gotStoredDID)
+}
+
+// RestoreDID clears the SoftDeleted flag of a did:jwk DID.
+func (h *jwkHandler) RestoreDID(ctx context.Context, request RestoreDIDRequest) error {
+	logrus.Debugf("restoring DID: %+v", request)
+
+	return h.storage.RestoreDIDDefault(ctx, request.ID)
+}
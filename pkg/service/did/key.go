@@ -147,3 +147,10 @@ func (h *keyHandler) SoftDeleteDID(ctx context.Context, request DeleteDIDRequest
 
 	return h.storage.StoreDID(ctx, *gotStoredDID)
 }
+
+// RestoreDID clears the SoftDeleted flag of a did:key DID.
+func (h *keyHandler) RestoreDID(ctx context.Context, request RestoreDIDRequest) error {
+	logrus.Debugf("restoring DID: %+v", request)
+
+	return h.storage.RestoreDIDDefault(ctx, request.ID)
+}
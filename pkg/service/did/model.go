@@ -8,6 +8,7 @@ import (
 	"github.com/TBD54566975/ssi-sdk/did/ion"
 	"github.com/TBD54566975/ssi-sdk/did/resolution"
 	"github.com/tbd54566975/ssi-service/pkg/service/common"
+	didresolution "github.com/tbd54566975/ssi-service/pkg/service/did/resolution"
 )
 
 type GetSupportedMethodsResponse struct {
@@ -16,6 +17,9 @@ type GetSupportedMethodsResponse struct {
 
 type ResolveDIDRequest struct {
 	DID string `json:"did" validate:"required"`
+
+	// Options of the resolution, such as the version of the document to resolve.
+	Options didresolution.Options `json:"options"`
 }
 
 type ResolveDIDResponse struct {
@@ -24,6 +28,19 @@ type ResolveDIDResponse struct {
 	DIDDocumentMetadata *resolution.DocumentMetadata `json:"didDocumentMetadata,omitempty"`
 }
 
+type DereferenceDIDURLRequest struct {
+	DIDURL string `json:"didUrl" validate:"required"`
+
+	// Options of the resolution of the DID. The version of the document may also be requested in the DID URL.
+	Options didresolution.Options `json:"options"`
+}
+
+type DereferenceDIDURLResponse struct {
+	DereferencingMetadata didresolution.DereferencingMetadata `json:"dereferencingMetadata"`
+	ContentStream         any                                 `json:"contentStream"`
+	ContentMetadata       *resolution.DocumentMetadata        `json:"contentMetadata,omitempty"`
+}
+
 type CreateDIDRequestOptions interface {
 	Method() didsdk.Method
 }
@@ -38,6 +55,9 @@ type CreateDIDRequest struct {
 // CreateDIDResponse is the JSON-serializable response for creating a DID
 type CreateDIDResponse struct {
 	DID didsdk.Document `json:"did"`
+	// OperationID is the ID of the operation that tracks the anchoring of the DID, for methods that anchor DIDs such
+	// as ion.
+	OperationID string `json:"operationId,omitempty"`
 }
 
 type BatchCreateDIDsRequest struct {
@@ -71,6 +91,8 @@ type GetKeyFromDIDResponse struct {
 type ListDIDsRequest struct {
 	Method  didsdk.Method `json:"method" validate:"required"`
 	Deleted bool          `json:"deleted"`
+	// IncludeDeleted lists soft-deleted DIDs along with the others. Deleted takes precedence over it.
+	IncludeDeleted bool `json:"includeDeleted"`
 
 	PageRequest *common.Page
 }
@@ -86,6 +108,22 @@ type DeleteDIDRequest struct {
 	ID     string        `json:"id" validate:"required"`
 }
 
+type RestoreDIDRequest struct {
+	Method didsdk.Method `json:"method" validate:"required"`
+	ID     string        `json:"id" validate:"required"`
+}
+
+// PurgeDIDRequest permanently removes a soft-deleted DID, along with its keys.
+type PurgeDIDRequest struct {
+	Method didsdk.Method `json:"method" validate:"required"`
+	ID     string        `json:"id" validate:"required"`
+}
+
+type PurgeDIDResponse struct {
+	// IDs of the keys controlled by the DID, which were deleted from the keystore.
+	DeletedKeyIDs []string `json:"deletedKeyIds"`
+}
+
 type UpdateIONDIDRequest struct {
 	DID ion.ION `json:"did"`
 
@@ -94,6 +132,97 @@ type UpdateIONDIDRequest struct {
 
 type UpdateIONDIDResponse struct {
 	DID didsdk.Document `json:"did"`
+	// OperationID is the ID of the operation that tracks the anchoring of the update.
+	OperationID string `json:"operationId"`
+}
+
+// RecoverIONDIDRequest replaces the document state of a did:ion DID. Unlike updates, recoveries are signed with the
+// DID's recovery key, so they can be made even when its update key was compromised.
+type RecoverIONDIDRequest struct {
+	DID ion.ION `json:"did"`
+
+	// Document is the state that replaces the whole document state of the DID.
+	Document ion.Document `json:"document"`
+}
+
+type RecoverIONDIDResponse struct {
+	DID didsdk.Document `json:"did"`
+	// OperationID is the ID of the operation that tracks the anchoring of the recovery.
+	OperationID string `json:"operationId"`
+}
+
+// DeactivateIONDIDRequest permanently deactivates a did:ion DID.
+type DeactivateIONDIDRequest struct {
+	DID ion.ION `json:"did"`
+}
+
+type DeactivateIONDIDResponse struct {
+	DID didsdk.Document `json:"did"`
+	// OperationID is the ID of the operation that tracks the anchoring of the deactivation.
+	OperationID string `json:"operationId"`
+}
+
+// UpdateDIDRequest describes changes to the document of a DID whose keys are held by the service. Changes are applied
+// in the order the fields of DocumentStateChange are declared.
+type UpdateDIDRequest struct {
+	Method      didsdk.Method       `json:"method" validate:"required"`
+	ID          string              `json:"id" validate:"required"`
+	StateChange DocumentStateChange `json:"stateChange"`
+}
+
+// DocumentStateChange describes the changes to make to a DID document. Verification method IDs may be given in full,
+// or as a fragment relative to the DID.
+type DocumentStateChange struct {
+	ServicesToAdd      []didsdk.Service `json:"servicesToAdd,omitempty"`
+	ServiceIDsToRemove []string         `json:"serviceIdsToRemove,omitempty"`
+
+	// Verification methods to add. Their keys are generated in the keystore.
+	VerificationMethodsToAdd []VerificationMethodToAdd `json:"verificationMethodsToAdd,omitempty"`
+
+	// Verification methods to remove. Their keys are revoked in the keystore.
+	VerificationMethodIDsToRemove []string `json:"verificationMethodIdsToRemove,omitempty"`
+
+	// Verification relationships to set for existing verification methods. Each replaces all the relationships of its
+	// verification method.
+	VerificationRelationshipsToSet []VerificationRelationships `json:"verificationRelationshipsToSet,omitempty"`
+
+	// Verification methods whose keys are rotated. The keystore keeps the earlier versions of rotated keys.
+	VerificationMethodIDsToRotate []string `json:"verificationMethodIdsToRotate,omitempty"`
+}
+
+type VerificationMethodToAdd struct {
+	ID       string                 `json:"id" validate:"required"`
+	KeyType  crypto.KeyType         `json:"keyType" validate:"required"`
+	Purposes []ion.PublicKeyPurpose `json:"purposes,omitempty"`
+}
+
+type VerificationRelationships struct {
+	ID       string                 `json:"id" validate:"required"`
+	Purposes []ion.PublicKeyPurpose `json:"purposes"`
+}
+
+type UpdateDIDResponse struct {
+	DID didsdk.Document `json:"did"`
+
+	// Version of the document after the update.
+	Version int `json:"version"`
+}
+
+type GetDIDVersionsRequest struct {
+	ID string `json:"id" validate:"required"`
+}
+
+type GetDIDVersionsResponse struct {
+	// Every version of the DID document, oldest first.
+	Versions []DIDVersion `json:"versions"`
+}
+
+type DIDVersion struct {
+	Version   int             `json:"version"`
+	UpdatedAt string          `json:"updatedAt,omitempty"`
+	DID       didsdk.Document `json:"did"`
+	// Deactivated is set on the version a DID was permanently deactivated with, which is its last one.
+	Deactivated bool `json:"deactivated,omitempty"`
 }
 
 type UpdateRequestStatus string
@@ -0,0 +1,366 @@
+package did
+
+import (
+	"context"
+	gocrypto "crypto"
+	b64 "encoding/base64"
+	"fmt"
+
+	"github.com/TBD54566975/ssi-sdk/crypto"
+	"github.com/TBD54566975/ssi-sdk/crypto/jwx"
+	"github.com/TBD54566975/ssi-sdk/cryptosuite"
+	"github.com/TBD54566975/ssi-sdk/did"
+	"github.com/TBD54566975/ssi-sdk/did/peer"
+	"github.com/TBD54566975/ssi-sdk/util"
+	"github.com/goccy/go-json"
+	"github.com/mr-tron/base58"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/tbd54566975/ssi-service/pkg/service/common"
+	"github.com/tbd54566975/ssi-service/pkg/service/keystore"
+)
+
+const (
+	// PeerNumAlgoInceptionKey is the did:peer generation method for a DID with a single key, equivalent to a did:key.
+	PeerNumAlgoInceptionKey = 0
+	// PeerNumAlgoMultipleKeys is the did:peer generation method for a DID with separate authentication and key
+	// agreement keys, and service endpoints.
+	PeerNumAlgoMultipleKeys = 2
+)
+
+func NewPeerHandler(s *Storage, ks *keystore.Service) (MethodHandler, error) {
+	if s == nil {
+		return nil, errors.New("storage cannot be empty")
+	}
+	if ks == nil {
+		return nil, errors.New("keystore cannot be empty")
+	}
+	return &peerHandler{method: did.PeerMethod, storage: s, keyStore: ks}, nil
+}
+
+type peerHandler struct {
+	method   did.Method
+	storage  *Storage
+	keyStore *keystore.Service
+}
+
+var _ MethodHandler = (*peerHandler)(nil)
+
+// CreatePeerDIDOptions describes the did:peer to create, following
+// https://identity.foundation/peer-did-method-spec/#generation-method. When no options are given, a numalgo 0 DID is
+// created.
+type CreatePeerDIDOptions struct {
+	// The generation method of the DID: 0 for a single inception key, or 2 for separate authentication and key
+	// agreement keys, and service endpoints.
+	NumAlgo int `json:"numalgo" validate:"oneof=0 2"`
+
+	// Type of the key agreement key for numalgo 2. The request's key type is used for the authentication key.
+	// Defaults to X25519.
+	KeyAgreementKeyType crypto.KeyType `json:"keyAgreementKeyType,omitempty"`
+
+	// Services to encode in the DID for numalgo 2, such as a DIDCommMessaging endpoint. Service endpoints must be URIs.
+	// Service IDs aren't encoded in the DID, so the services are identified by their position: `#service`,
+	// `#service-1`, etc.
+	Services []did.Service `json:"services,omitempty"`
+}
+
+func (c CreatePeerDIDOptions) Method() did.Method {
+	return did.PeerMethod
+}
+
+func (h *peerHandler) GetMethod() did.Method {
+	return h.method
+}
+
+// peerKey is a key generated for a did:peer, along with the multibase encoding of its public key.
+type peerKey struct {
+	keyType    crypto.KeyType
+	publicKey  gocrypto.PublicKey
+	privateKey gocrypto.PrivateKey
+	encoded    string
+}
+
+func (h *peerHandler) CreateDID(ctx context.Context, request CreateDIDRequest) (*CreateDIDResponse, error) {
+	logrus.Debugf("creating DID: %+v", request)
+
+	var opts CreatePeerDIDOptions
+	if request.Options != nil {
+		var ok bool
+		opts, ok = request.Options.(CreatePeerDIDOptions)
+		if !ok || request.Options.Method() != did.PeerMethod {
+			return nil, fmt.Errorf("invalid options for method, expected %s, got %s", did.PeerMethod, request.Options.Method())
+		}
+		if err := util.IsValidStruct(opts); err != nil {
+			return nil, errors.Wrap(err, "processing options")
+		}
+	}
+
+	if request.KeyType == crypto.X25519 {
+		return nil, errors.Errorf("key type <%s> cannot be used for authentication", request.KeyType)
+	}
+	authenticationKey, err := generatePeerKey(request.KeyType)
+	if err != nil {
+		return nil, errors.Wrap(err, "generating authentication key")
+	}
+
+	var doc *did.Document
+	keys := make(map[string]peerKey)
+	switch opts.NumAlgo {
+	case PeerNumAlgoInceptionKey:
+		if opts.KeyAgreementKeyType != "" || len(opts.Services) > 0 {
+			return nil, errors.New("numalgo 0 peer DIDs cannot have a separate key agreement key or services")
+		}
+		doc, err = peerNumAlgo0Document(*authenticationKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "building numalgo 0 did:peer document")
+		}
+		keys[doc.VerificationMethod[0].ID] = *authenticationKey
+	case PeerNumAlgoMultipleKeys:
+		keyAgreementKeyType := opts.KeyAgreementKeyType
+		if keyAgreementKeyType == "" {
+			keyAgreementKeyType = crypto.X25519
+		}
+		if keyAgreementKeyType == crypto.Ed25519 {
+			return nil, errors.Errorf("key type <%s> cannot be used for key agreement", keyAgreementKeyType)
+		}
+		keyAgreementKey, err := generatePeerKey(keyAgreementKeyType)
+		if err != nil {
+			return nil, errors.Wrap(err, "generating key agreement key")
+		}
+		doc, err = peerNumAlgo2Document(*authenticationKey, *keyAgreementKey, opts.Services)
+		if err != nil {
+			return nil, errors.Wrap(err, "building numalgo 2 did:peer document")
+		}
+		keys[doc.VerificationMethod[0].ID] = *authenticationKey
+		keys[doc.VerificationMethod[1].ID] = *keyAgreementKey
+	}
+
+	// store metadata in DID storage
+	id := doc.ID
+	storedDID := DefaultStoredDID{
+		ID:          id,
+		DID:         *doc,
+		SoftDeleted: false,
+	}
+	if err = h.storage.StoreDID(ctx, storedDID); err != nil {
+		return nil, errors.Wrap(err, "could not store did:peer value")
+	}
+
+	// store private keys in key storage
+	for _, vm := range doc.VerificationMethod {
+		key := keys[vm.ID]
+		privKeyBytes, err := crypto.PrivKeyToBytes(key.privateKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not encode private key as base58")
+		}
+		keyStoreRequest := keystore.StoreKeyRequest{
+			ID:               vm.ID,
+			Type:             key.keyType,
+			Controller:       id,
+			PrivateKeyBase58: base58.Encode(privKeyBytes),
+		}
+		if err = h.keyStore.StoreKey(ctx, keyStoreRequest); err != nil {
+			return nil, errors.Wrap(err, "could not store did:peer private key")
+		}
+	}
+	return &CreateDIDResponse{DID: storedDID.DID}, nil
+}
+
+// generatePeerKey generates a key of a type that can be encoded in a did:peer.
+func generatePeerKey(keyType crypto.KeyType) (*peerKey, error) {
+	if !peer.IsSupportedDIDPeerType(keyType) {
+		return nil, errors.Errorf("key type <%s> not supported for did:peer", keyType)
+	}
+	pubKey, privKey, err := crypto.GenerateKeyByKeyType(keyType)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not generate key for did:peer")
+	}
+	// a numalgo 0 DID is made up of the multibase, multicodec encoding of its key, which numalgo 2 uses too
+	inception, err := peer.Method0{}.Generate(keyType, pubKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "encoding public key")
+	}
+	encoded, err := inception.Suffix()
+	if err != nil {
+		return nil, errors.Wrap(err, "encoding public key")
+	}
+	return &peerKey{keyType: keyType, publicKey: pubKey, privateKey: privKey, encoded: encoded}, nil
+}
+
+// peerNumAlgo0Document builds the document of the numalgo 0 did:peer of the given key, which is used for every
+// verification relationship. Ed25519 keys can't be used for key agreement.
+func peerNumAlgo0Document(key peerKey) (*did.Document, error) {
+	id := fmt.Sprintf("%s:%d%s", peer.DIDPeerPrefix, PeerNumAlgoInceptionKey, key.encoded)
+	vm, err := peerVerificationMethod(id, id+peer.Hash+key.encoded, key)
+	if err != nil {
+		return nil, err
+	}
+	references := []did.VerificationMethodSet{vm.ID}
+	doc := did.Document{
+		Context:              did.KnownDIDContext,
+		ID:                   id,
+		VerificationMethod:   []did.VerificationMethod{*vm},
+		Authentication:       references,
+		AssertionMethod:      references,
+		CapabilityInvocation: references,
+		CapabilityDelegation: references,
+	}
+	if key.keyType != crypto.Ed25519 {
+		doc.KeyAgreement = references
+	}
+	return &doc, nil
+}
+
+// peerNumAlgo2Document builds the document of the numalgo 2 did:peer with the given authentication and key
+// agreement keys, and services. The verification method IDs match the ones the SDK's did:peer resolver uses.
+func peerNumAlgo2Document(authenticationKey, keyAgreementKey peerKey, services []did.Service) (*did.Document, error) {
+	id := fmt.Sprintf("%s:%d.%s%s.%s%s", peer.DIDPeerPrefix, PeerNumAlgoMultipleKeys,
+		peer.PurposeVerificationCode, authenticationKey.encoded, peer.PurposeEncryptionCode, keyAgreementKey.encoded)
+	for i, service := range services {
+		encoded, err := encodePeerService(service)
+		if err != nil {
+			return nil, errors.Wrapf(err, "encoding service<%d>", i)
+		}
+		id += "." + string(peer.PurposeCapabilityServiceCode) + encoded
+	}
+	docServices := make([]did.Service, 0, len(services))
+	for i, service := range services {
+		service.ID = id + peer.Hash + "service"
+		if i > 0 {
+			service.ID = fmt.Sprintf("%s-%d", service.ID, i)
+		}
+		docServices = append(docServices, service)
+	}
+
+	authenticationVM, err := peerVerificationMethod(id, id+peer.Hash+authenticationKey.encoded[1:], authenticationKey)
+	if err != nil {
+		return nil, err
+	}
+	keyAgreementVM, err := peerVerificationMethod(id, id+peer.Hash+keyAgreementKey.encoded[1:], keyAgreementKey)
+	if err != nil {
+		return nil, err
+	}
+	authenticationReferences := []did.VerificationMethodSet{authenticationVM.ID}
+	return &did.Document{
+		Context:              did.KnownDIDContext,
+		ID:                   id,
+		VerificationMethod:   []did.VerificationMethod{*authenticationVM, *keyAgreementVM},
+		Authentication:       authenticationReferences,
+		AssertionMethod:      authenticationReferences,
+		CapabilityInvocation: authenticationReferences,
+		CapabilityDelegation: authenticationReferences,
+		KeyAgreement:         []did.VerificationMethodSet{keyAgreementVM.ID},
+		Services:             docServices,
+	}, nil
+}
+
+func peerVerificationMethod(id, vmID string, key peerKey) (*did.VerificationMethod, error) {
+	publicKeyJWK, err := jwx.PublicKeyToPublicKeyJWK(vmID, key.publicKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "converting public key to JWK")
+	}
+	return &did.VerificationMethod{
+		ID:           vmID,
+		Type:         cryptosuite.JSONWebKey2020Type,
+		Controller:   id,
+		PublicKeyJWK: publicKeyJWK,
+	}, nil
+}
+
+// encodePeerService encodes a service the way the SDK's did:peer resolver decodes it: as base64url encoded JSON, with
+// the DIDCommMessaging type abbreviated.
+func encodePeerService(service did.Service) (string, error) {
+	if service.Type == "" {
+		return "", errors.New("service type cannot be empty")
+	}
+	endpoint, ok := service.ServiceEndpoint.(string)
+	if !ok || endpoint == "" {
+		return "", errors.New("service endpoint must be a URI")
+	}
+	block := peer.ServiceBlockEncoded{
+		ServiceType:     service.Type,
+		ServiceEndpoint: endpoint,
+		RoutingKeys:     service.RoutingKeys,
+		Accept:          service.Accept,
+	}
+	if block.ServiceType == peer.DIDCommMessaging {
+		block.ServiceType = peer.DIDCommMessagingAbbr
+	}
+	blockBytes, err := json.Marshal(block)
+	if err != nil {
+		return "", errors.Wrap(err, "marshalling service")
+	}
+	return b64.RawURLEncoding.EncodeToString(blockBytes), nil
+}
+
+func (h *peerHandler) GetDID(ctx context.Context, request GetDIDRequest) (*GetDIDResponse, error) {
+	logrus.Debugf("getting DID: %+v", request)
+
+	id := request.ID
+	gotDID, err := h.storage.GetDIDDefault(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting DID: %s", id)
+	}
+	if gotDID == nil {
+		return nil, fmt.Errorf("did with id<%s> could not be found", id)
+	}
+	return &GetDIDResponse{DID: gotDID.DID}, nil
+}
+
+func (h *peerHandler) ListDIDs(ctx context.Context, page *common.Page) (*ListDIDsResponse, error) {
+	gotDIDs, err := h.storage.ListDIDsPage(ctx, did.PeerMethod.String(), page, new(DefaultStoredDID))
+	if err != nil {
+		return nil, errors.Wrap(err, "listing did:peer DIDs page")
+	}
+	dids := make([]did.Document, 0, len(gotDIDs.DIDs))
+	for _, gotDID := range gotDIDs.DIDs {
+		if !gotDID.IsSoftDeleted() {
+			dids = append(dids, gotDID.GetDocument())
+		}
+	}
+	return &ListDIDsResponse{
+		DIDs:          dids,
+		NextPageToken: gotDIDs.NextPageToken,
+	}, nil
+}
+
+// ListDeletedDIDs returns only DIDs we have in storage for Peer with SoftDeleted flag set to true
+func (h *peerHandler) ListDeletedDIDs(ctx context.Context) (*ListDIDsResponse, error) {
+	logrus.Debug("listing did:peer DIDs")
+
+	gotDIDs, err := h.storage.ListDIDsDefault(ctx, did.PeerMethod.String())
+	if err != nil {
+		return nil, fmt.Errorf("error getting did:peer DIDs")
+	}
+	dids := make([]did.Document, 0, len(gotDIDs))
+	for _, gotDID := range gotDIDs {
+		if gotDID.IsSoftDeleted() {
+			dids = append(dids, gotDID.GetDocument())
+		}
+	}
+	return &ListDIDsResponse{DIDs: dids}, nil
+}
+
+func (h *peerHandler) SoftDeleteDID(ctx context.Context, request DeleteDIDRequest) error {
+	logrus.Debugf("soft deleting DID: %+v", request)
+
+	id := request.ID
+	gotStoredDID, err := h.storage.GetDIDDefault(ctx, id)
+	if err != nil {
+		return fmt.Errorf("error getting DID: %s", id)
+	}
+	if gotStoredDID == nil {
+		return fmt.Errorf("did with id<%s> could not be found", id)
+	}
+
+	gotStoredDID.SoftDeleted = true
+
+	return h.storage.StoreDID(ctx, *gotStoredDID)
+}
+
+// RestoreDID clears the SoftDeleted flag of a did:peer DID.
+func (h *peerHandler) RestoreDID(ctx context.Context, request RestoreDIDRequest) error {
+	logrus.Debugf("restoring DID: %+v", request)
+
+	return h.storage.RestoreDIDDefault(ctx, request.ID)
+}
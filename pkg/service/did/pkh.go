@@ -0,0 +1,320 @@
+package did
+
+import (
+	"context"
+	gocrypto "crypto"
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/TBD54566975/ssi-sdk/crypto"
+	"github.com/TBD54566975/ssi-sdk/crypto/jwx"
+	"github.com/TBD54566975/ssi-sdk/cryptosuite"
+	"github.com/TBD54566975/ssi-sdk/did"
+	"github.com/TBD54566975/ssi-sdk/did/pkh"
+	"github.com/TBD54566975/ssi-sdk/util"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/mr-tron/base58"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/sha3"
+
+	"github.com/tbd54566975/ssi-service/pkg/service/common"
+	"github.com/tbd54566975/ssi-service/pkg/service/keystore"
+)
+
+const (
+	// EIP155Namespace is the CAIP-2 namespace of EVM chains, whose accounts are backed by secp256k1 keys.
+	EIP155Namespace = "eip155"
+	// SolanaNamespace is the CAIP-2 namespace of Solana, whose accounts are backed by Ed25519 keys.
+	SolanaNamespace = "solana"
+
+	eip155MainnetReference = "1"
+	solanaMainnetReference = "5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp"
+)
+
+func NewPKHHandler(s *Storage, ks *keystore.Service) (MethodHandler, error) {
+	if s == nil {
+		return nil, errors.New("storage cannot be empty")
+	}
+	if ks == nil {
+		return nil, errors.New("keystore cannot be empty")
+	}
+	return &pkhHandler{method: did.PKHMethod, storage: s, keyStore: ks}, nil
+}
+
+type pkhHandler struct {
+	method   did.Method
+	storage  *Storage
+	keyStore *keystore.Service
+}
+
+var _ MethodHandler = (*pkhHandler)(nil)
+
+// CreatePKHDIDOptions describes the blockchain account of the did:pkh to create, as a CAIP-2 chain ID. The account's
+// address is derived from the key generated for the DID.
+type CreatePKHDIDOptions struct {
+	// Namespace of the chain. `eip155` accounts need a secp256k1 key, and `solana` accounts need an Ed25519 key.
+	Namespace string `json:"namespace" validate:"required,oneof=eip155 solana"`
+
+	// Reference of the chain within its namespace, such as `137` for Polygon. Defaults to the namespace's mainnet.
+	Reference string `json:"reference,omitempty"`
+}
+
+func (c CreatePKHDIDOptions) Method() did.Method {
+	return did.PKHMethod
+}
+
+func (h *pkhHandler) GetMethod() did.Method {
+	return h.method
+}
+
+func (h *pkhHandler) CreateDID(ctx context.Context, request CreateDIDRequest) (*CreateDIDResponse, error) {
+	logrus.Debugf("creating DID: %+v", request)
+
+	// process options
+	if request.Options == nil {
+		return nil, errors.New("options cannot be empty")
+	}
+	opts, ok := request.Options.(CreatePKHDIDOptions)
+	if !ok || request.Options.Method() != did.PKHMethod {
+		return nil, fmt.Errorf("invalid options for method, expected %s, got %s", did.PKHMethod, request.Options.Method())
+	}
+	if err := util.IsValidStruct(opts); err != nil {
+		return nil, errors.Wrap(err, "processing options")
+	}
+
+	reference := opts.Reference
+	var vmFragment string
+	var vmType cryptosuite.LDKeyType
+	switch opts.Namespace {
+	case EIP155Namespace:
+		if request.KeyType != crypto.SECP256k1 {
+			return nil, errors.Errorf("key type <%s> not supported for %s accounts", request.KeyType, opts.Namespace)
+		}
+		if reference == "" {
+			reference = eip155MainnetReference
+		}
+		vmFragment = "#blockchainAccountId"
+		vmType = pkh.ECDSASECP256k1RecoveryMethod2020
+	case SolanaNamespace:
+		if request.KeyType != crypto.Ed25519 {
+			return nil, errors.Errorf("key type <%s> not supported for %s accounts", request.KeyType, opts.Namespace)
+		}
+		if reference == "" {
+			reference = solanaMainnetReference
+		}
+		vmFragment = "#controller"
+		vmType = cryptosuite.Ed25519VerificationKey2018
+	}
+
+	pubKey, privKey, err := crypto.GenerateKeyByKeyType(request.KeyType)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not generate key for did:pkh")
+	}
+	address, err := accountAddress(opts.Namespace, pubKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "deriving account address")
+	}
+	didPKH, err := pkh.CreateDIDPKH(opts.Namespace, reference, address)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create did:pkh")
+	}
+
+	doc, err := pkhDocument(*didPKH, vmFragment, vmType, pubKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "error generating did:pkh document")
+	}
+
+	// store metadata in DID storage
+	id := didPKH.String()
+	exists, err := h.storage.DIDExists(ctx, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error getting DID: %s", id)
+	}
+	if exists {
+		return nil, fmt.Errorf("did with id<%s> already exists", id)
+	}
+	storedDID := DefaultStoredDID{
+		ID:          id,
+		DID:         *doc,
+		SoftDeleted: false,
+	}
+	if err = h.storage.StoreDID(ctx, storedDID); err != nil {
+		return nil, errors.Wrap(err, "could not store did:pkh value")
+	}
+
+	// convert to a serialized format for return to the client
+	privKeyBytes, err := crypto.PrivKeyToBytes(privKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not encode private key as base58")
+	}
+	privKeyBase58 := base58.Encode(privKeyBytes)
+
+	// store private key in key storage
+	keyStoreRequest := keystore.StoreKeyRequest{
+		ID:               doc.VerificationMethod[0].ID,
+		Type:             request.KeyType,
+		Controller:       id,
+		PrivateKeyBase58: privKeyBase58,
+	}
+
+	if err = h.keyStore.StoreKey(ctx, keyStoreRequest); err != nil {
+		return nil, errors.Wrap(err, "could not store did:pkh private key")
+	}
+	return &CreateDIDResponse{DID: storedDID.DID}, nil
+}
+
+// accountAddress derives the address of the account a public key controls in a chain namespace. EVM addresses are
+// the last 20 bytes of the Keccak-256 hash of the uncompressed key, with the EIP-55 checksum. Solana addresses are
+// the base58 encoded key.
+func accountAddress(namespace string, pubKey gocrypto.PublicKey) (string, error) {
+	switch namespace {
+	case EIP155Namespace:
+		secpPubKey, ok := pubKey.(secp256k1.PublicKey)
+		if !ok {
+			return "", errors.New("eip155 accounts need a secp256k1 key")
+		}
+		hash := sha3.NewLegacyKeccak256()
+		hash.Write(secpPubKey.SerializeUncompressed()[1:])
+		return eip55Checksum(hex.EncodeToString(hash.Sum(nil)[12:])), nil
+	case SolanaNamespace:
+		edPubKey, ok := pubKey.(ed25519.PublicKey)
+		if !ok {
+			return "", errors.New("solana accounts need an Ed25519 key")
+		}
+		return base58.Encode(edPubKey), nil
+	}
+	return "", errors.Errorf("unsupported namespace: %s", namespace)
+}
+
+// eip55Checksum capitalizes the letters of a lowercase hex address according to
+// https://eips.ethereum.org/EIPS/eip-55, and adds the 0x prefix.
+func eip55Checksum(address string) string {
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write([]byte(address))
+	addressHash := hex.EncodeToString(hash.Sum(nil))
+	var checksummed strings.Builder
+	checksummed.WriteString("0x")
+	for i, c := range address {
+		if c >= 'a' && c <= 'f' && addressHash[i] >= '8' {
+			c -= 'a' - 'A'
+		}
+		checksummed.WriteRune(c)
+	}
+	return checksummed.String()
+}
+
+// pkhDocument builds the document of a did:pkh. Unlike the documents the SDK's did:pkh resolver builds, the
+// verification method includes the public key, so that credentials issued by the DID can be verified without
+// recovering the key from their signatures.
+func pkhDocument(didPKH pkh.PKH, vmFragment string, vmType cryptosuite.LDKeyType, pubKey gocrypto.PublicKey) (*did.Document, error) {
+	id := didPKH.String()
+	accountID, err := didPKH.Suffix()
+	if err != nil {
+		return nil, err
+	}
+	contextJSON, err := pkh.GetDIDPKHContext()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get known context json")
+	}
+	didContext, err := util.ToJSONInterface(contextJSON)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not convert known context to json")
+	}
+
+	vmID := id + vmFragment
+	publicKeyJWK, err := jwx.PublicKeyToPublicKeyJWK(vmID, pubKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "converting public key to JWK")
+	}
+	references := []did.VerificationMethodSet{vmID}
+	return &did.Document{
+		Context: didContext,
+		ID:      id,
+		VerificationMethod: []did.VerificationMethod{{
+			ID:                  vmID,
+			Type:                vmType,
+			Controller:          id,
+			PublicKeyJWK:        publicKeyJWK,
+			BlockchainAccountID: accountID,
+		}},
+		Authentication:       references,
+		AssertionMethod:      references,
+		CapabilityInvocation: references,
+		CapabilityDelegation: references,
+	}, nil
+}
+
+func (h *pkhHandler) GetDID(ctx context.Context, request GetDIDRequest) (*GetDIDResponse, error) {
+	logrus.Debugf("getting DID: %+v", request)
+
+	id := request.ID
+	gotDID, err := h.storage.GetDIDDefault(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting DID: %s", id)
+	}
+	if gotDID == nil {
+		return nil, fmt.Errorf("did with id<%s> could not be found", id)
+	}
+	return &GetDIDResponse{DID: gotDID.DID}, nil
+}
+
+func (h *pkhHandler) ListDIDs(ctx context.Context, page *common.Page) (*ListDIDsResponse, error) {
+	gotDIDs, err := h.storage.ListDIDsPage(ctx, did.PKHMethod.String(), page, new(DefaultStoredDID))
+	if err != nil {
+		return nil, errors.Wrap(err, "listing did:pkh DIDs page")
+	}
+	dids := make([]did.Document, 0, len(gotDIDs.DIDs))
+	for _, gotDID := range gotDIDs.DIDs {
+		if !gotDID.IsSoftDeleted() {
+			dids = append(dids, gotDID.GetDocument())
+		}
+	}
+	return &ListDIDsResponse{
+		DIDs:          dids,
+		NextPageToken: gotDIDs.NextPageToken,
+	}, nil
+}
+
+// ListDeletedDIDs returns only DIDs we have in storage for PKH with SoftDeleted flag set to true
+func (h *pkhHandler) ListDeletedDIDs(ctx context.Context) (*ListDIDsResponse, error) {
+	logrus.Debug("listing did:pkh DIDs")
+
+	gotDIDs, err := h.storage.ListDIDsDefault(ctx, did.PKHMethod.String())
+	if err != nil {
+		return nil, fmt.Errorf("error getting did:pkh DIDs")
+	}
+	dids := make([]did.Document, 0, len(gotDIDs))
+	for _, gotDID := range gotDIDs {
+		if gotDID.IsSoftDeleted() {
+			dids = append(dids, gotDID.GetDocument())
+		}
+	}
+	return &ListDIDsResponse{DIDs: dids}, nil
+}
+
+func (h *pkhHandler) SoftDeleteDID(ctx context.Context, request DeleteDIDRequest) error {
+	logrus.Debugf("soft deleting DID: %+v", request)
+
+	id := request.ID
+	gotStoredDID, err := h.storage.GetDIDDefault(ctx, id)
+	if err != nil {
+		return fmt.Errorf("error getting DID: %s", id)
+	}
+	if gotStoredDID == nil {
+		return fmt.Errorf("did with id<%s> could not be found", id)
+	}
+
+	gotStoredDID.SoftDeleted = true
+
+	return h.storage.StoreDID(ctx, *gotStoredDID)
+}
+
+// RestoreDID clears the SoftDeleted flag of a did:pkh DID.
+func (h *pkhHandler) RestoreDID(ctx context.Context, request RestoreDIDRequest) error {
+	logrus.Debugf("restoring DID: %+v", request)
+
+	return h.storage.RestoreDIDDefault(ctx, request.ID)
+}
@@ -0,0 +1,355 @@
+package resolution
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	didsdk "github.com/TBD54566975/ssi-sdk/did"
+	"github.com/TBD54566975/ssi-sdk/did/resolution"
+	"github.com/goccy/go-json"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	utilint "github.com/tbd54566975/ssi-service/internal/util"
+	"github.com/tbd54566975/ssi-service/pkg/storage"
+)
+
+const (
+	// MemoryCacheBackend keeps cached resolutions in the memory of the process.
+	MemoryCacheBackend = "memory"
+	// StorageCacheBackend keeps cached resolutions in the service's storage, so that they are shared by every instance
+	// of the service.
+	StorageCacheBackend = "storage"
+
+	cacheNamespace         = "did-resolution-cache"
+	defaultCacheMaxEntries = 1000
+
+	// cacheOrderNamespace holds the DIDs with a resolution in the storage cache, in the order they were cached, so
+	// that the oldest can be evicted without reading every cached resolution.
+	cacheOrderNamespace = "did-resolution-cache-order"
+	cacheOrderKey       = "order"
+)
+
+// CacheOptions configures a CachingResolver.
+type CacheOptions struct {
+	// TTL is how long a resolved DID is cached for, unless its method has its own TTL in MethodTTLs.
+	TTL time.Duration
+	// MethodTTLs overrides TTL for DIDs of the given methods. A zero TTL keeps the method's DIDs out of the cache.
+	MethodTTLs map[string]time.Duration
+	// NegativeTTL is how long a DID that could not be resolved is cached as such. Zero disables negative caching.
+	NegativeTTL time.Duration
+	// MaxEntries bounds the number of cached DIDs. Defaults to 1000.
+	MaxEntries int
+	// Backend is where resolutions are cached: MemoryCacheBackend or StorageCacheBackend. Defaults to memory.
+	Backend string
+}
+
+// CachingResolver caches the results of another resolver, including failed resolutions. Cached resolutions of DIDs
+// whose documents the service changes must be invalidated with Invalidate.
+type CachingResolver struct {
+	resolver resolution.Resolver
+	cache    resolutionCache
+	options  CacheOptions
+	now      func() time.Time
+}
+
+var _ resolution.Resolver = (*CachingResolver)(nil)
+
+// NewCachingResolver layers a cache in front of the given resolver. The storage is only used by the storage backend.
+func NewCachingResolver(resolver resolution.Resolver, db storage.ServiceStorage, options CacheOptions) (*CachingResolver, error) {
+	if resolver == nil {
+		return nil, errors.New("resolver cannot be empty")
+	}
+	maxEntries := options.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = defaultCacheMaxEntries
+	}
+	var cache resolutionCache
+	switch options.Backend {
+	case "", MemoryCacheBackend:
+		cache = newMemoryCache(maxEntries)
+	case StorageCacheBackend:
+		if db == nil {
+			return nil, errors.New("storage cannot be empty for the storage cache backend")
+		}
+		cache = &storageCache{db: db, maxEntries: maxEntries}
+	default:
+		return nil, errors.Errorf("unsupported resolution cache backend: %s", options.Backend)
+	}
+	return &CachingResolver{resolver: resolver, cache: cache, options: options, now: time.Now}, nil
+}
+
+// cachedResolution is a resolution result, or the error resolving the DID, along with when it stops being valid.
+type cachedResolution struct {
+	Result    *resolution.Result `json:"result,omitempty"`
+	Error     string             `json:"error,omitempty"`
+	ErrorCode string             `json:"errorCode,omitempty"`
+	CachedAt  time.Time          `json:"cachedAt"`
+	ExpiresAt time.Time          `json:"expiresAt"`
+}
+
+// Resolve returns the cached resolution of the DID when there is one that hasn't expired. Otherwise, it resolves the
+// DID with the wrapped resolver, and caches the result. Failing to read or write the cache doesn't fail resolution.
+// Only resolutions of the latest version of documents, in their default representation, are cached. The NoCache option
+// skips the cached resolution, and replaces it with a fresh one.
+func (cr *CachingResolver) Resolve(ctx context.Context, did string, opts ...resolution.Option) (*resolution.Result, error) {
+	ttl := cr.ttlFor(did)
+	options := GetOptions(opts...)
+	if ttl <= 0 || !options.IsDefault() {
+		return cr.resolver.Resolve(ctx, did, opts...)
+	}
+
+	now := cr.now()
+	var cached *cachedResolution
+	var err error
+	if !options.NoCache {
+		if cached, err = cr.cache.get(ctx, did); err != nil {
+			logrus.WithError(err).Warnf("could not read cached resolution of DID: %s", did)
+		}
+	}
+	if cached != nil && now.Before(cached.ExpiresAt) {
+		if cached.Error != "" {
+			if cached.ErrorCode != "" {
+				return nil, NewError(cached.ErrorCode, errors.New(cached.Error))
+			}
+			return nil, errors.New(cached.Error)
+		}
+		return cached.Result, nil
+	}
+
+	resolved, resolveErr := cr.resolver.Resolve(ctx, did, opts...)
+	entry := cachedResolution{Result: resolved, CachedAt: now, ExpiresAt: now.Add(ttl)}
+	if resolveErr != nil {
+		if cr.options.NegativeTTL <= 0 {
+			return nil, resolveErr
+		}
+		entry = cachedResolution{
+			Error:     resolveErr.Error(),
+			ErrorCode: GetErrorCode(resolveErr),
+			CachedAt:  now,
+			ExpiresAt: now.Add(cr.options.NegativeTTL),
+		}
+	}
+	if err = cr.cache.put(ctx, did, entry); err != nil {
+		logrus.WithError(err).Warnf("could not cache resolution of DID: %s", did)
+	}
+	return resolved, resolveErr
+}
+
+// Invalidate removes the cached resolution of a DID, so that it's resolved again the next time.
+func (cr *CachingResolver) Invalidate(ctx context.Context, did string) error {
+	return cr.cache.delete(ctx, did)
+}
+
+func (cr *CachingResolver) Methods() []didsdk.Method {
+	return cr.resolver.Methods()
+}
+
+func (cr *CachingResolver) ttlFor(did string) time.Duration {
+	method, err := utilint.GetMethodForDID(did)
+	if err != nil {
+		return 0
+	}
+	if ttl, ok := cr.options.MethodTTLs[method.String()]; ok {
+		return ttl
+	}
+	return cr.options.TTL
+}
+
+// resolutionCache stores cached resolutions by DID. Entries are stored as JSON, so that callers can't change cached
+// documents through the results they are given.
+type resolutionCache interface {
+	// get returns nil when the DID has no cached resolution.
+	get(ctx context.Context, did string) (*cachedResolution, error)
+	put(ctx context.Context, did string, entry cachedResolution) error
+	delete(ctx context.Context, did string) error
+}
+
+// memoryCache is a least recently used cache of resolutions.
+type memoryCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List
+}
+
+type memoryCacheEntry struct {
+	did   string
+	bytes []byte
+}
+
+func newMemoryCache(maxEntries int) *memoryCache {
+	return &memoryCache{maxEntries: maxEntries, entries: make(map[string]*list.Element), order: list.New()}
+}
+
+func (mc *memoryCache) get(_ context.Context, did string) (*cachedResolution, error) {
+	mc.mu.Lock()
+	var entryBytes []byte
+	element, ok := mc.entries[did]
+	if ok {
+		mc.order.MoveToFront(element)
+		entryBytes = element.Value.(*memoryCacheEntry).bytes
+	}
+	mc.mu.Unlock()
+	if !ok {
+		return nil, nil
+	}
+	var entry cachedResolution
+	if err := json.Unmarshal(entryBytes, &entry); err != nil {
+		return nil, errors.Wrap(err, "unmarshalling cached resolution")
+	}
+	return &entry, nil
+}
+
+func (mc *memoryCache) put(_ context.Context, did string, entry cachedResolution) error {
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "marshalling cached resolution")
+	}
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if element, ok := mc.entries[did]; ok {
+		element.Value.(*memoryCacheEntry).bytes = entryBytes
+		mc.order.MoveToFront(element)
+		return nil
+	}
+	mc.entries[did] = mc.order.PushFront(&memoryCacheEntry{did: did, bytes: entryBytes})
+	for mc.order.Len() > mc.maxEntries {
+		oldest := mc.order.Back()
+		mc.order.Remove(oldest)
+		delete(mc.entries, oldest.Value.(*memoryCacheEntry).did)
+	}
+	return nil
+}
+
+func (mc *memoryCache) delete(_ context.Context, did string) error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if element, ok := mc.entries[did]; ok {
+		mc.order.Remove(element)
+		delete(mc.entries, did)
+	}
+	return nil
+}
+
+// storageCache keeps resolutions in the service's storage. Once it holds more than maxEntries resolutions, the ones
+// that were cached first are evicted.
+type storageCache struct {
+	db         storage.ServiceStorage
+	maxEntries int
+}
+
+func (sc *storageCache) get(ctx context.Context, did string) (*cachedResolution, error) {
+	entryBytes, err := sc.db.Read(ctx, cacheNamespace, did)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading cached resolution")
+	}
+	if len(entryBytes) == 0 {
+		return nil, nil
+	}
+	var entry cachedResolution
+	if err = json.Unmarshal(entryBytes, &entry); err != nil {
+		return nil, errors.Wrap(err, "unmarshalling cached resolution")
+	}
+	return &entry, nil
+}
+
+func (sc *storageCache) put(ctx context.Context, did string, entry cachedResolution) error {
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "marshalling cached resolution")
+	}
+	watchKeys := []storage.WatchKey{{Namespace: cacheOrderNamespace, Key: cacheOrderKey}}
+	evicted, err := sc.db.Execute(ctx, func(ctx context.Context, tx storage.Tx) (any, error) {
+		order, err := sc.readOrder(ctx)
+		if err != nil {
+			return nil, err
+		}
+		order = append(withoutDID(order, did), did)
+		var toEvict []string
+		if len(order) > sc.maxEntries {
+			toEvict = order[:len(order)-sc.maxEntries]
+			order = order[len(order)-sc.maxEntries:]
+		}
+		if err = tx.Write(ctx, cacheNamespace, did, entryBytes); err != nil {
+			return nil, errors.Wrap(err, "writing cached resolution")
+		}
+		if err = sc.writeOrder(ctx, tx, order); err != nil {
+			return nil, err
+		}
+		return toEvict, nil
+	}, watchKeys)
+	if err != nil {
+		return err
+	}
+	for _, toEvict := range evicted.([]string) {
+		if err = sc.deleteEntry(ctx, toEvict); err != nil {
+			return errors.Wrapf(err, "evicting cached resolution of DID: %s", toEvict)
+		}
+	}
+	return nil
+}
+
+func (sc *storageCache) delete(ctx context.Context, did string) error {
+	watchKeys := []storage.WatchKey{{Namespace: cacheOrderNamespace, Key: cacheOrderKey}}
+	if _, err := sc.db.Execute(ctx, func(ctx context.Context, tx storage.Tx) (any, error) {
+		order, err := sc.readOrder(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return nil, sc.writeOrder(ctx, tx, withoutDID(order, did))
+	}, watchKeys); err != nil {
+		return err
+	}
+	return sc.deleteEntry(ctx, did)
+}
+
+func (sc *storageCache) deleteEntry(ctx context.Context, did string) error {
+	// deleting fails when nothing was ever cached
+	exists, err := sc.db.Exists(ctx, cacheNamespace, did)
+	if err != nil {
+		return errors.Wrap(err, "checking for cached resolution")
+	}
+	if !exists {
+		return nil
+	}
+	return sc.db.Delete(ctx, cacheNamespace, did)
+}
+
+func (sc *storageCache) readOrder(ctx context.Context) ([]string, error) {
+	orderBytes, err := sc.db.Read(ctx, cacheOrderNamespace, cacheOrderKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading cached resolution order")
+	}
+	if len(orderBytes) == 0 {
+		return nil, nil
+	}
+	var order []string
+	if err = json.Unmarshal(orderBytes, &order); err != nil {
+		return nil, errors.Wrap(err, "unmarshalling cached resolution order")
+	}
+	return order, nil
+}
+
+func (sc *storageCache) writeOrder(ctx context.Context, tx storage.Tx, order []string) error {
+	orderBytes, err := json.Marshal(order)
+	if err != nil {
+		return errors.Wrap(err, "marshalling cached resolution order")
+	}
+	if err = tx.Write(ctx, cacheOrderNamespace, cacheOrderKey, orderBytes); err != nil {
+		return errors.Wrap(err, "writing cached resolution order")
+	}
+	return nil
+}
+
+func withoutDID(dids []string, did string) []string {
+	kept := make([]string, 0, len(dids))
+	for _, d := range dids {
+		if d != did {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
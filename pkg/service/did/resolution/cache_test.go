@@ -0,0 +1,171 @@
+package resolution
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	didsdk "github.com/TBD54566975/ssi-sdk/did"
+	"github.com/TBD54566975/ssi-sdk/did/resolution"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tbd54566975/ssi-service/pkg/testutil"
+)
+
+// countingResolver resolves every DID in resolvable, and counts how many times each DID was resolved.
+type countingResolver struct {
+	resolvable map[string]bool
+	calls      map[string]int
+}
+
+func (r *countingResolver) Resolve(_ context.Context, did string, _ ...resolution.Option) (*resolution.Result, error) {
+	r.calls[did]++
+	if !r.resolvable[did] {
+		return nil, fmt.Errorf("unable to resolve DID %s", did)
+	}
+	return &resolution.Result{Document: didsdk.Document{ID: did}}, nil
+}
+
+func (r *countingResolver) Methods() []didsdk.Method {
+	return []didsdk.Method{didsdk.KeyMethod, didsdk.WebMethod}
+}
+
+func TestCachingResolver(t *testing.T) {
+	keyDID := "did:key:z6MkpTHR8VNsBxYAAWHut2Geadd9jSwuBV8xRoAnwWsdvktH"
+	webDID := "did:web:example.com"
+	unknownDID := "did:key:z6MkunknownunknownunknownunknownunknownunknownAB"
+
+	for _, test := range testutil.TestDatabases {
+		for _, backend := range []string{MemoryCacheBackend, StorageCacheBackend} {
+			t.Run(fmt.Sprintf("%s with %s backend", test.Name, backend), func(tt *testing.T) {
+				newCachingResolver := func(tt *testing.T, options CacheOptions) (*CachingResolver, *countingResolver, *time.Time) {
+					resolver := &countingResolver{
+						resolvable: map[string]bool{keyDID: true, webDID: true},
+						calls:      make(map[string]int),
+					}
+					options.Backend = backend
+					cachingResolver, err := NewCachingResolver(resolver, test.ServiceStorage(tt), options)
+					require.NoError(tt, err)
+					now := time.Now()
+					cachingResolver.now = func() time.Time { return now }
+					return cachingResolver, resolver, &now
+				}
+
+				tt.Run("caches resolutions until they expire", func(ttt *testing.T) {
+					cachingResolver, resolver, now := newCachingResolver(ttt, CacheOptions{TTL: time.Minute})
+
+					for i := 0; i < 3; i++ {
+						resolved, err := cachingResolver.Resolve(context.Background(), keyDID)
+						require.NoError(ttt, err)
+						assert.Equal(ttt, keyDID, resolved.Document.ID)
+					}
+					assert.Equal(ttt, 1, resolver.calls[keyDID])
+
+					*now = now.Add(time.Minute)
+					_, err := cachingResolver.Resolve(context.Background(), keyDID)
+					require.NoError(ttt, err)
+					assert.Equal(ttt, 2, resolver.calls[keyDID])
+				})
+
+				tt.Run("uses per-method TTLs", func(ttt *testing.T) {
+					cachingResolver, resolver, now := newCachingResolver(ttt, CacheOptions{
+						TTL:        time.Hour,
+						MethodTTLs: map[string]time.Duration{"web": time.Minute, "key": 0},
+					})
+
+					for i := 0; i < 2; i++ {
+						_, err := cachingResolver.Resolve(context.Background(), keyDID)
+						require.NoError(ttt, err)
+						_, err = cachingResolver.Resolve(context.Background(), webDID)
+						require.NoError(ttt, err)
+					}
+					assert.Equal(ttt, 2, resolver.calls[keyDID])
+					assert.Equal(ttt, 1, resolver.calls[webDID])
+
+					*now = now.Add(time.Minute)
+					_, err := cachingResolver.Resolve(context.Background(), webDID)
+					require.NoError(ttt, err)
+					assert.Equal(ttt, 2, resolver.calls[webDID])
+				})
+
+				tt.Run("caches failed resolutions for the negative TTL", func(ttt *testing.T) {
+					cachingResolver, resolver, now := newCachingResolver(ttt, CacheOptions{TTL: time.Hour, NegativeTTL: time.Minute})
+
+					for i := 0; i < 2; i++ {
+						_, err := cachingResolver.Resolve(context.Background(), unknownDID)
+						assert.ErrorContains(ttt, err, "unable to resolve DID")
+					}
+					assert.Equal(ttt, 1, resolver.calls[unknownDID])
+
+					// once it can be resolved, it is after the negative TTL
+					resolver.resolvable[unknownDID] = true
+					*now = now.Add(time.Minute)
+					resolved, err := cachingResolver.Resolve(context.Background(), unknownDID)
+					require.NoError(ttt, err)
+					assert.Equal(ttt, unknownDID, resolved.Document.ID)
+				})
+
+				tt.Run("doesn't cache failed resolutions without a negative TTL", func(ttt *testing.T) {
+					cachingResolver, resolver, _ := newCachingResolver(ttt, CacheOptions{TTL: time.Hour})
+
+					for i := 0; i < 2; i++ {
+						_, err := cachingResolver.Resolve(context.Background(), unknownDID)
+						assert.Error(ttt, err)
+					}
+					assert.Equal(ttt, 2, resolver.calls[unknownDID])
+				})
+
+				tt.Run("invalidates resolutions", func(ttt *testing.T) {
+					cachingResolver, resolver, _ := newCachingResolver(ttt, CacheOptions{TTL: time.Hour})
+
+					// invalidating a DID that was never cached is fine
+					require.NoError(ttt, cachingResolver.Invalidate(context.Background(), keyDID))
+
+					_, err := cachingResolver.Resolve(context.Background(), keyDID)
+					require.NoError(ttt, err)
+					require.NoError(ttt, cachingResolver.Invalidate(context.Background(), keyDID))
+					_, err = cachingResolver.Resolve(context.Background(), keyDID)
+					require.NoError(ttt, err)
+					assert.Equal(ttt, 2, resolver.calls[keyDID])
+				})
+
+				tt.Run("evicts the oldest resolutions past the size bound", func(ttt *testing.T) {
+					cachingResolver, resolver, now := newCachingResolver(ttt, CacheOptions{TTL: time.Hour, MaxEntries: 1})
+
+					_, err := cachingResolver.Resolve(context.Background(), keyDID)
+					require.NoError(ttt, err)
+					*now = now.Add(time.Second)
+					_, err = cachingResolver.Resolve(context.Background(), webDID)
+					require.NoError(ttt, err)
+
+					_, err = cachingResolver.Resolve(context.Background(), webDID)
+					require.NoError(ttt, err)
+					_, err = cachingResolver.Resolve(context.Background(), keyDID)
+					require.NoError(ttt, err)
+					assert.Equal(ttt, 1, resolver.calls[webDID])
+					assert.Equal(ttt, 2, resolver.calls[keyDID])
+				})
+
+				tt.Run("invalidated resolutions don't count towards the size bound", func(ttt *testing.T) {
+					cachingResolver, resolver, _ := newCachingResolver(ttt, CacheOptions{TTL: time.Hour, MaxEntries: 1})
+
+					_, err := cachingResolver.Resolve(context.Background(), keyDID)
+					require.NoError(ttt, err)
+					require.NoError(ttt, cachingResolver.Invalidate(context.Background(), keyDID))
+					_, err = cachingResolver.Resolve(context.Background(), webDID)
+					require.NoError(ttt, err)
+					_, err = cachingResolver.Resolve(context.Background(), webDID)
+					require.NoError(ttt, err)
+					assert.Equal(ttt, 1, resolver.calls[webDID])
+				})
+			})
+		}
+	}
+
+	t.Run("unsupported backend", func(tt *testing.T) {
+		_, err := NewCachingResolver(&countingResolver{}, nil, CacheOptions{TTL: time.Minute, Backend: "disk"})
+		assert.ErrorContains(tt, err, "unsupported resolution cache backend")
+	})
+}
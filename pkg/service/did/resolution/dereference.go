@@ -0,0 +1,220 @@
+package resolution
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strings"
+
+	didsdk "github.com/TBD54566975/ssi-sdk/did"
+	"github.com/TBD54566975/ssi-sdk/did/resolution"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ServiceParam selects a service of the DID document by the fragment of its ID.
+	ServiceParam = "service"
+	// RelativeRefParam is a relative URI reference, resolved against the endpoint of the selected service.
+	RelativeRefParam = "relativeRef"
+	// VersionIDParam requests a specific version of the DID document.
+	VersionIDParam = "versionId"
+	// VersionTimeParam requests the version of the DID document that was valid at a given time.
+	VersionTimeParam = "versionTime"
+
+	// URIListContentType is the content type of the service endpoint URLs a DID URL may be dereferenced to.
+	URIListContentType = "text/uri-list"
+)
+
+// didPattern matches DIDs as described in https://www.w3.org/TR/did-core/#did-syntax.
+var didPattern = regexp.MustCompile(`^did:[a-z0-9]+:(([a-zA-Z0-9._-]|%[0-9A-Fa-f]{2})*:)*([a-zA-Z0-9._-]|%[0-9A-Fa-f]{2})+$`)
+
+// DIDURL is a parsed DID URL, as described in https://www.w3.org/TR/did-core/#did-url-syntax.
+type DIDURL struct {
+	DID      string
+	Path     string
+	Query    url.Values
+	Fragment string
+}
+
+// ParseDIDURL parses a DID URL such as `did:web:example.com#key-1` or
+// `did:example:123?service=files&relativeRef=/a`.
+func ParseDIDURL(didURL string) (*DIDURL, error) {
+	rest, fragment, _ := strings.Cut(didURL, "#")
+	rest, rawQuery, _ := strings.Cut(rest, "?")
+	did, path := rest, ""
+	if i := strings.Index(rest, "/"); i >= 0 {
+		did, path = rest[:i], rest[i:]
+	}
+	if !didPattern.MatchString(did) {
+		return nil, NewError(InvalidDIDURLCode, errors.Errorf("malformed DID in DID URL: %s", didURL))
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, NewError(InvalidDIDURLCode, errors.Wrapf(err, "malformed query in DID URL: %s", didURL))
+	}
+	return &DIDURL{DID: did, Path: path, Query: query, Fragment: fragment}, nil
+}
+
+// DereferencingMetadata describes the dereferencing of a DID URL, as described in
+// https://www.w3.org/TR/did-core/#did-url-dereferencing-metadata.
+type DereferencingMetadata struct {
+	ContentType string `json:"contentType,omitempty"`
+}
+
+// DereferencingResult is the resource a DID URL is dereferenced to, as described in
+// https://www.w3.org/TR/did-core/#did-url-dereferencing.
+type DereferencingResult struct {
+	DereferencingMetadata DereferencingMetadata `json:"dereferencingMetadata"`
+	// ContentStream is the DID document, a verification method or service from it, or service endpoint URLs.
+	ContentStream any `json:"contentStream"`
+	// ContentMetadata is the metadata of the DID document the resource was dereferenced from.
+	ContentMetadata *resolution.DocumentMetadata `json:"contentMetadata,omitempty"`
+}
+
+// Dereference dereferences a DID URL following https://w3c-ccg.github.io/did-resolution/#dereferencing-algorithm. The
+// DID is resolved with the resolver, at the version requested in the DID URL or the options. Then:
+//   - the `service` parameter selects the endpoint URLs of a service, which `relativeRef` is resolved against, and to
+//     which the fragment is appended;
+//   - otherwise, the fragment selects a verification method or service of the document;
+//   - otherwise, the DID URL dereferences to the document itself.
+//
+// Paths are method specific, and not supported. Failures are returned as an *Error with the matching error code.
+func Dereference(ctx context.Context, resolver resolution.Resolver, didURL string, options Options) (*DereferencingResult, error) {
+	parsed, err := ParseDIDURL(didURL)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Path != "" {
+		return nil, NewError(NotFoundCode, errors.Errorf("dereferencing DID URL paths is not supported: %s", didURL))
+	}
+	if versionID := parsed.Query.Get(VersionIDParam); versionID != "" {
+		options.VersionID = versionID
+	}
+	if versionTime := parsed.Query.Get(VersionTimeParam); versionTime != "" {
+		options.VersionTime = versionTime
+	}
+
+	resolved, err := resolver.Resolve(ctx, parsed.DID, options)
+	if err != nil {
+		return nil, err
+	}
+	document := resolved.Document
+	contentType := resolved.Metadata.ContentType
+	if contentType == "" {
+		contentType = DIDJSONLDContentType
+	}
+
+	if service := parsed.Query.Get(ServiceParam); service != "" {
+		endpoints, err := serviceEndpointURLs(document, parsed.DID, service, parsed.Query.Get(RelativeRefParam), parsed.Fragment)
+		if err != nil {
+			return nil, err
+		}
+		return &DereferencingResult{
+			DereferencingMetadata: DereferencingMetadata{ContentType: URIListContentType},
+			ContentStream:         strings.Join(endpoints, "\n"),
+		}, nil
+	}
+
+	result := DereferencingResult{
+		DereferencingMetadata: DereferencingMetadata{ContentType: contentType},
+		ContentStream:         document,
+		ContentMetadata:       resolved.DocumentMetadata,
+	}
+	if parsed.Fragment != "" {
+		resource := selectFragment(document, parsed.DID, parsed.Fragment)
+		if resource == nil {
+			return nil, NewError(NotFoundCode, errors.Errorf("DID URL fragment not found in document: %s", didURL))
+		}
+		result.ContentStream = resource
+	}
+	return &result, nil
+}
+
+// selectFragment returns the verification method, which may be embedded in a verification relationship, or the
+// service of the document that has the fragment as its ID. It returns nil when there is none.
+func selectFragment(document didsdk.Document, did, fragment string) any {
+	id := did + "#" + fragment
+	for _, vm := range document.VerificationMethod {
+		if didsdk.FullyQualifiedVerificationMethodID(did, vm.ID) == id {
+			return vm
+		}
+	}
+	relationships := [][]didsdk.VerificationMethodSet{
+		document.Authentication,
+		document.AssertionMethod,
+		document.KeyAgreement,
+		document.CapabilityInvocation,
+		document.CapabilityDelegation,
+	}
+	for _, relationship := range relationships {
+		for _, vmSet := range relationship {
+			var embeddedID string
+			switch vm := vmSet.(type) {
+			case didsdk.VerificationMethod:
+				embeddedID = vm.ID
+			case map[string]any:
+				embeddedID, _ = vm["id"].(string)
+			default:
+				// references to verification methods were searched above
+				continue
+			}
+			if embeddedID != "" && didsdk.FullyQualifiedVerificationMethodID(did, embeddedID) == id {
+				return vmSet
+			}
+		}
+	}
+	for _, service := range document.Services {
+		if didsdk.FullyQualifiedVerificationMethodID(did, service.ID) == id {
+			return service
+		}
+	}
+	return nil
+}
+
+// serviceEndpointURLs selects the URLs of the endpoint of the service with the given ID fragment, as described in
+// https://w3c-ccg.github.io/did-resolution/#service-endpoint-construction. Endpoints that are maps aren't URLs, and
+// are skipped.
+func serviceEndpointURLs(document didsdk.Document, did, service, relativeRef, fragment string) ([]string, error) {
+	id := didsdk.FullyQualifiedVerificationMethodID(did, service)
+	var endpoints []string
+	for _, s := range document.Services {
+		if didsdk.FullyQualifiedVerificationMethodID(did, s.ID) != id {
+			continue
+		}
+		switch endpoint := s.ServiceEndpoint.(type) {
+		case string:
+			endpoints = append(endpoints, endpoint)
+		case []string:
+			endpoints = append(endpoints, endpoint...)
+		case []any:
+			for _, e := range endpoint {
+				if endpointURL, ok := e.(string); ok {
+					endpoints = append(endpoints, endpointURL)
+				}
+			}
+		}
+	}
+	if len(endpoints) == 0 {
+		return nil, NewError(NotFoundCode, errors.Errorf("service<%s> with URL endpoints not found", service))
+	}
+
+	urls := make([]string, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		endpointURL, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing endpoint of service<%s>", service)
+		}
+		if relativeRef != "" {
+			ref, err := url.Parse(relativeRef)
+			if err != nil {
+				return nil, NewError(InvalidDIDURLCode, errors.Wrapf(err, "malformed relativeRef: %s", relativeRef))
+			}
+			endpointURL = endpointURL.ResolveReference(ref)
+		}
+		if endpointURL.Fragment == "" && fragment != "" {
+			endpointURL.Fragment = fragment
+		}
+		urls = append(urls, endpointURL.String())
+	}
+	return urls, nil
+}
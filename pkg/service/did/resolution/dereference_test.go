@@ -0,0 +1,119 @@
+package resolution
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	didsdk "github.com/TBD54566975/ssi-sdk/did"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDIDURL(t *testing.T) {
+	parsed, err := ParseDIDURL("did:example:123/path?service=files&relativeRef=%2Fa#frag")
+	require.NoError(t, err)
+	assert.Equal(t, &DIDURL{
+		DID:      "did:example:123",
+		Path:     "/path",
+		Query:    url.Values{"service": {"files"}, "relativeRef": {"/a"}},
+		Fragment: "frag",
+	}, parsed)
+
+	parsed, err = ParseDIDURL("did:web:example.com%3A8080:user#key-1")
+	require.NoError(t, err)
+	assert.Equal(t, "did:web:example.com%3A8080:user", parsed.DID)
+	assert.Equal(t, "key-1", parsed.Fragment)
+
+	for _, malformed := range []string{"", "did:", "did:example:", "did:Example:123", "example:123#key-1", "did:example:1 2"} {
+		_, err = ParseDIDURL(malformed)
+		assert.Equal(t, InvalidDIDURLCode, GetErrorCode(err), malformed)
+	}
+}
+
+func TestDereference(t *testing.T) {
+	id := "did:web:example.com"
+	embedded := didsdk.VerificationMethod{ID: "#embedded", Type: "JsonWebKey2020", Controller: id}
+	document := didsdk.Document{
+		ID: id,
+		VerificationMethod: []didsdk.VerificationMethod{
+			{ID: id + "#key-1", Type: "JsonWebKey2020", Controller: id},
+			{ID: "#key-2", Type: "JsonWebKey2020", Controller: id},
+		},
+		Authentication: []didsdk.VerificationMethodSet{id + "#key-1", embedded},
+		Services: []didsdk.Service{
+			{ID: "#files", Type: "Files", ServiceEndpoint: "https://files.example.com/root/"},
+			{ID: id + "#hub", Type: "Hub", ServiceEndpoint: []any{"https://hub1.example.com", map[string]any{"uri": "x"}, "https://hub2.example.com"}},
+			{ID: "#messaging", Type: "DIDCommMessaging", ServiceEndpoint: map[string]any{"uri": "https://example.com/didcomm"}},
+		},
+	}
+	resolver := &storedResolver{documents: map[string]didsdk.Document{id: document}}
+	dereference := func(didURL string) (*DereferencingResult, error) {
+		return Dereference(context.Background(), resolver, didURL, Options{})
+	}
+
+	t.Run("dereferences the document", func(tt *testing.T) {
+		dereferenced, err := dereference(id)
+		require.NoError(tt, err)
+		assert.Equal(tt, document, dereferenced.ContentStream)
+	})
+
+	t.Run("selects verification methods and services by fragment", func(tt *testing.T) {
+		dereferenced, err := dereference(id + "#key-1")
+		require.NoError(tt, err)
+		assert.Equal(tt, document.VerificationMethod[0], dereferenced.ContentStream)
+
+		// relative IDs are qualified with the DID
+		dereferenced, err = dereference(id + "#key-2")
+		require.NoError(tt, err)
+		assert.Equal(tt, document.VerificationMethod[1], dereferenced.ContentStream)
+
+		dereferenced, err = dereference(id + "#embedded")
+		require.NoError(tt, err)
+		assert.Equal(tt, embedded, dereferenced.ContentStream)
+
+		dereferenced, err = dereference(id + "#files")
+		require.NoError(tt, err)
+		assert.Equal(tt, document.Services[0], dereferenced.ContentStream)
+
+		_, err = dereference(id + "#unknown")
+		assert.Equal(tt, NotFoundCode, GetErrorCode(err))
+	})
+
+	t.Run("constructs service endpoint URLs", func(tt *testing.T) {
+		dereferenced, err := dereference(id + "?service=files&relativeRef=" + url.QueryEscape("docs/a.txt"))
+		require.NoError(tt, err)
+		assert.Equal(tt, URIListContentType, dereferenced.DereferencingMetadata.ContentType)
+		assert.Equal(tt, "https://files.example.com/root/docs/a.txt", dereferenced.ContentStream)
+
+		dereferenced, err = dereference(id + "?service=files&relativeRef=" + url.QueryEscape("/a") + "#section")
+		require.NoError(tt, err)
+		assert.Equal(tt, "https://files.example.com/a#section", dereferenced.ContentStream)
+
+		dereferenced, err = dereference(id + "?service=hub")
+		require.NoError(tt, err)
+		assert.Equal(tt, "https://hub1.example.com\nhttps://hub2.example.com", dereferenced.ContentStream)
+
+		_, err = dereference(id + "?service=messaging")
+		assert.Equal(tt, NotFoundCode, GetErrorCode(err))
+		_, err = dereference(id + "?service=unknown")
+		assert.Equal(tt, NotFoundCode, GetErrorCode(err))
+	})
+
+	t.Run("passes the requested version to the resolver", func(tt *testing.T) {
+		_, err := Dereference(context.Background(), resolver, id+"?versionId=2#key-1", Options{Accept: DIDJSONContentType})
+		require.NoError(tt, err)
+		assert.Equal(tt, Options{VersionID: "2", Accept: DIDJSONContentType}, resolver.options)
+	})
+
+	t.Run("fails to dereference", func(tt *testing.T) {
+		_, err := dereference("did:web:unknown.com#key-1")
+		assert.Equal(tt, NotFoundCode, GetErrorCode(err))
+
+		_, err = dereference(id + "/path")
+		assert.Equal(tt, NotFoundCode, GetErrorCode(err))
+
+		_, err = dereference("not a DID URL")
+		assert.Equal(tt, InvalidDIDURLCode, GetErrorCode(err))
+	})
+}
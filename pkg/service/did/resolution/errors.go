@@ -0,0 +1,57 @@
+package resolution
+
+import (
+	"github.com/TBD54566975/ssi-sdk/did/resolution"
+	"github.com/pkg/errors"
+)
+
+// The DID resolution and dereferencing error codes, as described in https://www.w3.org/TR/did-spec-registries/#error.
+const (
+	InvalidDIDCode                 = "invalidDid"
+	NotFoundCode                   = "notFound"
+	MethodNotSupportedCode         = "methodNotSupported"
+	RepresentationNotSupportedCode = "representationNotSupported"
+	InvalidOptionsCode             = "invalidOptions"
+	InvalidDIDURLCode              = "invalidDidUrl"
+)
+
+// Error is an error resolving a DID, along with the DID resolution error code that describes it.
+type Error struct {
+	Code string
+	Err  error
+}
+
+// NewError creates an Error with the given code.
+func NewError(code string, err error) *Error {
+	return &Error{Code: code, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Code
+	}
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Metadata returns the DID resolution metadata describing the error.
+func (e *Error) Metadata() resolution.Metadata {
+	return resolution.Metadata{Error: &resolution.Error{
+		Code:                       e.Code,
+		InvalidDID:                 e.Code == InvalidDIDCode,
+		NotFound:                   e.Code == NotFoundCode,
+		RepresentationNotSupported: e.Code == RepresentationNotSupportedCode,
+	}}
+}
+
+// GetErrorCode returns the DID resolution error code of an error, or an empty string when it has none.
+func GetErrorCode(err error) string {
+	var resolutionErr *Error
+	if errors.As(err, &resolutionErr) {
+		return resolutionErr.Code
+	}
+	return ""
+}
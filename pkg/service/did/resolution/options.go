@@ -0,0 +1,87 @@
+package resolution
+
+import (
+	"time"
+
+	"github.com/TBD54566975/ssi-sdk/did/resolution"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DIDJSONLDContentType is the JSON-LD representation of DID documents, and the default one.
+	DIDJSONLDContentType = "application/did+ld+json"
+	// DIDJSONContentType is the plain JSON representation of DID documents, which has no @context.
+	DIDJSONContentType = "application/did+json"
+)
+
+// Options are the DID resolution options the service supports, as described in
+// https://www.w3.org/TR/did-spec-registries/#did-resolution-options. They are passed to resolvers as a
+// resolution.Option.
+type Options struct {
+	// Accept is the representation the document is requested in: DIDJSONLDContentType or DIDJSONContentType.
+	Accept string `json:"accept,omitempty"`
+	// VersionID requests a specific version of the document.
+	VersionID string `json:"versionId,omitempty"`
+	// VersionTime requests the version of the document that was valid at a given time, formatted as RFC3339.
+	VersionTime string `json:"versionTime,omitempty"`
+	// NoCache requests that the DID is resolved without using a cached resolution.
+	NoCache bool `json:"noCache,omitempty"`
+}
+
+// IsVersioned returns whether a specific version of the document is requested.
+func (o Options) IsVersioned() bool {
+	return o.VersionID != "" || o.VersionTime != ""
+}
+
+// IsDefault returns whether the options only request the latest version of the document in its default
+// representation, so that resolutions made with them may be served from, and stored in, a cache.
+func (o Options) IsDefault() bool {
+	return !o.IsVersioned() && (o.Accept == "" || o.Accept == DIDJSONLDContentType)
+}
+
+// Validate checks that the options are well-formed.
+func (o Options) Validate() error {
+	if o.VersionID != "" && o.VersionTime != "" {
+		return NewError(InvalidOptionsCode, errors.New("versionId and versionTime cannot both be requested"))
+	}
+	if o.VersionTime != "" {
+		if _, err := o.GetVersionTime(); err != nil {
+			return NewError(InvalidOptionsCode, err)
+		}
+	}
+	switch o.Accept {
+	case "", DIDJSONLDContentType, DIDJSONContentType:
+	default:
+		return NewError(RepresentationNotSupportedCode, errors.Errorf("representation not supported: %s", o.Accept))
+	}
+	return nil
+}
+
+// GetVersionTime parses VersionTime.
+func (o Options) GetVersionTime() (time.Time, error) {
+	versionTime, err := time.Parse(time.RFC3339, o.VersionTime)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "versionTime<%s> is not an RFC3339 date time", o.VersionTime)
+	}
+	return versionTime, nil
+}
+
+// GetOptions returns the Options amongst the resolution options given to a resolver, or empty Options when there are
+// none. Some resolvers pass their options along as a single []resolution.Option, so nested options are searched too.
+func GetOptions(opts ...resolution.Option) Options {
+	for _, opt := range opts {
+		switch o := opt.(type) {
+		case Options:
+			return o
+		case *Options:
+			if o != nil {
+				return *o
+			}
+		case []resolution.Option:
+			if nested := GetOptions(o...); nested != (Options{}) {
+				return nested
+			}
+		}
+	}
+	return Options{}
+}
@@ -57,42 +57,95 @@ func NewServiceResolver(handlerResolver resolution.Resolver, localResolutionMeth
 // 1. Try to resolve with the handlers we have, wrapping the resulting DID in resolution result
 // 2. Try to resolve with the local resolver
 // 3. Try to resolve with the universal resolver
-// TODO(gabe) avoid caching DIDs that should be externally resolved https://github.com/TBD54566975/ssi-service/issues/361
+// Results aren't cached here; the DID service layers a CachingResolver in front of this resolver when configured to,
+// and invalidates the DIDs its handlers change.
+// The local resolver has no history of the documents it resolves, so it is skipped when a specific version of the
+// document is requested in the Options. Failures are returned as an *Error with the matching DID resolution error code.
 func (sr *ServiceResolver) Resolve(ctx context.Context, did string, opts ...resolution.Option) (*resolution.Result, error) {
 	// check the did is valid
-	if _, err := utilint.GetMethodForDID(did); err != nil {
-		return nil, errors.Wrap(err, "getting method DID")
+	method, err := utilint.GetMethodForDID(did)
+	if err != nil {
+		return nil, NewError(InvalidDIDCode, errors.Wrap(err, "getting method DID"))
 	}
+	options := GetOptions(opts...)
+	if err = options.Validate(); err != nil {
+		return nil, err
+	}
+	if !sr.supportsMethod(method) {
+		return nil, NewError(MethodNotSupportedCode, errors.Errorf("unsupported method: %s", method))
+	}
+
+	// the most specific error any of the resolvers failed with
+	var resolutionErr *Error
 
 	// first, try to resolve with the handlers we have
 	if sr.hr != nil {
-		handlersResolvedDID, err := sr.hr.Resolve(ctx, did, opts...)
+		handlersResolvedDID, err := sr.hr.Resolve(ctx, did, options)
 		if err == nil {
-			return handlersResolvedDID, nil
+			return represent(handlersResolvedDID, options), nil
 		}
+		errors.As(err, &resolutionErr)
 		logrus.WithError(err).Error("error resolving DID with handler resolver")
 	}
 
 	// next, try to resolve with the local resolver
-	if sr.lr != nil {
-		locallyResolvedDID, err := sr.lr.Resolve(ctx, did, opts...)
+	if sr.lr != nil && !options.IsVersioned() {
+		locallyResolvedDID, err := sr.lr.Resolve(ctx, did, options)
 		if err == nil {
-			return locallyResolvedDID, nil
+			return represent(locallyResolvedDID, options), nil
 		}
 		logrus.WithError(err).Error("error resolving DID with local resolver")
 	}
 
 	// finally, resolution with the universal resolver
 	if sr.ur != nil {
-		universallyResolvedDID, err := sr.ur.Resolve(ctx, did, opts...)
+		universallyResolvedDID, err := sr.ur.Resolve(ctx, did, options)
 		if err == nil {
-			return universallyResolvedDID, nil
-
+			return represent(universallyResolvedDID, options), nil
+		}
+		if resolutionErr == nil || resolutionErr.Code == NotFoundCode {
+			errors.As(err, &resolutionErr)
 		}
 		logrus.WithError(err).Error("error resolving DID with universal resolver")
 	}
 
-	return nil, fmt.Errorf("unable to resolve DID %s", did)
+	if resolutionErr != nil && resolutionErr.Code != NotFoundCode {
+		return nil, errors.Wrapf(resolutionErr, "unable to resolve DID %s", did)
+	}
+	return nil, NewError(NotFoundCode, fmt.Errorf("unable to resolve DID %s", did))
+}
+
+// supportsMethod returns whether any of the resolvers may resolve DIDs of the given method. The universal resolver is
+// assumed to support every method, so that its methods don't have to be fetched for each resolution.
+func (sr *ServiceResolver) supportsMethod(method didsdk.Method) bool {
+	if sr.ur != nil {
+		return true
+	}
+	if sr.hr != nil {
+		for _, m := range sr.hr.Methods() {
+			if m == method {
+				return true
+			}
+		}
+	}
+	for _, m := range sr.resolutionMethods {
+		if m == method.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// represent sets the content type of a resolved document to the requested representation. Documents represented as
+// plain JSON have no @context.
+func represent(resolved *resolution.Result, options Options) *resolution.Result {
+	if options.Accept == DIDJSONContentType {
+		resolved.Document.Context = nil
+		resolved.Metadata.ContentType = DIDJSONContentType
+		return resolved
+	}
+	resolved.Metadata.ContentType = DIDJSONLDContentType
+	return resolved
 }
 
 func (sr *ServiceResolver) Methods() []didsdk.Method {
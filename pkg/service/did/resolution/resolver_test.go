@@ -0,0 +1,120 @@
+package resolution
+
+import (
+	"context"
+	"testing"
+
+	didsdk "github.com/TBD54566975/ssi-sdk/did"
+	"github.com/TBD54566975/ssi-sdk/did/resolution"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+)
+
+// storedResolver resolves the DIDs in documents, and records the options it was given.
+type storedResolver struct {
+	documents map[string]didsdk.Document
+	options   Options
+}
+
+func (r *storedResolver) Resolve(_ context.Context, did string, opts ...resolution.Option) (*resolution.Result, error) {
+	r.options = GetOptions(opts...)
+	document, ok := r.documents[did]
+	if !ok {
+		return nil, NewError(NotFoundCode, assert.AnError)
+	}
+	return &resolution.Result{Document: document}, nil
+}
+
+func (r *storedResolver) Methods() []didsdk.Method {
+	return []didsdk.Method{didsdk.WebMethod}
+}
+
+func TestServiceResolver(t *testing.T) {
+	webDID := "did:web:example.com"
+	handlerResolver := &storedResolver{documents: map[string]didsdk.Document{
+		webDID: {Context: "https://www.w3.org/ns/did/v1", ID: webDID},
+	}}
+
+	t.Run("reports resolution error codes", func(tt *testing.T) {
+		resolver, err := NewServiceResolver(handlerResolver, []string{"key"}, "")
+		require.NoError(tt, err)
+
+		_, err = resolver.Resolve(context.Background(), "bad")
+		assert.Equal(tt, InvalidDIDCode, GetErrorCode(err))
+
+		_, err = resolver.Resolve(context.Background(), "did:example:123")
+		assert.Equal(tt, MethodNotSupportedCode, GetErrorCode(err))
+
+		_, err = resolver.Resolve(context.Background(), "did:web:unknown.com")
+		assert.Equal(tt, NotFoundCode, GetErrorCode(err))
+		assert.ErrorContains(tt, err, "unable to resolve DID did:web:unknown.com")
+
+		_, err = resolver.Resolve(context.Background(), webDID, Options{VersionID: "1", VersionTime: "2023-01-01T00:00:00Z"})
+		assert.Equal(tt, InvalidOptionsCode, GetErrorCode(err))
+
+		_, err = resolver.Resolve(context.Background(), webDID, Options{VersionTime: "yesterday"})
+		assert.Equal(tt, InvalidOptionsCode, GetErrorCode(err))
+
+		_, err = resolver.Resolve(context.Background(), webDID, Options{Accept: "application/xml"})
+		assert.Equal(tt, RepresentationNotSupportedCode, GetErrorCode(err))
+	})
+
+	t.Run("resolves the requested representation", func(tt *testing.T) {
+		resolver, err := NewServiceResolver(handlerResolver, nil, "")
+		require.NoError(tt, err)
+
+		resolved, err := resolver.Resolve(context.Background(), webDID)
+		require.NoError(tt, err)
+		assert.Equal(tt, DIDJSONLDContentType, resolved.Metadata.ContentType)
+		assert.NotEmpty(tt, resolved.Document.Context)
+
+		resolved, err = resolver.Resolve(context.Background(), webDID, Options{Accept: DIDJSONContentType})
+		require.NoError(tt, err)
+		assert.Equal(tt, DIDJSONContentType, resolved.Metadata.ContentType)
+		assert.Empty(tt, resolved.Document.Context)
+	})
+
+	t.Run("passes options to resolvers", func(tt *testing.T) {
+		resolver, err := NewServiceResolver(handlerResolver, nil, "")
+		require.NoError(tt, err)
+
+		// options nested the way the SDK's multi-method resolver passes them along are found too
+		_, err = resolver.Resolve(context.Background(), webDID, []resolution.Option{Options{VersionID: "2"}})
+		require.NoError(tt, err)
+		assert.Equal(tt, Options{VersionID: "2"}, handlerResolver.options)
+	})
+
+	t.Run("forwards options to the universal resolver", func(tt *testing.T) {
+		defer gock.Off()
+		ionDID := "did:ion:test"
+		gock.New("https://uniresolver.example.com").
+			Get("/1.0/identifiers/"+ionDID).
+			MatchParam("versionId", "3").
+			MatchParam("noCache", "true").
+			Reply(200).
+			JSON(map[string]any{"didDocument": map[string]any{"id": ionDID}})
+		gock.New("https://uniresolver.example.com").
+			Get("/1.0/identifiers/did:ion:missing").
+			Reply(404).
+			JSON(map[string]any{"didResolutionMetadata": map[string]any{"error": map[string]any{"code": NotFoundCode}}})
+		gock.New("https://uniresolver.example.com").
+			Get("/1.0/identifiers/did:ion:unsupported").
+			Reply(501).
+			JSON(map[string]any{"didResolutionMetadata": map[string]any{"error": map[string]any{"code": MethodNotSupportedCode}}})
+
+		resolver, err := NewServiceResolver(handlerResolver, nil, "https://uniresolver.example.com")
+		require.NoError(tt, err)
+
+		resolved, err := resolver.Resolve(context.Background(), ionDID, Options{VersionID: "3", NoCache: true})
+		require.NoError(tt, err)
+		assert.Equal(tt, ionDID, resolved.Document.ID)
+
+		_, err = resolver.Resolve(context.Background(), "did:ion:missing")
+		assert.Equal(tt, NotFoundCode, GetErrorCode(err))
+
+		_, err = resolver.Resolve(context.Background(), "did:ion:unsupported")
+		assert.Equal(tt, MethodNotSupportedCode, GetErrorCode(err))
+		assert.True(tt, gock.IsDone())
+	})
+}
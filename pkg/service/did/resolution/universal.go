@@ -5,6 +5,7 @@ import (
 	"context"
 	"io"
 	"net/http"
+	"net/url"
 
 	didsdk "github.com/TBD54566975/ssi-sdk/did"
 	"github.com/TBD54566975/ssi-sdk/did/resolution"
@@ -14,6 +15,10 @@ import (
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
+// universalResolverResultContentType requests the whole DID resolution result from the universal resolver, rather
+// than just the document.
+const universalResolverResultContentType = `application/ld+json;profile="https://w3id.org/did-resolution"`
+
 // universalResolver is a struct that implements the Resolver interface. It calls the universal resolver endpoint
 // to resolve any DID according to https://github.com/decentralized-identity/universal-resolver.
 type universalResolver struct {
@@ -34,18 +39,24 @@ func newUniversalResolver(url string) (*universalResolver, error) {
 	}, nil
 }
 
-// Resolve results resolution results by doing a GET on <url>/1.0.identifiers/<did>.
-func (ur *universalResolver) Resolve(ctx context.Context, did string, _ ...resolution.Option) (*resolution.Result, error) {
-	url := ur.url + "/1.0/identifiers/" + did
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+// Resolve results resolution results by doing a GET on <url>/1.0.identifiers/<did>. The Options amongst opts are
+// forwarded as query parameters, and resolution errors reported by the universal resolver keep their error codes.
+func (ur *universalResolver) Resolve(ctx context.Context, did string, opts ...resolution.Option) (*resolution.Result, error) {
+	resolveURL := ur.url + "/1.0/identifiers/" + did
+	if query := universalResolverQuery(GetOptions(opts...)); len(query) > 0 {
+		resolveURL += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, resolveURL, nil)
 	if err != nil {
 		return nil, errors.Wrap(err, "creating request")
 	}
+	req.Header.Set("Accept", universalResolverResultContentType)
 
 	resp, err := ur.client.Do(req)
 	if err != nil {
 		return nil, errors.Wrap(err, "performing http get")
 	}
+	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(bufio.NewReader(resp.Body))
 	if err != nil {
@@ -53,11 +64,36 @@ func (ur *universalResolver) Resolve(ctx context.Context, did string, _ ...resol
 	}
 	var result resolution.Result
 	if err = json.Unmarshal(respBody, &result); err != nil {
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, NewError(NotFoundCode, errors.Errorf("universal resolver could not find DID: %s", did))
+		}
 		return nil, errors.Wrap(err, "unmarshalling JSON")
 	}
+	if result.Metadata.Error != nil && result.Metadata.Error.Code != "" {
+		return nil, NewError(result.Metadata.Error.Code, errors.Errorf("universal resolver could not resolve DID: %s", did))
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, errors.Errorf("universal resolver responded with status %d", resp.StatusCode)
+	}
 	return &result, nil
 }
 
+// universalResolverQuery returns the query parameters the universal resolver takes the options as. The representation
+// of the document isn't forwarded, as the whole resolution result is always requested.
+func universalResolverQuery(options Options) url.Values {
+	query := url.Values{}
+	if options.VersionID != "" {
+		query.Set("versionId", options.VersionID)
+	}
+	if options.VersionTime != "" {
+		query.Set("versionTime", options.VersionTime)
+	}
+	if options.NoCache {
+		query.Set("noCache", "true")
+	}
+	return query
+}
+
 // Methods returns the methods that this resolver supports
 // as per https://github.com/decentralized-identity/universal-resolver/blob/main/swagger/api.yml#L121
 func (ur *universalResolver) Methods() []didsdk.Method {
@@ -3,13 +3,16 @@ package did
 import (
 	"context"
 	"fmt"
+	"time"
 
 	didsdk "github.com/TBD54566975/ssi-sdk/did"
 	didresolution "github.com/TBD54566975/ssi-sdk/did/resolution"
 	sdkutil "github.com/TBD54566975/ssi-sdk/util"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 
 	"github.com/tbd54566975/ssi-service/config"
+	"github.com/tbd54566975/ssi-service/internal/util"
 	"github.com/tbd54566975/ssi-service/pkg/service/did/resolution"
 	"github.com/tbd54566975/ssi-service/pkg/service/framework"
 	"github.com/tbd54566975/ssi-service/pkg/service/keystore"
@@ -24,7 +27,9 @@ type Service struct {
 	handlers map[didsdk.Method]MethodHandler
 
 	// resolver for DID methods
-	resolver *resolution.ServiceResolver
+	resolver didresolution.Resolver
+	// resolutionCache caches the resolver's results, when enabled. DIDs are invalidated when the handlers change them.
+	resolutionCache *resolution.CachingResolver
 
 	// external dependencies
 	keyStore          *keystore.Service
@@ -103,6 +108,21 @@ func NewDIDService(config config.DIDServiceConfig, s storage.ServiceStorage, key
 	}
 	service.resolver = resolver
 
+	if config.ResolutionCacheTTL > 0 || len(config.ResolutionCacheMethodTTLs) > 0 {
+		cache, err := resolution.NewCachingResolver(resolver, s, resolution.CacheOptions{
+			TTL:         config.ResolutionCacheTTL,
+			MethodTTLs:  config.ResolutionCacheMethodTTLs,
+			NegativeTTL: config.ResolutionCacheNegativeTTL,
+			MaxEntries:  config.ResolutionCacheMaxEntries,
+			Backend:     config.ResolutionCacheBackend,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "instantiating DID resolution cache")
+		}
+		service.resolver = cache
+		service.resolutionCache = cache
+	}
+
 	if !service.Status().IsReady() {
 		return nil, errors.New(service.Status().Message)
 	}
@@ -120,16 +140,41 @@ func (s *Service) instantiateHandlerForMethod(method didsdk.Method) error {
 		}
 		s.handlers[method] = kh
 	case didsdk.WebMethod:
-		wh, err := NewWebHandler(s.storage, s.keyStore)
+		wh, err := NewWebHandler(s.storage, s.keyStore, s.keyStoreFactory, s.didStorageFactory)
 		if err != nil {
 			return errors.Wrap(err, "instantiating web handler")
 		}
 		s.handlers[method] = wh
+	case didsdk.PeerMethod:
+		ph, err := NewPeerHandler(s.storage, s.keyStore)
+		if err != nil {
+			return errors.Wrap(err, "instantiating peer handler")
+		}
+		s.handlers[method] = ph
+	case didsdk.JWKMethod:
+		jh, err := NewJWKHandler(s.storage, s.keyStore)
+		if err != nil {
+			return errors.Wrap(err, "instantiating jwk handler")
+		}
+		s.handlers[method] = jh
+	case didsdk.PKHMethod:
+		ph, err := NewPKHHandler(s.storage, s.keyStore)
+		if err != nil {
+			return errors.Wrap(err, "instantiating pkh handler")
+		}
+		s.handlers[method] = ph
 	case didsdk.IONMethod:
 		ih, err := NewIONHandler(s.Config().IONResolverURL, s.storage, s.keyStore, s.keyStoreFactory, s.didStorageFactory)
 		if err != nil {
 			return errors.Wrap(err, "instantiating ion handler")
 		}
+		ionHandlerImpl := ih.(*ionHandler)
+		if s.config.IONRetryBackoff > 0 {
+			ionHandlerImpl.retryBackoff = s.config.IONRetryBackoff
+		}
+		if s.config.IONMaxRetryBackoff > 0 {
+			ionHandlerImpl.maxRetryBackoff = s.config.IONMaxRetryBackoff
+		}
 		s.handlers[method] = ih
 	default:
 		return sdkutil.LoggingNewErrorf("unsupported DID method: %s", method)
@@ -141,7 +186,7 @@ func (s *Service) ResolveDID(request ResolveDIDRequest) (*ResolveDIDResponse, er
 	if request.DID == "" {
 		return nil, sdkutil.LoggingNewError("cannot resolve empty DID")
 	}
-	resolved, err := s.Resolve(context.Background(), request.DID)
+	resolved, err := s.Resolve(context.Background(), request.DID, request.Options)
 	if err != nil {
 		return nil, err
 	}
@@ -152,8 +197,25 @@ func (s *Service) ResolveDID(request ResolveDIDRequest) (*ResolveDIDResponse, er
 	}, nil
 }
 
+// DereferenceDIDURL dereferences a DID URL to the DID document, or to a verification method, service or service
+// endpoint selected from it. The DID is resolved the same way ResolveDID resolves it.
+func (s *Service) DereferenceDIDURL(ctx context.Context, request DereferenceDIDURLRequest) (*DereferenceDIDURLResponse, error) {
+	if request.DIDURL == "" {
+		return nil, sdkutil.LoggingNewError("cannot dereference empty DID URL")
+	}
+	dereferenced, err := resolution.Dereference(ctx, s.resolver, request.DIDURL, request.Options)
+	if err != nil {
+		return nil, err
+	}
+	return &DereferenceDIDURLResponse{
+		DereferencingMetadata: dereferenced.DereferencingMetadata,
+		ContentStream:         dereferenced.ContentStream,
+		ContentMetadata:       dereferenced.ContentMetadata,
+	}, nil
+}
+
 func (s *Service) Resolve(ctx context.Context, did string, opts ...didresolution.Option) (*didresolution.Result, error) {
-	return s.resolver.Resolve(ctx, did, opts)
+	return s.resolver.Resolve(ctx, did, opts...)
 }
 
 func (s *Service) GetSupportedMethods() GetSupportedMethodsResponse {
@@ -169,10 +231,57 @@ func (s *Service) CreateDIDByMethod(ctx context.Context, request CreateDIDReques
 	if err != nil {
 		return nil, sdkutil.LoggingErrorMsgf(err, "could not get handler for method<%s>", request.Method)
 	}
-	return handler.CreateDID(ctx, request)
+	created, err := handler.CreateDID(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	// the DID may have been cached as one that could not be resolved
+	s.invalidateResolution(ctx, created.DID.ID)
+	return created, nil
 }
 
 func (s *Service) UpdateIONDID(ctx context.Context, request UpdateIONDIDRequest) (*UpdateIONDIDResponse, error) {
+	ionHandlerImpl, err := s.getIONHandler()
+	if err != nil {
+		return nil, err
+	}
+	updated, err := ionHandlerImpl.UpdateDID(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidateResolution(ctx, request.DID.String())
+	return updated, nil
+}
+
+// RecoverIONDID replaces the document state of a did:ion DID, and rotates its update and recovery keys.
+func (s *Service) RecoverIONDID(ctx context.Context, request RecoverIONDIDRequest) (*RecoverIONDIDResponse, error) {
+	ionHandlerImpl, err := s.getIONHandler()
+	if err != nil {
+		return nil, err
+	}
+	recovered, err := ionHandlerImpl.RecoverDID(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidateResolution(ctx, request.DID.String())
+	return recovered, nil
+}
+
+// DeactivateIONDID permanently deactivates a did:ion DID.
+func (s *Service) DeactivateIONDID(ctx context.Context, request DeactivateIONDIDRequest) (*DeactivateIONDIDResponse, error) {
+	ionHandlerImpl, err := s.getIONHandler()
+	if err != nil {
+		return nil, err
+	}
+	deactivated, err := ionHandlerImpl.DeactivateDID(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidateResolution(ctx, request.DID.String())
+	return deactivated, nil
+}
+
+func (s *Service) getIONHandler() (*ionHandler, error) {
 	handler, err := s.getHandler(didsdk.IONMethod)
 	if err != nil {
 		return nil, sdkutil.LoggingErrorMsgf(err, "could not get handler for method<%s>", didsdk.IONMethod)
@@ -181,7 +290,83 @@ func (s *Service) UpdateIONDID(ctx context.Context, request UpdateIONDIDRequest)
 	if !ok {
 		return nil, errors.New("cannot assert that handler is an ionHandler")
 	}
-	return ionHandlerImpl.UpdateDID(ctx, request)
+	return ionHandlerImpl, nil
+}
+
+// RunIONOperationProcessor processes ION operations with ProcessIONOperations, until the context is done. It returns
+// right away when ION isn't supported, or processing is disabled.
+func (s *Service) RunIONOperationProcessor(ctx context.Context) {
+	if _, ok := s.handlers[didsdk.IONMethod]; !ok || s.config.IONOperationPollInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(s.config.IONOperationPollInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.ProcessIONOperations(ctx); err != nil {
+			logrus.WithError(err).Error("could not process ION operations")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessIONOperations resubmits the ION operations the ION node was unavailable for once they are due, and marks
+// anchored ones as done once they are published. Their progress is tracked by operations under
+// anchor.ParentResource.
+func (s *Service) ProcessIONOperations(ctx context.Context) error {
+	ionHandlerImpl, err := s.getIONHandler()
+	if err != nil {
+		return err
+	}
+	updatedDIDs, err := ionHandlerImpl.ProcessOperations(ctx)
+	for _, id := range updatedDIDs {
+		s.invalidateResolution(ctx, id)
+	}
+	return err
+}
+
+// UpdateDIDByMethod applies changes to the document of a DID, for methods whose handler is a MethodUpdater.
+func (s *Service) UpdateDIDByMethod(ctx context.Context, request UpdateDIDRequest) (*UpdateDIDResponse, error) {
+	handler, err := s.getHandler(request.Method)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsgf(err, "could not get handler for method<%s>", request.Method)
+	}
+	updater, ok := handler.(MethodUpdater)
+	if !ok {
+		return nil, sdkutil.LoggingNewErrorf("updating DIDs is not supported for method<%s>", request.Method)
+	}
+	updated, err := updater.UpdateDIDDocument(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidateResolution(ctx, request.ID)
+	return updated, nil
+}
+
+// GetDIDVersions returns every version of a DID's document stored in the service, oldest first. Versions are kept by
+// the handler of the DID's method when it is a MethodVersioner.
+func (s *Service) GetDIDVersions(ctx context.Context, request GetDIDVersionsRequest) (*GetDIDVersionsResponse, error) {
+	method, err := util.GetMethodForDID(request.ID)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsgf(err, "could not get method of DID: %s", request.ID)
+	}
+	if handler, err := s.getHandler(method); err == nil {
+		if versioner, ok := handler.(MethodVersioner); ok {
+			versions, err := versioner.GetDIDVersions(ctx, request.ID)
+			if err != nil {
+				return nil, sdkutil.LoggingErrorMsgf(err, "could not get versions of DID: %s", request.ID)
+			}
+			return &GetDIDVersionsResponse{Versions: versions}, nil
+		}
+	}
+	storedDIDs, err := s.storage.GetDIDVersions(ctx, request.ID)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsgf(err, "could not get versions of DID: %s", request.ID)
+	}
+	return &GetDIDVersionsResponse{Versions: toDIDVersions(storedDIDs)}, nil
 }
 
 func (s *Service) GetDIDByMethod(ctx context.Context, request GetDIDRequest) (*GetDIDResponse, error) {
@@ -192,6 +377,30 @@ func (s *Service) GetDIDByMethod(ctx context.Context, request GetDIDRequest) (*G
 	return handler.GetDID(ctx, request)
 }
 
+// GetHostedWebDID returns the document of a did:web DID stored in the service, for the service to host it. It returns
+// nil when the DID is not stored in the service, or was deleted.
+func (s *Service) GetHostedWebDID(ctx context.Context, id string) (*didsdk.Document, error) {
+	if method, err := util.GetMethodForDID(id); err != nil || method != didsdk.WebMethod {
+		return nil, sdkutil.LoggingNewErrorf("not a did:web DID: %s", id)
+	}
+	exists, err := s.storage.DIDExists(ctx, id)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsgf(err, "could not check whether DID<%s> exists", id)
+	}
+	if !exists {
+		return nil, nil
+	}
+	gotDID, err := s.storage.GetDIDDefault(ctx, id)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsgf(err, "could not get DID: %s", id)
+	}
+	if gotDID.IsSoftDeleted() {
+		return nil, nil
+	}
+	document := gotDID.GetDocument()
+	return &document, nil
+}
+
 func (s *Service) GetKeyFromDID(ctx context.Context, request GetKeyFromDIDRequest) (*GetKeyFromDIDResponse, error) {
 	resolved, err := s.Resolve(ctx, request.ID)
 	if err != nil {
@@ -218,15 +427,114 @@ func (s *Service) ListDIDsByMethod(ctx context.Context, request ListDIDsRequest)
 	if request.Deleted {
 		return handler.ListDeletedDIDs(ctx)
 	}
+	if request.IncludeDeleted {
+		return s.listAllDIDs(ctx, request)
+	}
 	return handler.ListDIDs(ctx, request.PageRequest)
 }
 
+// listAllDIDs lists the DIDs of a method, whether they were soft-deleted or not. Every method stores its DIDs with the
+// fields of a DefaultStoredDID, which is all that's needed to list them.
+func (s *Service) listAllDIDs(ctx context.Context, request ListDIDsRequest) (*ListDIDsResponse, error) {
+	gotDIDs, err := s.storage.ListDIDsPage(ctx, request.Method.String(), request.PageRequest, new(DefaultStoredDID))
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsgf(err, "could not list DIDs for method<%s>", request.Method)
+	}
+	dids := make([]didsdk.Document, 0, len(gotDIDs.DIDs))
+	for _, gotDID := range gotDIDs.DIDs {
+		dids = append(dids, gotDID.GetDocument())
+	}
+	return &ListDIDsResponse{DIDs: dids, NextPageToken: gotDIDs.NextPageToken}, nil
+}
+
 func (s *Service) SoftDeleteDIDByMethod(ctx context.Context, request DeleteDIDRequest) error {
 	handler, err := s.getHandler(request.Method)
 	if err != nil {
 		return sdkutil.LoggingErrorMsgf(err, "could not get handler for method<%s>", request.Method)
 	}
-	return handler.SoftDeleteDID(ctx, request)
+	if err = handler.SoftDeleteDID(ctx, request); err != nil {
+		return err
+	}
+	s.invalidateResolution(ctx, request.ID)
+	return nil
+}
+
+// RestoreDIDByMethod restores a soft-deleted DID, so that it's listed again.
+func (s *Service) RestoreDIDByMethod(ctx context.Context, request RestoreDIDRequest) error {
+	handler, err := s.getHandler(request.Method)
+	if err != nil {
+		return sdkutil.LoggingErrorMsgf(err, "could not get handler for method<%s>", request.Method)
+	}
+	if err = handler.RestoreDID(ctx, request); err != nil {
+		return err
+	}
+	s.invalidateResolution(ctx, request.ID)
+	return nil
+}
+
+// PurgeDIDByMethod permanently removes a DID that was soft-deleted, along with every key whose controller is the DID.
+// The keys and the DID are removed in one transaction, so a purge that fails leaves both in place. It has no effect
+// on DIDs anchored in a network, such as did:ion DIDs.
+func (s *Service) PurgeDIDByMethod(ctx context.Context, request PurgeDIDRequest) (*PurgeDIDResponse, error) {
+	if _, err := s.getHandler(request.Method); err != nil {
+		return nil, sdkutil.LoggingErrorMsgf(err, "could not get handler for method<%s>", request.Method)
+	}
+	id := request.ID
+	ns, err := getNamespaceForDID(id)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsgf(err, "could not get namespace of DID: %s", id)
+	}
+	watchKeys := []storage.WatchKey{
+		{Namespace: ns, Key: id},
+		{Namespace: versionsNamespace, Key: id},
+	}
+	execResp, err := s.storage.db.Execute(ctx, func(ctx context.Context, tx storage.Tx) (any, error) {
+		keyStore, err := s.keyStoreFactory(tx)
+		if err != nil {
+			return nil, errors.Wrap(err, "creating key store service")
+		}
+		didStorage, err := s.didStorageFactory(tx)
+		if err != nil {
+			return nil, errors.Wrap(err, "creating did storage")
+		}
+
+		gotDID, err := didStorage.GetDIDDefault(ctx, id)
+		if err != nil {
+			return nil, sdkutil.LoggingErrorMsgf(err, "could not get DID: %s", id)
+		}
+		if !gotDID.IsSoftDeleted() {
+			return nil, sdkutil.LoggingNewErrorf("did with id<%s> must be soft deleted before it is purged", id)
+		}
+
+		keys, err := keyStore.ListKeysByController(ctx, keystore.ListKeysByControllerRequest{Controller: id})
+		if err != nil {
+			return nil, sdkutil.LoggingErrorMsgf(err, "could not list keys of DID: %s", id)
+		}
+		for _, keyID := range keys.KeyIDs {
+			if err = keyStore.DeleteKey(ctx, keystore.DeleteKeyRequest{ID: keyID}); err != nil {
+				return nil, sdkutil.LoggingErrorMsgf(err, "could not delete key<%s> of DID: %s", keyID, id)
+			}
+		}
+		if err = didStorage.PurgeDID(ctx, id); err != nil {
+			return nil, err
+		}
+		return keys.KeyIDs, nil
+	}, watchKeys)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidateResolution(ctx, id)
+	return &PurgeDIDResponse{DeletedKeyIDs: execResp.([]string)}, nil
+}
+
+// invalidateResolution removes the cached resolution of a DID whose document changed, if resolutions are cached.
+func (s *Service) invalidateResolution(ctx context.Context, id string) {
+	if s.resolutionCache == nil {
+		return
+	}
+	if err := s.resolutionCache.Invalidate(ctx, id); err != nil {
+		logrus.WithError(err).Warnf("could not invalidate cached resolution of DID: %s", id)
+	}
 }
 
 func (s *Service) getHandler(method didsdk.Method) (MethodHandler, error) {
@@ -17,17 +17,26 @@ import (
 )
 
 const (
-	namespace    = "did"
-	keyNamespace = "key"
-	webNamespace = "web"
-	ionNamespace = "ion"
+	namespace     = "did"
+	keyNamespace  = "key"
+	webNamespace  = "web"
+	ionNamespace  = "ion"
+	peerNamespace = "peer"
+	jwkNamespace  = "jwk"
+	pkhNamespace  = "pkh"
+
+	// versionsNamespace holds the earlier versions of updated DIDs, keyed by DID.
+	versionsNamespace = "did-versions"
 )
 
 var (
 	didMethodToNamespace = map[string]string{
-		keyNamespace: storage.MakeNamespace(namespace, keyNamespace),
-		webNamespace: storage.MakeNamespace(namespace, webNamespace),
-		ionNamespace: storage.MakeNamespace(namespace, ionNamespace),
+		keyNamespace:  storage.MakeNamespace(namespace, keyNamespace),
+		webNamespace:  storage.MakeNamespace(namespace, webNamespace),
+		ionNamespace:  storage.MakeNamespace(namespace, ionNamespace),
+		peerNamespace: storage.MakeNamespace(namespace, peerNamespace),
+		jwkNamespace:  storage.MakeNamespace(namespace, jwkNamespace),
+		pkhNamespace:  storage.MakeNamespace(namespace, pkhNamespace),
 	}
 )
 
@@ -40,10 +49,21 @@ type StoredDID interface {
 }
 
 // DefaultStoredDID is the default implementation of StoredDID if no other implementation requirements are needed.
+// Updating a DID stores its document as a new version, with the superseded versions kept in the DID's history.
 type DefaultStoredDID struct {
 	ID          string       `json:"id"`
 	DID         did.Document `json:"did"`
 	SoftDeleted bool         `json:"softDeleted"`
+	Version     int          `json:"version,omitempty"`
+	UpdatedAt   string       `json:"updatedAt,omitempty"`
+}
+
+// GetVersion returns the version of the document. DIDs stored before versioning was introduced are version 1.
+func (d DefaultStoredDID) GetVersion() int {
+	if d.Version == 0 {
+		return 1
+	}
+	return d.Version
 }
 
 func (d DefaultStoredDID) GetID() string {
@@ -219,12 +239,117 @@ func (ds *Storage) DeleteDID(ctx context.Context, id string) error {
 	if err != nil {
 		return sdkutil.LoggingErrorMsg(err, couldNotGetDIDErr)
 	}
-	if err = ds.db.Delete(ctx, ns, id); err != nil {
+	if err = ds.tx.Delete(ctx, ns, id); err != nil {
 		return sdkutil.LoggingErrorMsgf(err, "could not delete DID: %s", id)
 	}
 	return nil
 }
 
+// RestoreDIDDefault clears the SoftDeleted flag of a DID that is stored as a DefaultStoredDID.
+func (ds *Storage) RestoreDIDDefault(ctx context.Context, id string) error {
+	gotDID, err := ds.GetDIDDefault(ctx, id)
+	if err != nil {
+		return errors.Wrapf(err, "getting DID: %s", id)
+	}
+	if !gotDID.IsSoftDeleted() {
+		return errors.Errorf("did with id<%s> is not deleted", id)
+	}
+	gotDID.SoftDeleted = false
+	return ds.StoreDID(ctx, *gotDID)
+}
+
+// PurgeDID removes a DID from storage, along with the earlier versions of its document and the state of its did:ion
+// operations.
+func (ds *Storage) PurgeDID(ctx context.Context, id string) error {
+	for _, ns := range []string{versionsNamespace, updateRequestStatesNamespace} {
+		exists, err := ds.db.Exists(ctx, ns, id)
+		if err != nil {
+			return sdkutil.LoggingErrorMsgf(err, "could not check whether DID<%s> has %s", id, ns)
+		}
+		if !exists {
+			continue
+		}
+		if err = ds.tx.Delete(ctx, ns, id); err != nil {
+			return sdkutil.LoggingErrorMsgf(err, "could not delete %s of DID: %s", ns, id)
+		}
+	}
+	return ds.DeleteDID(ctx, id)
+}
+
+// UpdateDID stores updated as the latest version of the DID with the same ID. The version it replaces is moved to the
+// DID's history so that earlier documents can be audited.
+func (ds *Storage) UpdateDID(ctx context.Context, updated DefaultStoredDID) (*DefaultStoredDID, error) {
+	id := updated.ID
+	current, err := ds.GetDIDDefault(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	history, err := ds.getDIDHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	current.Version = current.GetVersion()
+	history = append(history, *current)
+	updated.Version = current.Version + 1
+	if err = ds.storeDIDHistory(ctx, id, history); err != nil {
+		return nil, err
+	}
+	if err = ds.StoreDID(ctx, updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// GetDIDVersions returns every version of the DID with the given id, oldest first.
+func (ds *Storage) GetDIDVersions(ctx context.Context, id string) ([]DefaultStoredDID, error) {
+	current, err := ds.GetDIDDefault(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	history, err := ds.getDIDHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	current.Version = current.GetVersion()
+	return append(history, *current), nil
+}
+
+func toDIDVersions(storedDIDs []DefaultStoredDID) []DIDVersion {
+	versions := make([]DIDVersion, 0, len(storedDIDs))
+	for _, storedDID := range storedDIDs {
+		versions = append(versions, DIDVersion{
+			Version:   storedDID.GetVersion(),
+			UpdatedAt: storedDID.UpdatedAt,
+			DID:       storedDID.DID,
+		})
+	}
+	return versions
+}
+
+func (ds *Storage) getDIDHistory(ctx context.Context, id string) ([]DefaultStoredDID, error) {
+	historyBytes, err := ds.db.Read(ctx, versionsNamespace, id)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsgf(err, "reading version history for DID: %s", id)
+	}
+	if len(historyBytes) == 0 {
+		return nil, nil
+	}
+	var history []DefaultStoredDID
+	if err = json.Unmarshal(historyBytes, &history); err != nil {
+		return nil, sdkutil.LoggingErrorMsgf(err, "unmarshalling version history for DID: %s", id)
+	}
+	return history, nil
+}
+
+func (ds *Storage) storeDIDHistory(ctx context.Context, id string, history []DefaultStoredDID) error {
+	historyBytes, err := json.Marshal(history)
+	if err != nil {
+		return sdkutil.LoggingErrorMsgf(err, "marshalling version history for DID: %s", id)
+	}
+	return ds.tx.Write(ctx, versionsNamespace, id, historyBytes)
+}
+
 func validateOut(out StoredDID) error {
 	if out == nil {
 		return errors.New("cannot be nil")
@@ -3,9 +3,13 @@ package did
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/TBD54566975/ssi-sdk/crypto"
+	"github.com/TBD54566975/ssi-sdk/cryptosuite"
 	"github.com/TBD54566975/ssi-sdk/did"
+	"github.com/TBD54566975/ssi-sdk/did/ion"
 	"github.com/TBD54566975/ssi-sdk/did/web"
 	"github.com/TBD54566975/ssi-sdk/util"
 	"github.com/mr-tron/base58"
@@ -13,22 +17,31 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/tbd54566975/ssi-service/pkg/service/common"
 	"github.com/tbd54566975/ssi-service/pkg/service/keystore"
+	"github.com/tbd54566975/ssi-service/pkg/storage"
 )
 
-func NewWebHandler(s *Storage, ks *keystore.Service) (MethodHandler, error) {
+func NewWebHandler(s *Storage, ks *keystore.Service, factory keystore.ServiceFactory, storageFactory StorageFactory) (MethodHandler, error) {
 	if s == nil {
 		return nil, errors.New("storage cannot be empty")
 	}
 	if ks == nil {
 		return nil, errors.New("keystore cannot be empty")
 	}
-	return &webHandler{method: did.WebMethod, storage: s, keyStore: ks}, nil
+	return &webHandler{
+		method:            did.WebMethod,
+		storage:           s,
+		keyStore:          ks,
+		keyStoreFactory:   factory,
+		didStorageFactory: storageFactory,
+	}, nil
 }
 
 type webHandler struct {
-	method   did.Method
-	storage  *Storage
-	keyStore *keystore.Service
+	method            did.Method
+	storage           *Storage
+	keyStore          *keystore.Service
+	keyStoreFactory   keystore.ServiceFactory
+	didStorageFactory StorageFactory
 }
 
 var _ MethodHandler = (*webHandler)(nil)
@@ -190,3 +203,344 @@ func (h *webHandler) SoftDeleteDID(ctx context.Context, request DeleteDIDRequest
 
 	return h.storage.StoreDID(ctx, *gotStoredDID)
 }
+
+// RestoreDID clears the SoftDeleted flag of a did:web DID.
+func (h *webHandler) RestoreDID(ctx context.Context, request RestoreDIDRequest) error {
+	logrus.Debugf("restoring DID: %+v", request)
+
+	return h.storage.RestoreDIDDefault(ctx, request.ID)
+}
+
+var (
+	_ MethodUpdater   = (*webHandler)(nil)
+	_ MethodVersioner = (*webHandler)(nil)
+)
+
+// GetDIDVersions returns every version of a did:web document stored in the service, oldest first.
+func (h *webHandler) GetDIDVersions(ctx context.Context, id string) ([]DIDVersion, error) {
+	storedDIDs, err := h.storage.GetDIDVersions(ctx, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting versions of DID: %s", id)
+	}
+	return toDIDVersions(storedDIDs), nil
+}
+
+// UpdateDIDDocument applies the requested changes to a did:web document, and stores it as a new version. Keys for
+// added verification methods are generated in the keystore, and keys of removed verification methods are revoked.
+func (h *webHandler) UpdateDIDDocument(ctx context.Context, request UpdateDIDRequest) (*UpdateDIDResponse, error) {
+	logrus.Debugf("updating DID: %+v", request)
+
+	id := request.ID
+	ns, err := getNamespaceForDID(id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting namespace of DID: %s", id)
+	}
+	watchKeys := []storage.WatchKey{
+		{Namespace: ns, Key: id},
+		{Namespace: versionsNamespace, Key: id},
+	}
+
+	// the keystore changes are made in the same transaction as the document, so that they're only committed along
+	// with it, and are discarded when the update fails partway through
+	execResp, err := h.storage.db.Execute(ctx, func(ctx context.Context, tx storage.Tx) (any, error) {
+		keyStore, err := h.keyStoreFactory(tx)
+		if err != nil {
+			return nil, errors.Wrap(err, "creating key store service")
+		}
+		didStorage, err := h.didStorageFactory(tx)
+		if err != nil {
+			return nil, errors.Wrap(err, "creating did storage")
+		}
+
+		gotStoredDID, err := didStorage.GetDIDDefault(ctx, id)
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting DID: %s", id)
+		}
+		if gotStoredDID.IsSoftDeleted() {
+			return nil, fmt.Errorf("did with id<%s> has been deleted", id)
+		}
+
+		updater := documentUpdater{document: gotStoredDID.DID, keyStore: keyStore}
+		if err = updater.apply(ctx, request.StateChange); err != nil {
+			return nil, errors.Wrapf(err, "updating DID: %s", id)
+		}
+
+		updatedDID, err := didStorage.UpdateDID(ctx, DefaultStoredDID{
+			ID:        id,
+			DID:       updater.document,
+			UpdatedAt: time.Now().Format(time.RFC3339),
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "could not store updated did:web value")
+		}
+		return updatedDID, nil
+	}, watchKeys)
+	if err != nil {
+		return nil, err
+	}
+	updatedDID := execResp.(*DefaultStoredDID)
+	return &UpdateDIDResponse{DID: updatedDID.DID, Version: updatedDID.Version}, nil
+}
+
+// ErrInvalidStateChange is returned when a DocumentStateChange can't be applied to a DID document, such as when it
+// removes a verification method the document doesn't have.
+var ErrInvalidStateChange = errors.New("invalid state change")
+
+// documentUpdater applies a DocumentStateChange to a DID document whose keys are held by the keystore.
+type documentUpdater struct {
+	document did.Document
+	keyStore *keystore.Service
+}
+
+func (u *documentUpdater) apply(ctx context.Context, change DocumentStateChange) error {
+	for _, service := range change.ServicesToAdd {
+		if !service.IsValid() {
+			return errors.Wrapf(ErrInvalidStateChange, "service<%s> is not valid", service.ID)
+		}
+		if u.serviceIndex(service.ID) >= 0 {
+			return errors.Wrapf(ErrInvalidStateChange, "service<%s> already exists", service.ID)
+		}
+		service.ID = u.qualify(service.ID)
+		u.document.Services = append(u.document.Services, service)
+	}
+	for _, serviceID := range change.ServiceIDsToRemove {
+		i := u.serviceIndex(serviceID)
+		if i < 0 {
+			return errors.Wrapf(ErrInvalidStateChange, "service<%s> does not exist", serviceID)
+		}
+		u.document.Services = append(u.document.Services[:i], u.document.Services[i+1:]...)
+	}
+
+	for _, toAdd := range change.VerificationMethodsToAdd {
+		if err := u.addVerificationMethod(ctx, toAdd); err != nil {
+			return err
+		}
+	}
+	for _, vmID := range change.VerificationMethodIDsToRemove {
+		if err := u.removeVerificationMethod(ctx, u.qualify(vmID)); err != nil {
+			return err
+		}
+	}
+	for _, relationships := range change.VerificationRelationshipsToSet {
+		vm := u.verificationMethod(u.qualify(relationships.ID))
+		if vm == nil {
+			return errors.Wrapf(ErrInvalidStateChange, "verification method<%s> does not exist", relationships.ID)
+		}
+		if err := u.setRelationships(*vm, relationships.Purposes); err != nil {
+			return err
+		}
+	}
+	for _, vmID := range change.VerificationMethodIDsToRotate {
+		if err := u.rotateVerificationMethod(ctx, u.qualify(vmID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *documentUpdater) addVerificationMethod(ctx context.Context, toAdd VerificationMethodToAdd) error {
+	vmID := u.qualify(toAdd.ID)
+	if u.verificationMethod(vmID) != nil {
+		return errors.Wrapf(ErrInvalidStateChange, "verification method<%s> already exists", vmID)
+	}
+	if err := validatePurposes(toAdd.Purposes); err != nil {
+		return err
+	}
+	if _, err := u.keyStore.GetKeyDetails(ctx, keystore.GetKeyDetailsRequest{ID: vmID}); err == nil {
+		return errors.Wrapf(ErrInvalidStateChange, "key<%s> already exists", vmID)
+	}
+	generated, err := u.keyStore.GenerateKey(ctx, keystore.GenerateKeyRequest{
+		ID:         vmID,
+		Type:       toAdd.KeyType,
+		Controller: u.document.ID,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "generating key for verification method<%s>", vmID)
+	}
+	publicKeyJWK := generated.PublicKeyJWK
+	publicKeyJWK.KID = vmID
+	vm := did.VerificationMethod{
+		ID:           vmID,
+		Type:         cryptosuite.JSONWebKey2020Type,
+		Controller:   u.document.ID,
+		PublicKeyJWK: &publicKeyJWK,
+	}
+	u.document.VerificationMethod = append(u.document.VerificationMethod, vm)
+	return u.setRelationships(vm, toAdd.Purposes)
+}
+
+func (u *documentUpdater) removeVerificationMethod(ctx context.Context, vmID string) error {
+	vm := u.verificationMethod(vmID)
+	if vm == nil {
+		return errors.Wrapf(ErrInvalidStateChange, "verification method<%s> does not exist", vmID)
+	}
+	if err := u.setRelationships(*vm, nil); err != nil {
+		return err
+	}
+	vms := make([]did.VerificationMethod, 0, len(u.document.VerificationMethod))
+	for _, existing := range u.document.VerificationMethod {
+		if existing.ID != vmID {
+			vms = append(vms, existing)
+		}
+	}
+	u.document.VerificationMethod = vms
+
+	// the key may not be held by the keystore, e.g. when the document was imported
+	if _, err := u.keyStore.GetKeyDetails(ctx, keystore.GetKeyDetailsRequest{ID: vmID}); err != nil {
+		return nil
+	}
+	if err := u.keyStore.RevokeKey(ctx, keystore.RevokeKeyRequest{ID: vmID}); err != nil {
+		return errors.Wrapf(err, "revoking key of verification method<%s>", vmID)
+	}
+	return nil
+}
+
+func (u *documentUpdater) rotateVerificationMethod(ctx context.Context, vmID string) error {
+	vm := u.verificationMethod(vmID)
+	if vm == nil {
+		return errors.Wrapf(ErrInvalidStateChange, "verification method<%s> does not exist", vmID)
+	}
+	keyDetails, err := u.keyStore.GetKeyDetails(ctx, keystore.GetKeyDetailsRequest{ID: vmID})
+	if err != nil {
+		return errors.Wrapf(err, "getting key of verification method<%s>", vmID)
+	}
+	if keyDetails.Controller != u.document.ID {
+		return errors.Wrapf(ErrInvalidStateChange, "key of verification method<%s> is not controlled by %s", vmID, u.document.ID)
+	}
+	rotated, err := u.keyStore.RotateKey(ctx, keystore.RotateKeyRequest{ID: vmID})
+	if err != nil {
+		return errors.Wrapf(err, "rotating key of verification method<%s>", vmID)
+	}
+	publicKeyJWK := rotated.PublicKeyJWK
+	publicKeyJWK.KID = vm.ID
+	if vm.PublicKeyJWK != nil && vm.PublicKeyJWK.KID != "" {
+		publicKeyJWK.KID = vm.PublicKeyJWK.KID
+	}
+	vm.Type = cryptosuite.JSONWebKey2020Type
+	vm.PublicKeyJWK = &publicKeyJWK
+	vm.PublicKeyBase58 = ""
+	vm.PublicKeyMultibase = ""
+	return nil
+}
+
+// setRelationships makes the verification method referenced by exactly the verification relationships given.
+func (u *documentUpdater) setRelationships(vm did.VerificationMethod, purposes []ion.PublicKeyPurpose) error {
+	if err := validatePurposes(purposes); err != nil {
+		return err
+	}
+	relationships := map[ion.PublicKeyPurpose]*[]did.VerificationMethodSet{
+		ion.Authentication:       &u.document.Authentication,
+		ion.AssertionMethod:      &u.document.AssertionMethod,
+		ion.KeyAgreement:         &u.document.KeyAgreement,
+		ion.CapabilityInvocation: &u.document.CapabilityInvocation,
+		ion.CapabilityDelegation: &u.document.CapabilityDelegation,
+	}
+	for purpose, relationship := range relationships {
+		*relationship = withoutReferences(*relationship, referencesOf(vm))
+		for _, p := range purposes {
+			if p == purpose {
+				*relationship = append(*relationship, vm.ID)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func validatePurposes(purposes []ion.PublicKeyPurpose) error {
+	for _, purpose := range purposes {
+		switch purpose {
+		case ion.Authentication, ion.AssertionMethod, ion.KeyAgreement, ion.CapabilityInvocation, ion.CapabilityDelegation:
+		default:
+			return errors.Wrapf(ErrInvalidStateChange, "unknown verification relationship: %s", purpose)
+		}
+	}
+	return nil
+}
+
+func (u *documentUpdater) verificationMethod(vmID string) *did.VerificationMethod {
+	for i := range u.document.VerificationMethod {
+		if u.document.VerificationMethod[i].ID == vmID {
+			return &u.document.VerificationMethod[i]
+		}
+	}
+	return nil
+}
+
+func (u *documentUpdater) serviceIndex(serviceID string) int {
+	for i, service := range u.document.Services {
+		if service.ID == serviceID || service.ID == u.qualify(serviceID) {
+			return i
+		}
+	}
+	return -1
+}
+
+// qualify turns an ID relative to the DID, like `#key-1` or `key-1`, into a fully qualified one.
+func (u *documentUpdater) qualify(id string) string {
+	if strings.HasPrefix(id, "did:") {
+		return id
+	}
+	return u.document.ID + "#" + strings.TrimPrefix(id, "#")
+}
+
+// referencesOf returns the IDs a verification relationship may reference a verification method by: its own ID, or
+// the `kid` of its key.
+func referencesOf(vm did.VerificationMethod) map[string]bool {
+	references := map[string]bool{vm.ID: true}
+	if vm.PublicKeyJWK != nil && vm.PublicKeyJWK.KID != "" {
+		references[vm.PublicKeyJWK.KID] = true
+	}
+	return references
+}
+
+// withoutReferences removes the references to a verification method from a verification relationship. Entries may be
+// references, embedded verification methods, or sets of references.
+func withoutReferences(relationship []did.VerificationMethodSet, references map[string]bool) []did.VerificationMethodSet {
+	kept := make([]did.VerificationMethodSet, 0, len(relationship))
+	for _, entry := range relationship {
+		switch e := entry.(type) {
+		case string:
+			if references[e] {
+				continue
+			}
+		case []string:
+			var remaining []string
+			for _, ref := range e {
+				if !references[ref] {
+					remaining = append(remaining, ref)
+				}
+			}
+			if len(remaining) == 0 {
+				continue
+			}
+			entry = remaining
+		case []did.VerificationMethodSet:
+			nested := withoutReferences(e, references)
+			if len(nested) == 0 {
+				continue
+			}
+			entry = nested
+		case []any:
+			nested := make([]did.VerificationMethodSet, 0, len(e))
+			for _, ref := range e {
+				nested = append(nested, ref)
+			}
+			nested = withoutReferences(nested, references)
+			if len(nested) == 0 {
+				continue
+			}
+			entry = nested
+		case did.VerificationMethod:
+			if references[e.ID] {
+				continue
+			}
+		case map[string]any:
+			if vmID, ok := e["id"].(string); ok && references[vmID] {
+				continue
+			}
+		}
+		kept = append(kept, entry)
+	}
+	return kept
+}
@@ -18,6 +18,7 @@ const (
 	Operation        Type = "operation"
 	Webhook          Type = "webhook"
 	DIDConfiguration Type = "did_configuration"
+	OID4VCI          Type = "oid4vci"
 
 	StatusReady    StatusState = "ready"
 	StatusNotReady StatusState = "not_ready"
@@ -0,0 +1,252 @@
+package keystore
+
+import (
+	"bytes"
+	"context"
+	gocrypto "crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/TBD54566975/ssi-sdk/crypto"
+	"github.com/TBD54566975/ssi-sdk/crypto/jwx"
+	"github.com/goccy/go-json"
+	"github.com/mr-tron/base58"
+	"github.com/pkg/errors"
+
+	"github.com/tbd54566975/ssi-service/internal/keyaccess"
+)
+
+// BackendType identifies where the private material of a key is held.
+type BackendType string
+
+const (
+	// LocalBackend keeps private keys encrypted in the service's own storage. It is the default backend.
+	LocalBackend BackendType = "local"
+
+	// RemoteBackend keeps private keys in a remote signer that is reached over HTTP. The service only ever stores the
+	// public key and a reference to the remote key.
+	RemoteBackend BackendType = "remote"
+)
+
+func (b BackendType) String() string {
+	return string(b)
+}
+
+// KeyBackend holds private key material on behalf of the keystore and provides access to it for signing.
+type KeyBackend interface {
+	// Type returns the identifier stored alongside every key owned by this backend.
+	Type() BackendType
+
+	// GenerateKey creates a new key of the given type. The returned StoredKey has the backend specific fields set;
+	// identifying fields such as the ID and controller are set by the caller.
+	GenerateKey(ctx context.Context, keyType crypto.KeyType) (*StoredKey, error)
+
+	// PrivateKey returns a private key that can be used for signing with the given key. Backends that do not expose
+	// private material return a keyaccess.ExternalSigner.
+	PrivateKey(ctx context.Context, key StoredKey) (gocrypto.PrivateKey, error)
+}
+
+// localKeyBackend is the KeyBackend for keys whose private material is stored in the service's own storage.
+type localKeyBackend struct{}
+
+var _ KeyBackend = (*localKeyBackend)(nil)
+
+func (localKeyBackend) Type() BackendType {
+	return LocalBackend
+}
+
+func (localKeyBackend) GenerateKey(_ context.Context, keyType crypto.KeyType) (*StoredKey, error) {
+	_, privKey, err := crypto.GenerateKeyByKeyType(keyType)
+	if err != nil {
+		return nil, errors.Wrapf(err, "generating key of type: %s", keyType)
+	}
+	privKeyBytes, err := crypto.PrivKeyToBytes(privKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "converting private key to bytes")
+	}
+	return &StoredKey{
+		KeyType:   keyType,
+		Base58Key: base58.Encode(privKeyBytes),
+	}, nil
+}
+
+func (localKeyBackend) PrivateKey(_ context.Context, key StoredKey) (gocrypto.PrivateKey, error) {
+	keyBytes, err := base58.Decode(key.Base58Key)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not deserialize key from base58")
+	}
+	privKey, err := crypto.BytesToPrivKey(keyBytes, key.KeyType)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not reconstruct private key from storage")
+	}
+	return privKey, nil
+}
+
+// RemoteGenerateKeyRequest is the body sent to `POST {remote_signer_url}/keys` to create a key in the remote signer.
+type RemoteGenerateKeyRequest struct {
+	Type crypto.KeyType `json:"type"`
+}
+
+// RemoteGenerateKeyResponse is the body returned by the remote signer after creating a key.
+type RemoteGenerateKeyResponse struct {
+	// KeyRef is the identifier of the key inside the remote signer.
+	KeyRef       string           `json:"keyRef"`
+	PublicKeyJWK jwx.PublicKeyJWK `json:"publicKeyJwk"`
+}
+
+// RemoteSignRequest is the body sent to `POST {remote_signer_url}/keys/{keyRef}/sign`. Its fields follow the
+// semantics of crypto.Signer: Digest is the hashed message, or the full message when Hash is empty (as with Ed25519).
+type RemoteSignRequest struct {
+	// Digest is base64url encoded without padding.
+	Digest string `json:"digest"`
+
+	// Hash names the hash function used to produce the digest, e.g. "SHA-256".
+	Hash string `json:"hash,omitempty"`
+
+	// PSS requests an RSASSA-PSS signature with a salt length equal to the hash length.
+	PSS bool `json:"pss,omitempty"`
+}
+
+// RemoteSignResponse is the body returned by the remote signer after signing.
+type RemoteSignResponse struct {
+	// Signature is base64url encoded without padding, in the format produced by crypto.Signer for the key type.
+	Signature string `json:"signature"`
+}
+
+// remoteKeyBackend is the KeyBackend for keys held by a remote signer. The remote signer is expected to implement
+// the protocol described by the Remote* request and response types.
+type remoteKeyBackend struct {
+	baseURL string
+	client  *http.Client
+}
+
+var _ KeyBackend = (*remoteKeyBackend)(nil)
+
+// NewRemoteKeyBackend creates a KeyBackend that delegates key generation and signing to the remote signer at baseURL.
+func NewRemoteKeyBackend(baseURL string, client *http.Client) (KeyBackend, error) {
+	if _, err := url.ParseRequestURI(baseURL); err != nil {
+		return nil, errors.Wrapf(err, "invalid remote signer url: %s", baseURL)
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &remoteKeyBackend{baseURL: strings.TrimSuffix(baseURL, "/"), client: client}, nil
+}
+
+func (*remoteKeyBackend) Type() BackendType {
+	return RemoteBackend
+}
+
+func (b *remoteKeyBackend) GenerateKey(ctx context.Context, keyType crypto.KeyType) (*StoredKey, error) {
+	var resp RemoteGenerateKeyResponse
+	if err := b.post(ctx, b.baseURL+"/keys", RemoteGenerateKeyRequest{Type: keyType}, &resp); err != nil {
+		return nil, errors.Wrap(err, "generating key in remote signer")
+	}
+	if resp.KeyRef == "" {
+		return nil, errors.New("remote signer returned a key without a reference")
+	}
+	if resp.PublicKeyJWK.IsEmpty() {
+		return nil, errors.New("remote signer returned a key without a public key")
+	}
+	publicKeyJWK := resp.PublicKeyJWK
+	return &StoredKey{
+		KeyType:      keyType,
+		KeyRef:       resp.KeyRef,
+		PublicKeyJWK: &publicKeyJWK,
+	}, nil
+}
+
+func (b *remoteKeyBackend) PrivateKey(ctx context.Context, key StoredKey) (gocrypto.PrivateKey, error) {
+	if key.KeyRef == "" || key.PublicKeyJWK == nil {
+		return nil, errors.Errorf("key<%s> is missing its remote reference", key.ID)
+	}
+	publicKey, err := key.PublicKeyJWK.ToPublicKey()
+	if err != nil {
+		return nil, errors.Wrapf(err, "reconstructing public key for key<%s>", key.ID)
+	}
+	// signing libraries expect pointers to ecdsa and rsa public keys
+	switch k := publicKey.(type) {
+	case ecdsa.PublicKey:
+		publicKey = &k
+	case rsa.PublicKey:
+		publicKey = &k
+	}
+	return &remoteSigner{ctx: ctx, backend: b, keyRef: key.KeyRef, publicKey: publicKey}, nil
+}
+
+func (b *remoteKeyBackend) sign(ctx context.Context, keyRef string, digest []byte, opts gocrypto.SignerOpts) ([]byte, error) {
+	request := RemoteSignRequest{Digest: base64.RawURLEncoding.EncodeToString(digest)}
+	if hash := opts.HashFunc(); hash != 0 {
+		request.Hash = hash.String()
+	}
+	if _, ok := opts.(*rsa.PSSOptions); ok {
+		request.PSS = true
+	}
+
+	var resp RemoteSignResponse
+	if err := b.post(ctx, fmt.Sprintf("%s/keys/%s/sign", b.baseURL, url.PathEscape(keyRef)), request, &resp); err != nil {
+		return nil, errors.Wrapf(err, "signing with remote key<%s>", keyRef)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(resp.Signature)
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding remote signature")
+	}
+	return signature, nil
+}
+
+func (b *remoteKeyBackend) post(ctx context.Context, endpoint string, body, result any) error {
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return errors.Wrap(err, "marshalling request body")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return errors.Wrap(err, "creating request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "calling remote signer at %s", endpoint)
+	}
+	defer resp.Body.Close()
+
+	respBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "reading remote signer response")
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("remote signer responded with status %d: %s", resp.StatusCode, string(respBytes))
+	}
+	if err = json.Unmarshal(respBytes, result); err != nil {
+		return errors.Wrap(err, "unmarshalling remote signer response")
+	}
+	return nil
+}
+
+// remoteSigner is a keyaccess.ExternalSigner for a single key held by a remote signer.
+type remoteSigner struct {
+	ctx       context.Context
+	backend   *remoteKeyBackend
+	keyRef    string
+	publicKey gocrypto.PublicKey
+}
+
+var _ keyaccess.ExternalSigner = (*remoteSigner)(nil)
+
+func (s *remoteSigner) Public() gocrypto.PublicKey {
+	return s.publicKey
+}
+
+func (s *remoteSigner) Sign(_ io.Reader, digest []byte, opts gocrypto.SignerOpts) ([]byte, error) {
+	return s.backend.sign(s.ctx, s.keyRef, digest, opts)
+}
+
+func (*remoteSigner) External() {}
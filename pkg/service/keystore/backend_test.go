@@ -0,0 +1,196 @@
+package keystore
+
+import (
+	"context"
+	gocrypto "crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/TBD54566975/ssi-sdk/credential"
+	"github.com/TBD54566975/ssi-sdk/crypto"
+	"github.com/TBD54566975/ssi-sdk/crypto/jwx"
+	secp "github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/goccy/go-json"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tbd54566975/ssi-service/config"
+	"github.com/tbd54566975/ssi-service/internal/keyaccess"
+	"github.com/tbd54566975/ssi-service/pkg/testutil"
+)
+
+func TestRemoteKeyBackend(t *testing.T) {
+	remoteSigner := newTestRemoteSigner(t)
+
+	for _, test := range testutil.TestDatabases {
+		t.Run(test.Name, func(t *testing.T) {
+			db := test.ServiceStorage(t)
+			keyStore, err := NewKeyStoreService(config.KeyStoreServiceConfig{RemoteSignerURL: remoteSigner.URL}, db)
+			require.NoError(t, err)
+			assert.True(t, keyStore.SupportsBackend(RemoteBackend))
+
+			for _, keyType := range []crypto.KeyType{crypto.Ed25519, crypto.SECP256k1, crypto.P256, crypto.P384, crypto.RSA} {
+				t.Run(string(keyType), func(tt *testing.T) {
+					generated, err := keyStore.GenerateKey(context.Background(), GenerateKeyRequest{
+						Type:       keyType,
+						Controller: "did:test:remote",
+						Backend:    RemoteBackend,
+					})
+					require.NoError(tt, err)
+
+					// the private key never reaches the keystore's storage
+					stored, err := keyStore.storage.GetKey(context.Background(), generated.ID)
+					require.NoError(tt, err)
+					assert.Equal(tt, RemoteBackend, stored.Backend)
+					assert.Empty(tt, stored.Base58Key)
+					assert.NotEmpty(tt, stored.KeyRef)
+
+					details, err := keyStore.GetKeyDetails(context.Background(), GetKeyDetailsRequest{ID: generated.ID})
+					require.NoError(tt, err)
+					assert.Equal(tt, RemoteBackend, details.Backend)
+					assert.Equal(tt, generated.PublicKeyJWK, details.PublicKeyJWK)
+
+					// signing through the keystore produces tokens verifiable with the public key
+					token, err := keyStore.Sign(context.Background(), generated.ID, map[string]any{"test": "data"})
+					require.NoError(tt, err)
+					verifier, err := jwx.NewJWXVerifierFromJWK("did:test:remote", generated.PublicKeyJWK)
+					require.NoError(tt, err)
+					assert.NoError(tt, verifier.Verify(token.String()))
+
+					// credential signing works unchanged with the key returned by the keystore
+					gotKey, err := keyStore.GetKey(context.Background(), GetKeyRequest{ID: generated.ID})
+					require.NoError(tt, err)
+					assert.Implements(tt, (*keyaccess.ExternalSigner)(nil), gotKey.Key)
+					keyAccess, err := keyaccess.NewJWKKeyAccess("did:test:remote", generated.ID, gotKey.Key)
+					require.NoError(tt, err)
+					cred := credential.VerifiableCredential{
+						Context:           []any{"https://www.w3.org/2018/credentials/v1"},
+						ID:                uuid.NewString(),
+						Type:              []string{"VerifiableCredential"},
+						Issuer:            "did:test:remote",
+						IssuanceDate:      "2023-06-23T00:00:00Z",
+						CredentialSubject: map[string]any{"id": "did:test:subject"},
+					}
+					credToken, err := keyAccess.SignVerifiableCredential(cred)
+					require.NoError(tt, err)
+					_, err = keyAccess.VerifyVerifiableCredential(*credToken)
+					assert.NoError(tt, err)
+
+					// rotation keeps the key in the remote backend
+					rotated, err := keyStore.RotateKey(context.Background(), RotateKeyRequest{ID: generated.ID})
+					require.NoError(tt, err)
+					assert.Equal(tt, 2, rotated.Version)
+					assert.NotEqual(tt, generated.PublicKeyJWK, rotated.PublicKeyJWK)
+					gotKey, err = keyStore.GetKey(context.Background(), GetKeyRequest{ID: generated.ID})
+					require.NoError(tt, err)
+					assert.Equal(tt, RemoteBackend, gotKey.Backend)
+				})
+			}
+		})
+	}
+
+	t.Run("backend not configured", func(tt *testing.T) {
+		keyStore, err := createKeyStoreService(tt)
+		require.NoError(tt, err)
+		assert.False(tt, keyStore.SupportsBackend(RemoteBackend))
+
+		_, err = keyStore.GenerateKey(context.Background(), GenerateKeyRequest{
+			Type:       crypto.Ed25519,
+			Controller: "did:test:remote",
+			Backend:    RemoteBackend,
+		})
+		assert.ErrorContains(tt, err, "key backend<remote> is not configured")
+	})
+
+	t.Run("remote signer unavailable", func(tt *testing.T) {
+		unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer unavailable.Close()
+
+		backend, err := NewRemoteKeyBackend(unavailable.URL, nil)
+		require.NoError(tt, err)
+		_, err = backend.GenerateKey(context.Background(), crypto.Ed25519)
+		assert.ErrorContains(tt, err, "remote signer responded with status 503")
+	})
+}
+
+// newTestRemoteSigner starts a stand-in for a remote signer which holds its keys in memory.
+func newTestRemoteSigner(t *testing.T) *httptest.Server {
+	var mu sync.Mutex
+	keys := make(map[string]gocrypto.Signer)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		var request RemoteGenerateKeyRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+
+		_, privKey, err := crypto.GenerateKeyByKeyType(request.Type)
+		require.NoError(t, err)
+		keyRef := uuid.NewString()
+		publicKeyJWK, _, err := jwx.PrivateKeyToPrivateKeyJWK(keyRef, privKey)
+		require.NoError(t, err)
+
+		mu.Lock()
+		keys[keyRef] = toCryptoSigner(t, privKey)
+		mu.Unlock()
+
+		require.NoError(t, json.NewEncoder(w).Encode(RemoteGenerateKeyResponse{KeyRef: keyRef, PublicKeyJWK: *publicKeyJWK}))
+	})
+	mux.HandleFunc("/keys/", func(w http.ResponseWriter, r *http.Request) {
+		keyRef := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/keys/"), "/sign")
+		mu.Lock()
+		signer, ok := keys[keyRef]
+		mu.Unlock()
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var request RemoteSignRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		digest, err := base64.RawURLEncoding.DecodeString(request.Digest)
+		require.NoError(t, err)
+
+		var opts gocrypto.SignerOpts = gocrypto.Hash(0)
+		for _, hash := range []gocrypto.Hash{gocrypto.SHA256, gocrypto.SHA384, gocrypto.SHA512} {
+			if hash.String() == request.Hash {
+				opts = hash
+			}
+		}
+		if request.PSS {
+			opts = &rsa.PSSOptions{Hash: opts.HashFunc(), SaltLength: rsa.PSSSaltLengthEqualsHash}
+		}
+		signature, err := signer.Sign(rand.Reader, digest, opts)
+		require.NoError(t, err)
+
+		require.NoError(t, json.NewEncoder(w).Encode(RemoteSignResponse{Signature: base64.RawURLEncoding.EncodeToString(signature)}))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func toCryptoSigner(t *testing.T, privKey gocrypto.PrivateKey) gocrypto.Signer {
+	switch k := privKey.(type) {
+	case secp.PrivateKey:
+		return k.ToECDSA()
+	case ecdsa.PrivateKey:
+		return &k
+	case rsa.PrivateKey:
+		return &k
+	case gocrypto.Signer:
+		return k
+	}
+	t.Fatalf("unsupported private key type: %T", privKey)
+	return nil
+}
@@ -14,8 +14,27 @@ type StoreKeyRequest struct {
 	PrivateKeyBase58 string
 }
 
+type GenerateKeyRequest struct {
+	// ID is optional; a random identifier is assigned when it is empty.
+	ID         string
+	Type       crypto.KeyType
+	Controller string
+
+	// Backend that holds the private key. When empty, LocalBackend is used.
+	Backend BackendType
+}
+
+type GenerateKeyResponse struct {
+	ID           string
+	PublicKeyJWK jwx.PublicKeyJWK
+}
+
 type GetKeyRequest struct {
 	ID string
+
+	// Version selects a specific version of the key. When zero, the newest non-revoked version is returned, or the
+	// latest version when every version has been revoked.
+	Version int
 }
 
 type GetKeyResponse struct {
@@ -25,7 +44,12 @@ type GetKeyResponse struct {
 	CreatedAt  string
 	Revoked    bool
 	RevokedAt  string
-	Key        gocrypto.PrivateKey
+	Version    int
+	Backend    BackendType
+
+	// Key is the private key for LocalBackend keys. For keys held by other backends, it is a
+	// keyaccess.ExternalSigner that delegates signing to the backend.
+	Key gocrypto.PrivateKey
 }
 
 type GetKeyDetailsRequest struct {
@@ -40,8 +64,40 @@ type GetKeyDetailsResponse struct {
 	Revoked      bool
 	RevokedAt    string
 	PublicKeyJWK jwx.PublicKeyJWK
+	Version      int
+	Versions     []KeyVersionDetails
+	Backend      BackendType
+}
+
+type RotateKeyRequest struct {
+	ID string
+
+	// Type of the new key version. When empty, the type of the current version is used.
+	Type crypto.KeyType
+}
+
+type RotateKeyResponse struct {
+	ID           string
+	Version      int
+	PublicKeyJWK jwx.PublicKeyJWK
 }
 
 type RevokeKeyRequest struct {
 	ID string
+
+	// Version restricts revocation to a single version of the key. When zero, every version is revoked.
+	Version int
+}
+
+type ListKeysByControllerRequest struct {
+	Controller string
+}
+
+type ListKeysByControllerResponse struct {
+	// IDs of the keys whose controller is the requested one.
+	KeyIDs []string
+}
+
+type DeleteKeyRequest struct {
+	ID string
 }
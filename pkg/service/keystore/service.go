@@ -7,6 +7,7 @@ import (
 
 	"github.com/TBD54566975/ssi-sdk/crypto"
 	sdkutil "github.com/TBD54566975/ssi-sdk/util"
+	"github.com/google/uuid"
 	"github.com/mr-tron/base58"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -20,11 +21,35 @@ import (
 	"github.com/tbd54566975/ssi-service/pkg/storage"
 )
 
+// generatableKeyTypes are the key types the service is able to generate on behalf of a caller.
+var generatableKeyTypes = map[crypto.KeyType]bool{
+	crypto.Ed25519:   true,
+	crypto.SECP256k1: true,
+	crypto.P256:      true,
+	crypto.P384:      true,
+	crypto.RSA:       true,
+}
+
+// IsSupportedGenerationKeyType returns whether the service can generate keys of the given type.
+func IsSupportedGenerationKeyType(kt crypto.KeyType) bool {
+	return generatableKeyTypes[kt]
+}
+
+var (
+	// ErrKeyAlreadyExists is returned when generating a key with the ID of a stored key.
+	ErrKeyAlreadyExists = errors.New("key already exists")
+	// ErrKeyNotFound is returned when there's no stored key with the given ID.
+	ErrKeyNotFound = errors.New("key not found")
+	// ErrUnsupportedKeyType is returned when a key of a type the service can't generate is requested.
+	ErrUnsupportedKeyType = errors.New("unsupported key type for generation")
+)
+
 type ServiceFactory func(storage.Tx) (*Service, error)
 
 type Service struct {
-	storage *Storage
-	config  config.KeyStoreServiceConfig
+	storage  *Storage
+	config   config.KeyStoreServiceConfig
+	backends map[BackendType]KeyBackend
 }
 
 func (s Service) Type() framework.Type {
@@ -36,6 +61,9 @@ func (s Service) Status() framework.Status {
 	if s.storage == nil {
 		ae.AppendString("no storage configured")
 	}
+	if s.backends[LocalBackend] == nil {
+		ae.AppendString("no local key backend configured")
+	}
 	if !ae.IsEmpty() {
 		return framework.Status{
 			Status:  framework.StatusNotReady,
@@ -67,9 +95,15 @@ func NewKeyStoreServiceFactory(config config.KeyStoreServiceConfig, s storage.Se
 			return nil, sdkutil.LoggingErrorMsg(err, "instantiating storage for the keystore service")
 		}
 
+		backends, err := newKeyBackends(config)
+		if err != nil {
+			return nil, sdkutil.LoggingErrorMsg(err, "instantiating key backends for the keystore service")
+		}
+
 		service := Service{
-			storage: keyStoreStorage,
-			config:  config,
+			storage:  keyStoreStorage,
+			config:   config,
+			backends: backends,
 		}
 		if !service.Status().IsReady() {
 			return nil, errors.New(service.Status().Message)
@@ -78,6 +112,36 @@ func NewKeyStoreServiceFactory(config config.KeyStoreServiceConfig, s storage.Se
 	}
 }
 
+// newKeyBackends creates the key backends enabled by the given config. The local backend is always available.
+func newKeyBackends(config config.KeyStoreServiceConfig) (map[BackendType]KeyBackend, error) {
+	backends := map[BackendType]KeyBackend{LocalBackend: localKeyBackend{}}
+	if config.RemoteSignerURL != "" {
+		remoteBackend, err := NewRemoteKeyBackend(config.RemoteSignerURL, nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "creating remote key backend")
+		}
+		backends[RemoteBackend] = remoteBackend
+	}
+	return backends, nil
+}
+
+// SupportsBackend returns whether the given key backend is configured for this service.
+func (s Service) SupportsBackend(backendType BackendType) bool {
+	_, err := s.getBackend(backendType)
+	return err == nil
+}
+
+func (s Service) getBackend(backendType BackendType) (KeyBackend, error) {
+	if backendType == "" {
+		backendType = LocalBackend
+	}
+	backend, ok := s.backends[backendType]
+	if !ok {
+		return nil, fmt.Errorf("key backend<%s> is not configured", backendType)
+	}
+	return backend, nil
+}
+
 func (s Service) StoreKey(ctx context.Context, request StoreKeyRequest) error {
 	logrus.Debugf("storing key: %+v", request)
 
@@ -93,6 +157,7 @@ func (s Service) StoreKey(ctx context.Context, request StoreKeyRequest) error {
 		KeyType:    request.Type,
 		Base58Key:  request.PrivateKeyBase58,
 		CreatedAt:  time.Now().Format(time.RFC3339),
+		Version:    1,
 	}
 	if err := s.storage.StoreKey(ctx, key); err != nil {
 		return sdkutil.LoggingErrorMsgf(err, "storing key: %s", request.ID)
@@ -100,26 +165,76 @@ func (s Service) StoreKey(ctx context.Context, request StoreKeyRequest) error {
 	return nil
 }
 
+// GenerateKey creates a new private key inside the service and stores it. Only the public portion of the key is ever
+// returned to the caller.
+func (s Service) GenerateKey(ctx context.Context, request GenerateKeyRequest) (*GenerateKeyResponse, error) {
+	logrus.Debugf("generating key: %+v", request)
+
+	if !IsSupportedGenerationKeyType(request.Type) {
+		return nil, sdkutil.LoggingNewErrorf("unsupported key type for generation: %s", request.Type)
+	}
+
+	id := request.ID
+	if id == "" {
+		id = uuid.NewString()
+	} else {
+		exists, err := s.storage.KeyExists(ctx, id)
+		if err != nil {
+			return nil, sdkutil.LoggingErrorMsgf(err, "checking for key: %s", id)
+		}
+		if exists {
+			return nil, errors.Wrapf(ErrKeyAlreadyExists, "key<%s>", id)
+		}
+	}
+
+	backend, err := s.getBackend(request.Backend)
+	if err != nil {
+		return nil, sdkutil.LoggingError(err)
+	}
+	key, err := backend.GenerateKey(ctx, request.Type)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsgf(err, "generating key of type: %s", request.Type)
+	}
+	key.ID = id
+	key.Controller = request.Controller
+	key.CreatedAt = time.Now().Format(time.RFC3339)
+	key.Version = 1
+	key.Backend = backend.Type()
+	if err = s.storage.StoreKey(ctx, *key); err != nil {
+		return nil, sdkutil.LoggingErrorMsgf(err, "storing generated key: %s", id)
+	}
+
+	publicJWK, err := publicKeyJWKFromStoredKey(*key)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsg(err, "converting generated key to JWK")
+	}
+	return &GenerateKeyResponse{
+		ID:           id,
+		PublicKeyJWK: *publicJWK,
+	}, nil
+}
+
 func (s Service) GetKey(ctx context.Context, request GetKeyRequest) (*GetKeyResponse, error) {
 	logrus.Debugf("getting key: %+v", request)
 
 	id := request.ID
-	gotKey, err := s.storage.GetKey(ctx, id)
+	versions, err := s.storage.GetKeyVersions(ctx, id)
 	if err != nil {
 		return nil, sdkutil.LoggingErrorMsgf(err, "getting key with id: %s", id)
 	}
+	gotKey := selectKeyVersion(versions, request.Version)
 	if gotKey == nil {
-		return nil, sdkutil.LoggingErrorMsgf(err, "key with id<%s> could not be found", id)
+		return nil, sdkutil.LoggingNewErrorf("version<%d> of key with id<%s> could not be found", request.Version, id)
 	}
 
-	// deserialize the key before returning
-	keyBytes, err := base58.Decode(gotKey.Base58Key)
+	// get hold of the private key from the backend that owns it before returning
+	backend, err := s.getBackend(gotKey.Backend)
 	if err != nil {
-		return nil, sdkutil.LoggingErrorMsg(err, "could not deserialize key from base58")
+		return nil, sdkutil.LoggingError(err)
 	}
-	privKey, err := crypto.BytesToPrivKey(keyBytes, gotKey.KeyType)
+	privKey, err := backend.PrivateKey(ctx, *gotKey)
 	if err != nil {
-		return nil, sdkutil.LoggingErrorMsg(err, "could not reconstruct private key from storage")
+		return nil, sdkutil.LoggingErrorMsgf(err, "getting private key for key<%s>", id)
 	}
 
 	return &GetKeyResponse{
@@ -130,6 +245,78 @@ func (s Service) GetKey(ctx context.Context, request GetKeyRequest) (*GetKeyResp
 		CreatedAt:  gotKey.CreatedAt,
 		Revoked:    gotKey.Revoked,
 		RevokedAt:  gotKey.RevokedAt,
+		Version:    gotKey.GetVersion(),
+		Backend:    gotKey.GetBackend(),
+	}, nil
+}
+
+// selectKeyVersion picks the requested version out of versions, which are ordered oldest first. When version is
+// zero, the newest non-revoked version is picked, falling back to the latest version when all are revoked.
+func selectKeyVersion(versions []StoredKey, version int) *StoredKey {
+	if len(versions) == 0 {
+		return nil
+	}
+	if version != 0 {
+		for i := range versions {
+			if versions[i].GetVersion() == version {
+				return &versions[i]
+			}
+		}
+		return nil
+	}
+	for i := len(versions) - 1; i >= 0; i-- {
+		if !versions[i].Revoked {
+			return &versions[i]
+		}
+	}
+	return &versions[len(versions)-1]
+}
+
+// RotateKey creates a new version of the key with the given ID. The new version is generated inside the service and
+// is used for all subsequent signing. Earlier versions are kept so that signatures they produced can be verified.
+func (s Service) RotateKey(ctx context.Context, request RotateKeyRequest) (*RotateKeyResponse, error) {
+	logrus.Debugf("rotating key: %+v", request)
+
+	id := request.ID
+	current, err := s.storage.GetKey(ctx, id)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsgf(err, "getting key with id: %s", id)
+	}
+
+	keyType := request.Type
+	if keyType == "" {
+		keyType = current.KeyType
+	}
+	if !IsSupportedGenerationKeyType(keyType) {
+		return nil, sdkutil.LoggingError(errors.Wrapf(ErrUnsupportedKeyType, "key type<%s>", keyType))
+	}
+
+	backend, err := s.getBackend(current.Backend)
+	if err != nil {
+		return nil, sdkutil.LoggingError(err)
+	}
+	newKey, err := backend.GenerateKey(ctx, keyType)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsgf(err, "generating key of type: %s", keyType)
+	}
+	newKey.ID = id
+	newKey.Controller = current.Controller
+	newKey.CreatedAt = time.Now().Format(time.RFC3339)
+	newKey.Backend = backend.Type()
+
+	rotated, err := s.storage.RotateKey(ctx, *newKey)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsgf(err, "rotating key: %s", id)
+	}
+
+	publicJWK, err := publicKeyJWKFromStoredKey(*rotated)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsg(err, "converting rotated key to JWK")
+	}
+	return &RotateKeyResponse{
+		ID:           id,
+		Version:      rotated.Version,
+		PublicKeyJWK: *publicJWK,
 	}, nil
 }
 
@@ -137,12 +324,45 @@ func (s Service) RevokeKey(ctx context.Context, request RevokeKeyRequest) error
 	logrus.Debugf("revoking key: %+v", request)
 
 	id := request.ID
+	if request.Version != 0 {
+		if err := s.storage.RevokeKeyVersion(ctx, id, request.Version); err != nil {
+			return sdkutil.LoggingErrorMsgf(err, "could not revoke version<%d> of key: %s", request.Version, id)
+		}
+		return nil
+	}
 	if err := s.storage.RevokeKey(ctx, id); err != nil {
 		return sdkutil.LoggingErrorMsgf(err, "could not revoke key: %s", id)
 	}
 	return nil
 }
 
+// ListKeysByController returns the IDs of the keys whose controller is the requested one, such as the keys of a DID.
+func (s Service) ListKeysByController(ctx context.Context, request ListKeysByControllerRequest) (*ListKeysByControllerResponse, error) {
+	if request.Controller == "" {
+		return nil, sdkutil.LoggingNewError("cannot list keys without a controller")
+	}
+	keys, err := s.storage.ListKeysByController(ctx, request.Controller)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsgf(err, "could not list keys of controller: %s", request.Controller)
+	}
+	keyIDs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		keyIDs = append(keyIDs, key.ID)
+	}
+	return &ListKeysByControllerResponse{KeyIDs: keyIDs}, nil
+}
+
+// DeleteKey permanently removes every version of a key from the keystore. Keys held by other backends are only
+// forgotten by the service, and remain in their backend.
+func (s Service) DeleteKey(ctx context.Context, request DeleteKeyRequest) error {
+	logrus.Debugf("deleting key: %+v", request)
+
+	if err := s.storage.DeleteKey(ctx, request.ID); err != nil {
+		return sdkutil.LoggingErrorMsgf(err, "could not delete key: %s", request.ID)
+	}
+	return nil
+}
+
 func (s Service) GetKeyDetails(ctx context.Context, request GetKeyDetailsRequest) (*GetKeyDetailsResponse, error) {
 	logrus.Debugf("getting key: %+v", request)
 
@@ -162,6 +382,9 @@ func (s Service) GetKeyDetails(ctx context.Context, request GetKeyDetailsRequest
 		Revoked:      gotKeyDetails.Revoked,
 		RevokedAt:    gotKeyDetails.RevokedAt,
 		PublicKeyJWK: gotKeyDetails.PublicKeyJWK,
+		Version:      gotKeyDetails.Version,
+		Versions:     gotKeyDetails.Versions,
+		Backend:      gotKeyDetails.Backend,
 	}, nil
 }
 
@@ -177,7 +400,8 @@ func GenerateServiceKey() (key string, err error) {
 	return
 }
 
-// Sign fetches the key in the store, and uses it to sign data. Data should be json or json-serializable.
+// Sign fetches the newest non-revoked version of the key in the store, and uses it to sign data. It fails when every
+// version is revoked. Data should be json or json-serializable.
 func (s Service) Sign(ctx context.Context, keyID string, data any) (*keyaccess.JWT, error) {
 	gotKey, err := s.GetKey(ctx, GetKeyRequest{ID: keyID})
 	if err != nil {
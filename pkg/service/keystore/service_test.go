@@ -10,6 +10,7 @@ import (
 	"github.com/TBD54566975/ssi-sdk/crypto/jwx"
 	"github.com/benbjohnson/clock"
 	"github.com/mr-tron/base58"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -51,6 +52,78 @@ func TestStoreAndGetKey(t *testing.T) {
 	assert.NotEmpty(t, signer)
 }
 
+func TestGenerateKey(t *testing.T) {
+	keyStore, err := createKeyStoreService(t)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, keyStore)
+
+	for _, keyType := range []crypto.KeyType{crypto.Ed25519, crypto.SECP256k1, crypto.P256, crypto.P384, crypto.RSA} {
+		t.Run(string(keyType), func(tt *testing.T) {
+			generated, err := keyStore.GenerateKey(context.Background(), GenerateKeyRequest{
+				Type:       keyType,
+				Controller: "test-controller",
+			})
+			assert.NoError(tt, err)
+			assert.NotEmpty(tt, generated.ID)
+
+			// the stored private key must correspond to the returned public key
+			gotKey, err := keyStore.GetKey(context.Background(), GetKeyRequest{ID: generated.ID})
+			assert.NoError(tt, err)
+			assert.Equal(tt, keyType, gotKey.Type)
+			assert.Equal(tt, "test-controller", gotKey.Controller)
+			wantJWK, _, err := jwx.PrivateKeyToPrivateKeyJWK(generated.ID, gotKey.Key)
+			assert.NoError(tt, err)
+			assert.Equal(tt, *wantJWK, generated.PublicKeyJWK)
+
+			// and be usable for signing
+			token, err := keyStore.Sign(context.Background(), generated.ID, map[string]any{"test": "data"})
+			assert.NoError(tt, err)
+			assert.NotEmpty(tt, token)
+		})
+	}
+
+	t.Run("provided ID is kept", func(tt *testing.T) {
+		generated, err := keyStore.GenerateKey(context.Background(), GenerateKeyRequest{
+			ID:         "test-generated-id",
+			Type:       crypto.Ed25519,
+			Controller: "test-controller",
+		})
+		assert.NoError(tt, err)
+		assert.Equal(tt, "test-generated-id", generated.ID)
+	})
+
+	t.Run("existing ID is rejected", func(tt *testing.T) {
+		generated, err := keyStore.GenerateKey(context.Background(), GenerateKeyRequest{
+			ID:         "test-conflicting-id",
+			Type:       crypto.Ed25519,
+			Controller: "test-controller",
+		})
+		assert.NoError(tt, err)
+
+		_, err = keyStore.GenerateKey(context.Background(), GenerateKeyRequest{
+			ID:         "test-conflicting-id",
+			Type:       crypto.P256,
+			Controller: "other-controller",
+		})
+		assert.ErrorIs(tt, err, ErrKeyAlreadyExists)
+
+		// the original key is untouched
+		gotKey, err := keyStore.GetKey(context.Background(), GetKeyRequest{ID: generated.ID})
+		assert.NoError(tt, err)
+		assert.Equal(tt, crypto.Ed25519, gotKey.Type)
+		assert.Equal(tt, "test-controller", gotKey.Controller)
+	})
+
+	t.Run("unsupported key type", func(tt *testing.T) {
+		_, err := keyStore.GenerateKey(context.Background(), GenerateKeyRequest{
+			Type:       crypto.X25519,
+			Controller: "test-controller",
+		})
+		assert.Error(tt, err)
+		assert.ErrorContains(tt, err, "unsupported key type for generation")
+	})
+}
+
 func TestRevokeKey(t *testing.T) {
 	keyStore, err := createKeyStoreService(t)
 	assert.NoError(t, err)
@@ -94,6 +167,226 @@ func TestRevokeKey(t *testing.T) {
 	assert.ErrorContains(t, err, "cannot use revoked key")
 }
 
+func TestListKeysByControllerAndDeleteKey(t *testing.T) {
+	keyStore, err := createKeyStoreService(t)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, keyStore)
+
+	ctx := context.Background()
+	storeKey := func(id, controller string) {
+		_, privKey, err := crypto.GenerateEd25519Key()
+		require.NoError(t, err)
+		require.NoError(t, keyStore.StoreKey(ctx, StoreKeyRequest{
+			ID:               id,
+			Type:             crypto.Ed25519,
+			Controller:       controller,
+			PrivateKeyBase58: base58.Encode(privKey),
+		}))
+	}
+	storeKey("did:example:123#key-1", "did:example:123")
+	storeKey("did:example:123#key-2", "did:example:123")
+	storeKey("did:example:456#key-1", "did:example:456")
+
+	// rotated keys are listed once
+	_, err = keyStore.RotateKey(ctx, RotateKeyRequest{ID: "did:example:123#key-2"})
+	require.NoError(t, err)
+
+	keys, err := keyStore.ListKeysByController(ctx, ListKeysByControllerRequest{Controller: "did:example:123"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"did:example:123#key-1", "did:example:123#key-2"}, keys.KeyIDs)
+
+	_, err = keyStore.ListKeysByController(ctx, ListKeysByControllerRequest{})
+	assert.ErrorContains(t, err, "cannot list keys without a controller")
+
+	// controllers that are a prefix of another don't get its keys
+	keys, err = keyStore.ListKeysByController(ctx, ListKeysByControllerRequest{Controller: "did:example:12"})
+	require.NoError(t, err)
+	assert.Empty(t, keys.KeyIDs)
+
+	// keys stored before they were indexed by controller are still listed
+	db := keyStore.storage.db
+	require.NoError(t, db.Delete(ctx, controllerIndexNamespace, controllerIndexedKey))
+	require.NoError(t, db.Delete(ctx, controllerIndexNamespace, controllerIndexKey("did:example:123", "did:example:123#key-1")))
+	keys, err = keyStore.ListKeysByController(ctx, ListKeysByControllerRequest{Controller: "did:example:123"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"did:example:123#key-1", "did:example:123#key-2"}, keys.KeyIDs)
+
+	// every version of a deleted key is gone
+	for _, keyID := range keys.KeyIDs {
+		require.NoError(t, keyStore.DeleteKey(ctx, DeleteKeyRequest{ID: keyID}))
+		_, err = keyStore.GetKey(ctx, GetKeyRequest{ID: keyID})
+		assert.Error(t, err)
+		_, err = keyStore.GetKey(ctx, GetKeyRequest{ID: keyID, Version: 1})
+		assert.Error(t, err)
+	}
+	keys, err = keyStore.ListKeysByController(ctx, ListKeysByControllerRequest{Controller: "did:example:123"})
+	require.NoError(t, err)
+	assert.Empty(t, keys.KeyIDs)
+	indexed, err := db.ReadPrefix(ctx, controllerIndexNamespace, controllerIndexKey("did:example:123", ""))
+	require.NoError(t, err)
+	assert.Empty(t, indexed)
+
+	_, err = keyStore.GetKey(ctx, GetKeyRequest{ID: "did:example:456#key-1"})
+	assert.NoError(t, err)
+}
+
+func TestRotateKeyErrors(t *testing.T) {
+	keyStore, err := createKeyStoreService(t)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	_, err = keyStore.RotateKey(ctx, RotateKeyRequest{ID: "missing"})
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	// keys of a type the service can't generate can't be rotated without choosing another type
+	_, privKey, err := crypto.GenerateX25519Key()
+	require.NoError(t, err)
+	require.NoError(t, keyStore.StoreKey(ctx, StoreKeyRequest{
+		ID:               "x25519-key",
+		Type:             crypto.X25519,
+		Controller:       "test-controller",
+		PrivateKeyBase58: base58.Encode(privKey),
+	}))
+	_, err = keyStore.RotateKey(ctx, RotateKeyRequest{ID: "x25519-key"})
+	assert.ErrorIs(t, err, ErrUnsupportedKeyType)
+	_, err = keyStore.RotateKey(ctx, RotateKeyRequest{ID: "x25519-key", Type: crypto.Ed25519})
+	assert.NoError(t, err)
+}
+
+func TestListKeysByControllerAndDeleteKeyInTransaction(t *testing.T) {
+	keyStore, err := createKeyStoreService(t)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	keyID := "did:example:123#key-1"
+	_, privKey, err := crypto.GenerateEd25519Key()
+	require.NoError(t, err)
+	require.NoError(t, keyStore.StoreKey(ctx, StoreKeyRequest{
+		ID:               keyID,
+		Type:             crypto.Ed25519,
+		Controller:       "did:example:123",
+		PrivateKeyBase58: base58.Encode(privKey),
+	}))
+
+	// force the controller index to be backfilled inside the transaction
+	db := keyStore.storage.db
+	require.NoError(t, db.Delete(ctx, controllerIndexNamespace, controllerIndexedKey))
+	require.NoError(t, db.Delete(ctx, controllerIndexNamespace, controllerIndexKey("did:example:123", keyID)))
+
+	encrypter, decrypter, err := NewServiceEncryption(db, config.EncryptionConfig{}, ServiceKeyEncryptionKey)
+	require.NoError(t, err)
+	factory := NewKeyStoreServiceFactory(config.KeyStoreServiceConfig{}, db, encrypter, decrypter)
+	deleteKeys := func(ctx context.Context, tx storage.Tx) (any, error) {
+		txKeyStore, err := factory(tx)
+		if err != nil {
+			return nil, err
+		}
+		keys, err := txKeyStore.ListKeysByController(ctx, ListKeysByControllerRequest{Controller: "did:example:123"})
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range keys.KeyIDs {
+			if err = txKeyStore.DeleteKey(ctx, DeleteKeyRequest{ID: id}); err != nil {
+				return nil, err
+			}
+		}
+		return keys.KeyIDs, nil
+	}
+
+	// a failed transaction keeps the key
+	_, err = db.Execute(ctx, func(ctx context.Context, tx storage.Tx) (any, error) {
+		if _, err := deleteKeys(ctx, tx); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("aborted")
+	}, nil)
+	assert.ErrorContains(t, err, "aborted")
+	_, err = keyStore.GetKey(ctx, GetKeyRequest{ID: keyID})
+	assert.NoError(t, err)
+
+	deleted, err := db.Execute(ctx, deleteKeys, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{keyID}, deleted)
+	_, err = keyStore.GetKey(ctx, GetKeyRequest{ID: keyID})
+	assert.Error(t, err)
+	keys, err := keyStore.ListKeysByController(ctx, ListKeysByControllerRequest{Controller: "did:example:123"})
+	require.NoError(t, err)
+	assert.Empty(t, keys.KeyIDs)
+}
+
+func TestRotateKey(t *testing.T) {
+	keyStore, err := createKeyStoreService(t)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, keyStore)
+
+	// store the first version of the key
+	_, privKey, err := crypto.GenerateEd25519Key()
+	assert.NoError(t, err)
+	keyID := "test-rotation-id"
+	err = keyStore.StoreKey(context.Background(), StoreKeyRequest{
+		ID:               keyID,
+		Type:             crypto.Ed25519,
+		Controller:       "test-rotation-controller",
+		PrivateKeyBase58: base58.Encode(privKey),
+	})
+	assert.NoError(t, err)
+
+	// rotate it twice, switching key types on the second rotation
+	rotated, err := keyStore.RotateKey(context.Background(), RotateKeyRequest{ID: keyID})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, rotated.Version)
+	rotated, err = keyStore.RotateKey(context.Background(), RotateKeyRequest{ID: keyID, Type: crypto.P256})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, rotated.Version)
+
+	// the newest version is used by default, earlier versions remain available
+	keyResponse, err := keyStore.GetKey(context.Background(), GetKeyRequest{ID: keyID})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, keyResponse.Version)
+	assert.Equal(t, crypto.P256, keyResponse.Type)
+	assert.Equal(t, "test-rotation-controller", keyResponse.Controller)
+	keyResponse, err = keyStore.GetKey(context.Background(), GetKeyRequest{ID: keyID, Version: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, privKey, keyResponse.Key)
+	_, err = keyStore.GetKey(context.Background(), GetKeyRequest{ID: keyID, Version: 4})
+	assert.ErrorContains(t, err, "version<4> of key with id<test-rotation-id> could not be found")
+
+	// details list all versions
+	details, err := keyStore.GetKeyDetails(context.Background(), GetKeyDetailsRequest{ID: keyID})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, details.Version)
+	assert.Equal(t, rotated.PublicKeyJWK, details.PublicKeyJWK)
+	assert.Len(t, details.Versions, 3)
+	for i, version := range details.Versions {
+		assert.Equal(t, i+1, version.Version)
+		assert.NotEmpty(t, version.CreatedAt)
+		assert.False(t, version.Revoked)
+	}
+	assert.Equal(t, rotated.PublicKeyJWK, details.Versions[2].PublicKeyJWK)
+
+	// revoking the newest version falls back to the previous one for signing
+	err = keyStore.RevokeKey(context.Background(), RevokeKeyRequest{ID: keyID, Version: 3})
+	assert.NoError(t, err)
+	keyResponse, err = keyStore.GetKey(context.Background(), GetKeyRequest{ID: keyID})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, keyResponse.Version)
+	assert.False(t, keyResponse.Revoked)
+	_, err = keyStore.Sign(context.Background(), keyID, map[string]any{"test": "data"})
+	assert.NoError(t, err)
+
+	// revoking the key revokes every version
+	err = keyStore.RevokeKey(context.Background(), RevokeKeyRequest{ID: keyID})
+	assert.NoError(t, err)
+	details, err = keyStore.GetKeyDetails(context.Background(), GetKeyDetailsRequest{ID: keyID})
+	assert.NoError(t, err)
+	for _, version := range details.Versions {
+		assert.True(t, version.Revoked)
+		assert.Equal(t, "2023-06-23T00:00:00Z", version.RevokedAt)
+	}
+	_, err = keyStore.Sign(context.Background(), keyID, "sampleDataAsString")
+	assert.ErrorContains(t, err, "cannot use revoked key")
+}
+
 func createKeyStoreService(t *testing.T) (*Service, error) {
 	file, err := os.CreateTemp("", "bolt")
 	require.NoError(t, err)
@@ -11,12 +11,14 @@ import (
 	"github.com/goccy/go-json"
 	"github.com/mr-tron/base58"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 
 	"github.com/tbd54566975/ssi-service/internal/encryption"
 	"github.com/tbd54566975/ssi-service/pkg/storage"
 )
 
-// StoredKey represents a common data model to store data on all key types
+// StoredKey represents a common data model to store data on all key types. Rotating a key creates a new StoredKey
+// with the same ID and an incremented Version; the superseded versions are kept in the key's history.
 type StoredKey struct {
 	ID         string         `json:"id"`
 	Controller string         `json:"controller"`
@@ -25,6 +27,30 @@ type StoredKey struct {
 	Revoked    bool           `json:"revoked"`
 	RevokedAt  string         `json:"revokedAt"`
 	CreatedAt  string         `json:"createdAt"`
+	Version    int            `json:"version,omitempty"`
+
+	// Backend identifies the KeyBackend holding the private material. Empty means LocalBackend.
+	Backend BackendType `json:"backend,omitempty"`
+	// KeyRef identifies the key within a non-local backend, which holds the private material instead of Base58Key.
+	KeyRef string `json:"keyRef,omitempty"`
+	// PublicKeyJWK is set for keys whose private material is not available to the service.
+	PublicKeyJWK *jwx.PublicKeyJWK `json:"publicKeyJwk,omitempty"`
+}
+
+// GetBackend returns the type of backend that owns the key.
+func (sk StoredKey) GetBackend() BackendType {
+	if sk.Backend == "" {
+		return LocalBackend
+	}
+	return sk.Backend
+}
+
+// GetVersion returns the version of the key. Keys stored before versioning was introduced are version 1.
+func (sk StoredKey) GetVersion() int {
+	if sk.Version == 0 {
+		return 1
+	}
+	return sk.Version
 }
 
 // KeyDetails represents a common data model to get information about a key, without revealing the key itself
@@ -36,6 +62,21 @@ type KeyDetails struct {
 	RevokedAt    string           `json:"revokedAt"`
 	CreatedAt    string           `json:"createdAt"`
 	PublicKeyJWK jwx.PublicKeyJWK `json:"publicKeyJwk"`
+	Version      int              `json:"version"`
+	Backend      BackendType      `json:"backend"`
+
+	// Versions describes every version of the key, oldest first.
+	Versions []KeyVersionDetails `json:"versions"`
+}
+
+// KeyVersionDetails describes a single version of a key, without revealing the key itself
+type KeyVersionDetails struct {
+	Version      int              `json:"version"`
+	KeyType      crypto.KeyType   `json:"keyType"`
+	Revoked      bool             `json:"revoked"`
+	RevokedAt    string           `json:"revokedAt"`
+	CreatedAt    string           `json:"createdAt"`
+	PublicKeyJWK jwx.PublicKeyJWK `json:"publicKeyJwk"`
 }
 
 type ServiceKey struct {
@@ -47,6 +88,8 @@ const (
 	namespace             = "keystore"
 	serviceInternalSuffix = "service-internal"
 	publicNamespaceSuffix = "public-keys"
+	versionsSuffix        = "key-versions"
+	controllersSuffix     = "controllers"
 	keyNotFoundErrMsg     = "key not found"
 
 	ServiceKeyEncryptionKey  = "ssi-service-key-encryption-key"
@@ -56,8 +99,18 @@ const (
 var (
 	serviceInternalNamespace = storage.Join(namespace, serviceInternalSuffix)
 	publicKeyNamespace       = storage.Join(namespace, publicNamespaceSuffix)
+	keyVersionsNamespace     = storage.Join(namespace, versionsSuffix)
+	// controllerIndexNamespace indexes the IDs of keys by their controller, with entries keyed by controllerIndexKey.
+	controllerIndexNamespace = storage.Join(namespace, controllersSuffix)
 )
 
+// controllerIndexedKey marks that every key stored before keys were indexed by controller was added to the index.
+const controllerIndexedKey = "indexed"
+
+func controllerIndexKey(controller, id string) string {
+	return controller + "/" + id
+}
+
 type Storage struct {
 	db        storage.ServiceStorage
 	tx        storage.Tx
@@ -182,8 +235,9 @@ func getServiceKey(ctx context.Context, db storage.ServiceStorage, namespace, sk
 	return keyBytes, nil
 }
 
+// StoreKey writes key under its ID, replacing any key stored there. Callers that must not replace an existing key
+// check KeyExists first.
 func (kss *Storage) StoreKey(ctx context.Context, key StoredKey) error {
-	// TODO(gabe): conflict checking on key id
 	id := key.ID
 	if id == "" {
 		return sdkutil.LoggingNewError("could not store key without an ID")
@@ -194,19 +248,9 @@ func (kss *Storage) StoreKey(ctx context.Context, key StoredKey) error {
 		return sdkutil.LoggingErrorMsg(err, "deserializing key from base58")
 	}
 
-	skBytes, err := base58.Decode(key.Base58Key)
+	publicJWK, err := publicKeyJWKFromStoredKey(key)
 	if err != nil {
-		return sdkutil.LoggingErrorMsg(err, "deserializing key from base58")
-	}
-
-	secretKey, err := crypto.BytesToPrivKey(skBytes, key.KeyType)
-	if err != nil {
-		return sdkutil.LoggingErrorMsg(err, "reconstructing private key from input")
-	}
-
-	publicJWK, _, err := jwx.PrivateKeyToPrivateKeyJWK(key.ID, secretKey)
-	if err != nil {
-		return sdkutil.LoggingErrorMsg(err, "reconstructing JWK")
+		return sdkutil.LoggingErrorMsg(err, "reconstructing public key from input")
 	}
 
 	publicBytes, err := json.Marshal(publicJWK)
@@ -217,6 +261,11 @@ func (kss *Storage) StoreKey(ctx context.Context, key StoredKey) error {
 	if err := kss.tx.Write(ctx, publicKeyNamespace, id, publicBytes); err != nil {
 		return sdkutil.LoggingErrorMsgf(err, "writing public key")
 	}
+	if key.Controller != "" {
+		if err := kss.tx.Write(ctx, controllerIndexNamespace, controllerIndexKey(key.Controller, id), []byte(id)); err != nil {
+			return sdkutil.LoggingErrorMsgf(err, "indexing key by controller")
+		}
+	}
 
 	// encrypt key before storing
 	encryptedKey, err := kss.encrypter.Encrypt(ctx, keyBytes, nil)
@@ -227,7 +276,35 @@ func (kss *Storage) StoreKey(ctx context.Context, key StoredKey) error {
 	return kss.tx.Write(ctx, namespace, id, encryptedKey)
 }
 
-// RevokeKey revokes a key by setting the revoked flag to true.
+// RotateKey stores newKey as the latest version of the key with the same ID. The version it replaces is moved to
+// the key's history so that it remains available for verifying signatures it produced.
+func (kss *Storage) RotateKey(ctx context.Context, newKey StoredKey) (*StoredKey, error) {
+	id := newKey.ID
+	current, err := kss.GetKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	history, err := kss.getKeyHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	current.Version = current.GetVersion()
+	history = append(history, *current)
+	newKey.Version = current.Version + 1
+	if newKey.Controller == "" {
+		newKey.Controller = current.Controller
+	}
+	if err = kss.storeKeyHistory(ctx, id, history); err != nil {
+		return nil, err
+	}
+	if err = kss.StoreKey(ctx, newKey); err != nil {
+		return nil, err
+	}
+	return &newKey, nil
+}
+
+// RevokeKey revokes every version of a key by setting the revoked flag to true.
 func (kss *Storage) RevokeKey(ctx context.Context, id string) error {
 	key, err := kss.GetKey(ctx, id)
 	if err != nil {
@@ -236,19 +313,76 @@ func (kss *Storage) RevokeKey(ctx context.Context, id string) error {
 	if key == nil {
 		return sdkutil.LoggingNewErrorf("key not found: %s", id)
 	}
+	history, err := kss.getKeyHistory(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	revokedAt := kss.Clock.Now().Format(time.RFC3339)
+	for i := range history {
+		if !history[i].Revoked {
+			history[i].Revoked = true
+			history[i].RevokedAt = revokedAt
+		}
+	}
+	if len(history) > 0 {
+		if err = kss.storeKeyHistory(ctx, id, history); err != nil {
+			return err
+		}
+	}
 
+	if key.Revoked {
+		return nil
+	}
 	key.Revoked = true
-	key.RevokedAt = kss.Clock.Now().Format(time.RFC3339)
+	key.RevokedAt = revokedAt
 	return kss.StoreKey(ctx, *key)
 }
 
+// RevokeKeyVersion revokes a single version of a key by setting its revoked flag to true.
+func (kss *Storage) RevokeKeyVersion(ctx context.Context, id string, version int) error {
+	key, err := kss.GetKey(ctx, id)
+	if err != nil {
+		return err
+	}
+	revokedAt := kss.Clock.Now().Format(time.RFC3339)
+	if key.GetVersion() == version {
+		key.Revoked = true
+		key.RevokedAt = revokedAt
+		return kss.StoreKey(ctx, *key)
+	}
+
+	history, err := kss.getKeyHistory(ctx, id)
+	if err != nil {
+		return err
+	}
+	for i := range history {
+		if history[i].GetVersion() == version {
+			history[i].Revoked = true
+			history[i].RevokedAt = revokedAt
+			return kss.storeKeyHistory(ctx, id, history)
+		}
+	}
+	return sdkutil.LoggingNewErrorf("version<%d> of key<%s> not found", version, id)
+}
+
+// KeyExists reports whether a key with the given id is stored.
+func (kss *Storage) KeyExists(ctx context.Context, id string) (bool, error) {
+	exists, err := kss.db.Exists(ctx, namespace, id)
+	if err != nil {
+		return false, sdkutil.LoggingErrorMsgf(err, "checking if key exists: %s", id)
+	}
+	return exists, nil
+}
+
+// GetKey returns the latest version of the key with the given id.
 func (kss *Storage) GetKey(ctx context.Context, id string) (*StoredKey, error) {
 	storedKeyBytes, err := kss.db.Read(ctx, namespace, id)
 	if err != nil {
 		return nil, sdkutil.LoggingErrorMsgf(err, "getting key details for key: %s", id)
 	}
 	if len(storedKeyBytes) == 0 {
-		return nil, sdkutil.LoggingNewErrorf("could not find key details for key: %s", id)
+		return nil, sdkutil.LoggingError(errors.Wrapf(ErrKeyNotFound, "could not find key details for key: %s", id))
 	}
 
 	// decrypt key before unmarshalling
@@ -264,11 +398,189 @@ func (kss *Storage) GetKey(ctx context.Context, id string) (*StoredKey, error) {
 	return &stored, nil
 }
 
+// ListKeysByController returns the latest version of every key whose controller is the given one. Only the keys in the
+// controller's index are read.
+func (kss *Storage) ListKeysByController(ctx context.Context, controller string) ([]StoredKey, error) {
+	backfilled, err := kss.ensureControllerIndexExists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// within a transaction, the entries that were just indexed can't be read back yet
+	if backfilled != nil {
+		var keys []StoredKey
+		for _, stored := range backfilled {
+			if stored.Controller == controller {
+				keys = append(keys, stored)
+			}
+		}
+		return keys, nil
+	}
+	indexed, err := kss.db.ReadPrefix(ctx, controllerIndexNamespace, controllerIndexKey(controller, ""))
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsgf(err, "reading keys of controller: %s", controller)
+	}
+	var keys []StoredKey
+	for _, idBytes := range indexed {
+		id := string(idBytes)
+		exists, err := kss.KeyExists(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			continue
+		}
+		stored, err := kss.GetKey(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		// the index isn't cleared when a key is replaced by one with another controller
+		if stored.Controller == controller {
+			keys = append(keys, *stored)
+		}
+	}
+	return keys, nil
+}
+
+// ensureControllerIndexExists adds the keys stored before keys were indexed by controller to the index. It only reads
+// every key the first time it's called, and returns the keys it read then.
+func (kss *Storage) ensureControllerIndexExists(ctx context.Context) ([]StoredKey, error) {
+	indexed, err := kss.db.Exists(ctx, controllerIndexNamespace, controllerIndexedKey)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsg(err, "checking for the controller index")
+	}
+	if indexed {
+		return nil, nil
+	}
+	storedKeys, err := kss.db.ReadAll(ctx, namespace)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsg(err, "reading keys")
+	}
+	keys := make([]StoredKey, 0, len(storedKeys))
+	for id, storedKeyBytes := range storedKeys {
+		decryptedKey, err := kss.decrypter.Decrypt(ctx, storedKeyBytes, nil)
+		if err != nil {
+			logrus.WithError(err).Debugf("skipping entry<%s> that could not be decrypted", id)
+			continue
+		}
+		var stored StoredKey
+		if err = json.Unmarshal(decryptedKey, &stored); err != nil {
+			logrus.WithError(err).Debugf("skipping entry<%s> that is not a key", id)
+			continue
+		}
+		if stored.ID == "" || stored.Controller == "" {
+			continue
+		}
+		if err = kss.tx.Write(ctx, controllerIndexNamespace, controllerIndexKey(stored.Controller, stored.ID), []byte(stored.ID)); err != nil {
+			return nil, sdkutil.LoggingErrorMsgf(err, "indexing key<%s> by controller", stored.ID)
+		}
+		keys = append(keys, stored)
+	}
+	if err = kss.tx.Write(ctx, controllerIndexNamespace, controllerIndexedKey, []byte{}); err != nil {
+		return nil, sdkutil.LoggingErrorMsg(err, "marking the controller index as built")
+	}
+	return keys, nil
+}
+
+// DeleteKey removes every version of a key, along with its public key.
+func (kss *Storage) DeleteKey(ctx context.Context, id string) error {
+	exists, err := kss.KeyExists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if exists {
+		key, err := kss.GetKey(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err = kss.deleteControllerIndexEntry(ctx, key.Controller, id); err != nil {
+			return err
+		}
+	}
+	for _, ns := range []string{namespace, publicKeyNamespace, keyVersionsNamespace} {
+		exists, err := kss.db.Exists(ctx, ns, id)
+		if err != nil {
+			return sdkutil.LoggingErrorMsgf(err, "checking whether key exists: %s", id)
+		}
+		if !exists {
+			continue
+		}
+		if err = kss.tx.Delete(ctx, ns, id); err != nil {
+			return sdkutil.LoggingErrorMsgf(err, "could not delete key: %s", id)
+		}
+	}
+	return nil
+}
+
+func (kss *Storage) deleteControllerIndexEntry(ctx context.Context, controller, id string) error {
+	if controller == "" {
+		return nil
+	}
+	indexKey := controllerIndexKey(controller, id)
+	exists, err := kss.db.Exists(ctx, controllerIndexNamespace, indexKey)
+	if err != nil {
+		return sdkutil.LoggingErrorMsgf(err, "checking whether key<%s> is indexed by controller", id)
+	}
+	if !exists {
+		return nil
+	}
+	if err = kss.tx.Delete(ctx, controllerIndexNamespace, indexKey); err != nil {
+		return sdkutil.LoggingErrorMsgf(err, "could not delete key<%s> from the controller index", id)
+	}
+	return nil
+}
+
+// GetKeyVersions returns every version of the key with the given id, oldest first.
+func (kss *Storage) GetKeyVersions(ctx context.Context, id string) ([]StoredKey, error) {
+	current, err := kss.GetKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	history, err := kss.getKeyHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	current.Version = current.GetVersion()
+	return append(history, *current), nil
+}
+
+func (kss *Storage) getKeyHistory(ctx context.Context, id string) ([]StoredKey, error) {
+	historyBytes, err := kss.db.Read(ctx, keyVersionsNamespace, id)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsgf(err, "reading version history for key: %s", id)
+	}
+	if len(historyBytes) == 0 {
+		return nil, nil
+	}
+
+	decryptedHistory, err := kss.decrypter.Decrypt(ctx, historyBytes, nil)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsgf(err, "could not decrypt version history for key: %s", id)
+	}
+	var history []StoredKey
+	if err = json.Unmarshal(decryptedHistory, &history); err != nil {
+		return nil, sdkutil.LoggingErrorMsgf(err, "unmarshalling version history for key: %s", id)
+	}
+	return history, nil
+}
+
+func (kss *Storage) storeKeyHistory(ctx context.Context, id string, history []StoredKey) error {
+	historyBytes, err := json.Marshal(history)
+	if err != nil {
+		return sdkutil.LoggingErrorMsgf(err, "marshalling version history for key: %s", id)
+	}
+	encryptedHistory, err := kss.encrypter.Encrypt(ctx, historyBytes, nil)
+	if err != nil {
+		return sdkutil.LoggingErrorMsgf(err, "could not encrypt version history for key: %s", id)
+	}
+	return kss.tx.Write(ctx, keyVersionsNamespace, id, encryptedHistory)
+}
+
 func (kss *Storage) GetKeyDetails(ctx context.Context, id string) (*KeyDetails, error) {
-	stored, err := kss.GetKey(ctx, id)
+	versions, err := kss.GetKeyVersions(ctx, id)
 	if err != nil {
 		return nil, sdkutil.LoggingErrorMsgf(err, "reading details for private key %q", id)
 	}
+	stored := versions[len(versions)-1]
 
 	storedPublicKeyBytes, err := kss.db.Read(ctx, publicKeyNamespace, id)
 	if err != nil {
@@ -279,12 +591,64 @@ func (kss *Storage) GetKeyDetails(ctx context.Context, id string) (*KeyDetails,
 		return nil, sdkutil.LoggingErrorMsgf(err, "unmarshalling public key")
 	}
 
+	versionDetails := make([]KeyVersionDetails, 0, len(versions))
+	for _, version := range versions[:len(versions)-1] {
+		publicKey, err := publicKeyJWKFromStoredKey(version)
+		if err != nil {
+			return nil, sdkutil.LoggingErrorMsgf(err, "reconstructing public key for version<%d> of key %q", version.GetVersion(), id)
+		}
+		versionDetails = append(versionDetails, KeyVersionDetails{
+			Version:      version.GetVersion(),
+			KeyType:      version.KeyType,
+			Revoked:      version.Revoked,
+			RevokedAt:    version.RevokedAt,
+			CreatedAt:    version.CreatedAt,
+			PublicKeyJWK: *publicKey,
+		})
+	}
+	versionDetails = append(versionDetails, KeyVersionDetails{
+		Version:      stored.GetVersion(),
+		KeyType:      stored.KeyType,
+		Revoked:      stored.Revoked,
+		RevokedAt:    stored.RevokedAt,
+		CreatedAt:    stored.CreatedAt,
+		PublicKeyJWK: storedPublicKey,
+	})
+
 	return &KeyDetails{
 		ID:           stored.ID,
 		Controller:   stored.Controller,
 		KeyType:      stored.KeyType,
 		CreatedAt:    stored.CreatedAt,
 		Revoked:      stored.Revoked,
+		RevokedAt:    stored.RevokedAt,
 		PublicKeyJWK: storedPublicKey,
+		Version:      stored.GetVersion(),
+		Versions:     versionDetails,
+		Backend:      stored.GetBackend(),
 	}, nil
 }
+
+func publicKeyJWKFromStoredKey(key StoredKey) (*jwx.PublicKeyJWK, error) {
+	if key.GetBackend() != LocalBackend {
+		if key.PublicKeyJWK == nil {
+			return nil, errors.Errorf("key<%s> held by backend<%s> has no public key", key.ID, key.GetBackend())
+		}
+		publicJWK := *key.PublicKeyJWK
+		publicJWK.KID = key.ID
+		return &publicJWK, nil
+	}
+	skBytes, err := base58.Decode(key.Base58Key)
+	if err != nil {
+		return nil, errors.Wrap(err, "deserializing key from base58")
+	}
+	secretKey, err := crypto.BytesToPrivKey(skBytes, key.KeyType)
+	if err != nil {
+		return nil, errors.Wrap(err, "reconstructing private key")
+	}
+	publicJWK, _, err := jwx.PrivateKeyToPrivateKeyJWK(key.ID, secretKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "reconstructing JWK")
+	}
+	return publicJWK, nil
+}
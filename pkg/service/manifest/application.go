@@ -77,6 +77,7 @@ func (s Service) validateCredentialApplication(ctx context.Context, credManifest
 	}
 
 	// signature and validity checks for each credential submitted with the application
+	var statusFailures []string
 	for _, credentialContainer := range request.Credentials {
 		verificationResult, verificationErr := s.credential.VerifyCredential(ctx, credential.VerifyCredentialRequest{
 			DataIntegrityCredential: credentialContainer.Credential,
@@ -88,10 +89,21 @@ func (s Service) validateCredentialApplication(ctx context.Context, credManifest
 			return
 		}
 
+		// credentials that are revoked or suspended, and otherwise valid, deny the application
+		if !verificationResult.Verified && verificationResult.Report.OnlyStatusFailed() {
+			for _, failure := range verificationResult.StatusFailures {
+				statusFailures = append(statusFailures, fmt.Sprintf("credential<%s> %s", failure.CredentialID, failure.Reason))
+			}
+			continue
+		}
+
 		if !verificationResult.Verified {
 			err = sdkutil.LoggingNewErrorf("submitted credential<%s> is not valid: %s", credentialContainer.Credential.ID, verificationResult.Reason)
 			return
 		}
 	}
+	if len(statusFailures) > 0 {
+		err = errresp.NewErrorResponsef(DenialResponse, "credential status check failed: %s", strings.Join(statusFailures, "; "))
+	}
 	return
 }
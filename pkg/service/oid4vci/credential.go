@@ -0,0 +1,277 @@
+package oid4vci
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/TBD54566975/ssi-sdk/credential/manifest"
+	"github.com/TBD54566975/ssi-sdk/crypto/jwx"
+	"github.com/TBD54566975/ssi-sdk/did"
+	sdkutil "github.com/TBD54566975/ssi-sdk/util"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	didint "github.com/tbd54566975/ssi-service/internal/did"
+	"github.com/tbd54566975/ssi-service/pkg/service/credential"
+)
+
+const (
+	// proofMaxAge is how long after it's issued a proof is accepted.
+	proofMaxAge = 5 * time.Minute
+	// proofClockSkew is how far in the future a proof's iat may be, to allow for clock differences with the wallet.
+	proofClockSkew = 30 * time.Second
+)
+
+var proofSigningAlgorithms = []string{"EdDSA", "ES256", "ES256K", "ES384", "PS256"}
+
+// IssueCredential handles a request to the credential endpoint. The access token determines the offer the credential
+// is issued from. The request's proof must be a JWT signed by a key of the holder's DID, carrying the access token's
+// c_nonce, and the credential is issued to that DID. Every offered credential can be issued once.
+func (s Service) IssueCredential(ctx context.Context, accessToken string, request CredentialRequest) (*CredentialResponse, error) {
+	token, err := s.getToken(ctx, accessToken, AccessToken)
+	if err != nil {
+		if errors.Is(err, ErrTokenNotFound) {
+			return nil, newError(InvalidToken, "access token is invalid or expired")
+		}
+		return nil, err
+	}
+	offer, err := s.storage.GetOffer(ctx, token.OfferID)
+	if err != nil {
+		if errors.Is(err, ErrOfferNotFound) {
+			return nil, newError(InvalidToken, "the credential offer was deleted")
+		}
+		return nil, errors.Wrap(err, "getting credential offer")
+	}
+
+	configurationID, err := selectConfiguration(*offer, request)
+	if err != nil {
+		return nil, err
+	}
+	holderDID, err := s.verifyProof(ctx, *token, request.Proof)
+	if err != nil {
+		// give the wallet a fresh c_nonce to retry with
+		if oauthErr := GetError(err); oauthErr != nil && oauthErr.Code == InvalidProof {
+			rotated, err := s.storage.UpdateToken(ctx, accessToken, s.rotateCNonce)
+			if err != nil {
+				return nil, errors.Wrap(err, "storing access token")
+			}
+			oauthErr.CNonce = rotated.CNonce
+			oauthErr.CNonceExpiresIn = int(cNonceDuration.Seconds())
+			return nil, oauthErr
+		}
+		return nil, err
+	}
+
+	// the proof's c_nonce is used up and the credential is claimed in a transaction, so that proofs can't be replayed,
+	// and concurrent requests can't issue the same credential twice
+	err = s.storage.UpdateTokenAndOffer(ctx, accessToken, offer.ID, func(current *StoredToken, currentOffer *StoredOffer) error {
+		if !s.usable(*current, AccessToken) {
+			return ErrTokenNotFound
+		}
+		if current.CNonce != token.CNonce {
+			return newError(InvalidProof, "proof nonce must be the current c_nonce")
+		}
+		if _, ok := currentOffer.IssuedCredentials[configurationID]; ok {
+			return newError(InvalidCredentialRequest, "credential %q was already issued", configurationID)
+		}
+		if currentOffer.IssuedCredentials == nil {
+			currentOffer.IssuedCredentials = make(map[string]string)
+		}
+		currentOffer.IssuedCredentials[configurationID] = ""
+		if err := s.rotateCNonce(current); err != nil {
+			return err
+		}
+		*token = *current
+		return nil
+	})
+	if err != nil {
+		if oauthErr := GetError(err); oauthErr != nil {
+			return nil, oauthErr
+		}
+		if errors.Is(err, ErrTokenNotFound) {
+			return nil, newError(InvalidToken, "access token is invalid or expired")
+		}
+		if errors.Is(err, ErrOfferNotFound) {
+			return nil, newError(InvalidToken, "the credential offer was deleted")
+		}
+		return nil, errors.Wrap(err, "claiming credential")
+	}
+
+	credentialResponse, err := s.createCredential(ctx, *offer, configurationID, holderDID)
+	if err != nil {
+		// credentials that couldn't be issued can be requested again
+		if _, releaseErr := s.storage.UpdateOffer(ctx, offer.ID, func(offer *StoredOffer) error {
+			if id, ok := offer.IssuedCredentials[configurationID]; ok && id == "" {
+				delete(offer.IssuedCredentials, configurationID)
+			}
+			return nil
+		}); releaseErr != nil {
+			logrus.WithError(releaseErr).Errorf("could not release credential<%s> of offer<%s>", configurationID, offer.ID)
+		}
+		return nil, err
+	}
+	if _, err = s.storage.UpdateOffer(ctx, offer.ID, func(offer *StoredOffer) error {
+		offer.IssuedCredentials[configurationID] = credentialResponse.ID
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "storing credential offer")
+	}
+	return &CredentialResponse{
+		Credential:      *credentialResponse.CredentialJWT,
+		CNonce:          token.CNonce,
+		CNonceExpiresIn: int(cNonceDuration.Seconds()),
+	}, nil
+}
+
+// createCredential creates an offered credential for the holder.
+func (s Service) createCredential(ctx context.Context, offer StoredOffer, configurationID, holderDID string) (*credential.CreateCredentialResponse, error) {
+	createCredentialRequest, err := s.createCredentialRequest(ctx, offer, configurationID, holderDID)
+	if err != nil {
+		return nil, err
+	}
+	credentialResponse, err := s.credential.CreateCredential(ctx, *createCredentialRequest)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsg(err, "could not create credential")
+	}
+	if credentialResponse.CredentialJWT == nil {
+		return nil, errors.New("created credential is not a JWT")
+	}
+	return credentialResponse, nil
+}
+
+// selectConfiguration returns the ID of the offered credential configuration a credential request is for. Requests
+// by format get the first offered credential that hasn't been issued yet.
+func selectConfiguration(offer StoredOffer, request CredentialRequest) (string, error) {
+	if request.CredentialIdentifier != "" {
+		if !sdkutil.Contains(request.CredentialIdentifier, offer.CredentialConfigurationIDs) {
+			return "", newError(InvalidCredentialRequest, "credential %q was not offered", request.CredentialIdentifier)
+		}
+		if _, ok := offer.IssuedCredentials[request.CredentialIdentifier]; ok {
+			return "", newError(InvalidCredentialRequest, "credential %q was already issued", request.CredentialIdentifier)
+		}
+		return request.CredentialIdentifier, nil
+	}
+
+	if request.Format == "" {
+		return "", newError(InvalidCredentialRequest, "format or credential_identifier is required")
+	}
+	if request.Format != JWTVCJSONFormat {
+		return "", newError(UnsupportedCredentialFormat, "format %q is not supported", request.Format)
+	}
+	if request.CredentialDefinition != nil && !sdkutil.Contains("VerifiableCredential", request.CredentialDefinition.Type) {
+		return "", newError(UnsupportedCredentialType, "credential_definition type must include VerifiableCredential")
+	}
+	for _, id := range offer.CredentialConfigurationIDs {
+		if _, ok := offer.IssuedCredentials[id]; !ok {
+			return id, nil
+		}
+	}
+	return "", newError(InvalidCredentialRequest, "every offered credential was already issued")
+}
+
+// verifyProof verifies a proof of possession, returning the DID of the holder it was signed by.
+func (s Service) verifyProof(ctx context.Context, token StoredToken, proof *Proof) (string, error) {
+	if proof == nil {
+		return "", newError(InvalidProof, "proof is required")
+	}
+	if proof.ProofType != JWTProofType {
+		return "", newError(InvalidProof, "proof_type must be %s", JWTProofType)
+	}
+
+	headers, err := jwx.GetJWSHeaders([]byte(proof.JWT))
+	if err != nil {
+		return "", newError(InvalidProof, "could not parse proof headers: %s", err.Error())
+	}
+	if headers.Type() != ProofJWTType {
+		return "", newError(InvalidProof, "proof typ must be %s", ProofJWTType)
+	}
+	kid := headers.KeyID()
+	holderDID, _, found := strings.Cut(kid, "#")
+	if !found || !strings.HasPrefix(holderDID, "did:") {
+		return "", newError(InvalidProof, "proof kid must be a DID URL")
+	}
+
+	claims, err := jwt.Parse([]byte(proof.JWT), jwt.WithVerify(false), jwt.WithValidate(false))
+	if err != nil {
+		return "", newError(InvalidProof, "could not parse proof: %s", err.Error())
+	}
+	if !sdkutil.Contains(IssuerIdentifier(), claims.Audience()) {
+		return "", newError(InvalidProof, "proof aud must be the credential issuer")
+	}
+	if nonce, _ := claims.Get("nonce"); nonce != token.CNonce || s.expired(token.CNonceExpiration) {
+		return "", newError(InvalidProof, "proof nonce must be the current c_nonce")
+	}
+	now := s.Clock.Now()
+	if claims.IssuedAt().IsZero() || claims.IssuedAt().Before(now.Add(-proofMaxAge)) || claims.IssuedAt().After(now.Add(proofClockSkew)) {
+		return "", newError(InvalidProof, "proof iat must be recent")
+	}
+
+	if err = didint.VerifyTokenFromDID(ctx, s.resolver, holderDID, kid, proof.JWT); err != nil {
+		return "", newError(InvalidProof, "could not verify proof signature: %s", err.Error())
+	}
+	return holderDID, nil
+}
+
+// createCredentialRequest builds the request for an offered credential from the offer's issuance template, with the
+// offer's overrides applied on top.
+func (s Service) createCredentialRequest(ctx context.Context, offer StoredOffer, configurationID, holderDID string) (*credential.CreateCredentialRequest, error) {
+	storedTemplate, err := s.issuanceStorage.GetIssuanceTemplate(ctx, offer.IssuanceTemplateID)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting issuance template")
+	}
+	template := storedTemplate.IssuanceTemplate
+	storedManifest, err := s.manifestStorage.GetManifest(ctx, offer.ManifestID)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting manifest")
+	}
+
+	outputDescriptorID := strings.TrimPrefix(configurationID, offer.ManifestID+"/")
+	od := outputDescriptor(storedManifest.Manifest, outputDescriptorID)
+	if od == nil {
+		return nil, errors.Errorf("output descriptor %q not found in manifest %s", outputDescriptorID, offer.ManifestID)
+	}
+	request := credential.CreateCredentialRequest{
+		Issuer:                             template.Issuer,
+		FullyQualifiedVerificationMethodID: did.FullyQualifiedVerificationMethodID(template.Issuer, template.VerificationMethodID),
+		Subject:                            holderDID,
+		SchemaID:                           od.Schema,
+		Data:                               make(map[string]any),
+	}
+	for _, templateCred := range template.Credentials {
+		if templateCred.ID != outputDescriptorID {
+			continue
+		}
+		for k, v := range templateCred.Data {
+			request.Data[k] = v
+		}
+		if templateCred.Expiry.Time != nil {
+			request.Expiry = templateCred.Expiry.Time.Format(time.RFC3339)
+		}
+		if templateCred.Expiry.Duration != nil {
+			request.Expiry = s.Clock.Now().Add(*templateCred.Expiry.Duration).Format(time.RFC3339)
+		}
+		request.Revocable = templateCred.Revocable
+	}
+	if override, ok := offer.CredentialOverrides[outputDescriptorID]; ok {
+		for k, v := range override.Data {
+			request.Data[k] = v
+		}
+		if override.Expiry != nil {
+			request.Expiry = override.Expiry.Format(time.RFC3339)
+		}
+		request.Revocable = request.Revocable || override.Revocable
+	}
+	return &request, nil
+}
+
+func outputDescriptor(credManifest manifest.CredentialManifest, id string) *manifest.OutputDescriptor {
+	for _, od := range credManifest.OutputDescriptors {
+		if od.ID == id {
+			od := od
+			return &od
+		}
+	}
+	return nil
+}
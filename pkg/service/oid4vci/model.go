@@ -0,0 +1,233 @@
+package oid4vci
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/tbd54566975/ssi-service/internal/keyaccess"
+	"github.com/tbd54566975/ssi-service/pkg/service/manifest/model"
+)
+
+const (
+	// PreAuthorizedCodeGrantType is the grant type of the pre-authorized code flow, as described in
+	// https://openid.net/specs/openid-4-verifiable-credential-issuance-1_0.html#name-pre-authorized-code-flow
+	PreAuthorizedCodeGrantType = "urn:ietf:params:oauth:grant-type:pre-authorized_code"
+	// AuthorizationCodeGrantType is the grant type of the authorization code flow.
+	AuthorizationCodeGrantType = "authorization_code"
+
+	// JWTVCJSONFormat is the only credential format that's issued.
+	JWTVCJSONFormat = "jwt_vc_json"
+	// JWTProofType is the only type of proof of possession that's accepted.
+	JWTProofType = "jwt"
+	// ProofJWTType is the required typ header of proof JWTs.
+	ProofJWTType = "openid4vci-proof+jwt"
+
+	// CodeChallengeMethodS256 is the only PKCE code challenge method that's accepted.
+	CodeChallengeMethodS256 = "S256"
+)
+
+// TxCode describes the transaction code a wallet must ask the holder for, and send alongside a pre-authorized code.
+type TxCode struct {
+	InputMode   string `json:"input_mode,omitempty"`
+	Length      int    `json:"length,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type PreAuthorizedCodeGrant struct {
+	PreAuthorizedCode string  `json:"pre-authorized_code"`
+	TxCode            *TxCode `json:"tx_code,omitempty"`
+}
+
+type AuthorizationCodeGrant struct {
+	// Binds the authorization request to the offer.
+	IssuerState string `json:"issuer_state,omitempty"`
+}
+
+type Grants struct {
+	AuthorizationCode *AuthorizationCodeGrant `json:"authorization_code,omitempty"`
+	PreAuthorizedCode *PreAuthorizedCodeGrant `json:"urn:ietf:params:oauth:grant-type:pre-authorized_code,omitempty"`
+}
+
+// CredentialOffer is sent to wallets to start issuance, as described in
+// https://openid.net/specs/openid-4-verifiable-credential-issuance-1_0.html#name-credential-offer-parameters
+type CredentialOffer struct {
+	CredentialIssuer           string   `json:"credential_issuer"`
+	CredentialConfigurationIDs []string `json:"credential_configuration_ids"`
+	Grants                     Grants   `json:"grants"`
+}
+
+type CreateOfferRequest struct {
+	// ID of the issuance template the offered credentials are issued with. The template's manifest determines which
+	// credentials are offered.
+	IssuanceTemplateID string `json:"issuanceTemplateId" validate:"required"`
+
+	// Overrides the template's claims, keyed by output descriptor ID.
+	CredentialOverrides map[string]model.CredentialOverride `json:"credentialOverrides,omitempty"`
+
+	// Whether the offer has a pre-authorized code. Otherwise, wallets go through the authorization code flow.
+	PreAuthorized bool `json:"preAuthorized"`
+
+	// Whether the holder must enter a numeric transaction code to redeem the pre-authorized code.
+	RequireTxCode bool `json:"requireTxCode"`
+
+	// Length of the transaction code. Defaults to 6.
+	TxCodeLength int `json:"txCodeLength,omitempty" validate:"omitempty,min=4,max=12"`
+
+	// When the offer expires. Defaults to 24 hours from now.
+	Expiration *time.Time `json:"expiration,omitempty"`
+}
+
+// Offer is a credential offer, along with what's needed to hand it to a wallet.
+type Offer struct {
+	ID                 string `json:"id"`
+	IssuanceTemplateID string `json:"issuanceTemplateId"`
+	ManifestID         string `json:"manifestId"`
+
+	CredentialOffer CredentialOffer `json:"credentialOffer"`
+
+	// Where wallets fetch the credential offer from.
+	CredentialOfferURI string `json:"credentialOfferUri"`
+
+	// Invokes a wallet with the offer by reference, such as from a QR code.
+	CredentialOfferDeepLink string `json:"credentialOfferDeepLink"`
+
+	// The transaction code the holder must enter, which must be sent to them out of band. Only returned when the offer
+	// is created.
+	TxCode string `json:"txCode,omitempty"`
+
+	Expiration time.Time `json:"expiration"`
+
+	// IDs of the credentials issued for the offer, keyed by credential configuration ID.
+	IssuedCredentials map[string]string `json:"issuedCredentials,omitempty"`
+}
+
+// AuthorizationRequest is a wallet's authorization request in the authorization code flow, which must use PKCE.
+type AuthorizationRequest struct {
+	ResponseType        string
+	RedirectURI         string
+	IssuerState         string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+type TokenRequest struct {
+	GrantType         string
+	PreAuthorizedCode string
+	TxCode            string
+	Code              string
+	CodeVerifier      string
+	RedirectURI       string
+}
+
+type TokenResponse struct {
+	AccessToken     string `json:"access_token"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int    `json:"expires_in"`
+	CNonce          string `json:"c_nonce"`
+	CNonceExpiresIn int    `json:"c_nonce_expires_in"`
+}
+
+type CredentialDefinition struct {
+	Type []string `json:"type"`
+}
+
+type Proof struct {
+	ProofType string        `json:"proof_type"`
+	JWT       keyaccess.JWT `json:"jwt"`
+}
+
+// CredentialRequest is a wallet's request for a credential, as described in
+// https://openid.net/specs/openid-4-verifiable-credential-issuance-1_0.html#name-credential-request
+type CredentialRequest struct {
+	Format               string                `json:"format,omitempty"`
+	CredentialIdentifier string                `json:"credential_identifier,omitempty"`
+	CredentialDefinition *CredentialDefinition `json:"credential_definition,omitempty"`
+	Proof                *Proof                `json:"proof,omitempty"`
+}
+
+type CredentialResponse struct {
+	Credential      keyaccess.JWT `json:"credential"`
+	CNonce          string        `json:"c_nonce"`
+	CNonceExpiresIn int           `json:"c_nonce_expires_in"`
+}
+
+type ProofType struct {
+	ProofSigningAlgValuesSupported []string `json:"proof_signing_alg_values_supported"`
+}
+
+type Display struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type CredentialConfiguration struct {
+	Format                               string               `json:"format"`
+	CryptographicBindingMethodsSupported []string             `json:"cryptographic_binding_methods_supported"`
+	ProofTypesSupported                  map[string]ProofType `json:"proof_types_supported"`
+	CredentialDefinition                 CredentialDefinition `json:"credential_definition"`
+	Display                              []Display            `json:"display,omitempty"`
+}
+
+// IssuerMetadata describes the credential issuer, as described in
+// https://openid.net/specs/openid-4-verifiable-credential-issuance-1_0.html#name-credential-issuer-metadata
+type IssuerMetadata struct {
+	CredentialIssuer                  string                             `json:"credential_issuer"`
+	CredentialEndpoint                string                             `json:"credential_endpoint"`
+	CredentialConfigurationsSupported map[string]CredentialConfiguration `json:"credential_configurations_supported"`
+}
+
+// AuthorizationServerMetadata describes the issuer's authorization server, as described in
+// https://www.rfc-editor.org/rfc/rfc8414.html#section-2
+type AuthorizationServerMetadata struct {
+	Issuer                                     string   `json:"issuer"`
+	AuthorizationEndpoint                      string   `json:"authorization_endpoint"`
+	TokenEndpoint                              string   `json:"token_endpoint"`
+	ResponseTypesSupported                     []string `json:"response_types_supported"`
+	GrantTypesSupported                        []string `json:"grant_types_supported"`
+	CodeChallengeMethodsSupported              []string `json:"code_challenge_methods_supported"`
+	PreAuthorizedGrantAnonymousAccessSupported bool     `json:"pre-authorized_grant_anonymous_access_supported"`
+}
+
+type ErrorCode string
+
+const (
+	InvalidRequest              ErrorCode = "invalid_request"
+	InvalidGrant                ErrorCode = "invalid_grant"
+	UnsupportedGrantType        ErrorCode = "unsupported_grant_type"
+	UnsupportedResponseType     ErrorCode = "unsupported_response_type"
+	AccessDenied                ErrorCode = "access_denied"
+	InvalidToken                ErrorCode = "invalid_token"
+	InvalidCredentialRequest    ErrorCode = "invalid_credential_request"
+	UnsupportedCredentialType   ErrorCode = "unsupported_credential_type"
+	UnsupportedCredentialFormat ErrorCode = "unsupported_credential_format"
+	InvalidProof                ErrorCode = "invalid_proof"
+)
+
+// Error is an OAuth 2.0 error response from the authorization, token, or credential endpoints.
+type Error struct {
+	Code        ErrorCode `json:"error"`
+	Description string    `json:"error_description,omitempty"`
+
+	// A fresh c_nonce, which is set when a proof is rejected so the wallet can retry.
+	CNonce          string `json:"c_nonce,omitempty"`
+	CNonceExpiresIn int    `json:"c_nonce_expires_in,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Description)
+}
+
+func newError(code ErrorCode, format string, args ...any) *Error {
+	return &Error{Code: code, Description: fmt.Sprintf(format, args...)}
+}
+
+// GetError returns the OAuth 2.0 error within err, or nil when there isn't one.
+func GetError(err error) *Error {
+	var oauthErr *Error
+	if errors.As(err, &oauthErr) {
+		return oauthErr
+	}
+	return nil
+}
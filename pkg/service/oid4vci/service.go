@@ -0,0 +1,365 @@
+package oid4vci
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/TBD54566975/ssi-sdk/did/resolution"
+	sdkutil "github.com/TBD54566975/ssi-sdk/util"
+	"github.com/benbjohnson/clock"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/tbd54566975/ssi-service/config"
+	"github.com/tbd54566975/ssi-service/internal/util"
+	"github.com/tbd54566975/ssi-service/pkg/service/credential"
+	"github.com/tbd54566975/ssi-service/pkg/service/framework"
+	"github.com/tbd54566975/ssi-service/pkg/service/issuance"
+	manifeststg "github.com/tbd54566975/ssi-service/pkg/service/manifest/storage"
+	"github.com/tbd54566975/ssi-service/pkg/storage"
+)
+
+const (
+	// OffersPath is where credential offers are served from, relative to the issuer identifier.
+	OffersPath = "/offers"
+	// AuthorizationPath is the authorization endpoint, relative to the issuer identifier.
+	AuthorizationPath = "/authorize"
+	// TokenPath is the token endpoint, relative to the issuer identifier.
+	TokenPath = "/token"
+	// CredentialPath is the credential endpoint, relative to the issuer identifier.
+	CredentialPath = "/credential"
+	// IssuerMetadataPath is where the credential issuer metadata is served from, relative to the issuer identifier.
+	IssuerMetadataPath = "/.well-known/openid-credential-issuer"
+	// AuthorizationServerMetadataPath is where the authorization server metadata is served from, relative to the
+	// issuer identifier.
+	AuthorizationServerMetadataPath = "/.well-known/oauth-authorization-server"
+
+	credentialOfferScheme = "openid-credential-offer"
+
+	defaultOfferDuration      = 24 * time.Hour
+	authorizationCodeDuration = 5 * time.Minute
+	accessTokenDuration       = 15 * time.Minute
+	cNonceDuration            = 5 * time.Minute
+	defaultTxCodeLength       = 6
+	maxTxCodeAttempts         = 5
+	tokenSize                 = 32
+)
+
+// Service is an OpenID for Verifiable Credential Issuance (OID4VCI) issuer, which issues credentials offered from
+// issuance templates to wallets. It acts as its own authorization server.
+type Service struct {
+	storage         Storage
+	issuanceStorage issuance.Storage
+	manifestStorage manifeststg.Storage
+	credential      *credential.Service
+	resolver        resolution.Resolver
+	Clock           clock.Clock
+}
+
+func (s Service) Type() framework.Type {
+	return framework.OID4VCI
+}
+
+func (s Service) Status() framework.Status {
+	ae := sdkutil.NewAppendError()
+	if s.credential == nil {
+		ae.AppendString("no credential service configured")
+	}
+	if s.resolver == nil {
+		ae.AppendString("no resolver configured")
+	}
+	if !ae.IsEmpty() {
+		return framework.Status{
+			Status:  framework.StatusNotReady,
+			Message: ae.Error().Error(),
+		}
+	}
+	return framework.Status{Status: framework.StatusReady}
+}
+
+func NewOID4VCIService(s storage.ServiceStorage, resolver resolution.Resolver, credentialService *credential.Service) (*Service, error) {
+	oid4vciStorage, err := NewOID4VCIStorage(s)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsg(err, "could not instantiate storage for the oid4vci service")
+	}
+	issuanceStorage, err := issuance.NewIssuanceStorage(s)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsg(err, "could not instantiate issuance storage for the oid4vci service")
+	}
+	manifestStorage, err := manifeststg.NewManifestStorage(s)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsg(err, "could not instantiate manifest storage for the oid4vci service")
+	}
+	if resolver == nil {
+		return nil, sdkutil.LoggingNewError("cannot instantiate oid4vci service without a DID resolver")
+	}
+	return &Service{
+		storage:         *oid4vciStorage,
+		issuanceStorage: *issuanceStorage,
+		manifestStorage: *manifestStorage,
+		credential:      credentialService,
+		resolver:        resolver,
+		Clock:           clock.New(),
+	}, nil
+}
+
+// IssuerIdentifier is the credential issuer identifier, which all the issuer's endpoints are relative to.
+func IssuerIdentifier() string {
+	return config.GetServicePath(framework.OID4VCI)
+}
+
+// CreateOffer creates a credential offer for every credential in an issuance template. The offer is redeemed either
+// with a pre-authorized code, optionally protected by a transaction code, or through the authorization code flow.
+func (s Service) CreateOffer(ctx context.Context, request CreateOfferRequest) (*Offer, error) {
+	if err := sdkutil.IsValidStruct(request); err != nil {
+		return nil, sdkutil.LoggingErrorMsg(err, "invalid create credential offer request")
+	}
+	if request.RequireTxCode && !request.PreAuthorized {
+		return nil, errors.New("a transaction code can only be required for pre-authorized offers")
+	}
+
+	storedTemplate, err := s.issuanceStorage.GetIssuanceTemplate(ctx, request.IssuanceTemplateID)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting issuance template")
+	}
+	template := storedTemplate.IssuanceTemplate
+	storedManifest, err := s.manifestStorage.GetManifest(ctx, template.CredentialManifest)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting manifest")
+	}
+	// the issuer metadata is derived from the manifest, so credentials must be issued by the manifest's issuer
+	if template.Issuer != storedManifest.Manifest.Issuer.ID {
+		return nil, errors.Errorf("issuance template issuer %s is not the issuer of manifest %s", template.Issuer, storedManifest.ID)
+	}
+
+	configurationIDs := make([]string, 0, len(template.Credentials))
+	for _, templateCred := range template.Credentials {
+		if outputDescriptor(storedManifest.Manifest, templateCred.ID) == nil {
+			return nil, errors.Errorf("issuance template credential %q is not an output descriptor of manifest %s", templateCred.ID, storedManifest.ID)
+		}
+		// offers aren't made from credential applications, so there's nothing to look up JSON paths in
+		for k, v := range templateCred.Data {
+			if vs, ok := v.(string); ok && strings.HasPrefix(vs, "$") {
+				return nil, errors.Errorf("claim %q of issuance template credential %q is a JSON path, which offers can't resolve", k, templateCred.ID)
+			}
+		}
+		configurationIDs = append(configurationIDs, configurationID(storedManifest.ID, templateCred.ID))
+	}
+	if len(configurationIDs) == 0 {
+		return nil, errors.Errorf("issuance template %s has no credentials to offer", template.ID)
+	}
+	for id := range request.CredentialOverrides {
+		if !sdkutil.Contains(configurationID(storedManifest.ID, id), configurationIDs) {
+			return nil, errors.Errorf("credential override %q is not a credential of the issuance template", id)
+		}
+	}
+
+	expiration := s.Clock.Now().Add(defaultOfferDuration)
+	if request.Expiration != nil {
+		if !request.Expiration.After(s.Clock.Now()) {
+			return nil, errors.New("expiration must be in the future")
+		}
+		expiration = *request.Expiration
+	}
+
+	stored := StoredOffer{
+		ID:                         uuid.NewString(),
+		IssuanceTemplateID:         template.ID,
+		ManifestID:                 storedManifest.ID,
+		CredentialConfigurationIDs: configurationIDs,
+		CredentialOverrides:        request.CredentialOverrides,
+		Expiration:                 expiration.Format(time.RFC3339),
+	}
+	var txCode string
+	if request.PreAuthorized {
+		if stored.PreAuthorizedCode, err = randomToken(); err != nil {
+			return nil, err
+		}
+		if request.RequireTxCode {
+			stored.TxCodeLength = request.TxCodeLength
+			if stored.TxCodeLength == 0 {
+				stored.TxCodeLength = defaultTxCodeLength
+			}
+			if txCode, err = randomDigits(stored.TxCodeLength); err != nil {
+				return nil, err
+			}
+			stored.TxCodeHash = hashValue(txCode)
+		}
+		code := StoredToken{Kind: PreAuthorizedCodeToken, OfferID: stored.ID, Expiration: stored.Expiration}
+		if err = s.storage.StoreToken(ctx, stored.PreAuthorizedCode, code); err != nil {
+			return nil, errors.Wrap(err, "storing pre-authorized code")
+		}
+	}
+	if err = s.storage.StoreOffer(ctx, stored); err != nil {
+		return nil, errors.Wrap(err, "storing credential offer")
+	}
+
+	offer, err := offerModel(stored)
+	if err != nil {
+		return nil, err
+	}
+	offer.TxCode = txCode
+	return offer, nil
+}
+
+// GetOffer gets the credential offer with the given ID, as long as it hasn't expired.
+func (s Service) GetOffer(ctx context.Context, id string) (*Offer, error) {
+	logrus.Debugf("getting credential offer: %s", id)
+
+	stored, err := s.storage.GetOffer(ctx, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting credential offer")
+	}
+	if s.expired(stored.Expiration) {
+		return nil, errors.Errorf("credential offer %s has expired", id)
+	}
+	return offerModel(*stored)
+}
+
+// DeleteOffer deletes a credential offer, so it can't be redeemed anymore. Access tokens that were already issued
+// for the offer stop working.
+func (s Service) DeleteOffer(ctx context.Context, id string) error {
+	logrus.Debugf("deleting credential offer: %s", id)
+
+	stored, err := s.storage.GetOffer(ctx, id)
+	if err != nil {
+		return errors.Wrap(err, "getting credential offer")
+	}
+	if stored.PreAuthorizedCode != "" {
+		if err = s.storage.DeleteToken(ctx, stored.PreAuthorizedCode); err != nil {
+			return errors.Wrap(err, "deleting pre-authorized code")
+		}
+	}
+	if err = s.storage.DeleteOffer(ctx, id); err != nil {
+		return errors.Wrap(err, "deleting credential offer")
+	}
+	return nil
+}
+
+// GetIssuerMetadata returns the credential issuer metadata. There's a credential configuration for every output
+// descriptor of every stored manifest, with the ID `<manifest ID>/<output descriptor ID>`.
+func (s Service) GetIssuerMetadata(ctx context.Context) (*IssuerMetadata, error) {
+	manifests, err := s.manifestStorage.ListManifests(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing manifests")
+	}
+
+	bindingMethods := make([]string, 0, len(s.resolver.Methods()))
+	for _, method := range s.resolver.Methods() {
+		bindingMethods = append(bindingMethods, "did:"+method.String())
+	}
+	configurations := make(map[string]CredentialConfiguration)
+	for _, storedManifest := range manifests {
+		for _, od := range storedManifest.Manifest.OutputDescriptors {
+			configuration := CredentialConfiguration{
+				Format:                               JWTVCJSONFormat,
+				CryptographicBindingMethodsSupported: bindingMethods,
+				ProofTypesSupported: map[string]ProofType{
+					JWTProofType: {ProofSigningAlgValuesSupported: proofSigningAlgorithms},
+				},
+				CredentialDefinition: CredentialDefinition{Type: []string{"VerifiableCredential"}},
+			}
+			if od.Name != "" || od.Description != "" {
+				configuration.Display = []Display{{Name: od.Name, Description: od.Description}}
+			}
+			configurations[configurationID(storedManifest.ID, od.ID)] = configuration
+		}
+	}
+
+	issuer := IssuerIdentifier()
+	return &IssuerMetadata{
+		CredentialIssuer:                  issuer,
+		CredentialEndpoint:                issuer + CredentialPath,
+		CredentialConfigurationsSupported: configurations,
+	}, nil
+}
+
+// GetAuthorizationServerMetadata returns the metadata of the issuer's authorization server.
+func (s Service) GetAuthorizationServerMetadata() AuthorizationServerMetadata {
+	issuer := IssuerIdentifier()
+	return AuthorizationServerMetadata{
+		Issuer:                        issuer,
+		AuthorizationEndpoint:         issuer + AuthorizationPath,
+		TokenEndpoint:                 issuer + TokenPath,
+		ResponseTypesSupported:        []string{"code"},
+		GrantTypesSupported:           []string{AuthorizationCodeGrantType, PreAuthorizedCodeGrantType},
+		CodeChallengeMethodsSupported: []string{CodeChallengeMethodS256},
+		PreAuthorizedGrantAnonymousAccessSupported: true,
+	}
+}
+
+func offerModel(stored StoredOffer) (*Offer, error) {
+	expiration, err := time.Parse(time.RFC3339, stored.Expiration)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing expiration time")
+	}
+
+	issuer := IssuerIdentifier()
+	credentialOffer := CredentialOffer{
+		CredentialIssuer:           issuer,
+		CredentialConfigurationIDs: stored.CredentialConfigurationIDs,
+	}
+	if stored.PreAuthorizedCode != "" {
+		grant := PreAuthorizedCodeGrant{PreAuthorizedCode: stored.PreAuthorizedCode}
+		if stored.TxCodeHash != "" {
+			grant.TxCode = &TxCode{InputMode: "numeric", Length: stored.TxCodeLength}
+		}
+		credentialOffer.Grants.PreAuthorizedCode = &grant
+	} else {
+		credentialOffer.Grants.AuthorizationCode = &AuthorizationCodeGrant{IssuerState: stored.ID}
+	}
+
+	offerURI := issuer + OffersPath + "/" + stored.ID
+	query := url.Values{"credential_offer_uri": {offerURI}}
+	return &Offer{
+		ID:                      stored.ID,
+		IssuanceTemplateID:      stored.IssuanceTemplateID,
+		ManifestID:              stored.ManifestID,
+		CredentialOffer:         credentialOffer,
+		CredentialOfferURI:      offerURI,
+		CredentialOfferDeepLink: credentialOfferScheme + "://?" + query.Encode(),
+		Expiration:              expiration,
+		IssuedCredentials:       stored.IssuedCredentials,
+	}, nil
+}
+
+func configurationID(manifestID, outputDescriptorID string) string {
+	return manifestID + "/" + outputDescriptorID
+}
+
+// expired returns whether the given RFC3339 time has passed. Times that can't be parsed are treated as expired.
+func (s Service) expired(expiration string) bool {
+	t, err := time.Parse(time.RFC3339, expiration)
+	if err != nil {
+		logrus.WithError(err).Warnf("parsing expiration time: %s", expiration)
+		return true
+	}
+	return s.Clock.Now().After(t)
+}
+
+// randomToken returns an unguessable value for codes, tokens, and nonces.
+func randomToken() (string, error) {
+	value, err := util.GenerateSalt(tokenSize)
+	if err != nil {
+		return "", errors.Wrap(err, "generating token")
+	}
+	return base64.RawURLEncoding.EncodeToString(value), nil
+}
+
+func randomDigits(length int) (string, error) {
+	var digits strings.Builder
+	for i := 0; i < length; i++ {
+		digit, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", errors.Wrap(err, "generating transaction code")
+		}
+		digits.WriteString(digit.String())
+	}
+	return digits.String(), nil
+}
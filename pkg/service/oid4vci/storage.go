@@ -0,0 +1,230 @@
+package oid4vci
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/goccy/go-json"
+	"github.com/pkg/errors"
+
+	"github.com/tbd54566975/ssi-service/pkg/service/manifest/model"
+	"github.com/tbd54566975/ssi-service/pkg/storage"
+)
+
+const (
+	offerNamespace = "oid4vci_offer"
+	tokenNamespace = "oid4vci_token"
+)
+
+var (
+	ErrOfferNotFound = errors.New("credential offer not found")
+	ErrTokenNotFound = errors.New("token not found")
+)
+
+type TokenKind string
+
+const (
+	PreAuthorizedCodeToken TokenKind = "pre-authorized_code"
+	AuthorizationCodeToken TokenKind = "authorization_code"
+	AccessToken            TokenKind = "access_token"
+)
+
+type StoredOffer struct {
+	ID                         string                              `json:"id"`
+	IssuanceTemplateID         string                              `json:"issuanceTemplateId"`
+	ManifestID                 string                              `json:"manifestId"`
+	CredentialConfigurationIDs []string                            `json:"credentialConfigurationIds"`
+	CredentialOverrides        map[string]model.CredentialOverride `json:"credentialOverrides,omitempty"`
+	PreAuthorizedCode          string                              `json:"preAuthorizedCode,omitempty"`
+	TxCodeHash                 string                              `json:"txCodeHash,omitempty"`
+	TxCodeLength               int                                 `json:"txCodeLength,omitempty"`
+	TxCodeAttempts             int                                 `json:"txCodeAttempts,omitempty"`
+	Expiration                 string                              `json:"expiration"`
+
+	// IDs of the credentials issued for the offer, keyed by credential configuration ID. The ID is empty while the
+	// credential is being issued.
+	IssuedCredentials map[string]string `json:"issuedCredentials,omitempty"`
+}
+
+// StoredToken is a code or access token issued for an offer. Tokens are stored under a hash of their value, so the
+// stored values can't be redeemed.
+type StoredToken struct {
+	Kind        TokenKind `json:"kind"`
+	OfferID     string    `json:"offerId"`
+	Expiration  string    `json:"expiration"`
+	RedirectURI string    `json:"redirectUri,omitempty"`
+
+	// PKCE code challenge of an authorization code.
+	CodeChallenge string `json:"codeChallenge,omitempty"`
+
+	// Whether a code was exchanged, or used up by wrong transaction codes.
+	Redeemed bool `json:"redeemed,omitempty"`
+
+	// c_nonce of an access token, which proofs must include.
+	CNonce           string `json:"cNonce,omitempty"`
+	CNonceExpiration string `json:"cNonceExpiration,omitempty"`
+}
+
+type Storage struct {
+	db storage.ServiceStorage
+}
+
+func NewOID4VCIStorage(db storage.ServiceStorage) (*Storage, error) {
+	if db == nil {
+		return nil, errors.New("db reference is nil")
+	}
+	return &Storage{db: db}, nil
+}
+
+func (s Storage) StoreOffer(ctx context.Context, offer StoredOffer) error {
+	if offer.ID == "" {
+		return errors.New("cannot store credential offer without an ID")
+	}
+	return writeOffer(ctx, s.db, offer)
+}
+
+func (s Storage) GetOffer(ctx context.Context, id string) (*StoredOffer, error) {
+	data, err := s.db.Read(ctx, offerNamespace, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading credential offer: %s", id)
+	}
+	if len(data) == 0 {
+		return nil, errors.Wrapf(ErrOfferNotFound, "id: %s", id)
+	}
+	var offer StoredOffer
+	if err = json.Unmarshal(data, &offer); err != nil {
+		return nil, errors.Wrapf(err, "unmarshalling stored credential offer: %s", id)
+	}
+	return &offer, nil
+}
+
+// UpdateOffer changes the offer with the given ID with update, and stores it. The offer is read and stored in a
+// transaction that fails when the offer is changed concurrently.
+func (s Storage) UpdateOffer(ctx context.Context, id string, update func(offer *StoredOffer) error) (*StoredOffer, error) {
+	watchKeys := []storage.WatchKey{{Namespace: offerNamespace, Key: id}}
+	updated, err := s.db.Execute(ctx, func(ctx context.Context, tx storage.Tx) (any, error) {
+		offer, err := s.GetOffer(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if err = update(offer); err != nil {
+			return nil, err
+		}
+		if err = writeOffer(ctx, tx, *offer); err != nil {
+			return nil, err
+		}
+		return offer, nil
+	}, watchKeys)
+	if err != nil {
+		return nil, err
+	}
+	return updated.(*StoredOffer), nil
+}
+
+func (s Storage) DeleteOffer(ctx context.Context, id string) error {
+	if err := s.db.Delete(ctx, offerNamespace, id); err != nil {
+		return errors.Wrapf(err, "deleting credential offer: %s", id)
+	}
+	return nil
+}
+
+func (s Storage) StoreToken(ctx context.Context, value string, token StoredToken) error {
+	return writeToken(ctx, s.db, value, token)
+}
+
+func (s Storage) GetToken(ctx context.Context, value string) (*StoredToken, error) {
+	data, err := s.db.Read(ctx, tokenNamespace, hashValue(value))
+	if err != nil {
+		return nil, errors.Wrap(err, "reading token")
+	}
+	if len(data) == 0 {
+		return nil, ErrTokenNotFound
+	}
+	var token StoredToken
+	if err = json.Unmarshal(data, &token); err != nil {
+		return nil, errors.Wrap(err, "unmarshalling stored token")
+	}
+	return &token, nil
+}
+
+// UpdateToken changes a token with update, and stores it. The token is read and stored in a transaction that fails
+// when the token is changed concurrently, so that only one of concurrent updates is based on a given version of it.
+func (s Storage) UpdateToken(ctx context.Context, value string, update func(token *StoredToken) error) (*StoredToken, error) {
+	watchKeys := []storage.WatchKey{{Namespace: tokenNamespace, Key: hashValue(value)}}
+	updated, err := s.db.Execute(ctx, func(ctx context.Context, tx storage.Tx) (any, error) {
+		token, err := s.GetToken(ctx, value)
+		if err != nil {
+			return nil, err
+		}
+		if err = update(token); err != nil {
+			return nil, err
+		}
+		if err = writeToken(ctx, tx, value, *token); err != nil {
+			return nil, err
+		}
+		return token, nil
+	}, watchKeys)
+	if err != nil {
+		return nil, err
+	}
+	return updated.(*StoredToken), nil
+}
+
+// UpdateTokenAndOffer changes a token and the offer with the given ID with update, and stores both, in a transaction
+// that fails when either is changed concurrently.
+func (s Storage) UpdateTokenAndOffer(ctx context.Context, value, offerID string, update func(token *StoredToken, offer *StoredOffer) error) error {
+	watchKeys := []storage.WatchKey{
+		{Namespace: tokenNamespace, Key: hashValue(value)},
+		{Namespace: offerNamespace, Key: offerID},
+	}
+	_, err := s.db.Execute(ctx, func(ctx context.Context, tx storage.Tx) (any, error) {
+		token, err := s.GetToken(ctx, value)
+		if err != nil {
+			return nil, err
+		}
+		offer, err := s.GetOffer(ctx, offerID)
+		if err != nil {
+			return nil, err
+		}
+		if err = update(token, offer); err != nil {
+			return nil, err
+		}
+		if err = writeToken(ctx, tx, value, *token); err != nil {
+			return nil, err
+		}
+		if err = writeOffer(ctx, tx, *offer); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}, watchKeys)
+	return err
+}
+
+func (s Storage) DeleteToken(ctx context.Context, value string) error {
+	if err := s.db.Delete(ctx, tokenNamespace, hashValue(value)); err != nil {
+		return errors.Wrap(err, "deleting token")
+	}
+	return nil
+}
+
+func writeOffer(ctx context.Context, tx storage.Tx, offer StoredOffer) error {
+	data, err := json.Marshal(offer)
+	if err != nil {
+		return errors.Wrap(err, "marshalling credential offer")
+	}
+	return tx.Write(ctx, offerNamespace, offer.ID, data)
+}
+
+func writeToken(ctx context.Context, tx storage.Tx, value string, token StoredToken) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return errors.Wrap(err, "marshalling token")
+	}
+	return tx.Write(ctx, tokenNamespace, hashValue(value), data)
+}
+
+func hashValue(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,232 @@
+package oid4vci
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Authorize handles an authorization request in the authorization code flow, returning the authorization code to
+// redirect the wallet with. The request's issuer_state must be the ID of an offer made for the flow, and the request
+// must have a PKCE code challenge.
+func (s Service) Authorize(ctx context.Context, request AuthorizationRequest) (string, error) {
+	if request.ResponseType != "code" {
+		return "", newError(UnsupportedResponseType, "response_type must be code")
+	}
+	if request.CodeChallenge == "" || request.CodeChallengeMethod != CodeChallengeMethodS256 {
+		return "", newError(InvalidRequest, "a PKCE code_challenge with the S256 method is required")
+	}
+	if request.IssuerState == "" {
+		return "", newError(InvalidRequest, "issuer_state is required")
+	}
+
+	offer, err := s.storage.GetOffer(ctx, request.IssuerState)
+	if err != nil {
+		if errors.Is(err, ErrOfferNotFound) {
+			return "", newError(AccessDenied, "no credential offer found for issuer_state")
+		}
+		return "", errors.Wrap(err, "getting credential offer")
+	}
+	if offer.PreAuthorizedCode != "" {
+		return "", newError(AccessDenied, "the credential offer must be redeemed with its pre-authorized code")
+	}
+	if s.expired(offer.Expiration) {
+		return "", newError(AccessDenied, "the credential offer has expired")
+	}
+
+	code, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	stored := StoredToken{
+		Kind:          AuthorizationCodeToken,
+		OfferID:       offer.ID,
+		Expiration:    s.Clock.Now().Add(authorizationCodeDuration).Format(time.RFC3339),
+		RedirectURI:   request.RedirectURI,
+		CodeChallenge: request.CodeChallenge,
+	}
+	if err = s.storage.StoreToken(ctx, code, stored); err != nil {
+		return "", errors.Wrap(err, "storing authorization code")
+	}
+	return code, nil
+}
+
+// Token exchanges a pre-authorized code or an authorization code for an access token to the credential endpoint.
+// Codes can only be exchanged once. A pre-authorized code that requires a transaction code stops working after too
+// many wrong attempts.
+func (s Service) Token(ctx context.Context, request TokenRequest) (*TokenResponse, error) {
+	var offerID string
+	var err error
+	switch request.GrantType {
+	case PreAuthorizedCodeGrantType:
+		offerID, err = s.redeemPreAuthorizedCode(ctx, request)
+	case AuthorizationCodeGrantType:
+		offerID, err = s.redeemAuthorizationCode(ctx, request)
+	default:
+		return nil, newError(UnsupportedGrantType, "grant_type %q is not supported", request.GrantType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	stored := StoredToken{
+		Kind:       AccessToken,
+		OfferID:    offerID,
+		Expiration: s.Clock.Now().Add(accessTokenDuration).Format(time.RFC3339),
+	}
+	if err = s.rotateCNonce(&stored); err != nil {
+		return nil, err
+	}
+	if err = s.storage.StoreToken(ctx, accessToken, stored); err != nil {
+		return nil, errors.Wrap(err, "storing access token")
+	}
+	return &TokenResponse{
+		AccessToken:     accessToken,
+		TokenType:       "Bearer",
+		ExpiresIn:       int(accessTokenDuration.Seconds()),
+		CNonce:          stored.CNonce,
+		CNonceExpiresIn: int(cNonceDuration.Seconds()),
+	}, nil
+}
+
+func (s Service) redeemPreAuthorizedCode(ctx context.Context, request TokenRequest) (string, error) {
+	if request.PreAuthorizedCode == "" {
+		return "", newError(InvalidRequest, "pre-authorized_code is required")
+	}
+	code, err := s.getToken(ctx, request.PreAuthorizedCode, PreAuthorizedCodeToken)
+	if err != nil {
+		if errors.Is(err, ErrTokenNotFound) {
+			return "", newError(InvalidGrant, "pre-authorized_code is invalid or expired")
+		}
+		return "", err
+	}
+
+	// the code is redeemed, or an attempt is counted, in a transaction so that concurrent requests can't redeem it
+	// twice, or try more transaction codes than allowed
+	var txCodeInvalid bool
+	err = s.storage.UpdateTokenAndOffer(ctx, request.PreAuthorizedCode, code.OfferID, func(code *StoredToken, offer *StoredOffer) error {
+		txCodeInvalid = false
+		if !s.usable(*code, PreAuthorizedCodeToken) {
+			return ErrTokenNotFound
+		}
+		if offer.TxCodeHash != "" && request.TxCode == "" {
+			return newError(InvalidRequest, "tx_code is required")
+		}
+		if offer.TxCodeHash != "" && !equal(hashValue(request.TxCode), offer.TxCodeHash) {
+			txCodeInvalid = true
+			offer.TxCodeAttempts++
+			code.Redeemed = offer.TxCodeAttempts >= maxTxCodeAttempts
+			return nil
+		}
+		code.Redeemed = true
+		return nil
+	})
+	if err != nil {
+		if oauthErr := GetError(err); oauthErr != nil {
+			return "", oauthErr
+		}
+		if errors.Is(err, ErrTokenNotFound) || errors.Is(err, ErrOfferNotFound) {
+			return "", newError(InvalidGrant, "pre-authorized_code is invalid or expired")
+		}
+		return "", errors.Wrap(err, "redeeming pre-authorized code")
+	}
+	if txCodeInvalid {
+		return "", newError(InvalidGrant, "tx_code is invalid")
+	}
+	return code.OfferID, nil
+}
+
+func (s Service) redeemAuthorizationCode(ctx context.Context, request TokenRequest) (string, error) {
+	if request.Code == "" || request.CodeVerifier == "" {
+		return "", newError(InvalidRequest, "code and code_verifier are required")
+	}
+	code, err := s.getToken(ctx, request.Code, AuthorizationCodeToken)
+	if err != nil {
+		if errors.Is(err, ErrTokenNotFound) {
+			return "", newError(InvalidGrant, "code is invalid or expired")
+		}
+		return "", err
+	}
+
+	// codes are single use, whether or not the exchange succeeds, and are redeemed in a transaction so that
+	// concurrent requests can't redeem them twice
+	var exchangeErr error
+	err = s.storage.UpdateTokenAndOffer(ctx, request.Code, code.OfferID, func(code *StoredToken, offer *StoredOffer) error {
+		if !s.usable(*code, AuthorizationCodeToken) {
+			return ErrTokenNotFound
+		}
+		code.Redeemed = true
+		exchangeErr = s.checkAuthorizationCode(request, *code, *offer)
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrTokenNotFound) {
+			return "", newError(InvalidGrant, "code is invalid or expired")
+		}
+		if errors.Is(err, ErrOfferNotFound) {
+			return "", newError(InvalidGrant, "the credential offer was deleted")
+		}
+		return "", errors.Wrap(err, "redeeming authorization code")
+	}
+	if exchangeErr != nil {
+		return "", exchangeErr
+	}
+	return code.OfferID, nil
+}
+
+// checkAuthorizationCode checks that a token request matches the authorization request the code was issued for, and
+// that the code's offer is still valid.
+func (s Service) checkAuthorizationCode(request TokenRequest, code StoredToken, offer StoredOffer) error {
+	if request.RedirectURI != code.RedirectURI {
+		return newError(InvalidGrant, "redirect_uri does not match the authorization request")
+	}
+	challenge := sha256.Sum256([]byte(request.CodeVerifier))
+	if !equal(base64.RawURLEncoding.EncodeToString(challenge[:]), code.CodeChallenge) {
+		return newError(InvalidGrant, "code_verifier does not match the code_challenge")
+	}
+	if s.expired(offer.Expiration) {
+		return newError(InvalidGrant, "the credential offer has expired")
+	}
+	return nil
+}
+
+// getToken gets a stored token of the given kind. Tokens that are missing, of another kind, redeemed, or expired,
+// result in ErrTokenNotFound.
+func (s Service) getToken(ctx context.Context, value string, kind TokenKind) (*StoredToken, error) {
+	token, err := s.storage.GetToken(ctx, value)
+	if err != nil {
+		return nil, err
+	}
+	if !s.usable(*token, kind) {
+		return nil, ErrTokenNotFound
+	}
+	return token, nil
+}
+
+// usable returns whether a token is of the given kind, and can still be used.
+func (s Service) usable(token StoredToken, kind TokenKind) bool {
+	return token.Kind == kind && !token.Redeemed && !s.expired(token.Expiration)
+}
+
+// rotateCNonce sets a fresh c_nonce on an access token, which the next proof must include.
+func (s Service) rotateCNonce(token *StoredToken) error {
+	nonce, err := randomToken()
+	if err != nil {
+		return err
+	}
+	token.CNonce = nonce
+	token.CNonceExpiration = s.Clock.Now().Add(cNonceDuration).Format(time.RFC3339)
+	return nil
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
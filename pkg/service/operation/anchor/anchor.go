@@ -0,0 +1,61 @@
+package anchor
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// ParentResource is the prefix of the ION operation parent resource.
+	ParentResource = "dids/ion/operations"
+)
+
+// NewID returns a new ID for an operation that tracks the anchoring of an ION operation.
+func NewID() string {
+	return fmt.Sprintf("%s/%s", ParentResource, uuid.NewString())
+}
+
+// Type is the kind of ION operation being anchored.
+type Type string
+
+const (
+	TypeCreate     Type = "create"
+	TypeUpdate     Type = "update"
+	TypeRecover    Type = "recover"
+	TypeDeactivate Type = "deactivate"
+)
+
+// Status indicates how far an ION operation is from being published.
+type Status string
+
+const (
+	// StatusPending operations haven't been accepted by the ION node yet. They are retried with backoff.
+	StatusPending Status = "pending"
+	// StatusAnchored operations were accepted by the ION node, and are polled until they are published.
+	StatusAnchored Status = "anchored"
+	// StatusPublished operations can be resolved from the ION network.
+	StatusPublished Status = "published"
+	// StatusFailed operations were rejected by the ION node, and won't be retried.
+	StatusFailed Status = "failed"
+)
+
+// Anchoring is the progress of an ION operation. It's the response of the operation that tracks it, and is set from
+// the moment the operation is created.
+type Anchoring struct {
+	// DID the ION operation was made for.
+	DID  string `json:"did"`
+	Type Type   `json:"type"`
+
+	Status Status `json:"status"`
+	// Attempts is how many times the ION operation was submitted to the ION node.
+	Attempts int `json:"attempts"`
+	// LastError is why the last submission failed, if it did.
+	LastError string `json:"lastError,omitempty"`
+	// NextAttemptAt is when the ION operation is next submitted, while pending. Formatted as RFC3339.
+	NextAttemptAt string `json:"nextAttemptAt,omitempty"`
+	// AnchoredAt is when the ION node accepted the ION operation. Formatted as RFC3339.
+	AnchoredAt string `json:"anchoredAt,omitempty"`
+	// PublishedAt is when the ION operation was found to be published. Formatted as RFC3339.
+	PublishedAt string `json:"publishedAt,omitempty"`
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/tbd54566975/ssi-service/pkg/service/framework"
 	manifestmodel "github.com/tbd54566975/ssi-service/pkg/service/manifest/model"
 	manifeststg "github.com/tbd54566975/ssi-service/pkg/service/manifest/storage"
+	"github.com/tbd54566975/ssi-service/pkg/service/operation/anchor"
 	"github.com/tbd54566975/ssi-service/pkg/service/operation/credential"
 	opstorage "github.com/tbd54566975/ssi-service/pkg/service/operation/storage"
 	"github.com/tbd54566975/ssi-service/pkg/service/operation/submission"
@@ -96,6 +97,12 @@ func ServiceModel(op opstorage.StoredOperation) (*Operation, error) {
 				return nil, errors.Wrap(err, "unmarshalling cred response")
 			}
 			newOp.Result.Response = manifestmodel.ServiceModel(&s)
+		case strings.HasPrefix(op.ID, anchor.ParentResource):
+			var a anchor.Anchoring
+			if err := json.Unmarshal(op.Response, &a); err != nil {
+				return nil, errors.Wrap(err, "unmarshalling anchoring response")
+			}
+			newOp.Result.Response = a
 		default:
 			return nil, errors.New("unknown response type")
 		}
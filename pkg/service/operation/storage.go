@@ -11,6 +11,7 @@ import (
 	"github.com/tbd54566975/ssi-service/pkg/service/common"
 	"go.einride.tech/aip/filtering"
 
+	"github.com/tbd54566975/ssi-service/pkg/service/operation/anchor"
 	"github.com/tbd54566975/ssi-service/pkg/service/operation/credential"
 	opstorage "github.com/tbd54566975/ssi-service/pkg/service/operation/storage"
 	"github.com/tbd54566975/ssi-service/pkg/service/operation/storage/namespace"
@@ -57,6 +58,8 @@ func (s Storage) CancelOperation(ctx context.Context, id string) (*opstorage.Sto
 				}),
 			},
 		)
+	case strings.HasPrefix(id, anchor.ParentResource):
+		return nil, errors.New("ION operations cannot be cancelled once submitted")
 	default:
 		return nil, errors.New("unrecognized id structure")
 	}
@@ -1,6 +1,8 @@
 package model
 
 import (
+	"time"
+
 	credsdk "github.com/TBD54566975/ssi-sdk/credential"
 	"github.com/TBD54566975/ssi-sdk/credential/exchange"
 	"github.com/TBD54566975/ssi-sdk/util"
@@ -11,11 +13,14 @@ import (
 
 	"github.com/tbd54566975/ssi-service/internal/credential"
 	"github.com/tbd54566975/ssi-service/internal/keyaccess"
+	"github.com/tbd54566975/ssi-service/pkg/service/presentation/policy"
 	"github.com/tbd54566975/ssi-service/pkg/service/presentation/storage"
 )
 
 type CreatePresentationDefinitionRequest struct {
 	PresentationDefinition exchange.PresentationDefinition `json:"presentationDefinition" validate:"required"`
+	// Policy that automatically approves or denies the submissions made against the definition.
+	Policy *policy.Policy `json:"policy,omitempty"`
 }
 
 func (cpr CreatePresentationDefinitionRequest) IsValid() error {
@@ -24,6 +29,7 @@ func (cpr CreatePresentationDefinitionRequest) IsValid() error {
 
 type CreatePresentationDefinitionResponse struct {
 	PresentationDefinition exchange.PresentationDefinition `json:"presentationDefinition"`
+	Policy                 *policy.Policy                  `json:"policy,omitempty"`
 }
 
 type GetPresentationDefinitionRequest struct {
@@ -32,6 +38,7 @@ type GetPresentationDefinitionRequest struct {
 
 type GetPresentationDefinitionResponse struct {
 	PresentationDefinition exchange.PresentationDefinition `json:"presentationDefinition"`
+	Policy                 *policy.Policy                  `json:"policy,omitempty"`
 }
 
 type DeletePresentationDefinitionRequest struct {
@@ -160,3 +167,63 @@ type Request struct {
 	// This is an output only field.
 	PresentationDefinitionJWT keyaccess.JWT `json:"presentationRequestJwt"`
 }
+
+type CreateAuthorizationRequestRequest struct {
+	// DID of the verifier, which is the client_id of the authorization request.
+	ClientID string `json:"clientId" validate:"required"`
+
+	// ID of the verification method whose private key, stored in the service, signs the authorization request.
+	VerificationMethodID string `json:"verificationMethodId" validate:"required"`
+
+	// ID of the presentation definition the wallet's response must satisfy.
+	PresentationDefinitionID string `json:"presentationDefinitionId" validate:"required"`
+
+	// When the authorization request expires. Responses to expired requests are rejected.
+	Expiration *time.Time `json:"expiration,omitempty"`
+}
+
+// AuthorizationRequest is an OID4VP authorization request, as described in
+// https://openid.net/specs/openid-4-verifiable-presentations-1_0.html#name-authorization-request
+type AuthorizationRequest struct {
+	// ID of the request. It's also the request's state, which the wallet sends back with its response.
+	ID                       string     `json:"id"`
+	ClientID                 string     `json:"clientId"`
+	Nonce                    string     `json:"nonce"`
+	PresentationDefinitionID string     `json:"presentationDefinitionId"`
+	Expiration               *time.Time `json:"expiration,omitempty"`
+
+	// URI the wallet fetches the signed request from.
+	RequestURI string `json:"requestUri"`
+
+	// URI the wallet posts its response to, with the direct_post response mode.
+	ResponseURI string `json:"responseUri"`
+
+	// The signed request object, served at RequestURI.
+	RequestJWT keyaccess.JWT `json:"requestJwt"`
+
+	// URI that invokes the wallet, such as from a QR code, with the client_id and request_uri of the request.
+	AuthorizationURI string `json:"authorizationUri"`
+
+	// SubmissionID is set once the wallet has responded to the request with a presentation.
+	SubmissionID string `json:"submissionId,omitempty"`
+
+	// The error the wallet responded to the request with, such as `access_denied` when the holder declined it.
+	Error            string `json:"error,omitempty"`
+	ErrorDescription string `json:"errorDescription,omitempty"`
+}
+
+// AuthorizationResponse is a wallet's response to an authorization request, as posted with the direct_post response
+// mode.
+type AuthorizationResponse struct {
+	VPToken                keyaccess.JWT                   `json:"vp_token" validate:"required"`
+	PresentationSubmission exchange.PresentationSubmission `json:"presentation_submission" validate:"required"`
+	State                  string                          `json:"state" validate:"required"`
+}
+
+// AuthorizationErrorResponse is a wallet's error response to an authorization request, as posted with the direct_post
+// response mode, and described in https://openid.net/specs/openid-4-verifiable-presentations-1_0.html#name-error-response
+type AuthorizationErrorResponse struct {
+	Error            string `json:"error" validate:"required"`
+	ErrorDescription string `json:"error_description,omitempty"`
+	State            string `json:"state" validate:"required"`
+}
@@ -0,0 +1,300 @@
+package presentation
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	credsdk "github.com/TBD54566975/ssi-sdk/credential"
+	"github.com/TBD54566975/ssi-sdk/credential/integrity"
+	"github.com/TBD54566975/ssi-sdk/did"
+	sdkutil "github.com/TBD54566975/ssi-sdk/util"
+	"github.com/google/uuid"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/tbd54566975/ssi-service/config"
+	credint "github.com/tbd54566975/ssi-service/internal/credential"
+	"github.com/tbd54566975/ssi-service/internal/keyaccess"
+	"github.com/tbd54566975/ssi-service/internal/verification"
+	"github.com/tbd54566975/ssi-service/pkg/service/framework"
+	"github.com/tbd54566975/ssi-service/pkg/service/operation"
+	"github.com/tbd54566975/ssi-service/pkg/service/presentation/model"
+	presentationstorage "github.com/tbd54566975/ssi-service/pkg/service/presentation/storage"
+)
+
+const (
+	// OID4VPRequestsPath is where authorization requests are served from, relative to the presentation service's path.
+	OID4VPRequestsPath = "/oid4vp/requests"
+	// OID4VPResponsePath is where wallets post their responses to, relative to the presentation service's path.
+	OID4VPResponsePath = "/oid4vp/response"
+
+	// SDJWTFormat is the format of the descriptors of presentation submissions sent with an SD-JWT vp_token.
+	SDJWTFormat = "vc+sd-jwt"
+
+	// selfIssuedAudience is the audience of request objects sent to wallets, as described in
+	// https://openid.net/specs/openid-4-verifiable-presentations-1_0.html#name-aud-of-a-request-object
+	selfIssuedAudience = "https://self-issued.me/v2"
+	oid4vpScheme       = "openid4vp"
+)
+
+// CreateAuthorizationRequest creates an OID4VP authorization request that asks a wallet for a presentation satisfying
+// a stored presentation definition. The request object is signed with the verifier's key, and is served from the
+// request's RequestURI. Its nonce and state bind the wallet's response to it.
+func (s Service) CreateAuthorizationRequest(ctx context.Context, request model.CreateAuthorizationRequestRequest) (*model.AuthorizationRequest, error) {
+	if err := sdkutil.IsValidStruct(request); err != nil {
+		return nil, sdkutil.LoggingErrorMsg(err, "invalid create authorization request request")
+	}
+
+	storedDefinition, err := s.storage.GetDefinition(ctx, request.PresentationDefinitionID)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting presentation definition")
+	}
+
+	id := uuid.NewString()
+	nonce := uuid.NewString()
+	responseURI := config.GetServicePath(framework.Presentation) + OID4VPResponsePath
+	builder := jwt.NewBuilder().
+		Issuer(request.ClientID).
+		Audience([]string{selfIssuedAudience}).
+		IssuedAt(time.Now()).
+		Claim("response_type", "vp_token").
+		Claim("response_mode", "direct_post").
+		Claim("client_id", request.ClientID).
+		Claim("client_id_scheme", "did").
+		Claim("response_uri", responseURI).
+		Claim("nonce", nonce).
+		Claim("state", id).
+		Claim("presentation_definition", storedDefinition.PresentationDefinition)
+	var expiration string
+	if request.Expiration != nil {
+		builder.Expiration(*request.Expiration)
+		expiration = request.Expiration.Format(time.RFC3339)
+	}
+	token, err := builder.Build()
+	if err != nil {
+		return nil, errors.Wrap(err, "building request object")
+	}
+
+	keyStoreID := did.FullyQualifiedVerificationMethodID(request.ClientID, request.VerificationMethodID)
+	signedToken, err := s.keystore.Sign(ctx, keyStoreID, token)
+	if err != nil {
+		return nil, errors.Wrapf(err, "signing request object with KID %q", request.VerificationMethodID)
+	}
+
+	stored := presentationstorage.StoredAuthorizationRequest{
+		ID:                       id,
+		ClientID:                 request.ClientID,
+		VerificationMethodID:     request.VerificationMethodID,
+		Nonce:                    nonce,
+		PresentationDefinitionID: request.PresentationDefinitionID,
+		ResponseURI:              responseURI,
+		Expiration:               expiration,
+		JWT:                      signedToken.String(),
+	}
+	if err = s.storage.StoreAuthorizationRequest(ctx, stored); err != nil {
+		return nil, errors.Wrap(err, "storing authorization request")
+	}
+	return authorizationRequestModel(stored)
+}
+
+// GetAuthorizationRequest gets the OID4VP authorization request with the given ID.
+func (s Service) GetAuthorizationRequest(ctx context.Context, id string) (*model.AuthorizationRequest, error) {
+	logrus.Debugf("getting authorization request: %s", id)
+
+	stored, err := s.storage.GetAuthorizationRequest(ctx, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting authorization request with id: %s", id)
+	}
+	return authorizationRequestModel(*stored)
+}
+
+// SubmitAuthorizationResponse creates a submission from a wallet's response to an OID4VP authorization request. The
+// response's state must be the ID of a request that hasn't expired and hasn't been responded to. The vp_token must
+// carry the request's nonce, and be addressed to the request's client_id. The submission is made against the
+// request's presentation definition, and reviewed as any other submission is.
+func (s Service) SubmitAuthorizationResponse(ctx context.Context, response model.AuthorizationResponse) (*operation.Operation, error) {
+	if err := sdkutil.IsValidStruct(response); err != nil {
+		return nil, errors.Wrap(err, "invalid authorization response")
+	}
+
+	// the request is marked as responded to before the submission is created, so that concurrent responses can't
+	// each create one
+	submissionID := response.PresentationSubmission.ID
+	stored, err := s.respondToAuthorizationRequest(ctx, response.State, func(stored *presentationstorage.StoredAuthorizationRequest) error {
+		if response.PresentationSubmission.DefinitionID != stored.PresentationDefinitionID {
+			return errors.Errorf("presentation submission is for definition %q, but the authorization request is for %q",
+				response.PresentationSubmission.DefinitionID, stored.PresentationDefinitionID)
+		}
+		stored.SubmissionID = submissionID
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var op *operation.Operation
+	if keyaccess.IsSDJWT(response.VPToken.String()) {
+		op, err = s.createSDJWTSubmission(ctx, *stored, response)
+	} else {
+		op, err = s.createJWTSubmission(ctx, *stored, response)
+	}
+	if err != nil {
+		// responses that are rejected don't use up the request
+		if _, releaseErr := s.storage.UpdateAuthorizationRequest(ctx, stored.ID, func(stored *presentationstorage.StoredAuthorizationRequest) error {
+			if stored.SubmissionID == submissionID {
+				stored.SubmissionID = ""
+			}
+			return nil
+		}); releaseErr != nil {
+			logrus.WithError(releaseErr).Errorf("could not release authorization request<%s>", stored.ID)
+		}
+		return nil, errors.Wrap(err, "creating submission")
+	}
+	return op, nil
+}
+
+// SubmitAuthorizationErrorResponse records a wallet's error response to an OID4VP authorization request, such as when
+// the holder declined to present credentials. The response's state must be the ID of a request that hasn't expired
+// and hasn't been responded to, and the request can't be responded to afterwards.
+func (s Service) SubmitAuthorizationErrorResponse(ctx context.Context, response model.AuthorizationErrorResponse) error {
+	if err := sdkutil.IsValidStruct(response); err != nil {
+		return errors.Wrap(err, "invalid authorization error response")
+	}
+
+	stored, err := s.respondToAuthorizationRequest(ctx, response.State, func(stored *presentationstorage.StoredAuthorizationRequest) error {
+		stored.Error = response.Error
+		stored.ErrorDescription = response.ErrorDescription
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	logrus.Infof("wallet responded to authorization request<%s> with error<%s>: %s", stored.ID, stored.Error, stored.ErrorDescription)
+	return nil
+}
+
+// respondToAuthorizationRequest atomically checks that the authorization request with the given state can be responded
+// to, and marks it as responded to with respond.
+func (s Service) respondToAuthorizationRequest(ctx context.Context, state string, respond func(stored *presentationstorage.StoredAuthorizationRequest) error) (*presentationstorage.StoredAuthorizationRequest, error) {
+	stored, err := s.storage.UpdateAuthorizationRequest(ctx, state, func(stored *presentationstorage.StoredAuthorizationRequest) error {
+		if stored.Responded() {
+			return errors.Errorf("authorization request %s was already responded to", stored.ID)
+		}
+		if stored.Expiration != "" {
+			expiration, err := time.Parse(time.RFC3339, stored.Expiration)
+			if err != nil {
+				return errors.Wrap(err, "parsing expiration time")
+			}
+			if time.Now().After(expiration) {
+				return errors.Errorf("authorization request %s has expired", stored.ID)
+			}
+		}
+		return respond(stored)
+	})
+	if err != nil {
+		if errors.Is(err, presentationstorage.ErrAuthorizationRequestNotFound) {
+			return nil, errors.Errorf("no authorization request found for state: %s", state)
+		}
+		return nil, errors.Wrap(err, "responding to authorization request")
+	}
+	return stored, nil
+}
+
+// createJWTSubmission creates a submission from a vp_token that's a Verifiable Presentation encoded as a JWT.
+func (s Service) createJWTSubmission(ctx context.Context, stored presentationstorage.StoredAuthorizationRequest, response model.AuthorizationResponse) (*operation.Operation, error) {
+	_, token, vp, err := integrity.ParseVerifiablePresentationFromJWT(response.VPToken.String())
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing vp_token")
+	}
+	if nonce, _ := token.Get(integrity.NonceProperty); nonce != stored.Nonce {
+		return nil, errors.New("vp_token nonce does not match the authorization request")
+	}
+	if !sdkutil.Contains(stored.ClientID, token.Audience()) {
+		return nil, errors.Errorf("vp_token audience does not include the client_id: %s", stored.ClientID)
+	}
+
+	vp.PresentationSubmission = response.PresentationSubmission
+	credContainers, err := credint.NewCredentialContainerFromArray(vp.VerifiableCredential)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing verifiable credential array")
+	}
+	return s.CreateSubmission(ctx, model.CreateSubmissionRequest{
+		Presentation:  *vp,
+		SubmissionJWT: response.VPToken,
+		Submission:    response.PresentationSubmission,
+		Credentials:   credContainers,
+	})
+}
+
+// createSDJWTSubmission creates a submission from a vp_token that's an SD-JWT with a key binding JWT, which must be
+// for the authorization request's client_id and nonce. The SD-JWT's credential must satisfy the whole presentation
+// definition, and is stored as the only credential of a presentation whose holder is the credential's subject.
+func (s Service) createSDJWTSubmission(ctx context.Context, stored presentationstorage.StoredAuthorizationRequest, response model.AuthorizationResponse) (*operation.Operation, error) {
+	if err := sdkutil.IsValidStruct(response.PresentationSubmission); err != nil {
+		return nil, errors.Wrap(err, "provided value is not a valid presentation submission")
+	}
+	for _, descriptor := range response.PresentationSubmission.DescriptorMap {
+		if descriptor.Format != SDJWTFormat {
+			return nil, errors.Errorf("descriptor %q of an SD-JWT vp_token must have the %s format", descriptor.ID, SDJWTFormat)
+		}
+	}
+	storedDefinition, err := s.storage.GetDefinition(ctx, stored.PresentationDefinitionID)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting presentation definition")
+	}
+
+	presentation := keyaccess.SDJWT(response.VPToken)
+	report := s.verifier.VerifySDJWTPresentationWithReport(ctx, presentation, stored.ClientID, stored.Nonce,
+		verification.Options{PresentationDefinition: &storedDefinition.PresentationDefinition})
+	// credentials that are revoked or suspended deny the submission, rather than failing it
+	statusFailures := verification.GetStatusFailures(report.Err())
+	if err = report.Err(); err != nil && statusFailures == nil {
+		return nil, errors.Wrap(err, "verifying vp_token")
+	}
+
+	container, err := credint.NewCredentialContainerFromSDJWT(presentation.String())
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing vp_token")
+	}
+	vp := credsdk.VerifiablePresentation{
+		Context:                []string{credsdk.VerifiableCredentialsLinkedDataContext},
+		ID:                     uuid.NewString(),
+		Holder:                 container.Credential.CredentialSubject.GetID(),
+		Type:                   []string{credsdk.VerifiablePresentationType},
+		VerifiableCredential:   []any{presentation.String()},
+		PresentationSubmission: response.PresentationSubmission,
+	}
+	return s.storeSubmission(ctx, *storedDefinition, model.CreateSubmissionRequest{
+		Presentation: vp,
+		Submission:   response.PresentationSubmission,
+		Credentials:  []credint.Container{*container},
+	}, statusFailures)
+}
+
+func authorizationRequestModel(stored presentationstorage.StoredAuthorizationRequest) (*model.AuthorizationRequest, error) {
+	requestURI := config.GetServicePath(framework.Presentation) + OID4VPRequestsPath + "/" + stored.ID
+	request := &model.AuthorizationRequest{
+		ID:                       stored.ID,
+		ClientID:                 stored.ClientID,
+		Nonce:                    stored.Nonce,
+		PresentationDefinitionID: stored.PresentationDefinitionID,
+		RequestURI:               requestURI,
+		ResponseURI:              stored.ResponseURI,
+		RequestJWT:               keyaccess.JWT(stored.JWT),
+		SubmissionID:             stored.SubmissionID,
+		Error:                    stored.Error,
+		ErrorDescription:         stored.ErrorDescription,
+	}
+	query := url.Values{"client_id": {stored.ClientID}, "request_uri": {requestURI}}
+	request.AuthorizationURI = oid4vpScheme + "://?" + query.Encode()
+	if stored.Expiration != "" {
+		expiration, err := time.Parse(time.RFC3339, stored.Expiration)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing expiration time")
+		}
+		request.Expiration = &expiration
+	}
+	return request, nil
+}
@@ -0,0 +1,231 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	credsdk "github.com/TBD54566975/ssi-sdk/credential"
+	"github.com/TBD54566975/ssi-sdk/util"
+	"github.com/google/cel-go/cel"
+	"github.com/pkg/errors"
+)
+
+const (
+	// credentialsVariable is the list of presented credentials in policy expressions, each as its JSON object.
+	credentialsVariable = "credentials"
+	// holderVariable is the holder of the presentation in policy expressions.
+	holderVariable = "holder"
+)
+
+// Policy is attached to a presentation definition, and decides whether the submissions made against it are approved
+// or denied, without a manual review. Every rule that is set must pass for a submission to be approved.
+type Policy struct {
+	// DIDs trusted to issue the presented credentials. When set, a credential from any other issuer denies the
+	// submission.
+	TrustedIssuers []string `json:"trustedIssuers,omitempty"`
+
+	// IDs of the schemas that must each be the `credentialSchema` of one of the presented credentials.
+	RequiredSchemas []string `json:"requiredSchemas,omitempty"`
+
+	// Whether presented credentials that are revoked or suspended deny the submission. Submissions with credentials
+	// whose status can't be checked are left for a manual review.
+	CheckStatus bool `json:"checkStatus,omitempty"`
+
+	// The maximum time since the `issuanceDate` of each presented credential, as a Go duration such as `720h`.
+	MaxCredentialAge string `json:"maxCredentialAge,omitempty"`
+
+	// CEL expressions (https://github.com/google/cel-spec) that must each evaluate to true. The `credentials` variable
+	// is the list of presented credentials, and `holder` is the DID of the presentation's holder. For example,
+	// `credentials.exists(c, c.credentialSubject.age >= 18)`.
+	Expressions []string `json:"expressions,omitempty"`
+}
+
+// Decision is the outcome of evaluating a policy against a submission.
+type Decision string
+
+const (
+	DecisionApprove Decision = "approve"
+	DecisionDeny    Decision = "deny"
+	// DecisionManualReview is made when the policy can't decide, and the submission is left pending.
+	DecisionManualReview Decision = "manualReview"
+)
+
+// Result is the decision made by a policy, along with the reason it was made.
+type Result struct {
+	Decision Decision
+	Reason   string
+}
+
+// CredentialStatus is whether a credential is revoked or suspended.
+type CredentialStatus struct {
+	Revoked   bool
+	Suspended bool
+}
+
+// StatusChecker looks up the status of presented credentials.
+type StatusChecker interface {
+	// CheckStatus returns the status of a credential that has a `credentialStatus`, or nil when it's unknown.
+	CheckStatus(ctx context.Context, credential credsdk.VerifiableCredential) (*CredentialStatus, error)
+}
+
+// Validate checks that the max credential age is a positive duration, and that each expression compiles to a boolean.
+func (p Policy) Validate() error {
+	if _, err := p.maxCredentialAge(); err != nil {
+		return err
+	}
+	_, err := p.programs()
+	return err
+}
+
+// Evaluate decides whether a submission, made by the holder with the given credentials, is approved or denied.
+// The status checker is only used when the policy checks the status of credentials.
+func (p Policy) Evaluate(ctx context.Context, holder string, credentials []credsdk.VerifiableCredential, statusChecker StatusChecker) (*Result, error) {
+	maxAge, err := p.maxCredentialAge()
+	if err != nil {
+		return nil, err
+	}
+	programs, err := p.programs()
+	if err != nil {
+		return nil, err
+	}
+
+	var failures, undecided []string
+	presentedSchemas := make(map[string]bool)
+	for _, cred := range credentials {
+		if cred.CredentialSchema != nil {
+			presentedSchemas[cred.CredentialSchema.ID] = true
+		}
+
+		if len(p.TrustedIssuers) > 0 && !util.Contains(cred.IssuerID(), p.TrustedIssuers) {
+			failures = append(failures, fmt.Sprintf("credential<%s> was issued by untrusted issuer<%s>", cred.ID, cred.IssuerID()))
+		}
+
+		if maxAge > 0 {
+			issuanceDate, err := time.Parse(time.RFC3339, cred.IssuanceDate)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("credential<%s> has an invalid issuanceDate<%s>", cred.ID, cred.IssuanceDate))
+			} else if time.Since(issuanceDate) > maxAge {
+				failures = append(failures, fmt.Sprintf("credential<%s> is older than %s", cred.ID, p.MaxCredentialAge))
+			}
+		}
+
+		if p.CheckStatus && cred.CredentialStatus != nil {
+			if statusChecker == nil {
+				return nil, errors.New("policy checks the status of credentials, but no status checker was given")
+			}
+			status, err := statusChecker.CheckStatus(ctx, cred)
+			if err != nil {
+				return nil, errors.Wrapf(err, "checking status of credential<%s>", cred.ID)
+			}
+			switch {
+			case status == nil:
+				undecided = append(undecided, fmt.Sprintf("status of credential<%s> is unknown", cred.ID))
+			case status.Revoked:
+				failures = append(failures, fmt.Sprintf("credential<%s> is revoked", cred.ID))
+			case status.Suspended:
+				failures = append(failures, fmt.Sprintf("credential<%s> is suspended", cred.ID))
+			}
+		}
+	}
+
+	// rules about each credential pass vacuously when there are none, which mustn't approve the submission
+	if len(credentials) == 0 {
+		undecided = append(undecided, "no credentials were presented")
+	}
+
+	for _, schemaID := range p.RequiredSchemas {
+		if !presentedSchemas[schemaID] {
+			failures = append(failures, fmt.Sprintf("no credential with schema<%s> was presented", schemaID))
+		}
+	}
+
+	if len(programs) > 0 {
+		vars, err := expressionVariables(holder, credentials)
+		if err != nil {
+			return nil, err
+		}
+		for i, program := range programs {
+			out, _, err := program.Eval(vars)
+			if err != nil {
+				// expressions commonly fail on claims that weren't presented, which is left for a manual review
+				undecided = append(undecided, fmt.Sprintf("expression<%s> could not be evaluated: %s", p.Expressions[i], err))
+				continue
+			}
+			if passed, ok := out.Value().(bool); !ok || !passed {
+				failures = append(failures, fmt.Sprintf("expression<%s> is false", p.Expressions[i]))
+			}
+		}
+	}
+
+	switch {
+	case len(failures) > 0:
+		return &Result{Decision: DecisionDeny, Reason: "denied by policy: " + strings.Join(failures, "; ")}, nil
+	case len(undecided) > 0:
+		return &Result{Decision: DecisionManualReview, Reason: "left for manual review: " + strings.Join(undecided, "; ")}, nil
+	default:
+		return &Result{Decision: DecisionApprove, Reason: "approved by policy"}, nil
+	}
+}
+
+func (p Policy) maxCredentialAge() (time.Duration, error) {
+	if p.MaxCredentialAge == "" {
+		return 0, nil
+	}
+	maxAge, err := time.ParseDuration(p.MaxCredentialAge)
+	if err != nil {
+		return 0, errors.Wrapf(err, "parsing max credential age<%s>", p.MaxCredentialAge)
+	}
+	if maxAge <= 0 {
+		return 0, errors.Errorf("max credential age<%s> must be positive", p.MaxCredentialAge)
+	}
+	return maxAge, nil
+}
+
+// programs compiles each of the policy's expressions.
+func (p Policy) programs() ([]cel.Program, error) {
+	if len(p.Expressions) == 0 {
+		return nil, nil
+	}
+	env, err := cel.NewEnv(
+		cel.Variable(credentialsVariable, cel.ListType(cel.DynType)),
+		cel.Variable(holderVariable, cel.StringType),
+		// JSON numbers are doubles, which should still compare with integer literals
+		cel.CrossTypeNumericComparisons(true),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating cel env")
+	}
+	programs := make([]cel.Program, 0, len(p.Expressions))
+	for _, expression := range p.Expressions {
+		ast, issues := env.Compile(expression)
+		if issues != nil && issues.Err() != nil {
+			return nil, errors.Wrapf(issues.Err(), "compiling expression<%s>", expression)
+		}
+		if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+			return nil, errors.Errorf("expression<%s> must evaluate to a bool, not %s", expression, ast.OutputType())
+		}
+		program, err := env.Program(ast)
+		if err != nil {
+			return nil, errors.Wrapf(err, "creating program from expression<%s>", expression)
+		}
+		programs = append(programs, program)
+	}
+	return programs, nil
+}
+
+func expressionVariables(holder string, credentials []credsdk.VerifiableCredential) (map[string]any, error) {
+	credentialMaps := make([]any, 0, len(credentials))
+	for _, cred := range credentials {
+		credentialMap, err := util.ToJSONMap(cred)
+		if err != nil {
+			return nil, errors.Wrapf(err, "converting credential<%s> to JSON", cred.ID)
+		}
+		credentialMaps = append(credentialMaps, credentialMap)
+	}
+	return map[string]any{
+		credentialsVariable: credentialMaps,
+		holderVariable:      holder,
+	}, nil
+}
@@ -0,0 +1,111 @@
+package policy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	credsdk "github.com/TBD54566975/ssi-sdk/credential"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mapStatusChecker map[string]*CredentialStatus
+
+func (m mapStatusChecker) CheckStatus(_ context.Context, credential credsdk.VerifiableCredential) (*CredentialStatus, error) {
+	return m[credential.ID], nil
+}
+
+func testCredential(id string, issuanceDate time.Time) credsdk.VerifiableCredential {
+	return credsdk.VerifiableCredential{
+		ID:               id,
+		Issuer:           "did:example:issuer",
+		IssuanceDate:     issuanceDate.Format(time.RFC3339),
+		CredentialSchema: &credsdk.CredentialSchema{ID: "schema-" + id, Type: "JsonSchema"},
+		CredentialStatus: map[string]any{"type": "BitstringStatusListEntry"},
+		CredentialSubject: credsdk.CredentialSubject{
+			"id":  "did:example:holder",
+			"age": 21,
+		},
+	}
+}
+
+func TestPolicyEvaluate(t *testing.T) {
+	now := time.Now()
+	credentials := []credsdk.VerifiableCredential{testCredential("a", now.Add(-time.Hour)), testCredential("b", now.Add(-48*time.Hour))}
+	statuses := mapStatusChecker{"a": {}, "b": {}}
+	evaluate := func(p Policy, checker StatusChecker) *Result {
+		result, err := p.Evaluate(context.Background(), "did:example:holder", credentials, checker)
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("approves when every rule passes", func(tt *testing.T) {
+		result := evaluate(Policy{
+			TrustedIssuers:   []string{"did:example:issuer"},
+			RequiredSchemas:  []string{"schema-a", "schema-b"},
+			CheckStatus:      true,
+			MaxCredentialAge: "72h",
+			Expressions: []string{
+				"credentials.all(c, c.credentialSubject.age >= 18)",
+				"credentials.all(c, c.credentialSubject.id == holder)",
+			},
+		}, statuses)
+		assert.Equal(tt, DecisionApprove, result.Decision)
+	})
+
+	t.Run("denies with the reason of each failed rule", func(tt *testing.T) {
+		result := evaluate(Policy{
+			TrustedIssuers:   []string{"did:example:other"},
+			RequiredSchemas:  []string{"schema-c"},
+			CheckStatus:      true,
+			MaxCredentialAge: "24h",
+			Expressions:      []string{"credentials.size() > 2"},
+		}, mapStatusChecker{"a": {Revoked: true}, "b": {Suspended: true}})
+		assert.Equal(tt, DecisionDeny, result.Decision)
+		for _, reason := range []string{
+			"credential<a> was issued by untrusted issuer<did:example:issuer>",
+			"no credential with schema<schema-c> was presented",
+			"credential<b> is older than 24h",
+			"credential<a> is revoked",
+			"credential<b> is suspended",
+			"expression<credentials.size() > 2> is false",
+		} {
+			assert.Contains(tt, result.Reason, reason)
+		}
+	})
+
+	t.Run("leaves undecidable submissions for manual review", func(tt *testing.T) {
+		result := evaluate(Policy{CheckStatus: true}, mapStatusChecker{"a": {}})
+		assert.Equal(tt, DecisionManualReview, result.Decision)
+		assert.Contains(tt, result.Reason, "status of credential<b> is unknown")
+
+		result = evaluate(Policy{Expressions: []string{"credentials.all(c, c.credentialSubject.name != '')"}}, nil)
+		assert.Equal(tt, DecisionManualReview, result.Decision)
+
+		// failed rules still deny
+		result = evaluate(Policy{CheckStatus: true, RequiredSchemas: []string{"schema-c"}}, mapStatusChecker{})
+		assert.Equal(tt, DecisionDeny, result.Decision)
+	})
+
+	t.Run("leaves submissions without credentials for manual review", func(tt *testing.T) {
+		result, err := Policy{TrustedIssuers: []string{"did:example:issuer"}}.Evaluate(context.Background(), "did:example:holder", nil, nil)
+		require.NoError(tt, err)
+		assert.Equal(tt, DecisionManualReview, result.Decision)
+		assert.Contains(tt, result.Reason, "no credentials were presented")
+
+		result, err = Policy{RequiredSchemas: []string{"schema-a"}}.Evaluate(context.Background(), "did:example:holder", nil, nil)
+		require.NoError(tt, err)
+		assert.Equal(tt, DecisionDeny, result.Decision)
+	})
+}
+
+func TestPolicyValidate(t *testing.T) {
+	assert.NoError(t, Policy{}.Validate())
+	assert.NoError(t, Policy{MaxCredentialAge: "720h", Expressions: []string{"holder != ''"}}.Validate())
+
+	assert.Error(t, Policy{MaxCredentialAge: "30d"}.Validate())
+	assert.Error(t, Policy{MaxCredentialAge: "0s"}.Validate())
+	assert.Error(t, Policy{Expressions: []string{"unknown == 1"}}.Validate())
+	assert.Error(t, Policy{Expressions: []string{"credentials.size()"}}.Validate())
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	credsdk "github.com/TBD54566975/ssi-sdk/credential"
 	"github.com/TBD54566975/ssi-sdk/credential/exchange"
 	"github.com/TBD54566975/ssi-sdk/credential/integrity"
 	"github.com/TBD54566975/ssi-sdk/did/resolution"
@@ -16,12 +17,14 @@ import (
 	"github.com/tbd54566975/ssi-service/internal/keyaccess"
 	"github.com/tbd54566975/ssi-service/internal/verification"
 	"github.com/tbd54566975/ssi-service/pkg/service/common"
+	"github.com/tbd54566975/ssi-service/pkg/service/credential"
 	"github.com/tbd54566975/ssi-service/pkg/service/framework"
 	"github.com/tbd54566975/ssi-service/pkg/service/keystore"
 	"github.com/tbd54566975/ssi-service/pkg/service/operation"
 	opstorage "github.com/tbd54566975/ssi-service/pkg/service/operation/storage"
 	"github.com/tbd54566975/ssi-service/pkg/service/operation/submission"
 	"github.com/tbd54566975/ssi-service/pkg/service/presentation/model"
+	"github.com/tbd54566975/ssi-service/pkg/service/presentation/policy"
 	presentationstorage "github.com/tbd54566975/ssi-service/pkg/service/presentation/storage"
 	"github.com/tbd54566975/ssi-service/pkg/service/schema"
 	"github.com/tbd54566975/ssi-service/pkg/storage"
@@ -30,13 +33,14 @@ import (
 const presentationRequestNamespace = "presentation_request"
 
 type Service struct {
-	storage    presentationstorage.Storage
-	keystore   *keystore.Service
-	opsStorage *operation.Storage
-	resolver   resolution.Resolver
-	schema     *schema.Service
-	verifier   *verification.Verifier
-	reqStorage common.RequestStorage
+	storage       presentationstorage.Storage
+	keystore      *keystore.Service
+	opsStorage    *operation.Storage
+	resolver      resolution.Resolver
+	schema        *schema.Service
+	verifier      *verification.Verifier
+	reqStorage    common.RequestStorage
+	statusChecker policy.StatusChecker
 }
 
 func (s Service) Type() framework.Type {
@@ -67,19 +71,24 @@ func NewPresentationService(s storage.ServiceStorage,
 	if err != nil {
 		return nil, sdkutil.LoggingErrorMsg(err, "could not instantiate storage for the operations")
 	}
-	verifier, err := verification.NewVerifiableDataVerifier(resolver, schema)
+	credentialStorage, err := credential.NewCredentialStorage(s)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsg(err, "could not instantiate storage for the credentials")
+	}
+	verifier, err := verification.NewVerifiableDataVerifier(resolver, schema, credentialStorage)
 	if err != nil {
 		return nil, sdkutil.LoggingErrorMsg(err, "could not instantiate verifier")
 	}
 	requestStorage := common.NewRequestStorage(s, presentationRequestNamespace)
 	service := Service{
-		storage:    presentationStorage,
-		keystore:   keystore,
-		opsStorage: opsStorage,
-		resolver:   resolver,
-		schema:     schema,
-		verifier:   verifier,
-		reqStorage: requestStorage,
+		storage:       presentationStorage,
+		keystore:      keystore,
+		opsStorage:    opsStorage,
+		resolver:      resolver,
+		schema:        schema,
+		verifier:      verifier,
+		reqStorage:    requestStorage,
+		statusChecker: verifierStatusChecker{verifier: verifier},
 	}
 	if !service.Status().IsReady() {
 		return nil, errors.New(service.Status().Message)
@@ -88,22 +97,36 @@ func NewPresentationService(s storage.ServiceStorage,
 }
 
 type VerifyPresentationRequest struct {
-	PresentationJWT *keyaccess.JWT `json:"presentationJwt,omitempty" validate:"required"`
+	// Exactly one of PresentationJWT or PresentationSDJWT must be set.
+	PresentationJWT   *keyaccess.JWT   `json:"presentationJwt,omitempty" validate:"required_without=PresentationSDJWT,excluded_with=PresentationSDJWT"`
+	PresentationSDJWT *keyaccess.SDJWT `json:"presentationSdJwt,omitempty" validate:"required_without=PresentationJWT"`
+	// The audience and nonce the key binding JWT of an SD-JWT presentation must be for.
+	Audience string `json:"audience,omitempty" validate:"required_with=PresentationSDJWT"`
+	Nonce    string `json:"nonce,omitempty" validate:"required_with=PresentationSDJWT"`
+	// DIDs trusted to issue the presented credentials. The issuer trust check is skipped when empty.
+	TrustedIssuers []string `json:"trustedIssuers,omitempty"`
+	// ID of a stored presentation definition whose constraints the presentation's submission must satisfy. The
+	// presentation definition check is skipped when empty.
+	PresentationDefinitionID string `json:"presentationDefinitionId,omitempty"`
 }
 
 type VerifyPresentationResponse struct {
 	Verified bool   `json:"verified"`
 	Reason   string `json:"reason,omitempty"`
+	// Set for each credential that is revoked or suspended, or whose status couldn't be checked.
+	StatusFailures []verification.StatusFailure `json:"statusFailures,omitempty"`
+	// Each check that was run on the presentation and its credentials, and its outcome.
+	Report verification.Report `json:"report"`
 }
 
-// VerifyPresentation does a series of verification on a presentation:
-//  1. Makes sure the presentation has a valid signature
-//  2. Makes sure the presentation is not expired
-//  3. Makes sure the presentation complies with the VC Data Model v1.1
-//  4. For each verification in the presentation, makes sure:
-//     a. Makes sure the verification has a valid signature
-//     b. Makes sure the verification is not expired
-//     c. Makes sure the verification complies with the VC Data Model
+// VerifyPresentation runs the following checks on a presentation, and reports on each of them:
+//  1. Resolves the DID of the presentation's holder
+//  2. Makes sure the presentation has a valid signature
+//  3. For each credential in the presentation, runs the checks of credential verification, which include its
+//     signature, expiry, schema, status and issuer trust
+//  4. If a presentation definition is given, makes sure the presentation's submission satisfies its constraints
+//
+// The holder of an SD-JWT presentation is its credential's subject, and its signature is the key binding JWT's.
 func (s Service) VerifyPresentation(ctx context.Context, request VerifyPresentationRequest) (*VerifyPresentationResponse, error) {
 	logrus.Debugf("verifying presentation: %+v", request)
 
@@ -111,11 +134,27 @@ func (s Service) VerifyPresentation(ctx context.Context, request VerifyPresentat
 		return nil, sdkutil.LoggingErrorMsg(err, "invalid verify presentation request")
 	}
 
-	if err := s.verifier.VerifyJWTPresentation(ctx, *request.PresentationJWT); err != nil {
-		return &VerifyPresentationResponse{Verified: false, Reason: err.Error()}, nil
+	opts := verification.Options{TrustedIssuers: request.TrustedIssuers}
+	if request.PresentationDefinitionID != "" {
+		storedDefinition, err := s.storage.GetDefinition(ctx, request.PresentationDefinitionID)
+		if err != nil {
+			return nil, sdkutil.LoggingErrorMsgf(err, "getting presentation definition: %s", request.PresentationDefinitionID)
+		}
+		opts.PresentationDefinition = &storedDefinition.PresentationDefinition
 	}
 
-	return &VerifyPresentationResponse{Verified: true}, nil
+	var report *verification.Report
+	if request.PresentationSDJWT != nil {
+		report = s.verifier.VerifySDJWTPresentationWithReport(ctx, *request.PresentationSDJWT, request.Audience, request.Nonce, opts)
+	} else {
+		report = s.verifier.VerifyJWTPresentationWithReport(ctx, *request.PresentationJWT, opts)
+	}
+	resp := VerifyPresentationResponse{Verified: report.Verified, Report: *report}
+	if err := report.Err(); err != nil {
+		resp.Reason = err.Error()
+		resp.StatusFailures = report.StatusFailures()
+	}
+	return &resp, nil
 }
 
 // CreatePresentationDefinition houses the main service logic for presentation definition creation. It validates the input, and
@@ -132,9 +171,16 @@ func (s Service) CreatePresentationDefinition(ctx context.Context,
 		return nil, sdkutil.LoggingErrorMsg(err, "provided value is not a valid presentation definition")
 	}
 
+	if request.Policy != nil {
+		if err := request.Policy.Validate(); err != nil {
+			return nil, sdkutil.LoggingErrorMsg(err, "provided policy is not valid")
+		}
+	}
+
 	storedPresentation := presentationstorage.StoredDefinition{
 		ID:                     request.PresentationDefinition.ID,
 		PresentationDefinition: request.PresentationDefinition,
+		Policy:                 request.Policy,
 	}
 
 	if err := s.storage.StoreDefinition(ctx, storedPresentation); err != nil {
@@ -143,6 +189,7 @@ func (s Service) CreatePresentationDefinition(ctx context.Context,
 
 	var m model.CreatePresentationDefinitionResponse
 	m.PresentationDefinition = storedPresentation.PresentationDefinition
+	m.Policy = storedPresentation.Policy
 	return &m, nil
 }
 
@@ -159,6 +206,7 @@ func (s Service) GetPresentationDefinition(ctx context.Context,
 	}
 	return &model.GetPresentationDefinitionResponse{
 		PresentationDefinition: storedDefinition.PresentationDefinition,
+		Policy:                 storedDefinition.Policy,
 	}, nil
 }
 
@@ -190,7 +238,9 @@ func (s Service) DeletePresentationDefinition(ctx context.Context, request model
 }
 
 // CreateSubmission houses the main service logic for presentation submission creation. It validates the input, and
-// produces a presentation submission value that conforms with the Submission specification.
+// produces a presentation submission value that conforms with the Submission specification. Submissions with revoked
+// or suspended credentials are denied. When the presentation definition has a policy, the submission is approved or
+// denied by it, unless it can't decide. Otherwise, the submission is pending until it's reviewed.
 func (s Service) CreateSubmission(ctx context.Context, request model.CreateSubmissionRequest) (*operation.Operation, error) {
 	if !request.IsValid() {
 		return nil, errors.Errorf("invalid create presentation submission request: %+v", request)
@@ -219,29 +269,32 @@ func (s Service) CreateSubmission(ctx context.Context, request model.CreateSubmi
 		return nil, errors.Wrapf(err, "verifying token from did<%s> with kid<%s>", vp.Holder, kid)
 	}
 
-	if _, err = s.storage.GetSubmission(ctx, request.Submission.ID); !errors.Is(err, presentationstorage.ErrSubmissionNotFound) {
-		return nil, errors.Errorf("submission with id %s already present", request.Submission.ID)
-	}
-
 	storedDefinition, err := s.storage.GetDefinition(ctx, request.Submission.DefinitionID)
 	if err != nil {
 		return nil, errors.Wrap(err, "getting presentation definition")
 	}
 
+	// credentials that are revoked or suspended deny the submission, rather than failing it
+	var statusFailures []verification.StatusFailure
 	for _, cred := range request.Credentials {
 		if !cred.IsValid() {
 			return nil, errors.Errorf("invalid credential %+v", cred)
 		}
-		if cred.CredentialJWT != nil {
-			if err = s.verifier.VerifyJWTCredential(ctx, *cred.CredentialJWT); err != nil {
-				return nil, errors.Wrapf(err, "verifying jwt credential %s", cred.CredentialJWT)
-			}
-		} else {
-			if cred.HasDataIntegrityCredential() {
-				if err = s.verifier.VerifyDataIntegrityCredential(ctx, *cred.Credential); err != nil {
-					return nil, errors.Wrapf(err, "verifying data integrity credential %+v", cred.Credential)
-				}
+		var err error
+		switch {
+		case cred.HasSDJWTCredential():
+			_, err = s.verifier.VerifySDJWTCredential(ctx, *cred.SDJWT)
+		case cred.CredentialJWT != nil:
+			err = s.verifier.VerifyJWTCredential(ctx, *cred.CredentialJWT)
+		case cred.HasDataIntegrityCredential():
+			err = s.verifier.VerifyDataIntegrityCredential(ctx, *cred.Credential)
+		}
+		if err != nil {
+			if failures := verification.GetStatusFailures(err); failures != nil {
+				statusFailures = append(statusFailures, failures...)
+				continue
 			}
+			return nil, errors.Wrapf(err, "verifying credential %s", cred.Credential.ID)
 		}
 	}
 
@@ -249,12 +302,36 @@ func (s Service) CreateSubmission(ctx context.Context, request model.CreateSubmi
 	if _, err = exchange.VerifyPresentationSubmissionVP(storedDefinition.PresentationDefinition, request.Presentation); err != nil {
 		return nil, errors.Wrap(err, "verifying presentation submission vp")
 	}
+	return s.storeSubmission(ctx, *storedDefinition, request, statusFailures)
+}
+
+// storeSubmission stores a verified submission along with its operation. Submissions with revoked or suspended
+// credentials are denied, and otherwise reviewed by the definition's policy when it has one.
+func (s Service) storeSubmission(ctx context.Context, storedDefinition presentationstorage.StoredDefinition, request model.CreateSubmissionRequest, statusFailures []verification.StatusFailure) (*operation.Operation, error) {
+	_, err := s.storage.GetSubmission(ctx, request.Submission.ID)
+	if !errors.Is(err, presentationstorage.ErrSubmissionNotFound) {
+		return nil, errors.Errorf("submission with id %s already present", request.Submission.ID)
+	}
 
 	storedSubmission := presentationstorage.StoredSubmission{
 		Status:                 submission.StatusPending,
 		VerifiablePresentation: request.Presentation,
 	}
 
+	var autoReview *policy.Result
+	if len(statusFailures) > 0 {
+		statusErr := verification.StatusError{Failures: statusFailures}
+		autoReview = &policy.Result{Decision: policy.DecisionDeny, Reason: statusErr.Error()}
+	} else if storedDefinition.Policy != nil {
+		autoReview, err = s.evaluatePolicy(ctx, *storedDefinition.Policy, request)
+		if err != nil {
+			return nil, errors.Wrap(err, "evaluating presentation definition policy")
+		}
+		if autoReview.Decision == policy.DecisionManualReview {
+			storedSubmission.Reason = autoReview.Reason
+		}
+	}
+
 	// TODO(andres): IO requests should be done in parallel, once we have context wired up.
 	if err = s.storage.StoreSubmission(ctx, storedSubmission); err != nil {
 		return nil, errors.Wrap(err, "could not store presentation")
@@ -273,12 +350,37 @@ func (s Service) CreateSubmission(ctx context.Context, request model.CreateSubmi
 		return nil, errors.Wrap(err, "could not store operation")
 	}
 
+	if autoReview != nil && autoReview.Decision != policy.DecisionManualReview {
+		_, reviewedOp, err := s.storage.UpdateSubmission(ctx, sub.ID, autoReview.Decision == policy.DecisionApprove,
+			autoReview.Reason, opID)
+		if err != nil {
+			return nil, errors.Wrap(err, "reviewing submission by policy")
+		}
+		return operation.ServiceModel(reviewedOp)
+	}
+
 	return &operation.Operation{
 		ID:   storedOp.ID,
 		Done: false,
 	}, nil
 }
 
+// evaluatePolicy decides on a submission with the policy of its presentation definition.
+func (s Service) evaluatePolicy(ctx context.Context, p policy.Policy, request model.CreateSubmissionRequest) (*policy.Result, error) {
+	credentials := make([]credsdk.VerifiableCredential, 0, len(request.Credentials))
+	for i, cred := range request.Credentials {
+		// the policy can't decide on credentials it doesn't see
+		if cred.Credential == nil {
+			return &policy.Result{
+				Decision: policy.DecisionManualReview,
+				Reason:   fmt.Sprintf("left for manual review: credential<%d> could not be evaluated", i),
+			}, nil
+		}
+		credentials = append(credentials, *cred.Credential)
+	}
+	return p.Evaluate(ctx, request.Presentation.Holder, credentials, s.statusChecker)
+}
+
 func (s Service) GetSubmission(ctx context.Context, request model.GetSubmissionRequest) (*model.GetSubmissionResponse, error) {
 	logrus.Debugf("getting presentation submission: %s", request.ID)
 
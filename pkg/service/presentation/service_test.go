@@ -0,0 +1,38 @@
+package presentation
+
+import (
+	"context"
+	"testing"
+
+	credsdk "github.com/TBD54566975/ssi-sdk/credential"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	credint "github.com/tbd54566975/ssi-service/internal/credential"
+	"github.com/tbd54566975/ssi-service/pkg/service/presentation/model"
+	"github.com/tbd54566975/ssi-service/pkg/service/presentation/policy"
+)
+
+func TestEvaluatePolicy(t *testing.T) {
+	p := policy.Policy{TrustedIssuers: []string{"did:example:issuer"}}
+	evaluate := func(credentials ...credint.Container) *policy.Result {
+		result, err := Service{}.evaluatePolicy(context.Background(), p, model.CreateSubmissionRequest{
+			Presentation: credsdk.VerifiablePresentation{Holder: "did:example:holder"},
+			Credentials:  credentials,
+		})
+		require.NoError(t, err)
+		return result
+	}
+
+	trusted := credint.Container{Credential: &credsdk.VerifiableCredential{ID: "trusted", Issuer: "did:example:issuer"}}
+	assert.Equal(t, policy.DecisionApprove, evaluate(trusted).Decision)
+
+	// submissions are only approved when every credential was evaluated
+	result := evaluate()
+	assert.Equal(t, policy.DecisionManualReview, result.Decision)
+	assert.Contains(t, result.Reason, "no credentials were presented")
+
+	result = evaluate(trusted, credint.Container{})
+	assert.Equal(t, policy.DecisionManualReview, result.Decision)
+	assert.Contains(t, result.Reason, "credential<1> could not be evaluated")
+}
@@ -0,0 +1,28 @@
+package presentation
+
+import (
+	"context"
+
+	credsdk "github.com/TBD54566975/ssi-sdk/credential"
+	"github.com/sirupsen/logrus"
+
+	"github.com/tbd54566975/ssi-service/internal/verification"
+	"github.com/tbd54566975/ssi-service/pkg/service/presentation/policy"
+)
+
+// verifierStatusChecker looks up the status of credentials in their status lists.
+type verifierStatusChecker struct {
+	verifier *verification.Verifier
+}
+
+// CheckStatus returns the status of a credential, which is unknown when its status lists can't be read.
+func (c verifierStatusChecker) CheckStatus(ctx context.Context, cred credsdk.VerifiableCredential) (*policy.CredentialStatus, error) {
+	status, err := c.verifier.GetCredentialStatus(ctx, cred)
+	if err != nil {
+		logrus.WithError(err).Debugf("could not get the status of credential<%s>", cred.ID)
+		return nil, nil
+	}
+	return &policy.CredentialStatus{Revoked: status.Revoked, Suspended: status.Suspended}, nil
+}
+
+var _ policy.StatusChecker = (*verifierStatusChecker)(nil)
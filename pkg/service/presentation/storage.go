@@ -21,6 +21,7 @@ import (
 
 const (
 	presentationDefinitionNamespace = "presentation_definition"
+	authorizationRequestNamespace   = "oid4vp_authorization_request"
 )
 
 type Storage struct {
@@ -125,7 +126,7 @@ func (ps *Storage) GetDefinition(ctx context.Context, id string) (*prestorage.St
 		return nil, sdkutil.LoggingErrorMsgf(err, "could not get presentation definition: %s", id)
 	}
 	if len(jsonBytes) == 0 {
-		return nil, sdkutil.LoggingNewErrorf("presentation definition not found with id: %s", id)
+		return nil, sdkutil.LoggingErrorMsgf(prestorage.ErrDefinitionNotFound, "reading presentation definition with id: %s", id)
 	}
 	var stored prestorage.StoredDefinition
 	if err := json.Unmarshal(jsonBytes, &stored); err != nil {
@@ -189,3 +190,58 @@ func (ps *Storage) ListDefinitions(ctx context.Context) ([]prestorage.StoredDefi
 	}
 	return ts, nil
 }
+
+func (ps *Storage) StoreAuthorizationRequest(ctx context.Context, request prestorage.StoredAuthorizationRequest) error {
+	id := request.ID
+	if id == "" {
+		return sdkutil.LoggingNewError("could not store authorization request without an ID")
+	}
+	jsonBytes, err := json.Marshal(request)
+	if err != nil {
+		return sdkutil.LoggingErrorMsgf(err, "could not store authorization request: %s", id)
+	}
+	return ps.db.Write(ctx, authorizationRequestNamespace, id, jsonBytes)
+}
+
+func (ps *Storage) GetAuthorizationRequest(ctx context.Context, id string) (*prestorage.StoredAuthorizationRequest, error) {
+	jsonBytes, err := ps.db.Read(ctx, authorizationRequestNamespace, id)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsgf(err, "could not get authorization request: %s", id)
+	}
+	if len(jsonBytes) == 0 {
+		return nil, sdkutil.LoggingErrorMsgf(prestorage.ErrAuthorizationRequestNotFound, "reading authorization request with id: %s", id)
+	}
+	var stored prestorage.StoredAuthorizationRequest
+	if err := json.Unmarshal(jsonBytes, &stored); err != nil {
+		return nil, sdkutil.LoggingErrorMsgf(err, "could not unmarshal stored authorization request: %s", id)
+	}
+	return &stored, nil
+}
+
+// UpdateAuthorizationRequest changes the authorization request with the given ID with update, and stores it. The
+// request is read and stored in a transaction that fails when the request is changed concurrently, so that only one
+// of concurrent updates is based on a given version of the request.
+func (ps *Storage) UpdateAuthorizationRequest(ctx context.Context, id string, update func(request *prestorage.StoredAuthorizationRequest) error) (*prestorage.StoredAuthorizationRequest, error) {
+	watchKeys := []storage.WatchKey{{Namespace: authorizationRequestNamespace, Key: id}}
+	updated, err := ps.db.Execute(ctx, func(ctx context.Context, tx storage.Tx) (any, error) {
+		stored, err := ps.GetAuthorizationRequest(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if err = update(stored); err != nil {
+			return nil, err
+		}
+		jsonBytes, err := json.Marshal(stored)
+		if err != nil {
+			return nil, sdkutil.LoggingErrorMsgf(err, "could not store authorization request: %s", id)
+		}
+		if err = tx.Write(ctx, authorizationRequestNamespace, id, jsonBytes); err != nil {
+			return nil, sdkutil.LoggingErrorMsgf(err, "could not store authorization request: %s", id)
+		}
+		return stored, nil
+	}, watchKeys)
+	if err != nil {
+		return nil, err
+	}
+	return updated.(*prestorage.StoredAuthorizationRequest), nil
+}
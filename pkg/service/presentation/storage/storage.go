@@ -9,17 +9,20 @@ import (
 	"github.com/tbd54566975/ssi-service/pkg/service/common"
 	opstorage "github.com/tbd54566975/ssi-service/pkg/service/operation/storage"
 	"github.com/tbd54566975/ssi-service/pkg/service/operation/submission"
+	"github.com/tbd54566975/ssi-service/pkg/service/presentation/policy"
 	"go.einride.tech/aip/filtering"
 )
 
 type StoredDefinition struct {
 	ID                     string                          `json:"id"`
 	PresentationDefinition exchange.PresentationDefinition `json:"presentationDefinition"`
+	Policy                 *policy.Policy                  `json:"policy,omitempty"`
 }
 
 type Storage interface {
 	DefinitionStorage
 	SubmissionStorage
+	AuthorizationRequestStorage
 }
 
 type DefinitionStorage interface {
@@ -53,4 +56,38 @@ type SubmissionStorage interface {
 	UpdateSubmission(ctx context.Context, id string, approved bool, reason string, submissionID string) (StoredSubmission, opstorage.StoredOperation, error)
 }
 
+var ErrDefinitionNotFound = errors.New("presentation definition not found")
+
 var ErrSubmissionNotFound = errors.New("submission not found")
+
+// StoredAuthorizationRequest is an OID4VP authorization request, which is looked up by its state when the wallet
+// posts its response.
+type StoredAuthorizationRequest struct {
+	ID                       string `json:"id"`
+	ClientID                 string `json:"clientId"`
+	VerificationMethodID     string `json:"verificationMethodId"`
+	Nonce                    string `json:"nonce"`
+	PresentationDefinitionID string `json:"presentationDefinitionId"`
+	ResponseURI              string `json:"responseUri"`
+	Expiration               string `json:"expiration,omitempty"`
+	JWT                      string `json:"jwt"`
+	// ID of the submission created from the wallet's response. Once set, the request can't be responded to again.
+	SubmissionID string `json:"submissionId,omitempty"`
+	// The error the wallet responded with, such as when the holder declined the request. Once set, the request can't
+	// be responded to again.
+	Error            string `json:"error,omitempty"`
+	ErrorDescription string `json:"errorDescription,omitempty"`
+}
+
+// Responded returns whether the wallet has responded to the request, either with a presentation or with an error.
+func (r StoredAuthorizationRequest) Responded() bool {
+	return r.SubmissionID != "" || r.Error != ""
+}
+
+type AuthorizationRequestStorage interface {
+	StoreAuthorizationRequest(ctx context.Context, request StoredAuthorizationRequest) error
+	GetAuthorizationRequest(ctx context.Context, id string) (*StoredAuthorizationRequest, error)
+	UpdateAuthorizationRequest(ctx context.Context, id string, update func(request *StoredAuthorizationRequest) error) (*StoredAuthorizationRequest, error)
+}
+
+var ErrAuthorizationRequestNotFound = errors.New("authorization request not found")
@@ -13,6 +13,7 @@ import (
 	"github.com/tbd54566975/ssi-service/pkg/service/issuance"
 	"github.com/tbd54566975/ssi-service/pkg/service/keystore"
 	"github.com/tbd54566975/ssi-service/pkg/service/manifest"
+	"github.com/tbd54566975/ssi-service/pkg/service/oid4vci"
 	"github.com/tbd54566975/ssi-service/pkg/service/operation"
 	"github.com/tbd54566975/ssi-service/pkg/service/presentation"
 	"github.com/tbd54566975/ssi-service/pkg/service/schema"
@@ -35,6 +36,7 @@ type SSIService struct {
 	storage          storage.ServiceStorage
 	BatchDID         *did.BatchService
 	DIDConfiguration *wellknown.DIDConfigurationService
+	OID4VCI          *oid4vci.Service
 }
 
 // InstantiateSSIService creates a new instance of the SSIS which instantiates all services and their
@@ -137,6 +139,11 @@ func instantiateServices(config config.ServicesConfig) (*SSIService, error) {
 		return nil, sdkutil.LoggingErrorMsg(err, "could not instantiate the manifest service")
 	}
 
+	oid4vciService, err := oid4vci.NewOID4VCIService(storageProvider, didResolver, credentialService)
+	if err != nil {
+		return nil, sdkutil.LoggingErrorMsg(err, "could not instantiate the oid4vci service")
+	}
+
 	operationService, err := operation.NewOperationService(storageProvider)
 	if err != nil {
 		return nil, sdkutil.LoggingErrorMsg(err, "could not instantiate the operation service")
@@ -155,6 +162,7 @@ func instantiateServices(config config.ServicesConfig) (*SSIService, error) {
 		Operation:        operationService,
 		Webhook:          webhookService,
 		DIDConfiguration: didConfigurationService,
+		OID4VCI:          oid4vciService,
 		storage:          storageProvider,
 	}, nil
 }
@@ -171,6 +179,7 @@ func (s *SSIService) GetServices() []framework.Service {
 		s.Presentation,
 		s.Operation,
 		s.Webhook,
+		s.OID4VCI,
 	}
 }
 
@@ -28,6 +28,8 @@ const (
 	BatchCreate = Verb("BatchCreate")
 	Create      = Verb("Create")
 	Delete      = Verb("Delete")
+	// Expire is published by the service itself, rather than in response to a request, when a credential expires.
+	Expire = Verb("Expire")
 )
 
 type Webhook struct {
@@ -88,14 +90,14 @@ func (wh Webhook) IsEmpty() bool {
 }
 
 func (cwr DeleteWebhookRequest) IsValid() bool {
-	if cwr.Noun.IsValid() && cwr.Verb.isValid() && isValidURL(cwr.URL) {
+	if cwr.Noun.IsValid() && cwr.Verb.isValidFor(cwr.Noun) && isValidURL(cwr.URL) {
 		return true
 	}
 	return false
 }
 
 func (cwr CreateWebhookRequest) IsValid() bool {
-	if cwr.Noun.IsValid() && cwr.Verb.isValid() && isValidURL(cwr.URL) {
+	if cwr.Noun.IsValid() && cwr.Verb.isValidFor(cwr.Noun) && isValidURL(cwr.URL) {
 		return true
 	}
 	return false
@@ -111,13 +113,21 @@ func (n Noun) IsValid() bool {
 
 func (v Verb) isValid() bool {
 	switch v {
-	case Create, Delete:
+	case Create, Delete, Expire:
 		return true
 	default:
 		return false
 	}
 }
 
+// isValidFor returns whether the verb is valid, and can be published for the noun. Only credentials expire.
+func (v Verb) isValidFor(n Noun) bool {
+	if v == Expire {
+		return n == Credential
+	}
+	return v.isValid()
+}
+
 // isValidURL checks if there were any errors during parsing and if the parsed DIDWebID has a non-empty Scheme and Host.
 // currently we support any scheme including http, https, ftp ...
 func isValidURL(urlStr string) bool {
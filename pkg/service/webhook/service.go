@@ -174,12 +174,23 @@ func (s Service) GetSupportedNouns() GetSupportedNounsResponse {
 }
 
 func (s Service) GetSupportedVerbs() GetSupportedVerbsResponse {
-	return GetSupportedVerbsResponse{Verbs: []Verb{Create, Delete}}
+	return GetSupportedVerbsResponse{Verbs: []Verb{Create, Delete, Expire}}
 }
 
 // TODO: consider returning an error to be handled by the gin middleware
 func (s Service) PublishWebhook(c *gin.Context, noun Noun, verb Verb, payloadReader io.Reader) {
-	timeoutCtx, cancel := context.WithTimeout(c.Copy(), s.timeoutDuration)
+	payloadBytes, err := io.ReadAll(payloadReader)
+	if err != nil {
+		logrus.WithError(err).Error("converting payload to bytes")
+		return
+	}
+	s.Publish(c.Copy(), noun, verb, payloadBytes)
+}
+
+// Publish posts the payload to every URL registered for the noun and verb. Unlike PublishWebhook, it is meant for
+// events that are not a response to a request.
+func (s Service) Publish(ctx context.Context, noun Noun, verb Verb, payloadBytes []byte) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, s.timeoutDuration)
 	defer cancel()
 
 	nounString := string(noun)
@@ -195,12 +206,6 @@ func (s Service) PublishWebhook(c *gin.Context, noun Noun, verb Verb, payloadRea
 		return
 	}
 
-	payloadBytes, err := io.ReadAll(payloadReader)
-	if err != nil {
-		logrus.WithError(err).Error("converting payload to bytes")
-		return
-	}
-
 	var wg sync.WaitGroup
 	postPayload := Payload{Noun: noun, Verb: verb, Data: payloadBytes}
 	for _, url := range webhook.URLS {
@@ -34,7 +34,7 @@ type DIDConfigurationService struct {
 
 func NewDIDConfigurationService(keyStoreService *keystore.Service, didResolver resolution.Resolver, schema *schema.Service) (*DIDConfigurationService, error) {
 	client := &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
-	verifier, err := verification.NewVerifiableDataVerifier(didResolver, schema)
+	verifier, err := verification.NewVerifiableDataVerifier(didResolver, schema, nil)
 	if err != nil {
 		return nil, errors.Wrap(err, "could not instantiate verifier for the credential service")
 	}
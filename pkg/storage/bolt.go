@@ -154,6 +154,14 @@ func (btx *boltTx) Write(_ context.Context, namespace, key string, value []byte)
 	return writeFunc(namespace, key, value)(btx.tx)
 }
 
+func (btx *boltTx) Delete(_ context.Context, namespace, key string) error {
+	bucket := btx.tx.Bucket([]byte(namespace))
+	if bucket == nil {
+		return nil
+	}
+	return bucket.Delete([]byte(key))
+}
+
 // Execute runs the provided function within a transaction. Any failure during execution results in a rollback.
 // It is recommended to not open transactions within businessLogicFunc, as there are situation in which the interplay
 // between transactions may cause deadlocks.
@@ -13,6 +13,7 @@ import (
 	"github.com/alicebob/miniredis/v2"
 	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
 	"github.com/goccy/go-json"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -542,6 +543,36 @@ func TestDB_Execute(t *testing.T) {
 	}
 }
 
+func TestDB_ExecuteDelete(t *testing.T) {
+	for _, dbImpl := range getDBImplementations(t) {
+		db := dbImpl
+		require.NoError(t, db.Write(context.Background(), "hello", "my_key", []byte(`some bytes`)))
+
+		// deletes are rolled back with the rest of a failed transaction
+		_, err := db.Execute(context.Background(), func(ctx context.Context, tx Tx) (any, error) {
+			if err := tx.Delete(ctx, "hello", "my_key"); err != nil {
+				return nil, err
+			}
+			return nil, errors.New("failed")
+		}, nil)
+		assert.Error(t, err)
+		exists, err := db.Exists(context.Background(), "hello", "my_key")
+		assert.NoError(t, err)
+		assert.True(t, exists)
+
+		_, err = db.Execute(context.Background(), func(ctx context.Context, tx Tx) (any, error) {
+			if err := tx.Delete(ctx, "hello", "my_key"); err != nil {
+				return nil, err
+			}
+			return nil, tx.Delete(ctx, "missing", "my_key")
+		}, nil)
+		assert.NoError(t, err)
+		exists, err = db.Exists(context.Background(), "hello", "my_key")
+		assert.NoError(t, err)
+		assert.False(t, exists)
+	}
+}
+
 func TestDB_UpdatedSubmissionAndOperationTxFn(t *testing.T) {
 	for _, dbImpl := range getDBImplementations(t) {
 		db := dbImpl
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
 	"github.com/google/cel-go/common/types/ref"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -55,6 +56,10 @@ func simpleEquals(lhs ref.Val, rhs ref.Val) ref.Val {
 	return lhs.Equal(rhs)
 }
 
+func simpleAnd(lhs ref.Val, rhs ref.Val) ref.Val {
+	return types.Bool(lhs == types.True && rhs == types.True)
+}
+
 func newCelEnv() (*cel.Env, error) {
 	return cel.NewEnv(
 		cel.Function("=",
@@ -65,5 +70,10 @@ func newCelEnv() (*cel.Env, error) {
 			cel.Overload("=_string",
 				[]*cel.Type{cel.StringType, cel.StringType},
 				cel.BoolType,
-				cel.BinaryBinding(simpleEquals))))
+				cel.BinaryBinding(simpleEquals))),
+		cel.Function("AND",
+			cel.Overload("AND_bool",
+				[]*cel.Type{cel.BoolType, cel.BoolType},
+				cel.BoolType,
+				cel.BinaryBinding(simpleAnd))))
 }
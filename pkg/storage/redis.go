@@ -62,6 +62,11 @@ func (rtx *redisTx) Write(ctx context.Context, namespace, key string, value []by
 	return rtx.pipe.Set(ctx, nameSpaceKey, value, 0).Err()
 }
 
+func (rtx *redisTx) Delete(ctx context.Context, namespace, key string) error {
+	nameSpaceKey := getRedisKey(namespace, key)
+	return rtx.pipe.Del(ctx, nameSpaceKey).Err()
+}
+
 func (b *RedisDB) Init(opts ...Option) error {
 	address, password, err := processRedisOptions(opts...)
 	if err != nil {
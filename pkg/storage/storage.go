@@ -21,6 +21,8 @@ type WatchKey struct {
 
 type Tx interface {
 	Write(ctx context.Context, namespace, key string, value []byte) error
+	// Delete removes a key. Deleting a key that doesn't exist is not an error.
+	Delete(ctx context.Context, namespace, key string) error
 }
 
 const (